@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,9 +38,13 @@ const (
 )
 
 // DatabaseSpec defines the desired state of Database.
+// +kubebuilder:validation:XValidation:rule="has(self.targetCluster) == has(oldSelf.targetCluster)",message="targetCluster cannot be added or removed"
 type DatabaseSpec struct {
-	// Type specifies the database type (PostgreSQL, MongoDB, Redis, Elasticsearch, SQLite)
+	// Type specifies the database type (PostgreSQL, MongoDB, Redis, Elasticsearch,
+	// SQLite). It cannot be changed: the data of one engine does not run on
+	// another, and the workload of the previous engine would be left behind.
 	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="type cannot be changed, create a new Database for the other engine"
 	Type DatabaseType `json:"type"`
 
 	// Version specifies the version of the database to deploy
@@ -46,21 +52,65 @@ type DatabaseSpec struct {
 	// +kubebuilder:validation:MinLength=1
 	Version string `json:"version"`
 
-	// Replicas specifies the number of database replicas
+	// ClassName selects the cluster-scoped DatabaseClass of the Database. The
+	// engine, version and profile must be allowed by the class, which fills in
+	// the storage class, profile and backups the spec leaves unset.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+
+	// ImageResolution controls how the version tag is turned into an image. Tag
+	// runs the tag as is; Digest resolves the tag to a digest once, records it in
+	// status.image and pins the workloads to it, so a tag moved upstream never
+	// changes what runs. The tag is only resolved again when the version changes.
+	// +kubebuilder:default=Tag
+	// +optional
+	ImageResolution ImageResolution `json:"imageResolution,omitempty"`
+
+	// Image selects the image distribution the engine runs from
+	// +optional
+	Image *ImageSpec `json:"image,omitempty"`
+
+	// Replicas specifies the number of database replicas. It is also set
+	// through the scale subresource, by a HorizontalPodAutoscaler or KEDA.
 	// +kubebuilder:default=1
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Maximum=10
 	// +optional
 	Replicas *int32 `json:"replicas,omitempty"`
 
+	// Topology schedules replica counts by time of day, overriding replicas
+	// during the windows of its schedules
+	// +optional
+	Topology *TopologySpec `json:"topology,omitempty"`
+
 	// Storage defines the storage configuration for the database
 	// +optional
 	Storage *StorageSpec `json:"storage,omitempty"`
 
-	// Resources defines the compute resources for the database
+	// DiskPressure configures the thresholds at which the usage of the data
+	// volumes is reported by the DiskPressure condition
+	// +optional
+	DiskPressure *DiskPressureSpec `json:"diskPressure,omitempty"`
+
+	// Resources defines the compute resources for the database. Fields set
+	// here take precedence over the ones of the profile.
 	// +optional
 	Resources *ResourceRequirements `json:"resources,omitempty"`
 
+	// Profile presets the resources of the engine container and the memory
+	// settings of the engine sized to them. Fields of resources and engine
+	// parameters set in the spec take precedence.
+	// +optional
+	Profile DatabaseProfile `json:"profile,omitempty"`
+
+	// PodSecurity is the Pod Security Standards level the pods of the Database
+	// and of its Jobs are generated for. Restricted runs them as the non-root
+	// user of the image, and is the default of images running as non-root;
+	// the official PostgreSQL and SQLite images start as root and require
+	// Baseline. The level applies to workloads created after it is set.
+	// +optional
+	PodSecurity PodSecurityLevel `json:"podSecurity,omitempty"`
+
 	// PostgreSQL specific configuration
 	// +optional
 	PostgreSQL *PostgreSQLConfig `json:"postgresql,omitempty"`
@@ -84,210 +134,2348 @@ type DatabaseSpec struct {
 	// Environment variables to set in the database container
 	// +optional
 	Env []EnvVar `json:"env,omitempty"`
-}
 
-// StorageSpec defines the storage configuration
-type StorageSpec struct {
-	// Size specifies the size of the persistent volume
-	// +kubebuilder:validation:Required
-	Size string `json:"size"`
+	// AutoTune allows analysis Jobs to apply their recommendations automatically
+	// +optional
+	AutoTune bool `json:"autoTune,omitempty"`
 
-	// StorageClass specifies the storage class to use
+	// Observability configures logging of the database engine
 	// +optional
-	StorageClass *string `json:"storageClassName,omitempty"`
+	Observability *ObservabilitySpec `json:"observability,omitempty"`
 
-	// AccessMode specifies the access mode for the volume
-	// +kubebuilder:default=ReadWriteOnce
+	// Backup configures scheduled backups of the database
 	// +optional
-	AccessMode string `json:"accessMode,omitempty"`
-}
+	Backup *BackupSpec `json:"backup,omitempty"`
 
-// ResourceRequirements defines the compute resources
-type ResourceRequirements struct {
-	// CPU resource request
+	// ScaleDownProtection defers removing replicas that still serve client connections
 	// +optional
-	CPU string `json:"cpu,omitempty"`
+	ScaleDownProtection *ScaleDownProtectionSpec `json:"scaleDownProtection,omitempty"`
 
-	// Memory resource request
+	// Networking configures network access of the database and its Jobs
 	// +optional
-	Memory string `json:"memory,omitempty"`
+	Networking *NetworkingSpec `json:"networking,omitempty"`
 
-	// CPU resource limit
+	// TLS serves the database endpoint over TLS (PostgreSQL, MongoDB and Redis)
 	// +optional
-	CPULimit string `json:"cpuLimit,omitempty"`
+	TLS *TLSSpec `json:"tls,omitempty"`
 
-	// Memory resource limit
+	// Metadata adds labels and annotations to the generated Service, workload,
+	// pods and volume claims
 	// +optional
-	MemoryLimit string `json:"memoryLimit,omitempty"`
+	Metadata *ResourceMetadataSpec `json:"metadata,omitempty"`
+
+	// Bootstrap declares the logical databases and users provisioned in the
+	// instance. They are created once the database is ready and kept in sync with
+	// the spec; removing an entry does not drop the database or user.
+	// +optional
+	Bootstrap *BootstrapSpec `json:"bootstrap,omitempty"`
+
+	// RotationPolicy schedules the rotation of the generated password of the
+	// administrative user, or for Redis of its passwordSecret
+	// +optional
+	RotationPolicy *RotationPolicy `json:"rotationPolicy,omitempty"`
+
+	// Maintenance restricts the disruptive operations of the operator to weekly
+	// time windows: scale-downs, workload updates such as version and
+	// configuration changes, major upgrades and password rotations
+	// +optional
+	Maintenance *MaintenanceSpec `json:"maintenance,omitempty"`
+
+	// Rollout configures how changes of the pod template roll out to the
+	// replicas of the StatefulSet
+	// +optional
+	Rollout *RolloutSpec `json:"rollout,omitempty"`
+
+	// Lifecycle runs hooks in the engine container after it starts and before
+	// it stops, and hibernates the Database
+	// +optional
+	Lifecycle *LifecycleSpec `json:"lifecycle,omitempty"`
+
+	// Provisioning configures retries and rollback of the initial provisioning
+	// +optional
+	Provisioning *ProvisioningSpec `json:"provisioning,omitempty"`
+
+	// DeletionPolicy controls what happens to the data when the Database is
+	// deleted. Delete removes it with the workload; Snapshot takes a final
+	// DatabaseBackup first and blocks the deletion until it completed.
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// DeletionSnapshotTimeout bounds how long the deletion waits for the final
+	// backup of the Snapshot deletion policy (default: 1h). A backup still running
+	// then blocks the deletion like a failed one.
+	// +optional
+	DeletionSnapshotTimeout *metav1.Duration `json:"deletionSnapshotTimeout,omitempty"`
+
+	// TargetCluster places the child resources in another cluster, for
+	// operators running in fleet mode. Status stays in this cluster.
+	// +optional
+	TargetCluster *TargetClusterSpec `json:"targetCluster,omitempty"`
 }
 
-// PostgreSQLConfig defines PostgreSQL-specific configuration
-type PostgreSQLConfig struct {
-	// Database name to create
+// DeletionPolicy defines what happens to the data of a deleted Database
+// +kubebuilder:validation:Enum=Delete;Snapshot
+type DeletionPolicy string
+
+const (
+	DeletionPolicyDelete   DeletionPolicy = "Delete"
+	DeletionPolicySnapshot DeletionPolicy = "Snapshot"
+)
+
+// SkipDeletionSnapshotAnnotation set to "true" on a Database deletes it without
+// waiting for the final backup of the Snapshot deletion policy
+const SkipDeletionSnapshotAnnotation = "databases.database-operator.io/skip-deletion-snapshot"
+
+// FreezeUntilAnnotation set to an RFC 3339 timestamp (e.g. "2025-12-31T00:00:00Z")
+// suspends the disruptive actions of the operator on a Database until then, for
+// release freezes: scaling, password rotation and auto-tuning maintenance Jobs.
+// Backups keep running.
+const FreezeUntilAnnotation = "databases.database-operator.io/freeze-until"
+
+// PausedAnnotation set to "true" on a Database stops the operator from changing
+// its child resources until it is removed. The status, health checks included,
+// is still reported.
+const PausedAnnotation = "databases.database-operator.io/paused"
+
+// MaintenanceOverrideUntilAnnotation set to an RFC 3339 timestamp (e.g.
+// "2025-03-01T18:00:00Z") lets disruptive operations run outside the
+// maintenance windows until then, for emergencies
+const MaintenanceOverrideUntilAnnotation = "databases.database-operator.io/maintenance-override-until"
+
+// RotateCredentialsAnnotation requests a rotation of the administrative
+// password outside the schedule of the rotation policy, e.g. after a suspected
+// leak. Each new value (e.g. "now" or a timestamp) requests one rotation, which
+// is not deferred by a release freeze.
+const RotateCredentialsAnnotation = "databases.database-operator.io/rotate-credentials"
+
+// CredentialsConsumerLabel set to the name of a Database on Deployments of its
+// namespace restarts them after each rotation of its administrative password,
+// so their pods read the new password from the Secret
+const CredentialsConsumerLabel = "databases.database-operator.io/credentials-consumer"
+
+// AppliedSpecHashAnnotation records on the workload and the Service of a
+// Database the hash of the spec the operator last applied. Changes made by
+// anyone else are reported in the ExternallyModified condition until the spec
+// is reverted or the annotation is removed, which accepts the current spec.
+const AppliedSpecHashAnnotation = "databases.database-operator.io/applied-spec-hash"
+
+// AdoptAnnotation set to "true" on a Database lets the operator take ownership
+// of a StatefulSet, Deployment or Service that already exists under the name of
+// one of its child resources without an owner, e.g. after a migration from
+// another operator. Without it such resources are reported and left alone.
+const AdoptAnnotation = "databases.database-operator.io/adopt"
+
+// ApproveMajorUpgradeAnnotation set to the target version (e.g. "8.0") approves
+// the upgrade of a Database to a new major. A version change across majors is
+// rejected until the annotation names the new spec.version, so an approval does
+// not carry over to the next major.
+const ApproveMajorUpgradeAnnotation = "databases.database-operator.io/approve-major-upgrade"
+
+// TargetClusterSpec references the cluster the child resources of a Database
+// are created in
+type TargetClusterSpec struct {
+	// KubeconfigSecret references the kubeconfig of the cluster, in a Secret of
+	// the namespace of the Database. Cluster API stores the kubeconfig of a
+	// cluster in Secret <cluster>-kubeconfig under key "value".
+	KubeconfigSecret SecretReference `json:"kubeconfigSecret"`
+}
+
+// FleetOwnerLabel is set on the child resources of a Database in its target
+// cluster, with the UID of the Database as value, as owner references cannot
+// point to another cluster
+const FleetOwnerLabel = "databases.database-operator.io/fleet-owner"
+
+// TopologySpec configures time-based replica counts and the placement of the
+// replicas
+type TopologySpec struct {
+	// TimeZone is the IANA time zone the schedules are evaluated in, e.g.
+	// Europe/Paris (default: UTC)
 	// +optional
-	Database string `json:"database,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
 
-	// Username for the database
+	// Schedules run a replica count during daily time windows. When windows
+	// overlap the first schedule wins; outside all of them replicas applies.
 	// +optional
-	Username string `json:"username,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	Schedules []ReplicaSchedule `json:"schedules,omitempty"`
 
-	// Password secret reference
+	// AntiAffinity keeps the replicas off each other's nodes. Preferred lets the
+	// scheduler place replicas together when no other node fits, Required leaves
+	// them pending instead.
 	// +optional
-	PasswordSecret *SecretReference `json:"passwordSecret,omitempty"`
+	AntiAffinity AntiAffinityMode `json:"antiAffinity,omitempty"`
 
-	// Additional PostgreSQL configuration parameters
+	// ZoneSpread spreads the replicas evenly across zones
 	// +optional
-	Parameters map[string]string `json:"parameters,omitempty"`
+	ZoneSpread *ZoneSpreadSpec `json:"zoneSpread,omitempty"`
 }
 
-// MongoDBConfig defines MongoDB-specific configuration
-type MongoDBConfig struct {
-	// Database name to create
+// MaintenanceSpec configures the windows disruptive operations run in
+type MaintenanceSpec struct {
+	// TimeZone is the IANA time zone the windows are evaluated in, e.g.
+	// Europe/Paris (default: UTC)
 	// +optional
-	Database string `json:"database,omitempty"`
+	TimeZone string `json:"timeZone,omitempty"`
 
-	// Username for the database
+	// Windows are the weekly time windows disruptive operations run in. Outside
+	// all of them they are deferred to the next one.
+	// +kubebuilder:validation:MinItems=1
+	Windows []MaintenanceWindow `json:"windows"`
+
+	// AutoUpgrade moves spec.version to the latest patch release of its minor
+	// version found in the registry of the image, within the windows
 	// +optional
-	Username string `json:"username,omitempty"`
+	AutoUpgrade bool `json:"autoUpgrade,omitempty"`
+}
 
-	// Password secret reference
+// MaintenanceWindow is a daily time window on some days of the week
+type MaintenanceWindow struct {
+	// Days the window starts on (default: every day)
 	// +optional
-	PasswordSecret *SecretReference `json:"passwordSecret,omitempty"`
+	Days []Weekday `json:"days,omitempty"`
 
-	// ReplicaSet name for MongoDB replica set
+	// Start of the window, HH:MM
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End of the window, HH:MM. A window ending at or before its start ends on
+	// the next day.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+// LifecycleSpec configures the lifecycle of the engine: the hooks of its
+// container and hibernation
+type LifecycleSpec struct {
+	// Hibernate scales the workload to zero replicas while keeping the data
+	// volumes and the Secrets, and suspends the CronJobs of the Database. The
+	// phase is Hibernated once every replica stopped. Unsetting it starts the
+	// replicas again on the same volumes.
 	// +optional
-	ReplicaSetName string `json:"replicaSetName,omitempty"`
+	Hibernate bool `json:"hibernate,omitempty"`
 
-	// Additional MongoDB configuration parameters
+	// PreStopHook runs in the engine container before it is stopped, e.g. a
+	// CHECKPOINT of PostgreSQL or a BGSAVE of Redis so the engine restarts
+	// quickly. The container is stopped once it returns, or once the
+	// termination grace period ends.
 	// +optional
-	Parameters map[string]string `json:"parameters,omitempty"`
+	PreStopHook *corev1.LifecycleHandler `json:"preStopHook,omitempty"`
+
+	// PostStartHook runs in the engine container right after it is created.
+	// The container is restarted when it fails.
+	// +optional
+	PostStartHook *corev1.LifecycleHandler `json:"postStartHook,omitempty"`
+
+	// TerminationGracePeriodSeconds bounds how long a pod takes to stop,
+	// including the pre-stop hook (default: 30)
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
 }
 
-// RedisConfig defines Redis-specific configuration
-type RedisConfig struct {
-	// Password secret reference
+// RolloutSpec configures the rollout of pod template changes
+type RolloutSpec struct {
+	// Canary updates the replica with the highest ordinal first. The others
+	// follow once it stayed ready, and the engine healthy, for canaryPeriod;
+	// when it degrades the rollout is aborted with the others untouched.
 	// +optional
-	PasswordSecret *SecretReference `json:"passwordSecret,omitempty"`
+	Canary bool `json:"canary,omitempty"`
 
-	// Mode specifies Redis mode (standalone, sentinel, cluster)
-	// +kubebuilder:validation:Enum=standalone;sentinel;cluster
-	// +kubebuilder:default=standalone
+	// CanaryPeriod is how long the canary has to stay ready and healthy before
+	// the other replicas are updated (default: 5m)
 	// +optional
-	Mode string `json:"mode,omitempty"`
+	CanaryPeriod *metav1.Duration `json:"canaryPeriod,omitempty"`
+}
 
-	// Additional Redis configuration parameters
+// AntiAffinityMode is how strictly replicas are kept on different nodes
+// +kubebuilder:validation:Enum=Preferred;Required
+type AntiAffinityMode string
+
+const (
+	AntiAffinityPreferred AntiAffinityMode = "Preferred"
+	AntiAffinityRequired  AntiAffinityMode = "Required"
+)
+
+// ZoneSpreadSpec configures the topology spread constraint of the replicas
+type ZoneSpreadSpec struct {
+	// MaxSkew is the largest difference of replica counts between two zones
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
 	// +optional
-	Parameters map[string]string `json:"parameters,omitempty"`
+	MaxSkew int32 `json:"maxSkew,omitempty"`
+
+	// WhenUnsatisfiable is DoNotSchedule to leave replicas pending rather than
+	// exceed the skew, or ScheduleAnyway (default) to only prefer balanced zones
+	// +kubebuilder:validation:Enum=DoNotSchedule;ScheduleAnyway
+	// +kubebuilder:default=ScheduleAnyway
+	// +optional
+	WhenUnsatisfiable corev1.UnsatisfiableConstraintAction `json:"whenUnsatisfiable,omitempty"`
+
+	// TopologyKey is the node label the zones are read from (default:
+	// topology.kubernetes.io/zone)
+	// +optional
+	TopologyKey string `json:"topologyKey,omitempty"`
 }
 
-// ElasticsearchConfig defines Elasticsearch-specific configuration
-type ElasticsearchConfig struct {
-	// ClusterName specifies the Elasticsearch cluster name
+// ReplicaSchedule runs a replica count during a daily time window, or from the
+// times a cron expression fires
+// +kubebuilder:validation:XValidation:rule="has(self.cron) != (has(self.start) && has(self.end))",message="set either cron or start and end"
+// +kubebuilder:validation:XValidation:rule="!has(self.cron) || !has(self.days)",message="days apply to windows, set the days in the cron expression"
+type ReplicaSchedule struct {
+	// Name identifies the schedule in status
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Days the window starts on (default: every day)
 	// +optional
-	ClusterName string `json:"clusterName,omitempty"`
+	Days []Weekday `json:"days,omitempty"`
 
-	// NodeRoles specifies the roles for this node (master, data, ingest)
+	// Start of the window, HH:MM
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
 	// +optional
-	NodeRoles []string `json:"nodeRoles,omitempty"`
+	Start string `json:"start,omitempty"`
 
-	// Additional Elasticsearch configuration parameters
+	// End of the window, HH:MM. A window ending at or before its start ends on
+	// the next day.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
 	// +optional
-	Parameters map[string]string `json:"parameters,omitempty"`
+	End string `json:"end,omitempty"`
+
+	// Cron is a five-field cron expression or macro, e.g. "0 20 * * 1-5",
+	// evaluated in the time zone of the schedules. Replicas applies from each
+	// time it fires until another cron schedule fires, outside open windows.
+	// +optional
+	Cron string `json:"cron,omitempty"`
+
+	// Replicas is the replica count during the window, or since the cron
+	// expression fired
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	Replicas int32 `json:"replicas"`
 }
 
-// SQLiteConfig defines SQLite-specific configuration
-type SQLiteConfig struct {
-	// DatabaseFile specifies the SQLite database file path
+// Weekday is a day of the week
+// +kubebuilder:validation:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+type Weekday string
+
+// ImageResolution defines how the image of the version is referenced
+// +kubebuilder:validation:Enum=Tag;Digest
+type ImageResolution string
+
+const (
+	ImageResolutionTag    ImageResolution = "Tag"
+	ImageResolutionDigest ImageResolution = "Digest"
+)
+
+// DatabaseProfile is a preset of the resources and memory settings of a Database
+// +kubebuilder:validation:Enum=dev;small;prod;high-memory
+type DatabaseProfile string
+
+const (
+	// DatabaseProfileDev runs development and preview environments on a
+	// fraction of a core and 512Mi at most
+	DatabaseProfileDev DatabaseProfile = "dev"
+	// DatabaseProfileSmall runs small production workloads on half a core and 2Gi
+	DatabaseProfileSmall DatabaseProfile = "small"
+	// DatabaseProfileProd runs production workloads on two cores and 8Gi
+	DatabaseProfileProd DatabaseProfile = "prod"
+	// DatabaseProfileHighMemory runs caches and large working sets on two
+	// cores and 32Gi
+	DatabaseProfileHighMemory DatabaseProfile = "high-memory"
+)
+
+// PodSecurityLevel is a level of the Kubernetes Pod Security Standards
+// +kubebuilder:validation:Enum=Restricted;Baseline
+type PodSecurityLevel string
+
+const (
+	PodSecurityRestricted PodSecurityLevel = "Restricted"
+	PodSecurityBaseline   PodSecurityLevel = "Baseline"
+)
+
+// PodSecurityLabel is set on the pods of a Database and of its Jobs to the
+// lowercase Pod Security Standards level they comply with, as used by the
+// pod-security.kubernetes.io/enforce label of namespaces
+const PodSecurityLabel = "databases.database-operator.io/pod-security"
+
+// ImageSpec selects the image of the engine
+type ImageSpec struct {
+	// Flavor is the image distribution: Official (default) runs the Docker
+	// official images; Bitnami (PostgreSQL, MongoDB, Redis) and Percona
+	// (PostgreSQL, MongoDB) run their distributions, with their data paths,
+	// users, environment variables and entrypoints. The flavor applies to
+	// workloads created after it is set.
+	// +kubebuilder:default=Official
 	// +optional
-	DatabaseFile string `json:"databaseFile,omitempty"`
+	Flavor ImageFlavor `json:"flavor,omitempty"`
+}
 
-	// Additional SQLite configuration parameters
+// ImageFlavor is an image distribution of the database engines
+// +kubebuilder:validation:Enum=Official;Bitnami;Percona
+type ImageFlavor string
+
+const (
+	ImageFlavorOfficial ImageFlavor = "Official"
+	ImageFlavorBitnami  ImageFlavor = "Bitnami"
+	ImageFlavorPercona  ImageFlavor = "Percona"
+)
+
+// ProvisioningSpec configures the initial provisioning transaction, which creates
+// the configuration, the Service and the workload in this order
+type ProvisioningSpec struct {
+	// MaxAttempts is the number of failed attempts after which provisioning is
+	// considered failed. Attempts are retried with exponential backoff.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=5
 	// +optional
-	Parameters map[string]string `json:"parameters,omitempty"`
+	MaxAttempts *int32 `json:"maxAttempts,omitempty"`
+
+	// RollbackOnFailure deletes the resources created by a failed provisioning.
+	// Provisioning is attempted again after the next spec change.
+	// +optional
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
 }
 
-// SecretReference defines a reference to a Kubernetes Secret
-type SecretReference struct {
-	// Name of the secret
-	// +kubebuilder:validation:Required
-	Name string `json:"name"`
+// BootstrapSpec defines logical databases and users of the instance
+type BootstrapSpec struct {
+	// Databases to create
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Databases []BootstrapDatabase `json:"databases,omitempty"`
 
-	// Key in the secret to use
-	// +kubebuilder:validation:Required
-	Key string `json:"key"`
+	// Users to create, created before the databases so they can own them
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Users []BootstrapUser `json:"users,omitempty"`
 }
 
-// EnvVar defines an environment variable
-type EnvVar struct {
-	// Name of the environment variable
-	// +kubebuilder:validation:Required
+// BootstrapDatabase defines a logical database
+type BootstrapDatabase struct {
+	// Name of the database
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
 	Name string `json:"name"`
 
-	// Value of the environment variable
+	// Owner is the user owning the database (PostgreSQL) or granted dbOwner on it (MongoDB)
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
 	// +optional
-	Value string `json:"value,omitempty"`
+	Owner string `json:"owner,omitempty"`
 
-	// ValueFrom specifies a source for the environment variable's value
+	// Extensions to create in the database (PostgreSQL only)
 	// +optional
-	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"`
+	Extensions []BootstrapIdentifier `json:"extensions,omitempty"`
 }
 
-// EnvVarSource defines a source for an environment variable
-type EnvVarSource struct {
-	// SecretKeyRef selects a key from a secret
+// BootstrapIdentifier is the name of a database object, quoted by the operator
+// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_-]*$`
+// +kubebuilder:validation:MaxLength=63
+type BootstrapIdentifier string
+
+// BootstrapUser defines a login user
+type BootstrapUser struct {
+	// Name of the user
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// PasswordSecret holds the password of the user
+	PasswordSecret SecretReference `json:"passwordSecret"`
+
+	// Grants lists the privileges of the user per database
 	// +optional
-	SecretKeyRef *SecretReference `json:"secretKeyRef,omitempty"`
+	Grants []BootstrapGrant `json:"grants,omitempty"`
 }
 
-// DatabasePhase defines the phase of the database
-type DatabasePhase string
+// BootstrapGrant grants privileges on a database
+type BootstrapGrant struct {
+	// Database the privileges apply to
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	Database string `json:"database"`
+
+	// Privileges are database privileges for PostgreSQL (ALL, CONNECT, CREATE,
+	// TEMPORARY) and built-in roles for MongoDB (read, readWrite, dbAdmin, dbOwner)
+	// +kubebuilder:validation:MinItems=1
+	Privileges []BootstrapPrivilege `json:"privileges"`
+}
+
+// BootstrapPrivilege is a database privilege of PostgreSQL or a built-in role of MongoDB
+// +kubebuilder:validation:Enum=ALL;CONNECT;CREATE;TEMPORARY;read;readWrite;dbAdmin;dbOwner
+type BootstrapPrivilege string
 
 const (
-	DatabasePhasePending   DatabasePhase = "Pending"
-	DatabasePhaseCreating  DatabasePhase = "Creating"
-	DatabasePhaseReady     DatabasePhase = "Ready"
-	DatabasePhaseFailed    DatabasePhase = "Failed"
-	DatabasePhaseDeleting  DatabasePhase = "Deleting"
-	DatabasePhaseUpgrading DatabasePhase = "Upgrading"
+	BootstrapPrivilegeAll       BootstrapPrivilege = "ALL"
+	BootstrapPrivilegeConnect   BootstrapPrivilege = "CONNECT"
+	BootstrapPrivilegeCreate    BootstrapPrivilege = "CREATE"
+	BootstrapPrivilegeTemporary BootstrapPrivilege = "TEMPORARY"
+	BootstrapPrivilegeRead      BootstrapPrivilege = "read"
+	BootstrapPrivilegeReadWrite BootstrapPrivilege = "readWrite"
+	BootstrapPrivilegeDBAdmin   BootstrapPrivilege = "dbAdmin"
+	BootstrapPrivilegeDBOwner   BootstrapPrivilege = "dbOwner"
 )
 
-// DatabaseStatus defines the observed state of Database.
-type DatabaseStatus struct {
-	// Phase represents the current phase of the database
+// NetworkingSpec defines network access
+// +kubebuilder:validation:XValidation:rule="!has(self.externalDNS) || (has(self.serviceType) && self.serviceType != 'ClusterIP')",message="externalDNS requires serviceType NodePort or LoadBalancer"
+type NetworkingSpec struct {
+	// ServiceType is the type of the database Service. NodePort and
+	// LoadBalancer expose the database outside the cluster.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
 	// +optional
-	Phase DatabasePhase `json:"phase,omitempty"`
+	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
 
-	// Conditions represent the latest available observations of the database's state
+	// ExternalDNS publishes a DNS record of the exposed Service with external-dns
 	// +optional
-	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	ExternalDNS *ExternalDNSSpec `json:"externalDNS,omitempty"`
 
-	// ReadyReplicas is the number of ready database replicas
+	// Ingress routes HTTP clients to the database with an Ingress, or with a
+	// Gateway API HTTPRoute, for the engines speaking HTTP (Elasticsearch and
+	// SQLite)
 	// +optional
-	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	Ingress *IngressSpec `json:"ingress,omitempty"`
 
-	// ServiceName is the name of the service created for the database
+	// NetworkPolicy configures the NetworkPolicies generated by the operator
 	// +optional
-	ServiceName string `json:"serviceName,omitempty"`
+	NetworkPolicy *NetworkPolicySpec `json:"networkPolicy,omitempty"`
 
-	// ConnectionString provides connection information (without credentials)
+	// Proxy overrides the operator proxy configuration of the Jobs of this
+	// Database. An empty proxy disables the operator proxy.
 	// +optional
-	ConnectionString string `json:"connectionString,omitempty"`
+	Proxy *ProxySpec `json:"proxy,omitempty"`
+}
 
-	// ObservedGeneration is the most recent generation observed for this database
+// ExternalDNSSpec configures the record external-dns publishes for the Service
+type ExternalDNSSpec struct {
+	// Hostname is the DNS name of the database
+	// +kubebuilder:validation:MinLength=1
+	Hostname string `json:"hostname"`
+
+	// TTL of the record in seconds (default: the one of external-dns)
+	// +kubebuilder:validation:Minimum=1
 	// +optional
-	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	TTL *int32 `json:"ttl,omitempty"`
+}
 
-	// Message provides additional information about the current state
+// IngressSpec routes a host name to the database Service
+// +kubebuilder:validation:XValidation:rule="!has(self.gateway) || (!has(self.className) && !has(self.tlsSecretName))",message="className and tlsSecretName configure an Ingress, a gateway terminates TLS on its listeners"
+type IngressSpec struct {
+	// Host is the host name clients connect to
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host"`
+
+	// ClassName is the IngressClass of the Ingress (default: the default class
+	// of the cluster)
 	// +optional
-	Message string `json:"message,omitempty"`
+	ClassName string `json:"className,omitempty"`
+
+	// TLSSecretName is a kubernetes.io/tls Secret holding the certificate of the
+	// host, with which the ingress controller terminates TLS
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+
+	// Gateway attaches an HTTPRoute to a Gateway instead of creating an Ingress
+	// +optional
+	Gateway *GatewayReference `json:"gateway,omitempty"`
+}
+
+// GatewayReference references a Gateway API Gateway
+type GatewayReference struct {
+	// Name of the Gateway
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the Gateway (default: the namespace of the Database)
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// SectionName is the listener of the Gateway the route attaches to
+	// (default: every listener accepting it)
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// ResourceMetadataSpec holds the metadata added to each kind of generated
+// resource. Pods and volume claims get it when the workload is created.
+type ResourceMetadataSpec struct {
+	// Service is added to the database Service
+	// +optional
+	Service *ObjectMetadata `json:"service,omitempty"`
+
+	// Workload is added to the StatefulSet or Deployment
+	// +optional
+	Workload *ObjectMetadata `json:"workload,omitempty"`
+
+	// Pods is added to the pod template of the workload
+	// +optional
+	Pods *ObjectMetadata `json:"pods,omitempty"`
+
+	// PersistentVolumeClaims is added to the volume claim templates of the
+	// StatefulSet
+	// +optional
+	PersistentVolumeClaims *ObjectMetadata `json:"persistentVolumeClaims,omitempty"`
+}
+
+// ObjectMetadata holds labels and annotations
+type ObjectMetadata struct {
+	// Labels to add
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations to add
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// TLSSpec configures the server certificate of the database endpoint
+// +kubebuilder:validation:XValidation:rule="has(self.certManager) != has(self.secretName)",message="set one of certManager and secretName"
+type TLSSpec struct {
+	// CertManager issues the server certificate with cert-manager
+	// +optional
+	CertManager *CertManagerTLSSpec `json:"certManager,omitempty"`
+
+	// SecretName is a kubernetes.io/tls Secret holding the server certificate
+	// (tls.crt) and its key (tls.key), in the namespace of the Database
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// CertManagerTLSSpec requests the server certificate from a cert-manager issuer
+type CertManagerTLSSpec struct {
+	// IssuerRef is the issuer signing the certificate
+	IssuerRef CertManagerIssuerReference `json:"issuerRef"`
+}
+
+// CertManagerIssuerReference references a cert-manager Issuer or ClusterIssuer
+type CertManagerIssuerReference struct {
+	// Name of the issuer
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Kind of the issuer, Issuer (in the namespace of the Database) or
+	// ClusterIssuer
+	// +kubebuilder:default=Issuer
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// ProxySpec defines the HTTP proxy used by generated Jobs to reach external services
+type ProxySpec struct {
+	// HTTPProxy is the proxy for HTTP requests
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is the proxy for HTTPS requests
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy is a comma separated list of hosts, domains and CIDRs reached without
+	// proxy. The database Service is always added.
+	// +optional
+	NoProxy string `json:"noProxy,omitempty"`
+}
+
+// NetworkPolicySpec defines the generated NetworkPolicies
+type NetworkPolicySpec struct {
+	// Enabled generates NetworkPolicies. Jobs created by the operator (backups,
+	// analysis, ...) may then only reach the database, DNS and the S3 endpoint of
+	// the backups, and accept no traffic. Database pods accept clients on the
+	// database ports only, and may only reach their peers, DNS and S3.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// AllowedNamespaces are the namespaces whose pods may connect to the
+	// database. When neither allowedNamespaces nor podSelectors is set, the pods
+	// of the namespace of the Database may connect.
+	// +optional
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// PodSelectors select the pods of the namespace of the Database that may
+	// connect to the database
+	// +optional
+	PodSelectors []metav1.LabelSelector `json:"podSelectors,omitempty"`
+}
+
+// ScaleDownProtectionSpec defines when a replica scale-down is deferred. Connections
+// are counted for PostgreSQL and Redis; other engines scale down immediately.
+type ScaleDownProtectionSpec struct {
+	// Enabled turns the protection on
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// MaxConnections is the number of client connections a replica may still serve
+	// when it is removed
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConnections int64 `json:"maxConnections,omitempty"`
+
+	// DrainTimeout bounds how long a scale-down waits for connections to drain.
+	// When unset the scale-down waits until the connections are gone.
+	// +optional
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+}
+
+// BackupSpec defines scheduled backups
+type BackupSpec struct {
+	// Enabled turns scheduled backups on
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Method is the backup method. Dump writes logical dumps on schedule; WAL
+	// continuously archives the PostgreSQL write-ahead log with wal-g and takes
+	// periodic base backups; Snapshot takes CSI VolumeSnapshots of the data
+	// volumes, which requires spec.storage.snapshots; Incremental takes full,
+	// differential and incremental PostgreSQL backups with pgBackRest.
+	// +kubebuilder:default=Dump
+	// +optional
+	Method BackupMethod `json:"method,omitempty"`
+
+	// Schedule is the cron schedule of the backups
+	// +kubebuilder:default="0 2 * * *"
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Storage configures the volume backups are written to (default: 10Gi).
+	// The volume is kept when the Database is deleted.
+	// +optional
+	Storage *StorageSpec `json:"storage,omitempty"`
+
+	// S3 is the object storage destination, required by the WAL method
+	// +optional
+	S3 *S3Destination `json:"s3,omitempty"`
+
+	// WAL configures continuous WAL archiving
+	// +optional
+	WAL *WALArchivingSpec `json:"wal,omitempty"`
+
+	// PgBackRest configures the Incremental method
+	// +optional
+	PgBackRest *PgBackRestSpec `json:"pgBackRest,omitempty"`
+
+	// Retention prunes old backups. Without it backups are kept forever.
+	// +optional
+	Retention *BackupRetention `json:"retention,omitempty"`
+
+	// Copies upload every dump of the schedule above to object storage once it is
+	// on the backup volume, each copy with its own retention. Only the Dump method
+	// writes copies.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=3
+	// +optional
+	Copies []BackupCopy `json:"copies,omitempty"`
+
+	// Verify restores every completed DatabaseBackup into an ephemeral instance
+	// and runs a sanity query, recording the result in its status
+	// +optional
+	Verify bool `json:"verify,omitempty"`
+
+	// Schedules are additional backup schedules next to the one above, each with
+	// its own method, retention and volume, e.g. a nightly dump and a weekly
+	// snapshot next to continuous WAL archiving
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=10
+	// +optional
+	Schedules []BackupSchedule `json:"schedules,omitempty"`
+
+	// Encryption encrypts dumps and WAL archives with a KMS key of the Database.
+	// Snapshots are left to the encryption of the storage class.
+	// +optional
+	Encryption *BackupEncryption `json:"encryption,omitempty"`
+}
+
+// BackupEncryption configures envelope encryption of backups: every dump is
+// encrypted with a fresh data key, stored wrapped by the KMS key in a manifest
+// next to the backup. Backups of Databases with different keys stay unreadable
+// to each other even in a shared bucket.
+type BackupEncryption struct {
+	// KMSKeyID is an AWS KMS key ARN (arn:aws:kms:<region>:<account>:key/<id>)
+	// or a Google Cloud KMS key name
+	// (projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>).
+	// WAL archives can only be encrypted with AWS KMS keys.
+	// +kubebuilder:validation:Pattern=`^(arn:aws[a-z-]*:kms:[a-z0-9-]+:[0-9]{12}:(key|alias)/.+|projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+)$`
+	KMSKeyID string `json:"kmsKeyId"`
+
+	// CredentialsSecret is the name of a Secret holding the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY keys, or a Google Cloud service account key under
+	// credentials.json. Without it the pod identity of the backup Jobs is used.
+	// WAL archives are encrypted with the credentials of backup.s3.
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// Image provides the KMS command line client and openssl (default:
+	// amazon/aws-cli for AWS keys, google/cloud-sdk:slim for Google Cloud keys)
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// BackupSchedule defines an additional backup schedule. Its CronJob is named
+// <database>-backup-<name>.
+// +kubebuilder:validation:XValidation:rule="!has(self.method) || self.method != 'WAL'",message="WAL archiving is continuous, set it as backup.method"
+// +kubebuilder:validation:XValidation:rule="!has(self.method) || self.method != 'Incremental'",message="pgBackRest schedules its own backups, set Incremental as backup.method"
+type BackupSchedule struct {
+	// Name identifies the schedule in the names of its CronJob and backups
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=20
+	Name string `json:"name"`
+
+	// Method is the backup method of the schedule, Dump or Snapshot
+	// +kubebuilder:default=Dump
+	// +optional
+	Method BackupMethod `json:"method,omitempty"`
+
+	// Schedule is the cron schedule of the backups
+	Schedule string `json:"schedule"`
+
+	// Storage gives the dumps of the schedule a volume of their own,
+	// <database>-backups-<name>, kept when the Database is deleted (default: the
+	// volume of backup.storage)
+	// +optional
+	Storage *StorageSpec `json:"storage,omitempty"`
+
+	// Retention prunes the backups of the schedule, independently of the other
+	// schedules. Without it they are kept forever.
+	// +optional
+	Retention *BackupRetention `json:"retention,omitempty"`
+
+	// Copies upload the dumps of the schedule to object storage, each copy with
+	// its own retention
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=3
+	// +optional
+	Copies []BackupCopy `json:"copies,omitempty"`
+}
+
+// BackupCopy defines a secondary destination of the dumps of a schedule. Dumps
+// are uploaded to s3://<bucket>/<path>/<namespace>/<database>/ under the name
+// they have on the backup volume, with their manifest when encrypted, and can be
+// restored with a DatabaseRestore S3 source. A backup run fails when one of its
+// copies could not be uploaded.
+type BackupCopy struct {
+	// Name identifies the copy in the name of its container
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=20
+	Name string `json:"name"`
+
+	// S3 is the object storage the dumps are copied to
+	S3 S3Destination `json:"s3"`
+
+	// Retention prunes the copies independently of the backups on the volume.
+	// Without it copies are kept forever.
+	// +optional
+	Retention *BackupRetention `json:"retention,omitempty"`
+
+	// Image provides the aws command line client (default: amazon/aws-cli)
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// BackupRetention defines which backups are kept. Scheduled backups, WAL base
+// backups and DatabaseBackups are pruned separately.
+type BackupRetention struct {
+	// MaxCount is the number of most recent backups kept
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxCount *int32 `json:"maxCount,omitempty"`
+
+	// MaxAge deletes backups older than this age
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// BackupMethod defines how backups are taken
+// +kubebuilder:validation:Enum=Dump;WAL;Snapshot;Incremental
+type BackupMethod string
+
+const (
+	BackupMethodDump        BackupMethod = "Dump"
+	BackupMethodWAL         BackupMethod = "WAL"
+	BackupMethodSnapshot    BackupMethod = "Snapshot"
+	BackupMethodIncremental BackupMethod = "Incremental"
+)
+
+// S3Destination defines an S3 compatible object storage location
+type S3Destination struct {
+	// Bucket is the name of the bucket
+	Bucket string `json:"bucket"`
+
+	// Path is the key prefix within the bucket
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Endpoint overrides the S3 endpoint, for S3 compatible storage
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region of the bucket
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// ForcePathStyle uses path-style bucket addressing
+	// +optional
+	ForcePathStyle bool `json:"forcePathStyle,omitempty"`
+
+	// CredentialsSecret is the name of a Secret holding the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY keys
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+// WALArchivingSpec defines continuous WAL archiving with wal-g
+type WALArchivingSpec struct {
+	// Image provides the wal-g binary on its PATH. The binary is copied into the
+	// database pod, so the image only needs a shell.
+	Image string `json:"image"`
+
+	// BaseBackupInterval is the time between base backups (default: 24h)
+	// +optional
+	BaseBackupInterval *metav1.Duration `json:"baseBackupInterval,omitempty"`
+}
+
+// PgBackRestSpec defines incremental PostgreSQL backups with pgBackRest to the
+// backup.s3 bucket. A backup runs every incrementalInterval: full when the last
+// full backup is older than fullInterval, differential when the last full or
+// differential one is older than differentialInterval, incremental otherwise.
+type PgBackRestSpec struct {
+	// Image is a PostgreSQL image of the Database version with pgbackrest on its
+	// PATH. It replaces the engine image of the database pods, which push their
+	// WAL with pgbackrest archive-push.
+	Image string `json:"image"`
+
+	// FullInterval is the time between full backups (default: 168h)
+	// +optional
+	FullInterval *metav1.Duration `json:"fullInterval,omitempty"`
+
+	// DifferentialInterval is the time between differential backups, holding
+	// the changes since the last full backup (default: 24h)
+	// +optional
+	DifferentialInterval *metav1.Duration `json:"differentialInterval,omitempty"`
+
+	// IncrementalInterval is the time between incremental backups, holding the
+	// changes since the last backup of any type (default: 1h)
+	// +optional
+	IncrementalInterval *metav1.Duration `json:"incrementalInterval,omitempty"`
+
+	// DifferentialRetention is the number of differential backups kept. Full
+	// backups, and the backups depending on them, follow backup.retention.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	DifferentialRetention *int32 `json:"differentialRetention,omitempty"`
+
+	// Repo configures the pgBackRest repository
+	// +optional
+	Repo *PgBackRestRepo `json:"repo,omitempty"`
+}
+
+// PgBackRestRepo defines the pgBackRest repository stored in backup.s3. Each pod
+// uses the repository <path>/<namespace>/<database>/<pod>.
+type PgBackRestRepo struct {
+	// CipherSecret is the name of a Secret whose passphrase key encrypts the
+	// repository with aes-256-cbc. It cannot be changed once backups exist.
+	// +optional
+	CipherSecret string `json:"cipherSecret,omitempty"`
+
+	// CompressType is the compression of backups and archived WAL (default: gz)
+	// +kubebuilder:validation:Enum=none;gz;lz4;zst;bz2
+	// +optional
+	CompressType string `json:"compressType,omitempty"`
+
+	// ProcessMax is the number of processes compressing and transferring files
+	// (default: 1)
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProcessMax *int32 `json:"processMax,omitempty"`
+}
+
+// DebugAnnotation temporarily switches the engine log level to debug for the given
+// duration (e.g. "30m"). Change the value to start a new debug window.
+const DebugAnnotation = "databases.database-operator.io/debug"
+
+// EngineLogLevel defines the log level of the database engine
+// +kubebuilder:validation:Enum=debug;info;warning;error
+type EngineLogLevel string
+
+const (
+	EngineLogLevelDebug   EngineLogLevel = "debug"
+	EngineLogLevelInfo    EngineLogLevel = "info"
+	EngineLogLevelWarning EngineLogLevel = "warning"
+	EngineLogLevelError   EngineLogLevel = "error"
+)
+
+// ObservabilitySpec defines observability settings
+type ObservabilitySpec struct {
+	// Logging configures engine logging
+	// +optional
+	Logging *LoggingSpec `json:"logging,omitempty"`
+
+	// Metrics configures the collection of engine metrics
+	// +optional
+	Metrics *MetricsSpec `json:"metrics,omitempty"`
+
+	// SlowQuery captures the queries running longer than a threshold
+	// (PostgreSQL, MongoDB and Redis)
+	// +optional
+	SlowQuery *SlowQuerySpec `json:"slowQuery,omitempty"`
+}
+
+// SlowQuerySpec configures the capture of slow queries: PostgreSQL logs them
+// (log_min_duration_statement), MongoDB records them with its profiler
+// (system.profile) and Redis in its slow log. With metrics enabled, the
+// exporter reports them, from pg_stat_statements on PostgreSQL.
+type SlowQuerySpec struct {
+	// Enabled turns the capture on
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Threshold is the duration above which a query is slow (default 1s)
+	// +optional
+	Threshold *metav1.Duration `json:"threshold,omitempty"`
+}
+
+// MetricsSpec defines engine metrics collection
+type MetricsSpec struct {
+	// Enabled turns engine metrics on. Metrics exporters connect as a dedicated
+	// monitoring user with read-only access to statistics (pg_monitor on
+	// PostgreSQL, clusterMonitor on MongoDB, a Redis ACL user limited to INFO and
+	// introspection commands), never as the administrative user. A Prometheus
+	// exporter sidecar serves the metrics on the "metrics" port of the database
+	// Service (all engines but SQLite).
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// ExporterImage overrides the image of the exporter sidecar
+	// +optional
+	ExporterImage string `json:"exporterImage,omitempty"`
+}
+
+// LoggingSpec defines engine logging settings
+type LoggingSpec struct {
+	// EngineLevel is the log level of the database engine. It is mapped to the engine
+	// setting (log_min_messages, logLevel/quiet, loglevel, logger._root) and applied
+	// at runtime without restarting pods.
+	// +optional
+	EngineLevel EngineLogLevel `json:"engineLevel,omitempty"`
+
+	// Format is the format the engine writes its logs in. PostgreSQL writes text
+	// or, from version 15 with shipping, json (jsonlog); MongoDB and Elasticsearch
+	// only write json and Redis only text.
+	// +optional
+	Format LogFormat `json:"format,omitempty"`
+
+	// Shipping ships the engine logs to a sink with a fluent-bit sidecar. The
+	// engine then writes its logs to files read by the sidecar rather than to
+	// the container output. New workloads only.
+	// +optional
+	Shipping *LogShippingSpec `json:"shipping,omitempty"`
+}
+
+// LogFormat is the format of engine logs
+// +kubebuilder:validation:Enum=text;json
+type LogFormat string
+
+const (
+	LogFormatText LogFormat = "text"
+	LogFormatJSON LogFormat = "json"
+)
+
+// LogShippingSpec configures the fluent-bit sidecar shipping engine logs
+type LogShippingSpec struct {
+	// Output is the fluent-bit output plugin the logs are sent to
+	// +kubebuilder:validation:Required
+	Output LogOutputSpec `json:"output"`
+
+	// CredentialsSecret names a Secret whose keys are exposed to the sidecar as
+	// environment variables, referenced in output properties as ${KEY}
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// Image overrides the fluent-bit image of the sidecar
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// LogOutputSpec is a fluent-bit output
+type LogOutputSpec struct {
+	// Name is the output plugin, such as es, loki, forward, http or stdout
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Properties are the settings of the plugin, such as host and port
+	// +optional
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// StorageSpec defines the storage configuration
+type StorageSpec struct {
+	// Size specifies the size of the persistent volume
+	// +kubebuilder:validation:Required
+	Size string `json:"size"`
+
+	// StorageClass specifies the storage class to use
+	// +optional
+	StorageClass *string `json:"storageClassName,omitempty"`
+
+	// AccessMode specifies the access mode for the volume
+	// +kubebuilder:default=ReadWriteOnce
+	// +optional
+	AccessMode string `json:"accessMode,omitempty"`
+
+	// Snapshots declares that the storage class supports CSI VolumeSnapshots,
+	// which the Snapshot backup method requires
+	// +optional
+	Snapshots bool `json:"snapshots,omitempty"`
+
+	// SnapshotClassName is the VolumeSnapshotClass of the snapshots (default:
+	// the default class of the CSI driver)
+	// +optional
+	SnapshotClassName *string `json:"snapshotClassName,omitempty"`
+}
+
+// RotationPolicy defines when the administrative password is rotated
+type RotationPolicy struct {
+	// Schedule is the cron schedule of the rotations, in UTC (e.g. "0 3 1 * *")
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// RestartWorkload rolls the pods of the database workload after each
+	// rotation, for containers that read the password from their environment
+	// +optional
+	RestartWorkload bool `json:"restartWorkload,omitempty"`
+}
+
+// DiskPressureSpec defines the volume usage thresholds, in percent of the
+// volume capacity
+// +kubebuilder:validation:XValidation:rule="self.warningPercent < self.highPercent && self.highPercent < self.criticalPercent",message="thresholds must increase from warningPercent to criticalPercent"
+type DiskPressureSpec struct {
+	// WarningPercent is the first usage threshold
+	// +kubebuilder:default=80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	WarningPercent int32 `json:"warningPercent,omitempty"`
+
+	// HighPercent is the second usage threshold
+	// +kubebuilder:default=90
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	HighPercent int32 `json:"highPercent,omitempty"`
+
+	// CriticalPercent is the usage at which the database is about to fill its volume
+	// +kubebuilder:default=95
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	CriticalPercent int32 `json:"criticalPercent,omitempty"`
+
+	// ReadOnlyOnCritical pauses writes at the critical threshold, until the usage
+	// falls below the high threshold: PostgreSQL defaults transactions to read
+	// only, Elasticsearch blocks writes to the cluster except deletions.
+	// Supported by PostgreSQL and Elasticsearch.
+	// +optional
+	ReadOnlyOnCritical bool `json:"readOnlyOnCritical,omitempty"`
+
+	// AutoExpand grows the data volumes when the fullest one reaches a usage
+	// threshold, up to a maximum size
+	// +optional
+	AutoExpand *DiskAutoExpandSpec `json:"autoExpand,omitempty"`
+}
+
+// DiskAutoExpandSpec defines the automatic expansion of the data volumes
+type DiskAutoExpandSpec struct {
+	// ThresholdPercent is the usage of the fullest data volume at which the
+	// volumes are expanded
+	// +kubebuilder:default=85
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	ThresholdPercent int32 `json:"thresholdPercent,omitempty"`
+
+	// Step is the size added to the volumes at each expansion, e.g. 10Gi
+	// +kubebuilder:validation:Required
+	Step string `json:"step"`
+
+	// MaxSize bounds the size the volumes are expanded to
+	// +kubebuilder:validation:Required
+	MaxSize string `json:"maxSize"`
+}
+
+// ResourceRequirements defines the compute resources
+type ResourceRequirements struct {
+	// CPU resource request
+	// +optional
+	CPU string `json:"cpu,omitempty"`
+
+	// Memory resource request
+	// +optional
+	Memory string `json:"memory,omitempty"`
+
+	// CPU resource limit
+	// +optional
+	CPULimit string `json:"cpuLimit,omitempty"`
+
+	// Memory resource limit
+	// +optional
+	MemoryLimit string `json:"memoryLimit,omitempty"`
+}
+
+// PostgreSQLConfig defines PostgreSQL-specific configuration
+type PostgreSQLConfig struct {
+	// Database name to create
+	// +optional
+	Database string `json:"database,omitempty"`
+
+	// Username for the database
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Password secret reference. When unset, a random password is generated
+	// into the <name>-credentials Secret.
+	// +optional
+	PasswordSecret *SecretReference `json:"passwordSecret,omitempty"`
+
+	// Additional PostgreSQL configuration parameters
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// UpgradeImage runs pg_upgrade when version moves to a new major. It must
+	// ship the server binaries of both majors under /usr/lib/postgresql/<major>/bin.
+	// Defaults to tianon/postgres-upgrade:<from>-to-<to>.
+	// +optional
+	UpgradeImage string `json:"upgradeImage,omitempty"`
+}
+
+// MongoDBConfig defines MongoDB-specific configuration
+type MongoDBConfig struct {
+	// Database name to create
+	// +optional
+	Database string `json:"database,omitempty"`
+
+	// Username for the database
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Password secret reference. When unset, a random password is generated
+	// into the <name>-credentials Secret.
+	// +optional
+	PasswordSecret *SecretReference `json:"passwordSecret,omitempty"`
+
+	// ReplicaSet name for MongoDB replica set
+	// +optional
+	ReplicaSetName string `json:"replicaSetName,omitempty"`
+
+	// Additional MongoDB configuration parameters
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// RedisConfig defines Redis-specific configuration
+type RedisConfig struct {
+	// Password secret reference
+	// +optional
+	PasswordSecret *SecretReference `json:"passwordSecret,omitempty"`
+
+	// Mode specifies Redis mode (standalone, sentinel, cluster)
+	// +kubebuilder:validation:Enum=standalone;sentinel;cluster
+	// +kubebuilder:default=standalone
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// Additional Redis configuration parameters
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// KeyspaceAnalysis configures periodic big-key detection
+	// +optional
+	KeyspaceAnalysis *KeyspaceAnalysisSpec `json:"keyspaceAnalysis,omitempty"`
+}
+
+// KeyspaceAnalysisSpec defines the periodic Redis keyspace analysis Job
+type KeyspaceAnalysisSpec struct {
+	// Enabled turns on the keyspace analysis CronJob
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Schedule is the cron schedule for the analysis Job
+	// +kubebuilder:default="0 */6 * * *"
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// BigKeyThreshold is the memory usage above which a key is reported as a big key
+	// +kubebuilder:default="10Mi"
+	// +optional
+	BigKeyThreshold string `json:"bigKeyThreshold,omitempty"`
+}
+
+// ElasticsearchConfig defines Elasticsearch-specific configuration
+type ElasticsearchConfig struct {
+	// ClusterName specifies the Elasticsearch cluster name
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// NodeRoles specifies the roles for this node (master, data, ingest)
+	// +optional
+	NodeRoles []string `json:"nodeRoles,omitempty"`
+
+	// Additional Elasticsearch configuration parameters
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// ShardAnalysis configures periodic shard sizing analysis
+	// +optional
+	ShardAnalysis *ShardAnalysisSpec `json:"shardAnalysis,omitempty"`
+
+	// NodeSets split the cluster into pools of nodes with dedicated roles, each
+	// run by its own StatefulSet. They replace spec.replicas; without them the
+	// cluster runs a single node.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	NodeSets []ElasticsearchNodeSet `json:"nodeSets,omitempty"`
+}
+
+// ElasticsearchNodeRole is a role of an Elasticsearch node
+// +kubebuilder:validation:Enum=master;data;ingest;ml;remote_cluster_client;transform
+type ElasticsearchNodeRole string
+
+const (
+	ElasticsearchNodeRoleMaster ElasticsearchNodeRole = "master"
+	ElasticsearchNodeRoleData   ElasticsearchNodeRole = "data"
+	ElasticsearchNodeRoleIngest ElasticsearchNodeRole = "ingest"
+)
+
+// ElasticsearchNodeSet defines a pool of Elasticsearch nodes sharing their roles
+type ElasticsearchNodeSet struct {
+	// Name identifies the pool; its StatefulSet is named <database>-<name>
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=20
+	Name string `json:"name"`
+
+	// Roles of the nodes of the pool
+	// +kubebuilder:validation:MinItems=1
+	// +listType=set
+	Roles []ElasticsearchNodeRole `json:"roles"`
+
+	// Replicas is the number of nodes of the pool
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=50
+	Replicas int32 `json:"replicas"`
+
+	// Storage overrides spec.storage for the pool
+	// +optional
+	Storage *StorageSpec `json:"storage,omitempty"`
+
+	// Resources overrides spec.resources for the pool
+	// +optional
+	Resources *ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ShardAnalysisSpec defines the periodic Elasticsearch shard sizing analysis Job.
+// When spec.autoTune is set, an ILM rollover policy is attached to oversized indices
+// that have a rollover alias.
+type ShardAnalysisSpec struct {
+	// Enabled turns on the shard analysis CronJob
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Schedule is the cron schedule for the analysis Job
+	// +kubebuilder:default="0 */6 * * *"
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// MaxShardSize is the primary shard size above which an index is reported as oversized
+	// +kubebuilder:default="50Gi"
+	// +optional
+	MaxShardSize string `json:"maxShardSize,omitempty"`
+
+	// SmallShardSize is the size below which a shard is counted as small
+	// +kubebuilder:default="1Gi"
+	// +optional
+	SmallShardSize string `json:"smallShardSize,omitempty"`
+
+	// SmallShardLimit is the number of small shards above which consolidation is recommended
+	// +kubebuilder:default=1000
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SmallShardLimit int32 `json:"smallShardLimit,omitempty"`
+}
+
+// SQLiteConfig defines SQLite-specific configuration
+type SQLiteConfig struct {
+	// DatabaseFile specifies the SQLite database file path
+	// +optional
+	DatabaseFile string `json:"databaseFile,omitempty"`
+
+	// Additional SQLite configuration parameters
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// SecretReference defines a reference to a Kubernetes Secret
+type SecretReference struct {
+	// Name of the secret
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Key in the secret to use
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+}
+
+// EnvVar defines an environment variable
+type EnvVar struct {
+	// Name of the environment variable
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Value of the environment variable
+	// +optional
+	Value string `json:"value,omitempty"`
+
+	// ValueFrom specifies a source for the environment variable's value
+	// +optional
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"`
+}
+
+// EnvVarSource defines a source for an environment variable
+type EnvVarSource struct {
+	// SecretKeyRef selects a key from a secret
+	// +optional
+	SecretKeyRef *SecretReference `json:"secretKeyRef,omitempty"`
+}
+
+// DatabasePhase defines the phase of the database
+type DatabasePhase string
+
+const (
+	DatabasePhasePending       DatabasePhase = "Pending"
+	DatabasePhaseCreating      DatabasePhase = "Creating"
+	DatabasePhaseBootstrapping DatabasePhase = "Bootstrapping"
+	DatabasePhaseReady         DatabasePhase = "Ready"
+	DatabasePhaseFailed        DatabasePhase = "Failed"
+	DatabasePhaseDeleting      DatabasePhase = "Deleting"
+	DatabasePhaseUpgrading     DatabasePhase = "Upgrading"
+	DatabasePhaseRestoring     DatabasePhase = "Restoring"
+	DatabasePhaseHibernated    DatabasePhase = "Hibernated"
+	DatabasePhaseMigrating     DatabasePhase = "Migrating"
+)
+
+// DatabaseSubPhase is the step of the Creating and Upgrading phases
+type DatabaseSubPhase string
+
+const (
+	// DatabaseSubPhaseProvisioning creates the configuration, the Service and
+	// the workload
+	DatabaseSubPhaseProvisioning DatabaseSubPhase = "Provisioning"
+	// DatabaseSubPhasePreCheck checks that the data can be upgraded, and waits
+	// for the release freeze, the maintenance window or the running operation
+	// before an upgrade rolls out
+	DatabaseSubPhasePreCheck DatabaseSubPhase = "PreCheck"
+	// DatabaseSubPhaseConverting converts the data volumes of a major upgraded
+	// offline and binds the claims to them
+	DatabaseSubPhaseConverting DatabaseSubPhase = "Converting"
+	// DatabaseSubPhaseCanary runs the new version on the canary replica
+	DatabaseSubPhaseCanary DatabaseSubPhase = "Canary"
+	// DatabaseSubPhaseRolling updates the pods to the new version
+	DatabaseSubPhaseRolling DatabaseSubPhase = "Rolling"
+	// DatabaseSubPhaseVerify waits for every replica to be ready, and the engine
+	// healthy, on the new version
+	DatabaseSubPhaseVerify DatabaseSubPhase = "Verify"
+	// DatabaseSubPhaseRollingBack puts the data volumes of the previous major back
+	DatabaseSubPhaseRollingBack DatabaseSubPhase = "RollingBack"
+)
+
+// DatabaseStatus defines the observed state of Database.
+type DatabaseStatus struct {
+	// Phase represents the current phase of the database
+	// +optional
+	Phase DatabasePhase `json:"phase,omitempty"`
+
+	// SubPhase is the step of the Creating and Upgrading phases
+	// +optional
+	SubPhase DatabaseSubPhase `json:"subPhase,omitempty"`
+
+	// PhaseTransitionTime is when the phase or the sub-phase last changed
+	// +optional
+	PhaseTransitionTime *metav1.Time `json:"phaseTransitionTime,omitempty"`
+
+	// Conditions represent the latest available observations of the database's state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ReadyReplicas is the number of ready database replicas
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Selector is the label selector of the engine pods, read by autoscalers
+	// through the scale subresource
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// ServiceName is the name of the service created for the database
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+
+	// ConnectionString provides connection information (without credentials)
+	// +optional
+	ConnectionString string `json:"connectionString,omitempty"`
+
+	// CredentialsSecret is the Secret holding the password of the administrative
+	// user: spec passwordSecret, or the Secret generated when it is unset
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed for this database
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Message provides additional information about the current state
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Recommendations lists advisory findings from the latest analysis runs
+	// +optional
+	Recommendations []Recommendation `json:"recommendations,omitempty"`
+
+	// KeyspaceAnalysis holds the result of the latest Redis keyspace analysis
+	// +optional
+	KeyspaceAnalysis *KeyspaceAnalysisStatus `json:"keyspaceAnalysis,omitempty"`
+
+	// ShardAnalysis holds the result of the latest Elasticsearch shard analysis
+	// +optional
+	ShardAnalysis *ShardAnalysisStatus `json:"shardAnalysis,omitempty"`
+
+	// Logging reports the engine log level currently applied
+	// +optional
+	Logging *LoggingStatus `json:"logging,omitempty"`
+
+	// Downscale reports a replica scale-down deferred by scaleDownProtection
+	// +optional
+	Downscale *DownscaleStatus `json:"downscale,omitempty"`
+
+	// Operations reports the disruptive operation in progress and the queued ones
+	// +optional
+	Operations *OperationsStatus `json:"operations,omitempty"`
+
+	// AppliedConfigHash is the hash of the rendered engine configuration the
+	// workload runs. The rendered file is kept in the ConfigMap <name>-config-<hash>.
+	// +optional
+	AppliedConfigHash string `json:"appliedConfigHash,omitempty"`
+
+	// Bootstrap reports the provisioning of the logical databases and users
+	// +optional
+	Bootstrap *BootstrapStatus `json:"bootstrap,omitempty"`
+
+	// Monitoring reports the monitoring user of metrics exporters
+	// +optional
+	Monitoring *MonitoringStatus `json:"monitoring,omitempty"`
+
+	// Rotation reports the scheduled rotations of the administrative password
+	// +optional
+	Rotation *RotationStatus `json:"rotation,omitempty"`
+
+	// DiskUsage reports the usage of the fullest data volume
+	// +optional
+	DiskUsage *DiskUsageStatus `json:"diskUsage,omitempty"`
+
+	// Health is the result of the latest query of the engine
+	// +optional
+	Health *HealthStatus `json:"health,omitempty"`
+
+	// Provisioning tracks the initial provisioning transaction
+	// +optional
+	Provisioning *ProvisioningStatus `json:"provisioning,omitempty"`
+
+	// Image reports the digest the version tag was resolved to, with
+	// imageResolution Digest
+	// +optional
+	Image *ImageStatus `json:"image,omitempty"`
+
+	// CatalogImage reports the DatabaseVersion the image of the running
+	// version comes from, when the catalog lists the engine
+	// +optional
+	CatalogImage *CatalogImageStatus `json:"catalogImage,omitempty"`
+
+	// Version is the engine version the Database was last reconciled at. Changes
+	// of spec.version are validated against the upgrade paths of the engine from it.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// MajorUpgrade reports the latest upgrade of the data volumes to a new
+	// PostgreSQL major. It is kept until version changes again.
+	// +optional
+	MajorUpgrade *MajorUpgradeStatus `json:"majorUpgrade,omitempty"`
+
+	// StorageMigration reports the latest move of the data volumes to a new
+	// storage.storageClassName. It is kept until the class changes again.
+	// +optional
+	StorageMigration *StorageMigrationStatus `json:"storageMigration,omitempty"`
+
+	// Topology reports the replica schedule in effect
+	// +optional
+	Topology *TopologyStatus `json:"topology,omitempty"`
+
+	// Rollout reports the canary of the latest rollout of a pod template change
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// VerticalScale reports the replica by replica restart rolling out a change
+	// of the resources
+	// +optional
+	VerticalScale *VerticalScaleStatus `json:"verticalScale,omitempty"`
+
+	// Maintenance reports whether disruptive operations may run and which ones
+	// wait for the next maintenance window
+	// +optional
+	Maintenance *MaintenanceStatus `json:"maintenance,omitempty"`
+
+	// AutoUpgrade reports the patch releases found for maintenance.autoUpgrade
+	// and the last upgrade to one of them
+	// +optional
+	AutoUpgrade *AutoUpgradeStatus `json:"autoUpgrade,omitempty"`
+
+	// BackupSchedules reports the CronJob and the last runs of each additional
+	// backup schedule
+	// +optional
+	BackupSchedules []BackupScheduleStatus `json:"backupSchedules,omitempty"`
+
+	// Backups summarises the scheduled and on-demand backups of the Database
+	// +optional
+	Backups *BackupStatus `json:"backups,omitempty"`
+
+	// RecentOperations is the history of the last significant operations
+	// (provisioning, scaling, bootstrap, backups, restores), oldest first. Unlike
+	// Events it is kept as long as the Database exists.
+	// +optional
+	// +kubebuilder:validation:MaxItems=20
+	RecentOperations []OperationRecord `json:"recentOperations,omitempty"`
+}
+
+// TopologyStatus reports the replica count called for by the schedules
+type TopologyStatus struct {
+	// ActiveSchedule is the schedule whose window is open, or else the cron
+	// schedule that fired last, empty when none applies
+	// +optional
+	ActiveSchedule string `json:"activeSchedule,omitempty"`
+
+	// Replicas is the replica count applied to the workload
+	Replicas int32 `json:"replicas"`
+
+	// NextChange is when the next window opens or closes, or a cron schedule fires
+	// +optional
+	NextChange *metav1.Time `json:"nextChange,omitempty"`
+}
+
+// RolloutStep is a step of a canary rollout
+// +kubebuilder:validation:Enum=Canary;Promoted;Completed;Aborted
+type RolloutStep string
+
+const (
+	// RolloutStepCanary updates the canary and watches it
+	RolloutStepCanary RolloutStep = "Canary"
+	// RolloutStepPromoted updates the other replicas after a healthy canary
+	RolloutStepPromoted RolloutStep = "Promoted"
+	// RolloutStepCompleted means every replica runs the new template
+	RolloutStepCompleted RolloutStep = "Completed"
+	// RolloutStepAborted leaves the other replicas on the previous template
+	// after the canary degraded
+	RolloutStepAborted RolloutStep = "Aborted"
+)
+
+// VerticalScaleStatus reports a change of the resources restarting the
+// replicas one at a time, the primary last
+type VerticalScaleStatus struct {
+	// StatefulSet is the workload scaled
+	StatefulSet string `json:"statefulSet"`
+
+	// Revision is the controller revision of the template with the new resources
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// Primary is the replica restarted last, after a switchover where the
+	// engine supports one
+	// +optional
+	Primary string `json:"primary,omitempty"`
+
+	// Pod is the replica being restarted
+	// +optional
+	Pod string `json:"pod,omitempty"`
+
+	// SwitchoverAt is when the primary role was handed over to another replica
+	// +optional
+	SwitchoverAt *metav1.Time `json:"switchoverAt,omitempty"`
+
+	// Message details the step the restart is at
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// StartedAt is when the new resources were applied
+	StartedAt metav1.Time `json:"startedAt"`
+}
+
+// RolloutStatus reports a canary rollout
+type RolloutStatus struct {
+	// StatefulSet is the workload rolled out
+	StatefulSet string `json:"statefulSet"`
+
+	// Canary is the pod updated first
+	Canary string `json:"canary"`
+
+	// Revision is the controller revision of the new template
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// Step is the step the rollout is at
+	Step RolloutStep `json:"step"`
+
+	// Message details the step, or why the rollout was aborted
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// StartedAt is when the new template was applied
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// CanaryReadyAt is when the canary became ready on the new template
+	// +optional
+	CanaryReadyAt *metav1.Time `json:"canaryReadyAt,omitempty"`
+}
+
+// MaintenanceStatus reports the maintenance windows
+type MaintenanceStatus struct {
+	// Open is true within a window, or while the maintenance-override-until
+	// annotation lets disruptive operations run outside of them
+	Open bool `json:"open"`
+
+	// NextChange is when the next window opens or closes, or a cron schedule fires
+	// +optional
+	NextChange *metav1.Time `json:"nextChange,omitempty"`
+
+	// Deferred lists the disruptive operations waiting for the next window
+	// +optional
+	Deferred []string `json:"deferred,omitempty"`
+}
+
+// AutoUpgradeStatus reports the automatic patch release upgrades
+type AutoUpgradeStatus struct {
+	// CheckedAt is when the registry was last asked for the versions
+	// +optional
+	CheckedAt *metav1.Time `json:"checkedAt,omitempty"`
+
+	// LatestVersion is the latest patch release of spec.version found
+	// +optional
+	LatestVersion string `json:"latestVersion,omitempty"`
+
+	// Message explains why an available upgrade waits, or why none was found
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// FromVersion is the version the last upgrade started from
+	// +optional
+	FromVersion string `json:"fromVersion,omitempty"`
+
+	// ToVersion is the version the last upgrade moved to
+	// +optional
+	ToVersion string `json:"toVersion,omitempty"`
+
+	// UpgradedAt is when spec.version was last upgraded
+	// +optional
+	UpgradedAt *metav1.Time `json:"upgradedAt,omitempty"`
+}
+
+// BackupScheduleStatus reports an additional backup schedule
+type BackupScheduleStatus struct {
+	// Name of the schedule
+	Name string `json:"name"`
+
+	// Method of the schedule
+	Method BackupMethod `json:"method"`
+
+	// CronJob running the schedule
+	// +optional
+	CronJob string `json:"cronJob,omitempty"`
+
+	// LastScheduleTime is when the schedule last started a backup
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// LastSuccessfulTime is when a backup of the schedule last succeeded
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+}
+
+// BackupStatus summarises the backups of a Database, taken by the backup CronJobs
+// or recorded as DatabaseBackups
+type BackupStatus struct {
+	// LastSuccessfulBackup is when a backup last succeeded
+	// +optional
+	LastSuccessfulBackup *metav1.Time `json:"lastSuccessfulBackup,omitempty"`
+
+	// LastBackupSize is the size of the most recent successful backup whose size
+	// is known
+	// +optional
+	LastBackupSize *resource.Quantity `json:"lastBackupSize,omitempty"`
+
+	// NextScheduledBackup is when the next scheduled backup starts, over all
+	// schedules. WAL base backups are not scheduled by a CronJob and not included.
+	// +optional
+	NextScheduledBackup *metav1.Time `json:"nextScheduledBackup,omitempty"`
+
+	// FailureCount is the number of backups that failed since the last successful one
+	// +optional
+	FailureCount int32 `json:"failureCount,omitempty"`
+
+	// Destination is where the main schedule stores its backups: pvc://<claim>,
+	// s3://<bucket>/<path>/<namespace>/<name> for WAL archiving, or
+	// volumesnapshot://<class> for snapshots, without a class for the default one
+	// +optional
+	Destination string `json:"destination,omitempty"`
+}
+
+// OperationOutcome is the result of a recorded operation
+// +kubebuilder:validation:Enum=Succeeded;Failed
+type OperationOutcome string
+
+const (
+	OperationSucceeded OperationOutcome = "Succeeded"
+	OperationFailed    OperationOutcome = "Failed"
+)
+
+// OperationRecord is an entry of the operation history
+type OperationRecord struct {
+	// Type of the operation, e.g. Provision, Scale, Backup or Restore
+	Type string `json:"type"`
+
+	// Time is when the operation finished
+	Time metav1.Time `json:"time"`
+
+	// Outcome of the operation
+	Outcome OperationOutcome `json:"outcome"`
+
+	// Detail describes the operation or its failure
+	// +optional
+	Detail string `json:"detail,omitempty"`
+}
+
+// MajorUpgradeStep is the step of a major upgrade
+// +kubebuilder:validation:Enum=PreCheck;Upgrade;Cutover;Verify;RollingBack;Completed;RolledBack
+type MajorUpgradeStep string
+
+const (
+	// MajorUpgradeStepPreCheck stops the workload and checks with pg_upgrade
+	// --check that every data volume can be upgraded
+	MajorUpgradeStepPreCheck MajorUpgradeStep = "PreCheck"
+	// MajorUpgradeStepUpgrade converts every data volume into a new volume
+	MajorUpgradeStepUpgrade MajorUpgradeStep = "Upgrade"
+	// MajorUpgradeStepCutover binds the data volume claims to the new volumes
+	MajorUpgradeStepCutover MajorUpgradeStep = "Cutover"
+	// MajorUpgradeStepVerify waits for the workload to become ready on the new major
+	MajorUpgradeStepVerify MajorUpgradeStep = "Verify"
+	// MajorUpgradeStepRollingBack puts the previous volumes back
+	MajorUpgradeStepRollingBack MajorUpgradeStep = "RollingBack"
+	// MajorUpgradeStepCompleted reports a Database running the new major
+	MajorUpgradeStepCompleted MajorUpgradeStep = "Completed"
+	// MajorUpgradeStepRolledBack reports a Database back on the previous major
+	MajorUpgradeStepRolledBack MajorUpgradeStep = "RolledBack"
+)
+
+// MajorUpgradeStatus describes an upgrade of the data volumes to a new major
+type MajorUpgradeStatus struct {
+	// FromVersion is the version the data volumes were written by
+	FromVersion string `json:"fromVersion"`
+
+	// ToVersion is the version being upgraded to
+	ToVersion string `json:"toVersion"`
+
+	// Step of the upgrade
+	Step MajorUpgradeStep `json:"step"`
+
+	// Message describes the progress of the step, or why the upgrade was rolled back
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Volumes are the data volumes of the replicas being upgraded
+	// +optional
+	Volumes []MajorUpgradeVolume `json:"volumes,omitempty"`
+
+	// StartedAt is when the upgrade started
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// CutOverAt is when the claims were bound to the upgraded volumes
+	// +optional
+	CutOverAt *metav1.Time `json:"cutOverAt,omitempty"`
+
+	// CompletedAt is when the upgrade completed or was rolled back
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// MajorUpgradeVolume tracks the data volume of a replica through a major upgrade
+type MajorUpgradeVolume struct {
+	// Claim is the data volume claim of the replica
+	Claim string `json:"claim"`
+
+	// PreviousVolume is the PersistentVolume holding the data of the previous
+	// major. It is retained after the upgrade until you delete it.
+	PreviousVolume string `json:"previousVolume"`
+
+	// UpgradedVolume is the PersistentVolume pg_upgrade wrote the new major to
+	// +optional
+	UpgradedVolume string `json:"upgradedVolume,omitempty"`
+}
+
+// StorageMigrationStep is the step of a storage class migration
+// +kubebuilder:validation:Enum=Copy;Cutover;Verify;RollingBack;Completed;RolledBack
+type StorageMigrationStep string
+
+const (
+	// StorageMigrationStepCopy stops the workload and copies every data volume
+	// into a new volume of the new class
+	StorageMigrationStepCopy StorageMigrationStep = "Copy"
+	// StorageMigrationStepCutover binds the data volume claims to the new volumes
+	StorageMigrationStepCutover StorageMigrationStep = "Cutover"
+	// StorageMigrationStepVerify waits for the workload to become ready on the new volumes
+	StorageMigrationStepVerify StorageMigrationStep = "Verify"
+	// StorageMigrationStepRollingBack puts the previous volumes back
+	StorageMigrationStepRollingBack StorageMigrationStep = "RollingBack"
+	// StorageMigrationStepCompleted reports a Database running on the new class
+	StorageMigrationStepCompleted StorageMigrationStep = "Completed"
+	// StorageMigrationStepRolledBack reports a Database back on the previous volumes
+	StorageMigrationStepRolledBack StorageMigrationStep = "RolledBack"
+)
+
+// StorageMigrationStatus describes a move of the data volumes to another storage class
+type StorageMigrationStatus struct {
+	// FromClass is the storage class of the data volumes before the migration
+	// +optional
+	FromClass string `json:"fromClass,omitempty"`
+
+	// ToClass is the storage class being migrated to
+	ToClass string `json:"toClass"`
+
+	// Step of the migration
+	Step StorageMigrationStep `json:"step"`
+
+	// Message describes the progress of the step, or why the migration was rolled back
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Volumes are the data volumes of the replicas being migrated
+	// +optional
+	Volumes []StorageMigrationVolume `json:"volumes,omitempty"`
+
+	// StartedAt is when the migration started
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// CutOverAt is when the claims were bound to the migrated volumes
+	// +optional
+	CutOverAt *metav1.Time `json:"cutOverAt,omitempty"`
+
+	// CompletedAt is when the migration completed or was rolled back
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+}
+
+// StorageMigrationVolume tracks the data volume of a replica through a storage
+// class migration
+type StorageMigrationVolume struct {
+	// Claim is the data volume claim of the replica
+	Claim string `json:"claim"`
+
+	// PreviousVolume is the PersistentVolume of the previous class. It is
+	// deleted once the migration completed with the Delete deletionPolicy, and
+	// retained until you delete it with Snapshot.
+	PreviousVolume string `json:"previousVolume"`
+
+	// MigratedVolume is the PersistentVolume of the new class the data was copied to
+	// +optional
+	MigratedVolume string `json:"migratedVolume,omitempty"`
+}
+
+// ImageStatus records the resolution of the version tag to a digest
+type ImageStatus struct {
+	// Version is the spec version that was resolved
+	Version string `json:"version"`
+
+	// Tag is the image reference that was resolved, e.g. postgres:16
+	Tag string `json:"tag"`
+
+	// Digest is the manifest digest the tag pointed to, e.g. sha256:...
+	Digest string `json:"digest"`
+
+	// ResolvedAt is when the tag was resolved
+	// +optional
+	ResolvedAt *metav1.Time `json:"resolvedAt,omitempty"`
+}
+
+// CatalogImageStatus reports the catalog entry of the running version
+type CatalogImageStatus struct {
+	// DatabaseVersion is the name of the catalog entry
+	DatabaseVersion string `json:"databaseVersion"`
+
+	// Version is the running version the entry was looked up for
+	Version string `json:"version"`
+
+	// Image is the image of the entry
+	Image string `json:"image"`
+
+	// Deprecated reports that the version is deprecated
+	// +optional
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+// ProvisioningStatus tracks the initial provisioning transaction
+type ProvisioningStatus struct {
+	// Completed is set once every provisioning step succeeded. Later failures are
+	// retried but never rolled back.
+	// +optional
+	Completed bool `json:"completed,omitempty"`
+
+	// Attempts is the number of failed attempts of the current transaction
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// Created lists the resources created by the transaction, in creation order
+	// +optional
+	Created []ProvisionedResource `json:"created,omitempty"`
+
+	// FailedGeneration is the spec generation whose provisioning failed for good
+	// +optional
+	FailedGeneration int64 `json:"failedGeneration,omitempty"`
+}
+
+// ProvisionedResource identifies a resource created during provisioning
+type ProvisionedResource struct {
+	// Kind of the resource
+	Kind string `json:"kind"`
+
+	// Name of the resource
+	Name string `json:"name"`
+}
+
+// DiskUsageStatus reports the usage of the data volumes
+type DiskUsageStatus struct {
+	// Volume is the PersistentVolumeClaim with the highest usage
+	Volume string `json:"volume"`
+
+	// UsedBytes is the space used on the volume
+	UsedBytes int64 `json:"usedBytes"`
+
+	// CapacityBytes is the capacity of the volume
+	CapacityBytes int64 `json:"capacityBytes"`
+
+	// Percent is the usage of the volume
+	Percent int32 `json:"percent"`
+
+	// ReadOnly reports whether writes are paused by readOnlyOnCritical
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// ExpandedSize is the size diskPressure.autoExpand grew the data volumes
+	// to. The volumes get the larger of it and storage.size.
+	// +optional
+	ExpandedSize string `json:"expandedSize,omitempty"`
+
+	// CheckedAt is when the usage was read
+	CheckedAt metav1.Time `json:"checkedAt"`
+}
+
+// HealthState is the outcome of a health check
+// +kubebuilder:validation:Enum=Healthy;Unhealthy
+type HealthState string
+
+const (
+	// HealthStateHealthy is an engine that answered the health check
+	HealthStateHealthy HealthState = "Healthy"
+	// HealthStateUnhealthy is an engine that could not be reached or reported a failure
+	HealthStateUnhealthy HealthState = "Unhealthy"
+)
+
+// HealthStatus reports the health check of the engine, run over the Service
+// with the managed credentials
+type HealthStatus struct {
+	// State is the outcome of the check
+	State HealthState `json:"state"`
+
+	// Role is the replication role of the instance that answered: primary or
+	// replica for PostgreSQL, master or slave for Redis
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// ConnectedReplicas is the number of replicas streaming from the primary, or
+	// the data nodes of an Elasticsearch cluster
+	// +optional
+	ConnectedReplicas *int32 `json:"connectedReplicas,omitempty"`
+
+	// ReplicationLagSeconds is the replay lag of the slowest PostgreSQL replica
+	// +optional
+	ReplicationLagSeconds *int64 `json:"replicationLagSeconds,omitempty"`
+
+	// Message explains an unhealthy state
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// CheckedAt is when the check ran
+	CheckedAt metav1.Time `json:"checkedAt"`
+}
+
+// MonitoringStatus reports the monitoring user
+type MonitoringStatus struct {
+	// CredentialsSecret holds the username and password of the monitoring user
+	CredentialsSecret string `json:"credentialsSecret"`
+
+	// AppliedSecretVersion is the resourceVersion of the Secret whose password
+	// the monitoring user last got. A changed Secret sets the new password.
+	// +optional
+	AppliedSecretVersion string `json:"appliedSecretVersion,omitempty"`
+}
+
+// RotationPhase defines the phase of the credential rotation
+type RotationPhase string
+
+const (
+	// RotationPhaseScheduled waits for the next rotation
+	RotationPhaseScheduled RotationPhase = "Scheduled"
+	// RotationPhaseRotating sets the new password in the engine
+	RotationPhaseRotating RotationPhase = "Rotating"
+	// RotationPhaseFailed reports a failed rotation; the previous password stays
+	// valid and the next rotation is scheduled
+	RotationPhaseFailed RotationPhase = "Failed"
+)
+
+// RotationStatus reports the rotations of the administrative password
+type RotationStatus struct {
+	// Phase of the rotation
+	Phase RotationPhase `json:"phase"`
+
+	// NextRotation is when the next rotation starts
+	// +optional
+	NextRotation *metav1.Time `json:"nextRotation,omitempty"`
+
+	// Schedule is the schedule nextRotation was computed from
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// LastRotation is when the password was last rotated successfully
+	// +optional
+	LastRotation *metav1.Time `json:"lastRotation,omitempty"`
+
+	// Message explains a failed or deferred rotation
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// HandledRequest is the value of the rotate-credentials annotation last
+	// acted upon
+	// +optional
+	HandledRequest string `json:"handledRequest,omitempty"`
+}
+
+// BootstrapStatus reports the logical databases and users applied to the instance
+type BootstrapStatus struct {
+	// AppliedHash identifies the bootstrap spec last applied successfully
+	// +optional
+	AppliedHash string `json:"appliedHash,omitempty"`
+
+	// Databases lists the databases provisioned by the last successful run
+	// +optional
+	Databases []string `json:"databases,omitempty"`
+
+	// Users lists the users provisioned by the last successful run
+	// +optional
+	Users []string `json:"users,omitempty"`
+
+	// FailedHash identifies the bootstrap spec whose last run failed
+	// +optional
+	FailedHash string `json:"failedHash,omitempty"`
+
+	// Failures counts the consecutive failed runs of FailedHash
+	// +optional
+	Failures int32 `json:"failures,omitempty"`
+
+	// NextRetry is when the failed bootstrap Job is run again
+	// +optional
+	NextRetry *metav1.Time `json:"nextRetry,omitempty"`
+}
+
+// OperationsStatus reports disruptive operations (scaling, upgrades, restores,
+// credential rotations), which run one at a time per Database
+type OperationsStatus struct {
+	// Active is the operation currently running
+	// +optional
+	Active *ActiveOperation `json:"active,omitempty"`
+
+	// Pending lists the queued operations in the order they will run
+	// +optional
+	Pending []string `json:"pending,omitempty"`
+}
+
+// ActiveOperation describes the running disruptive operation
+type ActiveOperation struct {
+	// Name of the operation
+	Name string `json:"name"`
+
+	// StartedAt is when the operation acquired the lock
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// StopsWorkload is set by operations replacing the data volumes, such as
+	// snapshot restores. The workload is neither created nor scaled while they run.
+	// +optional
+	StopsWorkload bool `json:"stopsWorkload,omitempty"`
+}
+
+// DownscaleStatus describes a deferred scale-down
+type DownscaleStatus struct {
+	// TargetReplicas is the replica count the scale-down is waiting to apply
+	TargetReplicas int32 `json:"targetReplicas"`
+
+	// BlockedSince is when the scale-down was first deferred
+	// +optional
+	BlockedSince *metav1.Time `json:"blockedSince,omitempty"`
+
+	// Connections is the number of client connections per replica to be removed
+	// +optional
+	Connections map[string]int64 `json:"connections,omitempty"`
+}
+
+// LoggingStatus reports the applied engine logging settings
+type LoggingStatus struct {
+	// EngineLevel is the log level last applied to the engine
+	// +optional
+	EngineLevel EngineLogLevel `json:"engineLevel,omitempty"`
+
+	// DebugRequest is the value of the debug annotation the current debug window was started from
+	// +optional
+	DebugRequest string `json:"debugRequest,omitempty"`
+
+	// DebugUntil is the time debug logging reverts to the configured level
+	// +optional
+	DebugUntil *metav1.Time `json:"debugUntil,omitempty"`
+}
+
+// RecommendationSeverity defines how urgent a recommendation is
+// +kubebuilder:validation:Enum=Info;Warning
+type RecommendationSeverity string
+
+const (
+	RecommendationSeverityInfo    RecommendationSeverity = "Info"
+	RecommendationSeverityWarning RecommendationSeverity = "Warning"
+)
+
+// Recommendation is an advisory finding produced by an analysis Job
+type Recommendation struct {
+	// Type identifies the analysis that produced the recommendation
+	Type string `json:"type"`
+
+	// Severity of the finding
+	Severity RecommendationSeverity `json:"severity"`
+
+	// Target is the object the recommendation refers to (key, index, ...)
+	// +optional
+	Target string `json:"target,omitempty"`
+
+	// Message describes the finding and the suggested action
+	Message string `json:"message"`
+}
+
+// KeyspaceAnalysisStatus holds the result of a Redis keyspace analysis
+type KeyspaceAnalysisStatus struct {
+	// LastAnalysisTime is the completion time of the analyzed Job
+	// +optional
+	LastAnalysisTime *metav1.Time `json:"lastAnalysisTime,omitempty"`
+
+	// TopKeys lists the biggest key of each type, ordered by memory usage
+	// +optional
+	TopKeys []KeyUsage `json:"topKeys,omitempty"`
+
+	// KeyCounts is the number of keys per type
+	// +optional
+	KeyCounts map[string]int64 `json:"keyCounts,omitempty"`
+}
+
+// ShardAnalysisStatus holds the result of an Elasticsearch shard analysis
+type ShardAnalysisStatus struct {
+	// LastAnalysisTime is the completion time of the analyzed Job
+	// +optional
+	LastAnalysisTime *metav1.Time `json:"lastAnalysisTime,omitempty"`
+
+	// TotalShards is the number of shards in the cluster
+	// +optional
+	TotalShards int64 `json:"totalShards,omitempty"`
+
+	// SmallShards is the number of shards smaller than SmallShardSize
+	// +optional
+	SmallShards int64 `json:"smallShards,omitempty"`
+
+	// OversizedIndices lists indices with a primary shard larger than MaxShardSize
+	// +optional
+	OversizedIndices []IndexShardUsage `json:"oversizedIndices,omitempty"`
+
+	// TunedIndices lists indices an ILM rollover policy was applied to
+	// +optional
+	TunedIndices []string `json:"tunedIndices,omitempty"`
+
+	// DataNodes is the number of data nodes of the cluster
+	// +optional
+	DataNodes int32 `json:"dataNodes,omitempty"`
+
+	// HotIndices lists indices taking most of the indexing load on fewer primary
+	// shards than there are data nodes
+	// +optional
+	HotIndices []HotIndexUsage `json:"hotIndices,omitempty"`
+}
+
+// IndexShardUsage describes the largest primary shard of an index
+type IndexShardUsage struct {
+	// Index name
+	Index string `json:"index"`
+
+	// LargestShardBytes is the size of the largest primary shard
+	LargestShardBytes int64 `json:"largestShardBytes"`
+
+	// RolloverAlias is the index.lifecycle.rollover_alias setting of the index,
+	// empty when the index has no alias to be rolled over through
+	// +optional
+	RolloverAlias string `json:"rolloverAlias,omitempty"`
+}
+
+// HotIndexUsage describes the indexing load of an index
+type HotIndexUsage struct {
+	// Index name
+	Index string `json:"index"`
+
+	// IndexingRate is the number of documents indexed per second
+	IndexingRate int64 `json:"indexingRate"`
+
+	// IndexingShare is the percentage of the indexing load of the cluster
+	IndexingShare int32 `json:"indexingShare"`
+
+	// PrimaryShards is the number of primary shards of the index
+	PrimaryShards int32 `json:"primaryShards"`
+}
+
+// KeyUsage describes the memory usage of a single key
+type KeyUsage struct {
+	// Key name
+	Key string `json:"key"`
+
+	// Type of the key (string, list, hash, ...)
+	Type string `json:"type"`
+
+	// Bytes of memory used by the key
+	Bytes int64 `json:"bytes"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.readyReplicas,selectorpath=.status.selector
 // +kubebuilder:resource:shortName=db
 // +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
 // +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Step",type=string,JSONPath=`.status.subPhase`,priority=1
 // +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
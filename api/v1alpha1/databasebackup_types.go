@@ -0,0 +1,159 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseLabel is set on objects belonging to a Database, with the Database name as value
+const DatabaseLabel = "databases.database-operator.io/database"
+
+// BackupScheduleLabel is set on the CronJobs and DatabaseBackups of an additional
+// backup schedule, with the schedule name as value
+const BackupScheduleLabel = "databases.database-operator.io/backup-schedule"
+
+// DatabaseBackupSpec defines a single backup of a Database
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+// +kubebuilder:validation:XValidation:rule="!has(self.method) || self.method != 'WAL'",message="WAL archiving is continuous, a DatabaseBackup is a Dump or a Snapshot"
+type DatabaseBackupSpec struct {
+	// DatabaseRef references the Database to back up, in the same namespace
+	DatabaseRef corev1.LocalObjectReference `json:"databaseRef"`
+
+	// Method overrides the backup method of the Database, Dump or Snapshot
+	// +optional
+	Method BackupMethod `json:"method,omitempty"`
+}
+
+// DatabaseBackupPhase defines the phase of a backup
+type DatabaseBackupPhase string
+
+const (
+	DatabaseBackupPhasePending   DatabaseBackupPhase = "Pending"
+	DatabaseBackupPhaseRunning   DatabaseBackupPhase = "Running"
+	DatabaseBackupPhaseCompleted DatabaseBackupPhase = "Completed"
+	DatabaseBackupPhaseFailed    DatabaseBackupPhase = "Failed"
+)
+
+// DatabaseBackupStatus defines the observed state of DatabaseBackup
+type DatabaseBackupStatus struct {
+	// Phase represents the current phase of the backup
+	// +optional
+	Phase DatabaseBackupPhase `json:"phase,omitempty"`
+
+	// JobName is the name of the Job taking the backup
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// StartTime is when the backup Job was created
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the backup Job finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Location is where the backup is stored, e.g. pvc://<claim>/<file> or
+	// volumesnapshot://<snapshot>[,<snapshot>...]
+	// +optional
+	Location string `json:"location,omitempty"`
+
+	// Size of the backup
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// KMSKeyID is the KMS key wrapping the data key of an encrypted backup,
+	// stored in <file>.manifest.json next to it
+	// +optional
+	KMSKeyID string `json:"kmsKeyId,omitempty"`
+
+	// Snapshots lists the VolumeSnapshots of a Snapshot method backup, one per
+	// data volume in replica order
+	// +optional
+	Snapshots []string `json:"snapshots,omitempty"`
+
+	// Message provides additional information about the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Verification reports the restore test of the backup, when the Database
+	// enables backup verification
+	// +optional
+	Verification *BackupVerificationStatus `json:"verification,omitempty"`
+}
+
+// BackupVerificationResult defines the outcome of a backup verification
+type BackupVerificationResult string
+
+const (
+	BackupVerificationPending BackupVerificationResult = "Pending"
+	BackupVerificationRunning BackupVerificationResult = "Running"
+	BackupVerificationValid   BackupVerificationResult = "Valid"
+	BackupVerificationInvalid BackupVerificationResult = "Invalid"
+)
+
+// BackupVerificationStatus reports the restore test of a backup
+type BackupVerificationStatus struct {
+	// Result of the verification
+	Result BackupVerificationResult `json:"result"`
+
+	// JobName is the name of the Job restoring the backup into an ephemeral instance
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// CompletionTime is when the verification finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Message is the outcome of the sanity query, or why the backup is invalid
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=dbbackup
+// +kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseRef.name`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Size",type=string,JSONPath=`.status.size`
+// +kubebuilder:printcolumn:name="Verified",type=string,JSONPath=`.status.verification.result`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DatabaseBackup is the Schema for the databasebackups API. It records a single
+// backup run of a Database.
+type DatabaseBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseBackupSpec   `json:"spec,omitempty"`
+	Status DatabaseBackupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatabaseBackupList contains a list of DatabaseBackup.
+type DatabaseBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseBackup{}, &DatabaseBackupList{})
+}
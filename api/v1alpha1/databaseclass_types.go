@@ -0,0 +1,94 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseClassSpec defines an offering of the platform that Databases select
+// through spec.className: the engines and versions it allows, and the
+// defaults filled into the spec of its Databases
+type DatabaseClassSpec struct {
+	// Description of the offering, shown to the teams choosing a class
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Engines lists the engines and versions Databases of the class may run.
+	// Empty allows every engine.
+	// +optional
+	Engines []DatabaseClassEngine `json:"engines,omitempty"`
+
+	// StorageClassName is the storage class of Databases not setting
+	// spec.storage.storageClass
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// Profiles lists the profiles Databases of the class may select. Empty
+	// allows every profile.
+	// +optional
+	Profiles []DatabaseProfile `json:"profiles,omitempty"`
+
+	// DefaultProfile is the profile of Databases not setting spec.profile
+	// +optional
+	DefaultProfile DatabaseProfile `json:"defaultProfile,omitempty"`
+
+	// Backup is the backup configuration of Databases not setting spec.backup
+	// +optional
+	Backup *BackupSpec `json:"backup,omitempty"`
+}
+
+// DatabaseClassEngine allows an engine in a DatabaseClass
+type DatabaseClassEngine struct {
+	// Type of the engine
+	Type DatabaseType `json:"type"`
+
+	// Versions lists the versions allowed, each matching itself and the
+	// versions it is a prefix of at a dot: 16 allows 16.4. Empty allows every
+	// version.
+	// +optional
+	Versions []string `json:"versions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=dbclass
+// +kubebuilder:printcolumn:name="Storage Class",type=string,JSONPath=`.spec.storageClassName`
+// +kubebuilder:printcolumn:name="Profile",type=string,JSONPath=`.spec.defaultProfile`
+// +kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DatabaseClass is the Schema for the databaseclasses API. Platform teams
+// publish their tiers of Databases as cluster-wide classes.
+type DatabaseClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DatabaseClassSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatabaseClassList contains a list of DatabaseClass.
+type DatabaseClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseClass{}, &DatabaseClassList{})
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseMigrationSpec defines the schema migration scripts applied to a
+// Database, in the order of their names, by the operator
+// +kubebuilder:validation:XValidation:rule="self.databaseRef == oldSelf.databaseRef",message="databaseRef is immutable"
+type DatabaseMigrationSpec struct {
+	// DatabaseRef references the Database the scripts are applied to, in the
+	// same namespace
+	DatabaseRef corev1.LocalObjectReference `json:"databaseRef"`
+
+	// Source holds the migration scripts: .sql files for PostgreSQL, .js files
+	// for MongoDB
+	Source DatabaseMigrationSource `json:"source"`
+
+	// Database is the logical database the scripts run in (default: the
+	// database of the administrative connection)
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Database string `json:"database,omitempty"`
+}
+
+// DatabaseMigrationSource selects where the migration scripts are read from
+// +kubebuilder:validation:XValidation:rule="has(self.configMap) != has(self.image)",message="exactly one of configMap and image is required"
+type DatabaseMigrationSource struct {
+	// ConfigMap holds one script per key, the key being the name of the migration
+	// +optional
+	ConfigMap *corev1.LocalObjectReference `json:"configMap,omitempty"`
+
+	// Image ships the scripts in a directory. Pin it by digest: the scripts of
+	// an image are listed once per image reference.
+	// +optional
+	Image *MigrationImageSource `json:"image,omitempty"`
+}
+
+// MigrationImageSource is an image shipping migration scripts
+type MigrationImageSource struct {
+	// Image is the reference of the image, which needs a shell and sha256sum
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// Path is the directory of the scripts in the image
+	// +kubebuilder:default=/migrations
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// DatabaseMigrationPhase defines the phase of a DatabaseMigration
+type DatabaseMigrationPhase string
+
+const (
+	DatabaseMigrationPhasePending   DatabaseMigrationPhase = "Pending"
+	DatabaseMigrationPhaseRunning   DatabaseMigrationPhase = "Running"
+	DatabaseMigrationPhaseSucceeded DatabaseMigrationPhase = "Succeeded"
+	DatabaseMigrationPhaseFailed    DatabaseMigrationPhase = "Failed"
+)
+
+// MigrationScriptPhase defines the phase of a single migration script
+type MigrationScriptPhase string
+
+const (
+	MigrationScriptPhasePending MigrationScriptPhase = "Pending"
+	MigrationScriptPhaseRunning MigrationScriptPhase = "Running"
+	MigrationScriptPhaseApplied MigrationScriptPhase = "Applied"
+	MigrationScriptPhaseFailed  MigrationScriptPhase = "Failed"
+)
+
+// MigrationScriptStatus records a migration script and whether it was applied
+type MigrationScriptStatus struct {
+	// Name of the script
+	Name string `json:"name"`
+
+	// Checksum of the content of the script. The checksum of an applied script
+	// must not change.
+	Checksum string `json:"checksum"`
+
+	// Phase of the script
+	Phase MigrationScriptPhase `json:"phase"`
+
+	// AppliedAt is when the script was applied
+	// +optional
+	AppliedAt *metav1.Time `json:"appliedAt,omitempty"`
+
+	// Job is the Job that ran the script last
+	// +optional
+	Job string `json:"job,omitempty"`
+}
+
+// DatabaseMigrationStatus defines the observed state of DatabaseMigration
+type DatabaseMigrationStatus struct {
+	// Phase represents the current phase of the migrations
+	// +optional
+	Phase DatabaseMigrationPhase `json:"phase,omitempty"`
+
+	// Message provides additional information about the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Migrations lists the scripts of the source and the applied ones, in the
+	// order they run
+	// +optional
+	Migrations []MigrationScriptStatus `json:"migrations,omitempty"`
+
+	// ListedImage is the image the scripts of the image source were listed from
+	// +optional
+	ListedImage string `json:"listedImage,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed for this migration
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=dbmigration
+// +kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseRef.name`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DatabaseMigration is the Schema for the databasemigrations API. It applies
+// versioned schema migration scripts to a Database once it is ready.
+type DatabaseMigration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseMigrationSpec   `json:"spec,omitempty"`
+	Status DatabaseMigrationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatabaseMigrationList contains a list of DatabaseMigration.
+type DatabaseMigrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseMigration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseMigration{}, &DatabaseMigrationList{})
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DatabaseOpsRequestType defines the day-2 operation a DatabaseOpsRequest runs
+// +kubebuilder:validation:Enum=Restart;Failover;Compact;Resync;RotateCredentials;UpgradeApproval
+type DatabaseOpsRequestType string
+
+const (
+	// OpsRequestRestart restarts every pod of the Database with a rolling update
+	OpsRequestRestart DatabaseOpsRequestType = "Restart"
+	// OpsRequestFailover hands the primary role over to another replica
+	OpsRequestFailover DatabaseOpsRequestType = "Failover"
+	// OpsRequestCompact reclaims the space of deleted data: VACUUM ANALYZE for
+	// PostgreSQL, compact for MongoDB, an AOF rewrite for Redis and a force merge
+	// of deleted documents for Elasticsearch
+	OpsRequestCompact DatabaseOpsRequestType = "Compact"
+	// OpsRequestResync replaces the data volume of a replica, which recovers
+	// its data from the other replicas
+	OpsRequestResync DatabaseOpsRequestType = "Resync"
+	// OpsRequestRotateCredentials rotates the administrative password
+	OpsRequestRotateCredentials DatabaseOpsRequestType = "RotateCredentials"
+	// OpsRequestUpgradeApproval approves the upgrade to a new major version
+	OpsRequestUpgradeApproval DatabaseOpsRequestType = "UpgradeApproval"
+)
+
+// DatabaseOpsRequestSpec defines a day-2 operation on a Database
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+// +kubebuilder:validation:XValidation:rule="self.type != 'Resync' || has(self.resync)",message="type Resync requires resync"
+// +kubebuilder:validation:XValidation:rule="self.type != 'UpgradeApproval' || has(self.upgradeApproval)",message="type UpgradeApproval requires upgradeApproval"
+type DatabaseOpsRequestSpec struct {
+	// DatabaseRef references the Database to operate on, in the same namespace
+	DatabaseRef corev1.LocalObjectReference `json:"databaseRef"`
+
+	// Type of the operation
+	Type DatabaseOpsRequestType `json:"type"`
+
+	// Resync configures a Resync operation
+	// +optional
+	Resync *ResyncOpsRequest `json:"resync,omitempty"`
+
+	// UpgradeApproval configures an UpgradeApproval operation
+	// +optional
+	UpgradeApproval *UpgradeApprovalOpsRequest `json:"upgradeApproval,omitempty"`
+}
+
+// ResyncOpsRequest names the replica whose data volume is replaced
+type ResyncOpsRequest struct {
+	// Replica is the name of the pod, e.g. logs-data-1
+	// +kubebuilder:validation:MinLength=1
+	Replica string `json:"replica"`
+}
+
+// UpgradeApprovalOpsRequest names the approved major version
+type UpgradeApprovalOpsRequest struct {
+	// Version is the new major version, e.g. "8.0"
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+}
+
+// DatabaseOpsRequestPhase defines the phase of an ops request
+type DatabaseOpsRequestPhase string
+
+const (
+	DatabaseOpsRequestPhasePending   DatabaseOpsRequestPhase = "Pending"
+	DatabaseOpsRequestPhaseRunning   DatabaseOpsRequestPhase = "Running"
+	DatabaseOpsRequestPhaseSucceeded DatabaseOpsRequestPhase = "Succeeded"
+	DatabaseOpsRequestPhaseFailed    DatabaseOpsRequestPhase = "Failed"
+)
+
+// DatabaseOpsRequestStatus defines the observed state of DatabaseOpsRequest
+type DatabaseOpsRequestStatus struct {
+	// Phase represents the current phase of the operation. Pending requests
+	// wait for the earlier requests of the Database, or for its disruptive
+	// operation in progress, to finish.
+	// +optional
+	Phase DatabaseOpsRequestPhase `json:"phase,omitempty"`
+
+	// Message provides additional information about the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// StartTime is when the operation started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the operation finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// PreviousPrimary is the replica a Failover moved the primary role from
+	// +optional
+	PreviousPrimary string `json:"previousPrimary,omitempty"`
+
+	// PreviousClaimUID is the UID of the data volume claim a Resync replaced
+	// +optional
+	PreviousClaimUID types.UID `json:"previousClaimUID,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=dbops
+// +kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseRef.name`
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DatabaseOpsRequest is the Schema for the databaseopsrequests API. It runs a
+// day-2 operation on a Database once; the requests of a Database run one at a
+// time, in the order they were created.
+type DatabaseOpsRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseOpsRequestSpec   `json:"spec,omitempty"`
+	Status DatabaseOpsRequestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatabaseOpsRequestList contains a list of DatabaseOpsRequest.
+type DatabaseOpsRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseOpsRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseOpsRequest{}, &DatabaseOpsRequestList{})
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ForkedFromLabel is set on a Database created by a fork restore, with the
+	// name of the Database it was forked from as value
+	ForkedFromLabel = "databases.database-operator.io/forked-from"
+
+	// ForkRestoreAnnotation names the DatabaseRestore that created a forked Database
+	ForkRestoreAnnotation = "databases.database-operator.io/fork-restore"
+)
+
+// DatabaseRestoreSpec defines a restore of a backup into a Database
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+// +kubebuilder:validation:XValidation:rule="!has(self.pointInTime) || has(self.source.walArchive)",message="pointInTime requires a walArchive source"
+// +kubebuilder:validation:XValidation:rule="!has(self.fork) || !has(self.source.s3) || has(self.fork.from)",message="forking an s3 source requires fork.from"
+// +kubebuilder:validation:XValidation:rule="!has(self.performanceMode) || !self.performanceMode || !has(self.source.walArchive)",message="performanceMode does not apply to walArchive sources"
+type DatabaseRestoreSpec struct {
+	// DatabaseRef references the Database to restore into, in the same namespace
+	DatabaseRef corev1.LocalObjectReference `json:"databaseRef"`
+
+	// Source is the backup to restore
+	Source RestoreSource `json:"source"`
+
+	// PointInTime restores the database as it was at the given time. It requires a
+	// walArchive source; without it the archive is replayed to its end.
+	// +optional
+	PointInTime *metav1.Time `json:"pointInTime,omitempty"`
+
+	// Fork creates the Database of databaseRef, which must not exist yet, from the
+	// spec of the Database the backup was taken from, and restores into it. The
+	// origin is left untouched, for recovery drills or clones.
+	// +optional
+	Fork *ForkSpec `json:"fork,omitempty"`
+
+	// PerformanceMode relaxes the durability settings of the engine while the
+	// backup is loaded, for faster restores of large dumps: fsync, full page
+	// writes and synchronous commits are turned off and maintenance_work_mem is
+	// raised for PostgreSQL until the restore Job finishes; MongoDB writes
+	// without waiting for the journal. A crash in the meantime can corrupt the
+	// database, which is being overwritten anyway.
+	// +optional
+	PerformanceMode bool `json:"performanceMode,omitempty"`
+}
+
+// ForkSpec defines the Database a fork restore creates
+type ForkSpec struct {
+	// From references the Database whose spec is copied (default: the Database
+	// of the DatabaseBackup or of the WAL archive). Required for s3 sources.
+	// +optional
+	From *corev1.LocalObjectReference `json:"from,omitempty"`
+
+	// Replicas overrides the replica count and the replica schedules of the origin
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+}
+
+// RestoreSource defines where the restored backup comes from. Exactly one of
+// backupRef, s3 and walArchive must be set.
+// +kubebuilder:validation:XValidation:rule="(has(self.backupRef) ? 1 : 0) + (has(self.s3) ? 1 : 0) + (has(self.walArchive) ? 1 : 0) == 1",message="exactly one of backupRef, s3 and walArchive must be set"
+type RestoreSource struct {
+	// BackupRef references a completed DatabaseBackup in the same namespace
+	// +optional
+	BackupRef *corev1.LocalObjectReference `json:"backupRef,omitempty"`
+
+	// S3 downloads the backup from S3 compatible object storage
+	// +optional
+	S3 *S3Source `json:"s3,omitempty"`
+
+	// WALArchive recovers a PostgreSQL Database from the WAL archive of a Database
+	// with the WAL backup method: the latest base backup taken before pointInTime
+	// is fetched and the archived WAL is replayed up to pointInTime
+	// +optional
+	WALArchive *WALArchiveSource `json:"walArchive,omitempty"`
+}
+
+// WALArchiveSource defines the WAL archive a point-in-time recovery replays
+type WALArchiveSource struct {
+	// DatabaseRef references the Database whose archive is replayed (default: the
+	// restored Database, or fork.from for a fork)
+	// +optional
+	DatabaseRef *corev1.LocalObjectReference `json:"databaseRef,omitempty"`
+
+	// Pod is the pod whose archive is replayed, since every pod archives under its
+	// own prefix (default: <database>-0)
+	// +optional
+	Pod string `json:"pod,omitempty"`
+}
+
+// S3Source defines a backup file in S3 compatible object storage
+type S3Source struct {
+	// URI of the backup file, s3://<bucket>/<key>
+	// +kubebuilder:validation:Pattern=`^s3://[^/]+/.+`
+	URI string `json:"uri"`
+
+	// Endpoint overrides the S3 endpoint, for S3 compatible storage
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region of the bucket
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// CredentialsSecret is the name of a Secret holding the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY keys
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// Image provides the aws CLI used to download the backup (default: amazon/aws-cli)
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// DatabaseRestorePhase defines the phase of a restore
+type DatabaseRestorePhase string
+
+const (
+	DatabaseRestorePhasePending   DatabaseRestorePhase = "Pending"
+	DatabaseRestorePhaseQueued    DatabaseRestorePhase = "Queued"
+	DatabaseRestorePhaseRunning   DatabaseRestorePhase = "Running"
+	DatabaseRestorePhaseCompleted DatabaseRestorePhase = "Completed"
+	DatabaseRestorePhaseFailed    DatabaseRestorePhase = "Failed"
+)
+
+// DatabaseRestoreStatus defines the observed state of DatabaseRestore
+type DatabaseRestoreStatus struct {
+	// Phase represents the current phase of the restore. Queued restores wait for
+	// another disruptive operation of the Database to finish.
+	// +optional
+	Phase DatabaseRestorePhase `json:"phase,omitempty"`
+
+	// Progress describes the step the running restore is at
+	// +optional
+	Progress string `json:"progress,omitempty"`
+
+	// JobName is the name of the Job running the restore
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// StartTime is when the restore Job was created
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the restore Job finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Message provides additional information about the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// PerformanceMode records the engine settings relaxed by the restore and
+	// when they were reverted
+	// +optional
+	PerformanceMode *RestorePerformanceStatus `json:"performanceMode,omitempty"`
+
+	// ConnectionLimit records the connection limit of a PostgreSQL database that
+	// the restore lowered to 0, and when it was put back
+	// +optional
+	ConnectionLimit *RestoreConnectionLimitStatus `json:"connectionLimit,omitempty"`
+}
+
+// RestorePerformanceStatus records the settings relaxed by a restore in performance mode
+type RestorePerformanceStatus struct {
+	// Settings relaxed for the restore, e.g. fsync=off
+	Settings []string `json:"settings"`
+
+	// AppliedAt is when the restore Job relaxing them was created
+	// +optional
+	AppliedAt *metav1.Time `json:"appliedAt,omitempty"`
+
+	// RevertedAt is when the settings were reverted. Settings scoped to the
+	// restore session are reverted when its Job finishes.
+	// +optional
+	RevertedAt *metav1.Time `json:"revertedAt,omitempty"`
+}
+
+// RestoreConnectionLimitStatus records the connection limit lowered by a restore
+type RestoreConnectionLimitStatus struct {
+	// Previous is the connection limit before the restore, -1 for no limit
+	Previous int32 `json:"previous"`
+
+	// RestoredAt is when the previous limit was put back, once the restore Job
+	// finished or the restore was deleted
+	// +optional
+	RestoredAt *metav1.Time `json:"restoredAt,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=dbrestore
+// +kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseRef.name`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Progress",type=string,JSONPath=`.status.progress`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DatabaseRestore is the Schema for the databaserestores API. It restores a backup
+// into a Database once.
+type DatabaseRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseRestoreSpec   `json:"spec,omitempty"`
+	Status DatabaseRestoreStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatabaseRestoreList contains a list of DatabaseRestore.
+type DatabaseRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseRestore{}, &DatabaseRestoreList{})
+}
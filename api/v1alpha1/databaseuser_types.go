@@ -0,0 +1,120 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseUserSpec defines a user of a Database, created, updated and dropped
+// inside the engine by the operator
+// +kubebuilder:validation:XValidation:rule="self.databaseRef == oldSelf.databaseRef && self.username == oldSelf.username",message="databaseRef and username are immutable"
+type DatabaseUserSpec struct {
+	// DatabaseRef references the Database the user belongs to, in the same namespace
+	DatabaseRef corev1.LocalObjectReference `json:"databaseRef"`
+
+	// Username is the name of the user in the engine
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Username string `json:"username"`
+
+	// PasswordSecret holds the password of the user. Without it a random
+	// password is generated into the Secret of secretName.
+	// +optional
+	PasswordSecret *SecretReference `json:"passwordSecret,omitempty"`
+
+	// SecretName is the Secret the generated credentials are written to, with
+	// the username, password, host and port keys (default: the name of the
+	// DatabaseUser)
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Grants lists the privileges of the user per database
+	// +optional
+	Grants []BootstrapGrant `json:"grants,omitempty"`
+
+	// Roles are granted to the user: PostgreSQL roles (e.g. pg_read_all_data)
+	// or MongoDB roles on the admin database (e.g. clusterMonitor)
+	// +optional
+	Roles []BootstrapIdentifier `json:"roles,omitempty"`
+}
+
+// DatabaseUserPhase defines the phase of a DatabaseUser
+type DatabaseUserPhase string
+
+const (
+	DatabaseUserPhasePending DatabaseUserPhase = "Pending"
+	DatabaseUserPhaseReady   DatabaseUserPhase = "Ready"
+	DatabaseUserPhaseFailed  DatabaseUserPhase = "Failed"
+)
+
+// DatabaseUserStatus defines the observed state of DatabaseUser
+type DatabaseUserStatus struct {
+	// Phase represents the current phase of the user
+	// +optional
+	Phase DatabaseUserPhase `json:"phase,omitempty"`
+
+	// Message provides additional information about the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// SecretName is the Secret holding the password of the user
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// AppliedHash identifies the spec and password last applied successfully
+	// +optional
+	AppliedHash string `json:"appliedHash,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed for this user
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=dbuser
+// +kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseRef.name`
+// +kubebuilder:printcolumn:name="Username",type=string,JSONPath=`.spec.username`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secretName`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DatabaseUser is the Schema for the databaseusers API. It manages a user of a
+// Database in addition to the administrative user and the bootstrap users.
+type DatabaseUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseUserSpec   `json:"spec,omitempty"`
+	Status DatabaseUserStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatabaseUserList contains a list of DatabaseUser.
+type DatabaseUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseUser `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseUser{}, &DatabaseUserList{})
+}
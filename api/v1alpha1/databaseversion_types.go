@@ -0,0 +1,84 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseVersionSpec defines an engine version of the catalog and the image
+// it runs
+type DatabaseVersionSpec struct {
+	// Type of the engine
+	Type DatabaseType `json:"type"`
+
+	// Version is the spec.version of the Databases running the image, e.g. 16.4
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// Flavor is the image distribution the image belongs to
+	// +kubebuilder:default=Official
+	// +optional
+	Flavor ImageFlavor `json:"flavor,omitempty"`
+
+	// Image is the reference of the engine image, tagged or pinned by digest,
+	// e.g. registry.example.com/postgres:16.4@sha256:...
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// Deprecated marks a version Databases should move away from. Databases
+	// running it keep working, with a warning.
+	// +optional
+	Deprecated bool `json:"deprecated,omitempty"`
+
+	// DeprecationMessage tells the users of a deprecated version what to do,
+	// e.g. which version replaces it
+	// +optional
+	DeprecationMessage string `json:"deprecationMessage,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=dbversion
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`
+// +kubebuilder:printcolumn:name="Flavor",type=string,JSONPath=`.spec.flavor`
+// +kubebuilder:printcolumn:name="Deprecated",type=boolean,JSONPath=`.spec.deprecated`
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DatabaseVersion is the Schema for the databaseversions API. The
+// DatabaseVersions of an engine and flavor form the catalog of the versions
+// Databases may run and the images they run them with.
+type DatabaseVersion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DatabaseVersionSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DatabaseVersionList contains a list of DatabaseVersion.
+type DatabaseVersionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseVersion `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseVersion{}, &DatabaseVersionList{})
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LogicalDatabaseSpec defines a database, or a schema of a database, and the
+// user owning it inside a Database shared by several applications
+// +kubebuilder:validation:XValidation:rule="self.databaseRef == oldSelf.databaseRef && self.databaseName == oldSelf.databaseName && self.owner == oldSelf.owner && has(self.schema) == has(oldSelf.schema) && (!has(self.schema) || self.schema == oldSelf.schema)",message="databaseRef, databaseName, schema and owner are immutable"
+type LogicalDatabaseSpec struct {
+	// DatabaseRef references the Database instance hosting the database, in
+	// the same namespace
+	DatabaseRef corev1.LocalObjectReference `json:"databaseRef"`
+
+	// DatabaseName is the name of the database in the engine
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	DatabaseName string `json:"databaseName"`
+
+	// Schema creates a schema of this name owned by the owner inside
+	// databaseName, which then stays owned by the administrative user and may
+	// be shared with other LogicalDatabases (PostgreSQL only)
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Schema string `json:"schema,omitempty"`
+
+	// Owner is the name of the user owning the database or schema
+	// (PostgreSQL), or granted dbOwner on the database (MongoDB)
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Owner string `json:"owner"`
+
+	// PasswordSecret holds the password of the owner. Without it a random
+	// password is generated into the Secret of secretName.
+	// +optional
+	PasswordSecret *SecretReference `json:"passwordSecret,omitempty"`
+
+	// SecretName is the Secret the generated credentials are written to, with
+	// the username, password, host, port and database keys (default: the name
+	// of the LogicalDatabase)
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// DeletionPolicy controls what happens to the database and its owner when
+	// the LogicalDatabase is deleted. Retain keeps both; Delete drops the
+	// database, or the schema with its objects, and the owner.
+	// +kubebuilder:default=Retain
+	// +optional
+	DeletionPolicy LogicalDatabaseDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// LogicalDatabaseDeletionPolicy defines what happens to the data of a deleted
+// LogicalDatabase
+// +kubebuilder:validation:Enum=Retain;Delete
+type LogicalDatabaseDeletionPolicy string
+
+const (
+	LogicalDatabaseDeletionPolicyRetain LogicalDatabaseDeletionPolicy = "Retain"
+	LogicalDatabaseDeletionPolicyDelete LogicalDatabaseDeletionPolicy = "Delete"
+)
+
+// LogicalDatabasePhase defines the phase of a LogicalDatabase
+type LogicalDatabasePhase string
+
+const (
+	LogicalDatabasePhasePending LogicalDatabasePhase = "Pending"
+	LogicalDatabasePhaseReady   LogicalDatabasePhase = "Ready"
+	LogicalDatabasePhaseFailed  LogicalDatabasePhase = "Failed"
+)
+
+// LogicalDatabaseStatus defines the observed state of LogicalDatabase
+type LogicalDatabaseStatus struct {
+	// Phase represents the current phase of the database
+	// +optional
+	Phase LogicalDatabasePhase `json:"phase,omitempty"`
+
+	// Message provides additional information about the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// SecretName is the Secret holding the password of the owner
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// AppliedHash identifies the spec and password last applied successfully
+	// +optional
+	AppliedHash string `json:"appliedHash,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed for this database
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=ldb
+// +kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseRef.name`
+// +kubebuilder:printcolumn:name="Name",type=string,JSONPath=`.spec.databaseName`
+// +kubebuilder:printcolumn:name="Schema",type=string,JSONPath=`.spec.schema`,priority=1
+// +kubebuilder:printcolumn:name="Owner",type=string,JSONPath=`.spec.owner`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secretName`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// LogicalDatabase is the Schema for the logicaldatabases API. It provisions a
+// database and its owner inside an existing Database, so applications can
+// share one server.
+type LogicalDatabase struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LogicalDatabaseSpec   `json:"spec,omitempty"`
+	Status LogicalDatabaseStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LogicalDatabaseList contains a list of LogicalDatabase.
+type LogicalDatabaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LogicalDatabase `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LogicalDatabase{}, &LogicalDatabaseList{})
+}
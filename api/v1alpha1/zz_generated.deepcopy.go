@@ -21,369 +21,3366 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Database) DeepCopyInto(out *Database) {
+func (in *ActiveOperation) DeepCopyInto(out *ActiveOperation) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Database.
-func (in *Database) DeepCopy() *Database {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveOperation.
+func (in *ActiveOperation) DeepCopy() *ActiveOperation {
 	if in == nil {
 		return nil
 	}
-	out := new(Database)
+	out := new(ActiveOperation)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Database) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoUpgradeStatus) DeepCopyInto(out *AutoUpgradeStatus) {
+	*out = *in
+	if in.CheckedAt != nil {
+		in, out := &in.CheckedAt, &out.CheckedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.UpgradedAt != nil {
+		in, out := &in.UpgradedAt, &out.UpgradedAt
+		*out = (*in).DeepCopy()
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoUpgradeStatus.
+func (in *AutoUpgradeStatus) DeepCopy() *AutoUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DatabaseList) DeepCopyInto(out *DatabaseList) {
+func (in *BackupCopy) DeepCopyInto(out *BackupCopy) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]Database, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	out.S3 = in.S3
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(BackupRetention)
+		(*in).DeepCopyInto(*out)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseList.
-func (in *DatabaseList) DeepCopy() *DatabaseList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupCopy.
+func (in *BackupCopy) DeepCopy() *BackupCopy {
 	if in == nil {
 		return nil
 	}
-	out := new(DatabaseList)
+	out := new(BackupCopy)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DatabaseList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupEncryption) DeepCopyInto(out *BackupEncryption) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupEncryption.
+func (in *BackupEncryption) DeepCopy() *BackupEncryption {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(BackupEncryption)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DatabaseSpec) DeepCopyInto(out *DatabaseSpec) {
+func (in *BackupRetention) DeepCopyInto(out *BackupRetention) {
 	*out = *in
-	if in.Replicas != nil {
-		in, out := &in.Replicas, &out.Replicas
+	if in.MaxCount != nil {
+		in, out := &in.MaxCount, &out.MaxCount
 		*out = new(int32)
 		**out = **in
 	}
-	if in.Storage != nil {
-		in, out := &in.Storage, &out.Storage
-		*out = new(StorageSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Resources != nil {
-		in, out := &in.Resources, &out.Resources
-		*out = new(ResourceRequirements)
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(v1.Duration)
 		**out = **in
 	}
-	if in.PostgreSQL != nil {
-		in, out := &in.PostgreSQL, &out.PostgreSQL
-		*out = new(PostgreSQLConfig)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.MongoDB != nil {
-		in, out := &in.MongoDB, &out.MongoDB
-		*out = new(MongoDBConfig)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Redis != nil {
-		in, out := &in.Redis, &out.Redis
-		*out = new(RedisConfig)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupRetention.
+func (in *BackupRetention) DeepCopy() *BackupRetention {
+	if in == nil {
+		return nil
 	}
-	if in.Elasticsearch != nil {
-		in, out := &in.Elasticsearch, &out.Elasticsearch
-		*out = new(ElasticsearchConfig)
+	out := new(BackupRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSchedule) DeepCopyInto(out *BackupSchedule) {
+	*out = *in
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.SQLite != nil {
-		in, out := &in.SQLite, &out.SQLite
-		*out = new(SQLiteConfig)
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(BackupRetention)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.Env != nil {
-		in, out := &in.Env, &out.Env
-		*out = make([]EnvVar, len(*in))
+	if in.Copies != nil {
+		in, out := &in.Copies, &out.Copies
+		*out = make([]BackupCopy, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseSpec.
-func (in *DatabaseSpec) DeepCopy() *DatabaseSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSchedule.
+func (in *BackupSchedule) DeepCopy() *BackupSchedule {
 	if in == nil {
 		return nil
 	}
-	out := new(DatabaseSpec)
+	out := new(BackupSchedule)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DatabaseStatus) DeepCopyInto(out *DatabaseStatus) {
+func (in *BackupScheduleStatus) DeepCopyInto(out *BackupScheduleStatus) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseStatus.
-func (in *DatabaseStatus) DeepCopy() *DatabaseStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupScheduleStatus.
+func (in *BackupScheduleStatus) DeepCopy() *BackupScheduleStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(DatabaseStatus)
+	out := new(BackupScheduleStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ElasticsearchConfig) DeepCopyInto(out *ElasticsearchConfig) {
+func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
 	*out = *in
-	if in.NodeRoles != nil {
-		in, out := &in.NodeRoles, &out.NodeRoles
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.Parameters != nil {
-		in, out := &in.Parameters, &out.Parameters
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Destination)
+		**out = **in
+	}
+	if in.WAL != nil {
+		in, out := &in.WAL, &out.WAL
+		*out = new(WALArchivingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PgBackRest != nil {
+		in, out := &in.PgBackRest, &out.PgBackRest
+		*out = new(PgBackRestSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(BackupRetention)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Copies != nil {
+		in, out := &in.Copies, &out.Copies
+		*out = make([]BackupCopy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]BackupSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(BackupEncryption)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticsearchConfig.
-func (in *ElasticsearchConfig) DeepCopy() *ElasticsearchConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSpec.
+func (in *BackupSpec) DeepCopy() *BackupSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ElasticsearchConfig)
+	out := new(BackupSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *EnvVar) DeepCopyInto(out *EnvVar) {
+func (in *BackupStatus) DeepCopyInto(out *BackupStatus) {
 	*out = *in
-	if in.ValueFrom != nil {
-		in, out := &in.ValueFrom, &out.ValueFrom
-		*out = new(EnvVarSource)
-		(*in).DeepCopyInto(*out)
+	if in.LastSuccessfulBackup != nil {
+		in, out := &in.LastSuccessfulBackup, &out.LastSuccessfulBackup
+		*out = (*in).DeepCopy()
+	}
+	if in.LastBackupSize != nil {
+		in, out := &in.LastBackupSize, &out.LastBackupSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.NextScheduledBackup != nil {
+		in, out := &in.NextScheduledBackup, &out.NextScheduledBackup
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVar.
-func (in *EnvVar) DeepCopy() *EnvVar {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStatus.
+func (in *BackupStatus) DeepCopy() *BackupStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(EnvVar)
+	out := new(BackupStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *EnvVarSource) DeepCopyInto(out *EnvVarSource) {
+func (in *BackupVerificationStatus) DeepCopyInto(out *BackupVerificationStatus) {
 	*out = *in
-	if in.SecretKeyRef != nil {
-		in, out := &in.SecretKeyRef, &out.SecretKeyRef
-		*out = new(SecretReference)
-		**out = **in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVarSource.
-func (in *EnvVarSource) DeepCopy() *EnvVarSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationStatus.
+func (in *BackupVerificationStatus) DeepCopy() *BackupVerificationStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(EnvVarSource)
+	out := new(BackupVerificationStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MongoDBConfig) DeepCopyInto(out *MongoDBConfig) {
+func (in *BootstrapDatabase) DeepCopyInto(out *BootstrapDatabase) {
 	*out = *in
-	if in.PasswordSecret != nil {
-		in, out := &in.PasswordSecret, &out.PasswordSecret
-		*out = new(SecretReference)
-		**out = **in
-	}
-	if in.Parameters != nil {
-		in, out := &in.Parameters, &out.Parameters
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]BootstrapIdentifier, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBConfig.
-func (in *MongoDBConfig) DeepCopy() *MongoDBConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapDatabase.
+func (in *BootstrapDatabase) DeepCopy() *BootstrapDatabase {
 	if in == nil {
 		return nil
 	}
-	out := new(MongoDBConfig)
+	out := new(BootstrapDatabase)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PostgreSQLConfig) DeepCopyInto(out *PostgreSQLConfig) {
+func (in *BootstrapGrant) DeepCopyInto(out *BootstrapGrant) {
 	*out = *in
-	if in.PasswordSecret != nil {
-		in, out := &in.PasswordSecret, &out.PasswordSecret
-		*out = new(SecretReference)
-		**out = **in
-	}
-	if in.Parameters != nil {
-		in, out := &in.Parameters, &out.Parameters
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]BootstrapPrivilege, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgreSQLConfig.
-func (in *PostgreSQLConfig) DeepCopy() *PostgreSQLConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapGrant.
+func (in *BootstrapGrant) DeepCopy() *BootstrapGrant {
 	if in == nil {
 		return nil
 	}
-	out := new(PostgreSQLConfig)
+	out := new(BootstrapGrant)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RedisConfig) DeepCopyInto(out *RedisConfig) {
+func (in *BootstrapSpec) DeepCopyInto(out *BootstrapSpec) {
 	*out = *in
-	if in.PasswordSecret != nil {
-		in, out := &in.PasswordSecret, &out.PasswordSecret
-		*out = new(SecretReference)
-		**out = **in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]BootstrapDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	if in.Parameters != nil {
-		in, out := &in.Parameters, &out.Parameters
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]BootstrapUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisConfig.
-func (in *RedisConfig) DeepCopy() *RedisConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapSpec.
+func (in *BootstrapSpec) DeepCopy() *BootstrapSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(RedisConfig)
+	out := new(BootstrapSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceRequirements) DeepCopyInto(out *ResourceRequirements) {
+func (in *BootstrapStatus) DeepCopyInto(out *BootstrapStatus) {
 	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextRetry != nil {
+		in, out := &in.NextRetry, &out.NextRetry
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRequirements.
-func (in *ResourceRequirements) DeepCopy() *ResourceRequirements {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapStatus.
+func (in *BootstrapStatus) DeepCopy() *BootstrapStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceRequirements)
+	out := new(BootstrapStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SQLiteConfig) DeepCopyInto(out *SQLiteConfig) {
+func (in *BootstrapUser) DeepCopyInto(out *BootstrapUser) {
 	*out = *in
-	if in.Parameters != nil {
-		in, out := &in.Parameters, &out.Parameters
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
+	out.PasswordSecret = in.PasswordSecret
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]BootstrapGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SQLiteConfig.
-func (in *SQLiteConfig) DeepCopy() *SQLiteConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapUser.
+func (in *BootstrapUser) DeepCopy() *BootstrapUser {
 	if in == nil {
 		return nil
 	}
-	out := new(SQLiteConfig)
+	out := new(BootstrapUser)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+func (in *CatalogImageStatus) DeepCopyInto(out *CatalogImageStatus) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
-func (in *SecretReference) DeepCopy() *SecretReference {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CatalogImageStatus.
+func (in *CatalogImageStatus) DeepCopy() *CatalogImageStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretReference)
+	out := new(CatalogImageStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+func (in *CertManagerIssuerReference) DeepCopyInto(out *CertManagerIssuerReference) {
 	*out = *in
-	if in.StorageClass != nil {
-		in, out := &in.StorageClass, &out.StorageClass
-		*out = new(string)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerIssuerReference.
+func (in *CertManagerIssuerReference) DeepCopy() *CertManagerIssuerReference {
+	if in == nil {
+		return nil
 	}
+	out := new(CertManagerIssuerReference)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
-func (in *StorageSpec) DeepCopy() *StorageSpec {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerTLSSpec) DeepCopyInto(out *CertManagerTLSSpec) {
+	*out = *in
+	out.IssuerRef = in.IssuerRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerTLSSpec.
+func (in *CertManagerTLSSpec) DeepCopy() *CertManagerTLSSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(StorageSpec)
+	out := new(CertManagerTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Database) DeepCopyInto(out *Database) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Database.
+func (in *Database) DeepCopy() *Database {
+	if in == nil {
+		return nil
+	}
+	out := new(Database)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Database) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackup) DeepCopyInto(out *DatabaseBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackup.
+func (in *DatabaseBackup) DeepCopy() *DatabaseBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupList) DeepCopyInto(out *DatabaseBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupList.
+func (in *DatabaseBackupList) DeepCopy() *DatabaseBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupSpec) DeepCopyInto(out *DatabaseBackupSpec) {
+	*out = *in
+	out.DatabaseRef = in.DatabaseRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupSpec.
+func (in *DatabaseBackupSpec) DeepCopy() *DatabaseBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupStatus) DeepCopyInto(out *DatabaseBackupStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(BackupVerificationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupStatus.
+func (in *DatabaseBackupStatus) DeepCopy() *DatabaseBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseClass) DeepCopyInto(out *DatabaseClass) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseClass.
+func (in *DatabaseClass) DeepCopy() *DatabaseClass {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseClass) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseClassEngine) DeepCopyInto(out *DatabaseClassEngine) {
+	*out = *in
+	if in.Versions != nil {
+		in, out := &in.Versions, &out.Versions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseClassEngine.
+func (in *DatabaseClassEngine) DeepCopy() *DatabaseClassEngine {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseClassEngine)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseClassList) DeepCopyInto(out *DatabaseClassList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseClass, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseClassList.
+func (in *DatabaseClassList) DeepCopy() *DatabaseClassList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseClassList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseClassList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseClassSpec) DeepCopyInto(out *DatabaseClassSpec) {
+	*out = *in
+	if in.Engines != nil {
+		in, out := &in.Engines, &out.Engines
+		*out = make([]DatabaseClassEngine, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Profiles != nil {
+		in, out := &in.Profiles, &out.Profiles
+		*out = make([]DatabaseProfile, len(*in))
+		copy(*out, *in)
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(BackupSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseClassSpec.
+func (in *DatabaseClassSpec) DeepCopy() *DatabaseClassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseClassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseList) DeepCopyInto(out *DatabaseList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Database, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseList.
+func (in *DatabaseList) DeepCopy() *DatabaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMigration) DeepCopyInto(out *DatabaseMigration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMigration.
+func (in *DatabaseMigration) DeepCopy() *DatabaseMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseMigration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMigrationList) DeepCopyInto(out *DatabaseMigrationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseMigration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMigrationList.
+func (in *DatabaseMigrationList) DeepCopy() *DatabaseMigrationList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMigrationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseMigrationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMigrationSource) DeepCopyInto(out *DatabaseMigrationSource) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(MigrationImageSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMigrationSource.
+func (in *DatabaseMigrationSource) DeepCopy() *DatabaseMigrationSource {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMigrationSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMigrationSpec) DeepCopyInto(out *DatabaseMigrationSpec) {
+	*out = *in
+	out.DatabaseRef = in.DatabaseRef
+	in.Source.DeepCopyInto(&out.Source)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMigrationSpec.
+func (in *DatabaseMigrationSpec) DeepCopy() *DatabaseMigrationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMigrationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMigrationStatus) DeepCopyInto(out *DatabaseMigrationStatus) {
+	*out = *in
+	if in.Migrations != nil {
+		in, out := &in.Migrations, &out.Migrations
+		*out = make([]MigrationScriptStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMigrationStatus.
+func (in *DatabaseMigrationStatus) DeepCopy() *DatabaseMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseOpsRequest) DeepCopyInto(out *DatabaseOpsRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseOpsRequest.
+func (in *DatabaseOpsRequest) DeepCopy() *DatabaseOpsRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseOpsRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseOpsRequest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseOpsRequestList) DeepCopyInto(out *DatabaseOpsRequestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseOpsRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseOpsRequestList.
+func (in *DatabaseOpsRequestList) DeepCopy() *DatabaseOpsRequestList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseOpsRequestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseOpsRequestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseOpsRequestSpec) DeepCopyInto(out *DatabaseOpsRequestSpec) {
+	*out = *in
+	out.DatabaseRef = in.DatabaseRef
+	if in.Resync != nil {
+		in, out := &in.Resync, &out.Resync
+		*out = new(ResyncOpsRequest)
+		**out = **in
+	}
+	if in.UpgradeApproval != nil {
+		in, out := &in.UpgradeApproval, &out.UpgradeApproval
+		*out = new(UpgradeApprovalOpsRequest)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseOpsRequestSpec.
+func (in *DatabaseOpsRequestSpec) DeepCopy() *DatabaseOpsRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseOpsRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseOpsRequestStatus) DeepCopyInto(out *DatabaseOpsRequestStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseOpsRequestStatus.
+func (in *DatabaseOpsRequestStatus) DeepCopy() *DatabaseOpsRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseOpsRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestore) DeepCopyInto(out *DatabaseRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestore.
+func (in *DatabaseRestore) DeepCopy() *DatabaseRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreList) DeepCopyInto(out *DatabaseRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreList.
+func (in *DatabaseRestoreList) DeepCopy() *DatabaseRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreSpec) DeepCopyInto(out *DatabaseRestoreSpec) {
+	*out = *in
+	out.DatabaseRef = in.DatabaseRef
+	in.Source.DeepCopyInto(&out.Source)
+	if in.PointInTime != nil {
+		in, out := &in.PointInTime, &out.PointInTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Fork != nil {
+		in, out := &in.Fork, &out.Fork
+		*out = new(ForkSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreSpec.
+func (in *DatabaseRestoreSpec) DeepCopy() *DatabaseRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreStatus) DeepCopyInto(out *DatabaseRestoreStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PerformanceMode != nil {
+		in, out := &in.PerformanceMode, &out.PerformanceMode
+		*out = new(RestorePerformanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(RestoreConnectionLimitStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreStatus.
+func (in *DatabaseRestoreStatus) DeepCopy() *DatabaseRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseSpec) DeepCopyInto(out *DatabaseSpec) {
+	*out = *in
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(ImageSpec)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(TopologySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DiskPressure != nil {
+		in, out := &in.DiskPressure, &out.DiskPressure
+		*out = new(DiskPressureSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(ResourceRequirements)
+		**out = **in
+	}
+	if in.PostgreSQL != nil {
+		in, out := &in.PostgreSQL, &out.PostgreSQL
+		*out = new(PostgreSQLConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MongoDB != nil {
+		in, out := &in.MongoDB, &out.MongoDB
+		*out = new(MongoDBConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Redis != nil {
+		in, out := &in.Redis, &out.Redis
+		*out = new(RedisConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Elasticsearch != nil {
+		in, out := &in.Elasticsearch, &out.Elasticsearch
+		*out = new(ElasticsearchConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SQLite != nil {
+		in, out := &in.SQLite, &out.SQLite
+		*out = new(SQLiteConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Observability != nil {
+		in, out := &in.Observability, &out.Observability
+		*out = new(ObservabilitySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(BackupSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleDownProtection != nil {
+		in, out := &in.ScaleDownProtection, &out.ScaleDownProtection
+		*out = new(ScaleDownProtectionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Networking != nil {
+		in, out := &in.Networking, &out.Networking
+		*out = new(NetworkingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = new(ResourceMetadataSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = new(BootstrapSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RotationPolicy != nil {
+		in, out := &in.RotationPolicy, &out.RotationPolicy
+		*out = new(RotationPolicy)
+		**out = **in
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(MaintenanceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(LifecycleSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Provisioning != nil {
+		in, out := &in.Provisioning, &out.Provisioning
+		*out = new(ProvisioningSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeletionSnapshotTimeout != nil {
+		in, out := &in.DeletionSnapshotTimeout, &out.DeletionSnapshotTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TargetCluster != nil {
+		in, out := &in.TargetCluster, &out.TargetCluster
+		*out = new(TargetClusterSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseSpec.
+func (in *DatabaseSpec) DeepCopy() *DatabaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseStatus) DeepCopyInto(out *DatabaseStatus) {
+	*out = *in
+	if in.PhaseTransitionTime != nil {
+		in, out := &in.PhaseTransitionTime, &out.PhaseTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Recommendations != nil {
+		in, out := &in.Recommendations, &out.Recommendations
+		*out = make([]Recommendation, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeyspaceAnalysis != nil {
+		in, out := &in.KeyspaceAnalysis, &out.KeyspaceAnalysis
+		*out = new(KeyspaceAnalysisStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ShardAnalysis != nil {
+		in, out := &in.ShardAnalysis, &out.ShardAnalysis
+		*out = new(ShardAnalysisStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Logging != nil {
+		in, out := &in.Logging, &out.Logging
+		*out = new(LoggingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Downscale != nil {
+		in, out := &in.Downscale, &out.Downscale
+		*out = new(DownscaleStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Operations != nil {
+		in, out := &in.Operations, &out.Operations
+		*out = new(OperationsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = new(BootstrapStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(MonitoringStatus)
+		**out = **in
+	}
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(RotationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DiskUsage != nil {
+		in, out := &in.DiskUsage, &out.DiskUsage
+		*out = new(DiskUsageStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = new(HealthStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Provisioning != nil {
+		in, out := &in.Provisioning, &out.Provisioning
+		*out = new(ProvisioningStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(ImageStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CatalogImage != nil {
+		in, out := &in.CatalogImage, &out.CatalogImage
+		*out = new(CatalogImageStatus)
+		**out = **in
+	}
+	if in.MajorUpgrade != nil {
+		in, out := &in.MajorUpgrade, &out.MajorUpgrade
+		*out = new(MajorUpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StorageMigration != nil {
+		in, out := &in.StorageMigration, &out.StorageMigration
+		*out = new(StorageMigrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(TopologyStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VerticalScale != nil {
+		in, out := &in.VerticalScale, &out.VerticalScale
+		*out = new(VerticalScaleStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(MaintenanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AutoUpgrade != nil {
+		in, out := &in.AutoUpgrade, &out.AutoUpgrade
+		*out = new(AutoUpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BackupSchedules != nil {
+		in, out := &in.BackupSchedules, &out.BackupSchedules
+		*out = make([]BackupScheduleStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Backups != nil {
+		in, out := &in.Backups, &out.Backups
+		*out = new(BackupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RecentOperations != nil {
+		in, out := &in.RecentOperations, &out.RecentOperations
+		*out = make([]OperationRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseStatus.
+func (in *DatabaseStatus) DeepCopy() *DatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseUser) DeepCopyInto(out *DatabaseUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseUser.
+func (in *DatabaseUser) DeepCopy() *DatabaseUser {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseUserList) DeepCopyInto(out *DatabaseUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseUserList.
+func (in *DatabaseUserList) DeepCopy() *DatabaseUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseUserSpec) DeepCopyInto(out *DatabaseUserSpec) {
+	*out = *in
+	out.DatabaseRef = in.DatabaseRef
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.Grants != nil {
+		in, out := &in.Grants, &out.Grants
+		*out = make([]BootstrapGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]BootstrapIdentifier, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseUserSpec.
+func (in *DatabaseUserSpec) DeepCopy() *DatabaseUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseUserStatus) DeepCopyInto(out *DatabaseUserStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseUserStatus.
+func (in *DatabaseUserStatus) DeepCopy() *DatabaseUserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseUserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseVersion) DeepCopyInto(out *DatabaseVersion) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseVersion.
+func (in *DatabaseVersion) DeepCopy() *DatabaseVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseVersion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseVersion) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseVersionList) DeepCopyInto(out *DatabaseVersionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseVersion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseVersionList.
+func (in *DatabaseVersionList) DeepCopy() *DatabaseVersionList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseVersionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseVersionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseVersionSpec) DeepCopyInto(out *DatabaseVersionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseVersionSpec.
+func (in *DatabaseVersionSpec) DeepCopy() *DatabaseVersionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseVersionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskAutoExpandSpec) DeepCopyInto(out *DiskAutoExpandSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskAutoExpandSpec.
+func (in *DiskAutoExpandSpec) DeepCopy() *DiskAutoExpandSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskAutoExpandSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskPressureSpec) DeepCopyInto(out *DiskPressureSpec) {
+	*out = *in
+	if in.AutoExpand != nil {
+		in, out := &in.AutoExpand, &out.AutoExpand
+		*out = new(DiskAutoExpandSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskPressureSpec.
+func (in *DiskPressureSpec) DeepCopy() *DiskPressureSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskPressureSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskUsageStatus) DeepCopyInto(out *DiskUsageStatus) {
+	*out = *in
+	in.CheckedAt.DeepCopyInto(&out.CheckedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskUsageStatus.
+func (in *DiskUsageStatus) DeepCopy() *DiskUsageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskUsageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DownscaleStatus) DeepCopyInto(out *DownscaleStatus) {
+	*out = *in
+	if in.BlockedSince != nil {
+		in, out := &in.BlockedSince, &out.BlockedSince
+		*out = (*in).DeepCopy()
+	}
+	if in.Connections != nil {
+		in, out := &in.Connections, &out.Connections
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownscaleStatus.
+func (in *DownscaleStatus) DeepCopy() *DownscaleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DownscaleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticsearchConfig) DeepCopyInto(out *ElasticsearchConfig) {
+	*out = *in
+	if in.NodeRoles != nil {
+		in, out := &in.NodeRoles, &out.NodeRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ShardAnalysis != nil {
+		in, out := &in.ShardAnalysis, &out.ShardAnalysis
+		*out = new(ShardAnalysisSpec)
+		**out = **in
+	}
+	if in.NodeSets != nil {
+		in, out := &in.NodeSets, &out.NodeSets
+		*out = make([]ElasticsearchNodeSet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticsearchConfig.
+func (in *ElasticsearchConfig) DeepCopy() *ElasticsearchConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticsearchConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticsearchNodeSet) DeepCopyInto(out *ElasticsearchNodeSet) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]ElasticsearchNodeRole, len(*in))
+		copy(*out, *in)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(ResourceRequirements)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticsearchNodeSet.
+func (in *ElasticsearchNodeSet) DeepCopy() *ElasticsearchNodeSet {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticsearchNodeSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvVar) DeepCopyInto(out *EnvVar) {
+	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(EnvVarSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVar.
+func (in *EnvVar) DeepCopy() *EnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvVarSource) DeepCopyInto(out *EnvVarSource) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvVarSource.
+func (in *EnvVarSource) DeepCopy() *EnvVarSource {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvVarSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalDNSSpec) DeepCopyInto(out *ExternalDNSSpec) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalDNSSpec.
+func (in *ExternalDNSSpec) DeepCopy() *ExternalDNSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalDNSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForkSpec) DeepCopyInto(out *ForkSpec) {
+	*out = *in
+	if in.From != nil {
+		in, out := &in.From, &out.From
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForkSpec.
+func (in *ForkSpec) DeepCopy() *ForkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ForkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GatewayReference) DeepCopyInto(out *GatewayReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayReference.
+func (in *GatewayReference) DeepCopy() *GatewayReference {
+	if in == nil {
+		return nil
+	}
+	out := new(GatewayReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthStatus) DeepCopyInto(out *HealthStatus) {
+	*out = *in
+	if in.ConnectedReplicas != nil {
+		in, out := &in.ConnectedReplicas, &out.ConnectedReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReplicationLagSeconds != nil {
+		in, out := &in.ReplicationLagSeconds, &out.ReplicationLagSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	in.CheckedAt.DeepCopyInto(&out.CheckedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthStatus.
+func (in *HealthStatus) DeepCopy() *HealthStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HotIndexUsage) DeepCopyInto(out *HotIndexUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HotIndexUsage.
+func (in *HotIndexUsage) DeepCopy() *HotIndexUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(HotIndexUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSpec) DeepCopyInto(out *ImageSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSpec.
+func (in *ImageSpec) DeepCopy() *ImageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStatus) DeepCopyInto(out *ImageStatus) {
+	*out = *in
+	if in.ResolvedAt != nil {
+		in, out := &in.ResolvedAt, &out.ResolvedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatus.
+func (in *ImageStatus) DeepCopy() *ImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IndexShardUsage) DeepCopyInto(out *IndexShardUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IndexShardUsage.
+func (in *IndexShardUsage) DeepCopy() *IndexShardUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(IndexShardUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressSpec) DeepCopyInto(out *IngressSpec) {
+	*out = *in
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = new(GatewayReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressSpec.
+func (in *IngressSpec) DeepCopy() *IngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyUsage) DeepCopyInto(out *KeyUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyUsage.
+func (in *KeyUsage) DeepCopy() *KeyUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyspaceAnalysisSpec) DeepCopyInto(out *KeyspaceAnalysisSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyspaceAnalysisSpec.
+func (in *KeyspaceAnalysisSpec) DeepCopy() *KeyspaceAnalysisSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyspaceAnalysisSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyspaceAnalysisStatus) DeepCopyInto(out *KeyspaceAnalysisStatus) {
+	*out = *in
+	if in.LastAnalysisTime != nil {
+		in, out := &in.LastAnalysisTime, &out.LastAnalysisTime
+		*out = (*in).DeepCopy()
+	}
+	if in.TopKeys != nil {
+		in, out := &in.TopKeys, &out.TopKeys
+		*out = make([]KeyUsage, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeyCounts != nil {
+		in, out := &in.KeyCounts, &out.KeyCounts
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyspaceAnalysisStatus.
+func (in *KeyspaceAnalysisStatus) DeepCopy() *KeyspaceAnalysisStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyspaceAnalysisStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LifecycleSpec) DeepCopyInto(out *LifecycleSpec) {
+	*out = *in
+	if in.PreStopHook != nil {
+		in, out := &in.PreStopHook, &out.PreStopHook
+		*out = new(corev1.LifecycleHandler)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostStartHook != nil {
+		in, out := &in.PostStartHook, &out.PostStartHook
+		*out = new(corev1.LifecycleHandler)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LifecycleSpec.
+func (in *LifecycleSpec) DeepCopy() *LifecycleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LifecycleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogOutputSpec) DeepCopyInto(out *LogOutputSpec) {
+	*out = *in
+	if in.Properties != nil {
+		in, out := &in.Properties, &out.Properties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogOutputSpec.
+func (in *LogOutputSpec) DeepCopy() *LogOutputSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LogOutputSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogShippingSpec) DeepCopyInto(out *LogShippingSpec) {
+	*out = *in
+	in.Output.DeepCopyInto(&out.Output)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogShippingSpec.
+func (in *LogShippingSpec) DeepCopy() *LogShippingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LogShippingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoggingSpec) DeepCopyInto(out *LoggingSpec) {
+	*out = *in
+	if in.Shipping != nil {
+		in, out := &in.Shipping, &out.Shipping
+		*out = new(LogShippingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoggingSpec.
+func (in *LoggingSpec) DeepCopy() *LoggingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LoggingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoggingStatus) DeepCopyInto(out *LoggingStatus) {
+	*out = *in
+	if in.DebugUntil != nil {
+		in, out := &in.DebugUntil, &out.DebugUntil
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoggingStatus.
+func (in *LoggingStatus) DeepCopy() *LoggingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LoggingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogicalDatabase) DeepCopyInto(out *LogicalDatabase) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogicalDatabase.
+func (in *LogicalDatabase) DeepCopy() *LogicalDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(LogicalDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LogicalDatabase) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogicalDatabaseList) DeepCopyInto(out *LogicalDatabaseList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LogicalDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogicalDatabaseList.
+func (in *LogicalDatabaseList) DeepCopy() *LogicalDatabaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(LogicalDatabaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LogicalDatabaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogicalDatabaseSpec) DeepCopyInto(out *LogicalDatabaseSpec) {
+	*out = *in
+	out.DatabaseRef = in.DatabaseRef
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogicalDatabaseSpec.
+func (in *LogicalDatabaseSpec) DeepCopy() *LogicalDatabaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LogicalDatabaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogicalDatabaseStatus) DeepCopyInto(out *LogicalDatabaseStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogicalDatabaseStatus.
+func (in *LogicalDatabaseStatus) DeepCopy() *LogicalDatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LogicalDatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceSpec) DeepCopyInto(out *MaintenanceSpec) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceSpec.
+func (in *MaintenanceSpec) DeepCopy() *MaintenanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceStatus) DeepCopyInto(out *MaintenanceStatus) {
+	*out = *in
+	if in.NextChange != nil {
+		in, out := &in.NextChange, &out.NextChange
+		*out = (*in).DeepCopy()
+	}
+	if in.Deferred != nil {
+		in, out := &in.Deferred, &out.Deferred
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceStatus.
+func (in *MaintenanceStatus) DeepCopy() *MaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MajorUpgradeStatus) DeepCopyInto(out *MajorUpgradeStatus) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]MajorUpgradeVolume, len(*in))
+		copy(*out, *in)
+	}
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.CutOverAt != nil {
+		in, out := &in.CutOverAt, &out.CutOverAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MajorUpgradeStatus.
+func (in *MajorUpgradeStatus) DeepCopy() *MajorUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MajorUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MajorUpgradeVolume) DeepCopyInto(out *MajorUpgradeVolume) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MajorUpgradeVolume.
+func (in *MajorUpgradeVolume) DeepCopy() *MajorUpgradeVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(MajorUpgradeVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsSpec) DeepCopyInto(out *MetricsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsSpec.
+func (in *MetricsSpec) DeepCopy() *MetricsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationImageSource) DeepCopyInto(out *MigrationImageSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationImageSource.
+func (in *MigrationImageSource) DeepCopy() *MigrationImageSource {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationImageSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationScriptStatus) DeepCopyInto(out *MigrationScriptStatus) {
+	*out = *in
+	if in.AppliedAt != nil {
+		in, out := &in.AppliedAt, &out.AppliedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationScriptStatus.
+func (in *MigrationScriptStatus) DeepCopy() *MigrationScriptStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationScriptStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDBConfig) DeepCopyInto(out *MongoDBConfig) {
+	*out = *in
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDBConfig.
+func (in *MongoDBConfig) DeepCopy() *MongoDBConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDBConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStatus) DeepCopyInto(out *MonitoringStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStatus.
+func (in *MonitoringStatus) DeepCopy() *MonitoringStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicySpec) DeepCopyInto(out *NetworkPolicySpec) {
+	*out = *in
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSelectors != nil {
+		in, out := &in.PodSelectors, &out.PodSelectors
+		*out = make([]v1.LabelSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicySpec.
+func (in *NetworkPolicySpec) DeepCopy() *NetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkingSpec) DeepCopyInto(out *NetworkingSpec) {
+	*out = *in
+	if in.ExternalDNS != nil {
+		in, out := &in.ExternalDNS, &out.ExternalDNS
+		*out = new(ExternalDNSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(NetworkPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxySpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkingSpec.
+func (in *NetworkingSpec) DeepCopy() *NetworkingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectMetadata) DeepCopyInto(out *ObjectMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectMetadata.
+func (in *ObjectMetadata) DeepCopy() *ObjectMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObservabilitySpec) DeepCopyInto(out *ObservabilitySpec) {
+	*out = *in
+	if in.Logging != nil {
+		in, out := &in.Logging, &out.Logging
+		*out = new(LoggingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(MetricsSpec)
+		**out = **in
+	}
+	if in.SlowQuery != nil {
+		in, out := &in.SlowQuery, &out.SlowQuery
+		*out = new(SlowQuerySpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObservabilitySpec.
+func (in *ObservabilitySpec) DeepCopy() *ObservabilitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ObservabilitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationRecord) DeepCopyInto(out *OperationRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationRecord.
+func (in *OperationRecord) DeepCopy() *OperationRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationsStatus) DeepCopyInto(out *OperationsStatus) {
+	*out = *in
+	if in.Active != nil {
+		in, out := &in.Active, &out.Active
+		*out = new(ActiveOperation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Pending != nil {
+		in, out := &in.Pending, &out.Pending
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationsStatus.
+func (in *OperationsStatus) DeepCopy() *OperationsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgBackRestRepo) DeepCopyInto(out *PgBackRestRepo) {
+	*out = *in
+	if in.ProcessMax != nil {
+		in, out := &in.ProcessMax, &out.ProcessMax
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgBackRestRepo.
+func (in *PgBackRestRepo) DeepCopy() *PgBackRestRepo {
+	if in == nil {
+		return nil
+	}
+	out := new(PgBackRestRepo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgBackRestSpec) DeepCopyInto(out *PgBackRestSpec) {
+	*out = *in
+	if in.FullInterval != nil {
+		in, out := &in.FullInterval, &out.FullInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DifferentialInterval != nil {
+		in, out := &in.DifferentialInterval, &out.DifferentialInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.IncrementalInterval != nil {
+		in, out := &in.IncrementalInterval, &out.IncrementalInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DifferentialRetention != nil {
+		in, out := &in.DifferentialRetention, &out.DifferentialRetention
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Repo != nil {
+		in, out := &in.Repo, &out.Repo
+		*out = new(PgBackRestRepo)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgBackRestSpec.
+func (in *PgBackRestSpec) DeepCopy() *PgBackRestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PgBackRestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgreSQLConfig) DeepCopyInto(out *PostgreSQLConfig) {
+	*out = *in
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgreSQLConfig.
+func (in *PostgreSQLConfig) DeepCopy() *PostgreSQLConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgreSQLConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionedResource) DeepCopyInto(out *ProvisionedResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionedResource.
+func (in *ProvisionedResource) DeepCopy() *ProvisionedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningSpec) DeepCopyInto(out *ProvisioningSpec) {
+	*out = *in
+	if in.MaxAttempts != nil {
+		in, out := &in.MaxAttempts, &out.MaxAttempts
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningSpec.
+func (in *ProvisioningSpec) DeepCopy() *ProvisioningSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningStatus) DeepCopyInto(out *ProvisioningStatus) {
+	*out = *in
+	if in.Created != nil {
+		in, out := &in.Created, &out.Created
+		*out = make([]ProvisionedResource, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningStatus.
+func (in *ProvisioningStatus) DeepCopy() *ProvisioningStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxySpec) DeepCopyInto(out *ProxySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxySpec.
+func (in *ProxySpec) DeepCopy() *ProxySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Recommendation) DeepCopyInto(out *Recommendation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Recommendation.
+func (in *Recommendation) DeepCopy() *Recommendation {
+	if in == nil {
+		return nil
+	}
+	out := new(Recommendation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisConfig) DeepCopyInto(out *RedisConfig) {
+	*out = *in
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.KeyspaceAnalysis != nil {
+		in, out := &in.KeyspaceAnalysis, &out.KeyspaceAnalysis
+		*out = new(KeyspaceAnalysisSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisConfig.
+func (in *RedisConfig) DeepCopy() *RedisConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaSchedule) DeepCopyInto(out *ReplicaSchedule) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaSchedule.
+func (in *ReplicaSchedule) DeepCopy() *ReplicaSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceMetadataSpec) DeepCopyInto(out *ResourceMetadataSpec) {
+	*out = *in
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ObjectMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Workload != nil {
+		in, out := &in.Workload, &out.Workload
+		*out = new(ObjectMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = new(ObjectMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PersistentVolumeClaims != nil {
+		in, out := &in.PersistentVolumeClaims, &out.PersistentVolumeClaims
+		*out = new(ObjectMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceMetadataSpec.
+func (in *ResourceMetadataSpec) DeepCopy() *ResourceMetadataSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceMetadataSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceRequirements) DeepCopyInto(out *ResourceRequirements) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRequirements.
+func (in *ResourceRequirements) DeepCopy() *ResourceRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreConnectionLimitStatus) DeepCopyInto(out *RestoreConnectionLimitStatus) {
+	*out = *in
+	if in.RestoredAt != nil {
+		in, out := &in.RestoredAt, &out.RestoredAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreConnectionLimitStatus.
+func (in *RestoreConnectionLimitStatus) DeepCopy() *RestoreConnectionLimitStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreConnectionLimitStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestorePerformanceStatus) DeepCopyInto(out *RestorePerformanceStatus) {
+	*out = *in
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppliedAt != nil {
+		in, out := &in.AppliedAt, &out.AppliedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.RevertedAt != nil {
+		in, out := &in.RevertedAt, &out.RevertedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestorePerformanceStatus.
+func (in *RestorePerformanceStatus) DeepCopy() *RestorePerformanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RestorePerformanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreSource) DeepCopyInto(out *RestoreSource) {
+	*out = *in
+	if in.BackupRef != nil {
+		in, out := &in.BackupRef, &out.BackupRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Source)
+		**out = **in
+	}
+	if in.WALArchive != nil {
+		in, out := &in.WALArchive, &out.WALArchive
+		*out = new(WALArchiveSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreSource.
+func (in *RestoreSource) DeepCopy() *RestoreSource {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResyncOpsRequest) DeepCopyInto(out *ResyncOpsRequest) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResyncOpsRequest.
+func (in *ResyncOpsRequest) DeepCopy() *ResyncOpsRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(ResyncOpsRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutSpec) DeepCopyInto(out *RolloutSpec) {
+	*out = *in
+	if in.CanaryPeriod != nil {
+		in, out := &in.CanaryPeriod, &out.CanaryPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutSpec.
+func (in *RolloutSpec) DeepCopy() *RolloutSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.CanaryReadyAt != nil {
+		in, out := &in.CanaryReadyAt, &out.CanaryReadyAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotationPolicy) DeepCopyInto(out *RotationPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotationPolicy.
+func (in *RotationPolicy) DeepCopy() *RotationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RotationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotationStatus) DeepCopyInto(out *RotationStatus) {
+	*out = *in
+	if in.NextRotation != nil {
+		in, out := &in.NextRotation, &out.NextRotation
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRotation != nil {
+		in, out := &in.LastRotation, &out.LastRotation
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotationStatus.
+func (in *RotationStatus) DeepCopy() *RotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Destination) DeepCopyInto(out *S3Destination) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Destination.
+func (in *S3Destination) DeepCopy() *S3Destination {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Destination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Source) DeepCopyInto(out *S3Source) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Source.
+func (in *S3Source) DeepCopy() *S3Source {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Source)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SQLiteConfig) DeepCopyInto(out *SQLiteConfig) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SQLiteConfig.
+func (in *SQLiteConfig) DeepCopy() *SQLiteConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SQLiteConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleDownProtectionSpec) DeepCopyInto(out *ScaleDownProtectionSpec) {
+	*out = *in
+	if in.DrainTimeout != nil {
+		in, out := &in.DrainTimeout, &out.DrainTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleDownProtectionSpec.
+func (in *ScaleDownProtectionSpec) DeepCopy() *ScaleDownProtectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleDownProtectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShardAnalysisSpec) DeepCopyInto(out *ShardAnalysisSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShardAnalysisSpec.
+func (in *ShardAnalysisSpec) DeepCopy() *ShardAnalysisSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ShardAnalysisSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ShardAnalysisStatus) DeepCopyInto(out *ShardAnalysisStatus) {
+	*out = *in
+	if in.LastAnalysisTime != nil {
+		in, out := &in.LastAnalysisTime, &out.LastAnalysisTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OversizedIndices != nil {
+		in, out := &in.OversizedIndices, &out.OversizedIndices
+		*out = make([]IndexShardUsage, len(*in))
+		copy(*out, *in)
+	}
+	if in.TunedIndices != nil {
+		in, out := &in.TunedIndices, &out.TunedIndices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HotIndices != nil {
+		in, out := &in.HotIndices, &out.HotIndices
+		*out = make([]HotIndexUsage, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ShardAnalysisStatus.
+func (in *ShardAnalysisStatus) DeepCopy() *ShardAnalysisStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ShardAnalysisStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SlowQuerySpec) DeepCopyInto(out *SlowQuerySpec) {
+	*out = *in
+	if in.Threshold != nil {
+		in, out := &in.Threshold, &out.Threshold
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SlowQuerySpec.
+func (in *SlowQuerySpec) DeepCopy() *SlowQuerySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SlowQuerySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageMigrationStatus) DeepCopyInto(out *StorageMigrationStatus) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]StorageMigrationVolume, len(*in))
+		copy(*out, *in)
+	}
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.CutOverAt != nil {
+		in, out := &in.CutOverAt, &out.CutOverAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageMigrationStatus.
+func (in *StorageMigrationStatus) DeepCopy() *StorageMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageMigrationVolume) DeepCopyInto(out *StorageMigrationVolume) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageMigrationVolume.
+func (in *StorageMigrationVolume) DeepCopy() *StorageMigrationVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageMigrationVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+	*out = *in
+	if in.StorageClass != nil {
+		in, out := &in.StorageClass, &out.StorageClass
+		*out = new(string)
+		**out = **in
+	}
+	if in.SnapshotClassName != nil {
+		in, out := &in.SnapshotClassName, &out.SnapshotClassName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
+func (in *StorageSpec) DeepCopy() *StorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSpec) DeepCopyInto(out *TLSSpec) {
+	*out = *in
+	if in.CertManager != nil {
+		in, out := &in.CertManager, &out.CertManager
+		*out = new(CertManagerTLSSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSpec.
+func (in *TLSSpec) DeepCopy() *TLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetClusterSpec) DeepCopyInto(out *TargetClusterSpec) {
+	*out = *in
+	out.KubeconfigSecret = in.KubeconfigSecret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetClusterSpec.
+func (in *TargetClusterSpec) DeepCopy() *TargetClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetClusterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologySpec) DeepCopyInto(out *TopologySpec) {
+	*out = *in
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]ReplicaSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ZoneSpread != nil {
+		in, out := &in.ZoneSpread, &out.ZoneSpread
+		*out = new(ZoneSpreadSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologySpec.
+func (in *TopologySpec) DeepCopy() *TopologySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyStatus) DeepCopyInto(out *TopologyStatus) {
+	*out = *in
+	if in.NextChange != nil {
+		in, out := &in.NextChange, &out.NextChange
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyStatus.
+func (in *TopologyStatus) DeepCopy() *TopologyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeApprovalOpsRequest) DeepCopyInto(out *UpgradeApprovalOpsRequest) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeApprovalOpsRequest.
+func (in *UpgradeApprovalOpsRequest) DeepCopy() *UpgradeApprovalOpsRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeApprovalOpsRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerticalScaleStatus) DeepCopyInto(out *VerticalScaleStatus) {
+	*out = *in
+	if in.SwitchoverAt != nil {
+		in, out := &in.SwitchoverAt, &out.SwitchoverAt
+		*out = (*in).DeepCopy()
+	}
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerticalScaleStatus.
+func (in *VerticalScaleStatus) DeepCopy() *VerticalScaleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VerticalScaleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WALArchiveSource) DeepCopyInto(out *WALArchiveSource) {
+	*out = *in
+	if in.DatabaseRef != nil {
+		in, out := &in.DatabaseRef, &out.DatabaseRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WALArchiveSource.
+func (in *WALArchiveSource) DeepCopy() *WALArchiveSource {
+	if in == nil {
+		return nil
+	}
+	out := new(WALArchiveSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WALArchivingSpec) DeepCopyInto(out *WALArchivingSpec) {
+	*out = *in
+	if in.BaseBackupInterval != nil {
+		in, out := &in.BaseBackupInterval, &out.BaseBackupInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WALArchivingSpec.
+func (in *WALArchivingSpec) DeepCopy() *WALArchivingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WALArchivingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneSpreadSpec) DeepCopyInto(out *ZoneSpreadSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneSpreadSpec.
+func (in *ZoneSpreadSpec) DeepCopy() *ZoneSpreadSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneSpreadSpec)
 	in.DeepCopyInto(out)
 	return out
 }
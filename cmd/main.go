@@ -17,10 +17,14 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
 	"path/filepath"
+	"time"
+	// Embed the time zone database for the replica schedules of distroless images
+	_ "time/tzdata"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,9 +32,11 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -39,6 +45,7 @@ import (
 
 	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
 	"github.com/ivikasavnish/database-crd/internal/controller"
+	webhookdatabasesv1alpha1 "github.com/ivikasavnish/database-crd/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -61,6 +68,14 @@ func main() {
 	var webhookCertPath, webhookCertName, webhookCertKey string
 	var enableLeaderElection bool
 	var probeAddr string
+	var adminAPIAddr, adminAPICertPath string
+	var caBundlePath string
+	var jobProxy controller.ProxyConfig
+	var fleet bool
+	var tracingEndpoint string
+	var tracingInsecure bool
+	var concurrency controller.Concurrency
+	var engineWorkers string
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
@@ -81,6 +96,38 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&adminAPIAddr, "admin-api-bind-address", "0", "The address the database admin API binds to. "+
+		"Use :8444 to serve it over HTTPS to the aggregation layer, or leave as 0 to disable it.")
+	flag.StringVar(&adminAPICertPath, "admin-api-cert-path", "",
+		"The directory that contains the admin API certificate (tls.crt and tls.key). A self-signed "+
+			"certificate is generated when empty.")
+	flag.StringVar(&caBundlePath, "ca-bundle-path", "",
+		"Path of a PEM CA bundle trusted for outbound TLS, e.g. behind a TLS-intercepting proxy. It is added "+
+			"to the system roots of the operator and mounted into pods reaching S3.")
+	flag.StringVar(&jobProxy.HTTPProxy, "job-http-proxy", "",
+		"HTTP_PROXY of generated Jobs. Databases may override the proxy in spec.networking.proxy.")
+	flag.StringVar(&jobProxy.HTTPSProxy, "job-https-proxy", "", "HTTPS_PROXY of generated Jobs.")
+	flag.StringVar(&jobProxy.NoProxy, "job-no-proxy", "",
+		"NO_PROXY of generated Jobs. The database Service is always added.")
+	flag.BoolVar(&fleet, "fleet", false,
+		"Reconcile Databases with a targetCluster in the cluster of their kubeconfig Secret.")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "",
+		"host:port of an OTLP gRPC collector receiving a trace of every reconcile. Tracing is disabled when empty.")
+	flag.BoolVar(&tracingInsecure, "tracing-insecure", false,
+		"Connect to the tracing endpoint without TLS.")
+	flag.IntVar(&concurrency.MaxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Number of Databases reconciled at once.")
+	flag.StringVar(&engineWorkers, "engine-workers", "",
+		"Caps of the workers reconciling Databases of an engine, e.g. PostgreSQL=4,Elasticsearch=2. "+
+			"Engines without a cap may use every worker.")
+	flag.DurationVar(&concurrency.BaseDelay, "reconcile-base-delay", 5*time.Millisecond,
+		"Delay before retrying a failed reconcile, doubled on every failure.")
+	flag.DurationVar(&concurrency.MaxDelay, "reconcile-max-delay", 1000*time.Second,
+		"Maximum delay before retrying a failed reconcile.")
+	flag.Float64Var(&concurrency.QPS, "reconcile-qps", 10,
+		"Rate of reconciles over all Databases.")
+	flag.IntVar(&concurrency.Burst, "reconcile-burst", 100,
+		"Burst of reconciles over all Databases.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -202,15 +249,127 @@ func main() {
 		os.Exit(1)
 	}
 
+	var caBundle []byte
+	if caBundlePath != "" {
+		setupLog.Info("Trusting custom CA bundle", "path", caBundlePath)
+		if caBundle, err = controller.LoadCABundle(caBundlePath); err != nil {
+			setupLog.Error(err, "unable to load CA bundle")
+			os.Exit(1)
+		}
+	}
+	httpClient := controller.HTTPClient(caBundle)
+	options := controller.ReconcilerOptions{CABundle: caBundle, HTTPClient: httpClient, Proxy: jobProxy}
+
+	if concurrency.EngineWorkers, err = controller.ParseEngineWorkers(engineWorkers); err != nil {
+		setupLog.Error(err, "invalid engine workers")
+		os.Exit(1)
+	}
+
 	if err = (&controller.DatabaseReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:            client.WithFieldOwner(mgr.GetClient(), controller.FieldOwner),
+		Scheme:            mgr.GetScheme(),
+		ReconcilerOptions: options,
+		Fleet:             fleet,
+		Concurrency:       concurrency,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Database")
 		os.Exit(1)
 	}
+	if err = (&controller.DatabaseBackupReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		ReconcilerOptions: options,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DatabaseBackup")
+		os.Exit(1)
+	}
+	if err = (&controller.DatabaseRestoreReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		ReconcilerOptions: options,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DatabaseRestore")
+		os.Exit(1)
+	}
+	if err = (&controller.DatabaseUserReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		ReconcilerOptions: options,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DatabaseUser")
+		os.Exit(1)
+	}
+	if err = (&controller.DatabaseMigrationReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		ReconcilerOptions: options,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DatabaseMigration")
+		os.Exit(1)
+	}
+	if err = (&controller.DatabaseOpsRequestReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		ReconcilerOptions: options,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DatabaseOpsRequest")
+		os.Exit(1)
+	}
+	if err = (&controller.LogicalDatabaseReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		ReconcilerOptions: options,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "LogicalDatabase")
+		os.Exit(1)
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookdatabasesv1alpha1.SetupDatabaseWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Database")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if adminAPIAddr != "0" {
+		adminAPITLSOpts := tlsOpts
+		if len(adminAPICertPath) > 0 {
+			adminAPICertWatcher, err := certwatcher.New(
+				filepath.Join(adminAPICertPath, "tls.crt"),
+				filepath.Join(adminAPICertPath, "tls.key"),
+			)
+			if err != nil {
+				setupLog.Error(err, "Failed to initialize admin API certificate watcher")
+				os.Exit(1)
+			}
+			if err := mgr.Add(adminAPICertWatcher); err != nil {
+				setupLog.Error(err, "unable to add admin API certificate watcher to manager")
+				os.Exit(1)
+			}
+			adminAPITLSOpts = append(adminAPITLSOpts, func(config *tls.Config) {
+				config.GetCertificate = adminAPICertWatcher.GetCertificate
+			})
+		}
+
+		kubeClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			setupLog.Error(err, "unable to create admin API client")
+			os.Exit(1)
+		}
+		if err := mgr.Add(&controller.AdminAPIServer{
+			Client:      mgr.GetClient(),
+			APIReader:   mgr.GetAPIReader(),
+			HTTPClient:  httpClient,
+			KubeClient:  kubeClient,
+			BindAddress: adminAPIAddr,
+			TLSOpts:     adminAPITLSOpts,
+		}); err != nil {
+			setupLog.Error(err, "unable to add admin API server to manager")
+			os.Exit(1)
+		}
+	}
+
 	if metricsCertWatcher != nil {
 		setupLog.Info("Adding metrics certificate watcher to manager")
 		if err := mgr.Add(metricsCertWatcher); err != nil {
@@ -236,8 +395,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
+	if tracingEndpoint != "" {
+		setupLog.Info("Exporting reconcile traces", "endpoint", tracingEndpoint)
+		shutdownTracing, err := controller.SetupTracing(ctx, tracingEndpoint, tracingInsecure)
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+		defer func() {
+			// Flush the spans of the last reconciles
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(shutdownCtx); err != nil {
+				setupLog.Error(err, "problem flushing traces")
+			}
+		}()
+	}
+
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
@@ -0,0 +1,429 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/authenticatorfactory"
+	"k8s.io/apiserver/pkg/authentication/request/headerrequest"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/authorization/authorizerfactory"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	"k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+const (
+	// adminSubresource is the subresource callers need "get" access to
+	adminSubresource = "admin"
+	adminAPITimeout  = 10 * time.Second
+	adminAPIVersion  = "v1alpha1"
+
+	// authenticationConfigMap publishes the client CA and the headers of the
+	// front proxy of the aggregation layer
+	authenticationConfigMap   = "extension-apiserver-authentication"
+	authenticationNamespace   = "kube-system"
+	delegatedAuthCacheTTL     = 10 * time.Second
+	delegatedAuthDenyCacheTTL = 5 * time.Second
+)
+
+// adminAPIGroup is the group the admin API is registered under as an APIService
+var adminAPIGroup = "admin." + databasesv1alpha1.GroupVersion.Group
+
+// adminOperation runs a read-only admin query against a database
+type adminOperation func(ctx context.Context, conn adminConnection) (any, error)
+
+// adminConnection holds what an admin operation needs to reach the database
+type adminConnection struct {
+	host     string
+	user     string
+	password string
+	database string
+	// tls is set when the database serves TLS
+	tls *tls.Config
+	// httpClient sends the requests to HTTP engines
+	httpClient *http.Client
+}
+
+// Safe, read-only admin operations exposed per engine. Query text of other sessions
+// is deliberately left out of the PostgreSQL views.
+var adminOperations = map[databasesv1alpha1.DatabaseType]map[string]adminOperation{
+	databasesv1alpha1.DatabaseTypeElasticsearch: {
+		"cluster-health": func(ctx context.Context, conn adminConnection) (any, error) {
+			return elasticsearchGet(ctx, conn, "/_cluster/health")
+		},
+		"indices": func(ctx context.Context, conn adminConnection) (any, error) {
+			return elasticsearchGet(ctx, conn, "/_cat/indices?format=json&bytes=b")
+		},
+	},
+	databasesv1alpha1.DatabaseTypePostgreSQL: {
+		"stat-activity": func(ctx context.Context, conn adminConnection) (any, error) {
+			return postgresQuery(ctx, conn,
+				"SELECT pid, datname, usename, application_name, client_addr, backend_start, state, "+
+					"wait_event_type, wait_event FROM pg_stat_activity")
+		},
+		"stat-database": func(ctx context.Context, conn adminConnection) (any, error) {
+			return postgresQuery(ctx, conn,
+				"SELECT datname, numbackends, xact_commit, xact_rollback, blks_read, blks_hit, "+
+					"tup_returned, tup_fetched, tup_inserted, tup_updated, tup_deleted, deadlocks "+
+					"FROM pg_stat_database WHERE datname IS NOT NULL")
+		},
+		"stat-replication": func(ctx context.Context, conn adminConnection) (any, error) {
+			return postgresQuery(ctx, conn,
+				"SELECT application_name, client_addr, state, sync_state, sent_lsn, replay_lsn, replay_lag "+
+					"FROM pg_stat_replication")
+		},
+	},
+	databasesv1alpha1.DatabaseTypeRedis: {
+		"info": func(ctx context.Context, conn adminConnection) (any, error) {
+			return redisInfo(ctx, conn)
+		},
+	},
+}
+
+// AdminAPIServer serves read-only admin operations of each Database, so
+// dashboards can query database internals without holding database
+// credentials. It is registered with the aggregation layer as the APIService
+// of adminAPIGroup and serves
+// /apis/{group}/v1alpha1/namespaces/{namespace}/databases/{name}/{operation}.
+// Authentication and authorization are delegated to the API server: callers
+// need "get" on the databases/admin subresource.
+type AdminAPIServer struct {
+	// Client reads Databases
+	Client client.Client
+	// APIReader reads credential Secrets without caching every Secret of the cluster
+	APIReader client.Reader
+	// HTTPClient sends the requests to Elasticsearch (default: http.DefaultClient)
+	HTTPClient *http.Client
+	// KubeClient reviews tokens and access, and reads the front proxy settings of
+	// the aggregation layer
+	KubeClient kubernetes.Interface
+	// BindAddress is the address the server listens on
+	BindAddress string
+	// TLSOpts customizes the TLS configuration. Without a GetCertificate option a
+	// self-signed certificate is generated.
+	TLSOpts []func(*tls.Config)
+
+	authenticator authenticator.Request
+	authorizer    authorizer.Authorizer
+}
+
+// NeedLeaderElection makes the admin API available on every replica
+func (s *AdminAPIServer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the admin API until the context is cancelled
+func (s *AdminAPIServer) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("admin-api")
+
+	if err := s.delegateAuth(ctx); err != nil {
+		return fmt.Errorf("failed to set up delegated authentication: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The front proxy identifies itself with a client certificate, verified
+		// against the CA of the aggregation layer by the authenticator
+		ClientAuth: tls.RequestClientCert,
+	}
+	for _, opt := range s.TLSOpts {
+		opt(tlsConfig)
+	}
+	if tlsConfig.GetCertificate == nil && len(tlsConfig.Certificates) == 0 {
+		certPEM, keyPEM, err := certutil.GenerateSelfSignedCertKey("database-operator-admin-api", nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to generate admin API certificate: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	listener, err := tls.Listen("tcp", s.BindAddress, tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	log.Info("Serving admin API", "address", listener.Addr().String(), "group", adminAPIGroup)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// delegateAuth authenticates requests proxied by the aggregation layer with the
+// client CA and headers it publishes, and bearer tokens with TokenReviews.
+// Access is checked with SubjectAccessReviews. The front proxy settings are
+// followed as the API server rotates them.
+func (s *AdminAPIServer) delegateAuth(ctx context.Context) error {
+	backoff := &wait.Backoff{Duration: 500 * time.Millisecond, Factor: 1.5, Jitter: 0.2, Steps: 5}
+
+	clientCA, err := dynamiccertificates.NewDynamicCAFromConfigMapController("admin-api-requestheader-client-ca",
+		authenticationNamespace, authenticationConfigMap, "requestheader-client-ca-file", s.KubeClient)
+	if err != nil {
+		return err
+	}
+	headers := headerrequest.NewRequestHeaderAuthRequestController(authenticationConfigMap, authenticationNamespace,
+		s.KubeClient, "requestheader-username-headers", "requestheader-uid-headers", "requestheader-group-headers",
+		"requestheader-extra-headers-prefix", "requestheader-allowed-names")
+	if err := clientCA.RunOnce(ctx); err != nil {
+		return err
+	}
+	if err := headers.RunOnce(ctx); err != nil {
+		return err
+	}
+	go clientCA.Run(ctx, 1)
+	go headers.Run(ctx, 1)
+
+	s.authenticator, _, err = authenticatorfactory.DelegatingAuthenticatorConfig{
+		TokenAccessReviewClient: s.KubeClient.AuthenticationV1(),
+		WebhookRetryBackoff:     backoff,
+		CacheTTL:                delegatedAuthCacheTTL,
+		RequestHeaderConfig: &authenticatorfactory.RequestHeaderConfig{
+			UsernameHeaders:     headerrequest.StringSliceProviderFunc(headers.UsernameHeaders),
+			UIDHeaders:          headerrequest.StringSliceProviderFunc(headers.UIDHeaders),
+			GroupHeaders:        headerrequest.StringSliceProviderFunc(headers.GroupHeaders),
+			ExtraHeaderPrefixes: headerrequest.StringSliceProviderFunc(headers.ExtraHeaderPrefixes),
+			CAContentProvider:   clientCA,
+			AllowedClientNames:  headerrequest.StringSliceProviderFunc(headers.AllowedClientNames),
+		},
+	}.New()
+	if err != nil {
+		return err
+	}
+	s.authorizer, err = authorizerfactory.DelegatingAuthorizerConfig{
+		SubjectAccessReviewClient: s.KubeClient.AuthorizationV1(),
+		AllowCacheTTL:             delegatedAuthCacheTTL,
+		DenyCacheTTL:              delegatedAuthDenyCacheTTL,
+		WebhookRetryBackoff:       backoff,
+	}.New()
+	return err
+}
+
+// Handler returns the HTTP handler of the admin API
+func (s *AdminAPIServer) Handler() http.Handler {
+	prefix := fmt.Sprintf("/apis/%s/%s", adminAPIGroup, adminAPIVersion)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix, s.serveDiscovery)
+	mux.HandleFunc("GET "+prefix+"/namespaces/{namespace}/databases/{name}/{operation}", s.serveOperation)
+	return mux
+}
+
+// serveDiscovery lists the operations as subresources of databases, which the
+// aggregation layer probes to report the APIService available
+func (s *AdminAPIServer) serveDiscovery(w http.ResponseWriter, req *http.Request) {
+	if _, ok := s.authenticated(w, req); !ok {
+		return
+	}
+
+	names := []string{}
+	for _, operations := range adminOperations {
+		for name := range operations {
+			names = append(names, "databases/"+name)
+		}
+	}
+	slices.Sort(names)
+	resources := metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: adminAPIGroup + "/" + adminAPIVersion,
+	}
+	for _, name := range names {
+		resources.APIResources = append(resources.APIResources, metav1.APIResource{
+			Name: name, Namespaced: true, Kind: "Database", Verbs: metav1.Verbs{"get"},
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resources)
+}
+
+func (s *AdminAPIServer) serveOperation(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), adminAPITimeout)
+	defer cancel()
+
+	namespace, name, operation := req.PathValue("namespace"), req.PathValue("name"), req.PathValue("operation")
+	log := log.FromContext(ctx).WithValues("namespace", namespace, "database", name, "operation", operation)
+
+	user, ok := s.authenticated(w, req)
+	if !ok {
+		return
+	}
+
+	decision, _, err := s.authorizer.Authorize(ctx, authorizer.AttributesRecord{
+		User:            user,
+		Verb:            "get",
+		Namespace:       namespace,
+		APIGroup:        databasesv1alpha1.GroupVersion.Group,
+		APIVersion:      databasesv1alpha1.GroupVersion.Version,
+		Resource:        "databases",
+		Subresource:     adminSubresource,
+		Name:            name,
+		ResourceRequest: true,
+	})
+	if err != nil {
+		log.Error(err, "Failed to authorize admin API request")
+		writeAdminError(w, http.StatusInternalServerError, errors.New("authorization failed"))
+		return
+	}
+	if decision != authorizer.DecisionAllow {
+		writeAdminError(w, http.StatusForbidden, fmt.Errorf("user %q cannot get %s/%s in namespace %q",
+			user.GetName(), "databases", adminSubresource, namespace))
+		return
+	}
+
+	database := &databasesv1alpha1.Database{}
+	if err := s.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, database); err != nil {
+		if apierrors.IsNotFound(err) {
+			writeAdminError(w, http.StatusNotFound, err)
+			return
+		}
+		writeAdminError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	run, ok := adminOperations[database.Spec.Type][operation]
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, fmt.Errorf("operation %q is not available for %s", operation, database.Spec.Type))
+		return
+	}
+
+	conn, err := resolveAdminConnection(ctx, s.APIReader, s.HTTPClient, database)
+	if err != nil {
+		log.Error(err, "Failed to resolve database credentials")
+		writeAdminError(w, http.StatusInternalServerError, errors.New("failed to resolve database credentials"))
+		return
+	}
+
+	result, err := run(ctx, conn)
+	if err != nil {
+		log.Error(err, "Admin operation failed")
+		writeAdminError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	log.V(1).Info("Served admin operation", "user", user.GetName())
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// authenticated identifies the caller, from the headers of the front proxy or
+// a bearer token, and answers 401 when it cannot
+func (s *AdminAPIServer) authenticated(w http.ResponseWriter, req *http.Request) (user.Info, bool) {
+	resp, ok, err := s.authenticator.AuthenticateRequest(req)
+	if err != nil || !ok {
+		writeAdminError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return nil, false
+	}
+	return resp.User, true
+}
+
+// resolveAdminConnection resolves the host and admin credentials of a database from
+// the same environment the admin Jobs use, and the TLS settings of the engine.
+// The reader should not cache Secrets.
+func resolveAdminConnection(ctx context.Context, reader client.Reader, httpClient *http.Client, database *databasesv1alpha1.Database) (adminConnection, error) {
+	conn := adminConnection{httpClient: httpClient}
+	for _, ev := range (&DatabaseReconciler{}).adminClientEnv(database) {
+		value := ev.Value
+		if ev.ValueFrom != nil && ev.ValueFrom.SecretKeyRef != nil {
+			var err error
+			if value, err = secretValue(ctx, reader, database.Namespace, ev.ValueFrom.SecretKeyRef); err != nil {
+				return conn, err
+			}
+		}
+
+		switch ev.Name {
+		case "DB_HOST":
+			conn.host = value
+		case "PGUSER":
+			conn.user = value
+		case "PGPASSWORD", "REDISCLI_AUTH":
+			conn.password = value
+		case "PGDATABASE":
+			conn.database = value
+		}
+	}
+
+	if tlsEnabled(database) && database.Spec.Type != databasesv1alpha1.DatabaseTypeMongoDB {
+		secret := &corev1.Secret{}
+		key := types.NamespacedName{Namespace: database.Namespace, Name: tlsSecretName(database)}
+		if err := reader.Get(ctx, key, secret); err != nil {
+			return conn, fmt.Errorf("failed to read the TLS Secret: %w", err)
+		}
+		config, err := engineTLSConfig(serviceHost(database), secret.Data["ca.crt"])
+		if err != nil {
+			return conn, err
+		}
+		conn.tls = config
+	}
+	return conn, nil
+}
+
+func secretValue(ctx context.Context, reader client.Reader, namespace string, ref *corev1.SecretKeySelector) (string, error) {
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in secret %s", ref.Key, ref.Name)
+	}
+	return string(value), nil
+}
+
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
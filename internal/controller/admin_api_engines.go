@@ -0,0 +1,233 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// Engine clients used by the admin API and the controllers to query databases.
+// PostgreSQL and Redis are reached with their maintained drivers, over TLS when
+// the Database serves it.
+
+// elasticsearchGet performs a GET request against the Elasticsearch HTTP API
+func elasticsearchGet(ctx context.Context, conn adminConnection, path string) (any, error) {
+	return elasticsearchRequest(ctx, conn, http.MethodGet, path, nil)
+}
+
+// elasticsearchRequest sends a request with an optional JSON body to the
+// Elasticsearch HTTP API
+func elasticsearchRequest(ctx context.Context, conn adminConnection, method, path string, body any) (any, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("http://%s:9200%s", conn.host, path), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := conn.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("elasticsearch returned %s", resp.Status)
+	}
+
+	var result any
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// redisInfo runs INFO against Redis and returns its fields
+func redisInfo(ctx context.Context, conn adminConnection) (map[string]string, error) {
+	reply, err := redisRun(ctx, conn, "INFO")
+	if err != nil {
+		return nil, err
+	}
+	return parseRedisInfo(reply), nil
+}
+
+// redisRun runs a command against Redis, authenticated with the password of the
+// connection, and returns its reply as text
+func redisRun(ctx context.Context, conn adminConnection, args ...string) (string, error) {
+	return redisDo(ctx, redisOptions(conn), args...)
+}
+
+// redisDo runs a command on a new client and returns its reply as text
+func redisDo(ctx context.Context, options *redis.Options, args ...string) (string, error) {
+	rdb := redis.NewClient(options)
+	defer rdb.Close() //nolint:errcheck
+
+	cmdArgs := make([]any, len(args))
+	for i, arg := range args {
+		cmdArgs[i] = arg
+	}
+	return rdb.Do(ctx, cmdArgs...).Text()
+}
+
+// redisOptions configures a single connection to a Redis replica: the TLS port
+// when the Database serves TLS, and no retries so the caller's timeout holds
+func redisOptions(conn adminConnection) *redis.Options {
+	port := "6379"
+	if conn.tls != nil {
+		port = strconv.Itoa(redisTLSPort)
+	}
+	return &redis.Options{
+		Addr:            net.JoinHostPort(conn.host, port),
+		Password:        conn.password,
+		TLSConfig:       conn.tls,
+		Protocol:        2,
+		MaxRetries:      -1,
+		PoolSize:        1,
+		DisableIdentity: true,
+	}
+}
+
+// parseRedisInfo parses the "key:value" lines of an INFO reply
+func parseRedisInfo(info string) map[string]string {
+	result := map[string]string{}
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// postgresQuery runs a read-only query and returns its rows keyed by column
+// name, with the values in their text form
+func postgresQuery(ctx context.Context, conn adminConnection, query string) ([]map[string]any, error) {
+	config, err := postgresConfig(conn)
+	if err != nil {
+		return nil, err
+	}
+	return postgresRows(ctx, config, query)
+}
+
+// postgresExec runs a statement that returns no rows
+func postgresExec(ctx context.Context, conn adminConnection, statement string) error {
+	config, err := postgresConfig(conn)
+	if err != nil {
+		return err
+	}
+	pg, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer pg.Close(context.Background()) //nolint:errcheck
+
+	_, err = pg.Exec(ctx, statement, pgx.QueryExecModeSimpleProtocol)
+	return err
+}
+
+// postgresRows runs a query on a new connection and returns its rows as text
+func postgresRows(ctx context.Context, config *pgx.ConnConfig, query string) ([]map[string]any, error) {
+	pg, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer pg.Close(context.Background()) //nolint:errcheck
+
+	// The simple protocol returns every value as text, whatever its type
+	rows, err := pg.Query(ctx, query, pgx.QueryExecModeSimpleProtocol)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []map[string]any{}
+	fields := rows.FieldDescriptions()
+	for rows.Next() {
+		row := make(map[string]any, len(fields))
+		for i, value := range rows.RawValues() {
+			if value == nil {
+				row[fields[i].Name] = nil
+				continue
+			}
+			row[fields[i].Name] = string(value)
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// postgresConfig builds the configuration of a connection, ignoring the PG*
+// environment of the operator
+func postgresConfig(conn adminConnection) (*pgx.ConnConfig, error) {
+	config, err := pgx.ParseConfig("sslmode=disable")
+	if err != nil {
+		return nil, err
+	}
+	config.Host = conn.host
+	config.Port = 5432
+	config.User = conn.user
+	config.Password = conn.password
+	config.Database = conn.database
+	config.TLSConfig = conn.tls
+	config.Fallbacks = nil
+	config.RuntimeParams = map[string]string{"application_name": "database-operator"}
+	return config, nil
+}
+
+// engineTLSConfig verifies the certificate of a Database against the CA of its
+// TLS Secret, or the system roots without one. Replicas are reached by address,
+// so the name checked is the one of the database Service.
+func engineTLSConfig(serverName string, ca []byte) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: serverName}
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in the CA of %s", serverName)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
@@ -0,0 +1,337 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	authenticationv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	"k8s.io/client-go/rest"
+	certutil "k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Admin API", func() {
+	// serve accepts a single connection on a local port and hands it to handle
+	serve := func(handle func(conn net.Conn)) string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			defer listener.Close() //nolint:errcheck
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close() //nolint:errcheck
+			handle(conn)
+		}()
+		return listener.Addr().String()
+	}
+
+	Context("Redis", func() {
+		It("should authenticate and parse the INFO reply", func() {
+			commands := make(chan []string, 2)
+			addr := serve(func(conn net.Conn) {
+				reader := bufio.NewReader(conn)
+				hello := "*4\r\n$6\r\nserver\r\n$5\r\nredis\r\n$5\r\nproto\r\n:2\r\n"
+				for _, reply := range []string{hello, "$44\r\n# Server\r\nredis_version:7.2.4\r\nrole:master\r\n\r\n"} {
+					header, _ := reader.ReadString('\n')
+					var count int
+					_, _ = fmt.Sscanf(header, "*%d", &count)
+					args := []string{}
+					for range count {
+						_, _ = reader.ReadString('\n')
+						arg, _ := reader.ReadString('\n')
+						args = append(args, strings.TrimRight(arg, "\r\n"))
+					}
+					commands <- args
+					_, _ = conn.Write([]byte(reply))
+				}
+			})
+			host, port, _ := net.SplitHostPort(addr)
+			Expect(port).NotTo(BeEmpty())
+
+			options := redisOptions(adminConnection{host: host, password: "secret"})
+			Expect(options.Addr).To(Equal(net.JoinHostPort(host, "6379")))
+			options.Addr = addr
+			reply, err := redisDo(context.Background(), options, "INFO")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(<-commands).To(Equal([]string{"hello", "2", "auth", "default", "secret"}))
+			Expect(<-commands).To(Equal([]string{"INFO"}))
+			Expect(parseRedisInfo(reply)).To(Equal(map[string]string{
+				"redis_version": "7.2.4",
+				"role":          "master",
+			}))
+		})
+
+		It("should connect to the TLS port when the database serves TLS", func() {
+			options := redisOptions(adminConnection{host: "10.0.0.5", tls: &tls.Config{ServerName: "cache-service.shop.svc"}})
+			Expect(options.Addr).To(Equal("10.0.0.5:6380"))
+			Expect(options.TLSConfig.ServerName).To(Equal("cache-service.shop.svc"))
+		})
+	})
+
+	Context("PostgreSQL", func() {
+		It("should connect with the credentials and return the rows as text", func() {
+			queries := make(chan string, 1)
+			addr := serve(func(conn net.Conn) {
+				backend := pgproto3.NewBackend(conn, conn)
+				startup, err := backend.ReceiveStartupMessage()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(startup.(*pgproto3.StartupMessage).Parameters).To(HaveKeyWithValue("user", "postgres"))
+				backend.Send(&pgproto3.AuthenticationCleartextPassword{})
+				Expect(backend.Flush()).To(Succeed())
+				Expect(backend.SetAuthType(pgproto3.AuthTypeCleartextPassword)).To(Succeed())
+				password, err := backend.Receive()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(password.(*pgproto3.PasswordMessage).Password).To(Equal("secret"))
+				backend.Send(&pgproto3.AuthenticationOk{})
+				backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+				backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+				backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+				Expect(backend.Flush()).To(Succeed())
+
+				query, err := backend.Receive()
+				Expect(err).NotTo(HaveOccurred())
+				queries <- query.(*pgproto3.Query).String
+				backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+					{Name: []byte("datname"), DataTypeOID: 19, Format: 0},
+					{Name: []byte("numbackends"), DataTypeOID: 23, Format: 0},
+				}})
+				backend.Send(&pgproto3.DataRow{Values: [][]byte{[]byte("app"), nil}})
+				backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+				backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+				Expect(backend.Flush()).To(Succeed())
+				_, _ = backend.Receive()
+			})
+			host, port, _ := net.SplitHostPort(addr)
+
+			config, err := postgresConfig(adminConnection{host: host, user: "postgres", password: "secret", database: "postgres"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Port).To(Equal(uint16(5432)))
+			Expect(config.TLSConfig).To(BeNil())
+
+			portNumber, err := strconv.Atoi(port)
+			Expect(err).NotTo(HaveOccurred())
+			config.Port = uint16(portNumber)
+			rows, err := postgresRows(context.Background(), config, "SELECT datname, numbackends FROM pg_stat_database")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(<-queries).To(Equal("SELECT datname, numbackends FROM pg_stat_database"))
+			Expect(rows).To(Equal([]map[string]any{{"datname": "app", "numbackends": nil}}))
+		})
+	})
+
+	Context("TLS", func() {
+		It("should verify the engine against the CA of its TLS Secret", func() {
+			scheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+			Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+			ca := selfSignedCert("orders-ca")
+			database := &databasesv1alpha1.Database{
+				ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+				Spec: databasesv1alpha1.DatabaseSpec{
+					Type: databasesv1alpha1.DatabaseTypeRedis,
+					TLS:  &databasesv1alpha1.TLSSpec{SecretName: "orders-certs"},
+				},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "orders-certs", Namespace: "shop"},
+				Data:       map[string][]byte{"ca.crt": ca},
+			}).Build()
+
+			conn, err := resolveAdminConnection(context.Background(), c, nil, database)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conn.tls.ServerName).To(Equal("orders-service.shop.svc"))
+			Expect(conn.tls.RootCAs).NotTo(BeNil())
+
+			_, err = engineTLSConfig("orders-service.shop.svc", []byte("not a certificate"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("authorization", func() {
+		var allowed bool
+		var reviewed authorizer.Attributes
+
+		newServer := func() *AdminAPIServer {
+			scheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+			Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&databasesv1alpha1.Database{
+				ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop"},
+				Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeRedis},
+			}).Build()
+
+			return &AdminAPIServer{
+				Client:    c,
+				APIReader: c,
+				authenticator: authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+					if req.Header.Get("Authorization") != "Bearer valid" {
+						return nil, false, nil
+					}
+					return &authenticator.Response{User: &user.DefaultInfo{Name: "dashboard"}}, true, nil
+				}),
+				authorizer: authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+					reviewed = a
+					if allowed {
+						return authorizer.DecisionAllow, "", nil
+					}
+					return authorizer.DecisionNoOpinion, "", nil
+				}),
+			}
+		}
+
+		get := func(path, token string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/apis/admin.databases.database-operator.io/v1alpha1"+path, nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			recorder := httptest.NewRecorder()
+			newServer().Handler().ServeHTTP(recorder, req)
+			return recorder
+		}
+
+		BeforeEach(func() {
+			allowed = false
+			reviewed = nil
+		})
+
+		It("should reject requests without a valid token", func() {
+			Expect(get("/namespaces/shop/databases/cache/info", "").Code).To(Equal(http.StatusUnauthorized))
+			Expect(get("/namespaces/shop/databases/cache/info", "expired").Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should check access to the databases/admin subresource", func() {
+			Expect(get("/namespaces/shop/databases/cache/info", "valid").Code).To(Equal(http.StatusForbidden))
+			Expect(reviewed).To(Equal(authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "dashboard"},
+				Verb:            "get",
+				Namespace:       "shop",
+				APIGroup:        databasesv1alpha1.GroupVersion.Group,
+				APIVersion:      databasesv1alpha1.GroupVersion.Version,
+				Resource:        "databases",
+				Subresource:     "admin",
+				Name:            "cache",
+				ResourceRequest: true,
+			}))
+		})
+
+		It("should only expose the operations of the database engine", func() {
+			allowed = true
+			Expect(get("/namespaces/shop/databases/cache/stat-activity", "valid").Code).To(Equal(http.StatusNotFound))
+			Expect(get("/namespaces/shop/databases/missing/info", "valid").Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("should list the operations for the aggregation layer", func() {
+			recorder := get("", "valid")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			resources := metav1.APIResourceList{}
+			Expect(json.NewDecoder(recorder.Body).Decode(&resources)).To(Succeed())
+			Expect(resources.GroupVersion).To(Equal("admin.databases.database-operator.io/v1alpha1"))
+			Expect(resources.APIResources).To(ContainElement(metav1.APIResource{
+				Name: "databases/info", Namespaced: true, Kind: "Database", Verbs: metav1.Verbs{"get"},
+			}))
+		})
+	})
+
+	Context("delegated authentication", func() {
+		It("should review bearer tokens and not trust front proxy headers without its certificate", func() {
+			kubeClient := kubefake.NewClientset(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "extension-apiserver-authentication", Namespace: "kube-system"},
+				Data: map[string]string{
+					"requestheader-client-ca-file":   string(selfSignedCert("front-proxy-ca")),
+					"requestheader-username-headers": `["X-Remote-User"]`,
+					"requestheader-group-headers":    `["X-Remote-Group"]`,
+					"requestheader-allowed-names":    `["front-proxy-client"]`,
+				},
+			})
+			// the webhook authenticator posts TokenReviews through a REST client,
+			// which the fake clientset does not provide
+			apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				review := &authenticationv1.TokenReview{}
+				Expect(json.NewDecoder(req.Body).Decode(review)).To(Succeed())
+				review.Status.Authenticated = review.Spec.Token == "valid"
+				review.Status.User = authenticationv1.UserInfo{Username: "dashboard"}
+				w.Header().Set("Content-Type", "application/json")
+				Expect(json.NewEncoder(w).Encode(review)).To(Succeed())
+			}))
+			defer apiServer.Close()
+			reviews, err := authenticationv1client.NewForConfig(&rest.Config{Host: apiServer.URL})
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			server := &AdminAPIServer{KubeClient: tokenReviewClientset{Clientset: kubeClient, reviews: reviews}}
+			Expect(server.delegateAuth(ctx)).To(Succeed())
+
+			req := httptest.NewRequest(http.MethodGet, "/apis/admin.databases.database-operator.io/v1alpha1", nil)
+			req.Header.Set("X-Remote-User", "system:admin")
+			_, ok, _ := server.authenticator.AuthenticateRequest(req)
+			Expect(ok).To(BeFalse())
+
+			req.Header.Set("Authorization", "Bearer valid")
+			resp, ok, err := server.authenticator.AuthenticateRequest(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(resp.User.GetName()).To(Equal("dashboard"))
+			Expect(resp.User.GetGroups()).To(ContainElement(user.AllAuthenticated))
+		})
+	})
+})
+
+// tokenReviewClientset serves TokenReviews from a real REST client
+type tokenReviewClientset struct {
+	*kubefake.Clientset
+	reviews authenticationv1client.AuthenticationV1Interface
+}
+
+func (c tokenReviewClientset) AuthenticationV1() authenticationv1client.AuthenticationV1Interface {
+	return c.reviews
+}
+
+// selfSignedCert generates a self-signed certificate
+func selfSignedCert(host string) []byte {
+	cert, _, err := certutil.GenerateSelfSignedCertKey(host, nil, nil)
+	Expect(err).NotTo(HaveOccurred())
+	return cert
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// Admin Jobs run engine CLI commands (psql, mongosh, redis-cli, curl) against the
+// database Service from a short-lived pod using the engine image, so the operator
+// itself never needs network access to the databases.
+
+// engineImage returns the image of the database engine, which also ships its CLI
+// tools, pinned to the resolved digest when the Database resolves its image. A
+// DatabaseVersion that pins its image to a digest is run as it is.
+func engineImage(database *databasesv1alpha1.Database) string {
+	if image, found := catalogImage(database); found && strings.Contains(image, "@") {
+		return image
+	}
+	tag := engineImageTag(database)
+	if status := database.Status.Image; status != nil && status.Version == runningVersion(database) &&
+		database.Spec.ImageResolution == databasesv1alpha1.ImageResolutionDigest {
+		return tag + "@" + status.Digest
+	}
+	return tag
+}
+
+// engineImageTag returns the image tag of the version of the database engine the
+// workload runs, which lags behind spec.version during a major upgrade: the one
+// its DatabaseVersion lists, or the version in the repository of the flavor
+func engineImageTag(database *databasesv1alpha1.Database) string {
+	if image, found := catalogImage(database); found {
+		tag, _, _ := strings.Cut(image, "@")
+		return tag
+	}
+	return fmt.Sprintf("%s:%s", engineLayout(database).repository, runningVersion(database))
+}
+
+// serviceHost returns the in-cluster DNS name of the database Service
+func serviceHost(database *databasesv1alpha1.Database) string {
+	return fmt.Sprintf("%s-service.%s.svc", database.Name, database.Namespace)
+}
+
+// peersHost returns the DNS name of the headless Service, which resolves to the
+// addresses of all replicas
+func peersHost(database *databasesv1alpha1.Database) string {
+	return fmt.Sprintf("%s%s.%s.svc", database.Name, headlessServiceSuffix, database.Namespace)
+}
+
+// adminClientEnv returns the environment the engine CLI needs to connect to the
+// database as its administrative user. DB_HOST is always set.
+func (r *DatabaseReconciler) adminClientEnv(database *databasesv1alpha1.Database) []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{
+			Name:  "DB_HOST",
+			Value: serviceHost(database),
+		},
+	}
+
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		// Reuse the workload env so user, database and password always match
+		env = append(env, renameEnv(r.getPostgreSQLEnv(database), map[string]string{
+			"POSTGRES_DB":       "PGDATABASE",
+			"POSTGRES_USER":     "PGUSER",
+			"POSTGRES_PASSWORD": "PGPASSWORD",
+		})...)
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		env = append(env, renameEnv(r.getMongoDBEnv(database), map[string]string{
+			"MONGO_INITDB_ROOT_USERNAME": "MONGO_USERNAME",
+			"MONGO_INITDB_ROOT_PASSWORD": "MONGO_PASSWORD",
+		})...)
+	case databasesv1alpha1.DatabaseTypeRedis:
+		if secret := passwordSecret(database); secret != nil {
+			env = append(env, passwordEnv("REDISCLI_AUTH", secret))
+		}
+	case databasesv1alpha1.DatabaseTypeSQLite:
+		env = append(env, renameEnv(r.getSQLiteEnv(database), map[string]string{
+			"SQLITE_DATABASE": "SQLITE_DATABASE",
+		})...)
+	}
+
+	return env
+}
+
+// renameEnv keeps the variables listed in names, renamed to their mapped name
+func renameEnv(env []corev1.EnvVar, names map[string]string) []corev1.EnvVar {
+	result := []corev1.EnvVar{}
+	for _, ev := range env {
+		if name, ok := names[ev.Name]; ok {
+			ev.Name = name
+			result = append(result, ev)
+		}
+	}
+	return result
+}
+
+// jobComponentPrefixes start the components of the Jobs of backup schedules and
+// of other resources, whose pods share the component of their kind
+var jobComponentPrefixes = []string{
+	backupComponent + "-",
+	databaseUserComponentPrefix,
+	logicalDatabaseComponentPrefix,
+	migrationComponentPrefix,
+	opsRequestComponentPrefix,
+}
+
+// jobComponents lists the component labels of the pods of operator Jobs, which
+// the NetworkPolicy of the Jobs selects
+var jobComponents = append([]string{
+	backupCleanupComponent,
+	backupVerifyComponent,
+	bootstrapComponent,
+	keyspaceAnalysisComponent,
+	logLevelComponent,
+	majorUpgradeCheckComponent,
+	majorUpgradeComponent,
+	monitoringUserComponent,
+	readOnlyComponent,
+	restoreComponent,
+	restoreRevertComponent,
+	rotationComponent,
+	shardAnalysisComponent,
+	storageMigrationComponent,
+	walRestoreComponent,
+}, jobPrefixComponents()...)
+
+func jobPrefixComponents() []string {
+	components := []string{}
+	for _, prefix := range jobComponentPrefixes {
+		components = append(components, strings.TrimSuffix(prefix, "-"))
+	}
+	return components
+}
+
+// jobPodComponent returns the component label of the pods of a Job
+func jobPodComponent(component string) string {
+	if slices.Contains(jobComponents, component) {
+		return component
+	}
+	for _, prefix := range jobComponentPrefixes {
+		if strings.HasPrefix(component, prefix) {
+			return strings.TrimSuffix(prefix, "-")
+		}
+	}
+	return component
+}
+
+// adminPodTemplate builds the pod template running a shell script in a single container
+func (r *DatabaseReconciler) adminPodTemplate(database *databasesv1alpha1.Database, component, image, script string, env []corev1.EnvVar) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: r.getComponentLabels(database, jobPodComponent(component)),
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:                corev1.RestartPolicyNever,
+			ServiceAccountName:           jobsServiceAccountName(database),
+			AutomountServiceAccountToken: ptr.To(false),
+			Containers: []corev1.Container{
+				{
+					Name:    component,
+					Image:   image,
+					Command: []string{"/bin/sh", "-c", script},
+					Env:     env,
+				},
+			},
+		},
+	}
+	r.addCABundle(database, &template.Spec, &template.Spec.Containers[0])
+	r.addProxyEnv(database, &template.Spec.Containers[0])
+	r.applyPodSecurity(database, &template)
+	return template
+}
+
+// createAdminJob builds a one-shot Job running an admin script against the database
+func (r *DatabaseReconciler) createAdminJob(database *databasesv1alpha1.Database, component, script string, env []corev1.EnvVar) *batchv1.Job {
+	backoffLimit := int32(2)
+	ttl := int32(3600)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      database.Name + "-" + component,
+			Namespace: database.Namespace,
+			Labels:    r.getComponentLabels(database, component),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: r.adminPodTemplate(database, component, engineImage(database), script,
+				append(r.adminClientEnv(database), env...)),
+		},
+	}
+}
+
+// createAdminCronJob builds a CronJob running an admin script against the database
+func (r *DatabaseReconciler) createAdminCronJob(database *databasesv1alpha1.Database, component, schedule, script string, env []corev1.EnvVar) *batchv1.CronJob {
+	labels := r.getComponentLabels(database, component)
+	backoffLimit := int32(1)
+	historyLimit := int32(1)
+
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      database.Name + "-" + component,
+			Namespace: database.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   schedule,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: r.adminPodTemplate(database, component, engineImage(database), script,
+						append(r.adminClientEnv(database), env...)),
+				},
+			},
+		},
+	}
+}
+
+// jobFinished reports whether a Job has completed or failed
+func jobFinished(job *batchv1.Job) (finished bool, succeeded bool) {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return true, true
+		case batchv1.JobFailed:
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// jobFailedTime returns when a Job failed, or the zero time
+func jobFailedTime(job *batchv1.Job) time.Time {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return c.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+// jobDeadlineExceeded reports whether a Job failed for running past its
+// activeDeadlineSeconds
+func jobDeadlineExceeded(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue && c.Reason == batchv1.JobReasonDeadlineExceeded {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// SpecWarning inspects a Database at admission and returns the soft issues it
+// finds, which are shown to the user without rejecting the change. old is nil
+// when the Database is created.
+type SpecWarning func(database, old *databasesv1alpha1.Database, now time.Time) []string
+
+// specWarnings are the checks run on every create and update of a Database
+var specWarnings = []SpecWarning{
+	endOfLifeWarning,
+	storageCapacityWarning,
+	versionUpgradeWarning,
+}
+
+// endOfLifeWarningPeriod is how long before its end of life a release series is
+// reported
+const endOfLifeWarningPeriod = 180 * 24 * time.Hour
+
+// endOfLife is the end of upstream support of release series, as returned by
+// the series of the upgrade matrix
+var endOfLife = map[databasesv1alpha1.DatabaseType]map[engineVersion]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: {
+		{12, 0}: "2024-11-21", {13, 0}: "2025-11-13", {14, 0}: "2026-11-12",
+		{15, 0}: "2027-11-11", {16, 0}: "2028-11-09", {17, 0}: "2029-11-08",
+	},
+	databasesv1alpha1.DatabaseTypeMongoDB: {
+		{4, 4}: "2024-02-29", {5, 0}: "2024-10-31", {6, 0}: "2025-07-31",
+		{7, 0}: "2027-08-31", {8, 0}: "2029-10-31",
+	},
+	databasesv1alpha1.DatabaseTypeElasticsearch: {
+		{6, 0}: "2022-02-10", {7, 0}: "2026-01-15",
+	},
+}
+
+// SpecWarnings returns the warnings of every check for a Database being
+// created (old is nil) or updated
+func SpecWarnings(database, old *databasesv1alpha1.Database, now time.Time) []string {
+	warnings := []string{}
+	for _, check := range specWarnings {
+		warnings = append(warnings, check(database, old, now)...)
+	}
+	return warnings
+}
+
+// endOfLifeWarning reports versions whose release series reached, or is about
+// to reach, its end of life
+func endOfLifeWarning(database, _ *databasesv1alpha1.Database, now time.Time) []string {
+	version, ok := parseEngineVersion(database.Spec.Version)
+	if !ok {
+		return nil
+	}
+	series := upgradeMatrix[database.Spec.Type].series(version)
+	date, ok := endOfLife[database.Spec.Type][series]
+	if !ok {
+		return nil
+	}
+	end, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return nil
+	}
+	switch {
+	case !now.Before(end):
+		return []string{fmt.Sprintf("%s %s reached its end of life on %s and no longer receives security fixes, upgrade spec.version",
+			database.Spec.Type, database.Spec.Version, date)}
+	case end.Sub(now) <= endOfLifeWarningPeriod:
+		return []string{fmt.Sprintf("%s %s reaches its end of life on %s, plan an upgrade of spec.version",
+			database.Spec.Type, database.Spec.Version, date)}
+	}
+	return nil
+}
+
+// storageCapacityWarning reports a storage size below the data the volumes
+// already hold
+func storageCapacityWarning(database, old *databasesv1alpha1.Database, _ time.Time) []string {
+	if old == nil || old.Status.DiskUsage == nil || database.Spec.Storage == nil {
+		return nil
+	}
+	size, err := resource.ParseQuantity(database.Spec.Storage.Size)
+	if err != nil {
+		return nil
+	}
+	usage := old.Status.DiskUsage
+	if size.Value() >= usage.UsedBytes {
+		return nil
+	}
+	used := resource.NewQuantity(usage.UsedBytes, resource.BinarySI)
+	return []string{fmt.Sprintf("spec.storage.size %s is smaller than the %s of data on volume %s",
+		database.Spec.Storage.Size, used, usage.Volume)}
+}
+
+// versionUpgradeWarning reports a change of version the controller will reject:
+// downgrades, skipped release series and unapproved majors
+func versionUpgradeWarning(database, old *databasesv1alpha1.Database, _ time.Time) []string {
+	if old == nil || database.Spec.Version == old.Spec.Version {
+		return nil
+	}
+	current := old.Status.Version
+	if current == "" {
+		current = old.Spec.Version
+	}
+	if err := validateVersionChange(database, current); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Admission warnings", func() {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	newDatabase := func(dbType databasesv1alpha1.DatabaseType, version string) *databasesv1alpha1.Database {
+		return &databasesv1alpha1.Database{
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    dbType,
+				Version: version,
+				Storage: &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+			},
+		}
+	}
+
+	It("should warn about release series at or near their end of life", func() {
+		Expect(SpecWarnings(newDatabase(databasesv1alpha1.DatabaseTypePostgreSQL, "12-alpine"), nil, now)).To(ConsistOf(
+			"PostgreSQL 12-alpine reached its end of life on 2024-11-21 and no longer receives security fixes, upgrade spec.version"))
+		Expect(SpecWarnings(newDatabase(databasesv1alpha1.DatabaseTypeMongoDB, "6.0.14"), nil, now)).To(ConsistOf(
+			"MongoDB 6.0.14 reaches its end of life on 2025-07-31, plan an upgrade of spec.version"))
+		Expect(SpecWarnings(newDatabase(databasesv1alpha1.DatabaseTypeMongoDB, "4.4.18"), nil, now)).To(ConsistOf(
+			ContainSubstring("reached its end of life on 2024-02-29")))
+
+		for _, database := range []*databasesv1alpha1.Database{
+			newDatabase(databasesv1alpha1.DatabaseTypePostgreSQL, "16"),
+			newDatabase(databasesv1alpha1.DatabaseTypeMongoDB, "7.0"),
+			newDatabase(databasesv1alpha1.DatabaseTypeRedis, "6.2"),
+			newDatabase(databasesv1alpha1.DatabaseTypeElasticsearch, "latest"),
+		} {
+			Expect(SpecWarnings(database, nil, now)).To(BeEmpty(), database.Spec.Version)
+		}
+	})
+
+	It("should warn about storage smaller than the data it holds", func() {
+		database := newDatabase(databasesv1alpha1.DatabaseTypePostgreSQL, "16")
+		old := database.DeepCopy()
+		Expect(SpecWarnings(database, old, now)).To(BeEmpty())
+
+		old.Status.DiskUsage = &databasesv1alpha1.DiskUsageStatus{Volume: "data-orders-0", UsedBytes: 6 << 30}
+		Expect(SpecWarnings(database, old, now)).To(BeEmpty())
+		database.Spec.Storage.Size = "5Gi"
+		Expect(SpecWarnings(database, old, now)).To(ConsistOf(
+			"spec.storage.size 5Gi is smaller than the 6Gi of data on volume data-orders-0"))
+		database.Spec.Storage.Size = "five"
+		Expect(SpecWarnings(database, old, now)).To(BeEmpty())
+	})
+
+	It("should warn about version changes the controller rejects", func() {
+		database := newDatabase(databasesv1alpha1.DatabaseTypeMongoDB, "7.0")
+		old := database.DeepCopy()
+		old.Status.Version = "7.0"
+
+		database.Spec.Version = "6.0"
+		Expect(SpecWarnings(database, old, now)).To(ContainElement(ContainSubstring("cannot be downgraded from 7.0 to 6.0")))
+		database.Spec.Version = "8.0"
+		Expect(SpecWarnings(database, old, now)).To(ConsistOf(ContainSubstring("is a major upgrade")))
+		database.Annotations = map[string]string{databasesv1alpha1.ApproveMajorUpgradeAnnotation: "8.0"}
+		Expect(SpecWarnings(database, old, now)).To(BeEmpty())
+
+		// Before the first reconcile the previous spec is the current version
+		old.Status.Version = ""
+		old.Spec.Version = "8.0.4"
+		Expect(SpecWarnings(database, old, now)).To(ConsistOf(ContainSubstring("cannot be downgraded from 8.0.4")))
+	})
+})
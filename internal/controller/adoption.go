@@ -0,0 +1,66 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// ownedBy reports whether a child resource belongs to a Database: it is
+// controlled by it or, in a target cluster, carries its fleet owner label
+func ownedBy(database *databasesv1alpha1.Database, object client.Object) bool {
+	if reference := metav1.GetControllerOf(object); reference != nil {
+		return reference.UID == database.UID
+	}
+	return database.UID != "" && object.GetLabels()[databasesv1alpha1.FleetOwnerLabel] == string(database.UID)
+}
+
+// claimExisting makes sure an existing resource found under the name of a
+// child resource belongs to the Database before the operator changes it. A
+// resource without an owner is adopted when the Database has the adopt
+// annotation; one controlled by something else is never taken over.
+func (r *DatabaseReconciler) claimExisting(ctx context.Context, database *databasesv1alpha1.Database, kind string, object client.Object) error {
+	if ownedBy(database, object) {
+		return nil
+	}
+	if reference := metav1.GetControllerOf(object); reference != nil {
+		return fmt.Errorf("%s %s is controlled by %s %s and cannot be adopted", kind, object.GetName(), reference.Kind, reference.Name)
+	}
+	if database.Annotations[databasesv1alpha1.AdoptAnnotation] != "true" {
+		return fmt.Errorf("%s %s already exists without an owner; annotate the Database with %s=true to adopt it",
+			kind, object.GetName(), databasesv1alpha1.AdoptAnnotation)
+	}
+
+	if err := controllerutil.SetControllerReference(database, object, r.Scheme); err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("Adopting existing resource", "kind", kind, "name", object.GetName())
+	if err := r.Update(ctx, object); err != nil {
+		return err
+	}
+	r.event(database, corev1.EventTypeNormal, "Adopted", fmt.Sprintf("Adopted existing %s %s", kind, object.GetName()))
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Adoption", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		scheme     *runtime.Scheme
+	)
+
+	serviceKey := types.NamespacedName{Name: "orders-service", Namespace: "shop"}
+
+	build := func(objects ...runtime.Object) {
+		reconciler = &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).
+				WithInterceptorFuncs(applyPatches()).Build(),
+			Scheme: scheme,
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+		}
+	})
+
+	orphan := func() *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-service", Namespace: "shop"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "postgres", Port: 5432}}},
+		}
+	}
+
+	It("should leave a resource without an owner alone unless asked to adopt it", func() {
+		build(orphan())
+		Expect(reconciler.reconcileService(ctx, database)).To(MatchError(ContainSubstring(databasesv1alpha1.AdoptAnnotation)))
+
+		service := &corev1.Service{}
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(service.OwnerReferences).To(BeEmpty())
+		Expect(service.Labels).To(BeEmpty())
+	})
+
+	It("should adopt a resource without an owner with the adopt annotation", func() {
+		build(orphan())
+		database.Annotations = map[string]string{databasesv1alpha1.AdoptAnnotation: "true"}
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+
+		service := &corev1.Service{}
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(ownedBy(database, service)).To(BeTrue())
+		Expect(service.Labels).To(HaveKeyWithValue("app.kubernetes.io/managed-by", "database-operator"))
+	})
+
+	It("should never take over a resource controlled by something else", func() {
+		statefulSet := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "orders",
+				Namespace: "shop",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "example.com/v1", Kind: "Cluster", Name: "orders", UID: "other-uid", Controller: ptr.To(true),
+				}},
+			},
+			Spec: appsv1.StatefulSetSpec{Replicas: ptr.To(int32(1))},
+		}
+		build(statefulSet)
+		database.Annotations = map[string]string{databasesv1alpha1.AdoptAnnotation: "true"}
+		Expect(reconciler.reconcilePostgreSQL(ctx, database)).To(MatchError(ContainSubstring("controlled by Cluster orders")))
+	})
+})
@@ -0,0 +1,79 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// Analysis Jobs report their findings through the container termination message
+// (limited to 4096 bytes), so their scripts must print a compact summary to
+// /dev/termination-log rather than raw engine output.
+
+// latestAnalysisOutput returns the termination message of the most recent successful
+// Job of an analysis component, if it completed after the given time.
+func (r *DatabaseReconciler) latestAnalysisOutput(ctx context.Context, database *databasesv1alpha1.Database, component string, since *metav1.Time) (string, *metav1.Time, error) {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(database.Namespace),
+		client.MatchingLabels(r.getComponentLabels(database, component))); err != nil {
+		return "", nil, err
+	}
+
+	var latest *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if job.Status.Succeeded == 0 || job.Status.CompletionTime == nil {
+			continue
+		}
+		if latest == nil || job.Status.CompletionTime.After(latest.Status.CompletionTime.Time) {
+			latest = job
+		}
+	}
+	if latest == nil {
+		return "", nil, nil
+	}
+	if since != nil && !latest.Status.CompletionTime.After(since.Time) {
+		return "", nil, nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(database.Namespace),
+		client.MatchingLabels{"batch.kubernetes.io/job-name": latest.Name}); err != nil {
+		return "", nil, err
+	}
+
+	var message string
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name == component && cs.State.Terminated != nil {
+				message = cs.State.Terminated.Message
+			}
+		}
+	}
+
+	return message, latest.Status.CompletionTime.DeepCopy(), nil
+}
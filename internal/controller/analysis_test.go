@@ -0,0 +1,80 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Analysis Jobs", func() {
+	It("should parse redis-cli --memkeys summary output", func() {
+		output := `Biggest string found "session:1" has 72 bytes
+Biggest   hash found 'user:42' has 2097152 bytes
+3 strings with 168 bytes (60.00% of keys, avg size 56.00)
+1 hashs with 2097152 bytes (20.00% of keys, avg size 1.00)
+0 zsets with 0 bytes (00.00% of keys, avg size 0.00)`
+
+		result := parseKeyspaceAnalysis(output)
+		Expect(result.TopKeys).To(Equal([]databasesv1alpha1.KeyUsage{
+			{Key: "user:42", Type: "hash", Bytes: 2097152},
+			{Key: "session:1", Type: "string", Bytes: 72},
+		}))
+		Expect(result.KeyCounts).To(Equal(map[string]int64{
+			"string": 3,
+			"hash":   1,
+			"zset":   0,
+		}))
+	})
+
+	It("should parse the Elasticsearch shard analysis summary", func() {
+		output := `shards 1200 1100
+nodes 3
+hot metrics-000007 4500 92 1
+index logs-big 80000000000 logs
+tuned logs-big
+index logs-small 53687091300 -`
+
+		result := parseShardAnalysis(output)
+		Expect(result.TotalShards).To(Equal(int64(1200)))
+		Expect(result.SmallShards).To(Equal(int64(1100)))
+		Expect(result.DataNodes).To(Equal(int32(3)))
+		Expect(result.HotIndices).To(Equal([]databasesv1alpha1.HotIndexUsage{
+			{Index: "metrics-000007", IndexingRate: 4500, IndexingShare: 92, PrimaryShards: 1},
+		}))
+		Expect(result.OversizedIndices).To(Equal([]databasesv1alpha1.IndexShardUsage{
+			{Index: "logs-big", LargestShardBytes: 80000000000, RolloverAlias: "logs"},
+			{Index: "logs-small", LargestShardBytes: 53687091300},
+		}))
+		Expect(result.TunedIndices).To(Equal([]string{"logs-big"}))
+		Expect(shardAnalysisSummaryBytes).To(BeNumerically("<", 4096))
+	})
+
+	It("should replace only recommendations of the same type", func() {
+		database := &databasesv1alpha1.Database{}
+		database.Status.Recommendations = []databasesv1alpha1.Recommendation{
+			{Type: "Other", Severity: databasesv1alpha1.RecommendationSeverityInfo, Message: "keep"},
+			{Type: keyspaceAnalysisRecommendation, Severity: databasesv1alpha1.RecommendationSeverityWarning, Message: "stale"},
+		}
+
+		setRecommendations(database, keyspaceAnalysisRecommendation, nil)
+		Expect(database.Status.Recommendations).To(HaveLen(1))
+		Expect(database.Status.Recommendations[0].Message).To(Equal("keep"))
+	})
+})
@@ -0,0 +1,113 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// apply creates or updates a child resource with a server-side apply of its
+// desired state, as field manager FieldOwner. The desired object holds every
+// field the operator manages and nothing else: fields other managers set, like
+// sidecars injected into a pod template, are kept, and fields the operator
+// stopped setting are removed. Fields the operator set are taken back from
+// whoever changed them. Objects the operator updated before it applied them
+// are migrated first by upgradeManagedFields. The object is updated to the
+// applied resource.
+func (r *DatabaseReconciler) apply(ctx context.Context, object client.Object) error {
+	gvk, err := apiutil.GVKForObject(object, r.Client.Scheme())
+	if err != nil {
+		return err
+	}
+	object.GetObjectKind().SetGroupVersionKind(gvk)
+	if err := r.upgradeManagedFields(ctx, object); err != nil {
+		return err
+	}
+	object.SetManagedFields(nil)
+	object.SetResourceVersion("")
+	return r.Patch(ctx, object, client.Apply, client.FieldOwner(FieldOwner), client.ForceOwnership)
+}
+
+// upgradeManagedFields hands the fields the operator set with Update requests
+// over to its Apply entry in the managed fields of an existing object, so that
+// fields it stopped setting are removed by the apply rather than kept by the
+// Update entry. This runs until the object was applied once, as later Updates
+// of the operator, like stampApplied, set fields the apply does not own.
+func (r *DatabaseReconciler) upgradeManagedFields(ctx context.Context, object client.Object) error {
+	current := object.DeepCopyObject().(client.Object)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(object), current); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	updated := false
+	for _, entry := range current.GetManagedFields() {
+		if entry.Manager != FieldOwner || entry.Subresource != "" {
+			continue
+		}
+		if entry.Operation == metav1.ManagedFieldsOperationApply {
+			return nil
+		}
+		updated = true
+	}
+	if !updated {
+		return nil
+	}
+
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(current, sets.New(FieldOwner), FieldOwner)
+	if err != nil || patch == nil {
+		return err
+	}
+	return r.Patch(ctx, current, client.RawPatch(types.JSONPatchType, patch))
+}
+
+// appliedSpecStamped reports whether the spec of a tracked child resource is
+// the one recorded in its applied-spec-hash annotation, i.e. was not modified
+// by others since
+func appliedSpecStamped(object client.Object) bool {
+	hash, ok := object.GetAnnotations()[databasesv1alpha1.AppliedSpecHashAnnotation]
+	return ok && hash == appliedSpecHash(childSpec(object))
+}
+
+// stampApplied records the spec an apply left a tracked child resource with,
+// as completed by the API server. stamped tells whether the child was
+// unmodified before, otherwise the external change stays reported.
+func (r *DatabaseReconciler) stampApplied(ctx context.Context, object client.Object, stamped bool) error {
+	hash := appliedSpecHash(childSpec(object))
+	if !stamped || object.GetAnnotations()[databasesv1alpha1.AppliedSpecHashAnnotation] == hash {
+		return nil
+	}
+	patch := client.MergeFrom(object.DeepCopyObject().(client.Object))
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[databasesv1alpha1.AppliedSpecHashAnnotation] = hash
+	object.SetAnnotations(annotations)
+	return r.Patch(ctx, object, patch)
+}
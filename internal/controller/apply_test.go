@@ -0,0 +1,193 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// applyPatches emulates server-side apply, which the fake client does not
+// support, for a single field manager: the applied object is merged into the
+// stored one along with the removal of the fields no longer applied, so fields
+// set by others are kept and the applied ones are taken back. Lists are
+// replaced as a whole.
+func applyPatches() interceptor.Funcs {
+	applied := map[string][]byte{}
+	return interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if patch.Type() != types.ApplyPatchType {
+				return c.Patch(ctx, obj, patch, opts...)
+			}
+
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			key := gvk.String() + "/" + client.ObjectKeyFromObject(obj).String()
+			desired, err := json.Marshal(obj)
+			if err != nil {
+				return err
+			}
+
+			current := obj.DeepCopyObject().(client.Object)
+			if err := c.Get(ctx, client.ObjectKeyFromObject(obj), current); errors.IsNotFound(err) {
+				applied[key] = desired
+				return c.Create(ctx, obj)
+			} else if err != nil {
+				return err
+			}
+
+			var last client.Object
+			if _, ok := obj.(*unstructured.Unstructured); ok {
+				last = &unstructured.Unstructured{}
+			} else {
+				last = reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+			}
+			last.GetObjectKind().SetGroupVersionKind(gvk)
+			if previous, ok := applied[key]; ok {
+				if err := json.Unmarshal(previous, last); err != nil {
+					return err
+				}
+			}
+			data, err := client.MergeFrom(last).Data(obj)
+			if err != nil {
+				return err
+			}
+			fields := map[string]any{}
+			if err := json.Unmarshal(data, &fields); err != nil {
+				return err
+			}
+			var object map[string]any
+			if err := json.Unmarshal(desired, &object); err != nil {
+				return err
+			}
+			overlay(fields, object)
+			delete(fields, "status")
+			if data, err = json.Marshal(fields); err != nil {
+				return err
+			}
+
+			applied[key] = desired
+			return c.Patch(ctx, obj, client.RawPatch(types.MergePatchType, data))
+		},
+	}
+}
+
+// overlay sets the fields of an object in a JSON merge patch
+func overlay(patch, object map[string]any) {
+	for key, value := range object {
+		if fields, ok := value.(map[string]any); ok {
+			if nested, ok := patch[key].(map[string]any); ok {
+				overlay(nested, fields)
+				continue
+			}
+		}
+		patch[key] = value
+	}
+}
+
+var _ = Describe("Server-side apply", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	serviceKey := types.NamespacedName{Name: "orders-service", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build(),
+			Scheme: scheme,
+		}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "uid"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL},
+		}
+	})
+
+	It("should keep fields set by others and record the applied spec", func() {
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		Expect(reconciler.reconcileExternalChanges(ctx, database)).To(Succeed())
+		service := &corev1.Service{}
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+
+		service.Annotations["example.com/owner"] = "payments"
+		Expect(reconciler.Update(ctx, service)).To(Succeed())
+
+		database.Spec.Networking = &databasesv1alpha1.NetworkingSpec{ServiceType: corev1.ServiceTypeNodePort}
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeNodePort))
+		Expect(service.Annotations).To(HaveKeyWithValue("example.com/owner", "payments"))
+		Expect(service.Annotations).To(HaveKeyWithValue(databasesv1alpha1.AppliedSpecHashAnnotation, appliedSpecHash(service.Spec)))
+	})
+
+	It("should take back fields changed by others", func() {
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		Expect(reconciler.reconcileExternalChanges(ctx, database)).To(Succeed())
+		service := &corev1.Service{}
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		service.Spec.Ports[0].Port = 6543
+		Expect(reconciler.Update(ctx, service)).To(Succeed())
+
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(service.Spec.Ports[0].Port).To(Equal(int32(5432)))
+		Expect(appliedSpecStamped(service)).To(BeTrue())
+	})
+
+	It("should migrate the fields the operator updated before applying", func() {
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		service := &corev1.Service{}
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		service.ManagedFields = []metav1.ManagedFieldsEntry{{
+			Manager:    FieldOwner,
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:type":{}}}`)},
+		}}
+		Expect(reconciler.Update(ctx, service)).To(Succeed())
+
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(service.ManagedFields).To(ConsistOf(And(
+			HaveField("Manager", FieldOwner),
+			HaveField("Operation", metav1.ManagedFieldsOperationApply),
+		)))
+	})
+})
@@ -0,0 +1,170 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// autoUpgradeCheckInterval is how often the registry is asked for new
+	// patch releases
+	autoUpgradeCheckInterval = time.Hour
+
+	reasonAutoUpgrade = "AutoUpgrade"
+)
+
+// releaseParts splits a version tag into the numbers of its release and the
+// suffix after them, such as 16, 4 and -alpine for 16.4-alpine
+func releaseParts(tag string) ([]string, string) {
+	release := releasePattern.FindString(tag)
+	if release == "" {
+		return nil, ""
+	}
+	return strings.Split(release, "."), tag[len(release):]
+}
+
+// patchRelease reports whether candidate is a later release than current that
+// differs from it in the last number only, with the same suffix: 7.2.5 and
+// 7.2.4, or 16.4 and 16.2 for PostgreSQL, whose releases have two numbers
+func patchRelease(current, candidate string) bool {
+	currentNumbers, currentSuffix := releaseParts(current)
+	numbers, suffix := releaseParts(candidate)
+	if len(currentNumbers) < 2 || len(numbers) != len(currentNumbers) || suffix != currentSuffix ||
+		!slices.Equal(numbers[:len(numbers)-1], currentNumbers[:len(currentNumbers)-1]) {
+		return false
+	}
+	result, ok := compareReleases(candidate, current)
+	return ok && result > 0
+}
+
+// latestPatchRelease returns the latest patch release of current among
+// versions, or current when there is none
+func latestPatchRelease(current string, versions []string) string {
+	latest := current
+	for _, version := range versions {
+		if patchRelease(latest, version) {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// reconcileAutoUpgrade moves spec.version to the latest patch release of its
+// minor version when maintenance.autoUpgrade is set. The versions come from the
+// DatabaseVersions of the engine, leaving out deprecated ones, or without any
+// from the registry of the image, which is asked for its tags every
+// autoUpgradeCheckInterval. The upgrade waits for a maintenance window, the end
+// of a release freeze and of the running operation. The new version then rolls out like one set by the user.
+func (r *DatabaseReconciler) reconcileAutoUpgrade(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) error {
+	if maintenance := database.Spec.Maintenance; maintenance == nil || !maintenance.AutoUpgrade {
+		database.Status.AutoUpgrade = nil
+		return nil
+	}
+	status := database.Status.AutoUpgrade
+	if status == nil {
+		status = &databasesv1alpha1.AutoUpgradeStatus{}
+		database.Status.AutoUpgrade = status
+	}
+	version := database.Spec.Version
+	if numbers, _ := releaseParts(version); len(numbers) < 2 {
+		status.LatestVersion = ""
+		status.Message = fmt.Sprintf("Version %s names no patch release to upgrade from", version)
+		return nil
+	}
+
+	entries, err := listDatabaseVersions(ctx, r.Client, database)
+	if err != nil {
+		return err
+	}
+	// DatabaseVersions are watched, so a catalog of the engine is read on every
+	// reconcile. Otherwise check again when due, or right away once
+	// spec.version moved to another minor version than the latest patch
+	// release found.
+	if len(entries) > 0 {
+		status.LatestVersion = latestPatchRelease(version, catalogVersions(entries))
+		status.Message = ""
+	} else if status.CheckedAt == nil || now.Sub(status.CheckedAt.Time) >= autoUpgradeCheckInterval ||
+		(status.LatestVersion != version && !patchRelease(version, status.LatestVersion)) {
+		catalog := r.VersionCatalog
+		if catalog == nil {
+			catalog = &RegistryResolver{Client: r.HTTPClient}
+		}
+		repository := engineLayout(database).repository
+		checkedAt := metav1.NewTime(now)
+		status.CheckedAt = &checkedAt
+		versions, err := catalog.Versions(ctx, repository)
+		if err != nil {
+			// The workload does not depend on the catalog, so the check is only
+			// retried once due
+			status.Message = fmt.Sprintf("Failed to list the versions of %s: %v", repository, err)
+			log.FromContext(ctx).Error(err, "Failed to list the versions of the engine image", "image", repository)
+			return nil
+		}
+		status.LatestVersion = latestPatchRelease(version, versions)
+		status.Message = ""
+	}
+	latest := status.LatestVersion
+	if !patchRelease(version, latest) {
+		status.Message = ""
+		return nil
+	}
+
+	switch maintenance := database.Status.Maintenance; {
+	case frozen(database, now):
+		status.Message = fmt.Sprintf("Upgrade to %s waits for the release freeze to end", latest)
+		return nil
+	case maintenance == nil || !maintenance.Open:
+		status.Message = fmt.Sprintf("Upgrade to %s waits for the next maintenance window", latest)
+		return nil
+	case activeOperation(database) != "":
+		status.Message = fmt.Sprintf("Upgrade to %s waits for the %s operation to finish", latest, activeOperation(database))
+		return nil
+	case majorUpgradeRunning(database.Status.MajorUpgrade):
+		status.Message = fmt.Sprintf("Upgrade to %s waits for the major upgrade to finish", latest)
+		return nil
+	}
+
+	// Patch a copy so the status computed so far is not replaced by the stored one
+	upgraded := database.DeepCopy()
+	upgraded.Spec.Version = latest
+	if err := r.Patch(ctx, upgraded, client.MergeFrom(database)); err != nil {
+		return err
+	}
+	database.Spec.Version = upgraded.Spec.Version
+	database.Generation, database.ResourceVersion = upgraded.Generation, upgraded.ResourceVersion
+
+	upgradedAt := metav1.NewTime(now)
+	status.FromVersion, status.ToVersion, status.UpgradedAt = version, latest, &upgradedAt
+	status.Message = ""
+	message := fmt.Sprintf("Upgrading %s from %s to the latest patch release %s", database.Spec.Type, version, latest)
+	r.event(database, corev1.EventTypeNormal, reasonAutoUpgrade, message)
+	recordOperation(database, recordAutoUpgrade, databasesv1alpha1.OperationSucceeded, message, now)
+	log.FromContext(ctx).Info("Upgraded to the latest patch release", "from", version, "to", latest)
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// staticCatalog lists the same versions for every image and counts the calls
+type staticCatalog struct {
+	versions []string
+	calls    int
+}
+
+func (c *staticCatalog) Versions(_ context.Context, _ string) ([]string, error) {
+	c.calls++
+	return c.versions, nil
+}
+
+var _ = Describe("Automatic patch upgrades", func() {
+	It("should pick the latest patch release of the same minor version and suffix", func() {
+		versions := []string{"16", "16.1", "16.3", "16.4-alpine", "16.10", "17.0", "16.12rc1", "latest"}
+		Expect(latestPatchRelease("16.2", versions)).To(Equal("16.10"))
+		Expect(latestPatchRelease("16.2-alpine", versions)).To(Equal("16.4-alpine"))
+		Expect(latestPatchRelease("7.2.4", []string{"7.2.5", "7.4.0", "7.2.11", "7.2"})).To(Equal("7.2.11"))
+		Expect(latestPatchRelease("16.10", versions)).To(Equal("16.10"))
+		Expect(patchRelease("16", "16.4")).To(BeFalse())
+	})
+
+	It("should upgrade to the latest patch release inside the next maintenance window", func() {
+		ctx := context.Background()
+		// Saturday 1 March 2025, 21:30 in Paris
+		now := time.Date(2025, 3, 1, 20, 30, 0, 0, time.UTC)
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16.2",
+				Maintenance: &databasesv1alpha1.MaintenanceSpec{
+					TimeZone:    "Europe/Paris",
+					Windows:     []databasesv1alpha1.MaintenanceWindow{{Days: []databasesv1alpha1.Weekday{"Sat"}, Start: "22:00", End: "04:00"}},
+					AutoUpgrade: true,
+				},
+			},
+			Status: databasesv1alpha1.DatabaseStatus{ReadyReplicas: 1},
+		}
+		scheme := runtime.NewScheme()
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(database).WithStatusSubresource(database).Build()
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(database), database)).To(Succeed())
+		catalog := &staticCatalog{versions: []string{"16.2", "16.3", "16.4", "17.0"}}
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &DatabaseReconciler{Client: c, Scheme: scheme, VersionCatalog: catalog, Recorder: recorder}
+
+		By("waiting for the window")
+		Expect(reconcileMaintenance(ctx, database, now)).To(Succeed())
+		Expect(reconciler.reconcileAutoUpgrade(ctx, database, now)).To(Succeed())
+		Expect(database.Spec.Version).To(Equal("16.2"))
+		Expect(database.Status.AutoUpgrade.LatestVersion).To(Equal("16.4"))
+		Expect(database.Status.AutoUpgrade.Message).To(ContainSubstring("next maintenance window"))
+
+		By("upgrading once the window opened, without asking the registry again")
+		opened := now.Add(45 * time.Minute)
+		Expect(reconcileMaintenance(ctx, database, opened)).To(Succeed())
+		Expect(reconciler.reconcileAutoUpgrade(ctx, database, opened)).To(Succeed())
+		Expect(catalog.calls).To(Equal(1))
+		Expect(database.Spec.Version).To(Equal("16.4"))
+		stored := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(database), stored)).To(Succeed())
+		Expect(stored.Spec.Version).To(Equal("16.4"))
+		Expect(stored.ResourceVersion).To(Equal(database.ResourceVersion))
+		Expect(database.Status.AutoUpgrade).To(And(
+			HaveField("FromVersion", "16.2"),
+			HaveField("ToVersion", "16.4"),
+			HaveField("Message", ""),
+		))
+		Expect(database.Status.RecentOperations).To(ConsistOf(HaveField("Type", recordAutoUpgrade)))
+		Expect(recorder.Events).To(Receive(ContainSubstring("from 16.2 to the latest patch release 16.4")))
+
+		By("checking the registry again once due")
+		catalog.versions = append(catalog.versions, "16.5")
+		Expect(reconcileMaintenance(ctx, database, opened.Add(6*time.Hour))).To(Succeed())
+		Expect(reconciler.reconcileAutoUpgrade(ctx, database, opened.Add(6*time.Hour))).To(Succeed())
+		Expect(catalog.calls).To(Equal(2))
+		Expect(database.Spec.Version).To(Equal("16.4"))
+		Expect(database.Status.AutoUpgrade.Message).To(ContainSubstring("next maintenance window"))
+
+		By("dropping the status without automatic upgrades")
+		database.Spec.Maintenance.AutoUpgrade = false
+		Expect(reconciler.reconcileAutoUpgrade(ctx, database, opened)).To(Succeed())
+		Expect(database.Status.AutoUpgrade).To(BeNil())
+	})
+})
@@ -0,0 +1,336 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	backupComponent          = "backup"
+	backupMountPath          = "/backups"
+	defaultBackupSchedule    = "0 2 * * *"
+	defaultBackupStorageSize = "10Gi"
+
+	conditionBackupConfigured = "BackupConfigured"
+)
+
+// Dump commands writing a backup of the database to $BACKUP_FILE
+var backupScripts = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: `pg_dump -h "$DB_HOST" -Fc -f "$BACKUP_FILE"`,
+	databasesv1alpha1.DatabaseTypeMongoDB: `mongodump --host "$DB_HOST" -u "$MONGO_USERNAME" -p "$MONGO_PASSWORD" ` +
+		`--authenticationDatabase admin --gzip --archive="$BACKUP_FILE"`,
+	databasesv1alpha1.DatabaseTypeRedis:  `redis-cli -h "$DB_HOST" --rdb "$BACKUP_FILE"`,
+	databasesv1alpha1.DatabaseTypeSQLite: `sqlite3 "$SQLITE_DATABASE" ".backup '$BACKUP_FILE'"`,
+}
+
+// File extension of the backups of each engine
+var backupExtensions = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: "dump",
+	databasesv1alpha1.DatabaseTypeMongoDB:    "archive.gz",
+	databasesv1alpha1.DatabaseTypeRedis:      "rdb",
+	databasesv1alpha1.DatabaseTypeSQLite:     "db",
+}
+
+// backupScript wraps the engine dump command so that only complete backups get
+// their final name, and reports the size of the backup as termination message.
+// name is evaluated by the shell.
+func backupScript(database *databasesv1alpha1.Database, name string) string {
+	return fmt.Sprintf(`set -e
+name="%s"
+BACKUP_FILE="%s/.$name.partial"
+%s
+mv "$BACKUP_FILE" "%s/$name"
+wc -c < "%s/$name" > /dev/termination-log`,
+		name, backupMountPath, backupScripts[database.Spec.Type], backupMountPath, backupMountPath)
+}
+
+// scheduledBackupName names the backups of a CronJob after their start time
+func scheduledBackupName(database *databasesv1alpha1.Database, schedule databasesv1alpha1.BackupSchedule) string {
+	return fmt.Sprintf("%s-$(date -u +%%Y%%m%%dT%%H%%M%%SZ)%s", scheduleBackupPrefix(database, schedule), backupFileExtension(database))
+}
+
+// reconcileBackup manages the backup CronJob and its volume, and reports the
+// result in the BackupConfigured condition
+func (r *DatabaseReconciler) reconcileBackup(ctx context.Context, database *databasesv1alpha1.Database) error {
+	spec := database.Spec.Backup
+
+	if err := r.pruneDatabaseBackups(ctx, database); err != nil {
+		return err
+	}
+
+	if err := r.reconcileBackupSchedules(ctx, database); err != nil {
+		setBackupConfigured(database, metav1.ConditionFalse, "ScheduleFailed", err.Error())
+		return err
+	}
+
+	var desired *batchv1.CronJob
+	method := backupMethod(database)
+	if spec != nil && spec.Enabled {
+		schedule := mainBackupSchedule(database)
+		switch method {
+		case databasesv1alpha1.BackupMethodDump:
+			if err := r.reconcileBackupVolume(ctx, database); err != nil {
+				setBackupConfigured(database, metav1.ConditionFalse, "VolumeFailed", err.Error())
+				return err
+			}
+			desired = r.createBackupCronJob(database, schedule)
+		case databasesv1alpha1.BackupMethodSnapshot:
+			desired = r.createSnapshotCronJob(database, schedule)
+		}
+	}
+
+	cronJob, err := r.reconcileCronJob(ctx, database, backupComponent, desired)
+	if err != nil {
+		if desired != nil {
+			setBackupConfigured(database, metav1.ConditionFalse, "CronJobFailed", err.Error())
+		}
+		return err
+	}
+	if walArchivingEnabled(database) {
+		setBackupConfigured(database, metav1.ConditionTrue, "WALArchiving",
+			fmt.Sprintf("WAL archived to %s, base backups every %s",
+				walgPrefix(database, "<pod>"), baseBackupInterval(database)))
+		return nil
+	}
+	if pgBackRestEnabled(database) {
+		setBackupConfigured(database, metav1.ConditionTrue, "PgBackRest",
+			fmt.Sprintf("pgBackRest backups to %s, incremental every %s",
+				backupDestination(database)+"/<pod>", pgBackRestInterval(database)))
+		return nil
+	}
+	if cronJob == nil {
+		meta.RemoveStatusCondition(&database.Status.Conditions, conditionBackupConfigured)
+		return nil
+	}
+
+	if method == databasesv1alpha1.BackupMethodSnapshot {
+		if err := r.reconcileScheduledSnapshot(ctx, database, mainBackupSchedule(database), cronJob); err != nil {
+			setBackupConfigured(database, metav1.ConditionFalse, "SnapshotFailed", err.Error())
+			return err
+		}
+		setBackupConfigured(database, metav1.ConditionTrue, "SnapshotsScheduled",
+			fmt.Sprintf("VolumeSnapshots scheduled at %q", cronJob.Spec.Schedule))
+		return nil
+	}
+
+	message := fmt.Sprintf("Backups scheduled at %q", cronJob.Spec.Schedule)
+	for _, backupCopy := range spec.Copies {
+		message += fmt.Sprintf(", copied to %s", backupCopyPrefix(database, backupCopy.S3))
+	}
+	if cronJob.Status.LastSuccessfulTime != nil {
+		message += fmt.Sprintf(", last successful backup at %s", cronJob.Status.LastSuccessfulTime.UTC().Format(time.RFC3339))
+	}
+	setBackupConfigured(database, metav1.ConditionTrue, "CronJobReady", message)
+
+	return nil
+}
+
+// reconcileBackupVolume creates the volume backups are written to. It has no owner
+// reference so backups survive the deletion of the Database.
+func (r *DatabaseReconciler) reconcileBackupVolume(ctx context.Context, database *databasesv1alpha1.Database) error {
+	var storage *databasesv1alpha1.StorageSpec
+	if backup := database.Spec.Backup; backup != nil {
+		storage = backup.Storage
+	}
+	return r.reconcileBackupClaim(ctx, database, backupClaimName(database), storage)
+}
+
+// reconcileBackupClaim creates a backup volume, 10Gi unless storage says otherwise
+func (r *DatabaseReconciler) reconcileBackupClaim(ctx context.Context, database *databasesv1alpha1.Database, name string, storage *databasesv1alpha1.StorageSpec) error {
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, pvc)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	size := defaultBackupStorageSize
+	var storageClass *string
+	accessMode := corev1.ReadWriteOnce
+	if storage != nil {
+		size = storage.Size
+		storageClass = storage.StorageClass
+		if storage.AccessMode != "" {
+			accessMode = corev1.PersistentVolumeAccessMode(storage.AccessMode)
+		}
+	}
+
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return fmt.Errorf("invalid backup storage size %q: %w", size, err)
+	}
+
+	pvc = &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: database.Namespace,
+			Labels:    r.getComponentLabels(database, backupComponent),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: quantity,
+				},
+			},
+			StorageClassName: storageClass,
+		},
+	}
+
+	log.FromContext(ctx).Info("Creating backup volume", "name", pvc.Name)
+	return r.Create(ctx, pvc)
+}
+
+// createBackupCronJob builds the CronJob dumping the database to the backup
+// volume of a schedule
+func (r *DatabaseReconciler) createBackupCronJob(database *databasesv1alpha1.Database, schedule databasesv1alpha1.BackupSchedule) *batchv1.CronJob {
+	name := scheduledBackupName(database, schedule)
+	cronJob := r.createAdminCronJob(database, scheduleComponent(schedule), cronSchedule(schedule),
+		backupScript(database, name)+backupPruneScript(database, schedule), nil)
+	successfulJobsHistoryLimit := int32(3)
+	cronJob.Spec.SuccessfulJobsHistoryLimit = &successfulJobsHistoryLimit
+	podSpec := &cronJob.Spec.JobTemplate.Spec.Template.Spec
+	r.mountBackupVolumes(database, scheduleClaimName(database, schedule), podSpec)
+	if backupEncryption(database) != nil {
+		r.encryptBackupPod(database, podSpec, name, backupPruneScript(database, schedule))
+	}
+	if len(schedule.Copies) > 0 {
+		r.copyBackupPod(database, podSpec, schedule)
+	}
+
+	return cronJob
+}
+
+// mountBackupVolumes mounts a backup volume into a backup pod, and the data
+// volume for engines backed up from their files
+func (r *DatabaseReconciler) mountBackupVolumes(database *databasesv1alpha1.Database, claim string, podSpec *corev1.PodSpec) {
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: backupComponent,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claim,
+			},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      backupComponent,
+		MountPath: backupMountPath,
+	})
+
+	// SQLite is backed up from its data volume rather than over the network
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite {
+		r.mountSQLiteData(database, podSpec)
+	}
+}
+
+// mountSQLiteData mounts the data volume of a SQLite Database into an admin pod.
+// The volume is ReadWriteOnce, so the pod is scheduled onto the node running
+// the SQLite pod.
+func (r *DatabaseReconciler) mountSQLiteData(database *databasesv1alpha1.Database, podSpec *corev1.PodSpec) {
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: "data",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: database.Name + "-data",
+			},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      "data",
+		MountPath: engineLayout(database).dataPath,
+	})
+
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+	podSpec.Affinity.PodAffinity = &corev1.PodAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+			LabelSelector: &metav1.LabelSelector{MatchLabels: r.getLabels(database)},
+			TopologyKey:   corev1.LabelHostname,
+		}},
+	}
+}
+
+// validateBackupStorage checks the size of the backup volume, and that SQLite,
+// backed up from its files, keeps them on a data volume
+func validateBackupStorage(database *databasesv1alpha1.Database) error {
+	if storage := database.Spec.Backup.Storage; storage != nil {
+		if _, err := resource.ParseQuantity(storage.Size); err != nil {
+			return fmt.Errorf("invalid backup storage size %q: %w", storage.Size, err)
+		}
+	}
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite && database.Spec.Storage == nil {
+		return fmt.Errorf("%s backups are taken from the data volume, backup requires storage", database.Spec.Type)
+	}
+	return nil
+}
+
+// backupMethod returns the configured backup method
+func backupMethod(database *databasesv1alpha1.Database) databasesv1alpha1.BackupMethod {
+	if database.Spec.Backup == nil || database.Spec.Backup.Method == "" {
+		return databasesv1alpha1.BackupMethodDump
+	}
+	return database.Spec.Backup.Method
+}
+
+// s3CredentialsEnv reads the AWS credentials of S3 clients from a Secret
+func s3CredentialsEnv(secret string) []corev1.EnvVar {
+	if secret == "" {
+		return nil
+	}
+
+	env := []corev1.EnvVar{}
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		env = append(env, corev1.EnvVar{
+			Name: key,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+					Key:                  key,
+				},
+			},
+		})
+	}
+	return env
+}
+
+func backupClaimName(database *databasesv1alpha1.Database) string {
+	return database.Name + "-backups"
+}
+
+func setBackupConfigured(database *databasesv1alpha1.Database, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               conditionBackupConfigured,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+}
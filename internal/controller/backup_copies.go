@@ -0,0 +1,182 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	backupCopyContainerPrefix = "copy-"
+	defaultBackupCopyImage    = "amazon/aws-cli"
+)
+
+// backupNameFile is where the container writing a backup records its name for
+// the copy containers
+var backupNameFile = path.Join(backupStagingPath, "name")
+
+// validateBackupCopies checks that the copies of a schedule are written by its method
+func validateBackupCopies(copies []databasesv1alpha1.BackupCopy, method databasesv1alpha1.BackupMethod) error {
+	if len(copies) > 0 && method != databasesv1alpha1.BackupMethodDump {
+		return fmt.Errorf("copies only apply to Dump backups, not %s", method)
+	}
+	return nil
+}
+
+// backupCopyPrefix returns the S3 prefix the dumps of the Database are copied to
+func backupCopyPrefix(database *databasesv1alpha1.Database, s3 databasesv1alpha1.S3Destination) string {
+	parts := []string{s3.Bucket}
+	if path := strings.Trim(s3.Path, "/"); path != "" {
+		parts = append(parts, path)
+	}
+	parts = append(parts, database.Namespace, database.Name)
+	return "s3://" + strings.Join(parts, "/")
+}
+
+// backupS3Destinations returns every S3 destination backup pods write to: the
+// one of backup.s3 and those of the copies
+func backupS3Destinations(database *databasesv1alpha1.Database) []databasesv1alpha1.S3Destination {
+	backup := database.Spec.Backup
+	if backup == nil || !backup.Enabled {
+		return nil
+	}
+
+	destinations := []databasesv1alpha1.S3Destination{}
+	if backup.S3 != nil {
+		destinations = append(destinations, *backup.S3)
+	}
+	for _, backupCopy := range backup.Copies {
+		destinations = append(destinations, backupCopy.S3)
+	}
+	for _, schedule := range backup.Schedules {
+		for _, backupCopy := range schedule.Copies {
+			destinations = append(destinations, backupCopy.S3)
+		}
+	}
+	return destinations
+}
+
+// copyScript uploads the backup named in backupNameFile to $COPY_PREFIX, then
+// deletes the copies of the schedule beyond the retention of the copy. Copies
+// are named after their start time, so their names sort chronologically.
+func copyScript(database *databasesv1alpha1.Database, schedule databasesv1alpha1.BackupSchedule, backupCopy databasesv1alpha1.BackupCopy) string {
+	encrypted := backupEncryption(database) != nil
+	script := "set -e\n"
+	if backupCopy.S3.ForcePathStyle {
+		script += "aws configure set default.s3.addressing_style path\n"
+	}
+	script += fmt.Sprintf(`name="$(cat %s)"
+aws s3 cp "%s/$name" "$COPY_PREFIX/$name"`, backupNameFile, backupMountPath)
+	if encrypted {
+		script += fmt.Sprintf(`
+aws s3 cp "%s/$name%s" "$COPY_PREFIX/$name%s"`, backupMountPath, backupManifestSuffix, backupManifestSuffix)
+	}
+	script += fmt.Sprintf(`
+wc -c < "%s/$name" > /dev/termination-log`, backupMountPath)
+
+	retention := backupCopy.Retention
+	if retention == nil {
+		return script
+	}
+
+	prefix := scheduleBackupPrefix(database, schedule) + "-"
+	pattern := "^" + regexp.QuoteMeta(prefix) + "[0-9]{8}T[0-9]{6}Z" + regexp.QuoteMeta(backupFileExtension(database)) + "$"
+	remove := `aws s3 rm "$COPY_PREFIX/$file"`
+	if encrypted {
+		remove += fmt.Sprintf(`; aws s3 rm "$COPY_PREFIX/$file%s"`, backupManifestSuffix)
+	}
+	script += fmt.Sprintf(`
+copies() { aws s3 ls "$COPY_PREFIX/" | sed 's/.* //' | grep -E '%s'; }
+expire() { while read -r file; do %s; done; }`, pattern, remove)
+	if retention.MaxCount != nil {
+		script += fmt.Sprintf("\ncopies | sort -r | tail -n +%d | expire", *retention.MaxCount+1)
+	}
+	if retention.MaxAge != nil {
+		script += fmt.Sprintf(`
+cutoff="%s$(date -u -d "@$(($(date +%%s) - %d))" +%%Y%%m%%dT%%H%%M%%SZ)"
+{ copies; echo "$cutoff"; } | sort | sed "/^$cutoff\$/,\$d" | expire`, prefix, int64(retention.MaxAge.Seconds()))
+	}
+	return script
+}
+
+// copyBackupPod makes a backup pod upload its backup to the copies of the
+// schedule: the container writing the backup moves to the init containers and
+// records the name of the backup, then a container per copy uploads it. The
+// copy containers run in parallel and all report the size of the backup.
+func (r *DatabaseReconciler) copyBackupPod(database *databasesv1alpha1.Database, podSpec *corev1.PodSpec, schedule databasesv1alpha1.BackupSchedule) {
+	staging := corev1.VolumeMount{Name: backupStagingVolume, MountPath: backupStagingPath}
+	found := false
+	for _, volume := range podSpec.Volumes {
+		found = found || volume.Name == backupStagingVolume
+	}
+	if !found {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name:         backupStagingVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+	}
+
+	writer := podSpec.Containers[0]
+	writer.Command = []string{"/bin/sh", "-c", fmt.Sprintf("%s\necho \"$name\" > %s", writer.Command[2], backupNameFile)}
+	found = false
+	for _, mount := range writer.VolumeMounts {
+		found = found || mount.Name == backupStagingVolume
+	}
+	if !found {
+		writer.VolumeMounts = append(writer.VolumeMounts, staging)
+	}
+	podSpec.InitContainers = append(podSpec.InitContainers, writer)
+
+	containers := []corev1.Container{}
+	for _, backupCopy := range schedule.Copies {
+		image := backupCopy.Image
+		if image == "" {
+			image = defaultBackupCopyImage
+		}
+		env := []corev1.EnvVar{{Name: "COPY_PREFIX", Value: backupCopyPrefix(database, backupCopy.S3)}}
+		if backupCopy.S3.Endpoint != "" {
+			env = append(env, corev1.EnvVar{Name: "AWS_ENDPOINT_URL", Value: backupCopy.S3.Endpoint})
+		}
+		if backupCopy.S3.Region != "" {
+			env = append(env, corev1.EnvVar{Name: "AWS_REGION", Value: backupCopy.S3.Region})
+		}
+		env = append(env, s3CredentialsEnv(backupCopy.S3.CredentialsSecret)...)
+
+		container := corev1.Container{
+			Name:    backupCopyContainerPrefix + backupCopy.Name,
+			Image:   image,
+			Command: []string{"/bin/sh", "-c", copyScript(database, schedule, backupCopy)},
+			Env:     env,
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: backupComponent, MountPath: backupMountPath, ReadOnly: true},
+				staging,
+			},
+		}
+		r.addCABundle(database, podSpec, &container)
+		r.addProxyEnv(database, &container)
+		r.restrictContainer(database, &container)
+		containers = append(containers, container)
+	}
+	podSpec.Containers = containers
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Backup copies", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Backup: &databasesv1alpha1.BackupSpec{
+					Enabled:   true,
+					Retention: &databasesv1alpha1.BackupRetention{MaxCount: ptr.To(int32(7))},
+					Copies: []databasesv1alpha1.BackupCopy{{
+						Name: "offsite",
+						S3: databasesv1alpha1.S3Destination{
+							Bucket: "dr", Path: "/postgres/", Region: "eu-central-1", CredentialsSecret: "dr-s3",
+						},
+						Retention: &databasesv1alpha1.BackupRetention{
+							MaxCount: ptr.To(int32(30)),
+							MaxAge:   &metav1.Duration{Duration: 90 * 24 * time.Hour},
+						},
+					}},
+				},
+			},
+		}
+	})
+
+	It("should upload the dumps written to the volume and prune the copies separately", func() {
+		cronJob := reconciler.createBackupCronJob(database, mainBackupSchedule(database))
+		podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+
+		Expect(podSpec.InitContainers).To(HaveLen(1))
+		writer := podSpec.InitContainers[0]
+		Expect(writer.Name).To(Equal(backupComponent))
+		Expect(writer.Command[2]).To(ContainSubstring("ls -1t orders-[0-9]*T[0-9]*Z.dump 2>/dev/null | tail -n +8"))
+		Expect(writer.Command[2]).To(HaveSuffix(`echo "$name" > /staging/name`))
+		Expect(writer.VolumeMounts).To(ContainElements(HaveField("Name", backupComponent), HaveField("Name", backupStagingVolume)))
+
+		Expect(podSpec.Containers).To(HaveLen(1))
+		upload := podSpec.Containers[0]
+		Expect(upload.Name).To(Equal("copy-offsite"))
+		Expect(upload.Image).To(Equal(defaultBackupCopyImage))
+		Expect(upload.Env).To(ContainElements(
+			corev1.EnvVar{Name: "COPY_PREFIX", Value: "s3://dr/postgres/shop/orders"},
+			corev1.EnvVar{Name: "AWS_REGION", Value: "eu-central-1"},
+			HaveField("ValueFrom.SecretKeyRef.Name", "dr-s3")))
+		Expect(upload.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: backupComponent, MountPath: backupMountPath, ReadOnly: true}))
+		script := upload.Command[2]
+		Expect(script).To(ContainSubstring(`aws s3 cp "/backups/$name" "$COPY_PREFIX/$name"`))
+		Expect(script).To(ContainSubstring(`grep -E '^orders-[0-9]{8}T[0-9]{6}Z\.dump$'`))
+		Expect(script).To(ContainSubstring("copies | sort -r | tail -n +31 | expire"))
+		Expect(script).To(ContainSubstring(`cutoff="orders-$(date -u -d "@$(($(date +%s) - 7776000))" +%Y%m%dT%H%M%SZ)"`))
+		Expect(script).NotTo(ContainSubstring(backupManifestSuffix))
+	})
+
+	It("should copy encrypted dumps with their manifest", func() {
+		database.Spec.Backup.Encryption = &databasesv1alpha1.BackupEncryption{KMSKeyID: "arn:aws:kms:eu-west-1:123456789012:key/orders"}
+		schedule := databasesv1alpha1.BackupSchedule{
+			Name:     "hourly",
+			Schedule: "@hourly",
+			Copies: []databasesv1alpha1.BackupCopy{
+				{Name: "aws", S3: databasesv1alpha1.S3Destination{Bucket: "dr"}},
+				{Name: "minio", S3: databasesv1alpha1.S3Destination{Bucket: "local", Endpoint: "http://minio.storage:9000", ForcePathStyle: true}},
+			},
+		}
+
+		podSpec := reconciler.createBackupCronJob(database, schedule).Spec.JobTemplate.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(HaveExactElements(HaveField("Name", backupDumpContainer), HaveField("Name", "backup-hourly")))
+		Expect(podSpec.Volumes).To(ContainElement(HaveField("Name", backupStagingVolume)))
+		Expect(podSpec.Containers).To(HaveExactElements(HaveField("Name", "copy-aws"), HaveField("Name", "copy-minio")))
+		Expect(podSpec.Containers[0].Command[2]).To(ContainSubstring(`aws s3 cp "/backups/$name.manifest.json" "$COPY_PREFIX/$name.manifest.json"`))
+		Expect(podSpec.Containers[1].Command[2]).To(HavePrefix("set -e\naws configure set default.s3.addressing_style path\n"))
+		Expect(podSpec.Containers[1].Env).To(ContainElement(corev1.EnvVar{Name: "AWS_ENDPOINT_URL", Value: "http://minio.storage:9000"}))
+
+		By("letting backup Jobs reach every copy endpoint and the KMS")
+		database.Spec.Backup.Schedules = []databasesv1alpha1.BackupSchedule{schedule}
+		egress := reconciler.createJobsNetworkPolicy(database).Spec.Egress
+		Expect(egress).To(HaveLen(4))
+		Expect(egress[2].Ports).To(HaveExactElements(
+			HaveField("Port", HaveValue(Equal(intstr.FromInt(443)))),
+			HaveField("Port", HaveValue(Equal(intstr.FromInt(9000))))))
+		Expect(egress[3].Ports).To(HaveExactElements(HaveField("Port", HaveValue(Equal(intstr.FromInt(443))))))
+	})
+
+	It("should only copy dumps", func() {
+		database.Spec.Storage = &databasesv1alpha1.StorageSpec{Size: "10Gi", Snapshots: true}
+		database.Spec.Backup.Method = databasesv1alpha1.BackupMethodSnapshot
+		Expect(reconciler.validateSpec(database)).To(MatchError("backup: copies only apply to Dump backups, not Snapshot"))
+
+		database.Spec.Backup.Method = databasesv1alpha1.BackupMethodDump
+		database.Spec.Backup.Schedules = []databasesv1alpha1.BackupSchedule{{
+			Name:     "weekly",
+			Method:   databasesv1alpha1.BackupMethodSnapshot,
+			Schedule: "@weekly",
+			Copies:   database.Spec.Backup.Copies,
+		}}
+		Expect(reconciler.validateSpec(database)).To(MatchError("backup schedule weekly: copies only apply to Dump backups, not Snapshot"))
+	})
+})
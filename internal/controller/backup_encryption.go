@@ -0,0 +1,286 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// Encrypted backups are written as <file>.enc next to <file>.enc.manifest.json,
+// which records the KMS key, the cipher parameters and the data key wrapped by
+// the KMS key. The data key itself never leaves the backup pod unwrapped.
+
+const (
+	backupEncryptedExtension = ".enc"
+	backupManifestSuffix     = ".manifest.json"
+	backupStagingVolume      = "staging"
+	backupStagingPath        = "/staging"
+	backupDumpContainer      = "dump"
+	backupDecryptContainer   = "decrypt"
+	kmsCredentialsVolume     = "kms-credentials"
+	kmsCredentialsPath       = "/var/run/kms"
+
+	defaultAWSKMSImage    = "amazon/aws-cli"
+	defaultGoogleKMSImage = "google/cloud-sdk:slim"
+)
+
+// kmsProvider describes how a KMS client wraps and unwraps data keys
+type kmsProvider struct {
+	image string
+	// wrapKey sets $key to a new data key, hex encoded, and $wrapped to the data
+	// key encrypted with $KMS_KEY_ID, base64 encoded
+	wrapKey string
+	// unwrapKey sets $key from $wrapped, decrypted with $kms_key
+	unwrapKey string
+}
+
+var awsKMS = kmsProvider{
+	image: defaultAWSKMSImage,
+	wrapKey: `set -- $(aws kms generate-data-key --key-id "$KMS_KEY_ID" --key-spec AES_256 --query '[Plaintext,CiphertextBlob]' --output text)
+key="$(echo "$1" | base64 -d | od -An -v -tx1 | tr -d ' \n')"
+wrapped="$2"`,
+	unwrapKey: `echo "$wrapped" | base64 -d > /tmp/key.wrapped
+key="$(aws kms decrypt --key-id "$kms_key" --ciphertext-blob fileb:///tmp/key.wrapped --query Plaintext --output text | base64 -d | od -An -v -tx1 | tr -d ' \n')"`,
+}
+
+// Google Cloud KMS has no data key generation, the key is generated locally
+var googleKMS = kmsProvider{
+	image: defaultGoogleKMSImage,
+	wrapKey: `[ -z "$GOOGLE_APPLICATION_CREDENTIALS" ] || gcloud auth activate-service-account --key-file="$GOOGLE_APPLICATION_CREDENTIALS" --quiet
+openssl rand -out /tmp/key 32
+gcloud kms encrypt --key "$KMS_KEY_ID" --plaintext-file /tmp/key --ciphertext-file /tmp/key.wrapped
+key="$(od -An -v -tx1 /tmp/key | tr -d ' \n')"
+wrapped="$(base64 -w0 /tmp/key.wrapped)"
+rm -f /tmp/key`,
+	unwrapKey: `[ -z "$GOOGLE_APPLICATION_CREDENTIALS" ] || gcloud auth activate-service-account --key-file="$GOOGLE_APPLICATION_CREDENTIALS" --quiet
+echo "$wrapped" | base64 -d > /tmp/key.wrapped
+gcloud kms decrypt --key "$kms_key" --ciphertext-file /tmp/key.wrapped --plaintext-file /tmp/key
+key="$(od -An -v -tx1 /tmp/key | tr -d ' \n')"
+rm -f /tmp/key`,
+}
+
+// backupEncryption returns the encryption settings of the Database, nil when its
+// backups are not encrypted
+func backupEncryption(database *databasesv1alpha1.Database) *databasesv1alpha1.BackupEncryption {
+	if database.Spec.Backup == nil {
+		return nil
+	}
+	return database.Spec.Backup.Encryption
+}
+
+// isGoogleKMSKey reports whether a key is a Google Cloud KMS key rather than an
+// AWS KMS key
+func isGoogleKMSKey(keyID string) bool {
+	return strings.HasPrefix(keyID, "projects/")
+}
+
+func kmsProviderOf(keyID string) kmsProvider {
+	if isGoogleKMSKey(keyID) {
+		return googleKMS
+	}
+	return awsKMS
+}
+
+// awsKMSRegion returns the region of an AWS KMS key ARN
+func awsKMSRegion(keyID string) string {
+	if parts := strings.SplitN(keyID, ":", 5); len(parts) == 5 {
+		return parts[3]
+	}
+	return ""
+}
+
+// backupFileExtension returns the extension of the backup files the Database
+// writes, .enc being appended when they are encrypted
+func backupFileExtension(database *databasesv1alpha1.Database) string {
+	extension := "." + backupExtensions[database.Spec.Type]
+	if backupEncryption(database) != nil {
+		extension += backupEncryptedExtension
+	}
+	return extension
+}
+
+// encryptScript encrypts the dump staged by the dump container into the backup
+// volume, with a data key written wrapped into the manifest of the backup. The
+// manifest is written before the backup gets its final name, so a complete
+// backup always has one. name is evaluated by the shell.
+func encryptScript(provider kmsProvider, name string) string {
+	return fmt.Sprintf(`set -e
+name="%[1]s"
+%[2]s
+iv="$(openssl rand -hex 16)"
+openssl enc -aes-256-cbc -K "$key" -iv "$iv" -in %[3]s/dump -out "%[4]s/.$name.partial"
+cat > "%[4]s/$name%[5]s" <<EOF
+{"kmsKeyId": "$KMS_KEY_ID", "cipher": "aes-256-cbc", "iv": "$iv", "wrappedKey": "$wrapped"}
+EOF
+mv "%[4]s/.$name.partial" "%[4]s/$name"
+wc -c < "%[4]s/$name" > /dev/termination-log`,
+		name, provider.wrapKey, backupStagingPath, backupMountPath, backupManifestSuffix)
+}
+
+// decryptScript decrypts $ENCRYPTED_FILE into $DECRYPTED_FILE with the data key
+// of its manifest
+func decryptScript(provider kmsProvider) string {
+	return fmt.Sprintf(`set -e
+manifest="$ENCRYPTED_FILE%s"
+field() { sed -n "s/.*\"$1\": *\"\([^\"]*\)\".*/\1/p" "$manifest"; }
+kms_key="$(field kmsKeyId)"
+iv="$(field iv)"
+wrapped="$(field wrappedKey)"
+%s
+openssl enc -d -aes-256-cbc -K "$key" -iv "$iv" -in "$ENCRYPTED_FILE" -out "$DECRYPTED_FILE"`,
+		backupManifestSuffix, provider.unwrapKey)
+}
+
+// kmsContainer builds a container running a script with access to a KMS key
+func (r *DatabaseReconciler) kmsContainer(database *databasesv1alpha1.Database, podSpec *corev1.PodSpec, encryption *databasesv1alpha1.BackupEncryption, name, script string) corev1.Container {
+	provider := kmsProviderOf(encryption.KMSKeyID)
+	image := encryption.Image
+	if image == "" {
+		image = provider.image
+	}
+
+	container := corev1.Container{
+		Name:    name,
+		Image:   image,
+		Command: []string{"/bin/sh", "-c", script},
+		Env:     []corev1.EnvVar{{Name: "KMS_KEY_ID", Value: encryption.KMSKeyID}},
+	}
+	if isGoogleKMSKey(encryption.KMSKeyID) {
+		if encryption.CredentialsSecret != "" {
+			found := false
+			for _, volume := range podSpec.Volumes {
+				found = found || volume.Name == kmsCredentialsVolume
+			}
+			if !found {
+				podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+					Name: kmsCredentialsVolume,
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{SecretName: encryption.CredentialsSecret},
+					},
+				})
+			}
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name: kmsCredentialsVolume, MountPath: kmsCredentialsPath, ReadOnly: true,
+			})
+			container.Env = append(container.Env, corev1.EnvVar{
+				Name: "GOOGLE_APPLICATION_CREDENTIALS", Value: path.Join(kmsCredentialsPath, "credentials.json"),
+			})
+		}
+	} else {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "AWS_REGION", Value: awsKMSRegion(encryption.KMSKeyID)})
+		container.Env = append(container.Env, s3CredentialsEnv(encryption.CredentialsSecret)...)
+	}
+	r.addCABundle(database, podSpec, &container)
+	r.addProxyEnv(database, &container)
+	r.restrictContainer(database, &container)
+	return container
+}
+
+// encryptBackupPod turns a backup pod into an encrypting one: the engine dump
+// moves to an init container writing to a staging volume, from which the main
+// container encrypts it into the backup volume. script runs after the
+// encryption, e.g. to prune old backups.
+func (r *DatabaseReconciler) encryptBackupPod(database *databasesv1alpha1.Database, podSpec *corev1.PodSpec, name, script string) {
+	encryption := backupEncryption(database)
+	staging := corev1.VolumeMount{Name: backupStagingVolume, MountPath: backupStagingPath}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         backupStagingVolume,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	dump := podSpec.Containers[0]
+	component := dump.Name
+	dump.Name = backupDumpContainer
+	dump.Command = []string{"/bin/sh", "-c", fmt.Sprintf("set -e\nBACKUP_FILE=%s/dump\n%s",
+		backupStagingPath, backupScripts[database.Spec.Type])}
+	// The dump container never sees the backup volume
+	mounts := []corev1.VolumeMount{staging}
+	for _, mount := range dump.VolumeMounts {
+		if mount.Name != backupComponent {
+			mounts = append(mounts, mount)
+		}
+	}
+	dump.VolumeMounts = mounts
+	podSpec.InitContainers = append(podSpec.InitContainers, dump)
+
+	encrypt := r.kmsContainer(database, podSpec, encryption, component,
+		encryptScript(kmsProviderOf(encryption.KMSKeyID), name)+script)
+	encrypt.VolumeMounts = append(encrypt.VolumeMounts, staging,
+		corev1.VolumeMount{Name: backupComponent, MountPath: backupMountPath})
+	podSpec.Containers[0] = encrypt
+}
+
+// addBackupDecryption decrypts an encrypted backup file, with its manifest next
+// to it, in an init container writing to the given volume mount. It returns the
+// path of the decrypted file.
+func (r *DatabaseReconciler) addBackupDecryption(database *databasesv1alpha1.Database, podSpec *corev1.PodSpec, encryption *databasesv1alpha1.BackupEncryption, encryptedFile string, source, target corev1.VolumeMount) string {
+	decryptedFile := path.Join(target.MountPath, strings.TrimSuffix(path.Base(encryptedFile), backupEncryptedExtension))
+	decrypt := r.kmsContainer(database, podSpec, encryption, backupDecryptContainer, decryptScript(kmsProviderOf(encryption.KMSKeyID)))
+	decrypt.Env = append(decrypt.Env,
+		corev1.EnvVar{Name: "ENCRYPTED_FILE", Value: encryptedFile},
+		corev1.EnvVar{Name: "DECRYPTED_FILE", Value: decryptedFile})
+	decrypt.VolumeMounts = append(decrypt.VolumeMounts, source)
+	if target.Name != source.Name {
+		decrypt.VolumeMounts = append(decrypt.VolumeMounts, target)
+	}
+	podSpec.InitContainers = append(podSpec.InitContainers, decrypt)
+	return decryptedFile
+}
+
+// decryptionSettings returns the settings decrypting a backup wrapped by keyID:
+// the image and credentials of the encryption of the Database, or its pod
+// identity when the Database no longer encrypts its backups
+func decryptionSettings(database *databasesv1alpha1.Database, keyID string) *databasesv1alpha1.BackupEncryption {
+	settings := &databasesv1alpha1.BackupEncryption{KMSKeyID: keyID}
+	if encryption := backupEncryption(database); encryption != nil &&
+		isGoogleKMSKey(encryption.KMSKeyID) == isGoogleKMSKey(keyID) {
+		settings.CredentialsSecret = encryption.CredentialsSecret
+		settings.Image = encryption.Image
+	}
+	return settings
+}
+
+// restoreDecryption returns the settings decrypting the backup of a restore: the
+// encryption of the Database restored into or, for forks taking no backups of
+// their own, of the Database they copy. keyID is the key of a DatabaseBackup
+// source, unknown for S3 sources until their manifest is read.
+func (r *DatabaseRestoreReconciler) restoreDecryption(ctx context.Context, database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore, keyID string) (*databasesv1alpha1.BackupEncryption, error) {
+	source := database
+	if restore.Spec.Fork != nil {
+		origin, err := r.forkOrigin(ctx, restore)
+		if err != nil {
+			return nil, err
+		}
+		source = &databasesv1alpha1.Database{}
+		if err := r.Get(ctx, types.NamespacedName{Name: origin, Namespace: restore.Namespace}, source); client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+	}
+	if keyID != "" {
+		return decryptionSettings(source, keyID), nil
+	}
+	return backupEncryption(source), nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Backup encryption", func() {
+	const (
+		awsKey    = "arn:aws:kms:eu-west-1:123456789012:key/2f1b7c3e-tenant-a"
+		googleKey = "projects/shop/locations/europe-west1/keyRings/tenants/cryptoKeys/tenant-b"
+	)
+
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Backup: &databasesv1alpha1.BackupSpec{
+					Enabled:    true,
+					Retention:  &databasesv1alpha1.BackupRetention{MaxCount: ptr.To(int32(7))},
+					Encryption: &databasesv1alpha1.BackupEncryption{KMSKeyID: awsKey, CredentialsSecret: "tenant-a-kms"},
+				},
+			},
+		}
+	})
+
+	It("should dump to a staging volume and encrypt into the backup volume", func() {
+		cronJob := reconciler.createBackupCronJob(database, mainBackupSchedule(database))
+		podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+
+		Expect(podSpec.InitContainers).To(HaveLen(1))
+		dump := podSpec.InitContainers[0]
+		Expect(dump.Name).To(Equal(backupDumpContainer))
+		Expect(dump.Image).To(Equal("postgres:16"))
+		Expect(dump.Command[2]).To(ContainSubstring(`BACKUP_FILE=/staging/dump`))
+		Expect(dump.VolumeMounts).To(ConsistOf(HaveField("Name", backupStagingVolume)))
+
+		encrypt := podSpec.Containers[0]
+		Expect(encrypt.Name).To(Equal(backupComponent))
+		Expect(encrypt.Image).To(Equal(defaultAWSKMSImage))
+		Expect(encrypt.Env).To(ContainElements(
+			corev1.EnvVar{Name: "KMS_KEY_ID", Value: awsKey},
+			corev1.EnvVar{Name: "AWS_REGION", Value: "eu-west-1"},
+			HaveField("ValueFrom.SecretKeyRef.Name", "tenant-a-kms")))
+		Expect(encrypt.Env).NotTo(ContainElement(HaveField("Name", "PGPASSWORD")))
+		script := encrypt.Command[2]
+		Expect(script).To(ContainSubstring(`name="orders-$(date -u +%Y%m%dT%H%M%SZ).dump.enc"`))
+		Expect(script).To(ContainSubstring("aws kms generate-data-key"))
+		Expect(script).To(ContainSubstring(`cat > "/backups/$name.manifest.json"`))
+		Expect(script).To(ContainSubstring("ls -1t orders-[0-9]*T[0-9]*Z.dump.enc 2>/dev/null | tail -n +8"))
+		Expect(script).To(ContainSubstring(`for manifest in orders-[0-9]*T[0-9]*Z.dump.enc.manifest.json; do [ -e "${manifest%.manifest.json}" ]`))
+	})
+
+	It("should decrypt DatabaseBackups before verifying them", func() {
+		backup := &databasesv1alpha1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "shop"},
+			Status:     databasesv1alpha1.DatabaseBackupStatus{KMSKeyID: awsKey},
+		}
+		job := reconciler.createDatabaseBackupJob(database, backup)
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring(`name="nightly.dump.enc"`))
+
+		verify := reconciler.createBackupVerifyJob(database, backup)
+		podSpec := verify.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(ConsistOf(And(
+			HaveField("Name", backupDecryptContainer),
+			HaveField("Env", ContainElements(
+				corev1.EnvVar{Name: "ENCRYPTED_FILE", Value: "/backups/nightly.dump.enc"},
+				corev1.EnvVar{Name: "DECRYPTED_FILE", Value: "/verify/nightly.dump"})))))
+		Expect(podSpec.Containers[0].Command[2]).To(ContainSubstring(`BACKUP_FILE="/verify/nightly.dump"`))
+		Expect(podSpec.Containers[0].Env).To(BeEmpty())
+	})
+
+	It("should decrypt restores with the Google Cloud KMS key of the Database", func() {
+		database.Spec.Backup.Encryption = &databasesv1alpha1.BackupEncryption{KMSKeyID: googleKey, CredentialsSecret: "tenant-b-kms"}
+		restore := &databasesv1alpha1.DatabaseRestore{ObjectMeta: metav1.ObjectMeta{Name: "rollback", Namespace: "shop"}}
+
+		job, err := reconciler.createRestoreJob(database, restore, "pvc://orders-backups/nightly.dump.enc", database.Spec.Backup.Encryption)
+		Expect(err).NotTo(HaveOccurred())
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(ContainElement(HaveField("Secret.SecretName", "tenant-b-kms")))
+		Expect(podSpec.InitContainers).To(ConsistOf(And(
+			HaveField("Image", defaultGoogleKMSImage),
+			HaveField("Command", ContainElement(ContainSubstring("gcloud kms decrypt"))),
+			HaveField("Env", ContainElement(corev1.EnvVar{Name: "GOOGLE_APPLICATION_CREDENTIALS", Value: "/var/run/kms/credentials.json"})))))
+		Expect(podSpec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "RESTORE_FILE", Value: "/decrypted/nightly.dump"}))
+
+		By("downloading the manifest of encrypted S3 backups")
+		restore.Spec.Source.S3 = &databasesv1alpha1.S3Source{URI: "s3://shared/tenant-b/nightly.dump.enc"}
+		job, err = reconciler.createRestoreJob(database, restore, "", database.Spec.Backup.Encryption)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.InitContainers[0].Command[2]).To(HaveSuffix(
+			`aws s3 cp "$SOURCE_URI.manifest.json" "$RESTORE_FILE.manifest.json"`))
+
+		_, err = reconciler.createRestoreJob(database, restore, "", nil)
+		Expect(err).To(MatchError(ContainSubstring("backup nightly.dump.enc is encrypted")))
+	})
+
+	It("should encrypt WAL archives with AWS KMS keys only", func() {
+		database.Spec.Storage = &databasesv1alpha1.StorageSpec{Size: "10Gi"}
+		database.Spec.Backup.Method = databasesv1alpha1.BackupMethodWAL
+		database.Spec.Backup.S3 = &databasesv1alpha1.S3Destination{Bucket: "shared"}
+		database.Spec.Backup.WAL = &databasesv1alpha1.WALArchivingSpec{Image: "wal-g:3.0"}
+		Expect(validateWALArchiving(database)).To(Succeed())
+		Expect(walgEnv(database)).To(ContainElements(
+			corev1.EnvVar{Name: "WALG_CSE_KMS_ID", Value: awsKey},
+			corev1.EnvVar{Name: "WALG_CSE_KMS_REGION", Value: "eu-west-1"}))
+
+		database.Spec.Backup.Encryption.KMSKeyID = googleKey
+		Expect(validateWALArchiving(database)).To(MatchError("WAL archives can only be encrypted with AWS KMS keys"))
+	})
+})
@@ -0,0 +1,189 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databasebackups,verbs=delete
+
+const backupCleanupComponent = "backup-cleanup"
+
+// backupPruneScript deletes the backups of a schedule beyond its retention from
+// the backup volume. Only files named by the CronJob of the schedule are matched,
+// so the files of DatabaseBackups and other schedules are left alone.
+func backupPruneScript(database *databasesv1alpha1.Database, schedule databasesv1alpha1.BackupSchedule) string {
+	retention := schedule.Retention
+	if retention == nil {
+		return ""
+	}
+
+	pattern := fmt.Sprintf("%s-[0-9]*T[0-9]*Z%s", scheduleBackupPrefix(database, schedule), backupFileExtension(database))
+	script := "\ncd " + backupMountPath
+	if retention.MaxCount != nil {
+		script += fmt.Sprintf("\nls -1t %s 2>/dev/null | tail -n +%d | xargs -r rm -f --", pattern, *retention.MaxCount+1)
+	}
+	if retention.MaxAge != nil {
+		minutes := int64(retention.MaxAge.Minutes())
+		script += fmt.Sprintf("\nfind . -maxdepth 1 -name '%s' -mmin +%d -exec rm -f {} +", pattern, minutes)
+	}
+	if backupEncryption(database) != nil {
+		// Manifests go with their backup
+		script += fmt.Sprintf("\nfor manifest in %s%s; do [ -e \"${manifest%%%s}\" ] || rm -f -- \"$manifest\"; done",
+			pattern, backupManifestSuffix, backupManifestSuffix)
+	}
+	return script
+}
+
+// walRetentionEnv configures the base backup sidecar to delete base backups, and
+// the WAL they depend on, beyond the retention
+func walRetentionEnv(database *databasesv1alpha1.Database) []corev1.EnvVar {
+	retention := database.Spec.Backup.Retention
+	if retention == nil {
+		return nil
+	}
+
+	env := []corev1.EnvVar{}
+	if retention.MaxCount != nil {
+		env = append(env, corev1.EnvVar{Name: "RETAIN_FULL", Value: strconv.Itoa(int(*retention.MaxCount))})
+	}
+	if retention.MaxAge != nil {
+		env = append(env, corev1.EnvVar{Name: "RETAIN_SECONDS", Value: strconv.FormatInt(int64(retention.MaxAge.Seconds()), 10)})
+	}
+	return env
+}
+
+// expiredBackups returns the finished backups beyond the retention. backups must
+// be ordered oldest first. Backups being restored are not expired, nor counted.
+func expiredBackups(backups []databasesv1alpha1.DatabaseBackup, retention *databasesv1alpha1.BackupRetention, restoring map[string]bool, now time.Time) []databasesv1alpha1.DatabaseBackup {
+	expired := []databasesv1alpha1.DatabaseBackup{}
+	kept := int32(0)
+	for i := len(backups) - 1; i >= 0; i-- {
+		backup := backups[i]
+		if !backupFinished(&backup) || restoring[backup.Name] {
+			continue
+		}
+
+		tooMany := retention.MaxCount != nil && kept >= *retention.MaxCount
+		tooOld := retention.MaxAge != nil && now.Sub(backup.CreationTimestamp.Time) > retention.MaxAge.Duration
+		if tooMany || tooOld {
+			expired = append(expired, backup)
+			continue
+		}
+		kept++
+	}
+	return expired
+}
+
+// pruneDatabaseBackups deletes the DatabaseBackups beyond the retention of their
+// schedule, the backups of additional schedules being pruned by their own
+// retention. Their files are removed by the DatabaseBackup controller. Backups
+// read by a Pending, Queued or Running DatabaseRestore are kept until it finished.
+func (r *DatabaseReconciler) pruneDatabaseBackups(ctx context.Context, database *databasesv1alpha1.Database) error {
+	backup := database.Spec.Backup
+	if backup == nil {
+		return nil
+	}
+	retentions := map[string]*databasesv1alpha1.BackupRetention{"": backup.Retention}
+	for _, schedule := range backup.Schedules {
+		retentions[schedule.Name] = schedule.Retention
+	}
+
+	backups, err := listDatabaseBackups(ctx, r.Client, database)
+	if err != nil {
+		return err
+	}
+
+	restoring, err := restoringBackups(ctx, r.Client, database.Namespace)
+	if err != nil {
+		return err
+	}
+
+	bySchedule := map[string][]databasesv1alpha1.DatabaseBackup{}
+	for _, backup := range backups {
+		schedule := backup.Labels[databasesv1alpha1.BackupScheduleLabel]
+		bySchedule[schedule] = append(bySchedule[schedule], backup)
+	}
+	for schedule, backups := range bySchedule {
+		retention := retentions[schedule]
+		if retention == nil {
+			continue
+		}
+		for _, expired := range expiredBackups(backups, retention, restoring, time.Now()) {
+			log.FromContext(ctx).Info("Deleting expired DatabaseBackup", "name", expired.Name, "schedule", schedule)
+			if err := r.Delete(ctx, &expired); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoringBackups returns the names of the DatabaseBackups of a namespace that
+// unfinished DatabaseRestores read from
+func restoringBackups(ctx context.Context, reader client.Reader, namespace string) (map[string]bool, error) {
+	restores := &databasesv1alpha1.DatabaseRestoreList{}
+	if err := reader.List(ctx, restores, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	restoring := map[string]bool{}
+	for _, restore := range restores.Items {
+		if ref := restore.Spec.Source.BackupRef; ref != nil && !restoreFinished(&restore) {
+			restoring[ref.Name] = true
+		}
+	}
+	return restoring, nil
+}
+
+// createBackupCleanupJob builds the Job deleting the file of a DatabaseBackup
+func (r *DatabaseReconciler) createBackupCleanupJob(database *databasesv1alpha1.Database, backup *databasesv1alpha1.DatabaseBackup) (*batchv1.Job, error) {
+	claim, file, err := parseBackupLocation(backup.Status.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	job := r.createAdminJob(database, backupCleanupComponent, `rm -f "$BACKUP_FILE" "$BACKUP_FILE`+backupManifestSuffix+`"`,
+		[]corev1.EnvVar{{Name: "BACKUP_FILE", Value: path.Join(backupMountPath, file)}})
+	job.Name = backup.Name + "-" + backupCleanupComponent
+
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: backupComponent,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      backupComponent,
+		MountPath: backupMountPath,
+	})
+	return job, nil
+}
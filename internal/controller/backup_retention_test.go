@@ -0,0 +1,143 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Backup retention", func() {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	backupAt := func(name string, age time.Duration, phase databasesv1alpha1.DatabaseBackupPhase) databasesv1alpha1.DatabaseBackup {
+		return databasesv1alpha1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age))},
+			Status:     databasesv1alpha1.DatabaseBackupStatus{Phase: phase},
+		}
+	}
+
+	names := func(backups []databasesv1alpha1.DatabaseBackup) []string {
+		result := []string{}
+		for _, backup := range backups {
+			result = append(result, backup.Name)
+		}
+		return result
+	}
+
+	It("should expire finished backups beyond the count or the age", func() {
+		backups := []databasesv1alpha1.DatabaseBackup{
+			backupAt("oldest", 72*time.Hour, databasesv1alpha1.DatabaseBackupPhaseCompleted),
+			backupAt("failed", 48*time.Hour, databasesv1alpha1.DatabaseBackupPhaseFailed),
+			backupAt("yesterday", 24*time.Hour, databasesv1alpha1.DatabaseBackupPhaseCompleted),
+			backupAt("running", time.Hour, databasesv1alpha1.DatabaseBackupPhaseRunning),
+		}
+
+		byCount := &databasesv1alpha1.BackupRetention{MaxCount: ptr.To(int32(2))}
+		Expect(names(expiredBackups(backups, byCount, nil, now))).To(Equal([]string{"oldest"}))
+
+		byAge := &databasesv1alpha1.BackupRetention{MaxAge: &metav1.Duration{Duration: 36 * time.Hour}}
+		Expect(names(expiredBackups(backups, byAge, nil, now))).To(Equal([]string{"failed", "oldest"}))
+
+		By("keeping the backups being restored")
+		restoring := map[string]bool{"oldest": true}
+		Expect(names(expiredBackups(backups, byCount, restoring, now))).To(BeEmpty())
+		Expect(names(expiredBackups(backups, byAge, restoring, now))).To(Equal([]string{"failed"}))
+	})
+
+	It("should prune only the files of scheduled backups", func() {
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type: databasesv1alpha1.DatabaseTypePostgreSQL,
+				Backup: &databasesv1alpha1.BackupSpec{Retention: &databasesv1alpha1.BackupRetention{
+					MaxCount: ptr.To(int32(7)),
+					MaxAge:   &metav1.Duration{Duration: 30 * 24 * time.Hour},
+				}},
+			},
+		}
+
+		script := backupPruneScript(database, mainBackupSchedule(database))
+		Expect(script).To(ContainSubstring("ls -1t orders-[0-9]*T[0-9]*Z.dump 2>/dev/null | tail -n +8 | xargs -r rm -f --"))
+		Expect(script).To(ContainSubstring("-name 'orders-[0-9]*T[0-9]*Z.dump' -mmin +43200"))
+		Expect(walRetentionEnv(database)).To(ConsistOf(
+			corev1.EnvVar{Name: "RETAIN_FULL", Value: "7"},
+			corev1.EnvVar{Name: "RETAIN_SECONDS", Value: "2592000"},
+		))
+
+		database.Spec.Backup.Retention = nil
+		Expect(backupPruneScript(database, mainBackupSchedule(database))).To(BeEmpty())
+	})
+
+	It("should delete the file of a deleted DatabaseBackup before releasing it", func() {
+		ctx := context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		key := types.NamespacedName{Name: "nightly", Namespace: "shop"}
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.DatabaseBackup{}).
+			WithObjects(
+				&databasesv1alpha1.Database{
+					ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+					Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+				},
+				&databasesv1alpha1.DatabaseBackup{
+					ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "shop", Finalizers: []string{databaseBackupFinalizer}},
+					Spec:       databasesv1alpha1.DatabaseBackupSpec{DatabaseRef: corev1.LocalObjectReference{Name: "orders"}},
+					Status: databasesv1alpha1.DatabaseBackupStatus{
+						Phase:    databasesv1alpha1.DatabaseBackupPhaseCompleted,
+						Location: "pvc://orders-backups/nightly.dump",
+					},
+				},
+			).Build()
+		reconciler := &DatabaseBackupReconciler{Client: c, Scheme: scheme}
+
+		Expect(c.Delete(ctx, &databasesv1alpha1.DatabaseBackup{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "shop"}})).To(Succeed())
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: "nightly-backup-cleanup", Namespace: "shop"}, job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(
+			corev1.EnvVar{Name: "BACKUP_FILE", Value: "/backups/nightly.dump"}))
+		Expect(c.Get(ctx, key, &databasesv1alpha1.DatabaseBackup{})).To(Succeed())
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(c.Status().Update(ctx, job)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apierrors.IsNotFound(c.Get(ctx, key, &databasesv1alpha1.DatabaseBackup{}))).To(BeTrue())
+	})
+})
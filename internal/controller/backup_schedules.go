@@ -0,0 +1,184 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// mainBackupSchedule returns the schedule set by the top-level backup fields. It
+// has no name, so its CronJob, volume and backups keep their historical names.
+func mainBackupSchedule(database *databasesv1alpha1.Database) databasesv1alpha1.BackupSchedule {
+	spec := database.Spec.Backup
+	return databasesv1alpha1.BackupSchedule{
+		Method:    backupMethod(database),
+		Schedule:  spec.Schedule,
+		Storage:   spec.Storage,
+		Retention: spec.Retention,
+		Copies:    spec.Copies,
+	}
+}
+
+// scheduleComponent is the component of the CronJob of a schedule
+func scheduleComponent(schedule databasesv1alpha1.BackupSchedule) string {
+	if schedule.Name == "" {
+		return backupComponent
+	}
+	return backupComponent + "-" + schedule.Name
+}
+
+// scheduleBackupPrefix starts the names of the backups of a schedule
+func scheduleBackupPrefix(database *databasesv1alpha1.Database, schedule databasesv1alpha1.BackupSchedule) string {
+	if schedule.Name == "" {
+		return database.Name
+	}
+	return database.Name + "-" + schedule.Name
+}
+
+// scheduleClaimName returns the volume the dumps of a schedule are written to
+func scheduleClaimName(database *databasesv1alpha1.Database, schedule databasesv1alpha1.BackupSchedule) string {
+	if schedule.Name == "" || schedule.Storage == nil {
+		return backupClaimName(database)
+	}
+	return backupClaimName(database) + "-" + schedule.Name
+}
+
+func cronSchedule(schedule databasesv1alpha1.BackupSchedule) string {
+	if schedule.Schedule == "" {
+		return defaultBackupSchedule
+	}
+	return schedule.Schedule
+}
+
+// validateBackupSchedules checks the methods of the additional schedules against
+// the capabilities of the engine
+func validateBackupSchedules(database *databasesv1alpha1.Database, capabilities EngineCapabilities) error {
+	for _, schedule := range database.Spec.Backup.Schedules {
+		method := schedule.Method
+		if method == "" {
+			method = databasesv1alpha1.BackupMethodDump
+		}
+		if method == databasesv1alpha1.BackupMethodWAL {
+			return fmt.Errorf("backup schedule %s: WAL archiving is continuous, set it as backup.method", schedule.Name)
+		}
+		if method == databasesv1alpha1.BackupMethodIncremental {
+			return fmt.Errorf("backup schedule %s: pgBackRest schedules its own backups, set Incremental as backup.method", schedule.Name)
+		}
+		if !slices.Contains(capabilities.SupportedBackupMethods, string(method)) {
+			return fmt.Errorf("backup schedule %s: %s does not support %s backups", schedule.Name, database.Spec.Type, method)
+		}
+		if method == databasesv1alpha1.BackupMethodSnapshot {
+			if err := validateSnapshotBackups(database); err != nil {
+				return fmt.Errorf("backup schedule %s: %w", schedule.Name, err)
+			}
+		}
+		if err := validateBackupCopies(schedule.Copies, method); err != nil {
+			return fmt.Errorf("backup schedule %s: %w", schedule.Name, err)
+		}
+	}
+	return nil
+}
+
+// reconcileBackupSchedules runs a CronJob per additional backup schedule, deletes
+// the CronJobs of removed schedules and reports the schedules in status
+func (r *DatabaseReconciler) reconcileBackupSchedules(ctx context.Context, database *databasesv1alpha1.Database) error {
+	var schedules []databasesv1alpha1.BackupSchedule
+	if backup := database.Spec.Backup; backup != nil && backup.Enabled {
+		schedules = backup.Schedules
+	}
+
+	statuses := []databasesv1alpha1.BackupScheduleStatus{}
+	for _, schedule := range schedules {
+		if schedule.Method == "" {
+			schedule.Method = databasesv1alpha1.BackupMethodDump
+		}
+
+		var desired *batchv1.CronJob
+		switch schedule.Method {
+		case databasesv1alpha1.BackupMethodDump:
+			if err := r.reconcileBackupClaim(ctx, database, scheduleClaimName(database, schedule), schedule.Storage); err != nil {
+				return fmt.Errorf("backup schedule %s: %w", schedule.Name, err)
+			}
+			desired = r.createBackupCronJob(database, schedule)
+		case databasesv1alpha1.BackupMethodSnapshot:
+			desired = r.createSnapshotCronJob(database, schedule)
+		default:
+			return fmt.Errorf("backup schedule %s: unsupported method %s", schedule.Name, schedule.Method)
+		}
+		desired.Labels[databasesv1alpha1.BackupScheduleLabel] = schedule.Name
+
+		cronJob, err := r.reconcileCronJob(ctx, database, scheduleComponent(schedule), desired)
+		if err != nil {
+			return fmt.Errorf("backup schedule %s: %w", schedule.Name, err)
+		}
+		if schedule.Method == databasesv1alpha1.BackupMethodSnapshot {
+			if err := r.reconcileScheduledSnapshot(ctx, database, schedule, cronJob); err != nil {
+				return fmt.Errorf("backup schedule %s: %w", schedule.Name, err)
+			}
+		}
+
+		statuses = append(statuses, databasesv1alpha1.BackupScheduleStatus{
+			Name:               schedule.Name,
+			Method:             schedule.Method,
+			CronJob:            cronJob.Name,
+			LastScheduleTime:   cronJob.Status.LastScheduleTime,
+			LastSuccessfulTime: cronJob.Status.LastSuccessfulTime,
+		})
+	}
+
+	if err := r.deleteRemovedScheduleCronJobs(ctx, database, schedules); err != nil {
+		return err
+	}
+
+	if len(statuses) == 0 {
+		statuses = nil
+	}
+	database.Status.BackupSchedules = statuses
+	return nil
+}
+
+// deleteRemovedScheduleCronJobs deletes the CronJobs of schedules no longer in the spec
+func (r *DatabaseReconciler) deleteRemovedScheduleCronJobs(ctx context.Context, database *databasesv1alpha1.Database, schedules []databasesv1alpha1.BackupSchedule) error {
+	cronJobs := &batchv1.CronJobList{}
+	if err := r.List(ctx, cronJobs, client.InNamespace(database.Namespace),
+		client.MatchingLabels{"app.kubernetes.io/instance": database.Name, "app.kubernetes.io/managed-by": "database-operator"},
+		client.HasLabels{databasesv1alpha1.BackupScheduleLabel}); err != nil {
+		return err
+	}
+
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		name := cronJob.Labels[databasesv1alpha1.BackupScheduleLabel]
+		if slices.ContainsFunc(schedules, func(schedule databasesv1alpha1.BackupSchedule) bool { return schedule.Name == name }) {
+			continue
+		}
+		log.FromContext(ctx).Info("Deleting CronJob of removed backup schedule", "name", cronJob.Name, "schedule", name)
+		if err := r.Delete(ctx, cronJob); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Backup schedules", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "shop"}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build()
+		reconciler = &DatabaseReconciler{Client: c, Scheme: scheme}
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Storage: &databasesv1alpha1.StorageSpec{Size: "10Gi", Snapshots: true},
+				Backup: &databasesv1alpha1.BackupSpec{
+					Enabled: true,
+					Schedules: []databasesv1alpha1.BackupSchedule{
+						{
+							Name:      "hourly",
+							Schedule:  "0 * * * *",
+							Storage:   &databasesv1alpha1.StorageSpec{Size: "50Gi"},
+							Retention: &databasesv1alpha1.BackupRetention{MaxCount: ptr.To(int32(24))},
+						},
+						{
+							Name:      "weekly",
+							Method:    databasesv1alpha1.BackupMethodSnapshot,
+							Schedule:  "0 3 * * 0",
+							Retention: &databasesv1alpha1.BackupRetention{MaxCount: ptr.To(int32(1))},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	It("should run a CronJob per schedule next to the main one", func() {
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+		Expect(reconciler.reconcileBackup(ctx, database)).To(Succeed())
+
+		Expect(c.Get(ctx, key("orders-backup"), &batchv1.CronJob{})).To(Succeed())
+
+		hourly := &batchv1.CronJob{}
+		Expect(c.Get(ctx, key("orders-backup-hourly"), hourly)).To(Succeed())
+		Expect(hourly.Spec.Schedule).To(Equal("0 * * * *"))
+		Expect(hourly.Labels).To(HaveKeyWithValue(databasesv1alpha1.BackupScheduleLabel, "hourly"))
+		podSpec := hourly.Spec.JobTemplate.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "orders-backups-hourly")))
+		Expect(podSpec.Containers[0].Command[2]).To(And(
+			ContainSubstring(`name="orders-hourly-$(date -u`),
+			ContainSubstring("ls -1t orders-hourly-[0-9]*T[0-9]*Z.dump 2>/dev/null | tail -n +25")))
+
+		claim := &corev1.PersistentVolumeClaim{}
+		Expect(c.Get(ctx, key("orders-backups-hourly"), claim)).To(Succeed())
+		Expect(claim.Spec.Resources.Requests.Storage().String()).To(Equal("50Gi"))
+
+		Expect(database.Status.BackupSchedules).To(HaveLen(2))
+		Expect(database.Status.BackupSchedules[1]).To(And(
+			HaveField("Name", "weekly"), HaveField("Method", databasesv1alpha1.BackupMethodSnapshot),
+			HaveField("CronJob", "orders-backup-weekly")))
+
+		By("deleting the CronJob of a removed schedule")
+		database.Spec.Backup.Schedules = database.Spec.Backup.Schedules[1:]
+		Expect(reconciler.reconcileBackup(ctx, database)).To(Succeed())
+		Expect(apierrors.IsNotFound(c.Get(ctx, key("orders-backup-hourly"), &batchv1.CronJob{}))).To(BeTrue())
+		Expect(c.Get(ctx, key("orders-backup-weekly"), &batchv1.CronJob{})).To(Succeed())
+	})
+
+	It("should record snapshot schedule runs as DatabaseBackups pruned by the schedule retention", func() {
+		Expect(reconciler.reconcileBackup(ctx, database)).To(Succeed())
+
+		weekly := &batchv1.CronJob{}
+		Expect(c.Get(ctx, key("orders-backup-weekly"), weekly)).To(Succeed())
+		weekly.Status.LastScheduleTime = &metav1.Time{Time: time.Date(2025, 3, 2, 3, 0, 0, 0, time.UTC)}
+		Expect(c.Status().Update(ctx, weekly)).To(Succeed())
+		Expect(reconciler.reconcileBackup(ctx, database)).To(Succeed())
+
+		backup := &databasesv1alpha1.DatabaseBackup{}
+		Expect(c.Get(ctx, key("orders-weekly-20250302t030000z"), backup)).To(Succeed())
+		Expect(backup.Labels).To(HaveKeyWithValue(databasesv1alpha1.BackupScheduleLabel, "weekly"))
+		Expect(backup.Spec.Method).To(Equal(databasesv1alpha1.BackupMethodSnapshot))
+		Expect(databaseBackupMethod(database, backup)).To(Equal(databasesv1alpha1.BackupMethodSnapshot))
+
+		By("keeping the single most recent weekly backup, whatever the other backups")
+		for _, name := range []string{"orders-weekly-20250309t030000z", "orders-manual"} {
+			labels := map[string]string{databasesv1alpha1.DatabaseLabel: "orders"}
+			if name != "orders-manual" {
+				labels[databasesv1alpha1.BackupScheduleLabel] = "weekly"
+			}
+			Expect(c.Create(ctx, &databasesv1alpha1.DatabaseBackup{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: labels},
+				Status:     databasesv1alpha1.DatabaseBackupStatus{Phase: databasesv1alpha1.DatabaseBackupPhaseCompleted},
+			})).To(Succeed())
+		}
+		backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseCompleted
+		Expect(c.Update(ctx, backup)).To(Succeed())
+
+		Expect(reconciler.pruneDatabaseBackups(ctx, database)).To(Succeed())
+		Expect(apierrors.IsNotFound(c.Get(ctx, key("orders-weekly-20250302t030000z"), backup))).To(BeTrue())
+		Expect(c.Get(ctx, key("orders-weekly-20250309t030000z"), backup)).To(Succeed())
+		Expect(c.Get(ctx, key("orders-manual"), backup)).To(Succeed())
+	})
+
+	It("should reject schedules the engine cannot take", func() {
+		database.Spec.Storage.Snapshots = false
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("backup schedule weekly")))
+
+		database.Spec.Backup.Schedules = []databasesv1alpha1.BackupSchedule{{Name: "wal", Method: databasesv1alpha1.BackupMethodWAL, Schedule: "@hourly"}}
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("WAL archiving is continuous")))
+	})
+})
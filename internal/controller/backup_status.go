@@ -0,0 +1,224 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// backupRun is a finished backup, taken by a dump CronJob or recorded as a
+// DatabaseBackup
+type backupRun struct {
+	finished  time.Time
+	succeeded bool
+	size      *resource.Quantity
+	// job is set for CronJob runs, whose size is read from their pod when needed
+	job *batchv1.Job
+}
+
+// reconcileBackupStatus summarises the backups of the Database in status.backups
+func (r *DatabaseReconciler) reconcileBackupStatus(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) error {
+	if spec := database.Spec.Backup; spec == nil || !spec.Enabled {
+		database.Status.Backups = nil
+		return nil
+	}
+
+	var lastSuccess time.Time
+	cronJobs := map[string]bool{}
+	for _, schedule := range dumpSchedules(database) {
+		cronJob := &batchv1.CronJob{}
+		name := database.Name + "-" + scheduleComponent(schedule)
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, cronJob); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			continue
+		}
+		cronJobs[name] = true
+		if t := cronJob.Status.LastSuccessfulTime; t != nil && t.After(lastSuccess) {
+			lastSuccess = t.Time
+		}
+	}
+
+	runs, err := r.backupRuns(ctx, database, cronJobs)
+	if err != nil {
+		return err
+	}
+
+	status := &databasesv1alpha1.BackupStatus{
+		NextScheduledBackup: nextScheduledBackup(database, now),
+		Destination:         backupDestination(database),
+	}
+	for _, run := range runs {
+		if !run.succeeded {
+			continue
+		}
+		if run.finished.After(lastSuccess) {
+			lastSuccess = run.finished
+		}
+		if status.LastBackupSize != nil {
+			continue
+		}
+		size := run.size
+		if run.job != nil {
+			if size, err = backupSize(ctx, r.Client, run.job); err != nil {
+				return err
+			}
+		}
+		status.LastBackupSize = size
+	}
+	for _, run := range runs {
+		if !run.succeeded && run.finished.After(lastSuccess) {
+			status.FailureCount++
+		}
+	}
+	if !lastSuccess.IsZero() {
+		status.LastSuccessfulBackup = &metav1.Time{Time: lastSuccess}
+	}
+
+	database.Status.Backups = status
+	return nil
+}
+
+// backupRuns returns the finished runs of the given dump CronJobs still in their
+// Job history and the finished DatabaseBackups, newest first
+func (r *DatabaseReconciler) backupRuns(ctx context.Context, database *databasesv1alpha1.Database, cronJobs map[string]bool) ([]backupRun, error) {
+	var runs []backupRun
+
+	if len(cronJobs) > 0 {
+		jobs := &batchv1.JobList{}
+		if err := r.List(ctx, jobs, client.InNamespace(database.Namespace),
+			client.MatchingLabels{"app.kubernetes.io/instance": database.Name, "app.kubernetes.io/managed-by": "database-operator"}); err != nil {
+			return nil, err
+		}
+		for i := range jobs.Items {
+			job := &jobs.Items[i]
+			owner := metav1.GetControllerOf(job)
+			if owner == nil || owner.Kind != "CronJob" || !cronJobs[owner.Name] {
+				continue
+			}
+			if finished, succeeded := jobFinished(job); finished {
+				runs = append(runs, backupRun{finished: jobFinishTime(job), succeeded: succeeded, job: job})
+			}
+		}
+	}
+
+	backups, err := listDatabaseBackups(ctx, r.Client, database)
+	if err != nil {
+		return nil, err
+	}
+	for _, backup := range backups {
+		if !backupFinished(&backup) {
+			continue
+		}
+		finished := backup.CreationTimestamp.Time
+		if backup.Status.CompletionTime != nil {
+			finished = backup.Status.CompletionTime.Time
+		}
+		runs = append(runs, backupRun{
+			finished:  finished,
+			succeeded: backup.Status.Phase == databasesv1alpha1.DatabaseBackupPhaseCompleted,
+			size:      backup.Status.Size,
+		})
+	}
+
+	slices.SortStableFunc(runs, func(a, b backupRun) int { return b.finished.Compare(a.finished) })
+	return runs, nil
+}
+
+// jobFinishTime returns when a finished Job completed or failed
+func jobFinishTime(job *batchv1.Job) time.Time {
+	if job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime.Time
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			return c.LastTransitionTime.Time
+		}
+	}
+	return job.CreationTimestamp.Time
+}
+
+// dumpSchedules returns the backup schedules run by a dump CronJob
+func dumpSchedules(database *databasesv1alpha1.Database) []databasesv1alpha1.BackupSchedule {
+	var schedules []databasesv1alpha1.BackupSchedule
+	if backupMethod(database) == databasesv1alpha1.BackupMethodDump {
+		schedules = append(schedules, mainBackupSchedule(database))
+	}
+	for _, schedule := range database.Spec.Backup.Schedules {
+		if schedule.Method == "" || schedule.Method == databasesv1alpha1.BackupMethodDump {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules
+}
+
+// nextScheduledBackup returns when the next backup CronJob run starts, CronJobs
+// running in UTC. The CronJobs of a hibernated Database do not run.
+func nextScheduledBackup(database *databasesv1alpha1.Database, now time.Time) *metav1.Time {
+	if hibernated(database) {
+		return nil
+	}
+	schedules := slices.Clone(database.Spec.Backup.Schedules)
+	if method := backupMethod(database); method != databasesv1alpha1.BackupMethodWAL && method != databasesv1alpha1.BackupMethodIncremental {
+		schedules = append(schedules, mainBackupSchedule(database))
+	}
+
+	var next time.Time
+	for _, schedule := range schedules {
+		spec, err := parseCronSchedule(cronSchedule(schedule))
+		if err != nil {
+			continue
+		}
+		if t := spec.next(now.UTC()); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	if next.IsZero() {
+		return nil
+	}
+	return &metav1.Time{Time: next}
+}
+
+// backupDestination returns where the main schedule stores its backups
+func backupDestination(database *databasesv1alpha1.Database) string {
+	switch backupMethod(database) {
+	case databasesv1alpha1.BackupMethodWAL:
+		return strings.TrimSuffix(walgPrefix(database, ""), "/")
+	case databasesv1alpha1.BackupMethodIncremental:
+		return "s3://" + database.Spec.Backup.S3.Bucket + strings.TrimSuffix(pgBackRestRepoPath(database, ""), "/")
+	case databasesv1alpha1.BackupMethodSnapshot:
+		class := ""
+		if database.Spec.Storage != nil && database.Spec.Storage.SnapshotClassName != nil {
+			class = *database.Spec.Storage.SnapshotClassName
+		}
+		return "volumesnapshot://" + class
+	}
+	return "pvc://" + backupClaimName(database)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Backup status", func() {
+	var (
+		ctx      context.Context
+		database *databasesv1alpha1.Database
+	)
+
+	at := func(day, hour int) time.Time {
+		return time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	// backupJob is a finished run of the main backup CronJob
+	backupJob := func(name string, finished time.Time, succeeded bool) *batchv1.Job {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "shop",
+				Labels:    map[string]string{"app.kubernetes.io/instance": "orders", "app.kubernetes.io/managed-by": "database-operator"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "batch/v1", Kind: "CronJob", Name: "orders-backup", UID: "cronjob-uid", Controller: ptr.To(true),
+				}},
+			},
+		}
+		condition := batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(finished)}
+		if succeeded {
+			condition.Type = batchv1.JobComplete
+			job.Status.CompletionTime = &metav1.Time{Time: finished}
+		}
+		job.Status.Conditions = []batchv1.JobCondition{condition}
+		return job
+	}
+
+	newClient := func(objects ...client.Object) client.Client {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Backup: &databasesv1alpha1.BackupSpec{
+					Enabled:  true,
+					Schedule: "0 2 * * *",
+					Schedules: []databasesv1alpha1.BackupSchedule{
+						{Name: "hourly", Schedule: "30 */6 * * *"},
+					},
+				},
+			},
+		}
+	})
+
+	It("should summarise the runs of the CronJobs and the DatabaseBackups", func() {
+		cronJob := &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-backup", Namespace: "shop", UID: "cronjob-uid"},
+			Status:     batchv1.CronJobStatus{LastSuccessfulTime: &metav1.Time{Time: at(3, 2)}},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-backup-1-x", Namespace: "shop", Labels: map[string]string{batchv1.JobNameLabel: "orders-backup-1"}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "2048\n"}},
+			}}},
+		}
+		failedBackup := &databasesv1alpha1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: "shop", Labels: map[string]string{databasesv1alpha1.DatabaseLabel: "orders"}},
+			Status: databasesv1alpha1.DatabaseBackupStatus{
+				Phase:          databasesv1alpha1.DatabaseBackupPhaseFailed,
+				CompletionTime: &metav1.Time{Time: at(4, 9)},
+			},
+		}
+		c := newClient(cronJob, pod, failedBackup,
+			backupJob("orders-backup-1", at(3, 2), true),
+			backupJob("orders-backup-2", at(4, 2), false))
+
+		Expect((&DatabaseReconciler{Client: c}).reconcileBackupStatus(ctx, database, at(4, 10))).To(Succeed())
+		status := database.Status.Backups
+		Expect(status.LastSuccessfulBackup.Time).To(BeTemporally("==", at(3, 2)))
+		Expect(status.LastBackupSize.Cmp(resource.MustParse("2Ki"))).To(Equal(0))
+		Expect(status.FailureCount).To(Equal(int32(2)))
+		Expect(status.NextScheduledBackup.Time).To(BeTemporally("==", at(4, 12).Add(30*time.Minute)))
+		Expect(status.Destination).To(Equal("pvc://orders-backups"))
+
+		By("resetting the failures once a backup succeeds")
+		completed := &databasesv1alpha1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "retry", Namespace: "shop", Labels: map[string]string{databasesv1alpha1.DatabaseLabel: "orders"}},
+			Status: databasesv1alpha1.DatabaseBackupStatus{
+				Phase:          databasesv1alpha1.DatabaseBackupPhaseCompleted,
+				CompletionTime: &metav1.Time{Time: at(4, 11)},
+				Size:           ptr.To(resource.MustParse("3Ki")),
+			},
+		}
+		Expect(c.Create(ctx, completed)).To(Succeed())
+		Expect((&DatabaseReconciler{Client: c}).reconcileBackupStatus(ctx, database, at(4, 11))).To(Succeed())
+		status = database.Status.Backups
+		Expect(status.LastSuccessfulBackup.Time).To(BeTemporally("==", at(4, 11)))
+		Expect(status.LastBackupSize.Cmp(resource.MustParse("3Ki"))).To(Equal(0))
+		Expect(status.FailureCount).To(BeZero())
+	})
+
+	It("should report where WAL archiving and snapshots store their backups", func() {
+		database.Spec.Backup.Method = databasesv1alpha1.BackupMethodWAL
+		database.Spec.Backup.S3 = &databasesv1alpha1.S3Destination{Bucket: "backups", Path: "/prod/"}
+		database.Spec.Backup.Schedules = nil
+		Expect(backupDestination(database)).To(Equal("s3://backups/prod/shop/orders"))
+		Expect(nextScheduledBackup(database, at(4, 10))).To(BeNil())
+
+		database.Spec.Backup.Method = databasesv1alpha1.BackupMethodSnapshot
+		database.Spec.Storage = &databasesv1alpha1.StorageSpec{Size: "10Gi", SnapshotClassName: ptr.To("csi-snapclass")}
+		Expect(backupDestination(database)).To(Equal("volumesnapshot://csi-snapclass"))
+		Expect(nextScheduledBackup(database, at(4, 10)).Time).To(BeTemporally("==", at(5, 2)))
+	})
+
+	It("should find the next run of cron schedules", func() {
+		for _, tc := range []struct {
+			schedule string
+			next     time.Time
+		}{
+			{"@hourly", at(4, 11)},
+			{"*/15 9-17 * * mon-fri", time.Date(2025, 3, 4, 10, 15, 0, 0, time.UTC)},
+			{"0 3 * * 0", at(9, 3)},
+			{"0 0 1 jan,jul *", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+			// Either day field matches when both are restricted
+			{"0 0 13 * 5", at(7, 0)},
+			{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		} {
+			spec, err := parseCronSchedule(tc.schedule)
+			Expect(err).NotTo(HaveOccurred(), tc.schedule)
+			// Tuesday 4 March 2025, 10:05
+			Expect(spec.next(at(4, 10).Add(5*time.Minute))).To(Equal(tc.next), tc.schedule)
+		}
+
+		_, err := parseCronSchedule("0 25 * * *")
+		Expect(err).To(MatchError(ContainSubstring(`invalid value "25"`)))
+		_, err = parseCronSchedule("0 2 * *")
+		Expect(err).To(MatchError(ContainSubstring("expected 5 fields")))
+	})
+})
@@ -0,0 +1,229 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Backups", func() {
+	newDatabase := func(dbType databasesv1alpha1.DatabaseType) *databasesv1alpha1.Database {
+		return &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    dbType,
+				Version: "16",
+				Backup:  &databasesv1alpha1.BackupSpec{Enabled: true},
+			},
+		}
+	}
+
+	It("should declare a dump command for every engine supporting dump backups", func() {
+		for dbType, capabilities := range engineCapabilities {
+			for _, method := range capabilities.SupportedBackupMethods {
+				if method == backupMethodDump {
+					Expect(backupScripts).To(HaveKey(dbType))
+					Expect(backupExtensions).To(HaveKey(dbType))
+				}
+			}
+		}
+	})
+
+	It("should build a CronJob writing to the backup volume", func() {
+		reconciler := &DatabaseReconciler{}
+		database := newDatabase(databasesv1alpha1.DatabaseTypePostgreSQL)
+		cronJob := reconciler.createBackupCronJob(database, mainBackupSchedule(database))
+
+		Expect(cronJob.Name).To(Equal("orders-backup"))
+		Expect(cronJob.Spec.Schedule).To(Equal(defaultBackupSchedule))
+
+		podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("orders-backups"))
+		Expect(podSpec.Containers[0].Image).To(Equal("postgres:16"))
+		Expect(podSpec.Containers[0].VolumeMounts[0].MountPath).To(Equal(backupMountPath))
+		Expect(podSpec.Containers[0].Command[2]).To(ContainSubstring(`pg_dump -h "$DB_HOST" -Fc`))
+	})
+
+	It("should reject backups for engines without dump support", func() {
+		reconciler := &DatabaseReconciler{}
+		database := newDatabase(databasesv1alpha1.DatabaseTypeElasticsearch)
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("does not support Dump backups")))
+
+		database.Spec.Backup.Enabled = false
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+	})
+
+	It("should back up SQLite from its data volume on the node of the SQLite pod", func() {
+		reconciler := &DatabaseReconciler{}
+		database := newDatabase(databasesv1alpha1.DatabaseTypeSQLite)
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("backup requires storage")))
+
+		database.Spec.Storage = &databasesv1alpha1.StorageSpec{Size: "1Gi"}
+		database.Spec.Backup.Storage = &databasesv1alpha1.StorageSpec{Size: "lots"}
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring(`invalid backup storage size "lots"`)))
+
+		database.Spec.Backup.Storage = nil
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+
+		podSpec := reconciler.createBackupCronJob(database, mainBackupSchedule(database)).Spec.JobTemplate.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "orders-data")))
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "data", MountPath: "/data"}))
+		terms := podSpec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+		Expect(terms).To(HaveLen(1))
+		Expect(terms[0].TopologyKey).To(Equal(corev1.LabelHostname))
+		Expect(terms[0].LabelSelector.MatchLabels).To(Equal(reconciler.getLabels(database)))
+	})
+
+	Context("WAL archiving", func() {
+		newWALDatabase := func() *databasesv1alpha1.Database {
+			database := newDatabase(databasesv1alpha1.DatabaseTypePostgreSQL)
+			database.Spec.Storage = &databasesv1alpha1.StorageSpec{Size: "10Gi"}
+			database.Spec.Backup.Method = databasesv1alpha1.BackupMethodWAL
+			database.Spec.Backup.S3 = &databasesv1alpha1.S3Destination{
+				Bucket:            "archive",
+				Path:              "/pg/",
+				CredentialsSecret: "s3-credentials",
+			}
+			database.Spec.Backup.WAL = &databasesv1alpha1.WALArchivingSpec{Image: "wal-g:v3"}
+			return database
+		}
+
+		It("should require S3 storage, a wal-g image and a data volume", func() {
+			reconciler := &DatabaseReconciler{}
+			database := newWALDatabase()
+			Expect(reconciler.validateSpec(database)).To(Succeed())
+
+			database.Spec.Backup.WAL = nil
+			Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("backup.wal.image")))
+
+			database = newWALDatabase()
+			database.Spec.Storage = nil
+			Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("spec.storage")))
+
+			database = newWALDatabase()
+			database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+			Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("does not support WAL backups")))
+		})
+
+		It("should archive WAL and run base backups from a sidecar", func() {
+			reconciler := &DatabaseReconciler{}
+			podSpec := reconciler.createPostgreSQLStatefulSet(newWALDatabase(), 1, nil).Spec.Template.Spec
+
+			Expect(podSpec.InitContainers).To(HaveLen(1))
+			Expect(podSpec.InitContainers[0].Image).To(Equal("wal-g:v3"))
+
+			postgres := podSpec.Containers[0]
+			Expect(postgres.Args).To(ContainElements("archive_mode=on", "archive_command=/wal-g/wal-g wal-push %p"))
+			Expect(postgres.Env).To(ContainElement(HaveField("Value", "s3://archive/pg/shop/orders/$(POD_NAME)")))
+
+			Expect(podSpec.Containers).To(HaveLen(2))
+			sidecar := podSpec.Containers[1]
+			Expect(sidecar.Command[2]).To(ContainSubstring("backup-push"))
+			Expect(sidecar.Env).To(ContainElement(HaveField("Name", "PGPASSWORD")))
+			Expect(sidecar.Env).To(ContainElement(HaveField("Value", "86400")))
+		})
+	})
+
+	Context("pgBackRest", func() {
+		newIncrementalDatabase := func() *databasesv1alpha1.Database {
+			database := newDatabase(databasesv1alpha1.DatabaseTypePostgreSQL)
+			database.Spec.Storage = &databasesv1alpha1.StorageSpec{Size: "10Gi"}
+			database.Spec.Backup.Method = databasesv1alpha1.BackupMethodIncremental
+			database.Spec.Backup.Retention = &databasesv1alpha1.BackupRetention{MaxAge: &metav1.Duration{Duration: 30 * time.Hour}}
+			database.Spec.Backup.S3 = &databasesv1alpha1.S3Destination{
+				Bucket:            "archive",
+				Path:              "/pg/",
+				Region:            "eu-west-1",
+				Endpoint:          "https://minio.storage:9000",
+				CredentialsSecret: "s3-credentials",
+			}
+			database.Spec.Backup.PgBackRest = &databasesv1alpha1.PgBackRestSpec{
+				Image:                 "registry.example.com/postgres-pgbackrest:16",
+				DifferentialInterval:  &metav1.Duration{Duration: 12 * time.Hour},
+				DifferentialRetention: ptr.To(int32(3)),
+				Repo:                  &databasesv1alpha1.PgBackRestRepo{CipherSecret: "orders-repo", CompressType: "zst"},
+			}
+			return database
+		}
+
+		It("should require S3 storage with a region, a pgBackRest image and a data volume", func() {
+			reconciler := &DatabaseReconciler{}
+			database := newIncrementalDatabase()
+			Expect(reconciler.validateSpec(database)).To(Succeed())
+
+			database.Spec.Backup.S3.Region = ""
+			Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("backup.s3.region")))
+
+			database = newIncrementalDatabase()
+			database.Spec.Backup.PgBackRest = nil
+			Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("backup.pgBackRest.image")))
+
+			database = newIncrementalDatabase()
+			database.Spec.Backup.Schedules = []databasesv1alpha1.BackupSchedule{
+				{Name: "hourly", Method: databasesv1alpha1.BackupMethodIncremental, Schedule: "0 * * * *"},
+			}
+			Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("pgBackRest schedules its own backups")))
+
+			database = newIncrementalDatabase()
+			database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+			Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("does not support Incremental backups")))
+		})
+
+		It("should archive WAL with pgBackRest and schedule full, differential and incremental backups", func() {
+			reconciler := &DatabaseReconciler{}
+			podSpec := reconciler.createPostgreSQLStatefulSet(newIncrementalDatabase(), 1, nil).Spec.Template.Spec
+
+			postgres := podSpec.Containers[0]
+			Expect(postgres.Image).To(Equal("registry.example.com/postgres-pgbackrest:16"))
+			Expect(postgres.Args).To(ContainElement("archive_command=pgbackrest --stanza=db archive-push %p"))
+			Expect(postgres.VolumeMounts).To(ContainElement(HaveField("MountPath", postgresSocketDir)))
+			Expect(postgres.Env).To(ContainElements(
+				corev1.EnvVar{Name: "PGBACKREST_REPO1_PATH", Value: "/pg/shop/orders/$(POD_NAME)"},
+				corev1.EnvVar{Name: "PGBACKREST_REPO1_S3_ENDPOINT", Value: "minio.storage"},
+				corev1.EnvVar{Name: "PGBACKREST_REPO1_STORAGE_PORT", Value: "9000"},
+				corev1.EnvVar{Name: "PGBACKREST_REPO1_RETENTION_FULL_TYPE", Value: "time"},
+				corev1.EnvVar{Name: "PGBACKREST_REPO1_RETENTION_FULL", Value: "2"},
+				corev1.EnvVar{Name: "PGBACKREST_REPO1_RETENTION_DIFF", Value: "3"},
+				corev1.EnvVar{Name: "PGBACKREST_COMPRESS_TYPE", Value: "zst"},
+				HaveField("ValueFrom.SecretKeyRef.Name", "orders-repo"),
+				And(HaveField("Name", "PGBACKREST_REPO1_S3_KEY"), HaveField("ValueFrom.SecretKeyRef.Key", "AWS_ACCESS_KEY_ID"))))
+
+			Expect(podSpec.Containers).To(HaveLen(2))
+			sidecar := podSpec.Containers[1]
+			Expect(sidecar.Name).To(Equal(pgBackRestContainer))
+			Expect(sidecar.Command[2]).To(ContainSubstring(`pgbackrest backup --type="$type"`))
+			Expect(sidecar.Env).To(ContainElements(
+				corev1.EnvVar{Name: "FULL_INTERVAL", Value: "604800"},
+				corev1.EnvVar{Name: "DIFF_INTERVAL", Value: "43200"},
+				corev1.EnvVar{Name: "INCR_INTERVAL", Value: "3600"}))
+
+			database := newIncrementalDatabase()
+			Expect(backupDestination(database)).To(Equal("s3://archive/pg/shop/orders"))
+			Expect(nextScheduledBackup(database, time.Now())).To(BeNil())
+		})
+	})
+})
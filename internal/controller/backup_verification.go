@@ -0,0 +1,212 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	backupVerifyComponent = "backup-verify"
+	verifyMountPath       = "/verify"
+
+	// backupVerifyDeadline bounds a verification, so a restore hanging on a
+	// corrupt backup ends up Invalid
+	backupVerifyDeadline = int64(3600)
+)
+
+// Commands restoring $BACKUP_FILE into an ephemeral instance whose data lives in
+// /verify, and setting RESULT from a sanity query. The live database is never
+// contacted.
+var verifyScripts = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: `export PGDATA=/verify/data PGHOST=/verify PGUSER=postgres
+initdb --auth=trust --username=postgres >/dev/null
+pg_ctl --wait --log=/verify/postgres.log --options="-c listen_addresses='' -c unix_socket_directories=/verify" start >/dev/null
+pg_restore --no-owner --no-acl --exit-on-error -d postgres "$BACKUP_FILE"
+RESULT="$(psql -d postgres -Atc "SELECT count(*) FROM pg_catalog.pg_tables WHERE schemaname NOT IN ('pg_catalog', 'information_schema')") tables restored"`,
+	databasesv1alpha1.DatabaseTypeMongoDB: `mongod --dbpath /verify --bind_ip 127.0.0.1 --port 27018 --fork --logpath /verify/mongod.log >/dev/null
+mongorestore --host 127.0.0.1 --port 27018 --gzip --archive="$BACKUP_FILE" --quiet
+RESULT="$(mongosh --quiet --port 27018 --eval 'let n = 0; db.adminCommand({listDatabases: 1}).databases.forEach(d => { if (!["admin", "config", "local"].includes(d.name)) n += db.getSiblingDB(d.name).getCollectionNames().length }); print(n)') collections restored"`,
+	databasesv1alpha1.DatabaseTypeRedis: `redis-check-rdb "$BACKUP_FILE" >/dev/null
+cp "$BACKUP_FILE" /verify/dump.rdb
+redis-server --bind 127.0.0.1 --port 6390 --dir /verify --dbfilename dump.rdb --save "" --daemonize yes
+until redis-cli -p 6390 ping | grep -q PONG; do sleep 1; done
+RESULT="$(redis-cli -p 6390 DBSIZE) keys restored"`,
+	databasesv1alpha1.DatabaseTypeSQLite: `check="$(sqlite3 -readonly "$BACKUP_FILE" "PRAGMA integrity_check")"
+[ "$check" = ok ] || { echo "$check"; exit 1; }
+RESULT="$(sqlite3 -readonly "$BACKUP_FILE" "SELECT count(*) FROM sqlite_master WHERE type = 'table'") tables restored"`,
+}
+
+// verifyScript wraps the engine verification of a backup file so that the sanity
+// query result is reported as termination message
+func verifyScript(database *databasesv1alpha1.Database, file string) string {
+	return fmt.Sprintf(`set -e
+BACKUP_FILE="%s"
+%s
+echo "$RESULT" | tee /dev/termination-log`, file, verifyScripts[database.Spec.Type])
+}
+
+// backupVerificationEnabled reports whether completed backups of the Database are
+// verified. Snapshots are not files the ephemeral instance could restore.
+func backupVerificationEnabled(database *databasesv1alpha1.Database) bool {
+	_, supported := verifyScripts[database.Spec.Type]
+	return supported && database.Spec.Backup != nil && database.Spec.Backup.Verify &&
+		backupMethod(database) != databasesv1alpha1.BackupMethodSnapshot
+}
+
+// verificationPending reports whether a completed backup still waits for its verification
+func verificationPending(backup *databasesv1alpha1.DatabaseBackup) bool {
+	verification := backup.Status.Verification
+	return verification != nil &&
+		verification.Result != databasesv1alpha1.BackupVerificationValid &&
+		verification.Result != databasesv1alpha1.BackupVerificationInvalid
+}
+
+// reconcileBackupVerification runs the verification Job of a completed backup and
+// records whether the backup could be restored
+func (r *DatabaseBackupReconciler) reconcileBackupVerification(ctx context.Context, database *databasesv1alpha1.Database, backup *databasesv1alpha1.DatabaseBackup) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	verification := backup.Status.Verification
+
+	job := &batchv1.Job{}
+	jobName := backup.Name + "-" + backupVerifyComponent
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: backup.Namespace}, job)
+	if errors.IsNotFound(err) {
+		job = r.databaseReconciler(r.Client, r.Scheme).createBackupVerifyJob(database, backup)
+		if err := controllerutil.SetControllerReference(backup, job, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		log.Info("Creating backup verification Job", "name", job.Name)
+		if err := r.Create(ctx, job); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		verification.Result = databasesv1alpha1.BackupVerificationRunning
+		verification.JobName = job.Name
+		verification.Message = ""
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	verification.JobName = job.Name
+	finished, succeeded := jobFinished(job)
+	if !finished {
+		verification.Result = databasesv1alpha1.BackupVerificationRunning
+		return ctrl.Result{}, nil
+	}
+
+	now := metav1.Now()
+	verification.CompletionTime = &now
+	verification.Result = databasesv1alpha1.BackupVerificationInvalid
+	if succeeded {
+		verification.Result = databasesv1alpha1.BackupVerificationValid
+	}
+
+	message, err := r.verificationMessage(ctx, job)
+	if err != nil {
+		log.Error(err, "Failed to read verification result", "job", job.Name)
+	}
+	if message == "" && !succeeded {
+		message = fmt.Sprintf("Verification Job %s failed", job.Name)
+	}
+	verification.Message = message
+	log.Info("Backup verified", "result", verification.Result, "message", message)
+	return ctrl.Result{}, nil
+}
+
+// verificationMessage reads the termination message of the verification pod:
+// the sanity query result, or the end of the log when the restore failed
+func (r *DatabaseBackupReconciler) verificationMessage(ctx context.Context, job *batchv1.Job) (string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace),
+		client.MatchingLabels{batchv1.JobNameLabel: job.Name}); err != nil {
+		return "", err
+	}
+
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if terminated := status.State.Terminated; terminated != nil {
+				return strings.TrimSpace(terminated.Message), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// createBackupVerifyJob builds the Job restoring the backup file into an
+// ephemeral instance. It runs once: a failure means the backup is invalid.
+// Encrypted backups are decrypted into the ephemeral volume first.
+func (r *DatabaseReconciler) createBackupVerifyJob(database *databasesv1alpha1.Database, backup *databasesv1alpha1.DatabaseBackup) *batchv1.Job {
+	file := path.Join(backupMountPath, databaseBackupFile(database, backup))
+	job := r.createAdminJob(database, backupVerifyComponent, "", nil)
+	job.Name = backup.Name + "-" + backupVerifyComponent
+	backoffLimit := int32(0)
+	deadline := backupVerifyDeadline
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.ActiveDeadlineSeconds = &deadline
+	job.Spec.TTLSecondsAfterFinished = nil
+
+	podSpec := &job.Spec.Template.Spec
+	r.mountBackupVolumes(database, backupClaimName(database), podSpec)
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         backupVerifyComponent,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	if backup.Status.KMSKeyID != "" {
+		file = r.addBackupDecryption(database, podSpec, decryptionSettings(database, backup.Status.KMSKeyID), file,
+			corev1.VolumeMount{Name: backupComponent, MountPath: backupMountPath, ReadOnly: true},
+			corev1.VolumeMount{Name: backupVerifyComponent, MountPath: verifyMountPath})
+	}
+
+	container := &podSpec.Containers[0]
+	container.Command = []string{"/bin/sh", "-c", verifyScript(database, file)}
+	// The ephemeral instance needs no network access nor the credentials of the
+	// live database
+	container.Env = nil
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      backupVerifyComponent,
+		MountPath: verifyMountPath,
+	})
+	container.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypePostgreSQL {
+		// initdb refuses to run as root
+		uid := postgresUID
+		if user := engineLayout(database).user; user != 0 {
+			uid = user
+		}
+		container.SecurityContext = &corev1.SecurityContext{RunAsUser: &uid, RunAsGroup: &uid}
+	}
+	return job
+}
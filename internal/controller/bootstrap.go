@@ -0,0 +1,376 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	bootstrapComponent = "bootstrap"
+	// conditionBootstrapFailed reports a bootstrap spec whose Job failed
+	conditionBootstrapFailed = "BootstrapFailed"
+	// bootstrapHashAnnotation records on the Job which bootstrap spec it applies
+	bootstrapHashAnnotation = "databases.database-operator.io/bootstrap-hash"
+
+	// A failed bootstrap Job is run again after a delay doubling with each
+	// consecutive failure, up to bootstrapMaxBackoff
+	bootstrapBaseBackoff = 30 * time.Second
+	bootstrapMaxBackoff  = 30 * time.Minute
+)
+
+// privilegesByEngine lists the privileges a grant accepts on each engine
+var privilegesByEngine = map[databasesv1alpha1.DatabaseType][]databasesv1alpha1.BootstrapPrivilege{
+	databasesv1alpha1.DatabaseTypePostgreSQL: {
+		databasesv1alpha1.BootstrapPrivilegeAll,
+		databasesv1alpha1.BootstrapPrivilegeConnect,
+		databasesv1alpha1.BootstrapPrivilegeCreate,
+		databasesv1alpha1.BootstrapPrivilegeTemporary,
+	},
+	databasesv1alpha1.DatabaseTypeMongoDB: {
+		databasesv1alpha1.BootstrapPrivilegeRead,
+		databasesv1alpha1.BootstrapPrivilegeReadWrite,
+		databasesv1alpha1.BootstrapPrivilegeDBAdmin,
+		databasesv1alpha1.BootstrapPrivilegeDBOwner,
+	},
+}
+
+// bootstrapEnabled reports whether the spec declares logical databases or users
+func bootstrapEnabled(database *databasesv1alpha1.Database) bool {
+	bootstrap := database.Spec.Bootstrap
+	return bootstrap != nil && (len(bootstrap.Databases) > 0 || len(bootstrap.Users) > 0)
+}
+
+// validateBootstrap checks that the engine supports the declared bootstrap
+func validateBootstrap(database *databasesv1alpha1.Database) error {
+	if !bootstrapEnabled(database) {
+		return nil
+	}
+
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		for _, db := range database.Spec.Bootstrap.Databases {
+			if len(db.Extensions) > 0 {
+				return fmt.Errorf("bootstrap database %s: MongoDB has no extensions", db.Name)
+			}
+		}
+	default:
+		return fmt.Errorf("%s does not support bootstrap databases and users", database.Spec.Type)
+	}
+	for _, user := range database.Spec.Bootstrap.Users {
+		if err := validateGrants(database.Spec.Type, user.Grants); err != nil {
+			return fmt.Errorf("bootstrap user %s: %w", user.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateGrants checks that the engine knows the privileges of the grants
+func validateGrants(engine databasesv1alpha1.DatabaseType, grants []databasesv1alpha1.BootstrapGrant) error {
+	for _, grant := range grants {
+		for _, privilege := range grant.Privileges {
+			if !slices.Contains(privilegesByEngine[engine], privilege) {
+				return fmt.Errorf("%s has no privilege %s, use one of %v", engine, privilege, privilegesByEngine[engine])
+			}
+		}
+	}
+	return nil
+}
+
+// bootstrapHash identifies a bootstrap spec
+func bootstrapHash(database *databasesv1alpha1.Database) string {
+	spec, _ := json.Marshal(database.Spec.Bootstrap)
+	sum := sha256.Sum256(spec)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// bootstrapPasswordEnv is the variable holding the password of the i-th user
+func bootstrapPasswordEnv(i int) string {
+	return fmt.Sprintf("BOOTSTRAP_PASSWORD_%d", i)
+}
+
+// psqlPasswordVariable sets a psql variable from an environment variable. The
+// shell started by psql expands it, which keeps the password out of the
+// command lines of the Job.
+func psqlPasswordVariable(variable, env string) string {
+	return fmt.Sprintf("\\set %s `printf '%%s' \"$%s\"`\n", variable, env)
+}
+
+// bootstrapEnv passes the user passwords to the bootstrap Job
+func bootstrapEnv(database *databasesv1alpha1.Database) []corev1.EnvVar {
+	env := []corev1.EnvVar{}
+	for i, user := range database.Spec.Bootstrap.Users {
+		env = append(env, corev1.EnvVar{
+			Name: bootstrapPasswordEnv(i),
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: user.PasswordSecret.Name},
+					Key:                  user.PasswordSecret.Key,
+				},
+			},
+		})
+	}
+	return env
+}
+
+// bootstrapScript renders the idempotent commands provisioning the bootstrap
+// databases and users. Names are restricted by the CRD schema and quoted.
+func bootstrapScript(database *databasesv1alpha1.Database) string {
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB {
+		return mongoDBBootstrapScript(database.Spec.Bootstrap)
+	}
+	return postgreSQLBootstrapScript(database.Spec.Bootstrap)
+}
+
+func postgreSQLBootstrapScript(bootstrap *databasesv1alpha1.BootstrapSpec) string {
+	var sql strings.Builder
+	for i, user := range bootstrap.Users {
+		sql.WriteString(psqlPasswordVariable(fmt.Sprintf("password_%d", i), bootstrapPasswordEnv(i)))
+		fmt.Fprintf(&sql, "SELECT 'CREATE ROLE \"%[1]s\" LOGIN' WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '%[1]s')\\gexec\n", user.Name)
+		fmt.Fprintf(&sql, "ALTER ROLE \"%s\" LOGIN PASSWORD :'password_%d';\n", user.Name, i)
+	}
+	for _, db := range bootstrap.Databases {
+		fmt.Fprintf(&sql, "SELECT 'CREATE DATABASE \"%[1]s\"' WHERE NOT EXISTS (SELECT FROM pg_database WHERE datname = '%[1]s')\\gexec\n", db.Name)
+		if db.Owner != "" {
+			fmt.Fprintf(&sql, "ALTER DATABASE \"%s\" OWNER TO \"%s\";\n", db.Name, db.Owner)
+		}
+	}
+	for _, user := range bootstrap.Users {
+		for _, grant := range user.Grants {
+			privileges := []string{}
+			for _, privilege := range grant.Privileges {
+				privileges = append(privileges, string(privilege))
+			}
+			fmt.Fprintf(&sql, "GRANT %s ON DATABASE \"%s\" TO \"%s\";\n", strings.Join(privileges, ", "), grant.Database, user.Name)
+		}
+	}
+	// Extensions are created last, connected to their database
+	for _, db := range bootstrap.Databases {
+		if len(db.Extensions) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sql, "\\connect \"%s\"\n", db.Name)
+		for _, extension := range db.Extensions {
+			fmt.Fprintf(&sql, "CREATE EXTENSION IF NOT EXISTS \"%s\";\n", extension)
+		}
+	}
+
+	return fmt.Sprintf("psql -h \"$DB_HOST\" -v ON_ERROR_STOP=1 <<'SQL'\n%sSQL", sql.String())
+}
+
+func mongoDBBootstrapScript(bootstrap *databasesv1alpha1.BootstrapSpec) string {
+	owners := map[string][]string{}
+	for _, db := range bootstrap.Databases {
+		if db.Owner != "" {
+			owners[db.Owner] = append(owners[db.Owner], db.Name)
+		}
+	}
+
+	var js strings.Builder
+	js.WriteString(`const admin = db.getSiblingDB("admin");
+function ensureUser(name, pwd, roles) {
+  if (admin.getUser(name)) admin.updateUser(name, {pwd: pwd, roles: roles});
+  else admin.createUser({user: name, pwd: pwd, roles: roles});
+}
+`)
+	declared := map[string]bool{}
+	for i, user := range bootstrap.Users {
+		declared[user.Name] = true
+		roles := []string{}
+		for _, grant := range user.Grants {
+			for _, privilege := range grant.Privileges {
+				roles = append(roles, fmt.Sprintf("{role: %q, db: %q}", privilege, grant.Database))
+			}
+		}
+		for _, name := range owners[user.Name] {
+			roles = append(roles, fmt.Sprintf("{role: \"dbOwner\", db: %q}", name))
+		}
+		fmt.Fprintf(&js, "ensureUser(%q, process.env.%s, [%s]);\n", user.Name, bootstrapPasswordEnv(i), strings.Join(roles, ", "))
+	}
+	// Owners managed outside of the bootstrap keep their other roles
+	for _, db := range bootstrap.Databases {
+		if db.Owner != "" && !declared[db.Owner] {
+			fmt.Fprintf(&js, "admin.grantRolesToUser(%q, [{role: \"dbOwner\", db: %q}]);\n", db.Owner, db.Name)
+		}
+	}
+
+	return fmt.Sprintf(`cat > /tmp/bootstrap.js <<'JS'
+%sJS
+mongosh --host "$DB_HOST" -u "$MONGO_USERNAME" -p "$MONGO_PASSWORD" --authenticationDatabase admin --quiet --file /tmp/bootstrap.js`,
+		js.String())
+}
+
+// reconcileBootstrap provisions the bootstrap databases and users through an
+// admin Job whenever the bootstrap spec changes
+func (r *DatabaseReconciler) reconcileBootstrap(ctx context.Context, database *databasesv1alpha1.Database) error {
+	log := log.FromContext(ctx)
+
+	if !bootstrapEnabled(database) {
+		database.Status.Bootstrap = nil
+		meta.RemoveStatusCondition(&database.Status.Conditions, conditionBootstrapFailed)
+		return nil
+	}
+	// Wait for a server accepting connections
+	if database.Status.ReadyReplicas == 0 {
+		return nil
+	}
+
+	hash := bootstrapHash(database)
+	job := &batchv1.Job{}
+	jobName := database.Name + "-" + bootstrapComponent
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: database.Namespace}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if err == nil {
+		// The Job deletion event triggers the next step
+		if !job.DeletionTimestamp.IsZero() {
+			return nil
+		}
+		current := job.Annotations[bootstrapHashAnnotation] == hash
+		finished, succeeded := jobFinished(job)
+		if current && !finished {
+			return nil
+		}
+		if current && !succeeded {
+			return r.retryBootstrap(ctx, database, job, hash, time.Now())
+		}
+
+		// Remove finished Jobs and Jobs applying an outdated spec; the Job
+		// deletion event triggers the next step
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if database.Status.Bootstrap != nil {
+			database.Status.Bootstrap.NextRetry = nil
+		}
+
+		if current {
+			log.Info("Provisioned bootstrap databases and users", "hash", hash)
+			database.Status.Bootstrap = bootstrapStatus(database, hash)
+			meta.RemoveStatusCondition(&database.Status.Conditions, conditionBootstrapFailed)
+			recordOperation(database, recordBootstrap, databasesv1alpha1.OperationSucceeded,
+				fmt.Sprintf("Provisioned %d databases and %d users", len(database.Status.Bootstrap.Databases), len(database.Status.Bootstrap.Users)), time.Now())
+		}
+		return nil
+	}
+
+	if database.Status.Bootstrap != nil && database.Status.Bootstrap.AppliedHash == hash {
+		return nil
+	}
+
+	job = r.createAdminJob(database, bootstrapComponent, bootstrapScript(database), bootstrapEnv(database))
+	job.Annotations = map[string]string{bootstrapHashAnnotation: hash}
+
+	if err := controllerutil.SetControllerReference(database, job, r.Scheme); err != nil {
+		return err
+	}
+
+	log.Info("Provisioning bootstrap databases and users", "hash", hash)
+	return r.Create(ctx, job)
+}
+
+// retryBootstrap reports a failed bootstrap Job and removes it once its backoff
+// elapsed, for the next reconcile to run the spec again. The failure does not
+// stop the rest of the reconcile, which requeues when the backoff ends.
+func (r *DatabaseReconciler) retryBootstrap(ctx context.Context, database *databasesv1alpha1.Database, job *batchv1.Job, hash string, now time.Time) error {
+	message := fmt.Sprintf("Failed to provision bootstrap databases and users, see the logs of Job %s", job.Name)
+
+	// Retries of a spec that already failed are not recorded again
+	if database.Status.Bootstrap == nil {
+		database.Status.Bootstrap = &databasesv1alpha1.BootstrapStatus{}
+	}
+	status := database.Status.Bootstrap
+	if status.FailedHash != hash {
+		status.FailedHash = hash
+		status.Failures = 0
+		recordOperation(database, recordBootstrap, databasesv1alpha1.OperationFailed, message, now)
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               conditionBootstrapFailed,
+		Status:             metav1.ConditionTrue,
+		Reason:             "JobFailed",
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+
+	retry := jobFailedTime(job).Add(bootstrapBackoff(status.Failures + 1))
+	if now.Before(retry) {
+		status.NextRetry = &metav1.Time{Time: retry}
+		return nil
+	}
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	status.Failures++
+	status.NextRetry = nil
+	log.FromContext(ctx).Info("Running the failed bootstrap again", "hash", hash, "failures", status.Failures)
+	return nil
+}
+
+// bootstrapRetryRemaining returns the time left until a failed bootstrap is
+// run again, zero when none is waiting
+func bootstrapRetryRemaining(database *databasesv1alpha1.Database, now time.Time) time.Duration {
+	status := database.Status.Bootstrap
+	if status == nil || status.NextRetry == nil {
+		return 0
+	}
+	return max(status.NextRetry.Sub(now), time.Second)
+}
+
+// bootstrapBackoff is the delay before the bootstrap runs again after the
+// given number of consecutive failures
+func bootstrapBackoff(failures int32) time.Duration {
+	backoff := bootstrapBaseBackoff
+	for i := int32(1); i < failures && backoff < bootstrapMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, bootstrapMaxBackoff)
+}
+
+// bootstrapStatus lists what a successful bootstrap run provisioned
+func bootstrapStatus(database *databasesv1alpha1.Database, hash string) *databasesv1alpha1.BootstrapStatus {
+	status := &databasesv1alpha1.BootstrapStatus{AppliedHash: hash}
+	for _, db := range database.Spec.Bootstrap.Databases {
+		status.Databases = append(status.Databases, db.Name)
+	}
+	for _, user := range database.Spec.Bootstrap.Users {
+		status.Users = append(status.Users, user.Name)
+	}
+	return status
+}
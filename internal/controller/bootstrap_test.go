@@ -0,0 +1,190 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Bootstrap", func() {
+	var database *databasesv1alpha1.Database
+
+	BeforeEach(func() {
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Bootstrap: &databasesv1alpha1.BootstrapSpec{
+					Databases: []databasesv1alpha1.BootstrapDatabase{
+						{Name: "catalog", Owner: "app", Extensions: []databasesv1alpha1.BootstrapIdentifier{"uuid-ossp"}},
+					},
+					Users: []databasesv1alpha1.BootstrapUser{{
+						Name:           "app",
+						PasswordSecret: databasesv1alpha1.SecretReference{Name: "app-credentials", Key: "password"},
+						Grants: []databasesv1alpha1.BootstrapGrant{
+							{Database: "catalog", Privileges: []databasesv1alpha1.BootstrapPrivilege{
+								databasesv1alpha1.BootstrapPrivilegeConnect, databasesv1alpha1.BootstrapPrivilegeTemporary,
+							}},
+						},
+					}},
+				},
+			},
+		}
+	})
+
+	It("should render idempotent PostgreSQL statements", func() {
+		Expect(bootstrapScript(database)).To(Equal(`psql -h "$DB_HOST" -v ON_ERROR_STOP=1 <<'SQL'
+\set password_0 ` + "`printf '%s' \"$BOOTSTRAP_PASSWORD_0\"`" + `
+SELECT 'CREATE ROLE "app" LOGIN' WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'app')\gexec
+ALTER ROLE "app" LOGIN PASSWORD :'password_0';
+SELECT 'CREATE DATABASE "catalog"' WHERE NOT EXISTS (SELECT FROM pg_database WHERE datname = 'catalog')\gexec
+ALTER DATABASE "catalog" OWNER TO "app";
+GRANT CONNECT, TEMPORARY ON DATABASE "catalog" TO "app";
+\connect "catalog"
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+SQL`))
+		Expect(bootstrapEnv(database)[0].ValueFrom.SecretKeyRef.Name).To(Equal("app-credentials"))
+	})
+
+	It("should map MongoDB grants and ownership to roles", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		Expect(validateBootstrap(database)).To(MatchError(ContainSubstring("no extensions")))
+		database.Spec.Bootstrap.Databases[0].Extensions = nil
+		Expect(validateBootstrap(database)).To(MatchError(ContainSubstring("MongoDB has no privilege CONNECT")))
+
+		database.Spec.Bootstrap.Databases = []databasesv1alpha1.BootstrapDatabase{
+			{Name: "catalog", Owner: "app"},
+			{Name: "reports", Owner: "analyst"},
+		}
+		database.Spec.Bootstrap.Users[0].Grants[0].Privileges = []databasesv1alpha1.BootstrapPrivilege{databasesv1alpha1.BootstrapPrivilegeRead}
+		Expect(validateBootstrap(database)).To(Succeed())
+
+		script := bootstrapScript(database)
+		Expect(script).To(ContainSubstring(`ensureUser("app", process.env.BOOTSTRAP_PASSWORD_0, ` +
+			`[{role: "read", db: "catalog"}, {role: "dbOwner", db: "catalog"}]);`))
+		Expect(script).To(ContainSubstring(`admin.grantRolesToUser("analyst", [{role: "dbOwner", db: "reports"}]);`))
+	})
+
+	It("should reject engines without logical databases", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		Expect(validateBootstrap(database)).To(MatchError(ContainSubstring("does not support bootstrap")))
+	})
+
+	It("should run the bootstrap Job once per spec change", func() {
+		ctx := context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler := &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(database).Build(),
+			Scheme: scheme,
+		}
+		jobKey := types.NamespacedName{Name: "orders-bootstrap", Namespace: "shop"}
+
+		// Nothing runs before the database accepts connections
+		Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		database.Status.ReadyReplicas = 1
+		Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue(bootstrapHashAnnotation, bootstrapHash(database)))
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+		Expect(database.Status.Bootstrap.AppliedHash).To(Equal(bootstrapHash(database)))
+		Expect(database.Status.Bootstrap.Databases).To(Equal([]string{"catalog"}))
+		Expect(database.Status.Bootstrap.Users).To(Equal([]string{"app"}))
+
+		// The applied spec is not provisioned again
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+		Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		database.Spec.Bootstrap.Databases = append(database.Spec.Bootstrap.Databases,
+			databasesv1alpha1.BootstrapDatabase{Name: "billing"})
+		Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring(`CREATE DATABASE "billing"`))
+	})
+
+	It("should record a failing bootstrap spec once and retry it with a backoff", func() {
+		ctx := context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler := &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(database).Build(),
+			Scheme: scheme,
+		}
+		jobKey := types.NamespacedName{Name: "orders-bootstrap", Namespace: "shop"}
+		database.Status.ReadyReplicas = 1
+
+		for range 2 {
+			Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+			job := &batchv1.Job{}
+			Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+			job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+			Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+			Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+		}
+		Expect(database.Status.Bootstrap.FailedHash).To(Equal(bootstrapHash(database)))
+		Expect(database.Status.Bootstrap.Failures).To(Equal(int32(2)))
+		Expect(database.Status.RecentOperations).To(HaveLen(1))
+		Expect(database.Status.RecentOperations[0].Outcome).To(Equal(databasesv1alpha1.OperationFailed))
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, conditionBootstrapFailed)).To(BeTrue())
+
+		// A Job failing again is kept until its backoff elapses
+		Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		failed := batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: metav1.Now()}
+		job.Status.Conditions = []batchv1.JobCondition{failed}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(bootstrapBackoff(3)).To(Equal(2 * time.Minute))
+		Expect(bootstrapRetryRemaining(database, time.Now())).To(BeNumerically("~", bootstrapBackoff(3), time.Second))
+
+		job.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-bootstrapBackoff(3)))
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		Expect(reconciler.reconcileBootstrap(ctx, database)).To(Succeed())
+		Expect(bootstrapRetryRemaining(database, time.Now())).To(BeZero())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+		Expect(database.Status.Bootstrap.Failures).To(Equal(int32(3)))
+		Expect(bootstrapBackoff(20)).To(Equal(bootstrapMaxBackoff))
+	})
+})
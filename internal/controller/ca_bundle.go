@@ -0,0 +1,150 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	caBundleSuffix    = "-ca-bundle"
+	caBundleVolume    = "ca-bundle"
+	caBundleKey       = "ca.crt"
+	caBundleMountPath = "/etc/database-operator/ca"
+	caBundleFile      = caBundleMountPath + "/" + caBundleKey
+)
+
+// LoadCABundle reads a PEM CA bundle, which pods talking to external services
+// mount and HTTPClient trusts
+func LoadCABundle(path string) ([]byte, error) {
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return bundle, nil
+}
+
+// HTTPClient returns the client of the outbound HTTP requests of the operator.
+// Its transport trusts the CA bundle in addition to the system roots; without a
+// bundle it is http.DefaultClient.
+func HTTPClient(caBundle []byte) *http.Client {
+	if len(caBundle) == 0 {
+		return http.DefaultClient
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	pool.AppendCertsFromPEM(caBundle)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: pool}
+	return &http.Client{Transport: transport}
+}
+
+// reconcileCABundle copies the operator CA bundle into the namespace of the
+// Database, where pods talking to external services mount it
+func (r *DatabaseReconciler) reconcileCABundle(ctx context.Context, database *databasesv1alpha1.Database) error {
+	log := log.FromContext(ctx)
+
+	name := database.Name + caBundleSuffix
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, configMap)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if len(r.CABundle) == 0 {
+		if err == nil {
+			log.Info("Deleting CA bundle ConfigMap", "name", name)
+			if err := r.Delete(ctx, configMap); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if errors.IsNotFound(err) {
+		log.Info("Creating CA bundle ConfigMap", "name", name)
+	} else if configMap.Data[caBundleKey] == string(r.CABundle) {
+		return nil
+	}
+
+	configMap = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: database.Namespace,
+			Labels:    r.getComponentLabels(database, caBundleVolume),
+		},
+		Data: map[string]string{caBundleKey: string(r.CABundle)},
+	}
+	if err := controllerutil.SetControllerReference(database, configMap, r.Scheme); err != nil {
+		return err
+	}
+	return r.apply(ctx, configMap)
+}
+
+// addCABundle mounts the CA bundle into a container of the pod and points the S3
+// clients at it. It does nothing when the operator has no CA bundle.
+func (r *DatabaseReconciler) addCABundle(database *databasesv1alpha1.Database, podSpec *corev1.PodSpec, container *corev1.Container) {
+	if len(r.CABundle) == 0 {
+		return
+	}
+
+	found := false
+	for _, volume := range podSpec.Volumes {
+		found = found || volume.Name == caBundleVolume
+	}
+	if !found {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: caBundleVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: database.Name + caBundleSuffix},
+				},
+			},
+		})
+	}
+
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      caBundleVolume,
+		MountPath: caBundleMountPath,
+		ReadOnly:  true,
+	})
+	container.Env = append(container.Env,
+		corev1.EnvVar{Name: "AWS_CA_BUNDLE", Value: caBundleFile},
+		corev1.EnvVar{Name: "WALG_S3_CA_CERT_FILE", Value: caBundleFile},
+	)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	certutil "k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("CA bundle", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		bundle     []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		bundle, _, err = certutil.GenerateSelfSignedCertKey("proxy.example.com", nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{
+			Client:            fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build(),
+			Scheme:            scheme,
+			ReconcilerOptions: ReconcilerOptions{CABundle: bundle},
+		}
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "uid"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+		}
+	})
+
+	It("should trust the bundle in a dedicated HTTP client only", func() {
+		cert, key, err := certutil.GenerateSelfSignedCertKey("127.0.0.1", nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		pair, err := tls.X509KeyPair(cert, key)
+		Expect(err).NotTo(HaveOccurred())
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{pair}}
+		server.StartTLS()
+		DeferCleanup(server.Close)
+
+		path := filepath.Join(GinkgoT().TempDir(), "ca.crt")
+		Expect(os.WriteFile(path, cert, 0o600)).To(Succeed())
+		loaded, err := LoadCABundle(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(cert))
+
+		client := HTTPClient(loaded)
+		Expect(client).NotTo(BeIdenticalTo(http.DefaultClient))
+		resp, err := client.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+
+		_, err = http.DefaultClient.Get(server.URL)
+		Expect(err).To(HaveOccurred())
+		Expect(HTTPClient(nil)).To(BeIdenticalTo(http.DefaultClient))
+
+		Expect(os.WriteFile(path, []byte("not a certificate"), 0o600)).To(Succeed())
+		_, err = LoadCABundle(path)
+		Expect(err).To(MatchError(ContainSubstring("no PEM certificates")))
+	})
+
+	It("should copy the bundle into the Database namespace and remove it when unset", func() {
+		ctx := context.Background()
+		key := types.NamespacedName{Name: "orders-ca-bundle", Namespace: "shop"}
+
+		Expect(reconciler.reconcileCABundle(ctx, database)).To(Succeed())
+		configMap := &corev1.ConfigMap{}
+		Expect(reconciler.Get(ctx, key, configMap)).To(Succeed())
+		Expect(configMap.Data).To(HaveKeyWithValue("ca.crt", string(bundle)))
+
+		reconciler.CABundle = nil
+		Expect(reconciler.reconcileCABundle(ctx, database)).To(Succeed())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, key, &corev1.ConfigMap{}))).To(BeTrue())
+	})
+
+	It("should mount the bundle into Job pods", func() {
+		template := reconciler.adminPodTemplate(database, backupComponent, "postgres:16", "true", nil)
+
+		Expect(template.Spec.Volumes).To(ConsistOf(HaveField("ConfigMap.Name", "orders-ca-bundle")))
+		Expect(template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "AWS_CA_BUNDLE", Value: caBundleFile}))
+
+		reconciler.CABundle = nil
+		Expect(reconciler.adminPodTemplate(database, backupComponent, "postgres:16", "true", nil).Spec.Volumes).To(BeEmpty())
+	})
+})
@@ -0,0 +1,201 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// defaultCanaryPeriod is how long the canary has to stay ready and healthy
+	defaultCanaryPeriod = 5 * time.Minute
+	// canaryReadyTimeout is how long the canary has to become ready on the new
+	// template before the rollout is aborted
+	canaryReadyTimeout = 10 * time.Minute
+
+	reasonCanaryFailed = "CanaryFailed"
+)
+
+// canaryEnabled reports whether template changes roll out to a canary first
+func canaryEnabled(database *databasesv1alpha1.Database) bool {
+	return database.Spec.Rollout != nil && database.Spec.Rollout.Canary
+}
+
+// canaryPeriod returns how long the canary is watched before the rollout goes on
+func canaryPeriod(database *databasesv1alpha1.Database) time.Duration {
+	if rollout := database.Spec.Rollout; rollout != nil && rollout.CanaryPeriod != nil {
+		return rollout.CanaryPeriod.Duration
+	}
+	return defaultCanaryPeriod
+}
+
+// startCanary partitions the rolling update of a StatefulSet about to get a new
+// pod template so only the replica with the highest ordinal is updated, and
+// records the canary in status. A single replica is updated directly.
+func startCanary(database *databasesv1alpha1.Database, statefulSet *appsv1.StatefulSet, now time.Time) {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	if !canaryEnabled(database) || replicas < 2 {
+		if rollout := database.Status.Rollout; rollout != nil && rollout.StatefulSet == statefulSet.Name {
+			database.Status.Rollout = nil
+		}
+		return
+	}
+
+	partition := replicas - 1
+	statefulSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+		Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+	}
+	database.Status.Rollout = &databasesv1alpha1.RolloutStatus{
+		StatefulSet: statefulSet.Name,
+		Canary:      fmt.Sprintf("%s-%d", statefulSet.Name, partition),
+		Step:        databasesv1alpha1.RolloutStepCanary,
+		StartedAt:   metav1.NewTime(now),
+	}
+}
+
+// reconcileCanary watches the canary of a StatefulSet whose template is
+// applied. Once it ran the new template ready for the canary period, with the
+// engine health checked healthy since, the other replicas are updated. A canary
+// not ready in time, no longer ready, or an unhealthy engine aborts the rollout:
+// the other replicas stay on the previous template until the spec changes.
+func (r *DatabaseReconciler) reconcileCanary(ctx context.Context, database *databasesv1alpha1.Database, statefulSet *appsv1.StatefulSet, now time.Time) error {
+	rollout := database.Status.Rollout
+	if rollout == nil || rollout.Step != databasesv1alpha1.RolloutStepCanary || rollout.StatefulSet != statefulSet.Name {
+		return nil
+	}
+	if statefulSetPartition(statefulSet) == 0 {
+		rollout.Step = databasesv1alpha1.RolloutStepPromoted
+		rollout.Message = "The partition was lifted by others"
+		return nil
+	}
+	if !canaryEnabled(database) {
+		return r.promoteCanary(ctx, statefulSet, rollout, "Canary rollouts were disabled")
+	}
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation {
+		return nil
+	}
+	rollout.Revision = statefulSet.Status.UpdateRevision
+
+	pod := &corev1.Pod{}
+	err := r.Get(ctx, types.NamespacedName{Name: rollout.Canary, Namespace: database.Namespace}, pod)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	ready := err == nil && pod.Labels[appsv1.ControllerRevisionHashLabelKey] == rollout.Revision && podReady(pod)
+
+	switch health := database.Status.Health; {
+	case !ready && rollout.CanaryReadyAt != nil:
+		r.abortCanary(database, rollout, fmt.Sprintf("canary %s is no longer ready", rollout.Canary), now)
+	case !ready && now.Sub(rollout.StartedAt.Time) > canaryReadyTimeout:
+		r.abortCanary(database, rollout, fmt.Sprintf("canary %s did not become ready within %s", rollout.Canary, canaryReadyTimeout), now)
+	case !ready:
+		rollout.Message = fmt.Sprintf("Waiting for canary %s to become ready", rollout.Canary)
+	case rollout.CanaryReadyAt == nil:
+		readyAt := metav1.NewTime(now)
+		rollout.CanaryReadyAt = &readyAt
+		rollout.Message = fmt.Sprintf("Watching canary %s for %s", rollout.Canary, canaryPeriod(database))
+	case health != nil && health.State == databasesv1alpha1.HealthStateUnhealthy && health.CheckedAt.After(rollout.CanaryReadyAt.Time):
+		r.abortCanary(database, rollout, fmt.Sprintf("engine health check failed with canary %s: %s", rollout.Canary, health.Message), now)
+	case now.Sub(rollout.CanaryReadyAt.Time) < canaryPeriod(database):
+	case health != nil && !health.CheckedAt.After(rollout.CanaryReadyAt.Time):
+		rollout.Message = fmt.Sprintf("Waiting for a health check of canary %s", rollout.Canary)
+	default:
+		return r.promoteCanary(ctx, statefulSet, rollout, fmt.Sprintf("Canary %s stayed ready and healthy for %s", rollout.Canary, canaryPeriod(database)))
+	}
+	return nil
+}
+
+// promoteCanary lifts the partition so the other replicas are updated
+func (r *DatabaseReconciler) promoteCanary(ctx context.Context, statefulSet *appsv1.StatefulSet, rollout *databasesv1alpha1.RolloutStatus, message string) error {
+	log.FromContext(ctx).Info("Promoting canary", "pod", rollout.Canary, "reason", message)
+	before := *statefulSet.Spec.DeepCopy()
+	partition := int32(0)
+	statefulSet.Spec.UpdateStrategy.RollingUpdate.Partition = &partition
+	restampAppliedSpec(statefulSet, before)
+	if err := r.Update(ctx, statefulSet); err != nil {
+		return err
+	}
+	rollout.Step = databasesv1alpha1.RolloutStepPromoted
+	rollout.Message = message
+	return nil
+}
+
+// abortCanary stops the rollout at the canary and releases the Update lock, so
+// other operations can run while the other replicas keep the previous template
+func (r *DatabaseReconciler) abortCanary(database *databasesv1alpha1.Database, rollout *databasesv1alpha1.RolloutStatus, reason string, now time.Time) {
+	message := fmt.Sprintf("Aborted the rollout: %s", reason)
+	rollout.Step = databasesv1alpha1.RolloutStepAborted
+	rollout.Message = message
+	r.event(database, corev1.EventTypeWarning, reasonCanaryFailed, message)
+	if activeOperation(database) == disruptiveOperationUpdate {
+		recordOperation(database, recordUpdate, databasesv1alpha1.OperationFailed, message, now)
+	}
+	releaseOperation(database, disruptiveOperationUpdate)
+}
+
+// completeRollout records that every replica runs the template of a rollout,
+// which a canary does on its own when the template was set back to the one
+// the others run
+func completeRollout(database *databasesv1alpha1.Database, workload *appsv1.StatefulSet) {
+	if rollout := database.Status.Rollout; rollout != nil && rollout.StatefulSet == workload.Name &&
+		(rollout.Step == databasesv1alpha1.RolloutStepPromoted || rollout.Step == databasesv1alpha1.RolloutStepCanary) {
+		rollout.Step = databasesv1alpha1.RolloutStepCompleted
+		rollout.Message = ""
+	}
+}
+
+// canaryAborted returns the message of an aborted canary rollout
+func canaryAborted(database *databasesv1alpha1.Database) (string, bool) {
+	rollout := database.Status.Rollout
+	if rollout == nil || rollout.Step != databasesv1alpha1.RolloutStepAborted {
+		return "", false
+	}
+	return rollout.Message, true
+}
+
+// statefulSetPartition returns the ordinal from which replicas are updated
+func statefulSetPartition(statefulSet *appsv1.StatefulSet) int32 {
+	if update := statefulSet.Spec.UpdateStrategy.RollingUpdate; update != nil && update.Partition != nil {
+		return *update.Partition
+	}
+	return 0
+}
+
+// podReady reports whether the Ready condition of a pod is true
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
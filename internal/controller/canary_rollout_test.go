@@ -0,0 +1,168 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Canary rollouts", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		c          client.Client
+		now        time.Time
+	)
+
+	key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Now()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:  "16.2",
+				Replicas: ptr.To(int32(3)),
+				Rollout: &databasesv1alpha1.RolloutSpec{
+					Canary:       true,
+					CanaryPeriod: &metav1.Duration{Duration: time.Minute},
+				},
+			},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Scheme: scheme}
+		statefulSet := reconciler.createPostgreSQLStatefulSet(database, 3, reconciler.getPostgreSQLEnv(database))
+		setCredentialsChecksum(&statefulSet.Spec.Template, "0123456789abcdef")
+		Expect(controllerutil.SetControllerReference(database, statefulSet, scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(statefulSet).WithInterceptorFuncs(applyPatches()).Build()
+		reconciler.Client = c
+	})
+
+	current := func() *appsv1.StatefulSet {
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(c.Get(ctx, key, statefulSet)).To(Succeed())
+		return statefulSet
+	}
+
+	// startRollout changes the version and lets the StatefulSet controller
+	// update the canary to the new revision
+	startRollout := func(ready bool) {
+		database.Spec.Version = "16.4"
+		Expect(reconciler.reconcilePostgreSQL(ctx, database)).To(Succeed())
+		Expect(statefulSetPartition(current())).To(Equal(int32(2)))
+		Expect(database.Status.Rollout.Canary).To(Equal("orders-2"))
+		Expect(database.Status.Rollout.Step).To(Equal(databasesv1alpha1.RolloutStepCanary))
+
+		statefulSet := current()
+		statefulSet.Status.ObservedGeneration = statefulSet.Generation
+		statefulSet.Status.CurrentRevision = "orders-old"
+		statefulSet.Status.UpdateRevision = "orders-new"
+		Expect(c.Status().Update(ctx, statefulSet)).To(Succeed())
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		Expect(c.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "orders-2",
+				Namespace: "shop",
+				Labels:    map[string]string{appsv1.ControllerRevisionHashLabelKey: "orders-new"},
+			},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+		})).To(Succeed())
+	}
+
+	It("should update the other replicas once the canary stayed ready and healthy", func() {
+		startRollout(true)
+		Expect(reconciler.reconcileCanary(ctx, database, current(), now)).To(Succeed())
+		Expect(database.Status.Rollout.CanaryReadyAt).NotTo(BeNil())
+		Expect(database.Status.Rollout.Revision).To(Equal("orders-new"))
+
+		database.Status.Health = &databasesv1alpha1.HealthStatus{
+			State:     databasesv1alpha1.HealthStateHealthy,
+			CheckedAt: metav1.NewTime(now.Add(30 * time.Second)),
+		}
+		Expect(reconciler.reconcileCanary(ctx, database, current(), now.Add(30*time.Second))).To(Succeed())
+		Expect(database.Status.Rollout.Step).To(Equal(databasesv1alpha1.RolloutStepCanary))
+		Expect(reconciler.reconcileCanary(ctx, database, current(), now.Add(2*time.Minute))).To(Succeed())
+		Expect(database.Status.Rollout.Step).To(Equal(databasesv1alpha1.RolloutStepPromoted))
+		Expect(statefulSetPartition(current())).To(BeZero())
+
+		By("completing once every pod runs the new template")
+		statefulSet := current()
+		statefulSet.Status.CurrentRevision = "orders-new"
+		Expect(c.Status().Update(ctx, statefulSet)).To(Succeed())
+		Expect(reconciler.reconcilePostgreSQL(ctx, database)).To(Succeed())
+		Expect(database.Status.Rollout.Step).To(Equal(databasesv1alpha1.RolloutStepCompleted))
+		Expect(database.Status.Operations).To(BeNil())
+	})
+
+	It("should abort the rollout when the engine is unhealthy with the canary", func() {
+		startRollout(true)
+		Expect(reconciler.reconcileCanary(ctx, database, current(), now)).To(Succeed())
+		database.Status.Health = &databasesv1alpha1.HealthStatus{
+			State:     databasesv1alpha1.HealthStateUnhealthy,
+			Message:   "connection refused",
+			CheckedAt: metav1.NewTime(now.Add(10 * time.Second)),
+		}
+		Expect(reconciler.reconcileCanary(ctx, database, current(), now.Add(10*time.Second))).To(Succeed())
+
+		Expect(database.Status.Rollout.Step).To(Equal(databasesv1alpha1.RolloutStepAborted))
+		Expect(database.Status.Rollout.Message).To(ContainSubstring("connection refused"))
+		Expect(statefulSetPartition(current())).To(Equal(int32(2)))
+		Expect(database.Status.Operations).To(BeNil())
+		Expect(database.Status.RecentOperations).To(ConsistOf(And(
+			HaveField("Type", recordUpdate),
+			HaveField("Outcome", databasesv1alpha1.OperationFailed),
+		)))
+		reportHealth(database, now)
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionDegraded).Reason).To(Equal(reasonCanaryFailed))
+	})
+
+	It("should abort the rollout when the canary does not become ready", func() {
+		startRollout(false)
+		Expect(reconciler.reconcileCanary(ctx, database, current(), now.Add(time.Minute))).To(Succeed())
+		Expect(database.Status.Rollout.Step).To(Equal(databasesv1alpha1.RolloutStepCanary))
+		Expect(reconciler.reconcileCanary(ctx, database, current(), now.Add(canaryReadyTimeout+time.Minute))).To(Succeed())
+		Expect(database.Status.Rollout.Step).To(Equal(databasesv1alpha1.RolloutStepAborted))
+		Expect(database.Status.Rollout.Message).To(ContainSubstring("did not become ready"))
+	})
+})
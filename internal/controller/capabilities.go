@@ -0,0 +1,273 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	topologyStandalone  = "standalone"
+	topologyReplication = "replication"
+	topologyReplicaSet  = "replicaset"
+	topologySentinel    = "sentinel"
+	topologyCluster     = "cluster"
+
+	backupMethodDump        = string(databasesv1alpha1.BackupMethodDump)
+	backupMethodSnapshot    = string(databasesv1alpha1.BackupMethodSnapshot)
+	backupMethodWAL         = string(databasesv1alpha1.BackupMethodWAL)
+	backupMethodIncremental = string(databasesv1alpha1.BackupMethodIncremental)
+
+	logFormatText = string(databasesv1alpha1.LogFormatText)
+	logFormatJSON = string(databasesv1alpha1.LogFormatJSON)
+)
+
+// EngineCapabilities describes what a database engine supports
+type EngineCapabilities struct {
+	// SupportsSharding reports whether data can be partitioned across nodes
+	SupportsSharding bool
+	// SupportsPITR reports whether point-in-time recovery is possible
+	SupportsPITR bool
+	// SupportsOnlineResize reports whether replicas can be added or removed without downtime
+	SupportsOnlineResize bool
+	// SupportsRuntimeLogLevel reports whether the log level can be changed without a restart
+	SupportsRuntimeLogLevel bool
+	// ServesHTTP reports whether clients speak HTTP to the engine, so ingress can route them
+	ServesHTTP bool
+	// MaxReplicas caps spec.replicas, zero means no engine specific limit
+	MaxReplicas int32
+	// SupportedTopologies lists the deployment topologies of the engine
+	SupportedTopologies []string
+	// SupportedBackupMethods lists the backup methods of the engine
+	SupportedBackupMethods []string
+	// SupportedLogFormats lists the formats the engine writes its logs in
+	SupportedLogFormats []string
+}
+
+var engineCapabilities = map[databasesv1alpha1.DatabaseType]EngineCapabilities{
+	databasesv1alpha1.DatabaseTypePostgreSQL: {
+		SupportsRuntimeLogLevel: true,
+		SupportsPITR:            true,
+		SupportsOnlineResize:    true,
+		SupportedTopologies:     []string{topologyStandalone, topologyReplication},
+		SupportedBackupMethods:  []string{backupMethodDump, backupMethodSnapshot, backupMethodWAL, backupMethodIncremental},
+		SupportedLogFormats:     []string{logFormatText, logFormatJSON},
+	},
+	databasesv1alpha1.DatabaseTypeMongoDB: {
+		SupportsRuntimeLogLevel: true,
+		SupportsSharding:        true,
+		SupportsPITR:            true,
+		SupportsOnlineResize:    true,
+		SupportedTopologies:     []string{topologyStandalone, topologyReplicaSet},
+		SupportedBackupMethods:  []string{backupMethodDump, backupMethodSnapshot},
+		SupportedLogFormats:     []string{logFormatJSON},
+	},
+	databasesv1alpha1.DatabaseTypeRedis: {
+		SupportsRuntimeLogLevel: true,
+		SupportsSharding:        true,
+		SupportsOnlineResize:    true,
+		SupportedTopologies:     []string{topologyStandalone, topologySentinel, topologyCluster},
+		SupportedBackupMethods:  []string{backupMethodDump, backupMethodSnapshot},
+		SupportedLogFormats:     []string{logFormatText},
+	},
+	databasesv1alpha1.DatabaseTypeElasticsearch: {
+		SupportsRuntimeLogLevel: true,
+		ServesHTTP:              true,
+		SupportsSharding:        true,
+		SupportsOnlineResize:    true,
+		SupportedTopologies:     []string{topologyCluster},
+		SupportedBackupMethods:  []string{backupMethodSnapshot},
+		SupportedLogFormats:     []string{logFormatJSON},
+	},
+	databasesv1alpha1.DatabaseTypeSQLite: {
+		ServesHTTP:             true,
+		MaxReplicas:            1,
+		SupportedTopologies:    []string{topologyStandalone},
+		SupportedBackupMethods: []string{backupMethodDump, backupMethodSnapshot},
+	},
+}
+
+// Capabilities returns the capabilities of a database engine
+func Capabilities(dbType databasesv1alpha1.DatabaseType) (EngineCapabilities, bool) {
+	capabilities, ok := engineCapabilities[dbType]
+	return capabilities, ok
+}
+
+// desiredTopology derives the topology requested by the spec
+func desiredTopology(database *databasesv1alpha1.Database) string {
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		if database.Spec.Replicas != nil && *database.Spec.Replicas > 1 {
+			return topologyReplication
+		}
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		if database.Spec.MongoDB != nil && database.Spec.MongoDB.ReplicaSetName != "" {
+			return topologyReplicaSet
+		}
+	case databasesv1alpha1.DatabaseTypeRedis:
+		if database.Spec.Redis != nil && database.Spec.Redis.Mode != "" {
+			return database.Spec.Redis.Mode
+		}
+	case databasesv1alpha1.DatabaseTypeElasticsearch:
+		return topologyCluster
+	}
+	return topologyStandalone
+}
+
+// validateSpec checks the spec against the capabilities of its engine
+func (r *DatabaseReconciler) validateSpec(database *databasesv1alpha1.Database) error {
+	capabilities, ok := Capabilities(database.Spec.Type)
+	if !ok {
+		return fmt.Errorf("unsupported database type: %s", database.Spec.Type)
+	}
+
+	if capabilities.MaxReplicas > 0 && database.Spec.Replicas != nil && *database.Spec.Replicas > capabilities.MaxReplicas {
+		return fmt.Errorf("%s supports at most %d replicas, got %d",
+			database.Spec.Type, capabilities.MaxReplicas, *database.Spec.Replicas)
+	}
+
+	if database.Spec.TargetCluster != nil && !r.Fleet {
+		return fmt.Errorf("targetCluster requires the operator to run in fleet mode")
+	}
+	if err := validateDeletionPolicy(database); err != nil {
+		return err
+	}
+
+	if err := validateVersionUpgrade(database); err != nil {
+		return err
+	}
+
+	if err := validateTopology(database, capabilities.MaxReplicas); err != nil {
+		return err
+	}
+	if err := validateMaintenance(database); err != nil {
+		return err
+	}
+	if err := validateNodeSets(database); err != nil {
+		return err
+	}
+
+	topology := desiredTopology(database)
+	if !slices.Contains(capabilities.SupportedTopologies, topology) {
+		return fmt.Errorf("%s does not support the %s topology (supported: %s)",
+			database.Spec.Type, topology, strings.Join(capabilities.SupportedTopologies, ", "))
+	}
+
+	if engineLogLevelRequested(database) && !capabilities.SupportsRuntimeLogLevel {
+		return fmt.Errorf("%s does not support runtime engine log levels", database.Spec.Type)
+	}
+	if err := validateLogging(database, capabilities); err != nil {
+		return err
+	}
+	if err := validateSlowQuery(database); err != nil {
+		return err
+	}
+
+	if backup := database.Spec.Backup; backup != nil && backup.Enabled {
+		method := backupMethod(database)
+		if !slices.Contains(capabilities.SupportedBackupMethods, string(method)) {
+			return fmt.Errorf("%s does not support %s backups", database.Spec.Type, method)
+		}
+		switch method {
+		case databasesv1alpha1.BackupMethodWAL:
+			if err := validateWALArchiving(database); err != nil {
+				return err
+			}
+		case databasesv1alpha1.BackupMethodSnapshot:
+			if err := validateSnapshotBackups(database); err != nil {
+				return err
+			}
+		case databasesv1alpha1.BackupMethodIncremental:
+			if err := validatePgBackRest(database); err != nil {
+				return err
+			}
+		}
+		if err := validateBackupStorage(database); err != nil {
+			return err
+		}
+		if err := validateBackupCopies(backup.Copies, method); err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+		if err := validateBackupSchedules(database, capabilities); err != nil {
+			return err
+		}
+	}
+
+	if err := validateBootstrap(database); err != nil {
+		return err
+	}
+
+	if database.Spec.Storage != nil {
+		if _, err := resource.ParseQuantity(database.Spec.Storage.Size); err != nil {
+			return fmt.Errorf("invalid storage size %q: %w", database.Spec.Storage.Size, err)
+		}
+	}
+
+	if err := validateDiskPressure(database); err != nil {
+		return err
+	}
+
+	if err := validateRotation(database); err != nil {
+		return err
+	}
+
+	if err := validateTLS(database); err != nil {
+		return err
+	}
+
+	if err := validateResourceMetadata(database); err != nil {
+		return err
+	}
+
+	if networking := database.Spec.Networking; networking != nil && networking.Ingress != nil && !capabilities.ServesHTTP {
+		return fmt.Errorf("%s does not speak HTTP, networking.ingress requires %s or %s",
+			database.Spec.Type, databasesv1alpha1.DatabaseTypeElasticsearch, databasesv1alpha1.DatabaseTypeSQLite)
+	}
+
+	if err := validateImageFlavor(database); err != nil {
+		return err
+	}
+
+	if database.Spec.PodSecurity == databasesv1alpha1.PodSecurityRestricted && engineLayout(database).user == 0 {
+		return fmt.Errorf("the %s %s image starts as root and requires the %s podSecurity level",
+			imageFlavor(database), database.Spec.Type, databasesv1alpha1.PodSecurityBaseline)
+	}
+
+	if res := database.Spec.Resources; res != nil {
+		for _, field := range []struct{ name, value string }{
+			{"cpu", res.CPU},
+			{"memory", res.Memory},
+			{"cpuLimit", res.CPULimit},
+			{"memoryLimit", res.MemoryLimit},
+		} {
+			if field.value == "" {
+				continue
+			}
+			if _, err := resource.ParseQuantity(field.value); err != nil {
+				return fmt.Errorf("invalid resources.%s %q: %w", field.name, field.value, err)
+			}
+		}
+	}
+
+	return nil
+}
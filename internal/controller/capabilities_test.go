@@ -0,0 +1,133 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Engine capabilities", func() {
+	reconciler := &DatabaseReconciler{}
+
+	newDatabase := func(dbType databasesv1alpha1.DatabaseType, replicas int32) *databasesv1alpha1.Database {
+		return &databasesv1alpha1.Database{
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     dbType,
+				Version:  "latest",
+				Replicas: &replicas,
+			},
+		}
+	}
+
+	It("should declare capabilities for every database type", func() {
+		for _, dbType := range []databasesv1alpha1.DatabaseType{
+			databasesv1alpha1.DatabaseTypePostgreSQL,
+			databasesv1alpha1.DatabaseTypeMongoDB,
+			databasesv1alpha1.DatabaseTypeRedis,
+			databasesv1alpha1.DatabaseTypeElasticsearch,
+			databasesv1alpha1.DatabaseTypeSQLite,
+		} {
+			capabilities, ok := Capabilities(dbType)
+			Expect(ok).To(BeTrue(), string(dbType))
+			Expect(capabilities.SupportedTopologies).NotTo(BeEmpty(), string(dbType))
+		}
+	})
+
+	It("should reject more replicas than the engine supports", func() {
+		Expect(reconciler.validateSpec(newDatabase(databasesv1alpha1.DatabaseTypeSQLite, 1))).To(Succeed())
+		Expect(reconciler.validateSpec(newDatabase(databasesv1alpha1.DatabaseTypeSQLite, 2))).
+			To(MatchError(ContainSubstring("at most 1 replicas")))
+	})
+
+	It("should validate the requested topology", func() {
+		database := newDatabase(databasesv1alpha1.DatabaseTypeRedis, 3)
+		database.Spec.Redis = &databasesv1alpha1.RedisConfig{Mode: "sentinel"}
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+
+		database.Spec.Redis.Mode = "replicaset"
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("does not support the replicaset topology")))
+	})
+
+	It("should reject unparsable quantities instead of panicking", func() {
+		database := newDatabase(databasesv1alpha1.DatabaseTypePostgreSQL, 1)
+		database.Spec.Storage = &databasesv1alpha1.StorageSpec{Size: "ten gigs"}
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("invalid storage size")))
+	})
+
+	It("should hold its invariants for arbitrary specs", func() {
+		random := rand.New(rand.NewPCG(2025, 3))
+		pick := func(values ...string) string { return values[random.IntN(len(values))] }
+		for range 2000 {
+			database := &databasesv1alpha1.Database{
+				ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+				Spec: databasesv1alpha1.DatabaseSpec{
+					Type: databasesv1alpha1.DatabaseType(pick("PostgreSQL", "MongoDB", "Redis", "Elasticsearch", "SQLite", "Oracle")),
+					Version: pick("16", "16.4", "15", "7.0", "8.0", "4.4.18", "8.11.0", "7.17.18", "6.8",
+						"7.2-alpine", "latest", "8.0.0-rc1", "08.010"),
+				},
+				Status: databasesv1alpha1.DatabaseStatus{Version: pick("", "16", "6.0", "7.0", "7.10.2", "8.15.0", "latest")},
+			}
+			if random.IntN(4) > 0 {
+				replicas := random.Int32N(7)
+				database.Spec.Replicas = &replicas
+			}
+			if random.IntN(3) == 0 {
+				database.Spec.Redis = &databasesv1alpha1.RedisConfig{Mode: pick("", "sentinel", "cluster", "replicaset")}
+			}
+			if random.IntN(3) == 0 {
+				database.Spec.Storage = &databasesv1alpha1.StorageSpec{Size: pick("", "1Gi", "500M", "ten gigs")}
+			}
+			if random.IntN(3) == 0 {
+				database.Spec.RotationPolicy = &databasesv1alpha1.RotationPolicy{Schedule: pick("@monthly", "0 3 1 * *", "monthly")}
+			}
+			original := database.DeepCopy()
+
+			err := reconciler.validateSpec(database)
+			Expect(database).To(Equal(original), "validateSpec modified %+v", original.Spec)
+			Expect(fmt.Sprint(reconciler.validateSpec(database))).To(Equal(fmt.Sprint(err)), "validateSpec is not deterministic for %+v", original.Spec)
+
+			capabilities, ok := Capabilities(database.Spec.Type)
+			if !ok {
+				Expect(err).To(MatchError(ContainSubstring("unsupported database type")))
+				continue
+			}
+			if capabilities.MaxReplicas > 0 && database.Spec.Replicas != nil && *database.Spec.Replicas > capabilities.MaxReplicas {
+				Expect(err).To(MatchError(ContainSubstring("replicas")), "%+v", original.Spec)
+			}
+
+			// A valid spec stays valid once the Database reconciled its version,
+			// and keeping the version is never rejected as an upgrade
+			reconciled := database.DeepCopy()
+			reconciled.Status.Version = reconciled.Spec.Version
+			reconciledErr := reconciler.validateSpec(reconciled)
+			if err == nil {
+				Expect(reconciledErr).NotTo(HaveOccurred(), "%+v", original.Spec)
+			}
+			if reconciledErr != nil {
+				Expect(reconciledErr.Error()).NotTo(Or(ContainSubstring("upgraded"), ContainSubstring("downgraded")))
+			}
+		}
+	})
+})
@@ -0,0 +1,160 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// engineBusyRequeue is how long a Database waits when every worker of its
+// engine is busy
+const engineBusyRequeue = 5 * time.Second
+
+// Concurrency configures the workers and the rate limiting of the Database
+// controller. Zero values keep the controller-runtime defaults.
+type Concurrency struct {
+	// MaxConcurrentReconciles is the number of Databases reconciled at once
+	MaxConcurrentReconciles int
+	// EngineWorkers caps the workers reconciling Databases of an engine, so
+	// slow reconciles of one engine do not hold every worker. Engines without a
+	// cap may use all of them.
+	EngineWorkers map[databasesv1alpha1.DatabaseType]int
+	// BaseDelay and MaxDelay bound the exponential backoff of failed reconciles
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// QPS and Burst limit the rate of reconciles over all Databases
+	QPS   float64
+	Burst int
+}
+
+// controllerOptions returns the options of the Database controller
+func (c Concurrency) controllerOptions() controller.Options {
+	options := controller.Options{MaxConcurrentReconciles: c.MaxConcurrentReconciles}
+	if c.BaseDelay == 0 && c.MaxDelay == 0 && c.QPS == 0 && c.Burst == 0 {
+		return options
+	}
+
+	// The defaults of workqueue.DefaultTypedControllerRateLimiter
+	baseDelay, maxDelay, qps, burst := 5*time.Millisecond, 1000*time.Second, 10.0, 100
+	if c.BaseDelay > 0 {
+		baseDelay = c.BaseDelay
+	}
+	if c.MaxDelay > 0 {
+		maxDelay = c.MaxDelay
+	}
+	if c.QPS > 0 {
+		qps = c.QPS
+	}
+	if c.Burst > 0 {
+		burst = c.Burst
+	}
+	options.RateLimiter = workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](baseDelay, maxDelay),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+	return options
+}
+
+// ParseEngineWorkers parses per-engine worker caps like
+// "PostgreSQL=4,Elasticsearch=2". Engine names are case insensitive.
+func ParseEngineWorkers(value string) (map[databasesv1alpha1.DatabaseType]int, error) {
+	workers := map[databasesv1alpha1.DatabaseType]int{}
+	if strings.TrimSpace(value) == "" {
+		return workers, nil
+	}
+	engines := []databasesv1alpha1.DatabaseType{
+		databasesv1alpha1.DatabaseTypePostgreSQL,
+		databasesv1alpha1.DatabaseTypeMongoDB,
+		databasesv1alpha1.DatabaseTypeRedis,
+		databasesv1alpha1.DatabaseTypeElasticsearch,
+		databasesv1alpha1.DatabaseTypeSQLite,
+	}
+	for _, entry := range strings.Split(value, ",") {
+		name, count, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("engine workers %q are not of the form engine=count", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("engine workers of %s must be a positive number, got %q", name, count)
+		}
+		found := false
+		for _, engine := range engines {
+			if strings.EqualFold(string(engine), strings.TrimSpace(name)) {
+				workers[engine] = n
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown engine %q", name)
+		}
+	}
+	return workers, nil
+}
+
+// engineWorkers hands out the workers of the engines with a cap
+type engineWorkers struct {
+	mu    sync.Mutex
+	caps  map[databasesv1alpha1.DatabaseType]int
+	inUse map[databasesv1alpha1.DatabaseType]int
+}
+
+func newEngineWorkers(caps map[databasesv1alpha1.DatabaseType]int) *engineWorkers {
+	return &engineWorkers{caps: caps, inUse: map[databasesv1alpha1.DatabaseType]int{}}
+}
+
+// acquire takes a worker of an engine. It returns false, without waiting,
+// when every worker of the engine is busy.
+func (w *engineWorkers) acquire(engine databasesv1alpha1.DatabaseType) bool {
+	if w == nil {
+		return true
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	limit, ok := w.caps[engine]
+	if !ok {
+		return true
+	}
+	if w.inUse[engine] >= limit {
+		return false
+	}
+	w.inUse[engine]++
+	return true
+}
+
+// release returns a worker taken by acquire
+func (w *engineWorkers) release(engine databasesv1alpha1.DatabaseType) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.inUse[engine] > 0 {
+		w.inUse[engine]--
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Concurrency", func() {
+	It("should parse the worker caps of the engines", func() {
+		workers, err := ParseEngineWorkers("postgresql=4, Elasticsearch=2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workers).To(Equal(map[databasesv1alpha1.DatabaseType]int{
+			databasesv1alpha1.DatabaseTypePostgreSQL:    4,
+			databasesv1alpha1.DatabaseTypeElasticsearch: 2,
+		}))
+
+		_, err = ParseEngineWorkers("Cassandra=1")
+		Expect(err).To(MatchError(ContainSubstring("unknown engine")))
+		_, err = ParseEngineWorkers("Redis=0")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseEngineWorkers("Redis")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should only cap the engines with a worker cap", func() {
+		workers := newEngineWorkers(map[databasesv1alpha1.DatabaseType]int{databasesv1alpha1.DatabaseTypeRedis: 1})
+		Expect(workers.acquire(databasesv1alpha1.DatabaseTypeRedis)).To(BeTrue())
+		Expect(workers.acquire(databasesv1alpha1.DatabaseTypeRedis)).To(BeFalse())
+		Expect(workers.acquire(databasesv1alpha1.DatabaseTypeMongoDB)).To(BeTrue())
+		Expect(workers.acquire(databasesv1alpha1.DatabaseTypeMongoDB)).To(BeTrue())
+
+		workers.release(databasesv1alpha1.DatabaseTypeRedis)
+		Expect(workers.acquire(databasesv1alpha1.DatabaseTypeRedis)).To(BeTrue())
+	})
+
+	It("should keep the controller-runtime rate limiter unless configured", func() {
+		Expect(Concurrency{MaxConcurrentReconciles: 8}.controllerOptions().RateLimiter).To(BeNil())
+
+		limiter := Concurrency{BaseDelay: time.Second, MaxDelay: time.Minute}.controllerOptions().RateLimiter
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "orders", Namespace: "shop"}}
+		Expect(limiter.When(request)).To(Equal(time.Second))
+		Expect(limiter.When(request)).To(Equal(2 * time.Second))
+	})
+
+	It("should requeue a Database while every worker of its engine is busy", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeRedis},
+		}
+		reconciler := &DatabaseReconciler{
+			Client:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(database).Build(),
+			Scheme:  scheme,
+			workers: newEngineWorkers(map[databasesv1alpha1.DatabaseType]int{databasesv1alpha1.DatabaseTypeRedis: 1}),
+		}
+		Expect(reconciler.workers.acquire(databasesv1alpha1.DatabaseTypeRedis)).To(BeTrue())
+
+		result, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(database)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(engineBusyRequeue))
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(database), database)).To(Succeed())
+		Expect(database.Finalizers).To(BeEmpty())
+	})
+})
@@ -0,0 +1,129 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// conditionReady reports whether clients can use the Database
+	conditionReady = "Ready"
+	// conditionProgressing reports whether the workload is moving towards the spec
+	conditionProgressing = "Progressing"
+	// conditionDegraded reports a Database running below its desired state
+	conditionDegraded = "Degraded"
+	// conditionBackupSucceeded reports the outcome of the latest backups
+	conditionBackupSucceeded = "BackupSucceeded"
+)
+
+// Reasons of the Ready, Progressing, Degraded and BackupSucceeded conditions
+const (
+	reasonDatabaseReady       = "DatabaseReady"
+	reasonHealthCheckFailed   = "HealthCheckFailed"
+	reasonRestoring           = "Restoring"
+	reasonUpgrading           = "Upgrading"
+	reasonMigrating           = "Migrating"
+	reasonProvisioning        = "Provisioning"
+	reasonOperationRunning    = "OperationRunning"
+	reasonReplicasStarting    = "ReplicasStarting"
+	reasonScalingDown         = "ScalingDown"
+	reasonReconciled          = "Reconciled"
+	reasonReplicasUnavailable = "ReplicasUnavailable"
+	reasonAsExpected          = "AsExpected"
+	reasonBackupCompleted     = "BackupCompleted"
+	reasonBackupFailed        = "BackupFailed"
+	reasonNoBackupYet         = "NoBackupYet"
+)
+
+// degradedAfter is how long replicas may be starting before the Database is
+// reported Degraded
+const degradedAfter = 5 * time.Minute
+
+// setCondition sets a condition observed at the current generation.
+// LastTransitionTime only moves when the status changes; reason and message
+// are updated in place.
+func setCondition(database *databasesv1alpha1.Database, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+}
+
+// reportHealth sets the Progressing, Degraded and BackupSucceeded conditions
+// from the reconciled status
+func reportHealth(database *databasesv1alpha1.Database, now time.Time) {
+	desired, ready := desiredReplicas(database), database.Status.ReadyReplicas
+	provisioned := database.Status.Provisioning != nil && database.Status.Provisioning.Completed
+
+	switch operation := activeOperation(database); {
+	case !provisioned:
+		setCondition(database, conditionProgressing, metav1.ConditionTrue, reasonProvisioning,
+			"Creating the configuration, Service and workload")
+	case operation != "":
+		setCondition(database, conditionProgressing, metav1.ConditionTrue, reasonOperationRunning,
+			fmt.Sprintf("Operation %s is running", operation))
+	case ready < desired:
+		setCondition(database, conditionProgressing, metav1.ConditionTrue, reasonReplicasStarting,
+			fmt.Sprintf("%d of %d replicas are ready", ready, desired))
+	case ready > desired:
+		setCondition(database, conditionProgressing, metav1.ConditionTrue, reasonScalingDown,
+			fmt.Sprintf("Scaling down from %d to %d replicas", ready, desired))
+	default:
+		setCondition(database, conditionProgressing, metav1.ConditionFalse, reasonReconciled,
+			fmt.Sprintf("%d replicas are ready", ready))
+	}
+
+	// Replicas starting are only a degradation once they had time to start;
+	// operations stopping the workload are expected
+	progressing := meta.FindStatusCondition(database.Status.Conditions, conditionProgressing)
+	canaryMessage, canaryFailed := canaryAborted(database)
+	switch pressure := meta.FindStatusCondition(database.Status.Conditions, conditionDiskPressure); {
+	case pressure != nil && pressure.Status == metav1.ConditionTrue:
+		setCondition(database, conditionDegraded, metav1.ConditionTrue, conditionDiskPressure, pressure.Message)
+	case canaryFailed:
+		setCondition(database, conditionDegraded, metav1.ConditionTrue, reasonCanaryFailed, canaryMessage)
+	case progressing.Reason == reasonReplicasStarting && now.Sub(progressing.LastTransitionTime.Time) > degradedAfter:
+		setCondition(database, conditionDegraded, metav1.ConditionTrue, reasonReplicasUnavailable,
+			fmt.Sprintf("%s for more than %s", progressing.Message, degradedAfter))
+	default:
+		setCondition(database, conditionDegraded, metav1.ConditionFalse, reasonAsExpected, "The Database runs as desired")
+	}
+
+	backups := database.Status.Backups
+	switch {
+	case backups == nil:
+		meta.RemoveStatusCondition(&database.Status.Conditions, conditionBackupSucceeded)
+	case backups.FailureCount > 0:
+		setCondition(database, conditionBackupSucceeded, metav1.ConditionFalse, reasonBackupFailed,
+			fmt.Sprintf("%d backups failed since the last successful one", backups.FailureCount))
+	case backups.LastSuccessfulBackup != nil:
+		setCondition(database, conditionBackupSucceeded, metav1.ConditionTrue, reasonBackupCompleted,
+			fmt.Sprintf("The last backup succeeded at %s", backups.LastSuccessfulBackup.UTC().Format(time.RFC3339)))
+	default:
+		setCondition(database, conditionBackupSucceeded, metav1.ConditionUnknown, reasonNoBackupYet, "No backup has run yet")
+	}
+}
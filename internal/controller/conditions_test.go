@@ -0,0 +1,116 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Status conditions", func() {
+	var (
+		database *databasesv1alpha1.Database
+		now      time.Time
+	)
+
+	condition := func(conditionType string) *metav1.Condition {
+		return meta.FindStatusCondition(database.Status.Conditions, conditionType)
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", Generation: 3},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:  "16",
+				Replicas: ptr.To[int32](3),
+			},
+		}
+	})
+
+	It("should update reason and message without moving the transition time", func() {
+		setCondition(database, conditionReady, metav1.ConditionFalse, "ReconciliationFailed", "first")
+		transition := condition(conditionReady).LastTransitionTime
+
+		database.Generation = 4
+		setCondition(database, conditionReady, metav1.ConditionFalse, conditionProvisioningFailed, "second")
+		Expect(condition(conditionReady).Reason).To(Equal(conditionProvisioningFailed))
+		Expect(condition(conditionReady).Message).To(Equal("second"))
+		Expect(condition(conditionReady).ObservedGeneration).To(BeEquivalentTo(4))
+		Expect(condition(conditionReady).LastTransitionTime).To(Equal(transition))
+	})
+
+	It("should report Progressing and Degraded from the replicas", func() {
+		reportHealth(database, now)
+		Expect(condition(conditionProgressing).Reason).To(Equal(reasonProvisioning))
+		Expect(condition(conditionDegraded).Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition(conditionBackupSucceeded)).To(BeNil())
+
+		database.Status.Provisioning = &databasesv1alpha1.ProvisioningStatus{Completed: true}
+		database.Status.ReadyReplicas = 1
+		reportHealth(database, now)
+		Expect(condition(conditionProgressing).Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition(conditionProgressing).Reason).To(Equal(reasonReplicasStarting))
+		Expect(condition(conditionProgressing).Message).To(Equal("1 of 3 replicas are ready"))
+		Expect(condition(conditionDegraded).Status).To(Equal(metav1.ConditionFalse))
+
+		// Replicas still missing after degradedAfter
+		condition(conditionProgressing).LastTransitionTime = metav1.NewTime(now.Add(-degradedAfter - time.Second))
+		reportHealth(database, now)
+		Expect(condition(conditionDegraded).Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition(conditionDegraded).Reason).To(Equal(reasonReplicasUnavailable))
+
+		database.Status.ReadyReplicas = 3
+		reportHealth(database, now)
+		Expect(condition(conditionProgressing).Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition(conditionProgressing).Reason).To(Equal(reasonReconciled))
+		Expect(condition(conditionDegraded).Reason).To(Equal(reasonAsExpected))
+
+		setCondition(database, conditionDiskPressure, metav1.ConditionTrue, "VolumeFull", "data-orders-0 is 97% full")
+		reportHealth(database, now)
+		Expect(condition(conditionDegraded).Reason).To(Equal(conditionDiskPressure))
+		Expect(condition(conditionDegraded).Message).To(Equal("data-orders-0 is 97% full"))
+	})
+
+	It("should report the outcome of the latest backups", func() {
+		database.Status.Backups = &databasesv1alpha1.BackupStatus{}
+		reportHealth(database, now)
+		Expect(condition(conditionBackupSucceeded).Status).To(Equal(metav1.ConditionUnknown))
+
+		database.Status.Backups.LastSuccessfulBackup = ptr.To(metav1.NewTime(now))
+		reportHealth(database, now)
+		Expect(condition(conditionBackupSucceeded).Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition(conditionBackupSucceeded).Message).To(Equal("The last backup succeeded at 2025-03-01T12:00:00Z"))
+
+		database.Status.Backups.FailureCount = 2
+		reportHealth(database, now)
+		Expect(condition(conditionBackupSucceeded).Reason).To(Equal(reasonBackupFailed))
+
+		database.Status.Backups = nil
+		reportHealth(database, now)
+		Expect(condition(conditionBackupSucceeded)).To(BeNil())
+	})
+})
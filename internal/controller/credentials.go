@@ -0,0 +1,228 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=create
+
+const (
+	credentialsComponent    = "credentials"
+	credentialsSuffix       = "-" + credentialsComponent
+	credentialsUsernameKey  = "username"
+	credentialsPasswordKey  = "password"
+	generatedPasswordLength = 32
+
+	// Letters and digits only, so passwords need no escaping in connection URIs
+	passwordAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// legacyPasswords are the passwords Databases without passwordSecret were
+// provisioned with before credentials were generated. Their data volumes are
+// initialized with them, so they are kept for the workloads that already exist.
+var legacyPasswords = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: "postgres",
+	databasesv1alpha1.DatabaseTypeMongoDB:    "password",
+}
+
+// randomPassword returns a password drawn from crypto/rand
+func randomPassword(length int) (string, error) {
+	password := make([]byte, length)
+	size := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// passwordSecret returns the Secret key holding the password of the
+// administrative user: spec passwordSecret or, for the engines that always have
+// a password, the generated Secret. It is nil for engines without a password.
+func passwordSecret(database *databasesv1alpha1.Database) *databasesv1alpha1.SecretReference {
+	generated := &databasesv1alpha1.SecretReference{Name: database.Name + credentialsSuffix, Key: credentialsPasswordKey}
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		if database.Spec.PostgreSQL != nil && database.Spec.PostgreSQL.PasswordSecret != nil {
+			return database.Spec.PostgreSQL.PasswordSecret
+		}
+		return generated
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		if database.Spec.MongoDB != nil && database.Spec.MongoDB.PasswordSecret != nil {
+			return database.Spec.MongoDB.PasswordSecret
+		}
+		return generated
+	case databasesv1alpha1.DatabaseTypeRedis:
+		if database.Spec.Redis != nil {
+			return database.Spec.Redis.PasswordSecret
+		}
+	}
+	return nil
+}
+
+// passwordEnv reads a password from a Secret key
+func passwordEnv(name string, secret *databasesv1alpha1.SecretReference) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+				Key:                  secret.Key,
+			},
+		},
+	}
+}
+
+// reconcileCredentials creates the Secret of the generated password when the
+// spec has no passwordSecret, and reports the Secret in status. An existing
+// Secret is never overwritten. The Secret is not owned by the Database: the data
+// volumes initialized with the password outlive the Database, and so must it.
+func (r *DatabaseReconciler) reconcileCredentials(ctx context.Context, database *databasesv1alpha1.Database) error {
+	reference := passwordSecret(database)
+	if reference == nil {
+		database.Status.CredentialsSecret = ""
+		return nil
+	}
+	if reference.Name != database.Name+credentialsSuffix {
+		database.Status.CredentialsSecret = reference.Name
+		return nil
+	}
+
+	secret, err := r.ensureGeneratedSecret(ctx, database, reference.Name, credentialsComponent, adminUsername(database), false,
+		func() (string, error) { return r.initialPassword(ctx, database) })
+	if err != nil {
+		return err
+	}
+	// Secrets generated by earlier versions were owned by the Database
+	if metav1.IsControlledBy(secret, database) {
+		if err := controllerutil.RemoveControllerReference(database, secret, r.Scheme); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("Releasing credentials Secret from the Database", "name", secret.Name)
+		if err := r.Update(ctx, secret); err != nil {
+			return err
+		}
+	}
+	database.Status.CredentialsSecret = secret.Name
+	return nil
+}
+
+// ensureGeneratedSecret creates a Secret holding a username and a password
+// unless it exists, owned by the Database if owned is set. An existing Secret is
+// never overwritten, it must only hold a password.
+func (r *DatabaseReconciler) ensureGeneratedSecret(ctx context.Context, database *databasesv1alpha1.Database, name, component, username string, owned bool, password func() (string, error)) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, secret)
+	if err == nil {
+		if _, ok := secret.Data[credentialsPasswordKey]; !ok {
+			return nil, fmt.Errorf("secret %s has no %q key", secret.Name, credentialsPasswordKey)
+		}
+		return secret, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	value, err := password()
+	if err != nil {
+		return nil, err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: database.Namespace,
+			Labels:    r.getComponentLabels(database, component),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			credentialsUsernameKey: []byte(username),
+			credentialsPasswordKey: []byte(value),
+		},
+	}
+	if owned {
+		if err := controllerutil.SetControllerReference(database, secret, r.Scheme); err != nil {
+			return nil, err
+		}
+	}
+
+	log.FromContext(ctx).Info("Creating credentials Secret", "name", secret.Name)
+	return secret, r.Create(ctx, secret)
+}
+
+// initialPassword generates the password of a new Database. Workloads created
+// before passwords were generated keep their legacy password, which the
+// Secret then records. A data volume left by an earlier Database of the same name
+// was initialized with a password that cannot be generated again, so it must be
+// given back in the Secret.
+func (r *DatabaseReconciler) initialPassword(ctx context.Context, database *databasesv1alpha1.Database) (string, error) {
+	err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, &appsv1.StatefulSet{})
+	if err == nil {
+		log.FromContext(ctx).Info("Recording the legacy password of an existing Database, rotate it to a generated one",
+			"secret", database.Name+credentialsSuffix)
+		return legacyPasswords[database.Spec.Type], nil
+	}
+	if !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	claim := "data-" + database.Name + "-0"
+	err = r.Get(ctx, types.NamespacedName{Name: claim, Namespace: database.Namespace}, &corev1.PersistentVolumeClaim{})
+	if err == nil {
+		return "", fmt.Errorf("data volume %s exists without Secret %s, create the Secret with the %q key "+
+			"holding the password the volume was initialized with, or delete the volume", claim,
+			database.Name+credentialsSuffix, credentialsPasswordKey)
+	}
+	if !errors.IsNotFound(err) {
+		return "", err
+	}
+	return randomPassword(generatedPasswordLength)
+}
+
+// adminUsername returns the name of the administrative user of the engine
+func adminUsername(database *databasesv1alpha1.Database) string {
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		if database.Spec.PostgreSQL != nil && database.Spec.PostgreSQL.Username != "" {
+			return database.Spec.PostgreSQL.Username
+		}
+		return "postgres"
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		if database.Spec.MongoDB != nil && database.Spec.MongoDB.Username != "" {
+			return database.Spec.MongoDB.Username
+		}
+		return "root"
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Credentials", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *DatabaseReconciler
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "shop"}
+	}
+
+	newDatabase := func(name string, engine databasesv1alpha1.DatabaseType) *databasesv1alpha1.Database {
+		return &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", UID: types.UID(name + "-uid")},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: engine, Version: "16"},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "catalog", Namespace: "shop"}}).
+			Build()
+		reconciler = &DatabaseReconciler{Client: c, Scheme: scheme}
+	})
+
+	It("should generate a random password once and never overwrite it", func() {
+		database := newDatabase("orders", databasesv1alpha1.DatabaseTypePostgreSQL)
+		database.Spec.PostgreSQL = &databasesv1alpha1.PostgreSQLConfig{Username: "app"}
+		Expect(reconciler.reconcileCredentials(ctx, database)).To(Succeed())
+		Expect(database.Status.CredentialsSecret).To(Equal("orders-credentials"))
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, key("orders-credentials"), secret)).To(Succeed())
+		Expect(string(secret.Data["username"])).To(Equal("app"))
+		Expect(string(secret.Data["password"])).To(MatchRegexp("^[A-Za-z0-9]{32}$"))
+		Expect(secret.OwnerReferences).To(BeEmpty())
+
+		Expect(reconciler.getPostgreSQLEnv(database)).To(ContainElement(And(
+			HaveField("Name", "POSTGRES_PASSWORD"),
+			HaveField("ValueFrom.SecretKeyRef.Name", "orders-credentials"),
+			HaveField("ValueFrom.SecretKeyRef.Key", "password"))))
+
+		By("keeping the Secret when it exists")
+		secret.Data["password"] = []byte("rotated")
+		Expect(c.Update(ctx, secret)).To(Succeed())
+		Expect(reconciler.reconcileCredentials(ctx, database)).To(Succeed())
+		Expect(c.Get(ctx, key("orders-credentials"), secret)).To(Succeed())
+		Expect(string(secret.Data["password"])).To(Equal("rotated"))
+	})
+
+	It("should record the legacy password of Databases provisioned without a Secret", func() {
+		database := newDatabase("catalog", databasesv1alpha1.DatabaseTypeMongoDB)
+		Expect(reconciler.reconcileCredentials(ctx, database)).To(Succeed())
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, key("catalog-credentials"), secret)).To(Succeed())
+		Expect(secret.Data).To(Equal(map[string][]byte{"username": []byte("root"), "password": []byte("password")}))
+	})
+
+	It("should not generate a password for the data volume of an earlier Database", func() {
+		Expect(c.Create(ctx, &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data-orders-0", Namespace: "shop"},
+		})).To(Succeed())
+		database := newDatabase("orders", databasesv1alpha1.DatabaseTypePostgreSQL)
+		Expect(reconciler.reconcileCredentials(ctx, database)).To(MatchError(ContainSubstring(
+			"data volume data-orders-0 exists without Secret orders-credentials")))
+		Expect(c.Get(ctx, key("orders-credentials"), &corev1.Secret{})).NotTo(Succeed())
+	})
+
+	It("should release the Secrets generated while they were owned by the Database", func() {
+		database := newDatabase("orders", databasesv1alpha1.DatabaseTypePostgreSQL)
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-credentials", Namespace: "shop"},
+			Data:       map[string][]byte{"password": []byte("generated")},
+		}
+		Expect(controllerutil.SetControllerReference(database, secret, reconciler.Scheme)).To(Succeed())
+		Expect(c.Create(ctx, secret)).To(Succeed())
+
+		Expect(reconciler.reconcileCredentials(ctx, database)).To(Succeed())
+		Expect(c.Get(ctx, key("orders-credentials"), secret)).To(Succeed())
+		Expect(secret.OwnerReferences).To(BeEmpty())
+		Expect(string(secret.Data["password"])).To(Equal("generated"))
+	})
+
+	It("should report the Secret of the spec and generate none", func() {
+		database := newDatabase("sessions", databasesv1alpha1.DatabaseTypeRedis)
+		Expect(reconciler.reconcileCredentials(ctx, database)).To(Succeed())
+		Expect(database.Status.CredentialsSecret).To(BeEmpty())
+
+		database.Spec.Redis = &databasesv1alpha1.RedisConfig{PasswordSecret: &databasesv1alpha1.SecretReference{Name: "redis-auth", Key: "password"}}
+		Expect(reconciler.reconcileCredentials(ctx, database)).To(Succeed())
+		Expect(database.Status.CredentialsSecret).To(Equal("redis-auth"))
+
+		secrets := &corev1.SecretList{}
+		Expect(c.List(ctx, secrets)).To(Succeed())
+		Expect(secrets.Items).To(BeEmpty())
+	})
+
+	It("should refuse a Secret without a password", func() {
+		Expect(c.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-credentials", Namespace: "shop"},
+			Data:       map[string][]byte{"token": []byte("abc")},
+		})).To(Succeed())
+		database := newDatabase("orders", databasesv1alpha1.DatabaseTypePostgreSQL)
+		Expect(reconciler.reconcileCredentials(ctx, database)).To(MatchError(`secret orders-credentials has no "password" key`))
+	})
+})
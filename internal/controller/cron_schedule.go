@@ -0,0 +1,219 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros are the predefined schedules accepted by CronJobs
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes the values of one field of a cron schedule
+type cronField struct {
+	min, max int
+	names    []string
+}
+
+var (
+	cronMinutes = cronField{min: 0, max: 59}
+	cronHours   = cronField{min: 0, max: 23}
+	cronDays    = cronField{min: 1, max: 31}
+	cronMonths  = cronField{min: 1, max: 12,
+		names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	// Day 7 is Sunday, like day 0
+	cronWeekdays = cronField{min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// cronSpec is a parsed cron schedule, each field holding a bit per matching value
+type cronSpec struct {
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday record unrestricted fields: when both day fields are
+	// restricted, a time matches if either does
+	anyDay, anyWeekday bool
+}
+
+// parseCronSchedule parses a five-field cron schedule or one of its macros
+func parseCronSchedule(schedule string) (*cronSpec, error) {
+	expr := strings.TrimSpace(schedule)
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron schedule %q: expected 5 fields, got %d", schedule, len(fields))
+	}
+
+	spec := &cronSpec{anyDay: isAnyCronValue(fields[2]), anyWeekday: isAnyCronValue(fields[4])}
+	for _, f := range []struct {
+		value string
+		field cronField
+		bits  *uint64
+	}{
+		{fields[0], cronMinutes, &spec.minutes},
+		{fields[1], cronHours, &spec.hours},
+		{fields[2], cronDays, &spec.days},
+		{fields[3], cronMonths, &spec.months},
+		{fields[4], cronWeekdays, &spec.weekdays},
+	} {
+		bits, err := parseCronField(f.value, f.field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron schedule %q: %w", schedule, err)
+		}
+		*f.bits = bits
+	}
+	if spec.weekdays&(1<<7) != 0 {
+		spec.weekdays |= 1
+	}
+	return spec, nil
+}
+
+func isAnyCronValue(value string) bool {
+	return value == "*" || value == "?"
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+func parseCronField(value string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		var start, end int
+		switch {
+		case isAnyCronValue(rangePart):
+			start, end = field.min, field.max
+		default:
+			low, high, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = field.value(low); err != nil {
+				return 0, err
+			}
+			end = start
+			if isRange {
+				if end, err = field.value(high); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				end = field.max
+			}
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range %q", part)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// value parses a number or name of the field
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q, expected %d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// next returns the first time after t matching the schedule, or the zero time
+// when none does within five years (e.g. 30 February)
+func (s *cronSpec) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// prev returns the last time at or before t matching the schedule, or the zero
+// time when none does within five years
+func (s *cronSpec) prev(t time.Time) time.Time {
+	t = t.Truncate(time.Minute)
+	limit := t.AddDate(-5, 0, 0)
+
+	for t.After(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(-time.Minute)
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(-time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *cronSpec) dayMatches(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// reconcileCronJob creates, updates or deletes the CronJob of a component. It
+// returns the CronJob as found in the cluster, or nil when it does not exist
+// after reconciliation. The CronJobs of a hibernated Database are suspended,
+// they have no engine to connect to.
+func (r *DatabaseReconciler) reconcileCronJob(ctx context.Context, database *databasesv1alpha1.Database, component string, desired *batchv1.CronJob) (*batchv1.CronJob, error) {
+	log := log.FromContext(ctx)
+
+	cronJob := &batchv1.CronJob{}
+	cronJobName := database.Name + "-" + component
+	err := r.Get(ctx, types.NamespacedName{Name: cronJobName, Namespace: database.Namespace}, cronJob)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if desired == nil {
+		if err == nil {
+			log.Info("Deleting CronJob", "name", cronJobName)
+			if err := r.Delete(ctx, cronJob); err != nil && !errors.IsNotFound(err) {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	desired.Spec.Suspend = ptr.To(hibernated(database))
+	if errors.IsNotFound(err) {
+		log.Info("Creating CronJob", "name", cronJobName)
+	} else if equality.Semantic.DeepDerivative(desired.Spec, cronJob.Spec) {
+		return cronJob, nil
+	}
+
+	if err := controllerutil.SetControllerReference(database, desired, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := r.apply(ctx, desired); err != nil {
+		return nil, err
+	}
+	return desired, nil
+}
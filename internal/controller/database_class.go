@@ -0,0 +1,124 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// classNameField indexes Databases by the name of their DatabaseClass
+const classNameField = ".spec.className"
+
+// applyDatabaseClass fills the storage class, profile and backups a Database
+// leaves unset with the defaults of its DatabaseClass. The defaults only apply
+// to the copy in memory: the stored spec keeps following the class. It returns
+// nil when the Database has no class or the class does not exist, which
+// missingReferences reports.
+func applyDatabaseClass(ctx context.Context, c client.Reader, database *databasesv1alpha1.Database) (*databasesv1alpha1.DatabaseClass, error) {
+	if database.Spec.ClassName == "" {
+		return nil, nil
+	}
+	class := &databasesv1alpha1.DatabaseClass{}
+	if err := c.Get(ctx, types.NamespacedName{Name: database.Spec.ClassName}, class); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get DatabaseClass %s: %w", database.Spec.ClassName, err)
+	}
+
+	spec := &database.Spec
+	if class.Spec.StorageClassName != "" && spec.Storage != nil && spec.Storage.StorageClass == nil {
+		spec.Storage.StorageClass = &class.Spec.StorageClassName
+	}
+	if spec.Profile == "" {
+		spec.Profile = class.Spec.DefaultProfile
+	}
+	if spec.Backup == nil && class.Spec.Backup != nil {
+		spec.Backup = class.Spec.Backup.DeepCopy()
+	}
+	return class, nil
+}
+
+// validateDatabaseClass checks that the engine, version and profile of a
+// Database are offered by its DatabaseClass
+func validateDatabaseClass(database *databasesv1alpha1.Database, class *databasesv1alpha1.DatabaseClass) error {
+	if class == nil {
+		return nil
+	}
+	if engines := class.Spec.Engines; len(engines) > 0 {
+		index := slices.IndexFunc(engines, func(engine databasesv1alpha1.DatabaseClassEngine) bool {
+			return engine.Type == database.Spec.Type
+		})
+		if index < 0 {
+			return fmt.Errorf("DatabaseClass %s does not offer %s", class.Name, database.Spec.Type)
+		}
+		if versions := engines[index].Versions; len(versions) > 0 && !slices.ContainsFunc(versions, func(version string) bool {
+			return classVersionMatches(version, database.Spec.Version)
+		}) {
+			return fmt.Errorf("DatabaseClass %s does not offer %s %s (offered: %s)",
+				class.Name, database.Spec.Type, database.Spec.Version, strings.Join(versions, ", "))
+		}
+	}
+	if profiles := class.Spec.Profiles; len(profiles) > 0 && !slices.Contains(profiles, database.Spec.Profile) {
+		if database.Spec.Profile == "" {
+			return fmt.Errorf("DatabaseClass %s requires a profile", class.Name)
+		}
+		return fmt.Errorf("DatabaseClass %s does not offer the %s profile", class.Name, database.Spec.Profile)
+	}
+	return nil
+}
+
+// classVersionMatches reports whether a version of a DatabaseClass allows a
+// version: itself, and the versions it is a prefix of at a dot
+func classVersionMatches(allowed, version string) bool {
+	return version == allowed || strings.HasPrefix(version, allowed+".")
+}
+
+// databaseClassName returns the name of the DatabaseClass of a Database
+func databaseClassName(object client.Object) []string {
+	database, ok := object.(*databasesv1alpha1.Database)
+	if !ok || database.Spec.ClassName == "" {
+		return nil
+	}
+	return []string{database.Spec.ClassName}
+}
+
+// databasesForClass maps a DatabaseClass to the Databases of every namespace
+// that select it, so they pick up its new defaults and offering
+func (r *DatabaseReconciler) databasesForClass(ctx context.Context, class client.Object) []reconcile.Request {
+	databases := &databasesv1alpha1.DatabaseList{}
+	if err := r.List(ctx, databases, client.MatchingFields{classNameField: class.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list the Databases of a DatabaseClass", "class", class.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(databases.Items))
+	for i := range databases.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&databases.Items[i])})
+	}
+	return requests
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Database classes", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		class      *databasesv1alpha1.DatabaseClass
+	)
+	key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:      databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:   "16.4",
+				ClassName: "gold",
+				Replicas:  ptr.To(int32(1)),
+				Storage:   &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+			},
+		}
+		class = &databasesv1alpha1.DatabaseClass{
+			ObjectMeta: metav1.ObjectMeta{Name: "gold"},
+			Spec: databasesv1alpha1.DatabaseClassSpec{
+				Engines: []databasesv1alpha1.DatabaseClassEngine{
+					{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Versions: []string{"15", "16"}},
+				},
+				StorageClassName: "fast",
+				Profiles:         []databasesv1alpha1.DatabaseProfile{databasesv1alpha1.DatabaseProfileSmall, databasesv1alpha1.DatabaseProfileProd},
+				DefaultProfile:   databasesv1alpha1.DatabaseProfileSmall,
+			},
+		}
+	})
+
+	build := func(objects ...runtime.Object) {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Scheme: scheme, Recorder: record.NewFakeRecorder(20)}
+		reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}, &appsv1.StatefulSet{}).
+			WithInterceptorFuncs(applyPatches()).
+			WithRuntimeObjects(objects...).Build()
+	}
+
+	reconcile := func() {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, key, database)).To(Succeed())
+	}
+
+	It("should fill in the defaults of the class without writing them to the spec", func() {
+		build(database, class)
+		reconcile()
+		reconcile()
+
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(reconciler.Get(ctx, key, statefulSet)).To(Succeed())
+		Expect(*statefulSet.Spec.VolumeClaimTemplates[0].Spec.StorageClassName).To(Equal("fast"))
+		Expect(statefulSet.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()).To(Equal("2Gi"))
+		Expect(database.Spec.Storage.StorageClass).To(BeNil())
+		Expect(database.Spec.Profile).To(BeEmpty())
+
+		By("rejecting a version the class no longer offers")
+		class.Spec.Engines[0].Versions = []string{"15", "17"}
+		Expect(reconciler.Update(ctx, class)).To(Succeed())
+		reconcile()
+		Expect(database.Status.Phase).To(Equal(databasesv1alpha1.DatabasePhaseFailed))
+		Expect(database.Status.Message).To(Equal("DatabaseClass gold does not offer PostgreSQL 16.4 (offered: 15, 17)"))
+	})
+
+	It("should reject the engines and profiles the class does not offer", func() {
+		database.Spec.Profile = databasesv1alpha1.DatabaseProfileDev
+		Expect(validateDatabaseClass(database, class)).To(MatchError("DatabaseClass gold does not offer the dev profile"))
+		database.Spec.Profile = ""
+		Expect(validateDatabaseClass(database, class)).To(MatchError("DatabaseClass gold requires a profile"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		Expect(validateDatabaseClass(database, class)).To(MatchError("DatabaseClass gold does not offer Redis"))
+
+		// 1 is not a prefix of 16 at a dot
+		Expect(classVersionMatches("1", "16")).To(BeFalse())
+		Expect(classVersionMatches("16", "16.4")).To(BeTrue())
+	})
+
+	It("should wait for a missing class", func() {
+		build(database)
+		reconcile()
+		reconcile()
+		Expect(database.Status.Phase).To(Equal(databasesv1alpha1.DatabasePhasePending))
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionMissingReference)).
+			To(HaveField("Message", `Missing DatabaseClass "gold" (spec.className)`))
+	})
+})
@@ -18,21 +18,31 @@ package controller
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
@@ -40,41 +50,123 @@ import (
 
 const (
 	databaseFinalizer = "databases.database-operator.io/finalizer"
+	// headlessServiceSuffix names the Service giving replicas their DNS names
+	headlessServiceSuffix = "-headless"
 )
 
+// ReconcilerOptions are the operator settings shared by the reconcilers that
+// generate pods or call the engines
+type ReconcilerOptions struct {
+	// CABundle is a PEM bundle mounted into pods that reach external services
+	CABundle []byte
+	// Proxy is the HTTP proxy of generated Jobs
+	Proxy ProxyConfig
+	// HTTPClient sends the HTTP requests of the operator to engines and registries
+	// (default: http.DefaultClient)
+	HTTPClient *http.Client
+}
+
+// databaseReconciler returns a DatabaseReconciler with the client and the
+// options of another reconciler, to build Database resources
+func (o ReconcilerOptions) databaseReconciler(c client.Client, scheme *runtime.Scheme) *DatabaseReconciler {
+	return &DatabaseReconciler{Client: c, Scheme: scheme, ReconcilerOptions: o}
+}
+
 // DatabaseReconciler reconciles a Database object
 type DatabaseReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// APIReader reads the credentials of admin connections uncached (default:
+	// the API reader of the manager)
+	APIReader client.Reader
+	ReconcilerOptions
+	// ImageResolver resolves version tags to digests (default: RegistryResolver)
+	ImageResolver ImageResolver
+	// VersionCatalog lists the versions of engine images for automatic patch
+	// upgrades (default: RegistryResolver)
+	VersionCatalog VersionCatalog
+	// VolumeUsageReader reads the usage of data volumes (default: KubeletStatsReader)
+	VolumeUsageReader VolumeUsageReader
+	// Recorder records Events on Databases
+	Recorder record.EventRecorder
+	// Fleet enables the targetCluster of Databases
+	Fleet bool
+	// Concurrency configures the workers and the rate limiting of the controller
+	Concurrency Concurrency
+
+	workers  *engineWorkers
+	failures *reconcileFailures
 }
 
 // +kubebuilder:rbac:groups=databases.database-operator.io,resources=databases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=databases.database-operator.io,resources=databases/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=databases.database-operator.io,resources=databases/finalizers,verbs=update
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaseclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaseversions,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	log := log.FromContext(ctx)
 
+	// Every reconcile is a trace, with a span per provisioning step
+	ctx, span := startSpan(ctx, "Reconcile",
+		attribute.String("database.namespace", req.Namespace), attribute.String("database.name", req.Name))
+	defer func() { endSpan(span, err) }()
+
 	// Fetch the Database instance
 	database := &databasesv1alpha1.Database{}
-	err := r.Get(ctx, req.NamespacedName, database)
+	err = r.Get(ctx, req.NamespacedName, database)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Info("Database resource not found. Ignoring since object must be deleted")
+			r.failures.reset(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "Failed to get Database")
 		return ctrl.Result{}, err
 	}
 
+	// Engines with a worker cap leave the other workers to the other engines
+	if !r.workers.acquire(database.Spec.Type) {
+		log.V(1).Info("Every worker of the engine is busy", "engine", database.Spec.Type)
+		return ctrl.Result{RequeueAfter: engineBusyRequeue}, nil
+	}
+	defer r.workers.release(database.Spec.Type)
+
+	defer observeReconcile(database)()
+	span.SetAttributes(databaseAttributes(database)...)
+
+	// Tag every following log line with the object UID and generation
+	ctx = withDatabaseLogger(ctx, database)
+
+	// Databases of a target cluster read and write their child resources there
+	if database.Spec.TargetCluster != nil && r.Fleet {
+		target, err := r.targetClusterReconciler(ctx, database)
+		if err != nil {
+			failures := r.failures.record(req.NamespacedName)
+			log.Error(err, "Failed to connect to the target cluster", "failures", failures)
+			r.updateStatusOnError(ctx, database, "TargetClusterUnavailable", err)
+			return ctrl.Result{RequeueAfter: failureBackoff(failures)}, nil
+		}
+		return target.reconcile(ctx, database)
+	}
+	return r.reconcile(ctx, database)
+}
+
+// reconcile moves the child resources of a Database to its spec
+func (r *DatabaseReconciler) reconcile(ctx context.Context, database *databasesv1alpha1.Database) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
 	// Add finalizer if not present
 	if !controllerutil.ContainsFinalizer(database, databaseFinalizer) {
 		controllerutil.AddFinalizer(database, databaseFinalizer)
@@ -86,8 +178,21 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	// Check if the Database is marked to be deleted
 	if !database.ObjectMeta.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(database, databaseFinalizer) {
+			// Keep the Database, and the workload it owns, until its final backup completed
+			done, err := r.reconcileDeletionSnapshot(withOperation(ctx, operationFinalize), database, time.Now())
+			if err != nil || !done {
+				return ctrl.Result{RequeueAfter: deletionSnapshotRecheckInterval}, err
+			}
+
+			// Child resources in a target cluster have no owner reference to
+			// the Database
+			if err := r.deleteTargetClusterResources(withOperation(ctx, operationFinalize), database); err != nil {
+				log.Error(err, "Failed to delete the child resources in the target cluster")
+				return ctrl.Result{}, err
+			}
+
 			// Perform cleanup
-			r.finalizeDatabase(ctx, database)
+			r.finalizeDatabase(withOperation(ctx, operationFinalize), database)
 
 			// Remove finalizer
 			controllerutil.RemoveFinalizer(database, databaseFinalizer)
@@ -98,9 +203,14 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, nil
 	}
 
+	// A paused Database only reports its status until the annotation is removed
+	if paused(database) {
+		return r.reconcilePaused(withOperation(ctx, operationStatus), database, time.Now())
+	}
+
 	// Update status phase to Creating if it's empty
 	if database.Status.Phase == "" {
-		database.Status.Phase = databasesv1alpha1.DatabasePhaseCreating
+		setPhase(database, databasesv1alpha1.DatabasePhaseCreating, databasesv1alpha1.DatabaseSubPhaseProvisioning, time.Now())
 		database.Status.ObservedGeneration = database.Generation
 		if err := r.Status().Update(ctx, database); err != nil {
 			log.Error(err, "Failed to update Database status")
@@ -108,109 +218,438 @@ func (r *DatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 	}
 
-	// Reconcile the database based on its type
-	if err := r.reconcileDatabase(ctx, database); err != nil {
-		log.Error(err, "Failed to reconcile database")
-		r.updateStatusOnError(ctx, database, err)
-		return ctrl.Result{RequeueAfter: time.Minute}, err
+	// Fill in the defaults of the DatabaseClass before anything reads the spec
+	class, err := applyDatabaseClass(ctx, r.Client, database)
+	if err != nil {
+		log.Error(err, "Failed to get DatabaseClass", "operation", operationValidate)
+		return ctrl.Result{}, err
 	}
 
-	// Update status to Ready
-	if database.Status.Phase != databasesv1alpha1.DatabasePhaseReady {
-		database.Status.Phase = databasesv1alpha1.DatabasePhaseReady
-		database.Status.ObservedGeneration = database.Generation
-		database.Status.Message = "Database is ready"
+	versions, err := listDatabaseVersions(ctx, r.Client, database)
+	if err != nil {
+		log.Error(err, "Failed to list DatabaseVersions", "operation", operationValidate)
+		return ctrl.Result{}, err
+	}
+
+	// Validate the spec against the engine capabilities, the offering of its
+	// class and the catalog of versions; an invalid spec is not retried until
+	// it is changed
+	err = r.validateSpec(database)
+	if err == nil {
+		err = validateDatabaseClass(database, class)
+	}
+	if err == nil {
+		err = validateVersionCatalog(database, versions)
+	}
+	if err != nil {
+		log.Error(err, "Invalid Database spec", "operation", operationValidate)
+		r.updateStatusOnError(ctx, database, "InvalidSpec", err)
+		return ctrl.Result{}, nil
+	}
 
-		condition := metav1.Condition{
-			Type:               "Ready",
-			Status:             metav1.ConditionTrue,
-			Reason:             "DatabaseReady",
-			Message:            "Database is successfully provisioned and ready",
-			LastTransitionTime: metav1.NewTime(time.Now()),
-			ObservedGeneration: database.Generation,
+	originalStatus := database.Status.DeepCopy()
+	r.resume(ctx, database)
+
+	// Wait for referenced Secrets rather than starting pods that crash-loop
+	ready, err := r.checkPrerequisites(ctx, database)
+	if err != nil {
+		log.Error(err, "Failed to look up referenced objects", "operation", operationValidate)
+		return ctrl.Result{}, err
+	}
+	if !ready {
+		log.Info("Waiting for referenced objects", "operation", operationValidate, "message", database.Status.Message)
+		if !equality.Semantic.DeepEqual(originalStatus, &database.Status) {
+			if err := r.Status().Update(ctx, database); err != nil {
+				log.Error(err, "Failed to update Database status", "operation", operationStatus)
+				return ctrl.Result{}, err
+			}
 		}
-		meta.SetStatusCondition(&database.Status.Conditions, condition)
+		return ctrl.Result{RequeueAfter: missingReferenceRecheckInterval}, nil
+	}
 
+	// Reconcile the database based on its type
+	if err := r.reconcileDatabase(ctx, database); err != nil {
+		var provisioningErr *provisioningError
+		if goerrors.As(err, &provisioningErr) {
+			return r.updateStatusOnProvisioningError(ctx, database, provisioningErr)
+		}
+		// Retried with a backoff of its own rather than the one of the queue,
+		// which is shared by every Database and has no jitter
+		failures := r.failures.record(client.ObjectKeyFromObject(database))
+		log.Error(err, "Failed to reconcile database", "failures", failures)
+		r.updateStatusOnError(ctx, database, "ReconciliationFailed", err)
+		return ctrl.Result{RequeueAfter: failureBackoff(failures)}, nil
+	}
+	r.failures.reset(client.ObjectKeyFromObject(database))
+	reportHealth(database, time.Now())
+
+	// Clients must not use the data while a restore replaces it; Ready is set
+	// again once the restore releases the lock
+	if restore, restoring := runningRestore(database); restoring {
+		markRestoring(database, restore)
+		if !equality.Semantic.DeepEqual(originalStatus, &database.Status) {
+			if err := r.Status().Update(ctx, database); err != nil {
+				log.Error(err, "Failed to update Database status", "operation", operationStatus)
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: restoreRecheckInterval}, nil
+	}
+	if upgrade := database.Status.MajorUpgrade; majorUpgradeRunning(upgrade) {
+		markUpgrading(database, upgrade)
+		if !equality.Semantic.DeepEqual(originalStatus, &database.Status) {
+			if err := r.Status().Update(ctx, database); err != nil {
+				log.Error(err, "Failed to update Database status", "operation", operationStatus)
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: majorUpgradeRecheckInterval}, nil
+	}
+	if migration := database.Status.StorageMigration; storageMigrationRunning(migration) &&
+		activeOperation(database) == disruptiveOperationStorageMigration {
+		markMigrating(database, migration)
+		if !equality.Semantic.DeepEqual(originalStatus, &database.Status) {
+			if err := r.Status().Update(ctx, database); err != nil {
+				log.Error(err, "Failed to update Database status", "operation", operationStatus)
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: storageMigrationRecheckInterval}, nil
+	}
+
+	// Update status to Ready; the condition is refreshed on every reconcile so
+	// it reports the generation it was observed at. A failed health check keeps
+	// the Database provisioned but not Ready.
+	r.checkHealth(ctx, database, time.Now())
+	previousPhase := database.Status.Phase
+	advancePhase(database, time.Now())
+	r.reportHibernation(database, previousPhase)
+	database.Status.ObservedGeneration = database.Generation
+	if hibernated(database) {
+		reason, message := hibernationCondition(database)
+		database.Status.Message = message
+		setCondition(database, conditionReady, metav1.ConditionFalse, reason, message)
+	} else if message, unhealthy := databaseUnhealthy(database); unhealthy {
+		database.Status.Message = message
+		setCondition(database, conditionReady, metav1.ConditionFalse, reasonHealthCheckFailed, message)
+	} else {
+		database.Status.Message = "Database is ready"
+		setCondition(database, conditionReady, metav1.ConditionTrue, reasonDatabaseReady, "Database is successfully provisioned and ready")
+	}
+	if !equality.Semantic.DeepEqual(originalStatus, &database.Status) {
 		if err := r.Status().Update(ctx, database); err != nil {
-			log.Error(err, "Failed to update Database status to Ready")
+			log.Error(err, "Failed to update Database status", "operation", operationStatus)
 			return ctrl.Result{}, err
 		}
 	}
 
-	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+	// Come back soon while the workload progresses, late once it is Ready, and
+	// when the debug window ends so the log level is reverted on time
+	requeueAfter := requeueInterval(database)
+	if remaining := debugWindowRemaining(database, time.Now()); remaining > 0 && remaining < requeueAfter {
+		requeueAfter = remaining
+	}
+	// Come back when a replica schedule window opens or closes
+	if remaining := nextTopologyChange(database, time.Now()); remaining > 0 && remaining < requeueAfter {
+		requeueAfter = remaining
+	}
+	// Come back when a maintenance window opens or closes
+	if remaining := nextMaintenanceChange(database, time.Now()); remaining > 0 && remaining < requeueAfter {
+		requeueAfter = remaining
+	}
+	// Come back when the release freeze ends to run the deferred actions
+	if remaining := freezeRemaining(database, time.Now()); remaining > 0 && remaining < requeueAfter {
+		requeueAfter = remaining
+	}
+	// Come back when a failed bootstrap is run again
+	if remaining := bootstrapRetryRemaining(database, time.Now()); remaining > 0 && remaining < requeueAfter {
+		requeueAfter = remaining
+	}
+	// Come back when the next password rotation is due
+	if remaining := rotationRemaining(database, time.Now()); remaining > 0 && remaining < requeueAfter {
+		requeueAfter = remaining
+	}
+	// Follow volumes filling up closely
+	if usage := database.Status.DiskUsage; usage != nil && diskPressureLevel(database, usage.Percent) != diskPressureNone &&
+		diskUsageCheckInterval < requeueAfter {
+		requeueAfter = diskUsageCheckInterval
+	}
+	// Check an unhealthy engine again as soon as the next check is due
+	if _, unhealthy := databaseUnhealthy(database); unhealthy && healthCheckInterval < requeueAfter {
+		requeueAfter = healthCheckInterval
+	}
+	// Verify an upgrade with the next health check
+	if database.Status.Phase == databasesv1alpha1.DatabasePhaseUpgrading && healthCheckInterval < requeueAfter {
+		requeueAfter = healthCheckInterval
+	}
+	// Restart the next replica of a vertical scale, or check the switchover
+	if database.Status.VerticalScale != nil && verticalScaleInterval < requeueAfter {
+		requeueAfter = verticalScaleInterval
+	}
+	// Follow the data volumes being expanded
+	if meta.IsStatusConditionTrue(database.Status.Conditions, conditionStorageResizing) && volumeExpansionInterval < requeueAfter {
+		requeueAfter = volumeExpansionInterval
+	}
+	// Retry deferred scale-downs while connections drain
+	if database.Status.Downscale != nil && downscaleRecheckInterval < requeueAfter {
+		requeueAfter = downscaleRecheckInterval
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *DatabaseReconciler) reconcileDatabase(ctx context.Context, database *databasesv1alpha1.Database) error {
-	log := log.FromContext(ctx)
+	provisionCtx := withOperation(ctx, operationProvision)
+
+	// validateSpec accepted the version, later upgrades are validated from it.
+	// A major upgraded offline records it once the data is converted.
+	if !offlineUpgrade(database) {
+		if previous := database.Status.Version; previous != "" && previous != database.Spec.Version {
+			r.event(database, corev1.EventTypeNormal, "Upgrading",
+				fmt.Sprintf("Upgrading %s from %s to %s", database.Spec.Type, previous, database.Spec.Version))
+			startUpgrade(database, time.Now())
+		}
+		database.Status.Version = database.Spec.Version
+	}
+
+	// Disruptive actions below check the release freeze and the maintenance windows
+	reconcileFreeze(provisionCtx, database, time.Now())
+	if err := reconcileMaintenance(provisionCtx, database, time.Now()); err != nil {
+		return err
+	}
+
+	// Move to the latest patch release within the maintenance windows, before
+	// the workload is updated to it
+	if err := r.reconcileAutoUpgrade(provisionCtx, database, time.Now()); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to upgrade to the latest patch release")
+		return err
+	}
+
+	// Report the PodSecurity level workloads and Jobs are generated for
+	reportPodSecurity(database)
+
+	// Autoscalers count the engine pods through the scale subresource
+	database.Status.Selector = labels.SelectorFromSet(r.getLabels(database)).String()
+
+	// Evaluate the replica schedules before the workload is scaled
+	if err := r.reconcileReplicaSchedule(provisionCtx, database, time.Now()); err != nil {
+		return err
+	}
+
+	// Generate the credentials before any workload or Job reads them
+	if err := r.reconcileCredentials(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to reconcile credentials")
+		return err
+	}
+
+	// Convert the data of a new major before the workload runs it
+	upgradeCtx := withOperation(ctx, operationMajorUpgrade)
+	if err := r.reconcileMajorUpgrade(upgradeCtx, database, time.Now()); err != nil {
+		log.FromContext(upgradeCtx).Error(err, "Failed to upgrade to the new major")
+		return err
+	}
+
+	// Move the data volumes to a new storage class before the workload runs on them
+	migrationCtx := withOperation(ctx, operationStorageMigration)
+	if err := r.reconcileStorageMigration(migrationCtx, database, time.Now()); err != nil {
+		log.FromContext(migrationCtx).Error(err, "Failed to migrate the data volumes to the new storage class")
+		return err
+	}
+
+	// Take the image of the running version from its DatabaseVersion
+	if err := r.reconcileVersionCatalog(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to look up the DatabaseVersion of the running version")
+		return err
+	}
+
+	// Pin the image before any workload or Job runs it
+	if err := r.reconcileImageResolution(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to resolve image digest")
+		return err
+	}
+
+	// Report changes made by others before the operator reverts any of them
+	if err := r.reconcileExternalChanges(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to check child resources for external changes")
+		return err
+	}
+
+	// Grow the data volumes before the workload is recreated with their new size
+	if err := r.reconcileVolumeExpansion(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to expand the data volumes")
+		return err
+	}
+
+	// Create the configuration, the Service and the workload, in this order
+	if err := r.reconcileProvisioning(provisionCtx, database); err != nil {
+		return err
+	}
 
-	// Reconcile Service
-	if err := r.reconcileService(ctx, database); err != nil {
-		log.Error(err, "Failed to reconcile Service")
+	if err := r.recordAppliedConfig(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to record applied engine configuration")
+		return err
+	}
+
+	// Restrict the network access of operator Jobs before any of them runs
+	if err := r.reconcileJobsNetworkPolicy(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to reconcile Job NetworkPolicy")
+		return err
+	}
+	if err := r.reconcileDatabaseNetworkPolicy(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to reconcile database NetworkPolicy")
+		return err
+	}
+	if err := r.reconcileIngress(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to reconcile Ingress")
+		return err
+	}
+	if err := r.reconcileJobsServiceAccount(provisionCtx, database); err != nil {
+		log.FromContext(provisionCtx).Error(err, "Failed to reconcile Job ServiceAccount")
 		return err
 	}
 
-	// Reconcile StatefulSet or Deployment based on database type
+	// Reconcile engine specific analysis
+	var err error
 	switch database.Spec.Type {
-	case databasesv1alpha1.DatabaseTypePostgreSQL:
-		return r.reconcilePostgreSQL(ctx, database)
-	case databasesv1alpha1.DatabaseTypeMongoDB:
-		return r.reconcileMongoDB(ctx, database)
 	case databasesv1alpha1.DatabaseTypeRedis:
-		return r.reconcileRedis(ctx, database)
+		err = r.reconcileKeyspaceAnalysis(withOperation(ctx, operationKeyspaceAnalysis), database)
 	case databasesv1alpha1.DatabaseTypeElasticsearch:
-		return r.reconcileElasticsearch(ctx, database)
-	case databasesv1alpha1.DatabaseTypeSQLite:
-		return r.reconcileSQLite(ctx, database)
-	default:
-		return fmt.Errorf("unsupported database type: %s", database.Spec.Type)
+		err = r.reconcileShardAnalysis(withOperation(ctx, operationShardAnalysis), database)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Provision the logical databases and users of the spec
+	if err := r.reconcileBootstrap(withOperation(ctx, operationBootstrap), database); err != nil {
+		return err
+	}
+
+	// Provision the user of the metrics exporters
+	if err := r.reconcileMonitoringUser(withOperation(ctx, operationMonitoringUser), database); err != nil {
+		return err
+	}
+
+	// Rotate the administrative password on schedule
+	if err := r.reconcileRotation(withOperation(ctx, operationRotation), database, time.Now()); err != nil {
+		return err
+	}
+
+	// Watch the volume usage of the running workload
+	if err := r.reconcileDiskPressure(withOperation(ctx, operationDiskPressure), database, time.Now()); err != nil {
+		return err
+	}
+
+	// Reconcile scheduled backups
+	if err := r.reconcileBackup(withOperation(ctx, operationBackup), database); err != nil {
+		return err
 	}
+	if err := r.reconcileBackupStatus(withOperation(ctx, operationBackup), database, time.Now()); err != nil {
+		return err
+	}
+
+	// Reconcile the engine log level
+	return r.reconcileEngineLogLevel(withOperation(ctx, operationLogLevel), database)
 }
 
 func (r *DatabaseReconciler) reconcileService(ctx context.Context, database *databasesv1alpha1.Database) error {
 	service := &corev1.Service{}
 	serviceName := database.Name + "-service"
 	err := r.Get(ctx, types.NamespacedName{Name: serviceName, Namespace: database.Namespace}, service)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if errors.IsNotFound(err) {
+		log.FromContext(ctx).Info("Creating Service", "name", serviceName)
+	} else if err := r.claimExisting(ctx, database, "Service", service); err != nil {
+		return err
+	}
+	stamped := err == nil && appliedSpecStamped(service)
 
-	ports := r.getServicePorts(database)
-
-	if err != nil && errors.IsNotFound(err) {
-		// Create the service
-		service = &corev1.Service{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      serviceName,
-				Namespace: database.Namespace,
-				Labels:    r.getLabels(database),
-			},
-			Spec: corev1.ServiceSpec{
-				Selector: r.getLabels(database),
-				Ports:    ports,
-				Type:     corev1.ServiceTypeClusterIP,
-			},
-		}
-
-		if err := controllerutil.SetControllerReference(database, service, r.Scheme); err != nil {
-			return err
-		}
+	service = &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      serviceName,
+			Namespace: database.Namespace,
+			Labels:    r.getLabels(database),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: r.getLabels(database),
+			Ports:    r.getServicePorts(database),
+		},
+	}
+	applyServiceExposure(database, service)
+	mergeMetadata(&service.ObjectMeta, resourceMetadata(database).Service)
+	if err := controllerutil.SetControllerReference(database, service, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.apply(ctx, service); err != nil {
+		return err
+	}
+	if err := r.stampApplied(ctx, service, stamped); err != nil {
+		return err
+	}
 
-		if err := r.Create(ctx, service); err != nil {
-			return err
-		}
+	database.Status.ServiceName = serviceName
+	database.Status.ConnectionString = r.getConnectionString(database, serviceName)
+	return r.reconcileHeadlessService(ctx, database)
+}
 
-		database.Status.ServiceName = serviceName
-		database.Status.ConnectionString = r.getConnectionString(database, serviceName)
+// reconcileHeadlessService applies the headless Service governing the
+// StatefulSet, which gives every replica a stable DNS name. It publishes pods
+// before they are ready, so replicas find their peers while they start.
+func (r *DatabaseReconciler) reconcileHeadlessService(ctx context.Context, database *databasesv1alpha1.Database) error {
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite {
+		return nil
+	}
+	name := database.Name + headlessServiceSuffix
+	service := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, service)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
 	}
+	if errors.IsNotFound(err) {
+		log.FromContext(ctx).Info("Creating headless Service", "name", name)
+	} else if err := r.claimExisting(ctx, database, "Service", service); err != nil {
+		return err
+	}
+	stamped := err == nil && appliedSpecStamped(service)
 
-	return nil
+	ports := r.getServicePorts(database)
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeElasticsearch {
+		ports = append(ports, corev1.ServicePort{
+			Name:       "transport",
+			Port:       elasticsearchTransportPort,
+			TargetPort: intstr.FromInt(elasticsearchTransportPort),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+	service = &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: database.Namespace,
+			Labels:    r.getLabels(database),
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:                corev1.ClusterIPNone,
+			Selector:                 r.getLabels(database),
+			Ports:                    ports,
+			PublishNotReadyAddresses: true,
+		},
+	}
+	if err := controllerutil.SetControllerReference(database, service, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.apply(ctx, service); err != nil {
+		return err
+	}
+	return r.stampApplied(ctx, service, stamped)
 }
 
 func (r *DatabaseReconciler) reconcilePostgreSQL(ctx context.Context, database *databasesv1alpha1.Database) error {
 	statefulSet := &appsv1.StatefulSet{}
 	err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, statefulSet)
 
-	replicas := int32(1)
-	if database.Spec.Replicas != nil {
-		replicas = *database.Spec.Replicas
-	}
+	replicas := desiredReplicas(database)
 
 	env := r.getPostgreSQLEnv(database)
 
@@ -222,11 +661,13 @@ func (r *DatabaseReconciler) reconcilePostgreSQL(ctx context.Context, database *
 			return err
 		}
 
-		if err := r.Create(ctx, statefulSet); err != nil {
+		if err := r.apply(ctx, statefulSet); err != nil {
 			return err
 		}
 	} else if err != nil {
 		return err
+	} else if err := r.updateStatefulSet(ctx, database, statefulSet, r.createPostgreSQLStatefulSet(database, replicas, env)); err != nil {
+		return err
 	}
 
 	// Update status
@@ -239,10 +680,7 @@ func (r *DatabaseReconciler) reconcileMongoDB(ctx context.Context, database *dat
 	statefulSet := &appsv1.StatefulSet{}
 	err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, statefulSet)
 
-	replicas := int32(1)
-	if database.Spec.Replicas != nil {
-		replicas = *database.Spec.Replicas
-	}
+	replicas := desiredReplicas(database)
 
 	env := r.getMongoDBEnv(database)
 
@@ -253,11 +691,13 @@ func (r *DatabaseReconciler) reconcileMongoDB(ctx context.Context, database *dat
 			return err
 		}
 
-		if err := r.Create(ctx, statefulSet); err != nil {
+		if err := r.apply(ctx, statefulSet); err != nil {
 			return err
 		}
 	} else if err != nil {
 		return err
+	} else if err := r.updateStatefulSet(ctx, database, statefulSet, r.createMongoDBStatefulSet(database, replicas, env)); err != nil {
+		return err
 	}
 
 	database.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
@@ -268,10 +708,7 @@ func (r *DatabaseReconciler) reconcileRedis(ctx context.Context, database *datab
 	statefulSet := &appsv1.StatefulSet{}
 	err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, statefulSet)
 
-	replicas := int32(1)
-	if database.Spec.Replicas != nil {
-		replicas = *database.Spec.Replicas
-	}
+	replicas := desiredReplicas(database)
 
 	env := r.getRedisEnv(database)
 
@@ -282,11 +719,13 @@ func (r *DatabaseReconciler) reconcileRedis(ctx context.Context, database *datab
 			return err
 		}
 
-		if err := r.Create(ctx, statefulSet); err != nil {
+		if err := r.apply(ctx, statefulSet); err != nil {
 			return err
 		}
 	} else if err != nil {
 		return err
+	} else if err := r.updateStatefulSet(ctx, database, statefulSet, r.createRedisStatefulSet(database, replicas, env)); err != nil {
+		return err
 	}
 
 	database.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
@@ -294,13 +733,14 @@ func (r *DatabaseReconciler) reconcileRedis(ctx context.Context, database *datab
 }
 
 func (r *DatabaseReconciler) reconcileElasticsearch(ctx context.Context, database *databasesv1alpha1.Database) error {
+	if len(elasticsearchNodeSets(database)) > 0 {
+		return r.reconcileElasticsearchNodeSets(ctx, database)
+	}
+
 	statefulSet := &appsv1.StatefulSet{}
 	err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, statefulSet)
 
-	replicas := int32(1)
-	if database.Spec.Replicas != nil {
-		replicas = *database.Spec.Replicas
-	}
+	replicas := desiredReplicas(database)
 
 	env := r.getElasticsearchEnv(database)
 
@@ -311,11 +751,13 @@ func (r *DatabaseReconciler) reconcileElasticsearch(ctx context.Context, databas
 			return err
 		}
 
-		if err := r.Create(ctx, statefulSet); err != nil {
+		if err := r.apply(ctx, statefulSet); err != nil {
 			return err
 		}
 	} else if err != nil {
 		return err
+	} else if err := r.updateStatefulSet(ctx, database, statefulSet, r.createElasticsearchStatefulSet(database, replicas, env)); err != nil {
+		return err
 	}
 
 	database.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
@@ -327,6 +769,9 @@ func (r *DatabaseReconciler) reconcileSQLite(ctx context.Context, database *data
 	err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, deployment)
 
 	replicas := int32(1)
+	if hibernated(database) {
+		replicas = 0
+	}
 	env := r.getSQLiteEnv(database)
 
 	if err != nil && errors.IsNotFound(err) {
@@ -336,11 +781,20 @@ func (r *DatabaseReconciler) reconcileSQLite(ctx context.Context, database *data
 			return err
 		}
 
-		if err := r.Create(ctx, deployment); err != nil {
+		if err := r.apply(ctx, deployment); err != nil {
 			return err
 		}
 	} else if err != nil {
 		return err
+	} else if err := r.claimExisting(ctx, database, "Deployment", deployment); err != nil {
+		return err
+	} else if err := r.scaleDeployment(ctx, deployment, replicas); err != nil {
+		return err
+	} else {
+		desired := r.createSQLiteDeployment(database, replicas, env)
+		if err := r.updatePodTemplate(ctx, database, deployment, &deployment.Spec.Template, desired, &desired.Spec.Template); err != nil {
+			return err
+		}
 	}
 
 	database.Status.ReadyReplicas = deployment.Status.ReadyReplicas
@@ -357,6 +811,34 @@ func (r *DatabaseReconciler) getLabels(database *databasesv1alpha1.Database) map
 	}
 }
 
+// getComponentLabels returns labels for auxiliary workloads (analysis Jobs, ...).
+// They deliberately omit the "app" label so these pods are never selected by the
+// database Service or workload selectors.
+func (r *DatabaseReconciler) getComponentLabels(database *databasesv1alpha1.Database, component string) map[string]string {
+	return map[string]string{
+		"database-type":                string(database.Spec.Type),
+		"app.kubernetes.io/name":       "database",
+		"app.kubernetes.io/instance":   database.Name,
+		"app.kubernetes.io/component":  component,
+		"app.kubernetes.io/managed-by": "database-operator",
+	}
+}
+
+// setRecommendations replaces all recommendations of the given type
+func setRecommendations(database *databasesv1alpha1.Database, recommendationType string, recommendations []databasesv1alpha1.Recommendation) {
+	kept := []databasesv1alpha1.Recommendation{}
+	for _, rec := range database.Status.Recommendations {
+		if rec.Type != recommendationType {
+			kept = append(kept, rec)
+		}
+	}
+	kept = append(kept, recommendations...)
+	if len(kept) == 0 {
+		kept = nil
+	}
+	database.Status.Recommendations = kept
+}
+
 func (r *DatabaseReconciler) getServicePorts(database *databasesv1alpha1.Database) []corev1.ServicePort {
 	var port int32
 
@@ -375,7 +857,7 @@ func (r *DatabaseReconciler) getServicePorts(database *databasesv1alpha1.Databas
 		port = 8080
 	}
 
-	return []corev1.ServicePort{
+	ports := []corev1.ServicePort{
 		{
 			Name:       "database",
 			Port:       port,
@@ -383,25 +865,52 @@ func (r *DatabaseReconciler) getServicePorts(database *databasesv1alpha1.Databas
 			Protocol:   corev1.ProtocolTCP,
 		},
 	}
+	if tlsEnabled(database) && database.Spec.Type == databasesv1alpha1.DatabaseTypeRedis {
+		ports = append(ports, corev1.ServicePort{
+			Name:       "database-tls",
+			Port:       redisTLSPort,
+			TargetPort: intstr.FromInt(redisTLSPort),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+	if port, ok := metricsServicePort(database); ok {
+		ports = append(ports, port)
+	}
+	return ports
 }
 
+// getConnectionString returns the connection string of the Service, over TLS
+// when it is enabled
 func (r *DatabaseReconciler) getConnectionString(database *databasesv1alpha1.Database, serviceName string) string {
+	tls := tlsEnabled(database)
 	switch database.Spec.Type {
 	case databasesv1alpha1.DatabaseTypePostgreSQL:
 		dbName := "postgres"
 		if database.Spec.PostgreSQL != nil && database.Spec.PostgreSQL.Database != "" {
 			dbName = database.Spec.PostgreSQL.Database
 		}
-		return fmt.Sprintf("postgresql://<username>:<password>@%s.%s.svc.cluster.local:5432/%s",
+		connection := fmt.Sprintf("postgresql://<username>:<password>@%s.%s.svc.cluster.local:5432/%s",
 			serviceName, database.Namespace, dbName)
+		if tls {
+			connection += "?sslmode=verify-full"
+		}
+		return connection
 	case databasesv1alpha1.DatabaseTypeMongoDB:
 		dbName := "admin"
 		if database.Spec.MongoDB != nil && database.Spec.MongoDB.Database != "" {
 			dbName = database.Spec.MongoDB.Database
 		}
-		return fmt.Sprintf("mongodb://<username>:<password>@%s.%s.svc.cluster.local:27017/%s",
+		connection := fmt.Sprintf("mongodb://<username>:<password>@%s.%s.svc.cluster.local:27017/%s",
 			serviceName, database.Namespace, dbName)
+		if tls {
+			connection += "?tls=true"
+		}
+		return connection
 	case databasesv1alpha1.DatabaseTypeRedis:
+		if tls {
+			return fmt.Sprintf("rediss://:%s@%s.%s.svc.cluster.local:%d",
+				"<password>", serviceName, database.Namespace, redisTLSPort)
+		}
 		return fmt.Sprintf("redis://:%s@%s.%s.svc.cluster.local:6379",
 			"<password>", serviceName, database.Namespace)
 	case databasesv1alpha1.DatabaseTypeElasticsearch:
@@ -423,32 +932,13 @@ func (r *DatabaseReconciler) getPostgreSQLEnv(database *databasesv1alpha1.Databa
 		},
 		{
 			Name:  "POSTGRES_USER",
-			Value: "postgres",
-		},
-		{
-			Name:  "POSTGRES_PASSWORD",
-			Value: "postgres",
+			Value: adminUsername(database),
 		},
+		passwordEnv("POSTGRES_PASSWORD", passwordSecret(database)),
 	}
 
-	if database.Spec.PostgreSQL != nil {
-		if database.Spec.PostgreSQL.Database != "" {
-			env[0].Value = database.Spec.PostgreSQL.Database
-		}
-		if database.Spec.PostgreSQL.Username != "" {
-			env[1].Value = database.Spec.PostgreSQL.Username
-		}
-		if database.Spec.PostgreSQL.PasswordSecret != nil {
-			env[2].ValueFrom = &corev1.EnvVarSource{
-				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: database.Spec.PostgreSQL.PasswordSecret.Name,
-					},
-					Key: database.Spec.PostgreSQL.PasswordSecret.Key,
-				},
-			}
-			env[2].Value = ""
-		}
+	if database.Spec.PostgreSQL != nil && database.Spec.PostgreSQL.Database != "" {
+		env[0].Value = database.Spec.PostgreSQL.Database
 	}
 
 	env = append(env, r.convertEnvVars(database.Spec.Env)...)
@@ -459,29 +949,9 @@ func (r *DatabaseReconciler) getMongoDBEnv(database *databasesv1alpha1.Database)
 	env := []corev1.EnvVar{
 		{
 			Name:  "MONGO_INITDB_ROOT_USERNAME",
-			Value: "root",
-		},
-		{
-			Name:  "MONGO_INITDB_ROOT_PASSWORD",
-			Value: "password",
+			Value: adminUsername(database),
 		},
-	}
-
-	if database.Spec.MongoDB != nil {
-		if database.Spec.MongoDB.Username != "" {
-			env[0].Value = database.Spec.MongoDB.Username
-		}
-		if database.Spec.MongoDB.PasswordSecret != nil {
-			env[1].ValueFrom = &corev1.EnvVarSource{
-				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: database.Spec.MongoDB.PasswordSecret.Name,
-					},
-					Key: database.Spec.MongoDB.PasswordSecret.Key,
-				},
-			}
-			env[1].Value = ""
-		}
+		passwordEnv("MONGO_INITDB_ROOT_PASSWORD", passwordSecret(database)),
 	}
 
 	env = append(env, r.convertEnvVars(database.Spec.Env)...)
@@ -491,18 +961,8 @@ func (r *DatabaseReconciler) getMongoDBEnv(database *databasesv1alpha1.Database)
 func (r *DatabaseReconciler) getRedisEnv(database *databasesv1alpha1.Database) []corev1.EnvVar {
 	env := []corev1.EnvVar{}
 
-	if database.Spec.Redis != nil && database.Spec.Redis.PasswordSecret != nil {
-		env = append(env, corev1.EnvVar{
-			Name: "REDIS_PASSWORD",
-			ValueFrom: &corev1.EnvVarSource{
-				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: database.Spec.Redis.PasswordSecret.Name,
-					},
-					Key: database.Spec.Redis.PasswordSecret.Key,
-				},
-			},
-		})
+	if secret := passwordSecret(database); secret != nil {
+		env = append(env, passwordEnv("REDIS_PASSWORD", secret))
 	}
 
 	env = append(env, r.convertEnvVars(database.Spec.Env)...)
@@ -520,6 +980,10 @@ func (r *DatabaseReconciler) getElasticsearchEnv(database *databasesv1alpha1.Dat
 			Value: "false",
 		},
 	}
+	// The nodes of the node sets discover each other, see createElasticsearchNodeSet
+	if len(elasticsearchNodeSets(database)) > 0 {
+		env = env[1:]
+	}
 
 	if database.Spec.Elasticsearch != nil && database.Spec.Elasticsearch.ClusterName != "" {
 		env = append(env, corev1.EnvVar{
@@ -585,7 +1049,7 @@ func (r *DatabaseReconciler) createPostgreSQLStatefulSet(database *databasesv1al
 				},
 				Resources: corev1.VolumeResourceRequirements{
 					Requests: corev1.ResourceList{
-						corev1.ResourceStorage: resource.MustParse(database.Spec.Storage.Size),
+						corev1.ResourceStorage: resource.MustParse(dataVolumeSize(database)),
 					},
 				},
 				StorageClassName: database.Spec.Storage.StorageClass,
@@ -595,7 +1059,7 @@ func (r *DatabaseReconciler) createPostgreSQLStatefulSet(database *databasesv1al
 
 	container := corev1.Container{
 		Name:  "postgresql",
-		Image: fmt.Sprintf("postgres:%s", database.Spec.Version),
+		Image: engineImage(database),
 		Ports: []corev1.ContainerPort{
 			{
 				Name:          "postgresql",
@@ -610,16 +1074,16 @@ func (r *DatabaseReconciler) createPostgreSQLStatefulSet(database *databasesv1al
 		container.VolumeMounts = []corev1.VolumeMount{
 			{
 				Name:      "data",
-				MountPath: "/var/lib/postgresql/data",
+				MountPath: engineLayout(database).dataPath,
 			},
 		}
 	}
 
-	if database.Spec.Resources != nil {
-		container.Resources = r.buildResourceRequirements(database.Spec.Resources)
+	if resources := effectiveResources(database); resources != nil {
+		container.Resources = r.buildResourceRequirements(resources)
 	}
 
-	return &appsv1.StatefulSet{
+	statefulSet := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      database.Name,
 			Namespace: database.Namespace,
@@ -627,7 +1091,7 @@ func (r *DatabaseReconciler) createPostgreSQLStatefulSet(database *databasesv1al
 		},
 		Spec: appsv1.StatefulSetSpec{
 			Replicas:    &replicas,
-			ServiceName: database.Name + "-service",
+			ServiceName: database.Name + headlessServiceSuffix,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -642,6 +1106,24 @@ func (r *DatabaseReconciler) createPostgreSQLStatefulSet(database *databasesv1al
 			VolumeClaimTemplates: volumeClaimTemplates,
 		},
 	}
+
+	r.applyEngineConfig(database, &statefulSet.Spec.Template)
+	applyTLS(database, &statefulSet.Spec.Template)
+	applyImageLayout(database, &statefulSet.Spec.Template.Spec.Containers[0])
+	addMetricsExporter(database, &statefulSet.Spec.Template)
+	addLogShipper(database, &statefulSet.Spec.Template)
+	r.applyPodSecurity(database, &statefulSet.Spec.Template)
+	applyLifecycleHooks(database, &statefulSet.Spec.Template)
+	applyPlacement(database, &statefulSet.Spec.Template, r.getLabels(database))
+	if walArchivingEnabled(database) {
+		r.addWALArchiving(database, &statefulSet.Spec.Template.Spec)
+	}
+	if pgBackRestEnabled(database) {
+		r.addPgBackRest(database, &statefulSet.Spec.Template.Spec)
+	}
+	applyWorkloadMetadata(database, &statefulSet.ObjectMeta, &statefulSet.Spec.Template, statefulSet.Spec.VolumeClaimTemplates)
+
+	return statefulSet
 }
 
 func (r *DatabaseReconciler) createMongoDBStatefulSet(database *databasesv1alpha1.Database, replicas int32, env []corev1.EnvVar) *appsv1.StatefulSet {
@@ -659,7 +1141,7 @@ func (r *DatabaseReconciler) createMongoDBStatefulSet(database *databasesv1alpha
 				},
 				Resources: corev1.VolumeResourceRequirements{
 					Requests: corev1.ResourceList{
-						corev1.ResourceStorage: resource.MustParse(database.Spec.Storage.Size),
+						corev1.ResourceStorage: resource.MustParse(dataVolumeSize(database)),
 					},
 				},
 				StorageClassName: database.Spec.Storage.StorageClass,
@@ -669,7 +1151,7 @@ func (r *DatabaseReconciler) createMongoDBStatefulSet(database *databasesv1alpha
 
 	container := corev1.Container{
 		Name:  "mongodb",
-		Image: fmt.Sprintf("mongo:%s", database.Spec.Version),
+		Image: engineImage(database),
 		Ports: []corev1.ContainerPort{
 			{
 				Name:          "mongodb",
@@ -684,16 +1166,16 @@ func (r *DatabaseReconciler) createMongoDBStatefulSet(database *databasesv1alpha
 		container.VolumeMounts = []corev1.VolumeMount{
 			{
 				Name:      "data",
-				MountPath: "/data/db",
+				MountPath: engineLayout(database).dataPath,
 			},
 		}
 	}
 
-	if database.Spec.Resources != nil {
-		container.Resources = r.buildResourceRequirements(database.Spec.Resources)
+	if resources := effectiveResources(database); resources != nil {
+		container.Resources = r.buildResourceRequirements(resources)
 	}
 
-	return &appsv1.StatefulSet{
+	statefulSet := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      database.Name,
 			Namespace: database.Namespace,
@@ -701,7 +1183,7 @@ func (r *DatabaseReconciler) createMongoDBStatefulSet(database *databasesv1alpha
 		},
 		Spec: appsv1.StatefulSetSpec{
 			Replicas:    &replicas,
-			ServiceName: database.Name + "-service",
+			ServiceName: database.Name + headlessServiceSuffix,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -716,6 +1198,17 @@ func (r *DatabaseReconciler) createMongoDBStatefulSet(database *databasesv1alpha
 			VolumeClaimTemplates: volumeClaimTemplates,
 		},
 	}
+
+	r.applyEngineConfig(database, &statefulSet.Spec.Template)
+	applyTLS(database, &statefulSet.Spec.Template)
+	applyImageLayout(database, &statefulSet.Spec.Template.Spec.Containers[0])
+	addMetricsExporter(database, &statefulSet.Spec.Template)
+	addLogShipper(database, &statefulSet.Spec.Template)
+	r.applyPodSecurity(database, &statefulSet.Spec.Template)
+	applyLifecycleHooks(database, &statefulSet.Spec.Template)
+	applyPlacement(database, &statefulSet.Spec.Template, r.getLabels(database))
+	applyWorkloadMetadata(database, &statefulSet.ObjectMeta, &statefulSet.Spec.Template, statefulSet.Spec.VolumeClaimTemplates)
+	return statefulSet
 }
 
 func (r *DatabaseReconciler) createRedisStatefulSet(database *databasesv1alpha1.Database, replicas int32, env []corev1.EnvVar) *appsv1.StatefulSet {
@@ -733,7 +1226,7 @@ func (r *DatabaseReconciler) createRedisStatefulSet(database *databasesv1alpha1.
 				},
 				Resources: corev1.VolumeResourceRequirements{
 					Requests: corev1.ResourceList{
-						corev1.ResourceStorage: resource.MustParse(database.Spec.Storage.Size),
+						corev1.ResourceStorage: resource.MustParse(dataVolumeSize(database)),
 					},
 				},
 				StorageClassName: database.Spec.Storage.StorageClass,
@@ -743,7 +1236,7 @@ func (r *DatabaseReconciler) createRedisStatefulSet(database *databasesv1alpha1.
 
 	container := corev1.Container{
 		Name:  "redis",
-		Image: fmt.Sprintf("redis:%s", database.Spec.Version),
+		Image: engineImage(database),
 		Ports: []corev1.ContainerPort{
 			{
 				Name:          "redis",
@@ -758,16 +1251,26 @@ func (r *DatabaseReconciler) createRedisStatefulSet(database *databasesv1alpha1.
 		container.VolumeMounts = []corev1.VolumeMount{
 			{
 				Name:      "data",
-				MountPath: "/data",
+				MountPath: engineLayout(database).dataPath,
 			},
 		}
 	}
 
-	if database.Spec.Resources != nil {
-		container.Resources = r.buildResourceRequirements(database.Spec.Resources)
+	// The image does not read REDIS_PASSWORD, so it is passed on the command
+	// line, where restarted pods also pick up rotated passwords
+	if passwordSecret(database) != nil {
+		container.Args = append(container.Args, "--requirepass", "$(REDIS_PASSWORD)")
+	}
+
+	if monitoringUserEnabled(database) {
+		addRedisMonitoringUser(database, &container)
 	}
 
-	return &appsv1.StatefulSet{
+	if resources := effectiveResources(database); resources != nil {
+		container.Resources = r.buildResourceRequirements(resources)
+	}
+
+	statefulSet := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      database.Name,
 			Namespace: database.Namespace,
@@ -775,7 +1278,7 @@ func (r *DatabaseReconciler) createRedisStatefulSet(database *databasesv1alpha1.
 		},
 		Spec: appsv1.StatefulSetSpec{
 			Replicas:    &replicas,
-			ServiceName: database.Name + "-service",
+			ServiceName: database.Name + headlessServiceSuffix,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -790,6 +1293,17 @@ func (r *DatabaseReconciler) createRedisStatefulSet(database *databasesv1alpha1.
 			VolumeClaimTemplates: volumeClaimTemplates,
 		},
 	}
+
+	r.applyEngineConfig(database, &statefulSet.Spec.Template)
+	applyTLS(database, &statefulSet.Spec.Template)
+	applyImageLayout(database, &statefulSet.Spec.Template.Spec.Containers[0])
+	addMetricsExporter(database, &statefulSet.Spec.Template)
+	addLogShipper(database, &statefulSet.Spec.Template)
+	r.applyPodSecurity(database, &statefulSet.Spec.Template)
+	applyLifecycleHooks(database, &statefulSet.Spec.Template)
+	applyPlacement(database, &statefulSet.Spec.Template, r.getLabels(database))
+	applyWorkloadMetadata(database, &statefulSet.ObjectMeta, &statefulSet.Spec.Template, statefulSet.Spec.VolumeClaimTemplates)
+	return statefulSet
 }
 
 func (r *DatabaseReconciler) createElasticsearchStatefulSet(database *databasesv1alpha1.Database, replicas int32, env []corev1.EnvVar) *appsv1.StatefulSet {
@@ -807,7 +1321,7 @@ func (r *DatabaseReconciler) createElasticsearchStatefulSet(database *databasesv
 				},
 				Resources: corev1.VolumeResourceRequirements{
 					Requests: corev1.ResourceList{
-						corev1.ResourceStorage: resource.MustParse(database.Spec.Storage.Size),
+						corev1.ResourceStorage: resource.MustParse(dataVolumeSize(database)),
 					},
 				},
 				StorageClassName: database.Spec.Storage.StorageClass,
@@ -817,7 +1331,7 @@ func (r *DatabaseReconciler) createElasticsearchStatefulSet(database *databasesv
 
 	container := corev1.Container{
 		Name:  "elasticsearch",
-		Image: fmt.Sprintf("docker.elastic.co/elasticsearch/elasticsearch:%s", database.Spec.Version),
+		Image: engineImage(database),
 		Ports: []corev1.ContainerPort{
 			{
 				Name:          "http",
@@ -837,16 +1351,16 @@ func (r *DatabaseReconciler) createElasticsearchStatefulSet(database *databasesv
 		container.VolumeMounts = []corev1.VolumeMount{
 			{
 				Name:      "data",
-				MountPath: "/usr/share/elasticsearch/data",
+				MountPath: engineLayout(database).dataPath,
 			},
 		}
 	}
 
-	if database.Spec.Resources != nil {
-		container.Resources = r.buildResourceRequirements(database.Spec.Resources)
+	if resources := effectiveResources(database); resources != nil {
+		container.Resources = r.buildResourceRequirements(resources)
 	}
 
-	return &appsv1.StatefulSet{
+	statefulSet := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      database.Name,
 			Namespace: database.Namespace,
@@ -854,7 +1368,7 @@ func (r *DatabaseReconciler) createElasticsearchStatefulSet(database *databasesv
 		},
 		Spec: appsv1.StatefulSetSpec{
 			Replicas:    &replicas,
-			ServiceName: database.Name + "-service",
+			ServiceName: database.Name + headlessServiceSuffix,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -869,6 +1383,17 @@ func (r *DatabaseReconciler) createElasticsearchStatefulSet(database *databasesv
 			VolumeClaimTemplates: volumeClaimTemplates,
 		},
 	}
+
+	r.applyEngineConfig(database, &statefulSet.Spec.Template)
+	applyTLS(database, &statefulSet.Spec.Template)
+	applyImageLayout(database, &statefulSet.Spec.Template.Spec.Containers[0])
+	addMetricsExporter(database, &statefulSet.Spec.Template)
+	addLogShipper(database, &statefulSet.Spec.Template)
+	r.applyPodSecurity(database, &statefulSet.Spec.Template)
+	applyLifecycleHooks(database, &statefulSet.Spec.Template)
+	applyPlacement(database, &statefulSet.Spec.Template, r.getLabels(database))
+	applyWorkloadMetadata(database, &statefulSet.ObjectMeta, &statefulSet.Spec.Template, statefulSet.Spec.VolumeClaimTemplates)
+	return statefulSet
 }
 
 func (r *DatabaseReconciler) createSQLiteDeployment(database *databasesv1alpha1.Database, replicas int32, env []corev1.EnvVar) *appsv1.Deployment {
@@ -876,7 +1401,7 @@ func (r *DatabaseReconciler) createSQLiteDeployment(database *databasesv1alpha1.
 
 	// For SQLite, use the version specified by the user
 	// This allows flexibility for testing with "latest" or pinning to a specific version
-	image := fmt.Sprintf("nouchka/sqlite3:%s", database.Spec.Version)
+	image := engineImage(database)
 
 	container := corev1.Container{
 		Name:  "sqlite",
@@ -895,13 +1420,13 @@ func (r *DatabaseReconciler) createSQLiteDeployment(database *databasesv1alpha1.
 		container.VolumeMounts = []corev1.VolumeMount{
 			{
 				Name:      "data",
-				MountPath: "/data",
+				MountPath: engineLayout(database).dataPath,
 			},
 		}
 	}
 
-	if database.Spec.Resources != nil {
-		container.Resources = r.buildResourceRequirements(database.Spec.Resources)
+	if resources := effectiveResources(database); resources != nil {
+		container.Resources = r.buildResourceRequirements(resources)
 	}
 
 	podSpec := corev1.PodSpec{
@@ -921,7 +1446,7 @@ func (r *DatabaseReconciler) createSQLiteDeployment(database *databasesv1alpha1.
 		}
 	}
 
-	return &appsv1.Deployment{
+	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      database.Name,
 			Namespace: database.Namespace,
@@ -940,6 +1465,11 @@ func (r *DatabaseReconciler) createSQLiteDeployment(database *databasesv1alpha1.
 			},
 		},
 	}
+	applyImageLayout(database, &deployment.Spec.Template.Spec.Containers[0])
+	r.applyPodSecurity(database, &deployment.Spec.Template)
+	applyLifecycleHooks(database, &deployment.Spec.Template)
+	applyWorkloadMetadata(database, &deployment.ObjectMeta, &deployment.Spec.Template, nil)
+	return deployment
 }
 
 func (r *DatabaseReconciler) buildResourceRequirements(resources *databasesv1alpha1.ResourceRequirements) corev1.ResourceRequirements {
@@ -966,36 +1496,85 @@ func (r *DatabaseReconciler) buildResourceRequirements(resources *databasesv1alp
 
 func (r *DatabaseReconciler) finalizeDatabase(ctx context.Context, database *databasesv1alpha1.Database) {
 	log := log.FromContext(ctx)
-	log.Info("Finalizing database", "name", database.Name)
+	log.Info("Finalizing database")
+	deleteDatabaseMetrics(database)
 	// Perform cleanup if needed
 	// Kubernetes garbage collection will automatically clean up owned resources
 	// (StatefulSets, Deployments, Services) due to controller references
 }
 
-func (r *DatabaseReconciler) updateStatusOnError(ctx context.Context, database *databasesv1alpha1.Database, err error) {
-	database.Status.Phase = databasesv1alpha1.DatabasePhaseFailed
+func (r *DatabaseReconciler) updateStatusOnError(ctx context.Context, database *databasesv1alpha1.Database, reason string, err error) {
+	setPhase(database, databasesv1alpha1.DatabasePhaseFailed, "", time.Now())
 	database.Status.Message = err.Error()
 
-	condition := metav1.Condition{
-		Type:               "Ready",
-		Status:             metav1.ConditionFalse,
-		Reason:             "ReconciliationFailed",
-		Message:            err.Error(),
-		LastTransitionTime: metav1.NewTime(time.Now()),
-		ObservedGeneration: database.Generation,
-	}
-	meta.SetStatusCondition(&database.Status.Conditions, condition)
+	setCondition(database, conditionReady, metav1.ConditionFalse, reason, err.Error())
+	r.event(database, corev1.EventTypeWarning, reason, err.Error())
 
 	_ = r.Status().Update(ctx, database)
 }
 
+// updateStatusOnProvisioningError reports a failed provisioning attempt and
+// schedules the next one, if any
+func (r *DatabaseReconciler) updateStatusOnProvisioningError(ctx context.Context, database *databasesv1alpha1.Database, err *provisioningError) (ctrl.Result, error) {
+	if err.retryAfter == 0 {
+		setPhase(database, databasesv1alpha1.DatabasePhaseFailed, "", time.Now())
+	} else {
+		setPhase(database, databasesv1alpha1.DatabasePhaseCreating, databasesv1alpha1.DatabaseSubPhaseProvisioning, time.Now())
+	}
+	database.Status.Message = err.Error()
+	setCondition(database, conditionReady, metav1.ConditionFalse, conditionProvisioningFailed, err.Error())
+
+	if updateErr := r.Status().Update(ctx, database); updateErr != nil {
+		log.FromContext(ctx).Error(updateErr, "Failed to update Database status", "operation", operationStatus)
+		return ctrl.Result{}, updateErr
+	}
+	return ctrl.Result{RequeueAfter: err.retryAfter}, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *DatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := registerStalledDatabasesCollector(mgr.GetClient()); err != nil {
+		return err
+	}
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("database-controller")
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+	if r.VolumeUsageReader == nil {
+		reader, err := NewKubeletStatsReader(mgr.GetConfig())
+		if err != nil {
+			return err
+		}
+		r.VolumeUsageReader = reader
+	}
+	r.workers = newEngineWorkers(r.Concurrency.EngineWorkers)
+	r.failures = newReconcileFailures()
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &databasesv1alpha1.Database{},
+		secretNamesField, secretNames); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &databasesv1alpha1.Database{},
+		classNameField, databaseClassName); err != nil {
+		return err
+	}
+	managed := builder.WithPredicates(managedByOperator())
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&databasesv1alpha1.Database{}).
-		Owns(&appsv1.StatefulSet{}).
-		Owns(&appsv1.Deployment{}).
-		Owns(&corev1.Service{}).
+		WithOptions(r.Concurrency.controllerOptions()).
+		For(&databasesv1alpha1.Database{}, builder.WithPredicates(databaseChanged())).
+		Owns(&appsv1.StatefulSet{}, managed).
+		Owns(&appsv1.Deployment{}, managed).
+		Owns(&corev1.Service{}, managed).
+		Owns(&batchv1.CronJob{}, managed).
+		Owns(&batchv1.Job{}, managed).
+		Owns(&networkingv1.NetworkPolicy{}, managed).
+		Owns(&networkingv1.Ingress{}, managed).
+		Owns(&corev1.ServiceAccount{}, managed).
+		Owns(&corev1.ConfigMap{}, managed).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.databasesForSecret)).
+		Watches(&databasesv1alpha1.DatabaseClass{}, handler.EnqueueRequestsFromMapFunc(r.databasesForClass)).
+		Watches(&databasesv1alpha1.DatabaseVersion{}, handler.EnqueueRequestsFromMapFunc(r.databasesForVersion)).
 		Named("database").
 		Complete(r)
 }
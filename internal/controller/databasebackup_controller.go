@@ -0,0 +1,431 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// backupPendingRecheckInterval is how often a backup waiting for its Database is retried
+	backupPendingRecheckInterval = 30 * time.Second
+
+	// databaseBackupFinalizer deletes the backup file before the DatabaseBackup
+	databaseBackupFinalizer = "databases.database-operator.io/backup-finalizer"
+)
+
+// DatabaseBackupReconciler reconciles a DatabaseBackup object
+type DatabaseBackupReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	ReconcilerOptions
+	// Recorder records Events on DatabaseBackups and their Databases
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databasebackups,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databasebackups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databasebackups/finalizers,verbs=update
+
+// Reconcile runs the backup Job of a DatabaseBackup, or takes VolumeSnapshots
+// for the Snapshot method, and records its outcome. Finished backups are never
+// run again.
+func (r *DatabaseBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	backup := &databasesv1alpha1.DatabaseBackup{}
+	if err := r.Get(ctx, req.NamespacedName, backup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !backup.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.deleteBackupFile(ctx, backup)
+	}
+	if backupFinished(backup) && !verificationPending(backup) {
+		return ctrl.Result{}, nil
+	}
+
+	originalStatus := backup.Status.DeepCopy()
+	result, err := r.reconcileDatabaseBackup(ctx, backup)
+	if err != nil {
+		log.Error(err, "Failed to reconcile DatabaseBackup")
+	}
+
+	if !equality.Semantic.DeepEqual(originalStatus, &backup.Status) {
+		if err := r.Status().Update(ctx, backup); err != nil {
+			log.Error(err, "Failed to update DatabaseBackup status")
+			return ctrl.Result{}, err
+		}
+		if err := r.recordBackupOperation(ctx, originalStatus, backup); err != nil {
+			log.Error(err, "Failed to record backup in the Database history")
+		}
+	}
+	return result, err
+}
+
+// recordBackupOperation adds a backup that just finished, or whose verification
+// just finished, to the operation history of its Database and records it as an
+// Event on both
+func (r *DatabaseBackupReconciler) recordBackupOperation(ctx context.Context, original *databasesv1alpha1.DatabaseBackupStatus, backup *databasesv1alpha1.DatabaseBackup) error {
+	key := types.NamespacedName{Name: backup.Spec.DatabaseRef.Name, Namespace: backup.Namespace}
+	status := backup.Status
+
+	if original.Phase != status.Phase && backupFinished(backup) {
+		if status.Phase == databasesv1alpha1.DatabaseBackupPhaseFailed {
+			detail := fmt.Sprintf("Backup %s failed: %s", backup.Name, status.Message)
+			r.backupEvent(ctx, backup, key, corev1.EventTypeWarning, "BackupFailed", detail)
+			return recordDatabaseOperation(ctx, r.Client, key, recordBackup, databasesv1alpha1.OperationFailed, detail)
+		}
+		detail := fmt.Sprintf("Backup %s stored at %s", backup.Name, status.Location)
+		r.backupEvent(ctx, backup, key, corev1.EventTypeNormal, "BackupCompleted", detail)
+		return recordDatabaseOperation(ctx, r.Client, key, recordBackup, databasesv1alpha1.OperationSucceeded, detail)
+	}
+
+	if original.Verification != nil && verificationPending(&databasesv1alpha1.DatabaseBackup{Status: *original}) &&
+		status.Verification != nil && !verificationPending(backup) {
+		outcome := databasesv1alpha1.OperationSucceeded
+		if status.Verification.Result == databasesv1alpha1.BackupVerificationInvalid {
+			outcome = databasesv1alpha1.OperationFailed
+		}
+		detail := fmt.Sprintf("Backup %s is %s", backup.Name, strings.ToLower(string(status.Verification.Result)))
+		if status.Verification.Message != "" {
+			detail += ": " + status.Verification.Message
+		}
+		if outcome == databasesv1alpha1.OperationFailed {
+			r.backupEvent(ctx, backup, key, corev1.EventTypeWarning, "BackupVerificationFailed", detail)
+		} else {
+			r.backupEvent(ctx, backup, key, corev1.EventTypeNormal, "BackupVerified", detail)
+		}
+		return recordDatabaseOperation(ctx, r.Client, key, recordBackupVerification, outcome, detail)
+	}
+	return nil
+}
+
+// backupEvent records an Event on the DatabaseBackup, and on its Database when
+// it still exists
+func (r *DatabaseBackupReconciler) backupEvent(ctx context.Context, backup *databasesv1alpha1.DatabaseBackup, key types.NamespacedName, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	recordEvent(r.Recorder, backup, eventType, reason, message)
+	database := &databasesv1alpha1.Database{}
+	if err := r.Get(ctx, key, database); err == nil {
+		recordEvent(r.Recorder, database, eventType, reason, message)
+	}
+}
+
+func (r *DatabaseBackupReconciler) reconcileDatabaseBackup(ctx context.Context, backup *databasesv1alpha1.DatabaseBackup) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	database := &databasesv1alpha1.Database{}
+	key := types.NamespacedName{Name: backup.Spec.DatabaseRef.Name, Namespace: backup.Namespace}
+	if err := r.Get(ctx, key, database); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		message := fmt.Sprintf("Database %q not found", key.Name)
+		if backupFinished(backup) {
+			backup.Status.Verification.Message = message
+		} else {
+			backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhasePending
+			backup.Status.Message = message
+		}
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+	ctx = withDatabaseLogger(ctx, database)
+	// Backups follow the defaults of the DatabaseClass like the Database does
+	if _, err := applyDatabaseClass(ctx, r.Client, database); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if backupFinished(backup) {
+		return r.reconcileBackupVerification(ctx, database, backup)
+	}
+
+	if database.Spec.TargetCluster != nil {
+		backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseFailed
+		backup.Status.Message = "Databases in a target cluster do not support DatabaseBackups, use spec.backup"
+		return ctrl.Result{}, nil
+	}
+
+	snapshot := databaseBackupMethod(database, backup) == databasesv1alpha1.BackupMethodSnapshot
+	if _, ok := backupScripts[database.Spec.Type]; !ok && !snapshot {
+		backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseFailed
+		backup.Status.Message = fmt.Sprintf("%s does not support %s backups", database.Spec.Type, backupMethodDump)
+		return ctrl.Result{}, nil
+	}
+
+	// Label the backup so the backups of a Database can be listed, and make sure
+	// its file is deleted with it
+	if backup.Labels[databasesv1alpha1.DatabaseLabel] != database.Name ||
+		!controllerutil.ContainsFinalizer(backup, databaseBackupFinalizer) {
+		if backup.Labels == nil {
+			backup.Labels = map[string]string{}
+		}
+		backup.Labels[databasesv1alpha1.DatabaseLabel] = database.Name
+		controllerutil.AddFinalizer(backup, databaseBackupFinalizer)
+		status := backup.Status
+		if err := r.Update(ctx, backup); err != nil {
+			return ctrl.Result{}, err
+		}
+		backup.Status = status
+	}
+
+	if snapshot {
+		return r.reconcileSnapshotBackup(ctx, database, backup)
+	}
+
+	builder := r.databaseReconciler(r.Client, r.Scheme)
+	if err := builder.reconcileBackupVolume(ctx, database); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	job := &batchv1.Job{}
+	jobName := backup.Name + "-" + backupComponent
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: backup.Namespace}, job)
+	if errors.IsNotFound(err) {
+		backup.Status.KMSKeyID = ""
+		if encryption := backupEncryption(database); encryption != nil {
+			backup.Status.KMSKeyID = encryption.KMSKeyID
+		}
+		job = builder.createDatabaseBackupJob(database, backup)
+		if err := controllerutil.SetControllerReference(backup, job, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		log.Info("Creating backup Job", "name", job.Name)
+		if err := r.Create(ctx, job); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		now := metav1.Now()
+		backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseRunning
+		backup.Status.JobName = job.Name
+		backup.Status.StartTime = &now
+		backup.Status.Message = ""
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	backup.Status.JobName = job.Name
+	finished, succeeded := jobFinished(job)
+	if !finished {
+		backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseRunning
+		return ctrl.Result{}, nil
+	}
+
+	now := metav1.Now()
+	backup.Status.CompletionTime = &now
+	if !succeeded {
+		backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseFailed
+		backup.Status.Message = fmt.Sprintf("Backup Job %s failed", job.Name)
+		return ctrl.Result{}, nil
+	}
+
+	backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseCompleted
+	backup.Status.Location = fmt.Sprintf("pvc://%s/%s", backupClaimName(database), databaseBackupFile(database, backup))
+	backup.Status.Message = ""
+	size, err := backupSize(ctx, r.Client, job)
+	if err != nil {
+		log.Error(err, "Failed to read backup size", "job", job.Name)
+	}
+	backup.Status.Size = size
+
+	if backupVerificationEnabled(database) {
+		backup.Status.Verification = &databasesv1alpha1.BackupVerificationStatus{
+			Result: databasesv1alpha1.BackupVerificationPending,
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// deleteBackupFile runs a Job deleting the file of a deleted DatabaseBackup, and
+// releases the finalizer once it finished. The file of a backup whose Database is
+// gone is kept with the backup volume.
+func (r *DatabaseBackupReconciler) deleteBackupFile(ctx context.Context, backup *databasesv1alpha1.DatabaseBackup) error {
+	if !controllerutil.ContainsFinalizer(backup, databaseBackupFinalizer) {
+		return nil
+	}
+
+	done, err := r.runBackupCleanup(ctx, backup)
+	if err != nil || !done {
+		return err
+	}
+
+	controllerutil.RemoveFinalizer(backup, databaseBackupFinalizer)
+	return r.Update(ctx, backup)
+}
+
+// runBackupCleanup reports whether the file of the backup is gone or must be kept
+func (r *DatabaseBackupReconciler) runBackupCleanup(ctx context.Context, backup *databasesv1alpha1.DatabaseBackup) (bool, error) {
+	log := log.FromContext(ctx)
+	// VolumeSnapshots are owned by the backup and garbage collected with it
+	if backup.Status.Location == "" || len(backup.Status.Snapshots) > 0 {
+		return true, nil
+	}
+
+	database := &databasesv1alpha1.Database{}
+	key := types.NamespacedName{Name: backup.Spec.DatabaseRef.Name, Namespace: backup.Namespace}
+	if err := r.Get(ctx, key, database); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Keeping backup file of deleted Database", "location", backup.Status.Location)
+			return true, nil
+		}
+		return false, err
+	}
+	if _, err := applyDatabaseClass(ctx, r.Client, database); err != nil {
+		return false, err
+	}
+
+	job := &batchv1.Job{}
+	jobName := backup.Name + "-" + backupCleanupComponent
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: backup.Namespace}, job)
+	if errors.IsNotFound(err) {
+		job, err = r.databaseReconciler(r.Client, r.Scheme).createBackupCleanupJob(database, backup)
+		if err != nil {
+			log.Error(err, "Cannot delete backup file", "location", backup.Status.Location)
+			return true, nil
+		}
+		if err := controllerutil.SetControllerReference(backup, job, r.Scheme); err != nil {
+			return false, err
+		}
+
+		log.Info("Creating backup cleanup Job", "name", job.Name)
+		return false, r.Create(ctx, job)
+	} else if err != nil {
+		return false, err
+	}
+
+	finished, succeeded := jobFinished(job)
+	if finished && !succeeded {
+		log.Info("Failed to delete backup file, keeping it", "location", backup.Status.Location)
+	}
+	return finished, nil
+}
+
+// backupSize reads the size the backup pod reported as its termination message
+func backupSize(ctx context.Context, reader client.Reader, job *batchv1.Job) (*resource.Quantity, error) {
+	pods := &corev1.PodList{}
+	if err := reader.List(ctx, pods, client.InNamespace(job.Namespace),
+		client.MatchingLabels{batchv1.JobNameLabel: job.Name}); err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			terminated := status.State.Terminated
+			if terminated == nil || terminated.ExitCode != 0 {
+				continue
+			}
+			bytes, err := strconv.ParseInt(strings.TrimSpace(terminated.Message), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid backup size %q: %w", terminated.Message, err)
+			}
+			return resource.NewQuantity(bytes, resource.BinarySI), nil
+		}
+	}
+	return nil, nil
+}
+
+// createDatabaseBackupJob builds the Job dumping the database to the backup volume
+// under the name of the DatabaseBackup
+func (r *DatabaseReconciler) createDatabaseBackupJob(database *databasesv1alpha1.Database, backup *databasesv1alpha1.DatabaseBackup) *batchv1.Job {
+	file := databaseBackupFile(database, backup)
+	job := r.createAdminJob(database, backupComponent, backupScript(database, file), nil)
+	job.Name = backup.Name + "-" + backupComponent
+	// The Job is owned by the DatabaseBackup and kept until it is deleted
+	job.Spec.TTLSecondsAfterFinished = nil
+	r.mountBackupVolumes(database, backupClaimName(database), &job.Spec.Template.Spec)
+	if backup.Status.KMSKeyID != "" {
+		r.encryptBackupPod(database, &job.Spec.Template.Spec, file, "")
+	}
+	return job
+}
+
+// databaseBackupFile is the file name of the backup taken for a DatabaseBackup,
+// encrypted when status.kmsKeyId is set
+func databaseBackupFile(database *databasesv1alpha1.Database, backup *databasesv1alpha1.DatabaseBackup) string {
+	file := backup.Name + "." + backupExtensions[database.Spec.Type]
+	if backup.Status.KMSKeyID != "" {
+		file += backupEncryptedExtension
+	}
+	return file
+}
+
+// databaseBackupMethod returns the method a DatabaseBackup is taken with
+func databaseBackupMethod(database *databasesv1alpha1.Database, backup *databasesv1alpha1.DatabaseBackup) databasesv1alpha1.BackupMethod {
+	if backup.Spec.Method != "" {
+		return backup.Spec.Method
+	}
+	return backupMethod(database)
+}
+
+// backupFinished reports whether a backup reached a final phase
+func backupFinished(backup *databasesv1alpha1.DatabaseBackup) bool {
+	return backup.Status.Phase == databasesv1alpha1.DatabaseBackupPhaseCompleted ||
+		backup.Status.Phase == databasesv1alpha1.DatabaseBackupPhaseFailed
+}
+
+// listDatabaseBackups returns the DatabaseBackups of a Database, oldest first
+func listDatabaseBackups(ctx context.Context, reader client.Reader, database *databasesv1alpha1.Database) ([]databasesv1alpha1.DatabaseBackup, error) {
+	backups := &databasesv1alpha1.DatabaseBackupList{}
+	if err := reader.List(ctx, backups, client.InNamespace(database.Namespace),
+		client.MatchingLabels{databasesv1alpha1.DatabaseLabel: database.Name}); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(backups.Items, func(a, b databasesv1alpha1.DatabaseBackup) int {
+		if c := a.CreationTimestamp.Compare(b.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return backups.Items, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DatabaseBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("databasebackup-controller")
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasesv1alpha1.DatabaseBackup{}).
+		Owns(&batchv1.Job{}).
+		Named("databasebackup").
+		Complete(r)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("DatabaseBackup Controller", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseBackupReconciler
+		key        types.NamespacedName
+	)
+
+	newBackup := func(name, database string, created time.Time) *databasesv1alpha1.DatabaseBackup {
+		return &databasesv1alpha1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", CreationTimestamp: metav1.NewTime(created)},
+			Spec: databasesv1alpha1.DatabaseBackupSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: database},
+			},
+		}
+	}
+
+	reconcile := func() *databasesv1alpha1.DatabaseBackup {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		backup := &databasesv1alpha1.DatabaseBackup{}
+		Expect(reconciler.Get(ctx, key, backup)).To(Succeed())
+		return backup
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		key = types.NamespacedName{Name: "nightly", Namespace: "shop"}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.DatabaseBackup{}).
+			WithObjects(
+				&databasesv1alpha1.Database{
+					ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+					Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+				},
+				newBackup("nightly", "orders", time.Now()),
+			).Build()
+		reconciler = &DatabaseBackupReconciler{Client: c, Scheme: scheme}
+	})
+
+	It("should run a backup Job and record its size and location", func() {
+		backup := reconcile()
+		Expect(backup.Status.Phase).To(Equal(databasesv1alpha1.DatabaseBackupPhaseRunning))
+		Expect(backup.Status.StartTime).NotTo(BeNil())
+		Expect(backup.Labels).To(HaveKeyWithValue(databasesv1alpha1.DatabaseLabel, "orders"))
+
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "nightly-backup", Namespace: "shop"}, job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring(`name="nightly.dump"`))
+		Expect(job.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("orders-backups"))
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders-backups", Namespace: "shop"},
+			&corev1.PersistentVolumeClaim{})).To(Succeed())
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		Expect(reconciler.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "nightly-backup-x7k2p", Namespace: "shop",
+				Labels: map[string]string{batchv1.JobNameLabel: "nightly-backup"},
+			},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "2048\n"}},
+			}}},
+		})).To(Succeed())
+
+		backup = reconcile()
+		Expect(backup.Status.Phase).To(Equal(databasesv1alpha1.DatabaseBackupPhaseCompleted))
+		Expect(backup.Status.Location).To(Equal("pvc://orders-backups/nightly.dump"))
+		Expect(backup.Status.Size.Value()).To(Equal(int64(2048)))
+		Expect(backup.Status.CompletionTime).NotTo(BeNil())
+	})
+
+	It("should verify completed backups when the Database asks for it", func() {
+		database := &databasesv1alpha1.Database{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders", Namespace: "shop"}, database)).To(Succeed())
+		database.Spec.Backup = &databasesv1alpha1.BackupSpec{Verify: true}
+		Expect(reconciler.Update(ctx, database)).To(Succeed())
+
+		finish := func(name string, condition batchv1.JobConditionType, message string) {
+			job := &batchv1.Job{}
+			Expect(reconciler.Get(ctx, types.NamespacedName{Name: name, Namespace: "shop"}, job)).To(Succeed())
+			job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+			Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+			Expect(reconciler.Create(ctx, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name + "-x7k2p", Namespace: "shop",
+					Labels: map[string]string{batchv1.JobNameLabel: name},
+				},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}},
+				}}},
+			})).To(Succeed())
+		}
+
+		reconcile()
+		finish("nightly-backup", batchv1.JobComplete, "2048")
+		backup := reconcile()
+		Expect(backup.Status.Phase).To(Equal(databasesv1alpha1.DatabaseBackupPhaseCompleted))
+		Expect(backup.Status.Verification.Result).To(Equal(databasesv1alpha1.BackupVerificationPending))
+
+		backup = reconcile()
+		Expect(backup.Status.Verification.Result).To(Equal(databasesv1alpha1.BackupVerificationRunning))
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "nightly-backup-verify", Namespace: "shop"}, job)).To(Succeed())
+		container := job.Spec.Template.Spec.Containers[0]
+		Expect(container.Command[2]).To(ContainSubstring(`BACKUP_FILE="/backups/nightly.dump"`))
+		Expect(container.Command[2]).To(ContainSubstring("pg_restore"))
+		Expect(container.Env).To(BeEmpty())
+		Expect(*container.SecurityContext.RunAsUser).To(Equal(postgresUID))
+		Expect(*job.Spec.BackoffLimit).To(BeZero())
+
+		finish("nightly-backup-verify", batchv1.JobFailed, "pg_restore: error: could not read input file\n")
+		backup = reconcile()
+		Expect(backup.Status.Verification.Result).To(Equal(databasesv1alpha1.BackupVerificationInvalid))
+		Expect(backup.Status.Verification.Message).To(ContainSubstring("could not read input file"))
+		Expect(backup.Status.Verification.CompletionTime).NotTo(BeNil())
+	})
+
+	It("should wait for a missing Database", func() {
+		key.Name = "orphan"
+		Expect(reconciler.Create(ctx, newBackup("orphan", "missing", time.Now()))).To(Succeed())
+
+		backup := reconcile()
+		Expect(backup.Status.Phase).To(Equal(databasesv1alpha1.DatabaseBackupPhasePending))
+		Expect(backup.Status.Message).To(ContainSubstring(`"missing" not found`))
+	})
+
+	It("should list the backups of a Database oldest first", func() {
+		now := time.Now()
+		for name, created := range map[string]time.Time{"b": now.Add(-time.Hour), "a": now.Add(-2 * time.Hour)} {
+			backup := newBackup(name, "orders", created)
+			backup.Labels = map[string]string{databasesv1alpha1.DatabaseLabel: "orders"}
+			Expect(reconciler.Create(ctx, backup)).To(Succeed())
+		}
+
+		database := &databasesv1alpha1.Database{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"}}
+		backups, err := listDatabaseBackups(ctx, reconciler, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backups).To(HaveLen(2))
+		Expect(backups[0].Name).To(Equal("a"))
+		Expect(backups[1].Name).To(Equal("b"))
+	})
+})
@@ -0,0 +1,520 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// migrationTargetAnnotation records on the Job the script and checksum it
+	// applies, or the image it lists the scripts of
+	migrationTargetAnnotation = "databases.database-operator.io/migration-target"
+	listScriptsTarget         = "list "
+
+	migrationComponentPrefix = "migration-"
+	migrationScriptsVolume   = "migrations"
+	migrationScriptsDir      = "/migrations"
+	migrationCopyContainer   = "scripts"
+)
+
+// migrationScriptName matches the names of scripts that are safe to pass to a shell
+var migrationScriptName = regexp.MustCompile(`^[a-zA-Z0-9][-._a-zA-Z0-9]*$`)
+
+// DatabaseMigrationReconciler reconciles a DatabaseMigration object
+type DatabaseMigrationReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	ReconcilerOptions
+	// Proxy is the HTTP proxy of generated Jobs
+	Proxy ProxyConfig
+}
+
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databasemigrations,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databasemigrations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databasemigrations/finalizers,verbs=update
+
+// Reconcile applies the pending migration scripts to the Database one Job at a
+// time, in the order of their names, once the Database is ready
+func (r *DatabaseMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	migration := &databasesv1alpha1.DatabaseMigration{}
+	if err := r.Get(ctx, req.NamespacedName, migration); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !migration.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	database := &databasesv1alpha1.Database{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migration.Spec.DatabaseRef.Name, Namespace: migration.Namespace}, database); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		database = nil
+	} else {
+		ctx = withDatabaseLogger(ctx, database)
+	}
+
+	originalStatus := migration.Status.DeepCopy()
+	result, err := r.reconcileDatabaseMigration(ctx, migration, database)
+	if err != nil {
+		log.Error(err, "Failed to reconcile DatabaseMigration")
+	}
+	if !equality.Semantic.DeepEqual(originalStatus, &migration.Status) {
+		if err := r.Status().Update(ctx, migration); err != nil {
+			log.Error(err, "Failed to update DatabaseMigration status")
+			return ctrl.Result{}, err
+		}
+	}
+	return result, err
+}
+
+func (r *DatabaseMigrationReconciler) reconcileDatabaseMigration(ctx context.Context, migration *databasesv1alpha1.DatabaseMigration, database *databasesv1alpha1.Database) (ctrl.Result, error) {
+	migration.Status.ObservedGeneration = migration.Generation
+
+	if database == nil {
+		setMigrationPending(migration, fmt.Sprintf("Database %q not found", migration.Spec.DatabaseRef.Name))
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+	if err := validateDatabaseMigration(database); err != nil {
+		failMigration(migration, err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	job, err := r.migrationJob(ctx, migration, database)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if job != nil {
+		finished, succeeded := jobFinished(job)
+		if !finished {
+			return ctrl.Result{}, nil
+		}
+		// Record what a successful Job did, whether or not the spec still
+		// asks for it, then remove the Job; its deletion triggers the next step
+		if succeeded {
+			if err := r.recordMigrationJob(ctx, migration, job); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, r.deleteMigrationJob(ctx, job)
+		}
+	}
+
+	// Scripts only run against a ready Database; a running Job is left to finish
+	if database.Status.Phase != databasesv1alpha1.DatabasePhaseReady {
+		setMigrationPending(migration, fmt.Sprintf("Waiting for Database %s to be Ready", database.Name))
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+
+	target, err := r.nextMigrationTarget(ctx, migration, database)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// A failed Job is kept until the script changes or the Job expires, which
+	// retries it
+	if job != nil && job.Annotations[migrationTargetAnnotation] != target {
+		return ctrl.Result{}, r.deleteMigrationJob(ctx, job)
+	}
+	if target == "" {
+		return ctrl.Result{}, nil
+	}
+	if job != nil {
+		if image, listing := strings.CutPrefix(target, listScriptsTarget); listing {
+			failMigration(migration, fmt.Sprintf("Failed to list the scripts of image %s, see the logs of Job %s", image, job.Name))
+		} else {
+			script := migrationScript(migration, target)
+			script.Phase = databasesv1alpha1.MigrationScriptPhaseFailed
+			failMigration(migration, fmt.Sprintf("Failed to apply migration %s, see the logs of Job %s", script.Name, job.Name))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	job = r.createMigrationJob(migration, database, target)
+	if err := controllerutil.SetControllerReference(migration, job, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	migration.Status.Phase = databasesv1alpha1.DatabaseMigrationPhaseRunning
+	if image, listing := strings.CutPrefix(target, listScriptsTarget); listing {
+		log.FromContext(ctx).Info("Listing migration scripts", "image", image)
+		migration.Status.Message = fmt.Sprintf("Listing the scripts of image %s in Job %s", image, job.Name)
+	} else {
+		script := migrationScript(migration, target)
+		log.FromContext(ctx).Info("Applying migration script", "script", script.Name, "checksum", script.Checksum)
+		script.Phase = databasesv1alpha1.MigrationScriptPhaseRunning
+		script.Job = job.Name
+		migration.Status.Message = fmt.Sprintf("Applying migration %s in Job %s", script.Name, job.Name)
+	}
+	return ctrl.Result{}, r.Create(ctx, job)
+}
+
+// nextMigrationTarget merges the scripts of the source into the status and
+// returns what the next Job does: list the scripts of the image, or apply the
+// first script not applied yet. It returns "" when there is nothing to do,
+// with the status telling why.
+func (r *DatabaseMigrationReconciler) nextMigrationTarget(ctx context.Context, migration *databasesv1alpha1.DatabaseMigration, database *databasesv1alpha1.Database) (string, error) {
+	source := migration.Spec.Source
+	if source.Image != nil && migration.Status.ListedImage != source.Image.Image {
+		return listScriptsTarget + source.Image.Image, nil
+	}
+
+	if source.ConfigMap != nil {
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Name: source.ConfigMap.Name, Namespace: migration.Namespace}, configMap); err != nil {
+			if !errors.IsNotFound(err) {
+				return "", err
+			}
+			setMigrationPending(migration, fmt.Sprintf("ConfigMap %q not found", source.ConfigMap.Name))
+			return "", nil
+		}
+		// Other keys, e.g. a README, are not scripts
+		scripts := map[string]string{}
+		for name, content := range configMap.Data {
+			if strings.HasSuffix(name, migrationScriptExtension(database)) && migrationScriptName.MatchString(name) {
+				scripts[name] = migrationChecksum([]byte(content))
+			}
+		}
+		migrations, err := mergeMigrationScripts(migration.Status.Migrations, scripts)
+		if err != nil {
+			failMigration(migration, err.Error())
+			return "", nil
+		}
+		migration.Status.Migrations = migrations
+	}
+
+	for _, script := range migration.Status.Migrations {
+		if script.Phase != databasesv1alpha1.MigrationScriptPhaseApplied {
+			return script.Name + "@" + script.Checksum, nil
+		}
+	}
+	migration.Status.Phase = databasesv1alpha1.DatabaseMigrationPhaseSucceeded
+	migration.Status.Message = fmt.Sprintf("Applied %d migrations", len(migration.Status.Migrations))
+	return "", nil
+}
+
+// recordMigrationJob records the result of a successful Job: the scripts the
+// image lists, or the script applied with the checksum it had
+func (r *DatabaseMigrationReconciler) recordMigrationJob(ctx context.Context, migration *databasesv1alpha1.DatabaseMigration, job *batchv1.Job) error {
+	target := job.Annotations[migrationTargetAnnotation]
+	if image, listing := strings.CutPrefix(target, listScriptsTarget); listing {
+		message, err := r.jobTerminationMessage(ctx, job)
+		if err != nil {
+			return err
+		}
+		scripts, err := parseMigrationScripts(message)
+		if err == nil {
+			migration.Status.Migrations, err = mergeMigrationScripts(migration.Status.Migrations, scripts)
+		}
+		if err != nil {
+			failMigration(migration, fmt.Sprintf("Image %s: %s", image, err))
+			return nil
+		}
+		migration.Status.ListedImage = image
+		log.FromContext(ctx).Info("Listed migration scripts", "image", image, "scripts", len(scripts))
+		return nil
+	}
+
+	script := migrationScript(migration, target)
+	if script == nil {
+		return nil
+	}
+	script.Checksum = target[strings.LastIndex(target, "@")+1:]
+	script.Phase = databasesv1alpha1.MigrationScriptPhaseApplied
+	script.AppliedAt = &metav1.Time{Time: time.Now()}
+	log.FromContext(ctx).Info("Applied migration script", "script", script.Name, "checksum", script.Checksum)
+	return nil
+}
+
+// mergeMigrationScripts merges the scripts of the source, by name and
+// checksum, into the recorded ones. Applied scripts stay recorded when they
+// are removed from the source, but must not change, nor be preceded by a
+// script that was not applied.
+func mergeMigrationScripts(recorded []databasesv1alpha1.MigrationScriptStatus, scripts map[string]string) ([]databasesv1alpha1.MigrationScriptStatus, error) {
+	merged := []databasesv1alpha1.MigrationScriptStatus{}
+	for _, script := range recorded {
+		checksum, found := scripts[script.Name]
+		switch {
+		case script.Phase == databasesv1alpha1.MigrationScriptPhaseApplied:
+			if found && checksum != script.Checksum {
+				return nil, fmt.Errorf("migration %s was applied with checksum %s and changed to %s", script.Name, script.Checksum, checksum)
+			}
+		case !found:
+			continue
+		case checksum != script.Checksum:
+			script = databasesv1alpha1.MigrationScriptStatus{Name: script.Name, Checksum: checksum, Phase: databasesv1alpha1.MigrationScriptPhasePending}
+		}
+		merged = append(merged, script)
+	}
+	for name, checksum := range scripts {
+		if !slices.ContainsFunc(merged, func(script databasesv1alpha1.MigrationScriptStatus) bool { return script.Name == name }) {
+			merged = append(merged, databasesv1alpha1.MigrationScriptStatus{Name: name, Checksum: checksum, Phase: databasesv1alpha1.MigrationScriptPhasePending})
+		}
+	}
+	slices.SortFunc(merged, func(a, b databasesv1alpha1.MigrationScriptStatus) int { return strings.Compare(a.Name, b.Name) })
+
+	pending := ""
+	for _, script := range merged {
+		if script.Phase != databasesv1alpha1.MigrationScriptPhaseApplied {
+			if pending == "" {
+				pending = script.Name
+			}
+		} else if pending != "" {
+			return nil, fmt.Errorf("migration %s sorts before the applied migration %s", pending, script.Name)
+		}
+	}
+	return merged, nil
+}
+
+// parseMigrationScripts parses the checksum and name of each script listed by
+// the Job of an image source
+func parseMigrationScripts(message string) (map[string]string, error) {
+	scripts := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(message), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 || !migrationScriptName.MatchString(fields[1]) {
+			return nil, fmt.Errorf("unsupported script name in %q", line)
+		}
+		scripts[fields[1]] = fields[0]
+	}
+	return scripts, nil
+}
+
+// migrationScriptExtension returns the file extension of the scripts of an engine
+func migrationScriptExtension(database *databasesv1alpha1.Database) string {
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB {
+		return ".js"
+	}
+	return ".sql"
+}
+
+// migrationChecksum identifies the content of a script
+func migrationChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// migrationScript returns the status of the script a Job target applies
+func migrationScript(migration *databasesv1alpha1.DatabaseMigration, target string) *databasesv1alpha1.MigrationScriptStatus {
+	name := target[:strings.LastIndex(target, "@")]
+	for i := range migration.Status.Migrations {
+		if migration.Status.Migrations[i].Name == name {
+			return &migration.Status.Migrations[i]
+		}
+	}
+	return nil
+}
+
+// validateDatabaseMigration checks that the engine runs migration scripts
+func validateDatabaseMigration(database *databasesv1alpha1.Database) error {
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL, databasesv1alpha1.DatabaseTypeMongoDB:
+	default:
+		return fmt.Errorf("%s does not support database migrations", database.Spec.Type)
+	}
+	if database.Spec.TargetCluster != nil {
+		return fmt.Errorf("targetCluster does not support database migrations")
+	}
+	return nil
+}
+
+// migrationJob returns the Job listing or applying the scripts, or nil
+func (r *DatabaseMigrationReconciler) migrationJob(ctx context.Context, migration *databasesv1alpha1.DatabaseMigration, database *databasesv1alpha1.Database) (*batchv1.Job, error) {
+	job := &batchv1.Job{}
+	name := database.Name + "-" + migrationComponent(migration)
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: migration.Namespace}, job); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return job, nil
+}
+
+func (r *DatabaseMigrationReconciler) deleteMigrationJob(ctx context.Context, job *batchv1.Job) error {
+	err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	return client.IgnoreNotFound(err)
+}
+
+// jobTerminationMessage returns the termination message of the container of a
+// succeeded Job
+func (r *DatabaseMigrationReconciler) jobTerminationMessage(ctx context.Context, job *batchv1.Job) (string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace),
+		client.MatchingLabels{"batch.kubernetes.io/job-name": job.Name}); err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.State.Terminated != nil {
+				return cs.State.Terminated.Message, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// migrationComponent names the Jobs of a DatabaseMigration
+func migrationComponent(migration *databasesv1alpha1.DatabaseMigration) string {
+	return migrationComponentPrefix + migration.Name
+}
+
+// createMigrationJob builds the Job of a target. Listing runs in the image of
+// the scripts; applying a script runs the engine CLI with the script mounted
+// from the ConfigMap or copied from the image by an init container.
+func (r *DatabaseMigrationReconciler) createMigrationJob(migration *databasesv1alpha1.DatabaseMigration, database *databasesv1alpha1.Database, target string) *batchv1.Job {
+	reconciler := r.databaseReconciler(r.Client, r.Scheme)
+	component := migrationComponent(migration)
+	source := migration.Spec.Source
+
+	if image, listing := strings.CutPrefix(target, listScriptsTarget); listing {
+		script := fmt.Sprintf(`cd %q && for f in *%s; do if [ -f "$f" ]; then sha256sum "$f" | cut -c1-16,65-; fi; done > /dev/termination-log`,
+			source.Image.Path, migrationScriptExtension(database))
+		job := reconciler.createAdminJob(database, component, script, nil)
+		job.Spec.Template = reconciler.adminPodTemplate(database, component, image, script, nil)
+		job.Annotations = map[string]string{migrationTargetAnnotation: target}
+		return job
+	}
+
+	name := migrationScript(migration, target).Name
+	file := migrationScriptsDir + "/" + name
+	var command string
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB {
+		command = fmt.Sprintf(`mongosh --host "$DB_HOST" -u "$MONGO_USERNAME" -p "$MONGO_PASSWORD" --authenticationDatabase admin --quiet %s"%s"`,
+			optionalArgument("", migration.Spec.Database), file)
+	} else {
+		command = fmt.Sprintf(`psql -h "$DB_HOST" -v ON_ERROR_STOP=1 --single-transaction %s-f "%s"`,
+			optionalArgument("-d ", migration.Spec.Database), file)
+	}
+
+	job := reconciler.createAdminJob(database, component, command, nil)
+	job.Annotations = map[string]string{migrationTargetAnnotation: target}
+	podSpec := &job.Spec.Template.Spec
+	mount := corev1.VolumeMount{Name: migrationScriptsVolume, MountPath: migrationScriptsDir, ReadOnly: true}
+	if source.ConfigMap != nil {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: migrationScriptsVolume,
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: *source.ConfigMap,
+				Items:                []corev1.KeyToPath{{Key: name, Path: name}},
+			}},
+		})
+	} else {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name:         migrationScriptsVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+		podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+			Name:         migrationCopyContainer,
+			Image:        source.Image.Image,
+			Command:      []string{"/bin/sh", "-c", fmt.Sprintf(`cp "%s/%s" %s/`, source.Image.Path, name, migrationScriptsDir)},
+			VolumeMounts: []corev1.VolumeMount{{Name: migrationScriptsVolume, MountPath: migrationScriptsDir}},
+		})
+		reconciler.restrictContainer(database, &podSpec.InitContainers[len(podSpec.InitContainers)-1])
+	}
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, mount)
+	return job
+}
+
+// optionalArgument renders a quoted command line argument followed by a space,
+// or nothing for an empty value
+func optionalArgument(flag, value string) string {
+	if value == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s%q ", flag, value)
+}
+
+func setMigrationPending(migration *databasesv1alpha1.DatabaseMigration, message string) {
+	migration.Status.Phase = databasesv1alpha1.DatabaseMigrationPhasePending
+	migration.Status.Message = message
+}
+
+func failMigration(migration *databasesv1alpha1.DatabaseMigration, message string) {
+	migration.Status.Phase = databasesv1alpha1.DatabaseMigrationPhaseFailed
+	migration.Status.Message = message
+}
+
+// migrationsOfDatabase enqueues the DatabaseMigrations of a Database, so they
+// run once it becomes ready
+func (r *DatabaseMigrationReconciler) migrationsOfDatabase(ctx context.Context, database client.Object) []reconcile.Request {
+	return r.migrationsReferencing(ctx, database, func(migration *databasesv1alpha1.DatabaseMigration) bool {
+		return migration.Spec.DatabaseRef.Name == database.GetName()
+	})
+}
+
+// migrationsOfConfigMap enqueues the DatabaseMigrations reading their scripts
+// from a ConfigMap, so added scripts are applied
+func (r *DatabaseMigrationReconciler) migrationsOfConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	return r.migrationsReferencing(ctx, configMap, func(migration *databasesv1alpha1.DatabaseMigration) bool {
+		source := migration.Spec.Source.ConfigMap
+		return source != nil && source.Name == configMap.GetName()
+	})
+}
+
+func (r *DatabaseMigrationReconciler) migrationsReferencing(ctx context.Context, object client.Object, references func(*databasesv1alpha1.DatabaseMigration) bool) []reconcile.Request {
+	migrations := &databasesv1alpha1.DatabaseMigrationList{}
+	if err := r.List(ctx, migrations, client.InNamespace(object.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list DatabaseMigrations", "name", object.GetName())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for i := range migrations.Items {
+		if references(&migrations.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&migrations.Items[i])})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DatabaseMigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasesv1alpha1.DatabaseMigration{}).
+		Owns(&batchv1.Job{}).
+		Watches(&databasesv1alpha1.Database{}, handler.EnqueueRequestsFromMapFunc(r.migrationsOfDatabase)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.migrationsOfConfigMap)).
+		Named("databasemigration").
+		Complete(r)
+}
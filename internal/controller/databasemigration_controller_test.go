@@ -0,0 +1,209 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("DatabaseMigration Controller", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseMigrationReconciler
+		migration  *databasesv1alpha1.DatabaseMigration
+		configMap  *corev1.ConfigMap
+	)
+
+	migrationKey := types.NamespacedName{Name: "schema", Namespace: "shop"}
+	jobKey := types.NamespacedName{Name: "orders-migration-schema", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		migration = &databasesv1alpha1.DatabaseMigration{
+			ObjectMeta: metav1.ObjectMeta{Name: "schema", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseMigrationSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: "orders"},
+				Source: databasesv1alpha1.DatabaseMigrationSource{
+					ConfigMap: &corev1.LocalObjectReference{Name: "schema"},
+				},
+				Database: "app",
+			},
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "schema", Namespace: "shop"},
+			Data: map[string]string{
+				"002_add_total.sql":     "ALTER TABLE orders ADD COLUMN total numeric;",
+				"001_create_orders.sql": "CREATE TABLE orders (id bigserial PRIMARY KEY);",
+				"README.md":             "Migrations of the orders schema",
+			},
+		}
+	})
+
+	build := func() {
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+			Status:     databasesv1alpha1.DatabaseStatus{Phase: databasesv1alpha1.DatabasePhaseReady, ReadyReplicas: 1},
+		}
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.DatabaseMigration{}, &batchv1.Job{}).
+			WithObjects(database, migration, configMap).
+			Build()
+		reconciler = &DatabaseMigrationReconciler{Client: c, Scheme: scheme}
+	}
+
+	reconcile := func() *databasesv1alpha1.DatabaseMigration {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: migrationKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, migrationKey, migration)).To(Succeed())
+		return migration
+	}
+
+	job := func() *batchv1.Job {
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		return job
+	}
+
+	finishJob := func(condition batchv1.JobConditionType) {
+		current := job()
+		current.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, current)).To(Succeed())
+	}
+
+	It("should apply the scripts of a ConfigMap one Job at a time in the order of their names", func() {
+		build()
+		Expect(reconcile().Status.Phase).To(Equal(databasesv1alpha1.DatabaseMigrationPhaseRunning))
+		Expect(migration.Status.Migrations).To(HaveLen(2))
+		Expect(migration.Status.Migrations[0]).To(And(
+			HaveField("Name", "001_create_orders.sql"),
+			HaveField("Phase", databasesv1alpha1.MigrationScriptPhaseRunning),
+			HaveField("Job", jobKey.Name),
+		))
+		Expect(migration.Status.Migrations[1].Phase).To(Equal(databasesv1alpha1.MigrationScriptPhasePending))
+
+		spec := job().Spec.Template.Spec
+		Expect(metav1.IsControlledBy(job(), migration)).To(BeTrue())
+		Expect(spec.Containers[0].Command[2]).To(Equal(
+			`psql -h "$DB_HOST" -v ON_ERROR_STOP=1 --single-transaction -d "app" -f "/migrations/001_create_orders.sql"`))
+		Expect(spec.Volumes).To(ContainElement(HaveField("ConfigMap.Items",
+			[]corev1.KeyToPath{{Key: "001_create_orders.sql", Path: "001_create_orders.sql"}})))
+
+		finishJob(batchv1.JobComplete)
+		reconcile()
+		Expect(migration.Status.Migrations[0].Phase).To(Equal(databasesv1alpha1.MigrationScriptPhaseApplied))
+		Expect(migration.Status.Migrations[0].AppliedAt).NotTo(BeNil())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		reconcile()
+		Expect(job().Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring("002_add_total.sql"))
+		finishJob(batchv1.JobComplete)
+		reconcile()
+		Expect(reconcile().Status.Phase).To(Equal(databasesv1alpha1.DatabaseMigrationPhaseSucceeded))
+		Expect(migration.Status.Message).To(Equal("Applied 2 migrations"))
+
+		By("refusing to run once an applied script changed")
+		configMap.Data["001_create_orders.sql"] = "CREATE TABLE orders (id int PRIMARY KEY);"
+		Expect(reconciler.Update(ctx, configMap)).To(Succeed())
+		Expect(reconcile().Status.Phase).To(Equal(databasesv1alpha1.DatabaseMigrationPhaseFailed))
+		Expect(migration.Status.Message).To(MatchRegexp(`^migration 001_create_orders.sql was applied with checksum \w+ and changed to \w+$`))
+	})
+
+	It("should keep a failed script failed until it changes", func() {
+		build()
+		reconcile()
+		finishJob(batchv1.JobFailed)
+		Expect(reconcile().Status.Phase).To(Equal(databasesv1alpha1.DatabaseMigrationPhaseFailed))
+		Expect(migration.Status.Message).To(Equal("Failed to apply migration 001_create_orders.sql, see the logs of Job orders-migration-schema"))
+		Expect(migration.Status.Migrations[0].Phase).To(Equal(databasesv1alpha1.MigrationScriptPhaseFailed))
+		Expect(reconcile().Status.Phase).To(Equal(databasesv1alpha1.DatabaseMigrationPhaseFailed))
+		Expect(job().Status.Conditions).NotTo(BeEmpty())
+
+		configMap.Data["001_create_orders.sql"] = "CREATE TABLE IF NOT EXISTS orders (id bigserial PRIMARY KEY);"
+		Expect(reconciler.Update(ctx, configMap)).To(Succeed())
+		reconcile()
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+		Expect(reconcile().Status.Phase).To(Equal(databasesv1alpha1.DatabaseMigrationPhaseRunning))
+		Expect(job().Status.Conditions).To(BeEmpty())
+	})
+
+	It("should list the scripts of an image before applying them", func() {
+		migration.Spec.Source = databasesv1alpha1.DatabaseMigrationSource{
+			Image: &databasesv1alpha1.MigrationImageSource{Image: "registry.example.com/orders-schema:1.4", Path: "/migrations"},
+		}
+		build()
+		reconcile()
+		Expect(job().Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/orders-schema:1.4"))
+		Expect(job().Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring(`cd "/migrations" && for f in *.sql`))
+
+		// The listing Job reports the scripts in its termination message
+		Expect(reconciler.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "list", Namespace: "shop", Labels: map[string]string{"batch.kubernetes.io/job-name": jobKey.Name}},
+			Status: corev1.PodStatus{Phase: corev1.PodSucceeded, ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Message: "9f86d081884c7d65  V1__init.sql\n60303ae22b998861  V2__orders.sql\n",
+				}},
+			}}},
+		})).To(Succeed())
+		finishJob(batchv1.JobComplete)
+		reconcile()
+		Expect(migration.Status.ListedImage).To(Equal("registry.example.com/orders-schema:1.4"))
+		Expect(migration.Status.Migrations).To(ConsistOf(
+			HaveField("Checksum", "9f86d081884c7d65"),
+			HaveField("Checksum", "60303ae22b998861"),
+		))
+
+		reconcile()
+		spec := job().Spec.Template.Spec
+		Expect(job().Annotations).To(HaveKeyWithValue(migrationTargetAnnotation, "V1__init.sql@9f86d081884c7d65"))
+		Expect(spec.InitContainers).To(ConsistOf(And(
+			HaveField("Image", "registry.example.com/orders-schema:1.4"),
+			HaveField("Command", ContainElement(`cp "/migrations/V1__init.sql" /migrations/`)),
+		)))
+	})
+
+	It("should reject scripts added before applied ones", func() {
+		applied := []databasesv1alpha1.MigrationScriptStatus{
+			{Name: "002_add_total.sql", Checksum: "a", Phase: databasesv1alpha1.MigrationScriptPhaseApplied},
+		}
+		_, err := mergeMigrationScripts(applied, map[string]string{"001_create_orders.sql": "b", "002_add_total.sql": "a"})
+		Expect(err).To(MatchError("migration 001_create_orders.sql sorts before the applied migration 002_add_total.sql"))
+
+		// Applied scripts removed from the source stay recorded
+		merged, err := mergeMigrationScripts(applied, map[string]string{"003_index.sql": "c"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(merged).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,734 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// opsRequestFinalizer releases the operation lock of an ops request deleted
+	// while it holds or waits for it
+	opsRequestFinalizer = "databases.database-operator.io/ops-request-finalizer"
+
+	opsRequestRecheckInterval = 15 * time.Second
+	opsRequestComponentPrefix = "ops-"
+
+	// restartedAtAnnotation is the pod template annotation kubectl rollout restart sets
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+)
+
+// disruptiveOpsRequests are the ops request types holding the operation lock of
+// the Database while they run. Rotations and upgrade approvals are handed to
+// the Database controller, which takes the lock itself.
+var disruptiveOpsRequests = map[databasesv1alpha1.DatabaseOpsRequestType]bool{
+	databasesv1alpha1.OpsRequestRestart:  true,
+	databasesv1alpha1.OpsRequestFailover: true,
+	databasesv1alpha1.OpsRequestCompact:  true,
+	databasesv1alpha1.OpsRequestResync:   true,
+}
+
+// compactScripts reclaim the space of deleted data of the engines supporting
+// Compact ops requests
+var compactScripts = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: `vacuumdb -h "$DB_HOST" --all --analyze`,
+	databasesv1alpha1.DatabaseTypeMongoDB: `mongosh --host "$DB_HOST" -u "$MONGO_USERNAME" -p "$MONGO_PASSWORD" ` +
+		`--authenticationDatabase admin --quiet --eval '` +
+		`db.adminCommand({listDatabases: 1}).databases.forEach(d => { if (["admin", "config", "local"].includes(d.name)) return; ` +
+		`const database = db.getSiblingDB(d.name); database.getCollectionNames().forEach(c => { ` +
+		`if (!database.runCommand({compact: c}).ok) quit(1) }) })'`,
+	// Every replica rewrites its own append-only file
+	databasesv1alpha1.DatabaseTypeRedis: `set -e
+for host in $(getent hosts "$PEERS_HOST" | cut -d' ' -f1); do
+  reply="$(redis-cli -h "$host" BGREWRITEAOF)"
+  echo "$host: $reply"
+  case "$reply" in ERR*) exit 1 ;; esac
+done`,
+	databasesv1alpha1.DatabaseTypeElasticsearch: `curl -sf -X POST ` +
+		`"http://$DB_HOST:9200/_forcemerge?only_expunge_deletes=true" > /dev/null`,
+}
+
+// DatabaseOpsRequestReconciler reconciles a DatabaseOpsRequest object
+type DatabaseOpsRequestReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// APIReader reads the credentials of admin connections uncached (default:
+	// the API reader of the manager)
+	APIReader client.Reader
+	ReconcilerOptions
+	// Recorder records Events on DatabaseOpsRequests and their Databases
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaseopsrequests,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaseopsrequests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaseopsrequests/finalizers,verbs=update
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databases,verbs=get;list;watch;update;patch
+
+// Reconcile runs a day-2 operation on a Database once. The requests of a
+// Database run one at a time in the order they were created; restarts,
+// failovers, compactions and resyncs hold the disruptive operation lock of the
+// Database while they run.
+func (r *DatabaseOpsRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	request := &databasesv1alpha1.DatabaseOpsRequest{}
+	if err := r.Get(ctx, req.NamespacedName, request); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !request.DeletionTimestamp.IsZero() || opsRequestFinished(request) {
+		return ctrl.Result{}, r.finishOpsRequest(ctx, request)
+	}
+
+	if controllerutil.AddFinalizer(request, opsRequestFinalizer) {
+		if err := r.Update(ctx, request); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	originalStatus := request.Status.DeepCopy()
+	result, err := r.reconcileDatabaseOpsRequest(ctx, request)
+	if err != nil {
+		log.Error(err, "Failed to reconcile DatabaseOpsRequest")
+	}
+
+	if !equality.Semantic.DeepEqual(originalStatus, &request.Status) {
+		if err := r.Status().Update(ctx, request); err != nil {
+			log.Error(err, "Failed to update DatabaseOpsRequest status")
+			return ctrl.Result{}, err
+		}
+	}
+	if err == nil && opsRequestFinished(request) {
+		return ctrl.Result{}, r.finishOpsRequest(ctx, request)
+	}
+	return result, err
+}
+
+func (r *DatabaseOpsRequestReconciler) reconcileDatabaseOpsRequest(ctx context.Context, request *databasesv1alpha1.DatabaseOpsRequest) (ctrl.Result, error) {
+	database := &databasesv1alpha1.Database{}
+	if err := r.Get(ctx, opsRequestDatabaseKey(request), database); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		setOpsRequestPending(request, fmt.Sprintf("Database %q not found", request.Spec.DatabaseRef.Name))
+		return ctrl.Result{RequeueAfter: opsRequestRecheckInterval}, nil
+	}
+	ctx = withDatabaseLogger(ctx, database)
+	if _, err := applyDatabaseClass(ctx, r.Client, database); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if request.Status.Phase != databasesv1alpha1.DatabaseOpsRequestPhaseRunning {
+		if err := validateOpsRequest(database, request); err != nil {
+			failOpsRequest(request, err.Error())
+			return ctrl.Result{}, nil
+		}
+		ahead, err := r.opsRequestAhead(ctx, request)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if ahead != "" {
+			setOpsRequestPending(request, fmt.Sprintf("Waiting for DatabaseOpsRequest %q to finish", ahead))
+			return ctrl.Result{RequeueAfter: opsRequestRecheckInterval}, nil
+		}
+		if disruptiveOpsRequests[request.Spec.Type] {
+			acquired, active, err := r.acquireOpsRequestLock(ctx, request)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !acquired {
+				setOpsRequestPending(request, fmt.Sprintf("Waiting for operation %s to finish", active))
+				return ctrl.Result{RequeueAfter: opsRequestRecheckInterval}, nil
+			}
+		}
+		log.FromContext(ctx).Info("Starting DatabaseOpsRequest", "name", request.Name, "type", request.Spec.Type)
+		now := metav1.Now()
+		request.Status.Phase = databasesv1alpha1.DatabaseOpsRequestPhaseRunning
+		request.Status.StartTime = &now
+		request.Status.Message = ""
+	}
+
+	var err error
+	switch request.Spec.Type {
+	case databasesv1alpha1.OpsRequestRestart:
+		err = r.restartDatabase(ctx, database, request)
+	case databasesv1alpha1.OpsRequestFailover:
+		err = r.failoverDatabase(ctx, database, request, time.Now())
+	case databasesv1alpha1.OpsRequestCompact:
+		err = r.compactDatabase(ctx, database, request)
+	case databasesv1alpha1.OpsRequestResync:
+		err = r.resyncReplica(ctx, database, request)
+	case databasesv1alpha1.OpsRequestRotateCredentials:
+		err = r.rotateCredentials(ctx, database, request)
+	case databasesv1alpha1.OpsRequestUpgradeApproval:
+		err = r.approveUpgrade(ctx, database, request)
+	}
+	if err != nil || !opsRequestFinished(request) {
+		return ctrl.Result{RequeueAfter: opsRequestRecheckInterval}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// validateOpsRequest checks that the Database supports the requested operation
+func validateOpsRequest(database *databasesv1alpha1.Database, request *databasesv1alpha1.DatabaseOpsRequest) error {
+	engine := database.Spec.Type
+	if database.Spec.TargetCluster != nil {
+		return fmt.Errorf("Databases in a target cluster do not support DatabaseOpsRequests")
+	}
+	if disruptiveOpsRequests[request.Spec.Type] && hibernated(database) {
+		return fmt.Errorf("%s does not apply to a hibernated Database", request.Spec.Type)
+	}
+
+	switch request.Spec.Type {
+	case databasesv1alpha1.OpsRequestFailover:
+		// Every Redis replica is a standalone server: there is no replication
+		// for FAILOVER to hand the primary role over through
+		if engine == databasesv1alpha1.DatabaseTypeRedis {
+			return fmt.Errorf("Redis does not support failovers, its replicas do not replicate from a primary")
+		}
+		if _, ok := switchovers[engine]; !ok {
+			return fmt.Errorf("%s does not support failovers", engine)
+		}
+		if awakeReplicas(database) < 2 {
+			return fmt.Errorf("Failover requires at least 2 replicas")
+		}
+	case databasesv1alpha1.OpsRequestCompact:
+		if _, ok := compactScripts[engine]; !ok {
+			return fmt.Errorf("%s does not support compaction", engine)
+		}
+	case databasesv1alpha1.OpsRequestResync:
+		// Only Elasticsearch nodes recover their data from the others
+		if engine != databasesv1alpha1.DatabaseTypeElasticsearch {
+			return fmt.Errorf("%s does not support resyncs, its replicas do not recover data from each other", engine)
+		}
+		claims := dataClaimNames(database)
+		if len(claims) < 2 {
+			return fmt.Errorf("Resync requires at least 2 replicas")
+		}
+		replica := request.Spec.Resync.Replica
+		if !slices.Contains(claims, "data-"+replica) {
+			return fmt.Errorf("%q is not a replica with a data volume, expected one of %s",
+				replica, strings.Join(claimPods(claims), ", "))
+		}
+	case databasesv1alpha1.OpsRequestRotateCredentials:
+		return validateRotationTarget(database, "RotateCredentials")
+	}
+	return nil
+}
+
+// restartDatabase restarts the pods of the workloads of the Database with a
+// rolling update, like kubectl rollout restart, one workload at a time. The
+// restart is stamped with the start time of the request, so a workload is
+// restarted once.
+func (r *DatabaseOpsRequestReconciler) restartDatabase(ctx context.Context, database *databasesv1alpha1.Database, request *databasesv1alpha1.DatabaseOpsRequest) error {
+	stamp := request.Status.StartTime.UTC().Format(time.RFC3339)
+	for _, name := range workloadNames(database) {
+		workload, template, err := r.getWorkload(ctx, database, name)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if template.Annotations[restartedAtAnnotation] != stamp {
+			log.FromContext(ctx).Info("Restarting the pods of the workload", "name", name)
+			before := childSpec(workload)
+			patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+			if template.Annotations == nil {
+				template.Annotations = map[string]string{}
+			}
+			template.Annotations[restartedAtAnnotation] = stamp
+			restampAppliedSpec(workload, before)
+			if err := r.Patch(ctx, workload, patch); err != nil {
+				return err
+			}
+			request.Status.Message = fmt.Sprintf("Restarting the pods of %s", name)
+			return nil
+		}
+		if !rolledOut(workload) {
+			request.Status.Message = fmt.Sprintf("Restarting the pods of %s", name)
+			return nil
+		}
+	}
+	succeedOpsRequest(request, "Restarted every pod")
+	return nil
+}
+
+// getWorkload returns the StatefulSet, or the Deployment for SQLite, of a
+// Database and its pod template
+func (r *DatabaseOpsRequestReconciler) getWorkload(ctx context.Context, database *databasesv1alpha1.Database, name string) (client.Object, *corev1.PodTemplateSpec, error) {
+	key := types.NamespacedName{Name: name, Namespace: database.Namespace}
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite {
+		deployment := &appsv1.Deployment{}
+		return deployment, &deployment.Spec.Template, r.Get(ctx, key, deployment)
+	}
+	statefulSet := &appsv1.StatefulSet{}
+	return statefulSet, &statefulSet.Spec.Template, r.Get(ctx, key, statefulSet)
+}
+
+// failoverDatabase hands the primary role over to another replica, and waits
+// for another replica to report it
+func (r *DatabaseOpsRequestReconciler) failoverDatabase(ctx context.Context, database *databasesv1alpha1.Database, request *databasesv1alpha1.DatabaseOpsRequest, now time.Time) error {
+	pods, waiting, err := r.readyReplicas(ctx, database)
+	if err != nil {
+		return err
+	}
+	if waiting != "" {
+		request.Status.Message = fmt.Sprintf("Waiting for %s to become ready", waiting)
+		return nil
+	}
+	conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+	if err != nil {
+		return err
+	}
+
+	status := &request.Status
+	if status.PreviousPrimary == "" {
+		primary := findPrimary(ctx, database, conn, pods)
+		if primary == nil {
+			request.Status.Message = "Waiting for a replica to report the primary role"
+			return nil
+		}
+		log.FromContext(ctx).Info("Handing the primary role over to another replica", "pod", primary.Name)
+		conn.host = primary.Status.PodIP
+		switchoverCtx, cancel := context.WithTimeout(ctx, roleCheckTimeout)
+		err := switchovers[database.Spec.Type](switchoverCtx, conn)
+		cancel()
+		if err != nil {
+			failOpsRequest(request, fmt.Sprintf("Handing the primary role of %s over failed: %v", primary.Name, err))
+			return nil
+		}
+		status.PreviousPrimary = primary.Name
+		status.Message = fmt.Sprintf("Handing the primary role of %s over to another replica", primary.Name)
+		return nil
+	}
+
+	if primary := findPrimary(ctx, database, conn, pods); primary != nil && primary.Name != status.PreviousPrimary {
+		succeedOpsRequest(request, fmt.Sprintf("Moved the primary role from %s to %s", status.PreviousPrimary, primary.Name))
+		return nil
+	}
+	if now.Sub(status.StartTime.Time) >= switchoverTimeout {
+		failOpsRequest(request, fmt.Sprintf("The primary role did not move from %s within %s", status.PreviousPrimary, switchoverTimeout))
+	}
+	return nil
+}
+
+// readyReplicas returns the pods of the StatefulSet of a Database by ordinal,
+// or the name of the first one that is not ready
+func (r *DatabaseOpsRequestReconciler) readyReplicas(ctx context.Context, database *databasesv1alpha1.Database) ([]*corev1.Pod, string, error) {
+	pods := make([]*corev1.Pod, awakeReplicas(database))
+	for ordinal := range pods {
+		name := fmt.Sprintf("%s-%d", database.Name, ordinal)
+		pod := &corev1.Pod{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, pod)
+		if errors.IsNotFound(err) || (err == nil && !podReady(pod)) {
+			return nil, name, nil
+		} else if err != nil {
+			return nil, "", err
+		}
+		pods[ordinal] = pod
+	}
+	return pods, "", nil
+}
+
+// findPrimary returns the replica the engine reports as the primary, nil when
+// none does. Unlike primaryReplica it does not fall back to the first replica,
+// which would let a failed role check pass for a failover.
+func findPrimary(ctx context.Context, database *databasesv1alpha1.Database, conn adminConnection, pods []*corev1.Pod) *corev1.Pod {
+	check := primaryCheckers[database.Spec.Type]
+	for _, pod := range pods {
+		podConn := conn
+		podConn.host = pod.Status.PodIP
+		checkCtx, cancel := context.WithTimeout(ctx, roleCheckTimeout)
+		primary, err := check(checkCtx, podConn)
+		cancel()
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Failed to check the role of the replica", "pod", pod.Name)
+			continue
+		}
+		if primary {
+			return pod
+		}
+	}
+	return nil
+}
+
+// compactDatabase runs the compaction of the engine in an admin Job. The Job
+// is kept for its logs until its TTL expires.
+func (r *DatabaseOpsRequestReconciler) compactDatabase(ctx context.Context, database *databasesv1alpha1.Database, request *databasesv1alpha1.DatabaseOpsRequest) error {
+	var env []corev1.EnvVar
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeRedis {
+		env = append(env, corev1.EnvVar{Name: "PEERS_HOST", Value: peersHost(database)})
+	}
+	desired := r.databaseReconciler(r.Client, r.Scheme).createAdminJob(database, opsRequestComponentPrefix+request.Name,
+		compactScripts[database.Spec.Type], env)
+	job := &batchv1.Job{}
+	err := r.Get(ctx, client.ObjectKeyFromObject(desired), job)
+	if errors.IsNotFound(err) {
+		if err := controllerutil.SetControllerReference(request, desired, r.Scheme); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("Creating compaction Job", "name", desired.Name)
+		request.Status.Message = fmt.Sprintf("Compacting with Job %s", desired.Name)
+		return r.Create(ctx, desired)
+	} else if err != nil {
+		return err
+	}
+
+	finished, succeeded := jobFinished(job)
+	switch {
+	case !finished:
+		request.Status.Message = fmt.Sprintf("Compacting with Job %s", job.Name)
+	case succeeded:
+		succeedOpsRequest(request, "Compacted the database")
+	default:
+		failOpsRequest(request, fmt.Sprintf("Compaction failed, see the logs of Job %s", job.Name))
+	}
+	return nil
+}
+
+// resyncReplica replaces the data volume of a replica with an empty one. The
+// StatefulSet recreates the pod with a new claim, and the replica recovers its
+// data from the others. The old claim is deleted while the other replicas are
+// ready only, once the shards of the replica moved to them. The replica is
+// allowed shards again once it runs on the new claim.
+func (r *DatabaseOpsRequestReconciler) resyncReplica(ctx context.Context, database *databasesv1alpha1.Database, request *databasesv1alpha1.DatabaseOpsRequest) error {
+	replica := request.Spec.Resync.Replica
+	podKey := types.NamespacedName{Name: replica, Namespace: database.Namespace}
+	claimKey := types.NamespacedName{Name: "data-" + replica, Namespace: database.Namespace}
+	status := &request.Status
+
+	claim := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, claimKey, claim)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	claimFound := err == nil
+	pod := &corev1.Pod{}
+	err = r.Get(ctx, podKey, pod)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	podFound := err == nil
+
+	if status.PreviousClaimUID == "" {
+		if !claimFound {
+			failOpsRequest(request, fmt.Sprintf("PersistentVolumeClaim %s not found", claimKey.Name))
+			return nil
+		}
+		if database.Status.Phase != databasesv1alpha1.DatabasePhaseReady {
+			status.Message = "Waiting for the Database to be ready, so the other replicas hold the data"
+			return nil
+		}
+		drained, err := r.drainReplica(ctx, database, request, replica)
+		if err != nil || !drained {
+			return err
+		}
+		log.FromContext(ctx).Info("Replacing the data volume of the replica", "pod", replica, "claim", claim.Name)
+		if err := r.Delete(ctx, claim); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		status.PreviousClaimUID = claim.UID
+		status.Message = fmt.Sprintf("Replacing the data volume of %s", replica)
+		if podFound {
+			return client.IgnoreNotFound(r.Delete(ctx, pod))
+		}
+		return nil
+	}
+
+	switch {
+	case claimFound && claim.UID != status.PreviousClaimUID && podFound && podReady(pod):
+		conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+		if err != nil {
+			return err
+		}
+		drainCtx, cancel := context.WithTimeout(ctx, shardDrainTimeout)
+		defer cancel()
+		if _, err := elasticsearchExcludeNodes(drainCtx, conn, nil); err != nil {
+			return fmt.Errorf("failed to allow shards on %s again: %w", replica, err)
+		}
+		succeedOpsRequest(request, fmt.Sprintf("Replaced the data volume of %s, which recovers its data from the other replicas", replica))
+	case !claimFound && podFound && pod.DeletionTimestamp.IsZero():
+		// The pod was recreated before the old claim was gone, and waits for a
+		// claim the StatefulSet only creates along with the pod
+		log.FromContext(ctx).Info("Recreating the replica without data volume", "pod", replica)
+		return client.IgnoreNotFound(r.Delete(ctx, pod))
+	}
+	return nil
+}
+
+// drainReplica moves the shards off a replica once the cluster health is green,
+// so that no shard loses its last copy with the data volume, and reports
+// whether the replica holds none
+func (r *DatabaseOpsRequestReconciler) drainReplica(ctx context.Context, database *databasesv1alpha1.Database, request *databasesv1alpha1.DatabaseOpsRequest, replica string) (bool, error) {
+	conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+	if err != nil {
+		return false, err
+	}
+	drainCtx, cancel := context.WithTimeout(ctx, shardDrainTimeout)
+	defer cancel()
+
+	health, err := elasticsearchClusterStatus(drainCtx, conn)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the cluster health: %w", err)
+	}
+	if health != "green" {
+		request.Status.Message = fmt.Sprintf("Waiting for the cluster health to turn green, it is %s", health)
+		return false, nil
+	}
+	shards, err := elasticsearchExcludeNodes(drainCtx, conn, []string{replica})
+	if err != nil {
+		return false, fmt.Errorf("failed to drain %s: %w", replica, err)
+	}
+	if shards > 0 {
+		request.Status.Message = fmt.Sprintf("Waiting for %d shards to move off %s", shards, replica)
+		return false, nil
+	}
+	return true, nil
+}
+
+// rotateCredentials requests a rotation of the administrative password with
+// the rotate-credentials annotation, named after the UID of the request, and
+// follows it in the rotation status of the Database
+func (r *DatabaseOpsRequestReconciler) rotateCredentials(ctx context.Context, database *databasesv1alpha1.Database, request *databasesv1alpha1.DatabaseOpsRequest) error {
+	value := string(request.UID)
+	if rotation := database.Status.Rotation; rotation != nil && rotation.HandledRequest == value {
+		switch {
+		case rotation.Phase == databasesv1alpha1.RotationPhaseRotating:
+			request.Status.Message = "Rotating the administrative password"
+		case rotation.Phase == databasesv1alpha1.RotationPhaseFailed,
+			strings.HasPrefix(rotation.Message, "Ignored"):
+			failOpsRequest(request, rotation.Message)
+		default:
+			succeedOpsRequest(request, "Rotated the administrative password")
+		}
+		return nil
+	}
+
+	if database.Annotations[databasesv1alpha1.RotateCredentialsAnnotation] != value {
+		if err := r.annotateDatabase(ctx, database, databasesv1alpha1.RotateCredentialsAnnotation, value); err != nil {
+			return err
+		}
+	}
+	request.Status.Message = "Waiting for the rotation to start"
+	if rotation := database.Status.Rotation; rotation != nil && rotation.Message != "" {
+		request.Status.Message += ": " + rotation.Message
+	}
+	return nil
+}
+
+// approveUpgrade approves the upgrade of the Database to a new major version,
+// which starts once spec.version names it
+func (r *DatabaseOpsRequestReconciler) approveUpgrade(ctx context.Context, database *databasesv1alpha1.Database, request *databasesv1alpha1.DatabaseOpsRequest) error {
+	version := request.Spec.UpgradeApproval.Version
+	if err := r.annotateDatabase(ctx, database, databasesv1alpha1.ApproveMajorUpgradeAnnotation, version); err != nil {
+		return err
+	}
+	succeedOpsRequest(request, fmt.Sprintf("Approved the upgrade to version %s", version))
+	return nil
+}
+
+// annotateDatabase sets an annotation on the Database with a patch, which does
+// not conflict with the updates of the Database controller
+func (r *DatabaseOpsRequestReconciler) annotateDatabase(ctx context.Context, database *databasesv1alpha1.Database, key, value string) error {
+	patch := client.MergeFrom(database.DeepCopy())
+	if database.Annotations == nil {
+		database.Annotations = map[string]string{}
+	}
+	database.Annotations[key] = value
+	return r.Patch(ctx, database, patch)
+}
+
+// opsRequestAhead returns the name of the earliest unfinished request of the
+// same Database created before this one, which runs first
+func (r *DatabaseOpsRequestReconciler) opsRequestAhead(ctx context.Context, request *databasesv1alpha1.DatabaseOpsRequest) (string, error) {
+	requests := &databasesv1alpha1.DatabaseOpsRequestList{}
+	if err := r.List(ctx, requests, client.InNamespace(request.Namespace)); err != nil {
+		return "", err
+	}
+	var ahead *databasesv1alpha1.DatabaseOpsRequest
+	for i := range requests.Items {
+		other := &requests.Items[i]
+		if other.Spec.DatabaseRef.Name != request.Spec.DatabaseRef.Name || opsRequestFinished(other) ||
+			!other.DeletionTimestamp.IsZero() || !opsRequestBefore(other, request) {
+			continue
+		}
+		if ahead == nil || opsRequestBefore(other, ahead) {
+			ahead = other
+		}
+	}
+	if ahead == nil {
+		return "", nil
+	}
+	return ahead.Name, nil
+}
+
+// opsRequestBefore orders ops requests by creation, then by name
+func opsRequestBefore(a, b *databasesv1alpha1.DatabaseOpsRequest) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// acquireOpsRequestLock takes the place of the request in the operation lock of
+// the Database. It returns the operation holding the lock when it is not acquired.
+func (r *DatabaseOpsRequestReconciler) acquireOpsRequestLock(ctx context.Context, request *databasesv1alpha1.DatabaseOpsRequest) (acquired bool, active string, err error) {
+	err = updateDatabaseOperations(ctx, r.Client, opsRequestDatabaseKey(request), func(database *databasesv1alpha1.Database) {
+		acquired = acquireOperation(database, opsRequestOperation(request), time.Now())
+		active = activeOperation(database)
+	})
+	return acquired, active, err
+}
+
+// finishOpsRequest releases the lock or queue position of a finished or
+// deleted request, records a finished one in the history of the Database and
+// removes the finalizer
+func (r *DatabaseOpsRequestReconciler) finishOpsRequest(ctx context.Context, request *databasesv1alpha1.DatabaseOpsRequest) error {
+	var recorded *databasesv1alpha1.Database
+	eventType, reason := corev1.EventTypeNormal, "OpsRequestSucceeded"
+	outcome := databasesv1alpha1.OperationSucceeded
+	if request.Status.Phase == databasesv1alpha1.DatabaseOpsRequestPhaseFailed {
+		eventType, reason = corev1.EventTypeWarning, "OpsRequestFailed"
+		outcome = databasesv1alpha1.OperationFailed
+	}
+	message := fmt.Sprintf("%s %s: %s", request.Spec.Type, request.Name, request.Status.Message)
+	err := updateDatabaseOperations(ctx, r.Client, opsRequestDatabaseKey(request), func(database *databasesv1alpha1.Database) {
+		releaseOperation(database, opsRequestOperation(request))
+		// The finalizer is removed right after, so the request is recorded once
+		if opsRequestFinished(request) && controllerutil.ContainsFinalizer(request, opsRequestFinalizer) {
+			recordOperation(database, recordOpsRequest, outcome, message, time.Now())
+			recorded = database
+		}
+	})
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if recorded != nil {
+		recordEvent(r.Recorder, request, eventType, reason, message)
+		recordEvent(r.Recorder, recorded, eventType, reason, message)
+	}
+	if controllerutil.RemoveFinalizer(request, opsRequestFinalizer) {
+		return r.Update(ctx, request)
+	}
+	return nil
+}
+
+// opsRequestOperation is the name of the request in the operation lock
+func opsRequestOperation(request *databasesv1alpha1.DatabaseOpsRequest) string {
+	return disruptiveOperationOpsRequest + "/" + request.Name
+}
+
+func opsRequestDatabaseKey(request *databasesv1alpha1.DatabaseOpsRequest) types.NamespacedName {
+	return types.NamespacedName{Name: request.Spec.DatabaseRef.Name, Namespace: request.Namespace}
+}
+
+// claimPods returns the pods the data volume claims belong to
+func claimPods(claims []string) []string {
+	pods := make([]string, len(claims))
+	for i, claim := range claims {
+		pods[i] = strings.TrimPrefix(claim, "data-")
+	}
+	return pods
+}
+
+// opsRequestFinished reports whether a request reached a final phase
+func opsRequestFinished(request *databasesv1alpha1.DatabaseOpsRequest) bool {
+	return request.Status.Phase == databasesv1alpha1.DatabaseOpsRequestPhaseSucceeded ||
+		request.Status.Phase == databasesv1alpha1.DatabaseOpsRequestPhaseFailed
+}
+
+func setOpsRequestPending(request *databasesv1alpha1.DatabaseOpsRequest, message string) {
+	request.Status.Phase = databasesv1alpha1.DatabaseOpsRequestPhasePending
+	request.Status.Message = message
+}
+
+func succeedOpsRequest(request *databasesv1alpha1.DatabaseOpsRequest, message string) {
+	now := metav1.Now()
+	request.Status.Phase = databasesv1alpha1.DatabaseOpsRequestPhaseSucceeded
+	request.Status.Message = message
+	request.Status.CompletionTime = &now
+}
+
+func failOpsRequest(request *databasesv1alpha1.DatabaseOpsRequest, message string) {
+	now := metav1.Now()
+	request.Status.Phase = databasesv1alpha1.DatabaseOpsRequestPhaseFailed
+	request.Status.Message = message
+	request.Status.CompletionTime = &now
+}
+
+// opsRequestsOfDatabase enqueues the unfinished DatabaseOpsRequests of the
+// Database of an object, a Database or one of its requests, so they follow the
+// Database and start once the request ahead finished
+func (r *DatabaseOpsRequestReconciler) opsRequestsOfDatabase(ctx context.Context, object client.Object) []reconcile.Request {
+	name := object.GetName()
+	if request, ok := object.(*databasesv1alpha1.DatabaseOpsRequest); ok {
+		name = request.Spec.DatabaseRef.Name
+	}
+	requests := &databasesv1alpha1.DatabaseOpsRequestList{}
+	if err := r.List(ctx, requests, client.InNamespace(object.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list DatabaseOpsRequests", "database", name)
+		return nil
+	}
+	result := []reconcile.Request{}
+	for i := range requests.Items {
+		request := &requests.Items[i]
+		if request.Spec.DatabaseRef.Name == name && !opsRequestFinished(request) {
+			result = append(result, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(request)})
+		}
+	}
+	return result
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DatabaseOpsRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("databaseopsrequest-controller")
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasesv1alpha1.DatabaseOpsRequest{}).
+		Owns(&batchv1.Job{}).
+		Watches(&databasesv1alpha1.Database{}, handler.EnqueueRequestsFromMapFunc(r.opsRequestsOfDatabase)).
+		Watches(&databasesv1alpha1.DatabaseOpsRequest{}, handler.EnqueueRequestsFromMapFunc(r.opsRequestsOfDatabase)).
+		Named("databaseopsrequest").
+		Complete(r)
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("DatabaseOpsRequest Controller", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseOpsRequestReconciler
+		recorder   *record.FakeRecorder
+		database   *databasesv1alpha1.Database
+		created    time.Time
+	)
+
+	databaseKey := types.NamespacedName{Name: "orders", Namespace: "shop"}
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "shop"}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		recorder = record.NewFakeRecorder(20)
+		created = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:  "16",
+				Replicas: ptr.To(int32(1)),
+			},
+			Status: databasesv1alpha1.DatabaseStatus{Phase: databasesv1alpha1.DatabasePhaseReady, ReadyReplicas: 1},
+		}
+	})
+
+	newRequest := func(name string, opsType databasesv1alpha1.DatabaseOpsRequestType) *databasesv1alpha1.DatabaseOpsRequest {
+		created = created.Add(time.Minute)
+		return &databasesv1alpha1.DatabaseOpsRequest{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: "shop", UID: types.UID(name + "-uid"),
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: databasesv1alpha1.DatabaseOpsRequestSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: "orders"},
+				Type:        opsType,
+			},
+		}
+	}
+
+	build := func(objects ...client.Object) {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}, &databasesv1alpha1.DatabaseOpsRequest{},
+				&batchv1.Job{}, &appsv1.StatefulSet{}).
+			WithObjects(append(objects, database)...).
+			Build()
+		reconciler = &DatabaseOpsRequestReconciler{Client: c, Scheme: scheme, APIReader: c, Recorder: recorder}
+	}
+
+	reconcile := func(name string) *databasesv1alpha1.DatabaseOpsRequest {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key(name)})
+		Expect(err).NotTo(HaveOccurred())
+		request := &databasesv1alpha1.DatabaseOpsRequest{}
+		Expect(reconciler.Get(ctx, key(name), request)).To(Succeed())
+		return request
+	}
+
+	getDatabase := func() *databasesv1alpha1.Database {
+		current := &databasesv1alpha1.Database{}
+		Expect(reconciler.Get(ctx, databaseKey, current)).To(Succeed())
+		return current
+	}
+
+	It("should run the requests of a Database one at a time under the operation lock", func() {
+		build(newRequest("vacuum", databasesv1alpha1.OpsRequestCompact), newRequest("restart", databasesv1alpha1.OpsRequestRestart))
+
+		Expect(reconcile("restart").Status).To(And(
+			HaveField("Phase", databasesv1alpha1.DatabaseOpsRequestPhasePending),
+			HaveField("Message", `Waiting for DatabaseOpsRequest "vacuum" to finish`),
+		))
+
+		Expect(reconcile("vacuum").Status.Phase).To(Equal(databasesv1alpha1.DatabaseOpsRequestPhaseRunning))
+		Expect(activeOperation(getDatabase())).To(Equal("OpsRequest/vacuum"))
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, key("orders-ops-vacuum"), job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(Equal(`vacuumdb -h "$DB_HOST" --all --analyze`))
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		request := reconcile("vacuum")
+		Expect(request.Status.Phase).To(Equal(databasesv1alpha1.DatabaseOpsRequestPhaseSucceeded))
+		Expect(request.Status.CompletionTime).NotTo(BeNil())
+		Expect(request.Finalizers).To(BeEmpty())
+		Expect(activeOperation(getDatabase())).To(BeEmpty())
+		Expect(getDatabase().Status.RecentOperations).To(ConsistOf(And(
+			HaveField("Type", recordOpsRequest),
+			HaveField("Outcome", databasesv1alpha1.OperationSucceeded),
+			HaveField("Detail", "Compact vacuum: Compacted the database"),
+		)))
+		Expect(recorder.Events).To(Receive(Equal("Normal OpsRequestSucceeded Compact vacuum: Compacted the database")))
+
+		By("starting the next request once the one ahead finished")
+		Expect(reconcile("restart").Status.Phase).NotTo(Equal(databasesv1alpha1.DatabaseOpsRequestPhasePending))
+	})
+
+	It("should restart the pods once and wait for the rollout", func() {
+		statefulSet := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			// The StatefulSet controller picked up the restarted template
+			Status: appsv1.StatefulSetStatus{CurrentRevision: "orders-1", UpdateRevision: "orders-2"},
+		}
+		build(newRequest("restart", databasesv1alpha1.OpsRequestRestart), statefulSet)
+
+		request := reconcile("restart")
+		Expect(request.Status.Phase).To(Equal(databasesv1alpha1.DatabaseOpsRequestPhaseRunning))
+		Expect(request.Status.Message).To(Equal("Restarting the pods of orders"))
+		Expect(reconciler.Get(ctx, key("orders"), statefulSet)).To(Succeed())
+		stamp := statefulSet.Spec.Template.Annotations[restartedAtAnnotation]
+		Expect(stamp).To(Equal(request.Status.StartTime.UTC().Format(time.RFC3339)))
+
+		Expect(reconcile("restart").Status.Phase).To(Equal(databasesv1alpha1.DatabaseOpsRequestPhaseRunning))
+		statefulSet.Status.CurrentRevision = "orders-2"
+		Expect(reconciler.Status().Update(ctx, statefulSet)).To(Succeed())
+		Expect(reconcile("restart").Status.Phase).To(Equal(databasesv1alpha1.DatabaseOpsRequestPhaseSucceeded))
+		Expect(reconciler.Get(ctx, key("orders"), statefulSet)).To(Succeed())
+		Expect(statefulSet.Spec.Template.Annotations[restartedAtAnnotation]).To(Equal(stamp))
+	})
+
+	It("should replace the data volume of an Elasticsearch replica", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeElasticsearch
+		database.Spec.Version = "8.13.0"
+		database.Spec.Replicas = ptr.To(int32(3))
+		request := newRequest("resync", databasesv1alpha1.OpsRequestResync)
+		request.Spec.Resync = &databasesv1alpha1.ResyncOpsRequest{Replica: "orders-1"}
+		unknown := newRequest("resync-unknown", databasesv1alpha1.OpsRequestResync)
+		unknown.Spec.Resync = &databasesv1alpha1.ResyncOpsRequest{Replica: "orders-5"}
+		build(request, unknown,
+			&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-orders-1", Namespace: "shop", UID: "old-claim"}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "orders-1", Namespace: "shop"}},
+		)
+
+		health, shards := "yellow", 4
+		var excluded [][]string
+		originalStatus, originalExclude := elasticsearchClusterStatus, elasticsearchExcludeNodes
+		elasticsearchClusterStatus = func(context.Context, adminConnection) (string, error) {
+			return health, nil
+		}
+		elasticsearchExcludeNodes = func(_ context.Context, _ adminConnection, nodes []string) (int, error) {
+			excluded = append(excluded, nodes)
+			return shards, nil
+		}
+		DeferCleanup(func() {
+			elasticsearchClusterStatus, elasticsearchExcludeNodes = originalStatus, originalExclude
+		})
+
+		By("waiting for a green cluster")
+		request = reconcile("resync")
+		Expect(request.Status.Message).To(Equal("Waiting for the cluster health to turn green, it is yellow"))
+		Expect(excluded).To(BeEmpty())
+
+		By("waiting for the shards to move off the replica")
+		health = "green"
+		request = reconcile("resync")
+		Expect(request.Status.Message).To(Equal("Waiting for 4 shards to move off orders-1"))
+		Expect(excluded).To(Equal([][]string{{"orders-1"}}))
+		Expect(reconciler.Get(ctx, key("data-orders-1"), &corev1.PersistentVolumeClaim{})).To(Succeed())
+
+		shards = 0
+		request = reconcile("resync")
+		Expect(request.Status.PreviousClaimUID).To(Equal(types.UID("old-claim")))
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, key("data-orders-1"), &corev1.PersistentVolumeClaim{}))).To(BeTrue())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, key("orders-1"), &corev1.Pod{}))).To(BeTrue())
+
+		By("succeeding once the replica runs on a new claim, and allowing it shards again")
+		Expect(reconciler.Create(ctx, &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data-orders-1", Namespace: "shop", UID: "new-claim"},
+		})).To(Succeed())
+		Expect(reconciler.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-1", Namespace: "shop"},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			}},
+		})).To(Succeed())
+		Expect(reconcile("resync").Status.Phase).To(Equal(databasesv1alpha1.DatabaseOpsRequestPhaseSucceeded))
+		Expect(excluded[len(excluded)-1]).To(BeNil())
+
+		By("rejecting a replica the Database does not have")
+		Expect(reconcile("resync-unknown").Status).To(And(
+			HaveField("Phase", databasesv1alpha1.DatabaseOpsRequestPhaseFailed),
+			HaveField("Message", `"orders-5" is not a replica with a data volume, expected one of orders-0, orders-1, orders-2`),
+		))
+	})
+
+	It("should hand rotations and upgrade approvals to the Database controller", func() {
+		database.Spec.Version = "17"
+		build(newRequest("rotate", databasesv1alpha1.OpsRequestRotateCredentials), newRequest("failover", databasesv1alpha1.OpsRequestFailover))
+
+		Expect(reconcile("rotate").Status.Phase).To(Equal(databasesv1alpha1.DatabaseOpsRequestPhaseRunning))
+		current := getDatabase()
+		Expect(current.Annotations).To(HaveKeyWithValue(databasesv1alpha1.RotateCredentialsAnnotation, "rotate-uid"))
+		Expect(activeOperation(current)).To(BeEmpty())
+
+		current.Status.Rotation = &databasesv1alpha1.RotationStatus{
+			Phase:          databasesv1alpha1.RotationPhaseRotating,
+			HandledRequest: "rotate-uid",
+		}
+		Expect(reconciler.Status().Update(ctx, current)).To(Succeed())
+		Expect(reconcile("rotate").Status.Message).To(Equal("Rotating the administrative password"))
+		current.Status.Rotation.Phase = databasesv1alpha1.RotationPhaseScheduled
+		Expect(reconciler.Status().Update(ctx, current)).To(Succeed())
+		Expect(reconcile("rotate").Status.Phase).To(Equal(databasesv1alpha1.DatabaseOpsRequestPhaseSucceeded))
+
+		By("rejecting a failover of an engine without switchover")
+		Expect(reconcile("failover").Status).To(And(
+			HaveField("Phase", databasesv1alpha1.DatabaseOpsRequestPhaseFailed),
+			HaveField("Message", "PostgreSQL does not support failovers"),
+		))
+		redis := &databasesv1alpha1.Database{Spec: databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeRedis, Replicas: ptr.To(int32(3))}}
+		Expect(validateOpsRequest(redis, newRequest("failover", databasesv1alpha1.OpsRequestFailover))).
+			To(MatchError("Redis does not support failovers, its replicas do not replicate from a primary"))
+
+		By("approving a major upgrade")
+		approval := newRequest("approve", databasesv1alpha1.OpsRequestUpgradeApproval)
+		approval.Spec.UpgradeApproval = &databasesv1alpha1.UpgradeApprovalOpsRequest{Version: "17"}
+		Expect(reconciler.Create(ctx, approval)).To(Succeed())
+		Expect(reconcile("approve").Status.Phase).To(Equal(databasesv1alpha1.DatabaseOpsRequestPhaseSucceeded))
+		Expect(getDatabase().Annotations).To(HaveKeyWithValue(databasesv1alpha1.ApproveMajorUpgradeAnnotation, "17"))
+	})
+})
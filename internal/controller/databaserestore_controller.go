@@ -0,0 +1,381 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// restoreFinalizer releases the operation lock of a restore deleted while it holds it
+	restoreFinalizer = "databases.database-operator.io/restore-finalizer"
+
+	restoreRecheckInterval = 15 * time.Second
+)
+
+// DatabaseRestoreReconciler reconciles a DatabaseRestore object
+type DatabaseRestoreReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// APIReader reads the credentials of admin connections uncached (default:
+	// the API reader of the manager)
+	APIReader client.Reader
+	ReconcilerOptions
+	// Recorder records Events on DatabaseRestores and their Databases
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaserestores,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaserestores/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaserestores/finalizers,verbs=update
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databases,verbs=get;list;watch;create
+
+// Reconcile restores a backup into a Database once. The restore holds the
+// disruptive operation lock of the Database while its Job runs, or while the
+// data volumes are replaced for a snapshot backup or recovered from a WAL archive.
+func (r *DatabaseRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	restore := &databasesv1alpha1.DatabaseRestore{}
+	if err := r.Get(ctx, req.NamespacedName, restore); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !restore.DeletionTimestamp.IsZero() || restoreFinished(restore) {
+		return ctrl.Result{}, r.finishRestore(ctx, restore)
+	}
+
+	if controllerutil.AddFinalizer(restore, restoreFinalizer) {
+		if err := r.Update(ctx, restore); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	originalStatus := restore.Status.DeepCopy()
+	result, err := r.reconcileDatabaseRestore(ctx, restore)
+	if err != nil {
+		log.Error(err, "Failed to reconcile DatabaseRestore")
+	}
+
+	if !equality.Semantic.DeepEqual(originalStatus, &restore.Status) {
+		if err := r.Status().Update(ctx, restore); err != nil {
+			log.Error(err, "Failed to update DatabaseRestore status")
+			return ctrl.Result{}, err
+		}
+	}
+	if err == nil && restoreFinished(restore) {
+		return ctrl.Result{}, r.finishRestore(ctx, restore)
+	}
+	return result, err
+}
+
+// finishRestore puts back the connection limit of a restore deleted while its Job
+// runs, releases the operation lock of a finished or deleted restore and removes
+// its finalizer
+func (r *DatabaseRestoreReconciler) finishRestore(ctx context.Context, restore *databasesv1alpha1.DatabaseRestore) error {
+	if limit := restore.Status.ConnectionLimit; limit != nil && limit.RestoredAt == nil {
+		database := &databasesv1alpha1.Database{}
+		err := r.Get(ctx, databaseKey(restore), database)
+		if err == nil {
+			err = r.restoreConnectionLimit(ctx, database, restore)
+		}
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if err := r.releaseRestoreLock(ctx, restore); err != nil {
+		return err
+	}
+	if controllerutil.RemoveFinalizer(restore, restoreFinalizer) {
+		return r.Update(ctx, restore)
+	}
+	return nil
+}
+
+func (r *DatabaseRestoreReconciler) reconcileDatabaseRestore(ctx context.Context, restore *databasesv1alpha1.DatabaseRestore) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if restore.Spec.Fork != nil {
+		ready, err := r.reconcileFork(ctx, restore)
+		if err != nil || !ready {
+			return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, err
+		}
+	}
+
+	database := &databasesv1alpha1.Database{}
+	if err := r.Get(ctx, databaseKey(restore), database); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		setRestorePending(restore, fmt.Sprintf("Database %q not found", restore.Spec.DatabaseRef.Name))
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+	ctx = withDatabaseLogger(ctx, database)
+	// Restores follow the defaults of the DatabaseClass like the Database does
+	if _, err := applyDatabaseClass(ctx, r.Client, database); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if database.Spec.TargetCluster != nil {
+		failRestore(restore, "Databases in a target cluster do not support DatabaseRestores")
+		return ctrl.Result{}, nil
+	}
+	if restore.Spec.Source.WALArchive != nil {
+		return r.reconcileWALRestore(ctx, database, restore)
+	}
+	if restore.Spec.PointInTime != nil {
+		failRestore(restore, "pointInTime requires a walArchive source, backups are restored as they were taken")
+		return ctrl.Result{}, nil
+	}
+
+	// Snapshot backups are restored by replacing the data volumes, for any engine
+	snapshotBackup, err := r.snapshotBackup(ctx, restore)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if message := restorePerformanceError(database, restore, snapshotBackup != nil); message != "" {
+		failRestore(restore, message)
+		return ctrl.Result{}, nil
+	}
+	if snapshotBackup != nil {
+		return r.reconcileSnapshotRestore(ctx, database, restore, snapshotBackup)
+	}
+
+	if _, ok := restoreScripts[database.Spec.Type]; !ok {
+		failRestore(restore, fmt.Sprintf("%s does not support restores", database.Spec.Type))
+		return ctrl.Result{}, nil
+	}
+
+	job := &batchv1.Job{}
+	jobName := restore.Name + "-" + restoreComponent
+	err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: restore.Namespace}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	if errors.IsNotFound(err) {
+		var location, keyID string
+		if ref := restore.Spec.Source.BackupRef; ref != nil {
+			backup := &databasesv1alpha1.DatabaseBackup{}
+			if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: restore.Namespace}, backup); err != nil {
+				if !errors.IsNotFound(err) {
+					return ctrl.Result{}, err
+				}
+				setRestorePending(restore, fmt.Sprintf("DatabaseBackup %q not found", ref.Name))
+				return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+			}
+			switch backup.Status.Phase {
+			case databasesv1alpha1.DatabaseBackupPhaseCompleted:
+				location = backup.Status.Location
+				keyID = backup.Status.KMSKeyID
+			case databasesv1alpha1.DatabaseBackupPhaseFailed:
+				failRestore(restore, fmt.Sprintf("DatabaseBackup %q failed", ref.Name))
+				return ctrl.Result{}, nil
+			default:
+				setRestorePending(restore, fmt.Sprintf("Waiting for DatabaseBackup %q to complete", ref.Name))
+				return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+			}
+		}
+
+		encryption, err := r.restoreDecryption(ctx, database, restore, keyID)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		desired, err := r.databaseReconciler(r.Client, r.Scheme).createRestoreJob(database, restore, location, encryption)
+		if err != nil {
+			failRestore(restore, err.Error())
+			return ctrl.Result{}, nil
+		}
+
+		acquired, active, err := r.acquireRestoreLock(ctx, restore)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !acquired {
+			restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseQueued
+			restore.Status.Message = fmt.Sprintf("Waiting for operation %s to finish", active)
+			return ctrl.Result{RequeueAfter: restoreRecheckInterval}, nil
+		}
+		connectionLimit, err := r.quiescedConnectionLimit(ctx, database)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := controllerutil.SetControllerReference(restore, desired, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Creating restore Job", "name", desired.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		now := metav1.Now()
+		restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseRunning
+		restore.Status.Progress = "Starting"
+		restore.Status.JobName = desired.Name
+		restore.Status.StartTime = &now
+		restore.Status.Message = ""
+		restore.Status.ConnectionLimit = connectionLimit
+		if restore.Spec.PerformanceMode {
+			restore.Status.PerformanceMode = &databasesv1alpha1.RestorePerformanceStatus{
+				Settings:  restorePerformanceModes[database.Spec.Type].settings,
+				AppliedAt: &now,
+			}
+		}
+		return ctrl.Result{RequeueAfter: restoreRecheckInterval}, nil
+	}
+
+	restore.Status.JobName = job.Name
+	finished, succeeded := jobFinished(job)
+	if !finished {
+		pods := &corev1.PodList{}
+		if err := r.List(ctx, pods, client.InNamespace(job.Namespace),
+			client.MatchingLabels{batchv1.JobNameLabel: job.Name}); err != nil {
+			return ctrl.Result{}, err
+		}
+		restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseRunning
+		restore.Status.Progress = restoreProgress(pods.Items)
+		return ctrl.Result{RequeueAfter: restoreRecheckInterval}, nil
+	}
+
+	if err := r.restoreConnectionLimit(ctx, database, restore); err != nil {
+		restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseRunning
+		restore.Status.Progress = "Restoring the connection limit"
+		return ctrl.Result{RequeueAfter: restoreRecheckInterval}, err
+	}
+	done, err := r.revertRestorePerformanceMode(ctx, database, restore)
+	if err != nil || !done {
+		restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseRunning
+		restore.Status.Progress = "Reverting durability settings"
+		return ctrl.Result{RequeueAfter: restoreRecheckInterval}, err
+	}
+
+	now := metav1.Now()
+	restore.Status.CompletionTime = &now
+	restore.Status.Progress = ""
+	if performance := restore.Status.PerformanceMode; performance != nil && performance.RevertedAt == nil {
+		failRestore(restore, fmt.Sprintf("Reverting %s failed, see the logs of Job %s-%s",
+			strings.Join(performance.Settings, ", "), restore.Name, restoreRevertComponent))
+		return ctrl.Result{}, nil
+	}
+	if !succeeded {
+		failRestore(restore, fmt.Sprintf("Restore Job %s failed", job.Name))
+		return ctrl.Result{}, nil
+	}
+	restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseCompleted
+	restore.Status.Message = fmt.Sprintf("Restored into Database %q", database.Name)
+	if performance := restore.Status.PerformanceMode; performance != nil {
+		restore.Status.Message += fmt.Sprintf(" in performance mode (%s, since reverted)", strings.Join(performance.Settings, ", "))
+	}
+	return ctrl.Result{}, nil
+}
+
+// acquireRestoreLock takes the place of the restore in the operation lock of the
+// Database. It returns the operation holding the lock when it is not acquired.
+func (r *DatabaseRestoreReconciler) acquireRestoreLock(ctx context.Context, restore *databasesv1alpha1.DatabaseRestore) (acquired bool, active string, err error) {
+	err = updateDatabaseOperations(ctx, r.Client, databaseKey(restore), func(database *databasesv1alpha1.Database) {
+		acquired = acquireOperation(database, restoreOperation(restore), time.Now())
+		active = activeOperation(database)
+	})
+	return acquired, active, err
+}
+
+// releaseRestoreLock releases the lock or queue position of the restore
+func (r *DatabaseRestoreReconciler) releaseRestoreLock(ctx context.Context, restore *databasesv1alpha1.DatabaseRestore) error {
+	var recorded *databasesv1alpha1.Database
+	eventType, reason := corev1.EventTypeNormal, "RestoreCompleted"
+	if restore.Status.Phase == databasesv1alpha1.DatabaseRestorePhaseFailed {
+		eventType, reason = corev1.EventTypeWarning, "RestoreFailed"
+	}
+	message := fmt.Sprintf("Restore %s: %s", restore.Name, restore.Status.Message)
+	err := updateDatabaseOperations(ctx, r.Client, databaseKey(restore), func(database *databasesv1alpha1.Database) {
+		releaseOperation(database, restoreOperation(restore))
+		// The finalizer is removed right after, so the restore is recorded once
+		if restoreFinished(restore) && controllerutil.ContainsFinalizer(restore, restoreFinalizer) {
+			outcome := databasesv1alpha1.OperationSucceeded
+			if eventType == corev1.EventTypeWarning {
+				outcome = databasesv1alpha1.OperationFailed
+			}
+			recordOperation(database, recordRestore, outcome, message, time.Now())
+			recorded = database
+		}
+	})
+	if err == nil && recorded != nil {
+		recordEvent(r.Recorder, restore, eventType, reason, message)
+		recordEvent(r.Recorder, recorded, eventType, reason, message)
+	}
+	return client.IgnoreNotFound(err)
+}
+
+// restoreOperation is the name of the restore in the operation lock
+func restoreOperation(restore *databasesv1alpha1.DatabaseRestore) string {
+	return disruptiveOperationRestore + "/" + restore.Name
+}
+
+func databaseKey(restore *databasesv1alpha1.DatabaseRestore) types.NamespacedName {
+	return types.NamespacedName{Name: restore.Spec.DatabaseRef.Name, Namespace: restore.Namespace}
+}
+
+// restoreFinished reports whether a restore reached a final phase
+func restoreFinished(restore *databasesv1alpha1.DatabaseRestore) bool {
+	return restore.Status.Phase == databasesv1alpha1.DatabaseRestorePhaseCompleted ||
+		restore.Status.Phase == databasesv1alpha1.DatabaseRestorePhaseFailed
+}
+
+func setRestorePending(restore *databasesv1alpha1.DatabaseRestore, message string) {
+	restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhasePending
+	restore.Status.Message = message
+}
+
+func failRestore(restore *databasesv1alpha1.DatabaseRestore, message string) {
+	restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseFailed
+	restore.Status.Progress = ""
+	restore.Status.Message = message
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DatabaseRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("databaserestore-controller")
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasesv1alpha1.DatabaseRestore{}).
+		Owns(&batchv1.Job{}).
+		Named("databaserestore").
+		Complete(r)
+}
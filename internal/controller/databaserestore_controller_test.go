@@ -0,0 +1,269 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("DatabaseRestore Controller", func() {
+	var (
+		ctx              context.Context
+		reconciler       *DatabaseRestoreReconciler
+		connectionLimits *[]int32
+	)
+
+	newRestore := func(name string, source databasesv1alpha1.RestoreSource) *databasesv1alpha1.DatabaseRestore {
+		return &databasesv1alpha1.DatabaseRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseRestoreSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: "orders"},
+				Source:      source,
+			},
+		}
+	}
+	fromBackup := databasesv1alpha1.RestoreSource{BackupRef: &corev1.LocalObjectReference{Name: "nightly"}}
+
+	reconcile := func(name string) *databasesv1alpha1.DatabaseRestore {
+		key := types.NamespacedName{Name: name, Namespace: "shop"}
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		restore := &databasesv1alpha1.DatabaseRestore{}
+		Expect(reconciler.Get(ctx, key, restore)).To(Succeed())
+		return restore
+	}
+
+	database := func() *databasesv1alpha1.Database {
+		database := &databasesv1alpha1.Database{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders", Namespace: "shop"}, database)).To(Succeed())
+		return database
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}, &databasesv1alpha1.DatabaseRestore{}).
+			WithInterceptorFuncs(applyPatches()).
+			WithObjects(
+				&databasesv1alpha1.Database{
+					ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+					Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+				},
+				&databasesv1alpha1.DatabaseBackup{
+					ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "shop"},
+					Status: databasesv1alpha1.DatabaseBackupStatus{
+						Phase:    databasesv1alpha1.DatabaseBackupPhaseCompleted,
+						Location: "pvc://orders-backups/nightly.dump",
+					},
+				},
+				newRestore("first", fromBackup),
+				newRestore("second", fromBackup),
+			).Build()
+		reconciler = &DatabaseRestoreReconciler{Client: c, Scheme: scheme, APIReader: passwordReader{}}
+		connectionLimits = stubConnectionLimits(100)
+	})
+
+	It("should restore a DatabaseBackup while holding the operation lock", func() {
+		restore := reconcile("first")
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+		Expect(restore.Finalizers).To(ContainElement(restoreFinalizer))
+		Expect(activeOperation(database())).To(Equal("Restore/first"))
+
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "first-restore", Namespace: "shop"}, job)).To(Succeed())
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "orders-backups")))
+		Expect(podSpec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "RESTORE_FILE", Value: "/restore/nightly.dump"}))
+		Expect(podSpec.Containers[0].Command[2]).To(ContainSubstring("pg_restore"))
+		Expect(restore.Status.ConnectionLimit).To(Equal(&databasesv1alpha1.RestoreConnectionLimitStatus{Previous: 100}))
+
+		By("queueing a second restore behind the first")
+		Expect(reconcile("second").Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseQueued))
+
+		By("releasing the lock once the Job completed")
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+
+		restore = reconcile("first")
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseCompleted))
+		Expect(restore.Finalizers).To(BeEmpty())
+		Expect(restore.Status.ConnectionLimit.RestoredAt).NotTo(BeNil())
+		Expect(*connectionLimits).To(Equal([]int32{100}))
+		Expect(database().Status.Operations.Pending).To(Equal([]string{"Restore/second"}))
+
+		Expect(reconcile("second").Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+	})
+
+	It("should keep the Database out of Ready until the restore completed", func() {
+		databases := &DatabaseReconciler{Client: reconciler.Client, Scheme: reconciler.Scheme}
+		reconcileDatabase := func() *databasesv1alpha1.Database {
+			_, err := databases.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "orders", Namespace: "shop"}})
+			Expect(err).NotTo(HaveOccurred())
+			return database()
+		}
+
+		Expect(reconcile("first").Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+		restoring := reconcileDatabase()
+		Expect(restoring.Status.Phase).To(Equal(databasesv1alpha1.DatabasePhaseRestoring))
+		Expect(meta.FindStatusCondition(restoring.Status.Conditions, "Ready")).To(And(
+			HaveField("Status", metav1.ConditionFalse), HaveField("Reason", "Restoring")))
+
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "first-restore", Namespace: "shop"}, job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(And(
+			ContainSubstring("CONNECTION LIMIT"), ContainSubstring("pg_terminate_backend"), ContainSubstring("--single-transaction")))
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		Expect(reconcile("first").Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseCompleted))
+
+		ready := reconcileDatabase()
+		Expect(ready.Status.Phase).To(Equal(databasesv1alpha1.DatabasePhaseReady))
+		Expect(meta.IsStatusConditionTrue(ready.Status.Conditions, "Ready")).To(BeTrue())
+	})
+
+	It("should put back the connection limit before releasing the lock of a failed restore", func() {
+		Expect(reconcile("first").Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "first-restore", Namespace: "shop"}, job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+
+		By("retrying while the database cannot be reached")
+		restoreConnectionLimits.set = func(context.Context, adminConnection, int32) error {
+			return fmt.Errorf("connection refused")
+		}
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "first", Namespace: "shop"}})
+		Expect(err).To(HaveOccurred())
+		restore := &databasesv1alpha1.DatabaseRestore{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "first", Namespace: "shop"}, restore)).To(Succeed())
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+		Expect(restore.Status.Progress).To(Equal("Restoring the connection limit"))
+		Expect(activeOperation(database())).To(Equal("Restore/first"))
+
+		connectionLimits = stubConnectionLimits(100)
+		restore = reconcile("first")
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseFailed))
+		Expect(*connectionLimits).To(Equal([]int32{100}))
+		Expect(activeOperation(database())).To(BeEmpty())
+	})
+
+	It("should put back the connection limit of a restore deleted while its Job runs", func() {
+		Expect(reconcile("first").Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+		restore := &databasesv1alpha1.DatabaseRestore{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "first", Namespace: "shop"}, restore)).To(Succeed())
+		Expect(reconciler.Delete(ctx, restore)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "first", Namespace: "shop"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*connectionLimits).To(Equal([]int32{100}))
+		Expect(activeOperation(database())).To(BeEmpty())
+	})
+
+	It("should download S3 backups before restoring them", func() {
+		s3 := databasesv1alpha1.RestoreSource{S3: &databasesv1alpha1.S3Source{
+			URI:               "s3://archive/orders/nightly.dump",
+			CredentialsSecret: "s3-credentials",
+		}}
+		job, err := reconciler.databaseReconciler(reconciler.Client, reconciler.Scheme).createRestoreJob(database(), newRestore("from-s3", s3), "", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(ContainElement(HaveField("EmptyDir", Not(BeNil()))))
+		Expect(podSpec.InitContainers).To(HaveLen(1))
+		Expect(podSpec.InitContainers[0].Image).To(Equal(defaultRestoreDownloadImage))
+		Expect(podSpec.InitContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "RESTORE_FILE", Value: "/restore/nightly.dump"}))
+		Expect(podSpec.InitContainers[0].Env).To(ContainElement(HaveField("Name", "AWS_SECRET_ACCESS_KEY")))
+	})
+
+	It("should report the restore step from the Job pods", func() {
+		running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+		download := corev1.Pod{Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{{Name: restoreDownloadContainer, State: running}},
+		}}
+		restoring := corev1.Pod{Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: restoreComponent, State: running}},
+		}}
+
+		Expect(restoreProgress(nil)).To(Equal("Starting"))
+		Expect(restoreProgress([]corev1.Pod{download})).To(Equal("Downloading backup"))
+		Expect(restoreProgress([]corev1.Pod{restoring})).To(Equal("Restoring backup"))
+	})
+
+	It("should reject point-in-time restores of dump backups and engines without restore", func() {
+		restore := newRestore("pitr", fromBackup)
+		now := metav1.Now()
+		restore.Spec.PointInTime = &now
+		Expect(reconciler.Create(ctx, restore)).To(Succeed())
+		Expect(reconcile("pitr").Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseFailed))
+
+		Expect(parseBackupLocation("s3://bucket/key")).Error().To(HaveOccurred())
+		Expect(restoreScripts).NotTo(HaveKey(databasesv1alpha1.DatabaseTypeRedis))
+	})
+})
+
+// passwordReader serves every Secret with the same password, so admin
+// connections of restores resolve without the credentials Secrets
+type passwordReader struct{}
+
+func (passwordReader) Get(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	obj.(*corev1.Secret).Data = map[string][]byte{credentialsPasswordKey: []byte("secret")}
+	return nil
+}
+
+func (passwordReader) List(_ context.Context, _ client.ObjectList, _ ...client.ListOption) error {
+	return nil
+}
+
+// stubConnectionLimits serves the given connection limit to the restores of the
+// spec and returns the limits they put back
+func stubConnectionLimits(limit int32) *[]int32 {
+	restored := &[]int32{}
+	original := restoreConnectionLimits
+	restoreConnectionLimits.get = func(context.Context, adminConnection) (int32, error) {
+		return limit, nil
+	}
+	restoreConnectionLimits.set = func(_ context.Context, _ adminConnection, limit int32) error {
+		*restored = append(*restored, limit)
+		return nil
+	}
+	DeferCleanup(func() {
+		restoreConnectionLimits = original
+	})
+	return restored
+}
@@ -0,0 +1,535 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// databaseUserFinalizer drops the user from the engine before the DatabaseUser goes away
+	databaseUserFinalizer = "databases.database-operator.io/user-finalizer"
+	// databaseUserHashAnnotation records on the Job which spec and password it
+	// applies, or dropUserHash for the Job dropping the user
+	databaseUserHashAnnotation = "databases.database-operator.io/user-hash"
+	dropUserHash               = "drop"
+
+	databaseUserComponentPrefix = "user-"
+	databaseUserPasswordEnv     = "USER_PASSWORD"
+	databaseUserHostKey         = "host"
+	databaseUserPortKey         = "port"
+
+	// databaseUserResyncInterval picks up passwords changed in referenced Secrets
+	databaseUserResyncInterval = 5 * time.Minute
+)
+
+// privilegedRoles are the built-in roles a DatabaseUser is never granted: they
+// reach the files and programs of the server, or administer every user
+var privilegedRoles = map[databasesv1alpha1.DatabaseType][]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: {
+		"postgres", "pg_execute_server_program", "pg_read_server_files", "pg_write_server_files",
+	},
+	databasesv1alpha1.DatabaseTypeMongoDB: {
+		"root", "__system", "userAdmin", "userAdminAnyDatabase", "dbOwner", "restore",
+	},
+}
+
+// DatabaseUserReconciler reconciles a DatabaseUser object
+type DatabaseUserReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	ReconcilerOptions
+	// Proxy is the HTTP proxy of generated Jobs
+	Proxy ProxyConfig
+}
+
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaseusers,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaseusers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databaseusers/finalizers,verbs=update
+
+// Reconcile creates or updates the user in the engine through an admin Job
+// whenever its spec or password changes, and drops it when the DatabaseUser is
+// deleted
+func (r *DatabaseUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	user := &databasesv1alpha1.DatabaseUser{}
+	if err := r.Get(ctx, req.NamespacedName, user); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	database := &databasesv1alpha1.Database{}
+	if err := r.Get(ctx, types.NamespacedName{Name: user.Spec.DatabaseRef.Name, Namespace: user.Namespace}, database); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		database = nil
+	} else {
+		ctx = withDatabaseLogger(ctx, database)
+	}
+
+	originalStatus := user.Status.DeepCopy()
+	var result ctrl.Result
+	var err error
+	if !user.DeletionTimestamp.IsZero() {
+		err = r.finalizeDatabaseUser(ctx, user, database)
+	} else {
+		if controllerutil.AddFinalizer(user, databaseUserFinalizer) {
+			if err := r.Update(ctx, user); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		result, err = r.reconcileDatabaseUser(ctx, user, database)
+	}
+	if err != nil {
+		log.Error(err, "Failed to reconcile DatabaseUser")
+	}
+
+	if user.DeletionTimestamp.IsZero() || controllerutil.ContainsFinalizer(user, databaseUserFinalizer) {
+		if !equality.Semantic.DeepEqual(originalStatus, &user.Status) {
+			if err := r.Status().Update(ctx, user); err != nil {
+				log.Error(err, "Failed to update DatabaseUser status")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	return result, err
+}
+
+func (r *DatabaseUserReconciler) reconcileDatabaseUser(ctx context.Context, user *databasesv1alpha1.DatabaseUser, database *databasesv1alpha1.Database) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	user.Status.ObservedGeneration = user.Generation
+
+	if database == nil {
+		setUserPending(user, fmt.Sprintf("Database %q not found", user.Spec.DatabaseRef.Name))
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+	if err := r.validateDatabaseUser(ctx, user, database); err != nil {
+		failUser(user, err.Error())
+		return ctrl.Result{}, nil
+	}
+	// Wait for a server accepting connections
+	if database.Status.ReadyReplicas == 0 {
+		setUserPending(user, fmt.Sprintf("Waiting for Database %s to be ready", database.Name))
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+
+	secret, version, err := r.userPasswordSecret(ctx, user, database)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if secret == nil {
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+	hash := databaseUserHash(user, version)
+
+	job, err := r.userJob(ctx, user, database)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if job != nil {
+		finished, succeeded := jobFinished(job)
+		if job.Annotations[databaseUserHashAnnotation] == hash && !finished {
+			return ctrl.Result{}, nil
+		}
+
+		// Remove finished Jobs and Jobs applying an outdated spec; the Job
+		// deletion event triggers the next step
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if job.Annotations[databaseUserHashAnnotation] == hash {
+			if !succeeded {
+				failUser(user, fmt.Sprintf("Failed to apply user %s, see the logs of Job %s", user.Spec.Username, job.Name))
+				return ctrl.Result{}, fmt.Errorf("failed to apply user %s", user.Spec.Username)
+			}
+			log.Info("Applied database user", "username", user.Spec.Username, "hash", hash)
+			user.Status.AppliedHash = hash
+			user.Status.Phase = databasesv1alpha1.DatabaseUserPhaseReady
+			user.Status.Message = fmt.Sprintf("User %s is provisioned in Database %s", user.Spec.Username, database.Name)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if user.Status.AppliedHash == hash {
+		return ctrl.Result{RequeueAfter: databaseUserResyncInterval}, nil
+	}
+
+	job = r.databaseReconciler(r.Client, r.Scheme).createAdminJob(database, databaseUserComponent(user), databaseUserScript(database, user),
+		[]corev1.EnvVar{passwordEnv(databaseUserPasswordEnv, secret)})
+	job.Annotations = map[string]string{databaseUserHashAnnotation: hash}
+	if err := controllerutil.SetControllerReference(user, job, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Applying database user", "username", user.Spec.Username, "hash", hash)
+	if user.Status.Phase != databasesv1alpha1.DatabaseUserPhaseReady {
+		setUserPending(user, fmt.Sprintf("Applying user %s in Job %s", user.Spec.Username, job.Name))
+	}
+	return ctrl.Result{}, r.Create(ctx, job)
+}
+
+// finalizeDatabaseUser drops the user from the engine, then removes the
+// finalizer. Users that were never applied, or whose Database is gone or being
+// deleted, have nothing to drop.
+func (r *DatabaseUserReconciler) finalizeDatabaseUser(ctx context.Context, user *databasesv1alpha1.DatabaseUser, database *databasesv1alpha1.Database) error {
+	if !controllerutil.ContainsFinalizer(user, databaseUserFinalizer) {
+		return nil
+	}
+
+	dropped := true
+	if database != nil && database.DeletionTimestamp.IsZero() && user.Status.AppliedHash != "" {
+		var err error
+		if dropped, err = r.dropDatabaseUser(ctx, user, database); err != nil || !dropped {
+			return err
+		}
+	}
+
+	if dropped && controllerutil.RemoveFinalizer(user, databaseUserFinalizer) {
+		return r.Update(ctx, user)
+	}
+	return nil
+}
+
+// dropDatabaseUser runs the Job dropping the user and reports whether it succeeded
+func (r *DatabaseUserReconciler) dropDatabaseUser(ctx context.Context, user *databasesv1alpha1.DatabaseUser, database *databasesv1alpha1.Database) (bool, error) {
+	job, err := r.userJob(ctx, user, database)
+	if err != nil {
+		return false, err
+	}
+	if job != nil {
+		finished, succeeded := jobFinished(job)
+		drop := job.Annotations[databaseUserHashAnnotation] == dropUserHash
+		if drop && !finished {
+			return false, nil
+		}
+		// Apply Jobs are replaced by the drop Job once deleted
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		if !drop {
+			return false, nil
+		}
+		if !succeeded {
+			failUser(user, fmt.Sprintf("Failed to drop user %s, see the logs of Job %s; reassign or drop the objects it owns", user.Spec.Username, job.Name))
+			return false, fmt.Errorf("failed to drop user %s", user.Spec.Username)
+		}
+		log.FromContext(ctx).Info("Dropped database user", "username", user.Spec.Username)
+		return true, nil
+	}
+
+	job = r.databaseReconciler(r.Client, r.Scheme).createAdminJob(database, databaseUserComponent(user), dropDatabaseUserScript(database, user), nil)
+	job.Annotations = map[string]string{databaseUserHashAnnotation: dropUserHash}
+	if err := controllerutil.SetControllerReference(user, job, r.Scheme); err != nil {
+		return false, err
+	}
+
+	log.FromContext(ctx).Info("Dropping database user", "username", user.Spec.Username)
+	user.Status.Message = fmt.Sprintf("Dropping user %s in Job %s", user.Spec.Username, job.Name)
+	return false, r.Create(ctx, job)
+}
+
+// validateDatabaseUser checks that the engine manages users and that no other
+// user definition claims the username
+func (r *DatabaseUserReconciler) validateDatabaseUser(ctx context.Context, user *databasesv1alpha1.DatabaseUser, database *databasesv1alpha1.Database) error {
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL, databasesv1alpha1.DatabaseTypeMongoDB:
+	default:
+		return fmt.Errorf("%s does not support database users", database.Spec.Type)
+	}
+	if database.Spec.TargetCluster != nil {
+		return fmt.Errorf("targetCluster does not support database users")
+	}
+	if err := validateGrants(database.Spec.Type, user.Spec.Grants); err != nil {
+		return err
+	}
+
+	username := user.Spec.Username
+	if slices.Contains(reservedUsernames(database), username) {
+		return fmt.Errorf("user %s is managed by Database %s", username, database.Name)
+	}
+	if err := validateDatabaseUserRoles(user, database); err != nil {
+		return err
+	}
+
+	// The oldest DatabaseUser or LogicalDatabase of a username owns it
+	users := &databasesv1alpha1.DatabaseUserList{}
+	if err := r.List(ctx, users, client.InNamespace(user.Namespace)); err != nil {
+		return err
+	}
+	for _, other := range users.Items {
+		if other.Name == user.Name || other.Spec.DatabaseRef.Name != database.Name || other.Spec.Username != username {
+			continue
+		}
+		if claimedBefore(&other, user) {
+			return fmt.Errorf("user %s is managed by DatabaseUser %s", username, other.Name)
+		}
+	}
+	logicals := &databasesv1alpha1.LogicalDatabaseList{}
+	if err := r.List(ctx, logicals, client.InNamespace(user.Namespace)); err != nil {
+		return err
+	}
+	for _, logical := range logicals.Items {
+		if logical.Spec.DatabaseRef.Name == database.Name && logical.Spec.Owner == username && claimedBefore(&logical, user) {
+			return fmt.Errorf("user %s is managed by LogicalDatabase %s", username, logical.Name)
+		}
+	}
+	return nil
+}
+
+// userPasswordSecret returns the Secret key holding the password of the user
+// and the resourceVersion of the Secret, generating it when the spec has no
+// passwordSecret. It returns nil while the Secret is unusable.
+func (r *DatabaseUserReconciler) userPasswordSecret(ctx context.Context, user *databasesv1alpha1.DatabaseUser, database *databasesv1alpha1.Database) (*databasesv1alpha1.SecretReference, string, error) {
+	if reference := user.Spec.PasswordSecret; reference != nil {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: reference.Name, Namespace: user.Namespace}, secret); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, "", err
+			}
+			setUserPending(user, fmt.Sprintf("Secret %q not found", reference.Name))
+			return nil, "", nil
+		}
+		if _, ok := secret.Data[reference.Key]; !ok {
+			setUserPending(user, fmt.Sprintf("Secret %q has no %q key", reference.Name, reference.Key))
+			return nil, "", nil
+		}
+		user.Status.SecretName = secret.Name
+		return reference, secret.ResourceVersion, nil
+	}
+
+	name := user.Spec.SecretName
+	if name == "" {
+		name = user.Name
+	}
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: user.Namespace}, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, "", err
+	}
+	if err == nil {
+		if !metav1.IsControlledBy(secret, user) {
+			failUser(user, fmt.Sprintf("Secret %s exists and is not managed by DatabaseUser %s", name, user.Name))
+			return nil, "", nil
+		}
+		if _, ok := secret.Data[credentialsPasswordKey]; !ok {
+			return nil, "", fmt.Errorf("secret %s has no %q key", name, credentialsPasswordKey)
+		}
+	} else {
+		password, err := randomPassword(generatedPasswordLength)
+		if err != nil {
+			return nil, "", err
+		}
+		port := r.databaseReconciler(r.Client, r.Scheme).getServicePorts(database)[0].Port
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: user.Namespace,
+				Labels:    r.databaseReconciler(r.Client, r.Scheme).getComponentLabels(database, databaseUserComponent(user)),
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{
+				credentialsUsernameKey: []byte(user.Spec.Username),
+				credentialsPasswordKey: []byte(password),
+				databaseUserHostKey:    []byte(serviceHost(database)),
+				databaseUserPortKey:    []byte(strconv.Itoa(int(port))),
+			},
+		}
+		if err := controllerutil.SetControllerReference(user, secret, r.Scheme); err != nil {
+			return nil, "", err
+		}
+		log.FromContext(ctx).Info("Creating credentials Secret", "name", name)
+		if err := r.Create(ctx, secret); err != nil {
+			return nil, "", err
+		}
+	}
+
+	user.Status.SecretName = secret.Name
+	return &databasesv1alpha1.SecretReference{Name: secret.Name, Key: credentialsPasswordKey}, secret.ResourceVersion, nil
+}
+
+// userJob returns the Job applying or dropping the user, or nil
+func (r *DatabaseUserReconciler) userJob(ctx context.Context, user *databasesv1alpha1.DatabaseUser, database *databasesv1alpha1.Database) (*batchv1.Job, error) {
+	job := &batchv1.Job{}
+	name := database.Name + "-" + databaseUserComponent(user)
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: user.Namespace}, job); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return job, nil
+}
+
+// validateDatabaseUserRoles rejects roles granting the privileges of the server
+// or of the users managed by the Database. MongoDB roles and grants on the admin
+// database administer every user, so only the roles outside privilegedRoles are
+// granted there.
+func validateDatabaseUserRoles(user *databasesv1alpha1.DatabaseUser, database *databasesv1alpha1.Database) error {
+	reserved := reservedUsernames(database)
+	for _, role := range user.Spec.Roles {
+		if slices.Contains(privilegedRoles[database.Spec.Type], string(role)) ||
+			(database.Spec.Type == databasesv1alpha1.DatabaseTypePostgreSQL && slices.Contains(reserved, string(role))) {
+			return fmt.Errorf("role %s cannot be granted to a DatabaseUser", role)
+		}
+	}
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB {
+		for _, grant := range user.Spec.Grants {
+			if grant.Database == "admin" {
+				return fmt.Errorf("grants on the admin database are not supported, use roles")
+			}
+		}
+	}
+	return nil
+}
+
+// databaseUserComponent names the Jobs and Secret of a DatabaseUser
+func databaseUserComponent(user *databasesv1alpha1.DatabaseUser) string {
+	return databaseUserComponentPrefix + user.Name
+}
+
+// databaseUserHash identifies the spec and the password version applied by a Job
+func databaseUserHash(user *databasesv1alpha1.DatabaseUser, secretVersion string) string {
+	spec, _ := json.Marshal(user.Spec)
+	sum := sha256.Sum256(append(spec, secretVersion...))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// databaseUserScript renders the idempotent commands creating the user or
+// updating its password and privileges. Privileges and roles missing from the
+// spec are revoked, in the same transaction on PostgreSQL.
+func databaseUserScript(database *databasesv1alpha1.Database, user *databasesv1alpha1.DatabaseUser) string {
+	username := user.Spec.Username
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB {
+		roles := []string{}
+		for _, grant := range user.Spec.Grants {
+			for _, privilege := range grant.Privileges {
+				roles = append(roles, fmt.Sprintf("{role: %q, db: %q}", privilege, grant.Database))
+			}
+		}
+		for _, role := range user.Spec.Roles {
+			roles = append(roles, fmt.Sprintf("{role: %q, db: \"admin\"}", role))
+		}
+		return mongoDBUserScript(fmt.Sprintf(`const roles = [%[2]s];
+if (admin.getUser(%[1]q)) admin.updateUser(%[1]q, {pwd: process.env.%[3]s, roles: roles});
+else admin.createUser({user: %[1]q, pwd: process.env.%[3]s, roles: roles});
+`, username, strings.Join(roles, ", "), databaseUserPasswordEnv))
+	}
+
+	var sql strings.Builder
+	sql.WriteString(psqlPasswordVariable("password", databaseUserPasswordEnv))
+	fmt.Fprintf(&sql, "SELECT 'CREATE ROLE \"%[1]s\" LOGIN' WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '%[1]s')\\gexec\n", username)
+	fmt.Fprintf(&sql, "ALTER ROLE \"%s\" LOGIN PASSWORD :'password';\n", username)
+	sql.WriteString(revokeDatabasePrivilegesSQL(username))
+	fmt.Fprintf(&sql, "SELECT format('REVOKE %%I FROM \"%s\"', r.rolname) FROM pg_auth_members m JOIN pg_roles r ON r.oid = m.roleid "+
+		"WHERE m.member = (SELECT oid FROM pg_roles WHERE rolname = '%[1]s')\\gexec\n", username)
+	for _, grant := range user.Spec.Grants {
+		privileges := []string{}
+		for _, privilege := range grant.Privileges {
+			privileges = append(privileges, string(privilege))
+		}
+		fmt.Fprintf(&sql, "GRANT %s ON DATABASE \"%s\" TO \"%s\";\n", strings.Join(privileges, ", "), grant.Database, username)
+	}
+	for _, role := range user.Spec.Roles {
+		fmt.Fprintf(&sql, "GRANT \"%s\" TO \"%s\";\n", role, username)
+	}
+	return fmt.Sprintf("psql -h \"$DB_HOST\" -v ON_ERROR_STOP=1 --single-transaction <<'SQL'\n%sSQL", sql.String())
+}
+
+// dropDatabaseUserScript renders the commands dropping the user if it exists.
+// PostgreSQL refuses to drop users owning objects.
+func dropDatabaseUserScript(database *databasesv1alpha1.Database, user *databasesv1alpha1.DatabaseUser) string {
+	username := user.Spec.Username
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB {
+		return mongoDBUserScript(fmt.Sprintf("if (admin.getUser(%[1]q)) admin.dropUser(%[1]q);\n", username))
+	}
+	return fmt.Sprintf("psql -h \"$DB_HOST\" -v ON_ERROR_STOP=1 --single-transaction <<'SQL'\n%sDROP ROLE IF EXISTS \"%s\";\nSQL",
+		revokeDatabasePrivilegesSQL(username), username)
+}
+
+// revokeDatabasePrivilegesSQL revokes the privileges of a user on every database
+func revokeDatabasePrivilegesSQL(username string) string {
+	return fmt.Sprintf("SELECT DISTINCT format('REVOKE ALL ON DATABASE %%I FROM \"%[1]s\"', d.datname) FROM pg_database d, aclexplode(d.datacl) a "+
+		"WHERE a.grantee = (SELECT oid FROM pg_roles WHERE rolname = '%[1]s')\\gexec\n", username)
+}
+
+// mongoDBUserScript runs JavaScript against the admin database of MongoDB
+func mongoDBUserScript(js string) string {
+	return fmt.Sprintf(`cat > /tmp/user.js <<'JS'
+const admin = db.getSiblingDB("admin");
+%sJS
+mongosh --host "$DB_HOST" -u "$MONGO_USERNAME" -p "$MONGO_PASSWORD" --authenticationDatabase admin --quiet --file /tmp/user.js`, js)
+}
+
+func setUserPending(user *databasesv1alpha1.DatabaseUser, message string) {
+	user.Status.Phase = databasesv1alpha1.DatabaseUserPhasePending
+	user.Status.Message = message
+}
+
+func failUser(user *databasesv1alpha1.DatabaseUser, message string) {
+	user.Status.Phase = databasesv1alpha1.DatabaseUserPhaseFailed
+	user.Status.Message = message
+}
+
+// usersOfDatabase enqueues the DatabaseUsers of a Database, so they are applied
+// once it becomes ready
+func (r *DatabaseUserReconciler) usersOfDatabase(ctx context.Context, database client.Object) []reconcile.Request {
+	users := &databasesv1alpha1.DatabaseUserList{}
+	if err := r.List(ctx, users, client.InNamespace(database.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list DatabaseUsers", "database", database.GetName())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, user := range users.Items {
+		if user.Spec.DatabaseRef.Name == database.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&user)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DatabaseUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasesv1alpha1.DatabaseUser{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.Secret{}).
+		Watches(&databasesv1alpha1.Database{}, handler.EnqueueRequestsFromMapFunc(r.usersOfDatabase)).
+		Named("databaseuser").
+		Complete(r)
+}
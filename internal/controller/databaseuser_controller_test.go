@@ -0,0 +1,211 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("DatabaseUser Controller", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseUserReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	userKey := types.NamespacedName{Name: "reporting", Namespace: "shop"}
+	jobKey := types.NamespacedName{Name: "orders-user-reporting", Namespace: "shop"}
+
+	newUser := func(name, username string) *databasesv1alpha1.DatabaseUser {
+		return &databasesv1alpha1.DatabaseUser{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseUserSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: "orders"},
+				Username:    username,
+				Grants: []databasesv1alpha1.BootstrapGrant{
+					{Database: "orders", Privileges: []databasesv1alpha1.BootstrapPrivilege{databasesv1alpha1.BootstrapPrivilegeConnect}},
+				},
+				Roles: []databasesv1alpha1.BootstrapIdentifier{"pg_read_all_data"},
+			},
+		}
+	}
+
+	reconcile := func() *databasesv1alpha1.DatabaseUser {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: userKey})
+		Expect(err).NotTo(HaveOccurred())
+		user := &databasesv1alpha1.DatabaseUser{}
+		if err := reconciler.Get(ctx, userKey, user); apierrors.IsNotFound(err) {
+			return nil
+		}
+		return user
+	}
+
+	completeJob := func() {
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Bootstrap: &databasesv1alpha1.BootstrapSpec{
+					Users: []databasesv1alpha1.BootstrapUser{{Name: "app"}},
+				},
+			},
+			Status: databasesv1alpha1.DatabaseStatus{ReadyReplicas: 1},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.DatabaseUser{}, &batchv1.Job{}).
+			WithObjects(database, newUser("reporting", "reporting")).
+			Build()
+		reconciler = &DatabaseUserReconciler{Client: c, Scheme: scheme}
+	})
+
+	It("should generate credentials and apply the user in a Job", func() {
+		user := reconcile()
+		Expect(user.Finalizers).To(ContainElement(databaseUserFinalizer))
+		Expect(user.Status.Phase).To(Equal(databasesv1alpha1.DatabaseUserPhasePending))
+		Expect(user.Status.SecretName).To(Equal("reporting"))
+
+		secret := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, userKey, secret)).To(Succeed())
+		Expect(metav1.IsControlledBy(secret, user)).To(BeTrue())
+		Expect(secret.Data).To(HaveKeyWithValue("username", []byte("reporting")))
+		Expect(secret.Data).To(HaveKeyWithValue("host", []byte("orders-service.shop.svc")))
+		Expect(secret.Data).To(HaveKeyWithValue("port", []byte("5432")))
+		Expect(secret.Data["password"]).To(HaveLen(generatedPasswordLength))
+
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(metav1.IsControlledBy(job, user)).To(BeTrue())
+		container := job.Spec.Template.Spec.Containers[0]
+		Expect(container.Env).To(ContainElement(passwordEnv(databaseUserPasswordEnv,
+			&databasesv1alpha1.SecretReference{Name: "reporting", Key: "password"})))
+		Expect(container.Command[2]).To(And(
+			ContainSubstring(`GRANT CONNECT ON DATABASE "orders" TO "reporting";`),
+			ContainSubstring(`GRANT "pg_read_all_data" TO "reporting";`),
+			ContainSubstring("--single-transaction"),
+		))
+
+		completeJob()
+		user = reconcile()
+		Expect(user.Status.Phase).To(Equal(databasesv1alpha1.DatabaseUserPhaseReady))
+		Expect(user.Status.AppliedHash).NotTo(BeEmpty())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		By("applying the user again once the spec changes")
+		Expect(reconcile().Status.Phase).To(Equal(databasesv1alpha1.DatabaseUserPhaseReady))
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+		user.Spec.Roles = nil
+		Expect(reconciler.Update(ctx, user)).To(Succeed())
+		reconcile()
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).NotTo(ContainSubstring("pg_read_all_data"))
+	})
+
+	It("should drop applied users before removing the finalizer", func() {
+		reconcile()
+		completeJob()
+		reconcile()
+
+		user := &databasesv1alpha1.DatabaseUser{}
+		Expect(reconciler.Get(ctx, userKey, user)).To(Succeed())
+		Expect(reconciler.Delete(ctx, user)).To(Succeed())
+		Expect(reconcile()).NotTo(BeNil())
+
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue(databaseUserHashAnnotation, dropUserHash))
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring(`DROP ROLE IF EXISTS "reporting";`))
+
+		completeJob()
+		Expect(reconcile()).To(BeNil())
+	})
+
+	It("should reject usernames managed elsewhere", func() {
+		for _, username := range []string{"postgres", "app"} {
+			user := newUser("other", username)
+			Expect(reconciler.validateDatabaseUser(ctx, user, database)).To(MatchError(ContainSubstring("is managed by Database orders")))
+		}
+		duplicate := newUser("duplicate", "reporting")
+		duplicate.CreationTimestamp = metav1.Now()
+		Expect(reconciler.validateDatabaseUser(ctx, duplicate, database)).To(
+			MatchError("user reporting is managed by DatabaseUser reporting"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		Expect(reconciler.validateDatabaseUser(ctx, newUser("reporting", "reporting"), database)).To(
+			MatchError("Redis does not support database users"))
+	})
+
+	It("should reject roles granting the server or managed users", func() {
+		user := newUser("reporting", "reporting")
+		for _, role := range []databasesv1alpha1.BootstrapIdentifier{"postgres", "pg_execute_server_program", "pg_read_server_files", "app"} {
+			user.Spec.Roles = []databasesv1alpha1.BootstrapIdentifier{role}
+			Expect(reconciler.validateDatabaseUser(ctx, user, database)).To(
+				MatchError(ContainSubstring("role " + string(role) + " cannot be granted")))
+		}
+		database.Spec.Observability = &databasesv1alpha1.ObservabilitySpec{Metrics: &databasesv1alpha1.MetricsSpec{Enabled: true}}
+		user.Spec.Roles = []databasesv1alpha1.BootstrapIdentifier{monitoringUsername}
+		Expect(reconciler.validateDatabaseUser(ctx, user, database)).To(MatchError(ContainSubstring("cannot be granted")))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		user.Spec.Grants[0].Privileges = []databasesv1alpha1.BootstrapPrivilege{databasesv1alpha1.BootstrapPrivilegeRead}
+		for _, role := range []databasesv1alpha1.BootstrapIdentifier{"root", "userAdminAnyDatabase", "dbOwner"} {
+			user.Spec.Roles = []databasesv1alpha1.BootstrapIdentifier{role}
+			Expect(reconciler.validateDatabaseUser(ctx, user, database)).To(MatchError(ContainSubstring("cannot be granted")))
+		}
+		user.Spec.Roles = []databasesv1alpha1.BootstrapIdentifier{"clusterMonitor"}
+		Expect(reconciler.validateDatabaseUser(ctx, user, database)).To(Succeed())
+		user.Spec.Grants[0].Database = "admin"
+		Expect(reconciler.validateDatabaseUser(ctx, user, database)).To(MatchError(ContainSubstring("admin database")))
+	})
+
+	It("should render MongoDB users with roles on the admin database", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		user := newUser("reporting", "reporting")
+		user.Spec.Grants[0].Privileges = []databasesv1alpha1.BootstrapPrivilege{databasesv1alpha1.BootstrapPrivilegeRead}
+		user.Spec.Roles = []databasesv1alpha1.BootstrapIdentifier{"clusterMonitor"}
+		Expect(databaseUserScript(database, user)).To(ContainSubstring(
+			`const roles = [{role: "read", db: "orders"}, {role: "clusterMonitor", db: "admin"}];`))
+		Expect(dropDatabaseUserScript(database, user)).To(ContainSubstring(`admin.dropUser("reporting")`))
+	})
+})
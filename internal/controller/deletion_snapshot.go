@@ -0,0 +1,148 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	conditionDeletionBlocked = "DeletionBlocked"
+
+	defaultDeletionSnapshotTimeout  = time.Hour
+	deletionSnapshotRecheckInterval = 15 * time.Second
+)
+
+// deletionSnapshotName is the name of the final backup of a Database. It includes
+// the UID so a recreated Database with the same name takes its own.
+func deletionSnapshotName(database *databasesv1alpha1.Database) string {
+	uid := string(database.UID)
+	if len(uid) > 8 {
+		uid = uid[:8]
+	}
+	return fmt.Sprintf("%s-final-%s", database.Name, uid)
+}
+
+// validateDeletionPolicy rejects a final backup the operator cannot take: the
+// DatabaseBackups of Elasticsearch and of remote clusters are not supported
+func validateDeletionPolicy(database *databasesv1alpha1.Database) error {
+	if database.Spec.DeletionPolicy != databasesv1alpha1.DeletionPolicySnapshot {
+		return nil
+	}
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeElasticsearch {
+		return fmt.Errorf("%s does not support the %s deletionPolicy", database.Spec.Type, databasesv1alpha1.DeletionPolicySnapshot)
+	}
+	if database.Spec.TargetCluster != nil {
+		return fmt.Errorf("targetCluster does not support the %s deletionPolicy", databasesv1alpha1.DeletionPolicySnapshot)
+	}
+	return nil
+}
+
+func deletionSnapshotTimeout(database *databasesv1alpha1.Database) time.Duration {
+	if timeout := database.Spec.DeletionSnapshotTimeout; timeout != nil && timeout.Duration > 0 {
+		return timeout.Duration
+	}
+	return defaultDeletionSnapshotTimeout
+}
+
+// reconcileDeletionSnapshot takes the final backup of a deleted Database with the
+// Snapshot deletion policy and reports whether the deletion may proceed. The
+// backup is not owned by the Database so it outlives it, and the workload keeps
+// running until the finalizer is removed. A failed or timed out backup blocks the
+// deletion until the skip annotation is set.
+func (r *DatabaseReconciler) reconcileDeletionSnapshot(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) (bool, error) {
+	log := log.FromContext(ctx)
+
+	if database.Spec.DeletionPolicy != databasesv1alpha1.DeletionPolicySnapshot {
+		return true, nil
+	}
+	if database.Annotations[databasesv1alpha1.SkipDeletionSnapshotAnnotation] == "true" {
+		log.Info("Deleting without a final backup", "annotation", databasesv1alpha1.SkipDeletionSnapshotAnnotation)
+		return true, nil
+	}
+
+	skip := fmt.Sprintf("set the %s annotation to \"true\" to delete without it", databasesv1alpha1.SkipDeletionSnapshotAnnotation)
+	// Databases accepted before the policy was rejected cannot take the backup
+	if err := validateDeletionPolicy(database); err != nil {
+		return false, r.setDeletionBlocked(ctx, database, "SnapshotUnsupported", fmt.Sprintf("%s; %s", err, skip))
+	}
+
+	name := deletionSnapshotName(database)
+	backup := &databasesv1alpha1.DatabaseBackup{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, backup)
+	if apierrors.IsNotFound(err) {
+		backup = &databasesv1alpha1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: database.Namespace,
+				Labels:    map[string]string{databasesv1alpha1.DatabaseLabel: database.Name},
+			},
+			Spec: databasesv1alpha1.DatabaseBackupSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: database.Name},
+			},
+		}
+		log.Info("Creating final backup before deletion", "name", name)
+		if err := r.Create(ctx, backup); err != nil {
+			return false, err
+		}
+		return false, r.setDeletionBlocked(ctx, database, "SnapshotRunning",
+			fmt.Sprintf("Waiting for the final DatabaseBackup %s", name))
+	} else if err != nil {
+		return false, err
+	}
+
+	switch {
+	case backup.Status.Phase == databasesv1alpha1.DatabaseBackupPhaseCompleted:
+		log.Info("Final backup completed", "name", name, "location", backup.Status.Location)
+		return true, nil
+	case backup.Status.Phase == databasesv1alpha1.DatabaseBackupPhaseFailed:
+		return false, r.setDeletionBlocked(ctx, database, "SnapshotFailed",
+			fmt.Sprintf("Final DatabaseBackup %s failed: %s; %s", name, backup.Status.Message, skip))
+	case now.Sub(backup.CreationTimestamp.Time) > deletionSnapshotTimeout(database):
+		return false, r.setDeletionBlocked(ctx, database, "SnapshotTimedOut",
+			fmt.Sprintf("Final DatabaseBackup %s did not complete within %s; %s", name, deletionSnapshotTimeout(database), skip))
+	default:
+		return false, r.setDeletionBlocked(ctx, database, "SnapshotRunning",
+			fmt.Sprintf("Waiting for the final DatabaseBackup %s", name))
+	}
+}
+
+// setDeletionBlocked reports why the deletion waits in the DeletionBlocked condition
+func (r *DatabaseReconciler) setDeletionBlocked(ctx context.Context, database *databasesv1alpha1.Database, reason, message string) error {
+	changed := meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               conditionDeletionBlocked,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+	if !changed {
+		return nil
+	}
+	return r.Status().Update(ctx, database)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Deletion snapshots", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *DatabaseReconciler
+	)
+
+	key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+	backupKey := types.NamespacedName{Name: "orders-final-5f2b9c1e", Namespace: "shop"}
+
+	reconcile := func() *databasesv1alpha1.Database {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		database := &databasesv1alpha1.Database{}
+		if err := c.Get(ctx, key, database); apierrors.IsNotFound(err) {
+			return nil
+		}
+		return database
+	}
+
+	setBackupStatus := func(status databasesv1alpha1.DatabaseBackupStatus) {
+		backup := &databasesv1alpha1.DatabaseBackup{}
+		Expect(c.Get(ctx, backupKey, backup)).To(Succeed())
+		backup.Status = status
+		Expect(c.Status().Update(ctx, backup)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}, &databasesv1alpha1.DatabaseBackup{}).
+			WithObjects(&databasesv1alpha1.Database{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "orders",
+					Namespace:  "shop",
+					UID:        "5f2b9c1e-7d4a-4e0b-9a53-3c1f0e8d2a71",
+					Finalizers: []string{databaseFinalizer},
+				},
+				Spec: databasesv1alpha1.DatabaseSpec{
+					Type:           databasesv1alpha1.DatabaseTypePostgreSQL,
+					Version:        "16",
+					DeletionPolicy: databasesv1alpha1.DeletionPolicySnapshot,
+				},
+			}).Build()
+		reconciler = &DatabaseReconciler{Client: c, Scheme: c.Scheme()}
+
+		database := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key, database)).To(Succeed())
+		Expect(c.Delete(ctx, database)).To(Succeed())
+	})
+
+	It("should keep the Database until its final backup completed", func() {
+		database := reconcile()
+		Expect(database).NotTo(BeNil())
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionDeletionBlocked)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal("SnapshotRunning"))
+
+		backup := &databasesv1alpha1.DatabaseBackup{}
+		Expect(c.Get(ctx, backupKey, backup)).To(Succeed())
+		Expect(backup.Spec.DatabaseRef.Name).To(Equal("orders"))
+		Expect(backup.OwnerReferences).To(BeEmpty())
+
+		setBackupStatus(databasesv1alpha1.DatabaseBackupStatus{Phase: databasesv1alpha1.DatabaseBackupPhaseCompleted})
+		Expect(reconcile()).To(BeNil())
+		Expect(c.Get(ctx, backupKey, backup)).To(Succeed())
+	})
+
+	It("should block the deletion after a failed backup until the skip annotation is set", func() {
+		reconcile()
+		setBackupStatus(databasesv1alpha1.DatabaseBackupStatus{
+			Phase:   databasesv1alpha1.DatabaseBackupPhaseFailed,
+			Message: "Backup Job orders-final-5f2b9c1e-backup failed",
+		})
+
+		database := reconcile()
+		Expect(database).NotTo(BeNil())
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionDeletionBlocked)
+		Expect(condition.Reason).To(Equal("SnapshotFailed"))
+		Expect(condition.Message).To(ContainSubstring(databasesv1alpha1.SkipDeletionSnapshotAnnotation))
+
+		database.Annotations = map[string]string{databasesv1alpha1.SkipDeletionSnapshotAnnotation: "true"}
+		Expect(c.Update(ctx, database)).To(Succeed())
+		Expect(reconcile()).To(BeNil())
+	})
+
+	It("should block the deletion once the backup timed out", func() {
+		database := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key, database)).To(Succeed())
+		Expect(c.Create(ctx, &databasesv1alpha1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: backupKey.Name, Namespace: "shop"},
+		})).To(Succeed())
+		backup := &databasesv1alpha1.DatabaseBackup{}
+		Expect(c.Get(ctx, backupKey, backup)).To(Succeed())
+
+		done, err := reconciler.reconcileDeletionSnapshot(ctx, database,
+			backup.CreationTimestamp.Add(defaultDeletionSnapshotTimeout+1))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeFalse())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionDeletionBlocked).Reason).
+			To(Equal("SnapshotTimedOut"))
+	})
+
+	It("should reject final backups of Elasticsearch and of remote clusters", func() {
+		database := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key, database)).To(Succeed())
+		Expect(validateDeletionPolicy(database)).To(Succeed())
+		database.Spec.TargetCluster = &databasesv1alpha1.TargetClusterSpec{}
+		Expect(validateDeletionPolicy(database)).To(MatchError(ContainSubstring("targetCluster does not support")))
+
+		database.Spec.TargetCluster = nil
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeElasticsearch
+		Expect(validateDeletionPolicy(database)).To(MatchError("Elasticsearch does not support the Snapshot deletionPolicy"))
+
+		// An Elasticsearch Database accepted earlier waits for the skip annotation
+		done, err := reconciler.reconcileDeletionSnapshot(ctx, database, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeFalse())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionDeletionBlocked).Reason).
+			To(Equal("SnapshotUnsupported"))
+		Expect(apierrors.IsNotFound(c.Get(ctx, backupKey, &databasesv1alpha1.DatabaseBackup{}))).To(BeTrue())
+	})
+})
@@ -0,0 +1,451 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups="",resources=nodes/proxy,verbs=get
+
+const (
+	conditionDiskPressure = "DiskPressure"
+	readOnlyComponent     = "read-only"
+	// readOnlyAnnotation records on the Job whether it pauses or resumes writes
+	readOnlyAnnotation = "databases.database-operator.io/read-only"
+
+	// diskUsageCheckInterval is how often volume usage is read
+	diskUsageCheckInterval = time.Minute
+
+	defaultDiskWarningPercent  = int32(80)
+	defaultDiskHighPercent     = int32(90)
+	defaultDiskCriticalPercent = int32(95)
+	defaultAutoExpandPercent   = int32(85)
+)
+
+// Disk pressure levels, in increasing order
+const (
+	diskPressureNone     = ""
+	diskPressureWarning  = "Warning"
+	diskPressureHigh     = "High"
+	diskPressureCritical = "Critical"
+)
+
+var diskPressureLevels = []string{diskPressureNone, diskPressureWarning, diskPressureHigh, diskPressureCritical}
+
+// Scripts pausing writes when $READ_ONLY is true and resuming them otherwise.
+// ALTER SYSTEM is local to a PostgreSQL server, so every replica resolved from
+// $PEERS_HOST is set.
+var readOnlyScripts = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: `set -e
+if [ "$READ_ONLY" = true ]; then setting="SET default_transaction_read_only = on"; else setting="RESET default_transaction_read_only"; fi
+hosts=$(getent hosts "$PEERS_HOST" | cut -d' ' -f1)
+if [ -z "$hosts" ]; then
+  echo "No PostgreSQL server found behind $PEERS_HOST" >&2
+  exit 1
+fi
+for host in $hosts; do
+  psql -h "$host" -v ON_ERROR_STOP=1 -c "ALTER SYSTEM $setting" -c "SELECT pg_reload_conf()"
+done`,
+	databasesv1alpha1.DatabaseTypeElasticsearch: `if [ "$READ_ONLY" = true ]; then value=true; else value=null; fi
+curl -sf -X PUT "http://$DB_HOST:9200/_cluster/settings" -H 'Content-Type: application/json' \
+  -d "{\"persistent\":{\"cluster.blocks.read_only_allow_delete\":$value}}" > /dev/null`,
+}
+
+// VolumeUsage is the space used on a volume
+type VolumeUsage struct {
+	UsedBytes     int64
+	CapacityBytes int64
+}
+
+// VolumeUsageReader reads the usage of the PersistentVolumeClaims mounted by a
+// pod, keyed by claim name
+type VolumeUsageReader interface {
+	VolumeUsage(ctx context.Context, pod *corev1.Pod) (map[string]VolumeUsage, error)
+}
+
+// KubeletStatsReader reads volume usage from the stats summary of the kubelet
+// running the pod, through the node proxy of the API server
+type KubeletStatsReader struct {
+	Client rest.Interface
+}
+
+// NewKubeletStatsReader returns a KubeletStatsReader using the given API server
+func NewKubeletStatsReader(config *rest.Config) (*KubeletStatsReader, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &KubeletStatsReader{Client: clientset.CoreV1().RESTClient()}, nil
+}
+
+// kubeletSummary is the part of the kubelet stats summary reporting volumes
+type kubeletSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Volumes []struct {
+			UsedBytes     *int64 `json:"usedBytes"`
+			CapacityBytes *int64 `json:"capacityBytes"`
+			PVCRef        *struct {
+				Name string `json:"name"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// VolumeUsage returns the usage of the claims of the pod reported by its kubelet
+func (r *KubeletStatsReader) VolumeUsage(ctx context.Context, pod *corev1.Pod) (map[string]VolumeUsage, error) {
+	raw, err := r.Client.Get().Resource("nodes").Name(pod.Spec.NodeName).
+		SubResource("proxy").Suffix("stats/summary").DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseVolumeUsage(raw, pod)
+}
+
+// parseVolumeUsage extracts the claims of a pod from a kubelet stats summary
+func parseVolumeUsage(raw []byte, pod *corev1.Pod) (map[string]VolumeUsage, error) {
+	summary := kubeletSummary{}
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return nil, fmt.Errorf("invalid stats summary of node %s: %w", pod.Spec.NodeName, err)
+	}
+
+	usage := map[string]VolumeUsage{}
+	for _, stats := range summary.Pods {
+		if stats.PodRef.Name != pod.Name || stats.PodRef.Namespace != pod.Namespace {
+			continue
+		}
+		for _, volume := range stats.Volumes {
+			if volume.PVCRef == nil || volume.UsedBytes == nil || volume.CapacityBytes == nil || *volume.CapacityBytes == 0 {
+				continue
+			}
+			usage[volume.PVCRef.Name] = VolumeUsage{UsedBytes: *volume.UsedBytes, CapacityBytes: *volume.CapacityBytes}
+		}
+	}
+	return usage, nil
+}
+
+// diskPressureThresholds returns the warning, high and critical thresholds
+func diskPressureThresholds(database *databasesv1alpha1.Database) (warning, high, critical int32) {
+	warning, high, critical = defaultDiskWarningPercent, defaultDiskHighPercent, defaultDiskCriticalPercent
+	if spec := database.Spec.DiskPressure; spec != nil {
+		if spec.WarningPercent > 0 {
+			warning = spec.WarningPercent
+		}
+		if spec.HighPercent > 0 {
+			high = spec.HighPercent
+		}
+		if spec.CriticalPercent > 0 {
+			critical = spec.CriticalPercent
+		}
+	}
+	return warning, high, critical
+}
+
+// validateDiskPressure checks that the engine can pause writes when asked to,
+// and the sizes of the automatic expansion
+func validateDiskPressure(database *databasesv1alpha1.Database) error {
+	spec := database.Spec.DiskPressure
+	if spec == nil {
+		return nil
+	}
+	if _, ok := readOnlyScripts[database.Spec.Type]; spec.ReadOnlyOnCritical && !ok {
+		return fmt.Errorf("%s does not support diskPressure.readOnlyOnCritical", database.Spec.Type)
+	}
+	if expand := spec.AutoExpand; expand != nil {
+		if database.Spec.Storage == nil || len(elasticsearchNodeSets(database)) > 0 {
+			return fmt.Errorf("diskPressure.autoExpand requires spec.storage, without Elasticsearch node sets")
+		}
+		if step, err := resource.ParseQuantity(expand.Step); err != nil || step.Sign() <= 0 {
+			return fmt.Errorf("invalid diskPressure.autoExpand.step %q", expand.Step)
+		}
+		if _, err := resource.ParseQuantity(expand.MaxSize); err != nil {
+			return fmt.Errorf("invalid diskPressure.autoExpand.maxSize %q: %w", expand.MaxSize, err)
+		}
+	}
+	return nil
+}
+
+// diskPressureLevel returns the highest threshold the usage reaches
+func diskPressureLevel(database *databasesv1alpha1.Database, percent int32) string {
+	warning, high, critical := diskPressureThresholds(database)
+	switch {
+	case percent >= critical:
+		return diskPressureCritical
+	case percent >= high:
+		return diskPressureHigh
+	case percent >= warning:
+		return diskPressureWarning
+	}
+	return diskPressureNone
+}
+
+// reconcileDiskPressure reads the usage of the data volumes, reports the fullest
+// in status and the DiskPressure condition, and pauses writes at the critical
+// threshold when the spec asks for it
+func (r *DatabaseReconciler) reconcileDiskPressure(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) error {
+	if database.Spec.Storage == nil || r.VolumeUsageReader == nil {
+		database.Status.DiskUsage = nil
+		meta.RemoveStatusCondition(&database.Status.Conditions, conditionDiskPressure)
+		return nil
+	}
+
+	if usage := database.Status.DiskUsage; usage == nil || now.Sub(usage.CheckedAt.Time) >= diskUsageCheckInterval {
+		if err := r.readDiskUsage(ctx, database, now); err != nil {
+			// Volume usage is advisory, the Database keeps being reconciled
+			log.FromContext(ctx).Error(err, "Failed to read volume usage")
+			meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+				Type:               conditionDiskPressure,
+				Status:             metav1.ConditionUnknown,
+				Reason:             "UsageUnavailable",
+				Message:            err.Error(),
+				ObservedGeneration: database.Generation,
+			})
+		}
+	}
+
+	r.autoExpandVolumes(ctx, database)
+	return r.reconcileReadOnly(ctx, database)
+}
+
+// readDiskUsage records the usage of the fullest volume of the running pods and
+// raises a Warning Event whenever it reaches a higher threshold
+func (r *DatabaseReconciler) readDiskUsage(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) error {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(database.Namespace), client.MatchingLabels(r.getLabels(database))); err != nil {
+		return err
+	}
+
+	var fullest *databasesv1alpha1.DiskUsageStatus
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.Spec.NodeName == "" {
+			continue
+		}
+		usage, err := r.VolumeUsageReader.VolumeUsage(ctx, pod)
+		if err != nil {
+			return err
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+			claim := volume.PersistentVolumeClaim.ClaimName
+			stats, ok := usage[claim]
+			if !ok {
+				continue
+			}
+			percent := int32(stats.UsedBytes * 100 / stats.CapacityBytes)
+			if fullest == nil || percent > fullest.Percent {
+				fullest = &databasesv1alpha1.DiskUsageStatus{
+					Volume:        claim,
+					UsedBytes:     stats.UsedBytes,
+					CapacityBytes: stats.CapacityBytes,
+					Percent:       percent,
+				}
+			}
+		}
+	}
+	if fullest == nil {
+		// Nothing runs yet, keep what was last read
+		return nil
+	}
+
+	if previous := database.Status.DiskUsage; previous != nil {
+		fullest.ReadOnly = previous.ReadOnly
+		fullest.ExpandedSize = previous.ExpandedSize
+	}
+	fullest.CheckedAt = metav1.NewTime(now)
+	database.Status.DiskUsage = fullest
+
+	previousLevel := diskPressureNone
+	if condition := meta.FindStatusCondition(database.Status.Conditions, conditionDiskPressure); condition != nil &&
+		condition.Status == metav1.ConditionTrue {
+		previousLevel = condition.Reason[len("Usage"):]
+	}
+	level := diskPressureLevel(database, fullest.Percent)
+	condition := metav1.Condition{
+		Type:               conditionDiskPressure,
+		Status:             metav1.ConditionFalse,
+		Reason:             "UsageNormal",
+		Message:            fmt.Sprintf("Volume %s is %d%% full", fullest.Volume, fullest.Percent),
+		ObservedGeneration: database.Generation,
+	}
+	if level != diskPressureNone {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Usage" + level
+		condition.Message = fmt.Sprintf("Volume %s is %d%% full, above the %s threshold",
+			fullest.Volume, fullest.Percent, diskPressureLevelName(level))
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, condition)
+
+	if slices.Index(diskPressureLevels, level) > slices.Index(diskPressureLevels, previousLevel) {
+		log.FromContext(ctx).Info("Volume usage reached a threshold", "volume", fullest.Volume, "percent", fullest.Percent, "level", level)
+		r.event(database, corev1.EventTypeWarning, conditionDiskPressure, condition.Message)
+	}
+	return nil
+}
+
+// diskPressureLevelName returns the threshold of a level as named in the spec
+func diskPressureLevelName(level string) string {
+	switch level {
+	case diskPressureWarning:
+		return "warning"
+	case diskPressureHigh:
+		return "high"
+	}
+	return "critical"
+}
+
+// autoExpandVolumes grows the data volumes by the step of
+// diskPressure.autoExpand once the fullest reaches its threshold, up to the
+// maximum size. The usage is first read again after the previous expansion
+// completed, so each expansion is based on the grown capacity.
+func (r *DatabaseReconciler) autoExpandVolumes(ctx context.Context, database *databasesv1alpha1.Database) {
+	usage := database.Status.DiskUsage
+	if database.Spec.DiskPressure == nil || database.Spec.DiskPressure.AutoExpand == nil || usage == nil {
+		return
+	}
+	expand := database.Spec.DiskPressure.AutoExpand
+	threshold := expand.ThresholdPercent
+	if threshold == 0 {
+		threshold = defaultAutoExpandPercent
+	}
+	if usage.Percent < threshold {
+		return
+	}
+	if resizing := meta.FindStatusCondition(database.Status.Conditions, conditionStorageResizing); resizing != nil &&
+		(resizing.Status == metav1.ConditionTrue || resizing.Reason == reasonVolumeExpansionNotSupported ||
+			!usage.CheckedAt.After(resizing.LastTransitionTime.Time)) {
+		return
+	}
+
+	// Rejected by validateDiskPressure
+	size, err := resource.ParseQuantity(dataVolumeSize(database))
+	if err != nil {
+		return
+	}
+	step, err := resource.ParseQuantity(expand.Step)
+	if err != nil {
+		return
+	}
+	maxSize, err := resource.ParseQuantity(expand.MaxSize)
+	if err != nil || size.Cmp(maxSize) >= 0 {
+		return
+	}
+	expanded := size.DeepCopy()
+	expanded.Add(step)
+	if expanded.Cmp(maxSize) > 0 {
+		expanded = maxSize
+	}
+
+	message := fmt.Sprintf("Volume %s is %d%% full, expanding the data volumes from %s to %s",
+		usage.Volume, usage.Percent, size.String(), expanded.String())
+	if expanded.Cmp(maxSize) == 0 {
+		message += ", the maximum size of diskPressure.autoExpand"
+	}
+	log.FromContext(ctx).Info("Expanding the data volumes automatically", "volume", usage.Volume, "percent", usage.Percent,
+		"from", size.String(), "to", expanded.String())
+	usage.ExpandedSize = expanded.String()
+	r.event(database, corev1.EventTypeNormal, "VolumeAutoExpanding", message)
+}
+
+// reconcileReadOnly pauses writes once the usage reaches the critical threshold
+// and resumes them once it falls below the high threshold, through an admin Job
+func (r *DatabaseReconciler) reconcileReadOnly(ctx context.Context, database *databasesv1alpha1.Database) error {
+	usage := database.Status.DiskUsage
+	if usage == nil {
+		return nil
+	}
+
+	job := &batchv1.Job{}
+	jobName := database.Name + "-" + readOnlyComponent
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: database.Namespace}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	// Record the outcome of the last Job before starting another one; the Job
+	// deletion event triggers the next step
+	if err == nil {
+		finished, succeeded := jobFinished(job)
+		if !finished {
+			return nil
+		}
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if !succeeded {
+			return fmt.Errorf("failed to change the read-only mode, see the logs of Job %s", jobName)
+		}
+		usage.ReadOnly = job.Annotations[readOnlyAnnotation] == "true"
+		if usage.ReadOnly {
+			r.event(database, corev1.EventTypeWarning, "WritesPaused",
+				fmt.Sprintf("Writes paused, volume %s is %d%% full", usage.Volume, usage.Percent))
+		} else {
+			r.event(database, corev1.EventTypeNormal, "WritesResumed",
+				fmt.Sprintf("Writes resumed, volume %s is %d%% full", usage.Volume, usage.Percent))
+		}
+		return nil
+	}
+
+	readOnly := false
+	if spec := database.Spec.DiskPressure; spec != nil && spec.ReadOnlyOnCritical {
+		_, high, critical := diskPressureThresholds(database)
+		readOnly = usage.Percent >= critical || (usage.ReadOnly && usage.Percent >= high)
+	}
+	script, ok := readOnlyScripts[database.Spec.Type]
+	if readOnly == usage.ReadOnly || !ok {
+		return nil
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "READ_ONLY", Value: strconv.FormatBool(readOnly)},
+		{Name: "PEERS_HOST", Value: peersHost(database)},
+	}
+	job = r.createAdminJob(database, readOnlyComponent, script, env)
+	job.Annotations = map[string]string{readOnlyAnnotation: strconv.FormatBool(readOnly)}
+	if err := controllerutil.SetControllerReference(database, job, r.Scheme); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("Changing the read-only mode", "readOnly", readOnly, "volume", usage.Volume, "percent", usage.Percent)
+	return r.Create(ctx, job)
+}
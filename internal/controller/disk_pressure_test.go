@@ -0,0 +1,209 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// staticVolumeUsage serves fixed volume usage per claim
+type staticVolumeUsage map[string]VolumeUsage
+
+func (s staticVolumeUsage) VolumeUsage(_ context.Context, _ *corev1.Pod) (map[string]VolumeUsage, error) {
+	return s, nil
+}
+
+var _ = Describe("Disk pressure", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		recorder   *record.FakeRecorder
+		usage      staticVolumeUsage
+		database   *databasesv1alpha1.Database
+		now        time.Time
+	)
+
+	jobKey := types.NamespacedName{Name: "orders-read-only", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Now()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:         databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:      "16",
+				Storage:      &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+				DiskPressure: &databasesv1alpha1.DiskPressureSpec{ReadOnlyOnCritical: true},
+			},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Scheme: scheme}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-0", Namespace: "shop", Labels: reconciler.getLabels(database)},
+			Spec: corev1.PodSpec{
+				NodeName: "node-a",
+				Volumes: []corev1.Volume{{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data-orders-0"},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		recorder = record.NewFakeRecorder(10)
+		usage = staticVolumeUsage{"data-orders-0": {UsedBytes: 50, CapacityBytes: 100}}
+		reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(database, pod).Build()
+		reconciler.Recorder = recorder
+		reconciler.VolumeUsageReader = usage
+	})
+
+	// fill sets the usage of the volume and reads it again
+	fill := func(used int64) {
+		usage["data-orders-0"] = VolumeUsage{UsedBytes: used, CapacityBytes: 100}
+		now = now.Add(diskUsageCheckInterval)
+		Expect(reconciler.reconcileDiskPressure(ctx, database, now)).To(Succeed())
+	}
+
+	completeJob := func() {
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		Expect(reconciler.reconcileDiskPressure(ctx, database, now)).To(Succeed())
+	}
+
+	It("should raise the DiskPressure condition with an Event per threshold", func() {
+		fill(50)
+		Expect(database.Status.DiskUsage.Percent).To(Equal(int32(50)))
+		Expect(meta.IsStatusConditionFalse(database.Status.Conditions, conditionDiskPressure)).To(BeTrue())
+		Expect(recorder.Events).To(BeEmpty())
+
+		fill(82)
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionDiskPressure)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("UsageWarning"))
+		Expect(recorder.Events).To(Receive(Equal("Warning DiskPressure Volume data-orders-0 is 82% full, above the warning threshold")))
+
+		By("raising no Event while the level holds")
+		fill(85)
+		Expect(recorder.Events).To(BeEmpty())
+
+		fill(91)
+		Expect(recorder.Events).To(Receive(ContainSubstring("above the high threshold")))
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+	})
+
+	It("should pause writes at the critical threshold until the usage is back below high", func() {
+		fill(96)
+		Expect(recorder.Events).To(Receive(ContainSubstring("above the critical threshold")))
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue(readOnlyAnnotation, "true"))
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "READ_ONLY", Value: "true"}))
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "PEERS_HOST", Value: peersHost(database)}))
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(ContainSubstring(`for host in $hosts; do`))
+
+		completeJob()
+		Expect(database.Status.DiskUsage.ReadOnly).To(BeTrue())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning WritesPaused")))
+
+		By("staying read-only between the high and critical thresholds")
+		fill(92)
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		fill(70)
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue(readOnlyAnnotation, "false"))
+		completeJob()
+		Expect(database.Status.DiskUsage.ReadOnly).To(BeFalse())
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal WritesResumed")))
+	})
+
+	It("should read the claims of the pod from the kubelet summary", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "orders-0", Namespace: "shop"}}
+		summary := []byte(`{"pods": [
+			{"podRef": {"name": "orders-0", "namespace": "other"}, "volume": [{"usedBytes": 1, "capacityBytes": 2, "pvcRef": {"name": "data-orders-0"}}]},
+			{"podRef": {"name": "orders-0", "namespace": "shop"}, "volume": [
+				{"name": "tmp", "usedBytes": 5, "capacityBytes": 10},
+				{"name": "data", "usedBytes": 300, "capacityBytes": 1000, "pvcRef": {"name": "data-orders-0", "namespace": "shop"}}
+			]}
+		]}`)
+		Expect(parseVolumeUsage(summary, pod)).To(Equal(map[string]VolumeUsage{
+			"data-orders-0": {UsedBytes: 300, CapacityBytes: 1000},
+		}))
+	})
+
+	It("should expand the volumes by the step once the threshold is reached, up to the maximum size", func() {
+		database.Spec.DiskPressure.AutoExpand = &databasesv1alpha1.DiskAutoExpandSpec{Step: "5Gi", MaxSize: "18Gi"}
+		Expect(validateDiskPressure(database)).To(Succeed())
+		fill(80)
+		Expect(database.Status.DiskUsage.ExpandedSize).To(BeEmpty())
+		Expect(recorder.Events).To(Receive(ContainSubstring("above the warning threshold")))
+
+		fill(86)
+		Expect(database.Status.DiskUsage.ExpandedSize).To(Equal("15Gi"))
+		Expect(dataVolumeSize(database)).To(Equal("15Gi"))
+		Expect(recorder.Events).To(Receive(Equal("Normal VolumeAutoExpanding Volume data-orders-0 is 86% full, expanding the data volumes from 10Gi to 15Gi")))
+
+		By("waiting for the volumes to be resized")
+		setCondition(database, conditionStorageResizing, metav1.ConditionTrue, reasonVolumeExpanding, "Waiting")
+		fill(86)
+		Expect(database.Status.DiskUsage.ExpandedSize).To(Equal("15Gi"))
+		setCondition(database, conditionStorageResizing, metav1.ConditionFalse, reasonVolumeExpanded, "Expanded")
+		meta.FindStatusCondition(database.Status.Conditions, conditionStorageResizing).LastTransitionTime = metav1.NewTime(now)
+
+		fill(87)
+		Expect(database.Status.DiskUsage.ExpandedSize).To(Equal("18Gi"))
+		Expect(recorder.Events).To(Receive(HaveSuffix("from 15Gi to 18Gi, the maximum size of diskPressure.autoExpand")))
+
+		fill(90)
+		Expect(database.Status.DiskUsage.ExpandedSize).To(Equal("18Gi"))
+		Expect(recorder.Events).To(Receive(ContainSubstring("above the high threshold")))
+		Expect(recorder.Events).To(BeEmpty())
+
+		By("keeping a larger storage size")
+		database.Spec.Storage.Size = "20Gi"
+		Expect(dataVolumeSize(database)).To(Equal("20Gi"))
+	})
+
+	It("should only pause writes of engines that support it", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		Expect(validateDiskPressure(database)).To(MatchError("Redis does not support diskPressure.readOnlyOnCritical"))
+	})
+})
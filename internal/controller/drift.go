@@ -0,0 +1,232 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// FieldOwner is the field manager of the changes made by the operator, which
+// tells them apart from changes made by others in the managed fields
+const FieldOwner = "database-operator"
+
+const (
+	conditionExternallyModified = "ExternallyModified"
+
+	// maxReportedFields bounds the changed fields named per object
+	maxReportedFields = 3
+)
+
+// trackedChild is a child resource whose spec is checked for external changes
+type trackedChild struct {
+	kind   string
+	object client.Object
+	name   string
+}
+
+// trackedChildren returns the workload and the Services of a Database
+func trackedChildren(database *databasesv1alpha1.Database) []trackedChild {
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite {
+		return []trackedChild{
+			{kind: "Deployment", object: &appsv1.Deployment{}, name: database.Name},
+			{kind: "Service", object: &corev1.Service{}, name: database.Name + "-service"},
+		}
+	}
+	var children []trackedChild
+	for _, name := range workloadNames(database) {
+		children = append(children, trackedChild{kind: "StatefulSet", object: &appsv1.StatefulSet{}, name: name})
+	}
+	return append(children,
+		trackedChild{kind: "Service", object: &corev1.Service{}, name: database.Name + "-service"},
+		trackedChild{kind: "Service", object: &corev1.Service{}, name: database.Name + headlessServiceSuffix},
+	)
+}
+
+// childSpec returns the spec of a tracked child resource
+func childSpec(object client.Object) any {
+	switch object := object.(type) {
+	case *appsv1.StatefulSet:
+		return object.Spec
+	case *appsv1.Deployment:
+		return object.Spec
+	case *corev1.Service:
+		return object.Spec
+	}
+	return nil
+}
+
+// appliedSpecHash returns the hash recorded for the spec of a child resource
+func appliedSpecHash(spec any) string {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// restampAppliedSpec records the spec the operator is changing a child resource
+// to. A child already modified by others keeps its hash, so the external change
+// stays reported.
+func restampAppliedSpec(object client.Object, before any) {
+	annotations := object.GetAnnotations()
+	if annotations[databasesv1alpha1.AppliedSpecHashAnnotation] != appliedSpecHash(before) {
+		return
+	}
+	annotations[databasesv1alpha1.AppliedSpecHashAnnotation] = appliedSpecHash(childSpec(object))
+}
+
+// reconcileExternalChanges records the hash of the applied spec on the workload
+// and the Service, and reports in the ExternallyModified condition the children
+// whose spec was changed by someone else since. Children without the annotation
+// (new ones, and those whose change was accepted by removing it) are recorded
+// as they are.
+func (r *DatabaseReconciler) reconcileExternalChanges(ctx context.Context, database *databasesv1alpha1.Database) error {
+	var changes []string
+	for _, child := range trackedChildren(database) {
+		key := types.NamespacedName{Name: child.name, Namespace: database.Namespace}
+		if err := r.Get(ctx, key, child.object); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		hash := appliedSpecHash(childSpec(child.object))
+		applied, ok := child.object.GetAnnotations()[databasesv1alpha1.AppliedSpecHashAnnotation]
+		switch {
+		case !ok:
+			annotations := child.object.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[databasesv1alpha1.AppliedSpecHashAnnotation] = hash
+			child.object.SetAnnotations(annotations)
+			if err := r.Update(ctx, child.object); err != nil {
+				return err
+			}
+		case applied != hash:
+			changes = append(changes, describeExternalChange(child))
+		}
+	}
+
+	if len(changes) == 0 {
+		meta.RemoveStatusCondition(&database.Status.Conditions, conditionExternallyModified)
+		return nil
+	}
+	message := strings.Join(changes, "; ")
+	if previous := meta.FindStatusCondition(database.Status.Conditions, conditionExternallyModified); previous == nil ||
+		previous.Message != message {
+		log.FromContext(ctx).Info("Child resources were modified outside the operator", "changes", message)
+		r.event(database, corev1.EventTypeWarning, "ExternallyModified", message)
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               conditionExternallyModified,
+		Status:             metav1.ConditionTrue,
+		Reason:             "OutOfBandChange",
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+	return nil
+}
+
+// describeExternalChange names the field manager that last changed the spec of
+// a child resource, and the fields it owns there. No field manager is named
+// when the API server changed the spec itself, e.g. with defaults added by a
+// Kubernetes upgrade.
+func describeExternalChange(child trackedChild) string {
+	var latest *metav1.ManagedFieldsEntry
+	var paths []string
+	for i, entry := range child.object.GetManagedFields() {
+		if entry.Manager == FieldOwner || entry.Subresource == "status" || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		spec, ok := fields["f:spec"].(map[string]any)
+		if !ok {
+			continue
+		}
+		if latest == nil || (entry.Time != nil && (latest.Time == nil || latest.Time.Before(entry.Time))) {
+			latest = &child.object.GetManagedFields()[i]
+			paths = fieldPaths("spec", spec)
+		}
+	}
+
+	if latest == nil {
+		return fmt.Sprintf("%s %s was modified outside the operator", child.kind, child.name)
+	}
+	if len(paths) > maxReportedFields {
+		paths = append(paths[:maxReportedFields], fmt.Sprintf("%d more", len(paths)-maxReportedFields))
+	}
+	return fmt.Sprintf("%s %s was modified by %s (%s)", child.kind, child.name, latest.Manager, strings.Join(paths, ", "))
+}
+
+// fieldPaths returns the paths of the leaf fields of a managed fields set, e.g.
+// spec.template.spec.containers[name=postgres].image
+func fieldPaths(prefix string, fields map[string]any) []string {
+	var paths []string
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		var path string
+		switch {
+		case strings.HasPrefix(key, "f:"):
+			path = prefix + "." + key[2:]
+		case strings.HasPrefix(key, "k:"):
+			var selector map[string]any
+			if err := json.Unmarshal([]byte(key[2:]), &selector); err != nil {
+				continue
+			}
+			var parts []string
+			for _, field := range slices.Sorted(maps.Keys(selector)) {
+				parts = append(parts, fmt.Sprintf("%s=%v", field, selector[field]))
+			}
+			path = prefix + "[" + strings.Join(parts, ",") + "]"
+		case strings.HasPrefix(key, "v:"), strings.HasPrefix(key, "i:"):
+			path = prefix + "[" + key[2:] + "]"
+		default:
+			continue
+		}
+
+		children, _ := fields[key].(map[string]any)
+		if nested := fieldPaths(path, children); len(nested) > 0 {
+			paths = append(paths, nested...)
+		} else {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("External changes", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	statefulSetKey := types.NamespacedName{Name: "orders", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+			},
+		}
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+					Spec: appsv1.StatefulSetSpec{
+						Replicas: ptr.To(int32(1)),
+						Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "postgres", Image: "postgres:16"}},
+						}},
+					},
+				},
+				&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "orders-service", Namespace: "shop"}},
+			).Build(),
+			Scheme: scheme,
+		}
+	})
+
+	// edit changes the image of the StatefulSet as field manager kubectl-edit
+	edit := func() {
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(reconciler.Get(ctx, statefulSetKey, statefulSet)).To(Succeed())
+		statefulSet.Spec.Template.Spec.Containers[0].Image = "postgres:16.4"
+		statefulSet.ManagedFields = []metav1.ManagedFieldsEntry{
+			{
+				Manager: FieldOwner, Operation: metav1.ManagedFieldsOperationUpdate,
+				Time:     &metav1.Time{Time: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{},"f:template":{}}}`)},
+			},
+			{
+				Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate, Subresource: "status",
+				Time:     &metav1.Time{Time: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)},
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:replicas":{}}}`)},
+			},
+			{
+				Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate,
+				Time: &metav1.Time{Time: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)},
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(
+					`{"f:spec":{"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"postgres\"}":{".":{},"f:image":{}}}}}}}`)},
+			},
+		}
+		Expect(reconciler.Update(ctx, statefulSet)).To(Succeed())
+	}
+
+	It("should report the field manager of a hand-edited StatefulSet", func() {
+		Expect(reconciler.reconcileExternalChanges(ctx, database)).To(Succeed())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionExternallyModified)).To(BeNil())
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(reconciler.Get(ctx, statefulSetKey, statefulSet)).To(Succeed())
+		Expect(statefulSet.Annotations).To(HaveKeyWithValue(
+			databasesv1alpha1.AppliedSpecHashAnnotation, appliedSpecHash(statefulSet.Spec)))
+
+		edit()
+		Expect(reconciler.reconcileExternalChanges(ctx, database)).To(Succeed())
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionExternallyModified)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(Equal(
+			"StatefulSet orders was modified by kubectl-edit (spec.template.spec.containers[name=postgres].image)"))
+
+		// Removing the annotation accepts the change
+		Expect(reconciler.Get(ctx, statefulSetKey, statefulSet)).To(Succeed())
+		delete(statefulSet.Annotations, databasesv1alpha1.AppliedSpecHashAnnotation)
+		Expect(reconciler.Update(ctx, statefulSet)).To(Succeed())
+		Expect(reconciler.reconcileExternalChanges(ctx, database)).To(Succeed())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionExternallyModified)).To(BeNil())
+	})
+
+	It("should record the replicas the operator scales to", func() {
+		Expect(reconciler.reconcileExternalChanges(ctx, database)).To(Succeed())
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(reconciler.Get(ctx, statefulSetKey, statefulSet)).To(Succeed())
+		Expect(reconciler.scaleStatefulSet(ctx, database, statefulSet, 3)).To(Succeed())
+		Expect(reconciler.reconcileExternalChanges(ctx, database)).To(Succeed())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionExternallyModified)).To(BeNil())
+
+		// An edited StatefulSet keeps reporting the edit after scaling
+		edit()
+		Expect(reconciler.Get(ctx, statefulSetKey, statefulSet)).To(Succeed())
+		Expect(reconciler.scaleStatefulSet(ctx, database, statefulSet, 2)).To(Succeed())
+		Expect(reconciler.reconcileExternalChanges(ctx, database)).To(Succeed())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionExternallyModified)).NotTo(BeNil())
+	})
+})
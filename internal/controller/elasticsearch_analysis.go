@@ -0,0 +1,365 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	shardAnalysisComponent      = "shard-analysis"
+	shardAnalysisRecommendation = "ElasticsearchShardSizing"
+	hotIndexRecommendation      = "ElasticsearchHotIndex"
+	shardRolloverPolicy         = "database-operator-rollover"
+	defaultShardSchedule        = "0 */6 * * *"
+	defaultMaxShardSize         = "50Gi"
+	defaultSmallShardSize       = "1Gi"
+	defaultSmallShardLimit      = int32(1000)
+
+	// The indexing rate is sampled over hotIndexSampleSeconds. An index is hot
+	// when it takes hotIndexShare percent of it, at least hotIndexRate docs/s.
+	hotIndexSampleSeconds = 30
+	hotIndexShare         = 50
+	hotIndexRate          = 1000
+)
+
+// shardAnalysisScript summarizes the cluster into "shards <total> <small>",
+// "nodes <data nodes>", "hot <name> <docs/s> <percent> <primaries>" and
+// "index <name> <largest primary bytes> <rollover alias or ->" lines, largest
+// indices first. With AUTO_TUNE it attaches a rollover ILM policy to every
+// oversized index with a rollover alias and no other policy, and reports it with
+// a "tuned <name>" line. The summary is cut to fit the termination message.
+const shardAnalysisScript = `ES="http://$DB_HOST:9200"
+shards=$(curl -sf "$ES/_cat/shards?h=index,prirep,store&bytes=b") || exit 1
+echo "$shards" | awk -v max="$MAX_SHARD_BYTES" -v small="$SMALL_SHARD_BYTES" '
+  NF == 0 { next }
+  { total++; if ($3 != "" && $3 + 0 < small + 0) tiny++ }
+  $2 == "p" && $3 + 0 > max + 0 && $3 + 0 > big[$1] + 0 { big[$1] = $3 }
+  END {
+    printf "shards %d %d\n", total, tiny
+    for (i in big) printf "%s %s\n", i, big[i]
+  }' > /tmp/shards || exit 1
+head -n 1 /tmp/shards > /tmp/summary
+
+nodes=$(curl -sf "$ES/_cat/nodes?h=node.role" | grep -c '[cdfhsw]')
+indexing="$ES/_cat/indices?h=index,pri,pri.indexing.index_total"
+curl -sf "$indexing" > /tmp/before || exit 1
+sleep "$SAMPLE_SECONDS"
+curl -sf "$indexing" > /tmp/after || exit 1
+awk -v seconds="$SAMPLE_SECONDS" -v nodes="$nodes" -v share="$HOT_INDEX_SHARE" -v min="$HOT_INDEX_RATE" '
+  NR == FNR { before[$1] = $3; next }
+  ($1 in before) { rate[$1] = ($3 - before[$1]) / seconds; pri[$1] = $2; sum += rate[$1] }
+  END {
+    printf "nodes %d\n", nodes
+    for (i in rate) if (rate[i] >= min + 0 && rate[i] * 100 >= share * sum && pri[i] < nodes + 0)
+      printf "hot %s %d %d %d\n", i, rate[i], rate[i] * 100 / sum, pri[i]
+  }' /tmp/before /tmp/after >> /tmp/summary
+
+if [ "$AUTO_TUNE" = "true" ]; then
+  curl -sf -X PUT "$ES/_ilm/policy/$ILM_POLICY" -H 'Content-Type: application/json' \
+    -d "{\"policy\":{\"phases\":{\"hot\":{\"actions\":{\"rollover\":{\"max_primary_shard_size\":\"${MAX_SHARD_BYTES}b\"}}}}}}" > /dev/null || exit 1
+fi
+# Rollover writes through index.lifecycle.rollover_alias, ILM fails without it
+tail -n +2 /tmp/shards | sort -k2 -n -r | while read -r index bytes; do
+  settings=$(curl -sf "$ES/$index/_settings/index.lifecycle.*?flat_settings=true")
+  alias=$(echo "$settings" | sed -n 's/.*"index\.lifecycle\.rollover_alias":"\([^"]*\)".*/\1/p')
+  policy=$(echo "$settings" | sed -n 's/.*"index\.lifecycle\.name":"\([^"]*\)".*/\1/p')
+  echo "index $index $bytes ${alias:--}"
+  if [ "$AUTO_TUNE" = "true" ] && [ -n "$alias" ] && { [ -z "$policy" ] || [ "$policy" = "$ILM_POLICY" ]; } &&
+    curl -sf -X PUT "$ES/$index/_settings" -H 'Content-Type: application/json' \
+      -d "{\"index.lifecycle.name\":\"$ILM_POLICY\"}" > /dev/null; then
+    echo "tuned $index"
+  fi
+done >> /tmp/summary
+awk -v limit="$SUMMARY_BYTES" '{ n += length($0) + 1; if (n > limit + 0) exit; print }' /tmp/summary > /dev/termination-log`
+
+// shardAnalysisSummaryBytes keeps the summary below the 4096 bytes of the
+// termination message
+const shardAnalysisSummaryBytes = 4000
+
+func (r *DatabaseReconciler) reconcileShardAnalysis(ctx context.Context, database *databasesv1alpha1.Database) error {
+	spec := shardAnalysisSpec(database)
+
+	var desired *batchv1.CronJob
+	if spec != nil && spec.Enabled {
+		var err error
+		desired, err = r.createShardAnalysisCronJob(database, spec)
+		if err != nil {
+			return err
+		}
+	}
+
+	cronJob, err := r.reconcileCronJob(ctx, database, shardAnalysisComponent, desired)
+	if err != nil {
+		return err
+	}
+	if cronJob == nil {
+		database.Status.ShardAnalysis = nil
+		setRecommendations(database, shardAnalysisRecommendation, nil)
+		setRecommendations(database, hotIndexRecommendation, nil)
+		labels := databaseMetricLabels(database)
+		elasticsearchShards.DeletePartialMatch(labels)
+		elasticsearchSmallShards.DeletePartialMatch(labels)
+		return nil
+	}
+
+	return r.collectShardAnalysis(ctx, database, spec)
+}
+
+// collectShardAnalysis reads the result of the latest successful analysis Job
+// and publishes it in status and metrics
+func (r *DatabaseReconciler) collectShardAnalysis(ctx context.Context, database *databasesv1alpha1.Database, spec *databasesv1alpha1.ShardAnalysisSpec) error {
+	var since *metav1.Time
+	if database.Status.ShardAnalysis != nil {
+		since = database.Status.ShardAnalysis.LastAnalysisTime
+	}
+
+	output, completionTime, err := r.latestAnalysisOutput(ctx, database, shardAnalysisComponent, since)
+	if err != nil || completionTime == nil {
+		return err
+	}
+
+	maxShardSize, smallShardSize, err := shardSizeThresholds(spec)
+	if err != nil {
+		return err
+	}
+
+	result := parseShardAnalysis(output)
+	result.LastAnalysisTime = completionTime
+	database.Status.ShardAnalysis = result
+
+	tuned := map[string]bool{}
+	for _, index := range result.TunedIndices {
+		tuned[index] = true
+	}
+
+	var recommendations []databasesv1alpha1.Recommendation
+	for _, index := range result.OversizedIndices {
+		size := resource.NewQuantity(index.LargestShardBytes, resource.BinarySI).String()
+		rec := databasesv1alpha1.Recommendation{
+			Type:     shardAnalysisRecommendation,
+			Severity: databasesv1alpha1.RecommendationSeverityWarning,
+			Target:   index.Index,
+		}
+		switch {
+		case tuned[index.Index]:
+			rec.Severity = databasesv1alpha1.RecommendationSeverityInfo
+			rec.Message = fmt.Sprintf("largest primary shard is %s (limit %s); ILM policy %s rolls it over through alias %s",
+				size, maxShardSize.String(), shardRolloverPolicy, index.RolloverAlias)
+		case index.RolloverAlias != "":
+			rec.Message = fmt.Sprintf("largest primary shard is %s (limit %s); roll the index over through alias %s",
+				size, maxShardSize.String(), index.RolloverAlias)
+		default:
+			rec.Message = fmt.Sprintf("largest primary shard is %s (limit %s) and the index has no index.lifecycle.rollover_alias; "+
+				"write through an alias to roll it over, or split it into more primary shards", size, maxShardSize.String())
+		}
+		recommendations = append(recommendations, rec)
+	}
+
+	limit := defaultSmallShardLimit
+	if spec.SmallShardLimit > 0 {
+		limit = spec.SmallShardLimit
+	}
+	if result.SmallShards > int64(limit) {
+		recommendations = append(recommendations, databasesv1alpha1.Recommendation{
+			Type:     shardAnalysisRecommendation,
+			Severity: databasesv1alpha1.RecommendationSeverityWarning,
+			Message: fmt.Sprintf("%d of %d shards are smaller than %s; shrink or consolidate small indices to reduce cluster overhead",
+				result.SmallShards, result.TotalShards, smallShardSize.String()),
+		})
+	}
+	setRecommendations(database, shardAnalysisRecommendation, recommendations)
+
+	var hot []databasesv1alpha1.Recommendation
+	for _, index := range result.HotIndices {
+		hot = append(hot, databasesv1alpha1.Recommendation{
+			Type:     hotIndexRecommendation,
+			Severity: databasesv1alpha1.RecommendationSeverityWarning,
+			Target:   index.Index,
+			Message: fmt.Sprintf("takes %d%% of the indexing load (%d docs/s) on %d primary shards for %d data nodes; "+
+				"roll it over into an index with more primary shards to spread the writes",
+				index.IndexingShare, index.IndexingRate, index.PrimaryShards, result.DataNodes),
+		})
+	}
+	setRecommendations(database, hotIndexRecommendation, hot)
+
+	elasticsearchShards.WithLabelValues(database.Namespace, database.Name).Set(float64(result.TotalShards))
+	elasticsearchSmallShards.WithLabelValues(database.Namespace, database.Name).Set(float64(result.SmallShards))
+
+	return nil
+}
+
+// parseShardAnalysis parses the summary written by shardAnalysisScript
+func parseShardAnalysis(output string) *databasesv1alpha1.ShardAnalysisStatus {
+	result := &databasesv1alpha1.ShardAnalysisStatus{}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "shards":
+			if len(fields) != 3 {
+				continue
+			}
+			result.TotalShards, _ = strconv.ParseInt(fields[1], 10, 64)
+			result.SmallShards, _ = strconv.ParseInt(fields[2], 10, 64)
+		case "nodes":
+			if len(fields) != 2 {
+				continue
+			}
+			nodes, _ := strconv.ParseInt(fields[1], 10, 32)
+			result.DataNodes = int32(nodes)
+		case "hot":
+			if len(fields) != 5 {
+				continue
+			}
+			rate, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			share, _ := strconv.ParseInt(fields[3], 10, 32)
+			primaries, _ := strconv.ParseInt(fields[4], 10, 32)
+			result.HotIndices = append(result.HotIndices, databasesv1alpha1.HotIndexUsage{
+				Index:         fields[1],
+				IndexingRate:  rate,
+				IndexingShare: int32(share),
+				PrimaryShards: int32(primaries),
+			})
+		case "index":
+			if len(fields) != 3 && len(fields) != 4 {
+				continue
+			}
+			bytes, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			usage := databasesv1alpha1.IndexShardUsage{
+				Index:             fields[1],
+				LargestShardBytes: bytes,
+			}
+			if len(fields) == 4 && fields[3] != "-" {
+				usage.RolloverAlias = fields[3]
+			}
+			result.OversizedIndices = append(result.OversizedIndices, usage)
+		case "tuned":
+			if len(fields) != 2 {
+				continue
+			}
+			result.TunedIndices = append(result.TunedIndices, fields[1])
+		}
+	}
+
+	sort.SliceStable(result.OversizedIndices, func(i, j int) bool {
+		return result.OversizedIndices[i].LargestShardBytes > result.OversizedIndices[j].LargestShardBytes
+	})
+
+	return result
+}
+
+func (r *DatabaseReconciler) createShardAnalysisCronJob(database *databasesv1alpha1.Database, spec *databasesv1alpha1.ShardAnalysisSpec) (*batchv1.CronJob, error) {
+	schedule := spec.Schedule
+	if schedule == "" {
+		schedule = defaultShardSchedule
+	}
+
+	maxShardSize, smallShardSize, err := shardSizeThresholds(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	env := []corev1.EnvVar{
+		{
+			Name:  "MAX_SHARD_BYTES",
+			Value: strconv.FormatInt(maxShardSize.Value(), 10),
+		},
+		{
+			Name:  "SMALL_SHARD_BYTES",
+			Value: strconv.FormatInt(smallShardSize.Value(), 10),
+		},
+		{
+			Name: "AUTO_TUNE",
+			// Release freezes suspend tuning, the analysis keeps running
+			Value: strconv.FormatBool(database.Spec.AutoTune && !frozen(database, time.Now())),
+		},
+		{
+			Name:  "ILM_POLICY",
+			Value: shardRolloverPolicy,
+		},
+		{
+			Name:  "SAMPLE_SECONDS",
+			Value: strconv.Itoa(hotIndexSampleSeconds),
+		},
+		{
+			Name:  "HOT_INDEX_SHARE",
+			Value: strconv.Itoa(hotIndexShare),
+		},
+		{
+			Name:  "HOT_INDEX_RATE",
+			Value: strconv.Itoa(hotIndexRate),
+		},
+		{
+			Name:  "SUMMARY_BYTES",
+			Value: strconv.Itoa(shardAnalysisSummaryBytes),
+		},
+	}
+
+	return r.createAdminCronJob(database, shardAnalysisComponent, schedule, shardAnalysisScript, env), nil
+}
+
+func shardAnalysisSpec(database *databasesv1alpha1.Database) *databasesv1alpha1.ShardAnalysisSpec {
+	if database.Spec.Elasticsearch == nil {
+		return nil
+	}
+	return database.Spec.Elasticsearch.ShardAnalysis
+}
+
+func shardSizeThresholds(spec *databasesv1alpha1.ShardAnalysisSpec) (resource.Quantity, resource.Quantity, error) {
+	maxShardSize := spec.MaxShardSize
+	if maxShardSize == "" {
+		maxShardSize = defaultMaxShardSize
+	}
+	smallShardSize := spec.SmallShardSize
+	if smallShardSize == "" {
+		smallShardSize = defaultSmallShardSize
+	}
+
+	maxQuantity, err := resource.ParseQuantity(maxShardSize)
+	if err != nil {
+		return resource.Quantity{}, resource.Quantity{}, fmt.Errorf("invalid shard analysis maxShardSize: %w", err)
+	}
+	smallQuantity, err := resource.ParseQuantity(smallShardSize)
+	if err != nil {
+		return resource.Quantity{}, resource.Quantity{}, fmt.Errorf("invalid shard analysis smallShardSize: %w", err)
+	}
+
+	return maxQuantity, smallQuantity, nil
+}
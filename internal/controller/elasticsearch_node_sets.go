@@ -0,0 +1,387 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// elasticsearchNodeSetLabel tells the StatefulSets and the pods of the node sets apart
+	elasticsearchNodeSetLabel = "databases.database-operator.io/node-set"
+
+	elasticsearchInitialMasterNodes = "cluster.initial_master_nodes"
+	elasticsearchAllocationExclude  = "cluster.routing.allocation.exclude._name"
+	shardDrainTimeout               = 10 * time.Second
+)
+
+// elasticsearchExcludeNodes excludes the named nodes from shard allocation, so
+// their shards move to the other data nodes, and returns the number of shards
+// they still hold. Without names it lifts the exclusion.
+var elasticsearchExcludeNodes = func(ctx context.Context, conn adminConnection, nodes []string) (int, error) {
+	var exclude any
+	if len(nodes) > 0 {
+		exclude = strings.Join(nodes, ",")
+	}
+	settings := map[string]any{"persistent": map[string]any{elasticsearchAllocationExclude: exclude}}
+	if _, err := elasticsearchRequest(ctx, conn, http.MethodPut, "/_cluster/settings", settings); err != nil {
+		return 0, err
+	}
+	if len(nodes) == 0 {
+		return 0, nil
+	}
+
+	result, err := elasticsearchGet(ctx, conn, "/_cat/allocation?format=json&h=node,shards")
+	if err != nil {
+		return 0, err
+	}
+	rows, ok := result.([]any)
+	if !ok {
+		return 0, fmt.Errorf("unexpected allocation response")
+	}
+	shards := 0
+	for _, row := range rows {
+		fields, _ := row.(map[string]any)
+		node, _ := fields["node"].(string)
+		if !slices.Contains(nodes, node) {
+			continue
+		}
+		value, _ := fields["shards"].(string)
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid shard count %q of node %s", value, node)
+		}
+		shards += count
+	}
+	return shards, nil
+}
+
+// elasticsearchClusterStatus returns the status of the cluster health: green,
+// yellow or red
+var elasticsearchClusterStatus = func(ctx context.Context, conn adminConnection) (string, error) {
+	result, err := elasticsearchGet(ctx, conn, "/_cluster/health")
+	if err != nil {
+		return "", err
+	}
+	fields, _ := result.(map[string]any)
+	status, ok := fields["status"].(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected cluster health response")
+	}
+	return status, nil
+}
+
+// elasticsearchNodeSets returns the node sets of an Elasticsearch Database
+func elasticsearchNodeSets(database *databasesv1alpha1.Database) []databasesv1alpha1.ElasticsearchNodeSet {
+	if database.Spec.Type != databasesv1alpha1.DatabaseTypeElasticsearch || database.Spec.Elasticsearch == nil {
+		return nil
+	}
+	return database.Spec.Elasticsearch.NodeSets
+}
+
+// nodeSetName returns the name of the StatefulSet of a node set
+func nodeSetName(database *databasesv1alpha1.Database, nodeSet databasesv1alpha1.ElasticsearchNodeSet) string {
+	return database.Name + "-" + nodeSet.Name
+}
+
+// nodeSetOf returns the node set a StatefulSet runs
+func nodeSetOf(database *databasesv1alpha1.Database, statefulSet *appsv1.StatefulSet) (databasesv1alpha1.ElasticsearchNodeSet, bool) {
+	for _, nodeSet := range elasticsearchNodeSets(database) {
+		if nodeSetName(database, nodeSet) == statefulSet.Name {
+			return nodeSet, true
+		}
+	}
+	return databasesv1alpha1.ElasticsearchNodeSet{}, false
+}
+
+func hasNodeRole(nodeSet databasesv1alpha1.ElasticsearchNodeSet, role databasesv1alpha1.ElasticsearchNodeRole) bool {
+	return slices.Contains(nodeSet.Roles, role)
+}
+
+// nodeNames returns the names of the nodes of a StatefulSet with the given
+// ordinals; Elasticsearch names the nodes after their pods
+func nodeNames(statefulSet string, from, to int32) []string {
+	var names []string
+	for ordinal := from; ordinal < to; ordinal++ {
+		names = append(names, fmt.Sprintf("%s-%d", statefulSet, ordinal))
+	}
+	return names
+}
+
+// validateNodeSets checks that the node sets form a cluster: it needs master
+// eligible nodes to elect a master and data nodes to hold the shards
+func validateNodeSets(database *databasesv1alpha1.Database) error {
+	nodeSets := elasticsearchNodeSets(database)
+	if len(nodeSets) == 0 {
+		return nil
+	}
+	if topology := database.Spec.Topology; topology != nil && len(topology.Schedules) > 0 {
+		return fmt.Errorf("topology.schedules cannot be combined with elasticsearch.nodeSets")
+	}
+	for _, role := range []databasesv1alpha1.ElasticsearchNodeRole{
+		databasesv1alpha1.ElasticsearchNodeRoleMaster, databasesv1alpha1.ElasticsearchNodeRoleData,
+	} {
+		if !slices.ContainsFunc(nodeSets, func(nodeSet databasesv1alpha1.ElasticsearchNodeSet) bool {
+			return hasNodeRole(nodeSet, role)
+		}) {
+			return fmt.Errorf("elasticsearch.nodeSets need a node set with the %s role", role)
+		}
+	}
+	return nil
+}
+
+// nodeSetReplicas returns the number of nodes of all node sets
+func nodeSetReplicas(nodeSets []databasesv1alpha1.ElasticsearchNodeSet) int32 {
+	var replicas int32
+	for _, nodeSet := range nodeSets {
+		replicas += nodeSet.Replicas
+	}
+	return replicas
+}
+
+// nodeSetWorkload is the current and the generated StatefulSet of a node set
+type nodeSetWorkload struct {
+	nodeSet     databasesv1alpha1.ElasticsearchNodeSet
+	statefulSet *appsv1.StatefulSet
+	desired     *appsv1.StatefulSet
+}
+
+// reconcileElasticsearchNodeSets runs every node set in its own StatefulSet.
+// New node sets are created right away. Existing ones are scaled and rolled out
+// one at a time, in the order of the spec, so the cluster never loses nodes of
+// several pools at once. Master node sets shrink by one node at a time.
+func (r *DatabaseReconciler) reconcileElasticsearchNodeSets(ctx context.Context, database *databasesv1alpha1.Database) error {
+	// The data of a single node cluster does not move to the node sets
+	err := r.Get(ctx, types.NamespacedName{Name: database.Name, Namespace: database.Namespace}, &appsv1.StatefulSet{})
+	if err == nil {
+		return fmt.Errorf("StatefulSet %s runs the cluster as a single node and cannot be split into node sets", database.Name)
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	nodeSets := elasticsearchNodeSets(database)
+	existing := map[string]*appsv1.StatefulSet{}
+	for _, nodeSet := range nodeSets {
+		statefulSet := &appsv1.StatefulSet{}
+		err := r.Get(ctx, types.NamespacedName{Name: nodeSetName(database, nodeSet), Namespace: database.Namespace}, statefulSet)
+		if err == nil {
+			existing[nodeSet.Name] = statefulSet
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	bootstrap := bootstrapMasterNodes(database, existing)
+
+	var workloads []nodeSetWorkload
+	var ready int32
+	for _, nodeSet := range nodeSets {
+		statefulSet, found := existing[nodeSet.Name]
+		initialMasters := bootstrap
+		if found {
+			initialMasters = containerEnv(statefulSet, elasticsearchInitialMasterNodes)
+		}
+		desired := r.createElasticsearchNodeSet(database, nodeSet, initialMasters)
+		if !found {
+			log.FromContext(ctx).Info("Creating node set", "name", desired.Name, "roles", nodeSet.Roles)
+			if err := controllerutil.SetControllerReference(database, desired, r.Scheme); err != nil {
+				return err
+			}
+			if err := r.apply(ctx, desired); err != nil {
+				return err
+			}
+			continue
+		}
+		ready += statefulSet.Status.ReadyReplicas
+		workloads = append(workloads, nodeSetWorkload{nodeSet: nodeSet, statefulSet: statefulSet, desired: desired})
+	}
+	database.Status.ReadyReplicas = ready
+
+	// Only the first node set not running its spec changes. Once they all do,
+	// each of them releases the locks of the finished operations.
+	pending := workloads
+	for i, workload := range workloads {
+		if !nodeSetConverged(workload.statefulSet, workload.desired) {
+			pending = workloads[i : i+1]
+			break
+		}
+	}
+	for _, workload := range pending {
+		current := int32(1)
+		if workload.statefulSet.Spec.Replicas != nil {
+			current = *workload.statefulSet.Spec.Replicas
+		}
+		// A hibernating cluster stops its masters at once, with every other node
+		if hasNodeRole(workload.nodeSet, databasesv1alpha1.ElasticsearchNodeRoleMaster) && !hibernated(database) &&
+			workload.nodeSet.Replicas < current-1 {
+			workload.desired.Spec.Replicas = ptr.To(current - 1)
+		}
+		if err := r.updateStatefulSet(ctx, database, workload.statefulSet, workload.desired); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeSetConverged reports whether a node set runs the generated StatefulSet
+func nodeSetConverged(statefulSet, desired *appsv1.StatefulSet) bool {
+	replicas := *desired.Spec.Replicas
+	return statefulSet.Spec.Replicas != nil && *statefulSet.Spec.Replicas == replicas &&
+		statefulSet.Status.Replicas == replicas &&
+		equality.Semantic.DeepDerivative(desired.Spec.Template, statefulSet.Spec.Template) &&
+		rolledOut(statefulSet)
+}
+
+// bootstrapMasterNodes returns the master eligible nodes that bootstrap a new
+// cluster. Once a master node set exists the cluster has formed: the setting
+// is kept by the node sets created with it and left out of later ones, so
+// scaling the master node sets does not restart every node.
+func bootstrapMasterNodes(database *databasesv1alpha1.Database, existing map[string]*appsv1.StatefulSet) string {
+	var names []string
+	for _, nodeSet := range elasticsearchNodeSets(database) {
+		if !hasNodeRole(nodeSet, databasesv1alpha1.ElasticsearchNodeRoleMaster) {
+			continue
+		}
+		if _, found := existing[nodeSet.Name]; found {
+			return ""
+		}
+		names = append(names, nodeNames(nodeSetName(database, nodeSet), 0, nodeSet.Replicas)...)
+	}
+	return strings.Join(names, ",")
+}
+
+// containerEnv returns the value of an environment variable of the engine container
+func containerEnv(statefulSet *appsv1.StatefulSet, name string) string {
+	for _, ev := range statefulSet.Spec.Template.Spec.Containers[0].Env {
+		if ev.Name == name {
+			return ev.Value
+		}
+	}
+	return ""
+}
+
+// createElasticsearchNodeSet generates the StatefulSet of a node set from the
+// single node one, with the storage and resources of the node set. The nodes
+// find each other through the headless Service, which publishes the nodes of
+// every node set.
+func (r *DatabaseReconciler) createElasticsearchNodeSet(database *databasesv1alpha1.Database, nodeSet databasesv1alpha1.ElasticsearchNodeSet, initialMasters string) *appsv1.StatefulSet {
+	pool := database.DeepCopy()
+	if nodeSet.Storage != nil {
+		pool.Spec.Storage = nodeSet.Storage
+	}
+	if nodeSet.Resources != nil {
+		pool.Spec.Resources = nodeSet.Resources
+	}
+
+	roles := make([]string, len(nodeSet.Roles))
+	for i, role := range nodeSet.Roles {
+		roles[i] = string(role)
+	}
+	env := []corev1.EnvVar{
+		{Name: "node.roles", Value: strings.Join(roles, ",")},
+		{Name: "discovery.seed_hosts", Value: database.Name + headlessServiceSuffix},
+	}
+	if initialMasters != "" {
+		env = append(env, corev1.EnvVar{Name: elasticsearchInitialMasterNodes, Value: initialMasters})
+	}
+	replicas := nodeSet.Replicas
+	if hibernated(database) {
+		replicas = 0
+	}
+	statefulSet := r.createElasticsearchStatefulSet(pool, replicas, append(env, r.getElasticsearchEnv(database)...))
+
+	name := nodeSetName(database, nodeSet)
+	selector := r.getLabels(database)
+	selector[elasticsearchNodeSetLabel] = nodeSet.Name
+	statefulSet.Name = name
+	statefulSet.Labels[elasticsearchNodeSetLabel] = nodeSet.Name
+	statefulSet.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+	statefulSet.Spec.Template.Labels[elasticsearchNodeSetLabel] = nodeSet.Name
+	// Each node set is spread on its own, so that the masters do not end up in one zone
+	applyPlacement(database, &statefulSet.Spec.Template, selector)
+	return statefulSet
+}
+
+// shardsDrained moves the shards off the data nodes a scale-down of a node set
+// removes and reports whether they are empty. The scale-down is deferred,
+// with the DownscaleBlocked condition, while shards are relocating.
+func (r *DatabaseReconciler) shardsDrained(ctx context.Context, database *databasesv1alpha1.Database, statefulSet *appsv1.StatefulSet, current, replicas int32) (bool, error) {
+	nodeSet, ok := nodeSetOf(database, statefulSet)
+	if !ok || !hasNodeRole(nodeSet, databasesv1alpha1.ElasticsearchNodeRoleData) {
+		return true, nil
+	}
+	conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+	if err != nil {
+		return false, err
+	}
+
+	nodes := nodeNames(statefulSet.Name, replicas, current)
+	drainCtx, cancel := context.WithTimeout(ctx, shardDrainTimeout)
+	defer cancel()
+	shards, err := elasticsearchExcludeNodes(drainCtx, conn, nodes)
+	if err != nil {
+		return false, fmt.Errorf("failed to drain %s: %w", strings.Join(nodes, ", "), err)
+	}
+	if shards == 0 {
+		return true, nil
+	}
+
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:   conditionDownscaleBlocked,
+		Status: metav1.ConditionTrue,
+		Reason: "ShardsRelocating",
+		Message: fmt.Sprintf("Scale-down of %s from %d to %d replicas waits for %d shards to move off %s",
+			statefulSet.Name, current, replicas, shards, strings.Join(nodes, ", ")),
+		ObservedGeneration: database.Generation,
+	})
+	log.FromContext(ctx).Info("Waiting for the shards to move off the removed nodes", "nodes", nodes, "shards", shards)
+	return false, nil
+}
+
+// liftShardExclusion lets shards allocate to every node again once the node
+// sets are scaled
+func (r *DatabaseReconciler) liftShardExclusion(ctx context.Context, database *databasesv1alpha1.Database) error {
+	if len(elasticsearchNodeSets(database)) == 0 {
+		return nil
+	}
+	conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+	if err != nil {
+		return err
+	}
+	drainCtx, cancel := context.WithTimeout(ctx, shardDrainTimeout)
+	defer cancel()
+	_, err = elasticsearchExcludeNodes(drainCtx, conn, nil)
+	return err
+}
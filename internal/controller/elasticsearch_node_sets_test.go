@@ -0,0 +1,197 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Elasticsearch node sets", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		shards     map[string]int
+		excluded   [][]string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build(),
+			Scheme: scheme,
+		}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypeElasticsearch,
+				Version: "8.15.0",
+				Elasticsearch: &databasesv1alpha1.ElasticsearchConfig{
+					NodeSets: []databasesv1alpha1.ElasticsearchNodeSet{
+						{Name: "master", Roles: []databasesv1alpha1.ElasticsearchNodeRole{"master"}, Replicas: 3},
+						{Name: "data", Roles: []databasesv1alpha1.ElasticsearchNodeRole{"data", "ingest"}, Replicas: 2},
+					},
+				},
+			},
+		}
+
+		shards = map[string]int{}
+		excluded = nil
+		original := elasticsearchExcludeNodes
+		elasticsearchExcludeNodes = func(_ context.Context, _ adminConnection, nodes []string) (int, error) {
+			excluded = append(excluded, nodes)
+			total := 0
+			for _, node := range nodes {
+				total += shards[node]
+			}
+			return total, nil
+		}
+		DeferCleanup(func() {
+			elasticsearchExcludeNodes = original
+		})
+	})
+
+	get := func(name string) *appsv1.StatefulSet {
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: name, Namespace: "shop"}, statefulSet)).To(Succeed())
+		return statefulSet
+	}
+
+	// settle reports the StatefulSet as running its spec
+	settle := func(name string) {
+		statefulSet := get(name)
+		statefulSet.Status.Replicas = *statefulSet.Spec.Replicas
+		statefulSet.Status.ObservedGeneration = statefulSet.Generation
+		Expect(reconciler.Status().Update(ctx, statefulSet)).To(Succeed())
+	}
+
+	replicas := func(name string) int32 {
+		return *get(name).Spec.Replicas
+	}
+
+	env := func(statefulSet *appsv1.StatefulSet) map[string]string {
+		values := map[string]string{}
+		for _, ev := range statefulSet.Spec.Template.Spec.Containers[0].Env {
+			values[ev.Name] = ev.Value
+		}
+		return values
+	}
+
+	It("should run every node set in its own StatefulSet", func() {
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(Succeed())
+
+		master := get("orders-master")
+		Expect(*master.Spec.Replicas).To(Equal(int32(3)))
+		Expect(master.Spec.Selector.MatchLabels).To(HaveKeyWithValue(elasticsearchNodeSetLabel, "master"))
+		Expect(master.Spec.Template.Labels).To(HaveKeyWithValue(elasticsearchNodeSetLabel, "master"))
+		Expect(env(master)).To(HaveKeyWithValue("node.roles", "master"))
+		Expect(env(master)).To(HaveKeyWithValue("discovery.seed_hosts", "orders-headless"))
+		Expect(env(master)).To(HaveKeyWithValue(elasticsearchInitialMasterNodes, "orders-master-0,orders-master-1,orders-master-2"))
+		Expect(env(master)).NotTo(HaveKey("discovery.type"))
+
+		data := get("orders-data")
+		Expect(*data.Spec.Replicas).To(Equal(int32(2)))
+		Expect(env(data)).To(HaveKeyWithValue("node.roles", "data,ingest"))
+		Expect(specReplicas(database)).To(Equal(int32(5)))
+		Expect(workloadNames(database)).To(Equal([]string{"orders-master", "orders-data"}))
+		Expect(dataClaimNames(database)).To(ContainElements("data-orders-master-2", "data-orders-data-1"))
+	})
+
+	It("should require master and data nodes", func() {
+		Expect(validateNodeSets(database)).To(Succeed())
+
+		database.Spec.Elasticsearch.NodeSets[1].Roles = []databasesv1alpha1.ElasticsearchNodeRole{"ingest"}
+		Expect(validateNodeSets(database)).To(MatchError(ContainSubstring("the data role")))
+
+		database.Spec.Elasticsearch.NodeSets = database.Spec.Elasticsearch.NodeSets[1:]
+		Expect(validateNodeSets(database)).To(MatchError(ContainSubstring("the master role")))
+	})
+
+	It("should scale one node set at a time and masters one node at a time", func() {
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(Succeed())
+		settle("orders-master")
+		settle("orders-data")
+
+		database.Spec.Elasticsearch.NodeSets[0].Replicas = 1
+		database.Spec.Elasticsearch.NodeSets[1].Replicas = 3
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(Succeed())
+		Expect(replicas("orders-master")).To(Equal(int32(2)))
+		Expect(replicas("orders-data")).To(Equal(int32(2)))
+
+		settle("orders-master")
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(Succeed())
+		Expect(replicas("orders-master")).To(Equal(int32(1)))
+		Expect(replicas("orders-data")).To(Equal(int32(2)))
+
+		settle("orders-master")
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(Succeed())
+		Expect(replicas("orders-data")).To(Equal(int32(3)))
+
+		// Growing the masters does not restart the nodes with new bootstrap settings
+		Expect(env(get("orders-master"))).To(HaveKeyWithValue(elasticsearchInitialMasterNodes, "orders-master-0,orders-master-1,orders-master-2"))
+	})
+
+	It("should move the shards off data nodes before removing them", func() {
+		database.Spec.Elasticsearch.NodeSets[1].Replicas = 3
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(Succeed())
+		settle("orders-master")
+		settle("orders-data")
+
+		database.Spec.Elasticsearch.NodeSets[1].Replicas = 2
+		shards["orders-data-2"] = 4
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(Succeed())
+		Expect(replicas("orders-data")).To(Equal(int32(3)))
+		Expect(excluded).To(Equal([][]string{{"orders-data-2"}}))
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionDownscaleBlocked)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal("ShardsRelocating"))
+
+		shards["orders-data-2"] = 0
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(Succeed())
+		Expect(replicas("orders-data")).To(Equal(int32(2)))
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionDownscaleBlocked)).To(BeNil())
+
+		// The exclusion is lifted once the scale-down completed
+		settle("orders-data")
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(Succeed())
+		Expect(excluded[len(excluded)-1]).To(BeEmpty())
+		Expect(activeOperation(database)).To(BeEmpty())
+	})
+
+	It("should not split a single node cluster into node sets", func() {
+		single := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"}}
+		Expect(reconciler.Create(ctx, single)).To(Succeed())
+		Expect(reconciler.reconcileElasticsearch(ctx, database)).To(MatchError(ContainSubstring("single node")))
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders-master", Namespace: "shop"}, &appsv1.StatefulSet{})).NotTo(Succeed())
+	})
+})
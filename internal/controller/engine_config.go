@@ -0,0 +1,338 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"path"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// configHashAnnotation records the hash of the rendered engine configuration
+	// on its ConfigMap revision and on the pod template it was applied to
+	configHashAnnotation = "databases.database-operator.io/config-hash"
+
+	conditionConfigDrift = "ConfigDrift"
+	engineConfigVolume   = "engine-config"
+	engineConfigLabel    = "config"
+)
+
+// engineConfig is the configuration file rendered from the engine parameters
+type engineConfig struct {
+	// File is the name of the configuration file
+	File string
+	// Content is the rendered configuration file
+	Content string
+	// Hash identifies the rendered content
+	Hash string
+}
+
+// renderEngineConfig renders the engine parameters into the configuration file
+// format of the engine. Parameters are sorted, so equal specs render to the same
+// content and hash. It returns nil for engines without a configuration file.
+func renderEngineConfig(database *databasesv1alpha1.Database) (*engineConfig, error) {
+	var file, content string
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		file = "postgresql.conf"
+		parameters := postgreSQLParameters(database)
+		for _, key := range sortedParameterKeys(parameters) {
+			value := strings.ReplaceAll(parameters[key], "'", "''")
+			content += fmt.Sprintf("%s = '%s'\n", key, value)
+		}
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		file = "mongod.conf"
+		var err error
+		if content, err = renderMongoDBConfig(mongoDBParameters(database)); err != nil {
+			return nil, err
+		}
+	case databasesv1alpha1.DatabaseTypeRedis:
+		file = "redis.conf"
+		parameters := redisParameters(database)
+		for _, key := range sortedParameterKeys(parameters) {
+			content += fmt.Sprintf("%s %s\n", key, parameters[key])
+		}
+	case databasesv1alpha1.DatabaseTypeElasticsearch:
+		file = "elasticsearch.yml"
+		parameters := elasticsearchParameters(database)
+		for _, key := range sortedParameterKeys(parameters) {
+			content += fmt.Sprintf("%s: %s\n", key, parameters[key])
+		}
+	default:
+		return nil, nil
+	}
+
+	sum := sha256.Sum256([]byte(file + "\n" + content))
+	return &engineConfig{File: file, Content: content, Hash: hex.EncodeToString(sum[:])[:16]}, nil
+}
+
+// renderMongoDBConfig turns dotted parameter names (net.maxIncomingConnections)
+// into the nested YAML layout of mongod.conf
+func renderMongoDBConfig(parameters map[string]string) (string, error) {
+	keys := sortedParameterKeys(parameters)
+	for _, key := range keys {
+		for i := strings.LastIndex(key, "."); i > 0; i = strings.LastIndex(key[:i], ".") {
+			if _, ok := parameters[key[:i]]; ok {
+				return "", fmt.Errorf("mongodb parameter %q conflicts with %q", key[:i], key)
+			}
+		}
+	}
+
+	var b strings.Builder
+	var previous []string
+	for _, key := range keys {
+		path := strings.Split(key, ".")
+		common := 0
+		for common < len(previous)-1 && common < len(path)-1 && previous[common] == path[common] {
+			common++
+		}
+		for depth := common; depth < len(path)-1; depth++ {
+			fmt.Fprintf(&b, "%s%s:\n", strings.Repeat("  ", depth), path[depth])
+		}
+		fmt.Fprintf(&b, "%s%s: %s\n", strings.Repeat("  ", len(path)-1), path[len(path)-1], parameters[key])
+		previous = path
+	}
+	return b.String(), nil
+}
+
+func sortedParameterKeys(parameters map[string]string) []string {
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func postgreSQLParameters(database *databasesv1alpha1.Database) map[string]string {
+	if database.Spec.PostgreSQL == nil {
+		return withOperatorParameters(database, nil)
+	}
+	return withOperatorParameters(database, database.Spec.PostgreSQL.Parameters)
+}
+
+func mongoDBParameters(database *databasesv1alpha1.Database) map[string]string {
+	if database.Spec.MongoDB == nil {
+		return withOperatorParameters(database, nil)
+	}
+	return withOperatorParameters(database, database.Spec.MongoDB.Parameters)
+}
+
+func redisParameters(database *databasesv1alpha1.Database) map[string]string {
+	if database.Spec.Redis == nil {
+		return withOperatorParameters(database, nil)
+	}
+	return withOperatorParameters(database, database.Spec.Redis.Parameters)
+}
+
+// withOperatorParameters adds the memory settings of the profile and the
+// parameters of TLS, log shipping, the engine log level and slow query capture
+// to the engine parameters of the spec, which take precedence
+func withOperatorParameters(database *databasesv1alpha1.Database, parameters map[string]string) map[string]string {
+	operator := map[string]string{}
+	maps.Copy(operator, profileParameters(database))
+	maps.Copy(operator, tlsParameters(database))
+	maps.Copy(operator, loggingParameters(database))
+	maps.Copy(operator, engineLogLevelParameters(database))
+	maps.Copy(operator, slowQueryParameters(database))
+	if len(operator) == 0 {
+		return parameters
+	}
+	maps.Copy(operator, parameters)
+	return operator
+}
+
+func elasticsearchParameters(database *databasesv1alpha1.Database) map[string]string {
+	if database.Spec.Elasticsearch == nil {
+		return nil
+	}
+	return database.Spec.Elasticsearch.Parameters
+}
+
+// engineConfigName is the name of the ConfigMap holding a configuration revision
+func engineConfigName(database *databasesv1alpha1.Database, hash string) string {
+	return fmt.Sprintf("%s-config-%s", database.Name, hash)
+}
+
+// reconcileEngineConfig stores the rendered configuration in an immutable
+// ConfigMap named after its hash. Revisions are kept as long as a workload may
+// still run them, so the configuration in effect can always be retrieved.
+func (r *DatabaseReconciler) reconcileEngineConfig(ctx context.Context, database *databasesv1alpha1.Database) error {
+	config, err := renderEngineConfig(database)
+	if err != nil || config == nil {
+		return err
+	}
+
+	name := engineConfigName(database, config.Hash)
+	err = r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, &corev1.ConfigMap{})
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	immutable := true
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   database.Namespace,
+			Labels:      r.getComponentLabels(database, engineConfigLabel),
+			Annotations: map[string]string{configHashAnnotation: config.Hash},
+		},
+		Data:      map[string]string{config.File: config.Content},
+		Immutable: &immutable,
+	}
+	if err := controllerutil.SetControllerReference(database, configMap, r.Scheme); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("Creating configuration revision", "name", name, "hash", config.Hash)
+	return r.Create(ctx, configMap)
+}
+
+// applyEngineConfig configures a new workload from the rendered configuration
+// and stamps its pod template with the configuration hash
+func (r *DatabaseReconciler) applyEngineConfig(database *databasesv1alpha1.Database, template *corev1.PodTemplateSpec) {
+	config, err := renderEngineConfig(database)
+	if err != nil || config == nil {
+		return
+	}
+
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[configHashAnnotation] = config.Hash
+
+	container := &template.Spec.Containers[0]
+	mount := func(path string) {
+		template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+			Name: engineConfigVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: engineConfigName(database, config.Hash)},
+				},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      engineConfigVolume,
+			MountPath: path,
+			ReadOnly:  true,
+		})
+	}
+
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		// Command line settings keep the defaults initdb writes to the data directory
+		parameters := postgreSQLParameters(database)
+		for _, key := range sortedParameterKeys(parameters) {
+			container.Args = append(container.Args, "-c", fmt.Sprintf("%s=%s", key, parameters[key]))
+		}
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		if len(mongoDBParameters(database)) > 0 {
+			mount(engineLayout(database).configPath)
+			container.Args = append(container.Args, "--config", path.Join(engineLayout(database).configPath, config.File))
+		}
+	case databasesv1alpha1.DatabaseTypeRedis:
+		if len(redisParameters(database)) > 0 {
+			mount(engineLayout(database).configPath)
+			container.Args = append([]string{"redis-server", path.Join(engineLayout(database).configPath, config.File)}, container.Args...)
+		}
+	case databasesv1alpha1.DatabaseTypeElasticsearch:
+		// The image turns dotted environment variables into settings, which keeps
+		// the elasticsearch.yml shipped with the image in place
+		parameters := elasticsearchParameters(database)
+		for _, key := range sortedParameterKeys(parameters) {
+			container.Env = append(container.Env, corev1.EnvVar{Name: key, Value: parameters[key]})
+		}
+	}
+}
+
+// recordAppliedConfig reports the configuration revision the workload runs,
+// flags drift from the spec and deletes revisions nothing refers to anymore.
+// The workload rolls out a changed configuration with its pod template, so
+// drift lasts until the update is allowed to run.
+func (r *DatabaseReconciler) recordAppliedConfig(ctx context.Context, database *databasesv1alpha1.Database) error {
+	config, err := renderEngineConfig(database)
+	if err != nil || config == nil {
+		return err
+	}
+
+	// The node sets of an Elasticsearch cluster roll out the configuration in
+	// turn, the last one reports it
+	names := workloadNames(database)
+	statefulSet := &appsv1.StatefulSet{}
+	if err := r.Get(ctx, types.NamespacedName{Name: names[len(names)-1], Namespace: database.Namespace}, statefulSet); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	applied := statefulSet.Spec.Template.Annotations[configHashAnnotation]
+	database.Status.AppliedConfigHash = applied
+
+	switch applied {
+	case "":
+		meta.RemoveStatusCondition(&database.Status.Conditions, conditionConfigDrift)
+	case config.Hash:
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+			Type:               conditionConfigDrift,
+			Status:             metav1.ConditionFalse,
+			Reason:             "ConfigApplied",
+			Message:            fmt.Sprintf("Running configuration revision %s", engineConfigName(database, applied)),
+			ObservedGeneration: database.Generation,
+		})
+	default:
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+			Type:   conditionConfigDrift,
+			Status: metav1.ConditionTrue,
+			Reason: "ConfigChanged",
+			Message: fmt.Sprintf("Running configuration revision %s, spec renders %s",
+				engineConfigName(database, applied), engineConfigName(database, config.Hash)),
+			ObservedGeneration: database.Generation,
+		})
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := r.List(ctx, configMaps, client.InNamespace(database.Namespace),
+		client.MatchingLabels(r.getComponentLabels(database, engineConfigLabel))); err != nil {
+		return err
+	}
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+		if hash := configMap.Annotations[configHashAnnotation]; hash == applied || hash == config.Hash {
+			continue
+		}
+		log.FromContext(ctx).Info("Deleting unused configuration revision", "name", configMap.Name)
+		if err := r.Delete(ctx, configMap); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
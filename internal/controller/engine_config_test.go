@@ -0,0 +1,150 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Engine configuration", func() {
+	var database *databasesv1alpha1.Database
+
+	BeforeEach(func() {
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				PostgreSQL: &databasesv1alpha1.PostgreSQLConfig{Parameters: map[string]string{
+					"max_connections": "200",
+					"search_path":     "'$user', public",
+				}},
+			},
+		}
+	})
+
+	It("should render parameters in a stable order", func() {
+		config, err := renderEngineConfig(database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.File).To(Equal("postgresql.conf"))
+		Expect(config.Content).To(Equal("max_connections = '200'\nsearch_path = '''$user'', public'\n"))
+
+		again, err := renderEngineConfig(database.DeepCopy())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again.Hash).To(Equal(config.Hash))
+
+		database.Spec.PostgreSQL.Parameters["max_connections"] = "300"
+		changed, err := renderEngineConfig(database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed.Hash).NotTo(Equal(config.Hash))
+	})
+
+	It("should nest dotted MongoDB parameters", func() {
+		content, err := renderMongoDBConfig(map[string]string{
+			"net.maxIncomingConnections":                  "500",
+			"net.compression.compressors":                 "zstd",
+			"operationProfiling.slowOpThresholdMs":        "50",
+			"storage.wiredTiger.engineConfig.cacheSizeGB": "1",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(Equal(`net:
+  compression:
+    compressors: zstd
+  maxIncomingConnections: 500
+operationProfiling:
+  slowOpThresholdMs: 50
+storage:
+  wiredTiger:
+    engineConfig:
+      cacheSizeGB: 1
+`))
+
+		_, err = renderMongoDBConfig(map[string]string{"net": "x", "net.port": "27017"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should apply the configuration to new workloads", func() {
+		reconciler := &DatabaseReconciler{}
+		statefulSet := reconciler.createPostgreSQLStatefulSet(database, 1, nil)
+		config, _ := renderEngineConfig(database)
+		Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue(configHashAnnotation, config.Hash))
+		Expect(statefulSet.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{
+			"-c", "max_connections=200", "-c", "search_path='$user', public",
+		}))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		database.Spec.Redis = &databasesv1alpha1.RedisConfig{Parameters: map[string]string{"maxmemory": "256mb"}}
+		statefulSet = reconciler.createRedisStatefulSet(database, 1, nil)
+		container := statefulSet.Spec.Template.Spec.Containers[0]
+		Expect(container.Args).To(Equal([]string{"redis-server", "/usr/local/etc/redis/redis.conf"}))
+		Expect(container.VolumeMounts).To(ContainElement(HaveField("Name", engineConfigVolume)))
+	})
+
+	It("should record the applied revision and report drift", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		reconciler := &DatabaseReconciler{Scheme: scheme}
+		applied, _ := renderEngineConfig(database)
+		statefulSet := reconciler.createPostgreSQLStatefulSet(database, 1, nil)
+		reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(database, statefulSet).Build()
+		ctx := context.Background()
+
+		Expect(reconciler.reconcileEngineConfig(ctx, database)).To(Succeed())
+		Expect(reconciler.recordAppliedConfig(ctx, database)).To(Succeed())
+		Expect(database.Status.AppliedConfigHash).To(Equal(applied.Hash))
+		Expect(meta.IsStatusConditionFalse(database.Status.Conditions, conditionConfigDrift)).To(BeTrue())
+
+		database.Spec.PostgreSQL.Parameters["max_connections"] = "300"
+		desired, _ := renderEngineConfig(database)
+		Expect(reconciler.reconcileEngineConfig(ctx, database)).To(Succeed())
+		Expect(reconciler.recordAppliedConfig(ctx, database)).To(Succeed())
+		Expect(database.Status.AppliedConfigHash).To(Equal(applied.Hash))
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, conditionConfigDrift)).To(BeTrue())
+
+		configMaps := &corev1.ConfigMapList{}
+		Expect(reconciler.List(ctx, configMaps)).To(Succeed())
+		Expect(configMaps.Items).To(ConsistOf(
+			HaveField("Name", engineConfigName(database, applied.Hash)),
+			HaveField("Name", engineConfigName(database, desired.Hash)),
+		))
+
+		// A third revision replaces the unapplied one
+		database.Spec.PostgreSQL.Parameters["max_connections"] = "400"
+		latest, _ := renderEngineConfig(database)
+		Expect(reconciler.reconcileEngineConfig(ctx, database)).To(Succeed())
+		Expect(reconciler.recordAppliedConfig(ctx, database)).To(Succeed())
+		Expect(reconciler.List(ctx, configMaps)).To(Succeed())
+		Expect(configMaps.Items).To(ConsistOf(
+			HaveField("Name", engineConfigName(database, applied.Hash)),
+			HaveField("Name", engineConfigName(database, latest.Hash)),
+		))
+	})
+})
@@ -0,0 +1,39 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// event records a Kubernetes Event on the Database. Reconcilers built without a
+// Recorder, as in tests, record nothing.
+func (r *DatabaseReconciler) event(database *databasesv1alpha1.Database, eventType, reason, message string) {
+	recordEvent(r.Recorder, database, eventType, reason, message)
+}
+
+// recordEvent records a Kubernetes Event on an object when there is a recorder
+func recordEvent(recorder record.EventRecorder, object runtime.Object, eventType, reason, message string) {
+	if recorder != nil {
+		recorder.Event(object, eventType, reason, message)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"maps"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	externalDNSTTLAnnotation      = "external-dns.alpha.kubernetes.io/ttl"
+)
+
+// serviceType returns the type of the database Service, ClusterIP unless the
+// networking spec exposes it
+func serviceType(database *databasesv1alpha1.Database) corev1.ServiceType {
+	if networking := database.Spec.Networking; networking != nil && networking.ServiceType != "" {
+		return networking.ServiceType
+	}
+	return corev1.ServiceTypeClusterIP
+}
+
+// serviceExposed reports whether the database Service is reachable from
+// outside the cluster
+func serviceExposed(database *databasesv1alpha1.Database) bool {
+	return serviceType(database) != corev1.ServiceTypeClusterIP
+}
+
+// externalDNSAnnotations returns the annotations external-dns reads the record
+// of the Service from
+func externalDNSAnnotations(database *databasesv1alpha1.Database) map[string]string {
+	annotations := map[string]string{}
+	networking := database.Spec.Networking
+	if networking == nil || networking.ExternalDNS == nil || !serviceExposed(database) {
+		return annotations
+	}
+	annotations[externalDNSHostnameAnnotation] = networking.ExternalDNS.Hostname
+	if ttl := networking.ExternalDNS.TTL; ttl != nil {
+		annotations[externalDNSTTLAnnotation] = strconv.Itoa(int(*ttl))
+	}
+	return annotations
+}
+
+// applyServiceExposure sets the type and the external-dns annotations of the
+// database Service. The API server drops the node ports and other fields of
+// the previous type.
+func applyServiceExposure(database *databasesv1alpha1.Database, service *corev1.Service) {
+	service.Spec.Type = serviceType(database)
+	annotations := externalDNSAnnotations(database)
+	if len(annotations) == 0 {
+		return
+	}
+	if service.Annotations == nil {
+		service.Annotations = map[string]string{}
+	}
+	maps.Copy(service.Annotations, annotations)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Service exposure", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	serviceKey := types.NamespacedName{Name: "orders-service", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build(), Scheme: scheme}
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type: databasesv1alpha1.DatabaseTypePostgreSQL,
+				Networking: &databasesv1alpha1.NetworkingSpec{
+					ServiceType: corev1.ServiceTypeLoadBalancer,
+					ExternalDNS: &databasesv1alpha1.ExternalDNSSpec{Hostname: "orders.example.com", TTL: ptr.To(int32(60))},
+				},
+			},
+		}
+	})
+
+	It("should expose the Service and publish its DNS record", func() {
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+
+		service := &corev1.Service{}
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+		Expect(service.Annotations).To(HaveKeyWithValue(externalDNSHostnameAnnotation, "orders.example.com"))
+		Expect(service.Annotations).To(HaveKeyWithValue(externalDNSTTLAnnotation, "60"))
+	})
+
+	It("should follow changes of the exposure and record the applied spec", func() {
+		database.Spec.Networking = nil
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		service := &corev1.Service{}
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+		service.Annotations = map[string]string{databasesv1alpha1.AppliedSpecHashAnnotation: appliedSpecHash(service.Spec)}
+		Expect(reconciler.Update(ctx, service)).To(Succeed())
+
+		database.Spec.Networking = &databasesv1alpha1.NetworkingSpec{
+			ServiceType: corev1.ServiceTypeNodePort,
+			ExternalDNS: &databasesv1alpha1.ExternalDNSSpec{Hostname: "orders.example.com"},
+		}
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeNodePort))
+		Expect(service.Annotations).To(Equal(map[string]string{
+			externalDNSHostnameAnnotation:               "orders.example.com",
+			databasesv1alpha1.AppliedSpecHashAnnotation: appliedSpecHash(service.Spec),
+		}))
+
+		database.Spec.Networking = nil
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeClusterIP))
+		Expect(service.Annotations).To(Equal(map[string]string{
+			databasesv1alpha1.AppliedSpecHashAnnotation: appliedSpecHash(service.Spec),
+		}))
+	})
+})
@@ -0,0 +1,280 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// targetCluster is the connection to the cluster a Database places its child
+// resources in
+type targetCluster struct {
+	client      client.Client
+	volumeUsage VolumeUsageReader
+}
+
+// connectTargetCluster connects to a cluster with its kubeconfig; replaced in
+// tests
+var connectTargetCluster = func(kubeconfig []byte, scheme *runtime.Scheme) (*targetCluster, error) {
+	config, err := targetClusterConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewKubeletStatsReader(config)
+	if err != nil {
+		return nil, err
+	}
+	return &targetCluster{client: client.WithFieldOwner(c, FieldOwner), volumeUsage: reader}, nil
+}
+
+// targetClusterConfig builds the client configuration of a kubeconfig written
+// by users of the namespace. Only inline credentials are accepted: plugins
+// would run commands in the operator pod, and file paths would read its files,
+// such as its ServiceAccount token, and send them to the server of the
+// kubeconfig.
+func targetClusterConfig(kubeconfig []byte) (*rest.Config, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	for name, authInfo := range config.AuthInfos {
+		switch {
+		case authInfo.Exec != nil:
+			return nil, fmt.Errorf("user %s of the kubeconfig runs an exec plugin", name)
+		case authInfo.AuthProvider != nil:
+			return nil, fmt.Errorf("user %s of the kubeconfig uses an auth provider", name)
+		case authInfo.TokenFile != "", authInfo.ClientCertificate != "", authInfo.ClientKey != "":
+			return nil, fmt.Errorf("user %s of the kubeconfig reads credentials from files, use inline data", name)
+		}
+	}
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("cluster %s of the kubeconfig reads its certificate authority from a file, use certificate-authority-data", name)
+		}
+	}
+	return clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
+
+// targetClusters caches the connections to target clusters by kubeconfig
+// Secret, until the Secret changes
+var targetClusters = struct {
+	sync.Mutex
+	connections map[types.NamespacedName]cachedTargetCluster
+}{connections: map[types.NamespacedName]cachedTargetCluster{}}
+
+type cachedTargetCluster struct {
+	resourceVersion string
+	cluster         *targetCluster
+}
+
+// targetClusterReconciler returns a copy of the reconciler whose child
+// resources are read and written in the target cluster of the Database, while
+// the resources of the API group stay in this cluster
+func (r *DatabaseReconciler) targetClusterReconciler(ctx context.Context, database *databasesv1alpha1.Database) (*DatabaseReconciler, error) {
+	reference := database.Spec.TargetCluster.KubeconfigSecret
+	key := types.NamespacedName{Name: reference.Name, Namespace: database.Namespace}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig Secret %s: %w", reference.Name, err)
+	}
+	kubeconfig, ok := secret.Data[reference.Key]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig Secret %s has no key %s", reference.Name, reference.Key)
+	}
+
+	targetClusters.Lock()
+	defer targetClusters.Unlock()
+	cached, ok := targetClusters.connections[key]
+	if !ok || cached.resourceVersion != secret.ResourceVersion {
+		cluster, err := connectTargetCluster(kubeconfig, r.Scheme)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to the cluster of kubeconfig Secret %s: %w", reference.Name, err)
+		}
+		log.FromContext(ctx).Info("Connected to target cluster", "secret", reference.Name)
+		cached = cachedTargetCluster{resourceVersion: secret.ResourceVersion, cluster: cluster}
+		targetClusters.connections[key] = cached
+	}
+
+	target := *r
+	target.Client = &fleetClient{Client: cached.cluster.client, hub: r.Client, owner: database.UID}
+	target.VolumeUsageReader = cached.cluster.volumeUsage
+	return &target, nil
+}
+
+// fleetClient routes the resources of the API group to the hub cluster, which
+// runs the operator, and every other resource to the target cluster. Objects
+// written to the target cluster lose their owner references to resources of
+// the hub, which would get them garbage collected there, and are labeled with
+// the UID of their Database instead.
+type fleetClient struct {
+	client.Client
+	hub   client.Client
+	owner types.UID
+}
+
+// isHubObject reports whether an object is a resource of the API group
+func (c *fleetClient) isHubObject(obj runtime.Object) bool {
+	gvk, err := apiutil.GVKForObject(obj, c.hub.Scheme())
+	return err == nil && gvk.Group == databasesv1alpha1.GroupVersion.Group
+}
+
+func (c *fleetClient) route(obj runtime.Object) client.Client {
+	if c.isHubObject(obj) {
+		return c.hub
+	}
+	return c.Client
+}
+
+// adopt replaces the owner references to hub resources by the owner label
+func (c *fleetClient) adopt(obj client.Object) {
+	if c.isHubObject(obj) {
+		return
+	}
+	var references []metav1.OwnerReference
+	for _, reference := range obj.GetOwnerReferences() {
+		if gv, err := schema.ParseGroupVersion(reference.APIVersion); err == nil && gv.Group == databasesv1alpha1.GroupVersion.Group {
+			continue
+		}
+		references = append(references, reference)
+	}
+	obj.SetOwnerReferences(references)
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[databasesv1alpha1.FleetOwnerLabel] = string(c.owner)
+	obj.SetLabels(labels)
+}
+
+func (c *fleetClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.route(obj).Get(ctx, key, obj, opts...)
+}
+
+func (c *fleetClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.route(list).List(ctx, list, opts...)
+}
+
+func (c *fleetClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.adopt(obj)
+	return c.route(obj).Create(ctx, obj, opts...)
+}
+
+func (c *fleetClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.adopt(obj)
+	return c.route(obj).Update(ctx, obj, opts...)
+}
+
+func (c *fleetClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() == types.ApplyPatchType {
+		c.adopt(obj)
+	}
+	return c.route(obj).Patch(ctx, obj, patch, opts...)
+}
+
+func (c *fleetClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return c.route(obj).Delete(ctx, obj, opts...)
+}
+
+func (c *fleetClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	return c.route(obj).DeleteAllOf(ctx, obj, opts...)
+}
+
+func (c *fleetClient) Status() client.SubResourceWriter {
+	return c.SubResource("status")
+}
+
+func (c *fleetClient) SubResource(subResource string) client.SubResourceClient {
+	return &fleetSubResourceClient{fleet: c, subResource: subResource}
+}
+
+// fleetSubResourceClient routes subresource requests like fleetClient
+type fleetSubResourceClient struct {
+	fleet       *fleetClient
+	subResource string
+}
+
+func (c *fleetSubResourceClient) Get(ctx context.Context, obj, subResource client.Object, opts ...client.SubResourceGetOption) error {
+	return c.fleet.route(obj).SubResource(c.subResource).Get(ctx, obj, subResource, opts...)
+}
+
+func (c *fleetSubResourceClient) Create(ctx context.Context, obj, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	return c.fleet.route(obj).SubResource(c.subResource).Create(ctx, obj, subResource, opts...)
+}
+
+func (c *fleetSubResourceClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	return c.fleet.route(obj).SubResource(c.subResource).Update(ctx, obj, opts...)
+}
+
+func (c *fleetSubResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	return c.fleet.route(obj).SubResource(c.subResource).Patch(ctx, obj, patch, opts...)
+}
+
+// deleteTargetClusterResources deletes the child resources a Database created
+// in its target cluster, which garbage collection does not delete there.
+// Volumes are kept, as StatefulSets keep them.
+func (r *DatabaseReconciler) deleteTargetClusterResources(ctx context.Context, database *databasesv1alpha1.Database) error {
+	if _, ok := r.Client.(*fleetClient); !ok {
+		return nil
+	}
+	lists := []client.ObjectList{
+		&appsv1.StatefulSetList{}, &appsv1.DeploymentList{}, &corev1.ServiceList{}, &corev1.ConfigMapList{},
+		&corev1.SecretList{}, &corev1.ServiceAccountList{}, &batchv1.CronJobList{}, &batchv1.JobList{},
+		&networkingv1.NetworkPolicyList{}, &networkingv1.IngressList{},
+	}
+	for _, list := range lists {
+		if err := r.List(ctx, list, client.InNamespace(database.Namespace),
+			client.MatchingLabels{databasesv1alpha1.FleetOwnerLabel: string(database.UID)}); err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			err := r.Delete(ctx, item.(client.Object), client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	log.FromContext(ctx).Info("Deleted the child resources in the target cluster")
+	return nil
+}
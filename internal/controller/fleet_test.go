@@ -0,0 +1,155 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Fleet mode", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		spoke      client.Client
+		database   *databasesv1alpha1.Database
+		connected  []byte
+	)
+
+	serviceKey := types.NamespacedName{Name: "orders-service", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				TargetCluster: &databasesv1alpha1.TargetClusterSpec{
+					KubeconfigSecret: databasesv1alpha1.SecretReference{Name: "eu-west-kubeconfig", Key: "value"},
+				},
+			},
+		}
+		kubeconfig := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "eu-west-kubeconfig", Namespace: "shop"},
+			Data:       map[string][]byte{"value": []byte("eu-west")},
+		}
+		reconciler = &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(database, kubeconfig).
+				WithStatusSubresource(database).WithInterceptorFuncs(applyPatches()).Build(),
+			Scheme: scheme,
+			Fleet:  true,
+		}
+		spoke = fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build()
+
+		original := connectTargetCluster
+		connectTargetCluster = func(kubeconfig []byte, _ *runtime.Scheme) (*targetCluster, error) {
+			connected = kubeconfig
+			return &targetCluster{client: spoke}, nil
+		}
+		targetClusters.connections = map[types.NamespacedName]cachedTargetCluster{}
+		DeferCleanup(func() {
+			connectTargetCluster = original
+		})
+	})
+
+	It("should create child resources in the target cluster and keep the Database in the hub", func() {
+		target, err := reconciler.targetClusterReconciler(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(connected)).To(Equal("eu-west"))
+
+		Expect(target.reconcileService(ctx, database)).To(Succeed())
+		service := &corev1.Service{}
+		Expect(spoke.Get(ctx, serviceKey, service)).To(Succeed())
+		Expect(service.OwnerReferences).To(BeEmpty())
+		Expect(service.Labels).To(HaveKeyWithValue(databasesv1alpha1.FleetOwnerLabel, "orders-uid"))
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, serviceKey, &corev1.Service{}))).To(BeTrue())
+
+		database.Status.Message = "Provisioning in eu-west"
+		Expect(target.Status().Update(ctx, database)).To(Succeed())
+		hub := &databasesv1alpha1.Database{}
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(database), hub)).To(Succeed())
+		Expect(hub.Status.Message).To(Equal("Provisioning in eu-west"))
+
+		Expect(target.deleteTargetClusterResources(ctx, database)).To(Succeed())
+		Expect(apierrors.IsNotFound(spoke.Get(ctx, serviceKey, &corev1.Service{}))).To(BeTrue())
+	})
+
+	It("should reject target clusters outside fleet mode", func() {
+		reconciler.Fleet = false
+		Expect(reconciler.validateSpec(database)).To(MatchError("targetCluster requires the operator to run in fleet mode"))
+
+		database.Spec.TargetCluster.KubeconfigSecret.Key = "kubeconfig"
+		reconciler.Fleet = true
+		_, err := reconciler.targetClusterReconciler(ctx, database)
+		Expect(err).To(MatchError("kubeconfig Secret eu-west-kubeconfig has no key kubeconfig"))
+	})
+
+	It("should only accept kubeconfigs with inline credentials", func() {
+		kubeconfig := func(cluster, user string) []byte {
+			return []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: eu-west
+  cluster:
+    server: https://eu-west.example.com
+` + cluster + `
+users:
+- name: operator
+  user:
+` + user + `
+contexts:
+- name: eu-west
+  context: {cluster: eu-west, user: operator}
+current-context: eu-west
+`)
+		}
+		config, err := targetClusterConfig(kubeconfig("    certificate-authority-data: Y2E=", "    token: secret"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Host).To(Equal("https://eu-west.example.com"))
+		Expect(config.BearerToken).To(Equal("secret"))
+
+		for user, message := range map[string]string{
+			"    exec: {apiVersion: client.authentication.k8s.io/v1, command: sh}": "user operator of the kubeconfig runs an exec plugin",
+			"    auth-provider: {name: oidc}":                                      "user operator of the kubeconfig uses an auth provider",
+			"    tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token":   "user operator of the kubeconfig reads credentials from files, use inline data",
+			"    client-key: /etc/ssl/private/key.pem":                             "user operator of the kubeconfig reads credentials from files, use inline data",
+		} {
+			_, err := targetClusterConfig(kubeconfig("", user))
+			Expect(err).To(MatchError(message), user)
+		}
+		_, err = targetClusterConfig(kubeconfig("    certificate-authority: /var/run/secrets/kubernetes.io/serviceaccount/ca.crt", "    token: secret"))
+		Expect(err).To(MatchError(ContainSubstring("cluster eu-west of the kubeconfig reads its certificate authority from a file")))
+	})
+})
@@ -0,0 +1,98 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const conditionFrozen = "Frozen"
+
+// frozenUntil returns the end of the release freeze of the Database, or the zero
+// time when it is not frozen at now. An invalid annotation freezes nothing.
+func frozenUntil(database *databasesv1alpha1.Database, now time.Time) time.Time {
+	value, ok := database.Annotations[databasesv1alpha1.FreezeUntilAnnotation]
+	if !ok {
+		return time.Time{}
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil || !until.After(now) {
+		return time.Time{}
+	}
+	return until
+}
+
+// frozen reports whether the disruptive actions of the operator are suspended
+func frozen(database *databasesv1alpha1.Database, now time.Time) bool {
+	return !frozenUntil(database, now).IsZero()
+}
+
+// freezeRemaining returns the time left until the release freeze ends
+func freezeRemaining(database *databasesv1alpha1.Database, now time.Time) time.Duration {
+	until := frozenUntil(database, now)
+	if until.IsZero() {
+		return 0
+	}
+	return until.Sub(now)
+}
+
+// reconcileFreeze reports the release freeze of the freeze-until annotation in
+// the Frozen condition and the freeze metric
+func reconcileFreeze(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) {
+	labels := databaseMetricLabels(database)
+	value, ok := database.Annotations[databasesv1alpha1.FreezeUntilAnnotation]
+	if !ok {
+		freezeUntil.Delete(labels)
+		meta.RemoveStatusCondition(&database.Status.Conditions, conditionFrozen)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionFrozen,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: database.Generation,
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	switch {
+	case err != nil:
+		log.FromContext(ctx).Info("Ignoring invalid freeze annotation", "value", value)
+		condition.Reason = "InvalidFreeze"
+		condition.Message = fmt.Sprintf("Annotation %s=%q is not an RFC 3339 timestamp", databasesv1alpha1.FreezeUntilAnnotation, value)
+	case until.After(now):
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "ReleaseFreeze"
+		condition.Message = fmt.Sprintf("Scaling, workload updates, password rotation and auto-tuning are suspended until %s", until.UTC().Format(time.RFC3339))
+	default:
+		condition.Reason = "FreezeEnded"
+		condition.Message = fmt.Sprintf("Release freeze ended at %s", until.UTC().Format(time.RFC3339))
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, condition)
+
+	if condition.Status == metav1.ConditionTrue {
+		freezeUntil.With(labels).Set(float64(until.Unix()))
+	} else {
+		freezeUntil.Delete(labels)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Release freeze", func() {
+	var (
+		ctx      context.Context
+		database *databasesv1alpha1.Database
+		now      time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "orders",
+				Namespace:   "shop",
+				Annotations: map[string]string{databasesv1alpha1.FreezeUntilAnnotation: "2025-12-31T00:00:00Z"},
+			},
+			Spec: databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+		}
+		DeferCleanup(func() { deleteDatabaseMetrics(database) })
+	})
+
+	It("should report the freeze until it ends", func() {
+		reconcileFreeze(ctx, database, now)
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionFrozen)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(HaveSuffix("until 2025-12-31T00:00:00Z"))
+		Expect(testutil.ToFloat64(freezeUntil.With(databaseMetricLabels(database)))).To(Equal(float64(
+			time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC).Unix())))
+		Expect(freezeRemaining(database, now)).To(Equal(29*24*time.Hour + 12*time.Hour))
+
+		reconcileFreeze(ctx, database, now.AddDate(0, 1, 0))
+		condition = meta.FindStatusCondition(database.Status.Conditions, conditionFrozen)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("FreezeEnded"))
+		Expect(testutil.CollectAndCount(freezeUntil)).To(BeZero())
+
+		delete(database.Annotations, databasesv1alpha1.FreezeUntilAnnotation)
+		reconcileFreeze(ctx, database, now)
+		Expect(database.Status.Conditions).To(BeEmpty())
+	})
+
+	It("should ignore invalid timestamps", func() {
+		database.Annotations[databasesv1alpha1.FreezeUntilAnnotation] = "next week"
+		reconcileFreeze(ctx, database, now)
+		Expect(frozen(database, now)).To(BeFalse())
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionFrozen)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("InvalidFreeze"))
+	})
+
+	It("should defer scaling while frozen", func() {
+		database.Annotations[databasesv1alpha1.FreezeUntilAnnotation] = time.Now().Add(time.Hour).Format(time.RFC3339)
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		current := int32(3)
+		statefulSet := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &current},
+		}
+		reconciler := &DatabaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(statefulSet).Build(), Scheme: scheme}
+
+		Expect(reconciler.scaleStatefulSet(ctx, database, statefulSet, 1)).To(Succeed())
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders", Namespace: "shop"}, statefulSet)).To(Succeed())
+		Expect(*statefulSet.Spec.Replicas).To(Equal(int32(3)))
+		Expect(database.Status.Operations).To(BeNil())
+	})
+
+	It("should keep analysing without tuning while frozen", func() {
+		database.Annotations[databasesv1alpha1.FreezeUntilAnnotation] = time.Now().Add(time.Hour).Format(time.RFC3339)
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeElasticsearch
+		database.Spec.AutoTune = true
+		cronJob, err := (&DatabaseReconciler{}).createShardAnalysisCronJob(database, &databasesv1alpha1.ShardAnalysisSpec{Enabled: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "AUTO_TUNE", Value: "false"}))
+	})
+})
@@ -0,0 +1,151 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	healthCheckTimeout = 5 * time.Second
+	// healthCheckInterval is how often the engine is queried
+	healthCheckInterval = time.Minute
+)
+
+// healthCheckers query an engine over its Service. An error makes the engine
+// unhealthy; the returned status fills the remaining fields.
+var healthCheckers = map[databasesv1alpha1.DatabaseType]func(ctx context.Context, conn adminConnection) (*databasesv1alpha1.HealthStatus, error){
+	databasesv1alpha1.DatabaseTypePostgreSQL: func(ctx context.Context, conn adminConnection) (*databasesv1alpha1.HealthStatus, error) {
+		rows, err := postgresQuery(ctx, conn,
+			"SELECT pg_is_in_recovery() AS in_recovery, "+
+				"(SELECT count(*) FROM pg_stat_replication WHERE state = 'streaming') AS replicas, "+
+				"(SELECT floor(EXTRACT(EPOCH FROM max(replay_lag)))::bigint FROM pg_stat_replication) AS lag")
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) != 1 {
+			return nil, fmt.Errorf("unexpected result checking health")
+		}
+		return postgreSQLHealth(rows[0])
+	},
+	databasesv1alpha1.DatabaseTypeRedis: func(ctx context.Context, conn adminConnection) (*databasesv1alpha1.HealthStatus, error) {
+		info, err := redisInfo(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		health := &databasesv1alpha1.HealthStatus{State: databasesv1alpha1.HealthStateHealthy, Role: info["role"]}
+		if replicas, err := strconv.ParseInt(info["connected_slaves"], 10, 32); err == nil {
+			health.ConnectedReplicas = ptr.To(int32(replicas))
+		}
+		return health, nil
+	},
+	databasesv1alpha1.DatabaseTypeElasticsearch: func(ctx context.Context, conn adminConnection) (*databasesv1alpha1.HealthStatus, error) {
+		result, err := elasticsearchGet(ctx, conn, "/_cluster/health")
+		if err != nil {
+			return nil, err
+		}
+		return elasticsearchHealth(result)
+	},
+}
+
+// postgreSQLHealth reads the row of the PostgreSQL health query
+func postgreSQLHealth(row map[string]any) (*databasesv1alpha1.HealthStatus, error) {
+	health := &databasesv1alpha1.HealthStatus{State: databasesv1alpha1.HealthStateHealthy, Role: "primary"}
+	if row["in_recovery"] == "t" {
+		health.Role = "replica"
+	}
+	value, _ := row["replicas"].(string)
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replica count %q", value)
+	}
+	health.ConnectedReplicas = ptr.To(int32(replicas))
+	// The lag is NULL without replicas
+	if value, ok := row["lag"].(string); ok {
+		lag, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replication lag %q", value)
+		}
+		health.ReplicationLagSeconds = &lag
+	}
+	return health, nil
+}
+
+// elasticsearchHealth reads the cluster health; a red cluster is unhealthy
+func elasticsearchHealth(result any) (*databasesv1alpha1.HealthStatus, error) {
+	fields, ok := result.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected cluster health response")
+	}
+	health := &databasesv1alpha1.HealthStatus{State: databasesv1alpha1.HealthStateHealthy}
+	if nodes, ok := fields["number_of_data_nodes"].(float64); ok {
+		health.ConnectedReplicas = ptr.To(int32(nodes))
+	}
+	if status, _ := fields["status"].(string); status == "red" {
+		health.State = databasesv1alpha1.HealthStateUnhealthy
+		health.Message = "cluster status is red"
+	}
+	return health, nil
+}
+
+// checkHealth queries the engine once replicas are ready, at most every
+// healthCheckInterval, and records the result in status. Engines without a
+// checker, and workloads without ready replicas, report no health.
+func (r *DatabaseReconciler) checkHealth(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) {
+	check, ok := healthCheckers[database.Spec.Type]
+	if !ok || database.Status.ReadyReplicas == 0 {
+		database.Status.Health = nil
+		return
+	}
+	if health := database.Status.Health; health != nil && now.Sub(health.CheckedAt.Time) < healthCheckInterval {
+		return
+	}
+
+	health, err := func() (*databasesv1alpha1.HealthStatus, error) {
+		conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+		if err != nil {
+			return nil, err
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		defer cancel()
+		return check(checkCtx, conn)
+	}()
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Health check failed")
+		health = &databasesv1alpha1.HealthStatus{State: databasesv1alpha1.HealthStateUnhealthy, Message: err.Error()}
+	}
+	health.CheckedAt = metav1.NewTime(now)
+	database.Status.Health = health
+}
+
+// databaseUnhealthy returns the message of a failed health check
+func databaseUnhealthy(database *databasesv1alpha1.Database) (string, bool) {
+	health := database.Status.Health
+	if health == nil || health.State != databasesv1alpha1.HealthStateUnhealthy {
+		return "", false
+	}
+	return fmt.Sprintf("Health check failed: %s", health.Message), true
+}
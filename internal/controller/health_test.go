@@ -0,0 +1,111 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Health checks", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		checkErr   error
+		checks     int
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), Scheme: scheme}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeRedis, Version: "7"},
+			Status:     databasesv1alpha1.DatabaseStatus{ReadyReplicas: 1},
+		}
+		now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		checkErr, checks = nil, 0
+		original := healthCheckers[databasesv1alpha1.DatabaseTypeRedis]
+		healthCheckers[databasesv1alpha1.DatabaseTypeRedis] = func(_ context.Context, conn adminConnection) (*databasesv1alpha1.HealthStatus, error) {
+			checks++
+			Expect(conn.host).To(Equal("cache-service.shop.svc"))
+			if checkErr != nil {
+				return nil, checkErr
+			}
+			return &databasesv1alpha1.HealthStatus{State: databasesv1alpha1.HealthStateHealthy, Role: "master"}, nil
+		}
+		DeferCleanup(func() {
+			healthCheckers[databasesv1alpha1.DatabaseTypeRedis] = original
+		})
+	})
+
+	It("should record failed checks and check again after the interval", func() {
+		reconciler.checkHealth(context.Background(), database, now)
+		Expect(database.Status.Health.State).To(Equal(databasesv1alpha1.HealthStateHealthy))
+		Expect(database.Status.Health.Role).To(Equal("master"))
+		_, unhealthy := databaseUnhealthy(database)
+		Expect(unhealthy).To(BeFalse())
+
+		checkErr = errors.New("connection refused")
+		reconciler.checkHealth(context.Background(), database, now.Add(time.Second))
+		Expect(checks).To(Equal(1))
+
+		reconciler.checkHealth(context.Background(), database, now.Add(healthCheckInterval))
+		Expect(checks).To(Equal(2))
+		Expect(database.Status.Health.CheckedAt.Time).To(Equal(now.Add(healthCheckInterval)))
+		message, unhealthy := databaseUnhealthy(database)
+		Expect(unhealthy).To(BeTrue())
+		Expect(message).To(Equal("Health check failed: connection refused"))
+
+		database.Status.ReadyReplicas = 0
+		reconciler.checkHealth(context.Background(), database, now.Add(2*healthCheckInterval))
+		Expect(database.Status.Health).To(BeNil())
+	})
+
+	It("should read replication state from PostgreSQL and Elasticsearch", func() {
+		health, err := postgreSQLHealth(map[string]any{"in_recovery": "f", "replicas": "2", "lag": "3"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(health).To(Equal(&databasesv1alpha1.HealthStatus{
+			State: databasesv1alpha1.HealthStateHealthy, Role: "primary",
+			ConnectedReplicas: ptr.To[int32](2), ReplicationLagSeconds: ptr.To[int64](3),
+		}))
+		health, err = postgreSQLHealth(map[string]any{"in_recovery": "t", "replicas": "0", "lag": nil})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(health.Role).To(Equal("replica"))
+		Expect(health.ReplicationLagSeconds).To(BeNil())
+
+		health, err = elasticsearchHealth(map[string]any{"status": "red", "number_of_data_nodes": float64(3)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(health.State).To(Equal(databasesv1alpha1.HealthStateUnhealthy))
+		Expect(health.ConnectedReplicas).To(Equal(ptr.To[int32](3)))
+	})
+})
@@ -0,0 +1,74 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	reasonHibernated  = "Hibernated"
+	reasonHibernating = "Hibernating"
+	reasonWakingUp    = "WakingUp"
+)
+
+// hibernated reports whether spec.lifecycle.hibernate scales the workload to zero
+func hibernated(database *databasesv1alpha1.Database) bool {
+	return database.Spec.Lifecycle != nil && database.Spec.Lifecycle.Hibernate
+}
+
+// reportHibernation raises an Event when a Database starts hibernating, once
+// every replica stopped, and when it wakes up. previous is the phase before
+// advancePhase.
+func (r *DatabaseReconciler) reportHibernation(database *databasesv1alpha1.Database, previous databasesv1alpha1.DatabasePhase) {
+	phase := database.Status.Phase
+	switch {
+	case phase == databasesv1alpha1.DatabasePhaseHibernated && previous != phase:
+		r.event(database, corev1.EventTypeNormal, reasonHibernated,
+			"Every replica stopped, the data volumes and Secrets are kept")
+	case previous == databasesv1alpha1.DatabasePhaseHibernated && !hibernated(database):
+		r.event(database, corev1.EventTypeNormal, reasonWakingUp, "Starting the replicas on the kept data volumes")
+	}
+}
+
+// hibernationCondition returns the reason and message of the Ready condition
+// of a hibernated Database
+func hibernationCondition(database *databasesv1alpha1.Database) (string, string) {
+	if database.Status.Phase == databasesv1alpha1.DatabasePhaseHibernated {
+		return reasonHibernated, "Database is hibernated, unset spec.lifecycle.hibernate to start it"
+	}
+	return reasonHibernating, "Database is hibernating, the replicas are stopping"
+}
+
+// scaleDeployment applies the replica count of the SQLite Deployment, which
+// runs its single replica unless the Database is hibernated
+func (r *DatabaseReconciler) scaleDeployment(ctx context.Context, deployment *appsv1.Deployment, replicas int32) error {
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == replicas {
+		return nil
+	}
+	log.FromContext(ctx).Info("Scaling Deployment", "name", deployment.Name, "to", replicas)
+	before := *deployment.Spec.DeepCopy()
+	deployment.Spec.Replicas = &replicas
+	restampAppliedSpec(deployment, before)
+	return r.Update(ctx, deployment)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Hibernation", func() {
+	It("should scale the workload to zero, keep the data and wake up on the same volumes", func() {
+		ctx := context.Background()
+		key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:      databasesv1alpha1.DatabaseTypeMongoDB,
+				Version:   "7.0",
+				Replicas:  ptr.To(int32(3)),
+				Backup:    &databasesv1alpha1.BackupSpec{Enabled: true},
+				Lifecycle: &databasesv1alpha1.LifecycleSpec{},
+			},
+		}
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		recorder := record.NewFakeRecorder(20)
+		reconciler := &DatabaseReconciler{Scheme: scheme, Recorder: recorder}
+		claim := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-orders-0", Namespace: "shop"}}
+		credentials := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-credentials", Namespace: "shop"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		}
+		reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}, &appsv1.StatefulSet{}).
+			WithInterceptorFuncs(applyPatches()).
+			WithObjects(database, claim, credentials).Build()
+
+		reconcile := func() {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reconciler.Get(ctx, key, database)).To(Succeed())
+		}
+		statefulSet := func() *appsv1.StatefulSet {
+			statefulSet := &appsv1.StatefulSet{}
+			Expect(reconciler.Get(ctx, key, statefulSet)).To(Succeed())
+			return statefulSet
+		}
+		backupSuspended := func() bool {
+			cronJob := &batchv1.CronJob{}
+			Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders-" + backupComponent, Namespace: "shop"}, cronJob)).To(Succeed())
+			return ptr.Deref(cronJob.Spec.Suspend, false)
+		}
+
+		// runReplicas reports the replicas of the StatefulSet as running and ready
+		runReplicas := func(replicas int32) {
+			current := statefulSet()
+			current.Status.Replicas, current.Status.ReadyReplicas = replicas, replicas
+			Expect(reconciler.Status().Update(ctx, current)).To(Succeed())
+		}
+
+		reconcile()
+		reconcile()
+		runReplicas(3)
+		reconcile()
+		Expect(database.Status.Phase).To(Equal(databasesv1alpha1.DatabasePhaseReady))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal Provisioned")))
+
+		By("stopping every replica")
+		database.Spec.Lifecycle.Hibernate = true
+		Expect(reconciler.Update(ctx, database)).To(Succeed())
+		reconcile()
+		Expect(*statefulSet().Spec.Replicas).To(BeZero())
+		Expect(backupSuspended()).To(BeTrue())
+		Expect(database.Status.Backups.NextScheduledBackup).To(BeNil())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionReady)).
+			To(HaveField("Reason", reasonHibernating))
+
+		By("entering the Hibernated phase once they stopped")
+		runReplicas(0)
+		reconcile()
+		Expect(database.Status.Phase).To(Equal(databasesv1alpha1.DatabasePhaseHibernated))
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionReady)).
+			To(HaveField("Reason", reasonHibernated))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal Hibernated")))
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders-credentials", Namespace: "shop"}, &corev1.Secret{})).To(Succeed())
+
+		By("waking up when hibernate is unset")
+		database.Spec.Lifecycle.Hibernate = false
+		Expect(reconciler.Update(ctx, database)).To(Succeed())
+		reconcile()
+		Expect(*statefulSet().Spec.Replicas).To(Equal(int32(3)))
+		Expect(backupSuspended()).To(BeFalse())
+		Expect(database.Status.Phase).To(Equal(databasesv1alpha1.DatabasePhaseReady))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal WakingUp")))
+	})
+})
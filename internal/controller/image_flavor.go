@@ -0,0 +1,201 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// imageLayout describes an image distribution of an engine. Workloads are built
+// for the official image and adjusted to the layout of the flavor.
+type imageLayout struct {
+	// repository is the image, tagged with the version
+	repository string
+	// dataPath is where the data volume is mounted
+	dataPath string
+	// user is the non-root uid the image runs as, zero when it starts as root
+	user int64
+	// group is the gid the engine drops to when the image starts as root
+	group int64
+	// env renames the environment variables of the official image
+	env map[string]string
+	// extraEnv is added to the engine container
+	extraEnv []corev1.EnvVar
+	// flagsEnv receives the command line flags of the engine, for entrypoints
+	// that do not pass container arguments to it
+	flagsEnv string
+	// configPath is where the rendered configuration file is mounted, empty
+	// when the image reads no configuration file of the operator
+	configPath string
+}
+
+var imageLayouts = map[databasesv1alpha1.DatabaseType]map[databasesv1alpha1.ImageFlavor]imageLayout{
+	databasesv1alpha1.DatabaseTypePostgreSQL: {
+		// The entrypoint initializes the data directory as root before
+		// dropping to the postgres user
+		databasesv1alpha1.ImageFlavorOfficial: {
+			repository: "postgres",
+			dataPath:   "/var/lib/postgresql/data",
+			group:      999,
+		},
+		databasesv1alpha1.ImageFlavorBitnami: {
+			repository: "bitnami/postgresql",
+			dataPath:   "/bitnami/postgresql",
+			user:       1001,
+			env: map[string]string{
+				"POSTGRES_DB":       "POSTGRESQL_DATABASE",
+				"POSTGRES_USER":     "POSTGRESQL_USERNAME",
+				"POSTGRES_PASSWORD": "POSTGRESQL_PASSWORD",
+			},
+			flagsEnv: "POSTGRESQL_EXTRA_FLAGS",
+		},
+		databasesv1alpha1.ImageFlavorPercona: {
+			repository: "percona/percona-distribution-postgresql",
+			dataPath:   "/data/db",
+			user:       26,
+		},
+	},
+	databasesv1alpha1.DatabaseTypeMongoDB: {
+		databasesv1alpha1.ImageFlavorOfficial: {
+			repository: "mongo",
+			dataPath:   "/data/db",
+			user:       999,
+			configPath: "/etc/mongo",
+		},
+		databasesv1alpha1.ImageFlavorBitnami: {
+			repository: "bitnami/mongodb",
+			dataPath:   "/bitnami/mongodb",
+			user:       1001,
+			env: map[string]string{
+				"MONGO_INITDB_ROOT_USERNAME": "MONGODB_ROOT_USER",
+				"MONGO_INITDB_ROOT_PASSWORD": "MONGODB_ROOT_PASSWORD",
+			},
+			flagsEnv: "MONGODB_EXTRA_FLAGS",
+		},
+		databasesv1alpha1.ImageFlavorPercona: {
+			repository: "percona/percona-server-mongodb",
+			dataPath:   "/data/db",
+			user:       1001,
+			configPath: "/etc/mongo",
+		},
+	},
+	databasesv1alpha1.DatabaseTypeRedis: {
+		databasesv1alpha1.ImageFlavorOfficial: {
+			repository: "redis",
+			dataPath:   "/data",
+			user:       999,
+			configPath: "/usr/local/etc/redis",
+		},
+		// The image reads REDIS_PASSWORD, and refuses to start without a
+		// password unless told otherwise
+		databasesv1alpha1.ImageFlavorBitnami: {
+			repository: "bitnami/redis",
+			dataPath:   "/bitnami/redis/data",
+			user:       1001,
+			extraEnv:   []corev1.EnvVar{{Name: "ALLOW_EMPTY_PASSWORD", Value: "yes"}},
+			flagsEnv:   "REDIS_EXTRA_FLAGS",
+		},
+	},
+	databasesv1alpha1.DatabaseTypeElasticsearch: {
+		databasesv1alpha1.ImageFlavorOfficial: {
+			repository: "docker.elastic.co/elasticsearch/elasticsearch",
+			dataPath:   "/usr/share/elasticsearch/data",
+			user:       1000,
+		},
+	},
+	databasesv1alpha1.DatabaseTypeSQLite: {
+		databasesv1alpha1.ImageFlavorOfficial: {
+			repository: "nouchka/sqlite3",
+			dataPath:   "/data",
+		},
+	},
+}
+
+// imageFlavor returns the image distribution of a Database
+func imageFlavor(database *databasesv1alpha1.Database) databasesv1alpha1.ImageFlavor {
+	if database.Spec.Image == nil || database.Spec.Image.Flavor == "" {
+		return databasesv1alpha1.ImageFlavorOfficial
+	}
+	return database.Spec.Image.Flavor
+}
+
+// engineLayout returns the layout of the image of a Database, the official one
+// when validateSpec did not accept its flavor
+func engineLayout(database *databasesv1alpha1.Database) imageLayout {
+	layouts := imageLayouts[database.Spec.Type]
+	if layout, ok := layouts[imageFlavor(database)]; ok {
+		return layout
+	}
+	return layouts[databasesv1alpha1.ImageFlavorOfficial]
+}
+
+// validateImageFlavor checks that the engine has the flavor and that the
+// features of the spec work with its image
+func validateImageFlavor(database *databasesv1alpha1.Database) error {
+	flavor := imageFlavor(database)
+	layout, ok := imageLayouts[database.Spec.Type][flavor]
+	if !ok {
+		flavors := []string{}
+		for available := range imageLayouts[database.Spec.Type] {
+			flavors = append(flavors, string(available))
+		}
+		slices.Sort(flavors)
+		return fmt.Errorf("%s has no %s image flavor (available: %s)", database.Spec.Type, flavor, strings.Join(flavors, ", "))
+	}
+	if flavor == databasesv1alpha1.ImageFlavorOfficial {
+		return nil
+	}
+
+	// The WAL and pgBackRest sidecars share the data directory of the official image
+	if backup := database.Spec.Backup; backup != nil && backup.Enabled {
+		switch method := backupMethod(database); method {
+		case databasesv1alpha1.BackupMethodWAL, databasesv1alpha1.BackupMethodIncremental:
+			return fmt.Errorf("%s backups need the %s image flavor", method, databasesv1alpha1.ImageFlavorOfficial)
+		}
+	}
+	parameters := map[databasesv1alpha1.DatabaseType]map[string]string{
+		databasesv1alpha1.DatabaseTypeMongoDB: mongoDBParameters(database),
+		databasesv1alpha1.DatabaseTypeRedis:   redisParameters(database),
+	}[database.Spec.Type]
+	if len(parameters) > 0 && layout.configPath == "" {
+		return fmt.Errorf("the %s %s image does not read the configuration file of parameters, remove them",
+			flavor, database.Spec.Type)
+	}
+	return nil
+}
+
+// applyImageLayout adjusts the engine container, built for the official image,
+// to the layout of the image flavor
+func applyImageLayout(database *databasesv1alpha1.Database, container *corev1.Container) {
+	layout := engineLayout(database)
+	for i := range container.Env {
+		if name, ok := layout.env[container.Env[i].Name]; ok {
+			container.Env[i].Name = name
+		}
+	}
+	container.Env = append(container.Env, layout.extraEnv...)
+	if layout.flagsEnv != "" && len(container.Args) > 0 {
+		container.Env = append(container.Env, corev1.EnvVar{Name: layout.flagsEnv, Value: strings.Join(container.Args, " ")})
+		container.Args = nil
+	}
+}
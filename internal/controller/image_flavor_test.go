@@ -0,0 +1,131 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Image flavors", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Image:   &databasesv1alpha1.ImageSpec{Flavor: databasesv1alpha1.ImageFlavorBitnami},
+				Storage: &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+				PostgreSQL: &databasesv1alpha1.PostgreSQLConfig{
+					Parameters: map[string]string{"max_connections": "200", "work_mem": "8MB"},
+				},
+			},
+		}
+	})
+
+	It("should run Bitnami PostgreSQL with its data path, user, variables and flags", func() {
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+		template := reconciler.createPostgreSQLStatefulSet(database, 1, reconciler.getPostgreSQLEnv(database)).Spec.Template
+		container := template.Spec.Containers[0]
+		Expect(container.Image).To(Equal("bitnami/postgresql:16"))
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "data", MountPath: "/bitnami/postgresql"}))
+		Expect(container.Args).To(BeEmpty())
+		Expect(container.Env).To(ContainElements(
+			corev1.EnvVar{Name: "POSTGRESQL_DATABASE", Value: "postgres"},
+			corev1.EnvVar{Name: "POSTGRESQL_USERNAME", Value: "postgres"},
+			HaveField("Name", "POSTGRESQL_PASSWORD"),
+			corev1.EnvVar{Name: "POSTGRESQL_EXTRA_FLAGS", Value: "-c max_connections=200 -c work_mem=8MB"}))
+		Expect(container.Env).NotTo(ContainElement(HaveField("Name", "POSTGRES_PASSWORD")))
+		Expect(*template.Spec.SecurityContext.RunAsUser).To(Equal(int64(1001)))
+
+		// Jobs run the clients of the flavor with the variables of the operator
+		job := reconciler.createAdminJob(database, bootstrapComponent, "true", nil)
+		Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("bitnami/postgresql:16"))
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "PGUSER", Value: "postgres"}))
+	})
+
+	It("should pass Redis flags of the Bitnami image through its environment", func() {
+		database.Spec = databasesv1alpha1.DatabaseSpec{
+			Type:    databasesv1alpha1.DatabaseTypeRedis,
+			Version: "7.2",
+			Image:   &databasesv1alpha1.ImageSpec{Flavor: databasesv1alpha1.ImageFlavorBitnami},
+			Redis: &databasesv1alpha1.RedisConfig{
+				PasswordSecret: &databasesv1alpha1.SecretReference{Name: "sessions-password", Key: "password"},
+			},
+		}
+		container := reconciler.createRedisStatefulSet(database, 1, reconciler.getRedisEnv(database)).Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal("bitnami/redis:7.2"))
+		Expect(container.Args).To(BeEmpty())
+		Expect(container.Env).To(ContainElements(
+			HaveField("Name", "REDIS_PASSWORD"),
+			corev1.EnvVar{Name: "ALLOW_EMPTY_PASSWORD", Value: "yes"},
+			corev1.EnvVar{Name: "REDIS_EXTRA_FLAGS", Value: "--requirepass $(REDIS_PASSWORD)"}))
+	})
+
+	It("should mount the configuration of Percona MongoDB like the official image", func() {
+		database.Spec = databasesv1alpha1.DatabaseSpec{
+			Type:    databasesv1alpha1.DatabaseTypeMongoDB,
+			Version: "7.0",
+			Image:   &databasesv1alpha1.ImageSpec{Flavor: databasesv1alpha1.ImageFlavorPercona},
+			Storage: &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+			MongoDB: &databasesv1alpha1.MongoDBConfig{Parameters: map[string]string{"operationProfiling.mode": "slowOp"}},
+		}
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+		template := reconciler.createMongoDBStatefulSet(database, 1, reconciler.getMongoDBEnv(database)).Spec.Template
+		container := template.Spec.Containers[0]
+		Expect(container.Image).To(Equal("percona/percona-server-mongodb:7.0"))
+		Expect(container.VolumeMounts).To(ContainElements(
+			corev1.VolumeMount{Name: "data", MountPath: "/data/db"},
+			HaveField("MountPath", "/etc/mongo")))
+		Expect(container.Args).To(Equal([]string{"--config", "/etc/mongo/mongod.conf"}))
+		Expect(container.Env).To(ContainElement(HaveField("Name", "MONGO_INITDB_ROOT_PASSWORD")))
+		Expect(*template.Spec.SecurityContext.RunAsUser).To(Equal(int64(1001)))
+	})
+
+	It("should reject flavors and features the images do not support", func() {
+		database.Spec.Backup = &databasesv1alpha1.BackupSpec{Enabled: true, Method: databasesv1alpha1.BackupMethodWAL}
+		Expect(validateImageFlavor(database)).To(MatchError("WAL backups need the Official image flavor"))
+
+		database.Spec = databasesv1alpha1.DatabaseSpec{
+			Type:    databasesv1alpha1.DatabaseTypeRedis,
+			Version: "7.2",
+			Image:   &databasesv1alpha1.ImageSpec{Flavor: databasesv1alpha1.ImageFlavorPercona},
+		}
+		Expect(validateImageFlavor(database)).To(MatchError("Redis has no Percona image flavor (available: Bitnami, Official)"))
+
+		database.Spec.Image.Flavor = databasesv1alpha1.ImageFlavorBitnami
+		database.Spec.Redis = &databasesv1alpha1.RedisConfig{Parameters: map[string]string{"maxmemory": "1gb"}}
+		Expect(validateImageFlavor(database)).To(MatchError("the Bitnami Redis image does not read the configuration file of parameters, remove them"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeSQLite
+		database.Spec.Redis = nil
+		database.Spec.PodSecurity = databasesv1alpha1.PodSecurityRestricted
+		database.Spec.Image.Flavor = databasesv1alpha1.ImageFlavorOfficial
+		Expect(reconciler.validateSpec(database)).To(MatchError("the Official SQLite image starts as root and requires the Baseline podSecurity level"))
+	})
+})
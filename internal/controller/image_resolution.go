@@ -0,0 +1,343 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	conditionImageResolved = "ImageResolved"
+
+	dockerHubRegistry = "registry-1.docker.io"
+
+	// tagsPageSize is the number of tags asked for per page of a tag listing
+	tagsPageSize = 1000
+	// maxTagsPages bounds the pages of a tag listing
+	maxTagsPages = 50
+)
+
+// Manifest media types accepted when resolving a tag, so multi-architecture
+// images resolve to their index rather than to one platform
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ImageResolver resolves an image reference to the digest of its manifest
+type ImageResolver interface {
+	Resolve(ctx context.Context, image string) (string, error)
+}
+
+// VersionCatalog lists the versions an engine image is published in
+type VersionCatalog interface {
+	Versions(ctx context.Context, repository string) ([]string, error)
+}
+
+// RegistryResolver resolves image digests, and lists the tags of images, with
+// the registry HTTP API, using anonymous bearer tokens when the registry asks
+// for them
+type RegistryResolver struct {
+	// Client sends the registry requests (default: http.DefaultClient)
+	Client *http.Client
+}
+
+// imageReference is an image split into its registry, repository and tag
+type imageReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+}
+
+// parseImageReference splits an image reference the way the container runtime
+// does: a first component with a dot, a port or named localhost is a registry,
+// and Docker Hub images without a namespace live in library/
+func parseImageReference(image string) (imageReference, error) {
+	name, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, tag = image[:i], image[i+1:]
+	}
+	if name == "" || tag == "" || strings.Contains(image, "@") {
+		return imageReference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+
+	ref := imageReference{Registry: dockerHubRegistry, Repository: name, Tag: tag}
+	if first, rest, found := strings.Cut(name, "/"); found &&
+		(strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, ref.Repository = first, rest
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	return ref, nil
+}
+
+// Resolve returns the digest the registry serves for the tag of the image
+func (r *RegistryResolver) Resolve(ctx context.Context, image string) (string, error) {
+	ref, err := parseImageReference(image)
+	if err != nil {
+		return "", err
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Registry, ref.Repository, ref.Tag)
+	resp, err := r.headManifest(ctx, client, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := r.token(ctx, client, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("failed to authenticate to %s: %w", ref.Registry, err)
+		}
+		if resp, err = r.headManifest(ctx, client, manifestURL, token); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s: registry returned %s", image, resp.Status)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", fmt.Errorf("failed to resolve %s: registry returned no digest", image)
+	}
+	return digest, nil
+}
+
+func (r *RegistryResolver) headManifest(ctx context.Context, client *http.Client, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+// Versions lists the tags of the repository of an image, following the
+// pagination of the registry
+func (r *RegistryResolver) Versions(ctx context.Context, repository string) ([]string, error) {
+	ref, err := parseImageReference(repository)
+	if err != nil {
+		return nil, err
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	tags := []string{}
+	token := ""
+	next := fmt.Sprintf("https://%s/v2/%s/tags/list?n=%d", ref.Registry, ref.Repository, tagsPageSize)
+	for page := 0; next != ""; page++ {
+		if page == maxTagsPages {
+			return nil, fmt.Errorf("%s has more than %d pages of tags", repository, maxTagsPages)
+		}
+		resp, err := r.getTags(ctx, client, next, token)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && token == "" {
+			_ = resp.Body.Close()
+			if token, err = r.token(ctx, client, resp.Header.Get("WWW-Authenticate")); err != nil {
+				return nil, fmt.Errorf("failed to authenticate to %s: %w", ref.Registry, err)
+			}
+			if resp, err = r.getTags(ctx, client, next, token); err != nil {
+				return nil, err
+			}
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("failed to list the tags of %s: registry returned %s", repository, resp.Status)
+		}
+
+		var body struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, body.Tags...)
+
+		if next, err = nextPage(next, resp.Header.Get("Link")); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+func (r *RegistryResolver) getTags(ctx context.Context, client *http.Client, tagsURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tagsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return client.Do(req)
+}
+
+// nextPage returns the URL of the next page a Link header points to, relative
+// to the current page, or nothing on the last page
+func nextPage(current, link string) (string, error) {
+	target, params, found := strings.Cut(strings.TrimPrefix(strings.TrimSpace(link), "<"), ">")
+	if !found || !strings.Contains(params, `rel="next"`) {
+		return "", nil
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid Link header %q: %w", link, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// token requests an anonymous pull token from the realm of a Bearer challenge
+func (r *RegistryResolver) token(ctx context.Context, client *http.Client, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+
+	query := url.Values{}
+	var realm string
+	for _, param := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		value = strings.Trim(value, `"`)
+		switch key {
+		case "realm":
+			realm = value
+		case "service", "scope":
+			query.Set(key, value)
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("authentication challenge %q has no realm", challenge)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// reconcileImageResolution resolves the version tag to a digest when the
+// Database pins its image, and only again once the version or its image
+// changes. The digest a DatabaseVersion pins its image to is taken as it is.
+func (r *DatabaseReconciler) reconcileImageResolution(ctx context.Context, database *databasesv1alpha1.Database) error {
+	if database.Spec.ImageResolution != databasesv1alpha1.ImageResolutionDigest {
+		database.Status.Image = nil
+		meta.RemoveStatusCondition(&database.Status.Conditions, conditionImageResolved)
+		return nil
+	}
+	tag := engineImageTag(database)
+	pinned := catalogDigest(database)
+	if status := database.Status.Image; status != nil && status.Version == runningVersion(database) &&
+		status.Tag == tag && (pinned == "" || status.Digest == pinned) {
+		return nil
+	}
+
+	digest := pinned
+	var err error
+	if digest == "" {
+		resolver := r.ImageResolver
+		if resolver == nil {
+			resolver = &RegistryResolver{Client: r.HTTPClient}
+		}
+		digest, err = resolver.Resolve(ctx, tag)
+	}
+	if err != nil {
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+			Type:               conditionImageResolved,
+			Status:             metav1.ConditionFalse,
+			Reason:             "ResolutionFailed",
+			Message:            err.Error(),
+			ObservedGeneration: database.Generation,
+		})
+		return err
+	}
+
+	now := metav1.Now()
+	message := fmt.Sprintf("%s pinned to %s", tag, digest)
+	database.Status.Image = &databasesv1alpha1.ImageStatus{
+		Version:    runningVersion(database),
+		Tag:        tag,
+		Digest:     digest,
+		ResolvedAt: &now,
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               conditionImageResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "DigestPinned",
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+	recordOperation(database, recordImageResolution, databasesv1alpha1.OperationSucceeded, message, now.Time)
+	log.FromContext(ctx).Info("Resolved image digest", "image", tag, "digest", digest)
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// staticResolver resolves every image to the same digest and counts the calls
+type staticResolver struct {
+	digest string
+	images []string
+}
+
+func (r *staticResolver) Resolve(_ context.Context, image string) (string, error) {
+	r.images = append(r.images, image)
+	return r.digest, nil
+}
+
+var _ = Describe("Image resolution", func() {
+	It("should split image references like the container runtime", func() {
+		for image, expected := range map[string]imageReference{
+			"postgres:16":       {Registry: "registry-1.docker.io", Repository: "library/postgres", Tag: "16"},
+			"nouchka/sqlite3":   {Registry: "registry-1.docker.io", Repository: "nouchka/sqlite3", Tag: "latest"},
+			"localhost:5000/pg": {Registry: "localhost:5000", Repository: "pg", Tag: "latest"},
+			"docker.elastic.co/elasticsearch/elasticsearch:8.11.0": {
+				Registry: "docker.elastic.co", Repository: "elasticsearch/elasticsearch", Tag: "8.11.0",
+			},
+		} {
+			Expect(parseImageReference(image)).To(Equal(expected), image)
+		}
+		Expect(parseImageReference("postgres@sha256:abc")).Error().To(HaveOccurred())
+	})
+
+	It("should resolve the digest from the registry with an anonymous token", func() {
+		var server *httptest.Server
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/token":
+				if req.URL.Query().Get("scope") != "repository:library/postgres:pull" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				_, _ = w.Write([]byte(`{"token": "anonymous"}`))
+			case "/v2/library/postgres/manifests/16":
+				if req.Header.Get("Authorization") != "Bearer anonymous" {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry",scope="repository:library/postgres:pull"`)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if !strings.Contains(req.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+					w.WriteHeader(http.StatusNotAcceptable)
+					return
+				}
+				w.Header().Set("Docker-Content-Digest", "sha256:0123")
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		resolver := &RegistryResolver{Client: server.Client()}
+		registry := strings.TrimPrefix(server.URL, "https://")
+		Expect(resolver.Resolve(context.Background(), registry+"/library/postgres:16")).To(Equal("sha256:0123"))
+		Expect(resolver.Resolve(context.Background(), registry+"/library/postgres:17")).Error().
+			To(MatchError(ContainSubstring("404")))
+	})
+
+	It("should list the tags of an image across pages", func() {
+		var server *httptest.Server
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.URL.Path == "/token":
+				_, _ = w.Write([]byte(`{"access_token": "anonymous"}`))
+			case req.Header.Get("Authorization") != "Bearer anonymous":
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+			case req.URL.Path != "/v2/library/postgres/tags/list":
+				w.WriteHeader(http.StatusNotFound)
+			case req.URL.Query().Get("last") == "":
+				w.Header().Set("Link", `</v2/library/postgres/tags/list?last=16.2&n=1000>; rel="next"`)
+				_, _ = w.Write([]byte(`{"name": "library/postgres", "tags": ["16.1", "16.2"]}`))
+			default:
+				_, _ = w.Write([]byte(`{"name": "library/postgres", "tags": ["16.3"]}`))
+			}
+		}))
+		defer server.Close()
+
+		resolver := &RegistryResolver{Client: server.Client()}
+		registry := strings.TrimPrefix(server.URL, "https://")
+		Expect(resolver.Versions(context.Background(), registry+"/library/postgres")).
+			To(Equal([]string{"16.1", "16.2", "16.3"}))
+		Expect(resolver.Versions(context.Background(), registry+"/library/mysql")).Error().
+			To(MatchError(ContainSubstring("404")))
+	})
+
+	It("should pin the image and resolve it again only when the version changes", func() {
+		resolver := &staticResolver{digest: "sha256:0123"}
+		reconciler := &DatabaseReconciler{ImageResolver: resolver}
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:            databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:         "16",
+				ImageResolution: databasesv1alpha1.ImageResolutionDigest,
+			},
+		}
+		ctx := context.Background()
+
+		Expect(engineImage(database)).To(Equal("postgres:16"))
+		Expect(reconciler.reconcileImageResolution(ctx, database)).To(Succeed())
+		Expect(database.Status.Image.Digest).To(Equal("sha256:0123"))
+		Expect(engineImage(database)).To(Equal("postgres:16@sha256:0123"))
+		Expect(reconciler.createPostgreSQLStatefulSet(database, 1, nil).Spec.Template.Spec.Containers[0].Image).
+			To(Equal("postgres:16@sha256:0123"))
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, conditionImageResolved)).To(BeTrue())
+
+		By("keeping the pinned digest while the version is unchanged")
+		resolver.digest = "sha256:4567"
+		Expect(reconciler.reconcileImageResolution(ctx, database)).To(Succeed())
+		Expect(engineImage(database)).To(Equal("postgres:16@sha256:0123"))
+
+		By("resolving the new tag after a version change")
+		database.Spec.Version = "17"
+		Expect(engineImage(database)).To(Equal("postgres:17"))
+		Expect(reconciler.reconcileImageResolution(ctx, database)).To(Succeed())
+		Expect(engineImage(database)).To(Equal("postgres:17@sha256:4567"))
+		Expect(resolver.images).To(Equal([]string{"postgres:16", "postgres:17"}))
+
+		By("dropping the pin with tag resolution")
+		database.Spec.ImageResolution = databasesv1alpha1.ImageResolutionTag
+		Expect(reconciler.reconcileImageResolution(ctx, database)).To(Succeed())
+		Expect(database.Status.Image).To(BeNil())
+		Expect(engineImage(database)).To(Equal("postgres:17"))
+	})
+})
@@ -0,0 +1,208 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	ingressSuffix   = "-ingress"
+	httpRouteSuffix = "-route"
+)
+
+var httpRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+// reconcileIngress routes the host of networking.ingress to the database
+// Service, with an Ingress or an HTTPRoute, and deletes the one not in use
+func (r *DatabaseReconciler) reconcileIngress(ctx context.Context, database *databasesv1alpha1.Database) error {
+	var spec *databasesv1alpha1.IngressSpec
+	if database.Spec.Networking != nil {
+		spec = database.Spec.Networking.Ingress
+	}
+	var desired *networkingv1.Ingress
+	if spec != nil && spec.Gateway == nil {
+		desired = r.createIngress(database, spec)
+	}
+	if err := r.reconcileIngressObject(ctx, database, desired); err != nil {
+		return err
+	}
+	var route *unstructured.Unstructured
+	if spec != nil && spec.Gateway != nil {
+		route = r.createHTTPRoute(database, spec)
+	}
+	return r.reconcileHTTPRoute(ctx, database, route)
+}
+
+// reconcileIngressObject creates, updates or deletes the Ingress
+func (r *DatabaseReconciler) reconcileIngressObject(ctx context.Context, database *databasesv1alpha1.Database, desired *networkingv1.Ingress) error {
+	name := database.Name + ingressSuffix
+	ingress := &networkingv1.Ingress{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, ingress)
+	switch {
+	case err != nil && !errors.IsNotFound(err):
+		return err
+	case desired == nil:
+		if err == nil {
+			log.FromContext(ctx).Info("Deleting Ingress", "name", name)
+			if err := r.Delete(ctx, ingress); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	case errors.IsNotFound(err):
+		log.FromContext(ctx).Info("Creating Ingress", "name", name, "host", desired.Spec.Rules[0].Host)
+	case equality.Semantic.DeepEqual(desired.Spec, ingress.Spec):
+		return nil
+	}
+
+	if err := controllerutil.SetControllerReference(database, desired, r.Scheme); err != nil {
+		return err
+	}
+	return r.apply(ctx, desired)
+}
+
+// createIngress builds the Ingress of the host, terminating TLS when a
+// certificate is given
+func (r *DatabaseReconciler) createIngress(database *databasesv1alpha1.Database, spec *databasesv1alpha1.IngressSpec) *networkingv1.Ingress {
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      database.Name + ingressSuffix,
+			Namespace: database.Namespace,
+			Labels:    r.getLabels(database),
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: spec.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     "/",
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: database.Name + "-service",
+							Port: networkingv1.ServiceBackendPort{Number: r.getServicePorts(database)[0].Port},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+	if spec.ClassName != "" {
+		ingress.Spec.IngressClassName = ptr.To(spec.ClassName)
+	}
+	if spec.TLSSecretName != "" {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{spec.Host}, SecretName: spec.TLSSecretName}}
+	}
+	return ingress
+}
+
+// createHTTPRoute builds the HTTPRoute of the host, attached to the gateway.
+// Defaulted fields are set, so the stored route compares equal.
+func (r *DatabaseReconciler) createHTTPRoute(database *databasesv1alpha1.Database, spec *databasesv1alpha1.IngressSpec) *unstructured.Unstructured {
+	gateway := spec.Gateway
+	parent := map[string]any{
+		"group":     httpRouteGVK.Group,
+		"kind":      "Gateway",
+		"name":      gateway.Name,
+		"namespace": database.Namespace,
+	}
+	if gateway.Namespace != "" {
+		parent["namespace"] = gateway.Namespace
+	}
+	if gateway.SectionName != "" {
+		parent["sectionName"] = gateway.SectionName
+	}
+
+	route := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{
+			"parentRefs": []any{parent},
+			"hostnames":  []any{spec.Host},
+			"rules": []any{map[string]any{
+				"matches": []any{map[string]any{
+					"path": map[string]any{"type": "PathPrefix", "value": "/"},
+				}},
+				"backendRefs": []any{map[string]any{
+					"group":  "",
+					"kind":   "Service",
+					"name":   database.Name + "-service",
+					"port":   int64(r.getServicePorts(database)[0].Port),
+					"weight": int64(1),
+				}},
+			}},
+		},
+	}}
+	route.SetGroupVersionKind(httpRouteGVK)
+	route.SetName(database.Name + httpRouteSuffix)
+	route.SetNamespace(database.Namespace)
+	route.SetLabels(r.getLabels(database))
+	return route
+}
+
+// reconcileHTTPRoute creates, updates or deletes the HTTPRoute. Without the
+// Gateway API CRDs there is no route to delete.
+func (r *DatabaseReconciler) reconcileHTTPRoute(ctx context.Context, database *databasesv1alpha1.Database, desired *unstructured.Unstructured) error {
+	name := database.Name + httpRouteSuffix
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, route)
+	switch {
+	case meta.IsNoMatchError(err) && desired == nil:
+		return nil
+	case meta.IsNoMatchError(err):
+		return fmt.Errorf("networking.ingress.gateway requires the Gateway API CRDs to be installed: %w", err)
+	case err != nil && !errors.IsNotFound(err):
+		return err
+	case desired == nil:
+		if err == nil {
+			log.FromContext(ctx).Info("Deleting HTTPRoute", "name", name)
+			if err := r.Delete(ctx, route); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	case errors.IsNotFound(err):
+		log.FromContext(ctx).Info("Creating HTTPRoute", "name", name, "gateway", database.Spec.Networking.Ingress.Gateway.Name)
+	case equality.Semantic.DeepEqual(route.Object["spec"], desired.Object["spec"]):
+		return nil
+	default:
+		log.FromContext(ctx).Info("Updating HTTPRoute", "name", name)
+	}
+
+	if err := controllerutil.SetControllerReference(database, desired, r.Scheme); err != nil {
+		return err
+	}
+	return r.apply(ctx, desired)
+}
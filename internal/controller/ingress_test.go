@@ -0,0 +1,110 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Ingress", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	ingressKey := types.NamespacedName{Name: "search-ingress", Namespace: "shop"}
+	routeKey := types.NamespacedName{Name: "search-route", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build(), Scheme: scheme}
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "search", Namespace: "shop", UID: "uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypeElasticsearch,
+				Version: "8.11.0",
+				Networking: &databasesv1alpha1.NetworkingSpec{
+					Ingress: &databasesv1alpha1.IngressSpec{Host: "search.example.com", ClassName: "nginx", TLSSecretName: "search-example-com"},
+				},
+			},
+		}
+	})
+
+	It("should route the host to the Service with an Ingress", func() {
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+		Expect(reconciler.reconcileIngress(ctx, database)).To(Succeed())
+
+		ingress := &networkingv1.Ingress{}
+		Expect(reconciler.Get(ctx, ingressKey, ingress)).To(Succeed())
+		Expect(*ingress.Spec.IngressClassName).To(Equal("nginx"))
+		Expect(ingress.Spec.TLS).To(Equal([]networkingv1.IngressTLS{{Hosts: []string{"search.example.com"}, SecretName: "search-example-com"}}))
+		rule := ingress.Spec.Rules[0]
+		Expect(rule.Host).To(Equal("search.example.com"))
+		Expect(rule.HTTP.Paths[0].Backend.Service.Name).To(Equal("search-service"))
+		Expect(rule.HTTP.Paths[0].Backend.Service.Port.Number).To(Equal(int32(9200)))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		Expect(reconciler.validateSpec(database)).To(MatchError("Redis does not speak HTTP, networking.ingress requires Elasticsearch or SQLite"))
+	})
+
+	It("should replace the Ingress with an HTTPRoute attached to the gateway", func() {
+		Expect(reconciler.reconcileIngress(ctx, database)).To(Succeed())
+
+		database.Spec.Networking.Ingress = &databasesv1alpha1.IngressSpec{
+			Host:    "search.example.com",
+			Gateway: &databasesv1alpha1.GatewayReference{Name: "public", Namespace: "gateways", SectionName: "https"},
+		}
+		Expect(reconciler.reconcileIngress(ctx, database)).To(Succeed())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, ingressKey, &networkingv1.Ingress{}))).To(BeTrue())
+
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(httpRouteGVK)
+		Expect(reconciler.Get(ctx, routeKey, route)).To(Succeed())
+		parents, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+		Expect(parents).To(ConsistOf(HaveKeyWithValue("namespace", "gateways")))
+		Expect(parents[0]).To(HaveKeyWithValue("sectionName", "https"))
+		hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+		Expect(hostnames).To(Equal([]string{"search.example.com"}))
+		rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+		backend := rules[0].(map[string]any)["backendRefs"].([]any)[0]
+		Expect(backend).To(HaveKeyWithValue("name", "search-service"))
+		Expect(backend).To(HaveKeyWithValue("port", int64(9200)))
+
+		database.Spec.Networking = nil
+		Expect(reconciler.reconcileIngress(ctx, database)).To(Succeed())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, routeKey, route))).To(BeTrue())
+	})
+})
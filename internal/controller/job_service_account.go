@@ -0,0 +1,73 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+
+const jobsServiceAccountSuffix = "-jobs"
+
+// jobsServiceAccountName returns the ServiceAccount the Jobs of a Database run as
+func jobsServiceAccountName(database *databasesv1alpha1.Database) string {
+	return database.Name + jobsServiceAccountSuffix
+}
+
+// reconcileJobsServiceAccount creates the ServiceAccount of the operator Jobs.
+// The Jobs reach the database, S3 and KMS with their own credentials and never
+// call the Kubernetes API, so the ServiceAccount is bound to no Role and its
+// token is not mounted. It keeps Jobs off the default ServiceAccount, which other
+// workloads of the namespace may have been granted access through, and is
+// garbage-collected with the Database.
+func (r *DatabaseReconciler) reconcileJobsServiceAccount(ctx context.Context, database *databasesv1alpha1.Database) error {
+	serviceAccount := &corev1.ServiceAccount{}
+	err := r.Get(ctx, types.NamespacedName{Name: jobsServiceAccountName(database), Namespace: database.Namespace}, serviceAccount)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if errors.IsNotFound(err) {
+		log.FromContext(ctx).Info("Creating Job ServiceAccount", "name", jobsServiceAccountName(database))
+	} else if serviceAccount.AutomountServiceAccountToken != nil && !*serviceAccount.AutomountServiceAccountToken {
+		return nil
+	}
+
+	serviceAccount = &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobsServiceAccountName(database),
+			Namespace: database.Namespace,
+			Labels:    r.getComponentLabels(database, "jobs"),
+		},
+		AutomountServiceAccountToken: ptr.To(false),
+	}
+	if err := controllerutil.SetControllerReference(database, serviceAccount, r.Scheme); err != nil {
+		return err
+	}
+	return r.apply(ctx, serviceAccount)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Job ServiceAccount", func() {
+	It("should run Jobs as a dedicated ServiceAccount without API credentials", func() {
+		ctx := context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler := &DatabaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build(), Scheme: scheme}
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "uid"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+		}
+
+		Expect(reconciler.reconcileJobsServiceAccount(ctx, database)).To(Succeed())
+		serviceAccount := &corev1.ServiceAccount{}
+		key := types.NamespacedName{Name: "orders-jobs", Namespace: "shop"}
+		Expect(reconciler.Get(ctx, key, serviceAccount)).To(Succeed())
+		Expect(serviceAccount.AutomountServiceAccountToken).To(Equal(ptr.To(false)))
+		Expect(serviceAccount.OwnerReferences).To(ConsistOf(HaveField("UID", database.UID)))
+
+		job := reconciler.createAdminJob(database, bootstrapComponent, "true", nil)
+		Expect(job.Spec.Template.Spec.ServiceAccountName).To(Equal("orders-jobs"))
+		Expect(job.Spec.Template.Spec.AutomountServiceAccountToken).To(Equal(ptr.To(false)))
+
+		serviceAccount.AutomountServiceAccountToken = nil
+		Expect(reconciler.Update(ctx, serviceAccount)).To(Succeed())
+		Expect(reconciler.reconcileJobsServiceAccount(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, key, serviceAccount)).To(Succeed())
+		Expect(serviceAccount.AutomountServiceAccountToken).To(Equal(ptr.To(false)))
+	})
+})
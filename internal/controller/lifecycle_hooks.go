@@ -0,0 +1,43 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// applyLifecycleHooks renders the lifecycle hooks of the spec on the engine
+// container, the first one of the template, and the termination grace period
+// the pre-stop hook runs within
+func applyLifecycleHooks(database *databasesv1alpha1.Database, template *corev1.PodTemplateSpec) {
+	lifecycle := database.Spec.Lifecycle
+	if lifecycle == nil {
+		return
+	}
+	if lifecycle.PreStopHook != nil || lifecycle.PostStartHook != nil {
+		template.Spec.Containers[0].Lifecycle = &corev1.Lifecycle{
+			PreStop:   lifecycle.PreStopHook.DeepCopy(),
+			PostStart: lifecycle.PostStartHook.DeepCopy(),
+		}
+	}
+	if lifecycle.TerminationGracePeriodSeconds != nil {
+		seconds := *lifecycle.TerminationGracePeriodSeconds
+		template.Spec.TerminationGracePeriodSeconds = &seconds
+	}
+}
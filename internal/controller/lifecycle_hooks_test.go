@@ -0,0 +1,71 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Lifecycle hooks", func() {
+	reconciler := &DatabaseReconciler{}
+	preStop := &corev1.LifecycleHandler{
+		Exec: &corev1.ExecAction{Command: []string{"psql", "-U", "postgres", "-c", "CHECKPOINT"}},
+	}
+
+	database := func(dbType databasesv1alpha1.DatabaseType) *databasesv1alpha1.Database {
+		return &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    dbType,
+				Version: "7.2",
+				Lifecycle: &databasesv1alpha1.LifecycleSpec{
+					PreStopHook:                   preStop,
+					TerminationGracePeriodSeconds: ptr.To(int64(120)),
+				},
+			},
+		}
+	}
+
+	It("should render the hooks on the engine container of every workload", func() {
+		templates := []corev1.PodTemplateSpec{
+			reconciler.createPostgreSQLStatefulSet(database(databasesv1alpha1.DatabaseTypePostgreSQL), 1, nil).Spec.Template,
+			reconciler.createMongoDBStatefulSet(database(databasesv1alpha1.DatabaseTypeMongoDB), 1, nil).Spec.Template,
+			reconciler.createRedisStatefulSet(database(databasesv1alpha1.DatabaseTypeRedis), 1, nil).Spec.Template,
+			reconciler.createElasticsearchStatefulSet(database(databasesv1alpha1.DatabaseTypeElasticsearch), 1, nil).Spec.Template,
+			reconciler.createSQLiteDeployment(database(databasesv1alpha1.DatabaseTypeSQLite), 1, nil).Spec.Template,
+		}
+		for _, template := range templates {
+			Expect(template.Spec.Containers[0].Lifecycle).To(Equal(&corev1.Lifecycle{PreStop: preStop}))
+			Expect(template.Spec.TerminationGracePeriodSeconds).To(Equal(ptr.To(int64(120))))
+		}
+	})
+
+	It("should leave the container lifecycle alone without hooks", func() {
+		db := database(databasesv1alpha1.DatabaseTypeRedis)
+		db.Spec.Lifecycle = nil
+		template := reconciler.createRedisStatefulSet(db, 1, nil).Spec.Template
+		Expect(template.Spec.Containers[0].Lifecycle).To(BeNil())
+		Expect(template.Spec.TerminationGracePeriodSeconds).To(BeNil())
+	})
+})
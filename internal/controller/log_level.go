@@ -0,0 +1,247 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	logLevelComponent = "log-level"
+	// logLevelAnnotation records on the Job which level it applies
+	logLevelAnnotation = "databases.database-operator.io/log-level"
+)
+
+// Engine specific values for each log level
+var engineLogSettings = map[databasesv1alpha1.DatabaseType]map[databasesv1alpha1.EngineLogLevel]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: {
+		databasesv1alpha1.EngineLogLevelDebug:   "debug1",
+		databasesv1alpha1.EngineLogLevelInfo:    "info",
+		databasesv1alpha1.EngineLogLevelWarning: "warning",
+		databasesv1alpha1.EngineLogLevelError:   "error",
+	},
+	databasesv1alpha1.DatabaseTypeMongoDB: {
+		databasesv1alpha1.EngineLogLevelDebug:   "{logLevel: 2, quiet: false}",
+		databasesv1alpha1.EngineLogLevelInfo:    "{logLevel: 0, quiet: false}",
+		databasesv1alpha1.EngineLogLevelWarning: "{logLevel: 0, quiet: true}",
+		databasesv1alpha1.EngineLogLevelError:   "{logLevel: 0, quiet: true}",
+	},
+	databasesv1alpha1.DatabaseTypeRedis: {
+		databasesv1alpha1.EngineLogLevelDebug:   "debug",
+		databasesv1alpha1.EngineLogLevelInfo:    "notice",
+		databasesv1alpha1.EngineLogLevelWarning: "warning",
+		databasesv1alpha1.EngineLogLevelError:   "warning",
+	},
+	databasesv1alpha1.DatabaseTypeElasticsearch: {
+		databasesv1alpha1.EngineLogLevelDebug:   "DEBUG",
+		databasesv1alpha1.EngineLogLevelInfo:    "INFO",
+		databasesv1alpha1.EngineLogLevelWarning: "WARN",
+		databasesv1alpha1.EngineLogLevelError:   "ERROR",
+	},
+}
+
+// Admin commands applying $LOG_SETTING at runtime. The settings of PostgreSQL,
+// MongoDB and Redis are local to a server, so every replica resolved from
+// $PEERS_HOST is set; logger._root is a cluster setting of Elasticsearch.
+var engineLogLevelScripts = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: `set -e
+for host in $(getent hosts "$PEERS_HOST" | cut -d' ' -f1); do
+  psql -h "$host" -v ON_ERROR_STOP=1 -c "ALTER SYSTEM SET log_min_messages = '$LOG_SETTING'" -c "SELECT pg_reload_conf()"
+done`,
+	databasesv1alpha1.DatabaseTypeMongoDB: `set -e
+for host in $(getent hosts "$PEERS_HOST" | cut -d' ' -f1); do
+  mongosh --host "$host" -u "$MONGO_USERNAME" -p "$MONGO_PASSWORD" --authenticationDatabase admin --quiet \
+    --eval "if (!db.adminCommand(Object.assign({setParameter: 1}, $LOG_SETTING)).ok) quit(1)"
+done`,
+	databasesv1alpha1.DatabaseTypeRedis: `set -e
+for host in $(getent hosts "$PEERS_HOST" | cut -d' ' -f1); do
+  test "$(redis-cli -h "$host" CONFIG SET loglevel "$LOG_SETTING")" = "OK"
+done`,
+	databasesv1alpha1.DatabaseTypeElasticsearch: `curl -sf -X PUT "http://$DB_HOST:9200/_cluster/settings" ` +
+		`-H 'Content-Type: application/json' -d "{\"persistent\":{\"logger._root\":\"$LOG_SETTING\"}}" > /dev/null`,
+}
+
+// reconcileEngineLogLevel applies the desired engine log level to every replica
+// through an admin Job
+func (r *DatabaseReconciler) reconcileEngineLogLevel(ctx context.Context, database *databasesv1alpha1.Database) error {
+	log := log.FromContext(ctx)
+
+	level, err := desiredEngineLogLevel(database, time.Now())
+	if err != nil || level == "" {
+		return err
+	}
+
+	job := &batchv1.Job{}
+	jobName := database.Name + "-" + logLevelComponent
+	err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: database.Namespace}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if err == nil {
+		finished, succeeded := jobFinished(job)
+		if job.Annotations[logLevelAnnotation] == string(level) && !finished {
+			return nil
+		}
+
+		// Remove finished Jobs and Jobs applying an outdated level; the Job
+		// deletion event triggers the next step
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+
+		if job.Annotations[logLevelAnnotation] == string(level) {
+			if !succeeded {
+				return fmt.Errorf("failed to apply engine log level %s, see the logs of Job %s", level, jobName)
+			}
+			log.Info("Applied engine log level", "level", level)
+			database.Status.Logging.EngineLevel = level
+		}
+		return nil
+	}
+
+	if database.Status.Logging.EngineLevel == level {
+		return nil
+	}
+
+	env := []corev1.EnvVar{
+		{
+			Name:  "LOG_SETTING",
+			Value: engineLogSettings[database.Spec.Type][level],
+		},
+	}
+	if database.Spec.Type != databasesv1alpha1.DatabaseTypeElasticsearch {
+		env = append(env, corev1.EnvVar{Name: "PEERS_HOST", Value: peersHost(database)})
+	}
+	job = r.createAdminJob(database, logLevelComponent, engineLogLevelScripts[database.Spec.Type], env)
+	job.Annotations = map[string]string{logLevelAnnotation: string(level)}
+
+	if err := controllerutil.SetControllerReference(database, job, r.Scheme); err != nil {
+		return err
+	}
+
+	log.Info("Changing engine log level", "level", level)
+	return r.Create(ctx, job)
+}
+
+// engineLogLevelParameters writes the level of the spec to the configuration file
+// of MongoDB and Redis, whose runtime settings are lost when a server restarts.
+// PostgreSQL keeps ALTER SYSTEM settings in its data directory and Elasticsearch
+// its persistent cluster settings. A debug window is only applied at runtime.
+func engineLogLevelParameters(database *databasesv1alpha1.Database) map[string]string {
+	logging := loggingSpec(database)
+	if logging == nil || logging.EngineLevel == "" {
+		return nil
+	}
+	level := logging.EngineLevel
+
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		verbosity := "0"
+		if level == databasesv1alpha1.EngineLogLevelDebug {
+			verbosity = "2"
+		}
+		quiet := level == databasesv1alpha1.EngineLogLevelWarning || level == databasesv1alpha1.EngineLogLevelError
+		return map[string]string{
+			"systemLog.verbosity": verbosity,
+			"systemLog.quiet":     strconv.FormatBool(quiet),
+		}
+	case databasesv1alpha1.DatabaseTypeRedis:
+		return map[string]string{"loglevel": engineLogSettings[database.Spec.Type][level]}
+	}
+	return nil
+}
+
+// desiredEngineLogLevel returns the level the engine should run with, starting or
+// ending the debug window requested through the debug annotation. An empty level
+// means the operator does not manage the engine log level.
+func desiredEngineLogLevel(database *databasesv1alpha1.Database, now time.Time) (databasesv1alpha1.EngineLogLevel, error) {
+	var level databasesv1alpha1.EngineLogLevel
+	if obs := database.Spec.Observability; obs != nil && obs.Logging != nil {
+		level = obs.Logging.EngineLevel
+	}
+
+	logging := database.Status.Logging
+	request, requested := database.Annotations[databasesv1alpha1.DebugAnnotation]
+	switch {
+	case !requested:
+		if logging != nil {
+			logging.DebugRequest = ""
+			logging.DebugUntil = nil
+		}
+	case logging == nil || logging.DebugRequest != request:
+		duration, err := time.ParseDuration(request)
+		if err != nil || duration <= 0 {
+			return "", fmt.Errorf("invalid %s annotation %q: expected a positive duration such as 30m",
+				databasesv1alpha1.DebugAnnotation, request)
+		}
+		if logging == nil {
+			logging = &databasesv1alpha1.LoggingStatus{}
+			database.Status.Logging = logging
+		}
+		until := metav1.NewTime(now.Add(duration))
+		logging.DebugRequest = request
+		logging.DebugUntil = &until
+	}
+
+	if logging != nil && logging.DebugUntil != nil && now.Before(logging.DebugUntil.Time) {
+		level = databasesv1alpha1.EngineLogLevelDebug
+	}
+
+	// Once the operator has changed the level, reverting means going back to the engine default
+	if level == "" && logging != nil && logging.EngineLevel != "" {
+		level = databasesv1alpha1.EngineLogLevelInfo
+	}
+
+	if level != "" && database.Status.Logging == nil {
+		database.Status.Logging = &databasesv1alpha1.LoggingStatus{}
+	}
+
+	return level, nil
+}
+
+// engineLogLevelRequested reports whether the user asked for engine log level management
+func engineLogLevelRequested(database *databasesv1alpha1.Database) bool {
+	if _, ok := database.Annotations[databasesv1alpha1.DebugAnnotation]; ok {
+		return true
+	}
+	obs := database.Spec.Observability
+	return obs != nil && obs.Logging != nil && obs.Logging.EngineLevel != ""
+}
+
+// debugWindowRemaining returns the time left in the current debug window
+func debugWindowRemaining(database *databasesv1alpha1.Database, now time.Time) time.Duration {
+	logging := database.Status.Logging
+	if logging == nil || logging.DebugUntil == nil {
+		return 0
+	}
+	return logging.DebugUntil.Sub(now)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Engine log level", func() {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newDatabase := func(level databasesv1alpha1.EngineLogLevel) *databasesv1alpha1.Database {
+		return &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type: databasesv1alpha1.DatabaseTypePostgreSQL,
+				Observability: &databasesv1alpha1.ObservabilitySpec{
+					Logging: &databasesv1alpha1.LoggingSpec{EngineLevel: level},
+				},
+			},
+		}
+	}
+
+	It("should leave the engine alone when no level is configured", func() {
+		database := newDatabase("")
+		level, err := desiredEngineLogLevel(database, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(level).To(BeEmpty())
+		Expect(database.Status.Logging).To(BeNil())
+	})
+
+	It("should switch to debug for the annotated duration and revert afterwards", func() {
+		database := newDatabase(databasesv1alpha1.EngineLogLevelWarning)
+		database.Annotations[databasesv1alpha1.DebugAnnotation] = "30m"
+
+		level, err := desiredEngineLogLevel(database, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(level).To(Equal(databasesv1alpha1.EngineLogLevelDebug))
+		Expect(database.Status.Logging.DebugUntil.Time).To(Equal(now.Add(30 * time.Minute)))
+		Expect(debugWindowRemaining(database, now.Add(10*time.Minute))).To(Equal(20 * time.Minute))
+
+		// The same annotation value does not restart the window
+		level, err = desiredEngineLogLevel(database, now.Add(31*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(level).To(Equal(databasesv1alpha1.EngineLogLevelWarning))
+	})
+
+	It("should revert to the engine default once the operator changed the level", func() {
+		database := newDatabase("")
+		database.Status.Logging = &databasesv1alpha1.LoggingStatus{EngineLevel: databasesv1alpha1.EngineLogLevelDebug}
+
+		level, err := desiredEngineLogLevel(database, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(level).To(Equal(databasesv1alpha1.EngineLogLevelInfo))
+	})
+
+	It("should reject an invalid debug duration", func() {
+		database := newDatabase("")
+		database.Annotations[databasesv1alpha1.DebugAnnotation] = "forever"
+
+		_, err := desiredEngineLogLevel(database, now)
+		Expect(err).To(MatchError(ContainSubstring("expected a positive duration")))
+	})
+
+	It("should set the level on every replica and keep it in the MongoDB and Redis configuration", func() {
+		reconciler := &DatabaseReconciler{}
+		for _, dbType := range []databasesv1alpha1.DatabaseType{
+			databasesv1alpha1.DatabaseTypePostgreSQL, databasesv1alpha1.DatabaseTypeMongoDB, databasesv1alpha1.DatabaseTypeRedis,
+		} {
+			Expect(engineLogLevelScripts[dbType]).To(ContainSubstring(`getent hosts "$PEERS_HOST"`), string(dbType))
+		}
+
+		database := newDatabase(databasesv1alpha1.EngineLogLevelWarning)
+		database.Name = "cache"
+		database.Namespace = "shop"
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		config, err := renderEngineConfig(database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Content).To(Equal("loglevel warning\n"))
+		template := reconciler.createRedisStatefulSet(database, 1, nil).Spec.Template
+		Expect(template.Spec.Containers[0].Args).To(ContainElement("/usr/local/etc/redis/redis.conf"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		config, err = renderEngineConfig(database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Content).To(Equal("systemLog:\n  quiet: true\n  verbosity: 0\n"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypePostgreSQL
+		Expect(engineLogLevelParameters(database)).To(BeEmpty())
+	})
+
+	It("should map every level for every engine that supports it", func() {
+		for dbType, capabilities := range engineCapabilities {
+			if !capabilities.SupportsRuntimeLogLevel {
+				continue
+			}
+			Expect(engineLogLevelScripts).To(HaveKey(dbType))
+			Expect(engineLogSettings[dbType]).To(HaveLen(4), string(dbType))
+		}
+	})
+})
@@ -0,0 +1,260 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	logShipperContainer    = "log-shipper"
+	defaultLogShipperImage = "fluent/fluent-bit:3.1.9"
+	engineLogsVolume       = "engine-logs"
+	// shippedLogsPath is where the sidecar reads the log files of the engine
+	shippedLogsPath = "/var/log/engine"
+)
+
+// engineLogFiles are the log files an engine writes when its logs are shipped
+type engineLogFiles struct {
+	// directory is where the engine writes them, on a volume shared with the sidecar
+	directory string
+	// pattern matches them in the directory
+	pattern string
+}
+
+var logFiles = map[databasesv1alpha1.DatabaseType]engineLogFiles{
+	databasesv1alpha1.DatabaseTypePostgreSQL:    {directory: "/var/log/postgresql", pattern: "postgresql-*"},
+	databasesv1alpha1.DatabaseTypeMongoDB:       {directory: "/var/log/mongodb", pattern: "mongod.log"},
+	databasesv1alpha1.DatabaseTypeRedis:         {directory: "/var/log/redis", pattern: "redis.log"},
+	databasesv1alpha1.DatabaseTypeElasticsearch: {directory: "/usr/share/elasticsearch/logs", pattern: "*_server.json"},
+}
+
+// logOutputPorts are the default ports of the fluent-bit outputs with a host
+// property. Outputs without a host reach cloud APIs over HTTPS.
+var logOutputPorts = map[string]int{
+	"es":         9200,
+	"opensearch": 9200,
+	"loki":       3100,
+	"forward":    24224,
+	"http":       80,
+	"splunk":     8088,
+	"gelf":       12201,
+	"syslog":     514,
+	"tcp":        5170,
+	"influxdb":   8086,
+}
+
+// localLogOutputs are the fluent-bit outputs not sending logs over the network
+var localLogOutputs = []string{"stdout", "null", "counter", "file"}
+
+// loggingSpec returns the logging settings of a Database, nil when unset
+func loggingSpec(database *databasesv1alpha1.Database) *databasesv1alpha1.LoggingSpec {
+	if database.Spec.Observability == nil {
+		return nil
+	}
+	return database.Spec.Observability.Logging
+}
+
+// validateLogging checks the log format and shipping against the engine
+func validateLogging(database *databasesv1alpha1.Database, capabilities EngineCapabilities) error {
+	logging := loggingSpec(database)
+	if logging == nil {
+		return nil
+	}
+	if logging.Format != "" && !slices.Contains(capabilities.SupportedLogFormats, string(logging.Format)) {
+		if len(capabilities.SupportedLogFormats) == 0 {
+			return fmt.Errorf("%s has no server logs, remove observability.logging.format", database.Spec.Type)
+		}
+		return fmt.Errorf("%s does not write %s logs (supported: %s)",
+			database.Spec.Type, logging.Format, strings.Join(capabilities.SupportedLogFormats, ", "))
+	}
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypePostgreSQL && logging.Format == databasesv1alpha1.LogFormatJSON {
+		if logging.Shipping == nil {
+			return fmt.Errorf("PostgreSQL writes json logs to files only, set observability.logging.shipping")
+		}
+		if version, ok := parseEngineVersion(database.Spec.Version); ok && version.less(engineVersion{15, 0}) {
+			return fmt.Errorf("PostgreSQL writes json logs from version 15, got %s", database.Spec.Version)
+		}
+	}
+	if shipping := logging.Shipping; shipping != nil {
+		if _, ok := logFiles[database.Spec.Type]; !ok {
+			return fmt.Errorf("%s has no server logs to ship, remove observability.logging.shipping", database.Spec.Type)
+		}
+		for _, key := range sortedParameterKeys(shipping.Output.Properties) {
+			if key == "" || strings.ContainsAny(key, "= \t") {
+				return fmt.Errorf("observability.logging.shipping.output: invalid property name %q", key)
+			}
+		}
+	}
+	return nil
+}
+
+// loggingParameters returns the engine parameters writing the log files
+// shipped by the sidecar
+func loggingParameters(database *databasesv1alpha1.Database) map[string]string {
+	logging := loggingSpec(database)
+	if logging == nil || logging.Shipping == nil {
+		return nil
+	}
+	files := logFiles[database.Spec.Type]
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		destination := "stderr"
+		if logging.Format == databasesv1alpha1.LogFormatJSON {
+			destination = "jsonlog"
+		}
+		// 24 hourly files, each truncated when it is reused a day later
+		return map[string]string{
+			"logging_collector":        "on",
+			"log_destination":          destination,
+			"log_directory":            files.directory,
+			"log_filename":             "postgresql-%H.log",
+			"log_file_mode":            "0640",
+			"log_rotation_age":         "60",
+			"log_rotation_size":        "0",
+			"log_truncate_on_rotation": "on",
+		}
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		return map[string]string{
+			"systemLog.destination": "file",
+			"systemLog.path":        path.Join(files.directory, files.pattern),
+			"systemLog.logAppend":   "true",
+		}
+	case databasesv1alpha1.DatabaseTypeRedis:
+		return map[string]string{"logfile": path.Join(files.directory, files.pattern)}
+	}
+	return nil
+}
+
+// addLogShipper adds the fluent-bit sidecar to a pod template. The engine
+// writes its log files to a volume the sidecar tails, tagging every record with
+// the Database.
+func addLogShipper(database *databasesv1alpha1.Database, template *corev1.PodTemplateSpec) {
+	logging := loggingSpec(database)
+	files, ok := logFiles[database.Spec.Type]
+	if logging == nil || logging.Shipping == nil || !ok {
+		return
+	}
+	shipping := logging.Shipping
+
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name:         engineLogsVolume,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	engine := &template.Spec.Containers[0]
+	engine.VolumeMounts = append(engine.VolumeMounts, corev1.VolumeMount{Name: engineLogsVolume, MountPath: files.directory})
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeElasticsearch {
+		// The image logs to the console unless told otherwise
+		engine.Env = append(engine.Env, corev1.EnvVar{Name: "ES_LOG_STYLE", Value: "file"})
+	}
+
+	args := []string{
+		"-i", "tail", "-t", strings.ToLower(string(database.Spec.Type)),
+		"-p", "path=" + path.Join(shippedLogsPath, files.pattern),
+		"-F", "modify", "-m", "*",
+		"-p", "add=database " + database.Name,
+		"-p", "add=namespace " + database.Namespace,
+		"-o", shipping.Output.Name, "-m", "*",
+	}
+	for _, key := range sortedParameterKeys(shipping.Output.Properties) {
+		args = append(args, "-p", key+"="+shipping.Output.Properties[key])
+	}
+	image := defaultLogShipperImage
+	if shipping.Image != "" {
+		image = shipping.Image
+	}
+	sidecar := corev1.Container{
+		Name:         logShipperContainer,
+		Image:        image,
+		Args:         args,
+		VolumeMounts: []corev1.VolumeMount{{Name: engineLogsVolume, MountPath: shippedLogsPath, ReadOnly: true}},
+	}
+	if shipping.CredentialsSecret != "" {
+		sidecar.EnvFrom = []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: shipping.CredentialsSecret}},
+		}}
+	}
+	template.Spec.Containers = append(template.Spec.Containers, sidecar)
+}
+
+// logOutputProperty returns a property of the log output, whose names
+// fluent-bit matches case-insensitively
+func logOutputProperty(output databasesv1alpha1.LogOutputSpec, name string) string {
+	for key, value := range output.Properties {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// logShippingEgressRule allows the sidecar to reach the log output: its host
+// when it is an IP, any address otherwise, on the port of the output. It returns
+// nil without shipping or for outputs keeping the logs in the pod.
+func logShippingEgressRule(database *databasesv1alpha1.Database) *networkingv1.NetworkPolicyEgressRule {
+	logging := loggingSpec(database)
+	if logging == nil || logging.Shipping == nil || slices.Contains(localLogOutputs, logging.Shipping.Output.Name) {
+		return nil
+	}
+	output := logging.Shipping.Output
+	host := logOutputProperty(output, "host")
+
+	port, ok := logOutputPorts[output.Name]
+	if !ok || host == "" {
+		port = 443
+	}
+	if value, err := strconv.Atoi(logOutputProperty(output, "port")); err == nil {
+		port = value
+	}
+	// gelf and syslog send UDP datagrams unless told otherwise
+	protocol := corev1.ProtocolTCP
+	mode := strings.ToLower(logOutputProperty(output, "mode"))
+	if mode == "udp" || (mode == "" && (output.Name == "gelf" || output.Name == "syslog")) {
+		protocol = corev1.ProtocolUDP
+	}
+
+	// Outputs in the cluster are pods, which IP blocks may not cover
+	peers := []networkingv1.NetworkPolicyPeer{
+		{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}},
+		{IPBlock: &networkingv1.IPBlock{CIDR: "::/0"}},
+		{NamespaceSelector: &metav1.LabelSelector{}},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		bits := 128
+		if ip.To4() != nil {
+			bits = 32
+		}
+		peers = []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: ip.String() + "/" + strconv.Itoa(bits)}}}
+	}
+	policyPort := intstr.FromInt(port)
+	return &networkingv1.NetworkPolicyEgressRule{
+		To:    peers,
+		Ports: []networkingv1.NetworkPolicyPort{{Protocol: &protocol, Port: &policyPort}},
+	}
+}
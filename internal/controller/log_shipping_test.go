@@ -0,0 +1,134 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Log shipping", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		logging    *databasesv1alpha1.LoggingSpec
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		logging = &databasesv1alpha1.LoggingSpec{
+			Format: databasesv1alpha1.LogFormatJSON,
+			Shipping: &databasesv1alpha1.LogShippingSpec{
+				Output: databasesv1alpha1.LogOutputSpec{
+					Name:       "loki",
+					Properties: map[string]string{"host": "loki.logging.svc", "http_passwd": "${LOKI_PASSWORD}"},
+				},
+				CredentialsSecret: "loki-credentials",
+			},
+		}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:          databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:       "16",
+				Storage:       &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+				Observability: &databasesv1alpha1.ObservabilitySpec{Logging: logging},
+			},
+		}
+	})
+
+	It("should write engine logs to files tailed by the fluent-bit sidecar", func() {
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+		template := reconciler.createPostgreSQLStatefulSet(database, 1, reconciler.getPostgreSQLEnv(database)).Spec.Template
+		engine := template.Spec.Containers[0]
+		Expect(engine.Args).To(ContainElements("log_destination=jsonlog", "log_directory=/var/log/postgresql",
+			"logging_collector=on"))
+		Expect(engine.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: engineLogsVolume, MountPath: "/var/log/postgresql"}))
+
+		Expect(template.Spec.Containers).To(HaveLen(2))
+		shipper := template.Spec.Containers[1]
+		Expect(shipper.Name).To(Equal(logShipperContainer))
+		Expect(shipper.Image).To(Equal(defaultLogShipperImage))
+		Expect(shipper.Args).To(Equal([]string{
+			"-i", "tail", "-t", "postgresql", "-p", "path=/var/log/engine/postgresql-*",
+			"-F", "modify", "-m", "*", "-p", "add=database orders", "-p", "add=namespace shop",
+			"-o", "loki", "-m", "*", "-p", "host=loki.logging.svc", "-p", "http_passwd=${LOKI_PASSWORD}",
+		}))
+		Expect(shipper.VolumeMounts).To(Equal([]corev1.VolumeMount{{Name: engineLogsVolume, MountPath: shippedLogsPath, ReadOnly: true}}))
+		Expect(shipper.EnvFrom[0].SecretRef.Name).To(Equal("loki-credentials"))
+
+		// Redis writes its log file from the configuration file
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		database.Spec.Version = "7"
+		logging.Format = ""
+		config, err := renderEngineConfig(database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Content).To(Equal("logfile /var/log/redis/redis.log\n"))
+	})
+
+	It("should reject settings the engine does not support", func() {
+		database.Spec.Version = "14"
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("from version 15")))
+		database.Spec.Version = "16"
+		logging.Shipping = nil
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("set observability.logging.shipping")))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		database.Spec.Version = "7"
+		Expect(reconciler.validateSpec(database)).To(MatchError("Redis does not write json logs (supported: text)"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeSQLite
+		database.Spec.Version = "3"
+		logging.Format = ""
+		logging.Shipping = &databasesv1alpha1.LogShippingSpec{Output: databasesv1alpha1.LogOutputSpec{Name: "stdout"}}
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("no server logs to ship")))
+
+		// Without shipping settings only the engine level reaches the configuration
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		database.Spec.Observability.Logging = &databasesv1alpha1.LoggingSpec{EngineLevel: databasesv1alpha1.EngineLogLevelDebug}
+		Expect(mongoDBParameters(database)).To(Equal(map[string]string{"systemLog.verbosity": "2", "systemLog.quiet": "false"}))
+	})
+
+	It("should let the database pods reach the log output", func() {
+		database.Spec.Networking = &databasesv1alpha1.NetworkingSpec{
+			NetworkPolicy: &databasesv1alpha1.NetworkPolicySpec{Enabled: true},
+		}
+		egress := reconciler.createDatabaseNetworkPolicy(database).Spec.Egress
+		Expect(egress).To(HaveLen(3))
+		Expect(egress[2].To).To(ContainElement(HaveField("NamespaceSelector", Equal(&metav1.LabelSelector{}))))
+		Expect(egress[2].Ports).To(HaveLen(1))
+		Expect(egress[2].Ports[0].Port.IntValue()).To(Equal(3100))
+		Expect(*egress[2].Ports[0].Protocol).To(Equal(corev1.ProtocolTCP))
+
+		logging.Shipping.Output = databasesv1alpha1.LogOutputSpec{
+			Name:       "gelf",
+			Properties: map[string]string{"Host": "10.4.0.7", "Port": "12202"},
+		}
+		rule := logShippingEgressRule(database)
+		Expect(rule.To).To(HaveExactElements(HaveField("IPBlock.CIDR", "10.4.0.7/32")))
+		Expect(rule.Ports[0].Port.IntValue()).To(Equal(12202))
+		Expect(*rule.Ports[0].Protocol).To(Equal(corev1.ProtocolUDP))
+
+		logging.Shipping.Output = databasesv1alpha1.LogOutputSpec{Name: "stdout"}
+		Expect(logShippingEgressRule(database)).To(BeNil())
+	})
+})
@@ -0,0 +1,57 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// Operations reported in the "operation" field of reconcile log lines
+const (
+	operationValidate         = "validate"
+	operationProvision        = "provision"
+	operationKeyspaceAnalysis = "keyspace-analysis"
+	operationShardAnalysis    = "shard-analysis"
+	operationLogLevel         = "log-level"
+	operationBackup           = "backup"
+	operationBootstrap        = "bootstrap"
+	operationMonitoringUser   = "monitoring-user"
+	operationDiskPressure     = "disk-pressure"
+	operationRotation         = "rotation"
+	operationMajorUpgrade     = "major-upgrade"
+	operationStorageMigration = "storage-migration"
+	operationStatus           = "status"
+	operationFinalize         = "finalize"
+)
+
+// withDatabaseLogger returns a context whose logger identifies the Database by UID
+// and generation, so lines of concurrent reconciles can be told apart. Helpers pick
+// it up with log.FromContext.
+func withDatabaseLogger(ctx context.Context, database *databasesv1alpha1.Database) context.Context {
+	logger := log.FromContext(ctx).WithValues("uid", database.UID, "generation", database.Generation)
+	return log.IntoContext(ctx, logger)
+}
+
+// withOperation returns a context whose logger records the operation in progress.
+// Operations are not nested, each one is derived from the reconcile context.
+func withOperation(ctx context.Context, operation string) context.Context {
+	return log.IntoContext(ctx, log.FromContext(ctx).WithValues("operation", operation))
+}
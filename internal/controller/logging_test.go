@@ -0,0 +1,52 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Reconcile logging", func() {
+	It("should tag log lines with the UID, generation and operation", func() {
+		var lines []string
+		logger := funcr.New(func(prefix, args string) {
+			lines = append(lines, args)
+		}, funcr.Options{})
+
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{UID: "1234", Generation: 3},
+		}
+		ctx := withDatabaseLogger(log.IntoContext(context.Background(), logger), database)
+
+		log.FromContext(withOperation(ctx, operationProvision)).Info("Creating StatefulSet")
+		log.FromContext(withOperation(ctx, operationLogLevel)).Info("Changing engine log level")
+
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(ContainSubstring(`"uid"="1234" "generation"=3 "operation"="provision"`))
+		Expect(lines[1]).To(ContainSubstring(`"operation"="log-level"`))
+		Expect(lines[1]).NotTo(ContainSubstring("provision"))
+	})
+})
@@ -0,0 +1,541 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// logicalDatabaseFinalizer drops the database and its owner before a
+	// LogicalDatabase with the Delete deletion policy goes away
+	logicalDatabaseFinalizer = "databases.database-operator.io/logical-database-finalizer"
+	// logicalDatabaseHashAnnotation records on the Job which spec and password
+	// it applies, or dropLogicalDatabaseHash for the Job dropping the database
+	logicalDatabaseHashAnnotation = "databases.database-operator.io/logical-database-hash"
+	dropLogicalDatabaseHash       = "drop"
+
+	logicalDatabaseComponentPrefix = "logicaldb-"
+	logicalDatabaseDatabaseKey     = "database"
+)
+
+// systemDatabases are the databases of each engine LogicalDatabases cannot own
+var systemDatabases = map[databasesv1alpha1.DatabaseType][]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: {"postgres", "template0", "template1"},
+	databasesv1alpha1.DatabaseTypeMongoDB:    {"admin", "local", "config"},
+}
+
+// LogicalDatabaseReconciler reconciles a LogicalDatabase object
+type LogicalDatabaseReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	ReconcilerOptions
+	// Proxy is the HTTP proxy of generated Jobs
+	Proxy ProxyConfig
+}
+
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=logicaldatabases,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=logicaldatabases/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=logicaldatabases/finalizers,verbs=update
+
+// Reconcile creates the database and its owner in the engine through an admin
+// Job whenever the spec or the password changes, and drops them when a
+// LogicalDatabase with the Delete deletion policy is deleted
+func (r *LogicalDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	logical := &databasesv1alpha1.LogicalDatabase{}
+	if err := r.Get(ctx, req.NamespacedName, logical); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	database := &databasesv1alpha1.Database{}
+	if err := r.Get(ctx, types.NamespacedName{Name: logical.Spec.DatabaseRef.Name, Namespace: logical.Namespace}, database); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		database = nil
+	} else {
+		ctx = withDatabaseLogger(ctx, database)
+	}
+
+	originalStatus := logical.Status.DeepCopy()
+	var result ctrl.Result
+	var err error
+	if !logical.DeletionTimestamp.IsZero() {
+		err = r.finalizeLogicalDatabase(ctx, logical, database)
+	} else {
+		if controllerutil.AddFinalizer(logical, logicalDatabaseFinalizer) {
+			if err := r.Update(ctx, logical); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		result, err = r.reconcileLogicalDatabase(ctx, logical, database)
+	}
+	if err != nil {
+		log.Error(err, "Failed to reconcile LogicalDatabase")
+	}
+
+	if logical.DeletionTimestamp.IsZero() || controllerutil.ContainsFinalizer(logical, logicalDatabaseFinalizer) {
+		if !equality.Semantic.DeepEqual(originalStatus, &logical.Status) {
+			if err := r.Status().Update(ctx, logical); err != nil {
+				log.Error(err, "Failed to update LogicalDatabase status")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	return result, err
+}
+
+func (r *LogicalDatabaseReconciler) reconcileLogicalDatabase(ctx context.Context, logical *databasesv1alpha1.LogicalDatabase, database *databasesv1alpha1.Database) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	logical.Status.ObservedGeneration = logical.Generation
+
+	if database == nil {
+		setLogicalDatabasePending(logical, fmt.Sprintf("Database %q not found", logical.Spec.DatabaseRef.Name))
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+	if err := r.validateLogicalDatabase(ctx, logical, database); err != nil {
+		failLogicalDatabase(logical, err.Error())
+		return ctrl.Result{}, nil
+	}
+	// Wait for a server accepting connections
+	if database.Status.ReadyReplicas == 0 {
+		setLogicalDatabasePending(logical, fmt.Sprintf("Waiting for Database %s to be ready", database.Name))
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+
+	secret, version, err := r.ownerPasswordSecret(ctx, logical, database)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if secret == nil {
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+	hash := logicalDatabaseHash(logical, version)
+
+	job, err := r.logicalDatabaseJob(ctx, logical, database)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if job != nil {
+		finished, succeeded := jobFinished(job)
+		if job.Annotations[logicalDatabaseHashAnnotation] == hash && !finished {
+			return ctrl.Result{}, nil
+		}
+
+		// Remove finished Jobs and Jobs applying an outdated spec; the Job
+		// deletion event triggers the next step
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if job.Annotations[logicalDatabaseHashAnnotation] == hash {
+			if !succeeded {
+				failLogicalDatabase(logical, fmt.Sprintf("Failed to provision database %s, see the logs of Job %s", logicalDatabaseTarget(logical), job.Name))
+				return ctrl.Result{}, fmt.Errorf("failed to provision database %s", logicalDatabaseTarget(logical))
+			}
+			log.Info("Provisioned logical database", "database", logicalDatabaseTarget(logical), "owner", logical.Spec.Owner, "hash", hash)
+			logical.Status.AppliedHash = hash
+			logical.Status.Phase = databasesv1alpha1.LogicalDatabasePhaseReady
+			logical.Status.Message = fmt.Sprintf("Database %s owned by %s is provisioned in Database %s",
+				logicalDatabaseTarget(logical), logical.Spec.Owner, database.Name)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if logical.Status.AppliedHash == hash {
+		return ctrl.Result{RequeueAfter: databaseUserResyncInterval}, nil
+	}
+
+	job = r.databaseReconciler(r.Client, r.Scheme).createAdminJob(database, logicalDatabaseComponent(logical), logicalDatabaseScript(database, logical),
+		[]corev1.EnvVar{passwordEnv(databaseUserPasswordEnv, secret)})
+	job.Annotations = map[string]string{logicalDatabaseHashAnnotation: hash}
+	if err := controllerutil.SetControllerReference(logical, job, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Provisioning logical database", "database", logicalDatabaseTarget(logical), "owner", logical.Spec.Owner, "hash", hash)
+	if logical.Status.Phase != databasesv1alpha1.LogicalDatabasePhaseReady {
+		setLogicalDatabasePending(logical, fmt.Sprintf("Provisioning database %s in Job %s", logicalDatabaseTarget(logical), job.Name))
+	}
+	return ctrl.Result{}, r.Create(ctx, job)
+}
+
+// finalizeLogicalDatabase drops the database and its owner with the Delete
+// deletion policy, then removes the finalizer. Databases that were never
+// provisioned, or whose Database is gone or being deleted, have nothing to drop.
+func (r *LogicalDatabaseReconciler) finalizeLogicalDatabase(ctx context.Context, logical *databasesv1alpha1.LogicalDatabase, database *databasesv1alpha1.Database) error {
+	if !controllerutil.ContainsFinalizer(logical, logicalDatabaseFinalizer) {
+		return nil
+	}
+
+	dropped := true
+	if logical.Spec.DeletionPolicy == databasesv1alpha1.LogicalDatabaseDeletionPolicyDelete &&
+		database != nil && database.DeletionTimestamp.IsZero() && logical.Status.AppliedHash != "" {
+		var err error
+		if dropped, err = r.dropLogicalDatabase(ctx, logical, database); err != nil || !dropped {
+			return err
+		}
+	}
+
+	if dropped && controllerutil.RemoveFinalizer(logical, logicalDatabaseFinalizer) {
+		return r.Update(ctx, logical)
+	}
+	return nil
+}
+
+// dropLogicalDatabase runs the Job dropping the database and its owner and
+// reports whether it succeeded
+func (r *LogicalDatabaseReconciler) dropLogicalDatabase(ctx context.Context, logical *databasesv1alpha1.LogicalDatabase, database *databasesv1alpha1.Database) (bool, error) {
+	job, err := r.logicalDatabaseJob(ctx, logical, database)
+	if err != nil {
+		return false, err
+	}
+	if job != nil {
+		finished, succeeded := jobFinished(job)
+		drop := job.Annotations[logicalDatabaseHashAnnotation] == dropLogicalDatabaseHash
+		if drop && !finished {
+			return false, nil
+		}
+		// Provisioning Jobs are replaced by the drop Job once deleted
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		if !drop {
+			return false, nil
+		}
+		if !succeeded {
+			failLogicalDatabase(logical, fmt.Sprintf("Failed to drop database %s, see the logs of Job %s", logicalDatabaseTarget(logical), job.Name))
+			return false, fmt.Errorf("failed to drop database %s", logicalDatabaseTarget(logical))
+		}
+		log.FromContext(ctx).Info("Dropped logical database", "database", logicalDatabaseTarget(logical), "owner", logical.Spec.Owner)
+		return true, nil
+	}
+
+	job = r.databaseReconciler(r.Client, r.Scheme).createAdminJob(database, logicalDatabaseComponent(logical), dropLogicalDatabaseScript(database, logical), nil)
+	job.Annotations = map[string]string{logicalDatabaseHashAnnotation: dropLogicalDatabaseHash}
+	if err := controllerutil.SetControllerReference(logical, job, r.Scheme); err != nil {
+		return false, err
+	}
+
+	log.FromContext(ctx).Info("Dropping logical database", "database", logicalDatabaseTarget(logical), "owner", logical.Spec.Owner)
+	logical.Status.Message = fmt.Sprintf("Dropping database %s in Job %s", logicalDatabaseTarget(logical), job.Name)
+	return false, r.Create(ctx, job)
+}
+
+// validateLogicalDatabase checks that the engine manages databases and that
+// neither the database nor the owner is claimed by the Database, another
+// LogicalDatabase or a DatabaseUser created before
+func (r *LogicalDatabaseReconciler) validateLogicalDatabase(ctx context.Context, logical *databasesv1alpha1.LogicalDatabase, database *databasesv1alpha1.Database) error {
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL, databasesv1alpha1.DatabaseTypeMongoDB:
+	default:
+		return fmt.Errorf("%s does not support logical databases", database.Spec.Type)
+	}
+	if database.Spec.TargetCluster != nil {
+		return fmt.Errorf("targetCluster does not support logical databases")
+	}
+	if logical.Spec.Schema != "" && database.Spec.Type != databasesv1alpha1.DatabaseTypePostgreSQL {
+		return fmt.Errorf("%s does not support schemas", database.Spec.Type)
+	}
+
+	name := logical.Spec.DatabaseName
+	if slices.Contains(systemDatabases[database.Spec.Type], name) && logical.Spec.Schema == "" {
+		return fmt.Errorf("database %s is a system database", name)
+	}
+	// The schema of a LogicalDatabase may live in a bootstrap database
+	if bootstrap := database.Spec.Bootstrap; bootstrap != nil && logical.Spec.Schema == "" {
+		for _, db := range bootstrap.Databases {
+			if db.Name == name {
+				return fmt.Errorf("database %s is managed by Database %s", name, database.Name)
+			}
+		}
+	}
+	owner := logical.Spec.Owner
+	if slices.Contains(reservedUsernames(database), owner) {
+		return fmt.Errorf("user %s is managed by Database %s", owner, database.Name)
+	}
+
+	// The oldest LogicalDatabase or DatabaseUser of a name owns it
+	logicals := &databasesv1alpha1.LogicalDatabaseList{}
+	if err := r.List(ctx, logicals, client.InNamespace(logical.Namespace)); err != nil {
+		return err
+	}
+	for _, other := range logicals.Items {
+		if other.Name == logical.Name || other.Spec.DatabaseRef.Name != database.Name || !claimedBefore(&other, logical) {
+			continue
+		}
+		if other.Spec.DatabaseName == name && other.Spec.Schema == logical.Spec.Schema {
+			return fmt.Errorf("database %s is managed by LogicalDatabase %s", logicalDatabaseTarget(logical), other.Name)
+		}
+		if other.Spec.Owner == owner {
+			return fmt.Errorf("user %s is managed by LogicalDatabase %s", owner, other.Name)
+		}
+	}
+	users := &databasesv1alpha1.DatabaseUserList{}
+	if err := r.List(ctx, users, client.InNamespace(logical.Namespace)); err != nil {
+		return err
+	}
+	for _, user := range users.Items {
+		if user.Spec.DatabaseRef.Name == database.Name && user.Spec.Username == owner && claimedBefore(&user, logical) {
+			return fmt.Errorf("user %s is managed by DatabaseUser %s", owner, user.Name)
+		}
+	}
+	return nil
+}
+
+// reservedUsernames returns the users the Database manages itself: the
+// administrative, monitoring and bootstrap users
+func reservedUsernames(database *databasesv1alpha1.Database) []string {
+	reserved := []string{adminUsername(database)}
+	if monitoringUserEnabled(database) {
+		reserved = append(reserved, monitoringUsername)
+	}
+	if database.Spec.Bootstrap != nil {
+		for _, bootstrapUser := range database.Spec.Bootstrap.Users {
+			reserved = append(reserved, bootstrapUser.Name)
+		}
+	}
+	return reserved
+}
+
+// claimedBefore reports whether other was created before object, ordering
+// objects created in the same second by name
+func claimedBefore(other, object metav1.Object) bool {
+	created, objectCreated := other.GetCreationTimestamp(), object.GetCreationTimestamp()
+	return created.Before(&objectCreated) ||
+		(created.Equal(&objectCreated) && other.GetName() < object.GetName())
+}
+
+// ownerPasswordSecret returns the Secret key holding the password of the owner
+// and the resourceVersion of the Secret, generating it when the spec has no
+// passwordSecret. It returns nil while the Secret is unusable.
+func (r *LogicalDatabaseReconciler) ownerPasswordSecret(ctx context.Context, logical *databasesv1alpha1.LogicalDatabase, database *databasesv1alpha1.Database) (*databasesv1alpha1.SecretReference, string, error) {
+	if reference := logical.Spec.PasswordSecret; reference != nil {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: reference.Name, Namespace: logical.Namespace}, secret); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, "", err
+			}
+			setLogicalDatabasePending(logical, fmt.Sprintf("Secret %q not found", reference.Name))
+			return nil, "", nil
+		}
+		if _, ok := secret.Data[reference.Key]; !ok {
+			setLogicalDatabasePending(logical, fmt.Sprintf("Secret %q has no %q key", reference.Name, reference.Key))
+			return nil, "", nil
+		}
+		logical.Status.SecretName = secret.Name
+		return reference, secret.ResourceVersion, nil
+	}
+
+	name := logical.Spec.SecretName
+	if name == "" {
+		name = logical.Name
+	}
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: logical.Namespace}, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, "", err
+	}
+	if err == nil {
+		if !metav1.IsControlledBy(secret, logical) {
+			failLogicalDatabase(logical, fmt.Sprintf("Secret %s exists and is not managed by LogicalDatabase %s", name, logical.Name))
+			return nil, "", nil
+		}
+		if _, ok := secret.Data[credentialsPasswordKey]; !ok {
+			return nil, "", fmt.Errorf("secret %s has no %q key", name, credentialsPasswordKey)
+		}
+	} else {
+		password, err := randomPassword(generatedPasswordLength)
+		if err != nil {
+			return nil, "", err
+		}
+		port := r.databaseReconciler(r.Client, r.Scheme).getServicePorts(database)[0].Port
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: logical.Namespace,
+				Labels:    r.databaseReconciler(r.Client, r.Scheme).getComponentLabels(database, logicalDatabaseComponent(logical)),
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{
+				credentialsUsernameKey:     []byte(logical.Spec.Owner),
+				credentialsPasswordKey:     []byte(password),
+				databaseUserHostKey:        []byte(serviceHost(database)),
+				databaseUserPortKey:        []byte(strconv.Itoa(int(port))),
+				logicalDatabaseDatabaseKey: []byte(logical.Spec.DatabaseName),
+			},
+		}
+		if err := controllerutil.SetControllerReference(logical, secret, r.Scheme); err != nil {
+			return nil, "", err
+		}
+		log.FromContext(ctx).Info("Creating credentials Secret", "name", name)
+		if err := r.Create(ctx, secret); err != nil {
+			return nil, "", err
+		}
+	}
+
+	logical.Status.SecretName = secret.Name
+	return &databasesv1alpha1.SecretReference{Name: secret.Name, Key: credentialsPasswordKey}, secret.ResourceVersion, nil
+}
+
+// logicalDatabaseJob returns the Job provisioning or dropping the database, or nil
+func (r *LogicalDatabaseReconciler) logicalDatabaseJob(ctx context.Context, logical *databasesv1alpha1.LogicalDatabase, database *databasesv1alpha1.Database) (*batchv1.Job, error) {
+	job := &batchv1.Job{}
+	name := database.Name + "-" + logicalDatabaseComponent(logical)
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: logical.Namespace}, job); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return job, nil
+}
+
+// logicalDatabaseComponent names the Jobs and Secret of a LogicalDatabase
+func logicalDatabaseComponent(logical *databasesv1alpha1.LogicalDatabase) string {
+	return logicalDatabaseComponentPrefix + logical.Name
+}
+
+// logicalDatabaseTarget names the database, or the schema, of a LogicalDatabase
+func logicalDatabaseTarget(logical *databasesv1alpha1.LogicalDatabase) string {
+	if logical.Spec.Schema != "" {
+		return logical.Spec.DatabaseName + "." + logical.Spec.Schema
+	}
+	return logical.Spec.DatabaseName
+}
+
+// logicalDatabaseHash identifies the spec and the password version applied by a Job
+func logicalDatabaseHash(logical *databasesv1alpha1.LogicalDatabase, secretVersion string) string {
+	spec, _ := json.Marshal(logical.Spec)
+	sum := sha256.Sum256(append(spec, secretVersion...))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// logicalDatabaseScript renders the idempotent commands creating the owner or
+// resetting its password, and creating the database or schema it owns. On
+// PostgreSQL other users lose the default CONNECT privilege on an owned
+// database; CREATE DATABASE cannot run in a transaction.
+func logicalDatabaseScript(database *databasesv1alpha1.Database, logical *databasesv1alpha1.LogicalDatabase) string {
+	name, schema, owner := logical.Spec.DatabaseName, logical.Spec.Schema, logical.Spec.Owner
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB {
+		// Owners keep the roles granted to them by others
+		return mongoDBUserScript(fmt.Sprintf(`const owner = {role: "dbOwner", db: %[2]q};
+if (admin.getUser(%[1]q)) {
+  admin.updateUser(%[1]q, {pwd: process.env.%[3]s});
+  admin.grantRolesToUser(%[1]q, [owner]);
+} else admin.createUser({user: %[1]q, pwd: process.env.%[3]s, roles: [owner]});
+`, owner, name, databaseUserPasswordEnv))
+	}
+
+	var sql strings.Builder
+	fmt.Fprintf(&sql, "SELECT 'CREATE ROLE \"%[1]s\" LOGIN' WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '%[1]s')\\gexec\n", owner)
+	fmt.Fprintf(&sql, "ALTER ROLE \"%s\" LOGIN PASSWORD :'password';\n", owner)
+	fmt.Fprintf(&sql, "SELECT 'CREATE DATABASE \"%[1]s\"' WHERE NOT EXISTS (SELECT FROM pg_database WHERE datname = '%[1]s')\\gexec\n", name)
+	if schema == "" {
+		fmt.Fprintf(&sql, "ALTER DATABASE \"%s\" OWNER TO \"%s\";\n", name, owner)
+		fmt.Fprintf(&sql, "REVOKE ALL ON DATABASE \"%s\" FROM PUBLIC;\n", name)
+	} else {
+		fmt.Fprintf(&sql, "GRANT CONNECT ON DATABASE \"%s\" TO \"%s\";\n", name, owner)
+		fmt.Fprintf(&sql, "ALTER ROLE \"%s\" IN DATABASE \"%s\" SET search_path TO \"%s\";\n", owner, name, schema)
+		fmt.Fprintf(&sql, "\\connect \"%s\"\n", name)
+		fmt.Fprintf(&sql, "CREATE SCHEMA IF NOT EXISTS \"%[1]s\" AUTHORIZATION \"%[2]s\";\nALTER SCHEMA \"%[1]s\" OWNER TO \"%[2]s\";\n", schema, owner)
+	}
+	return fmt.Sprintf("psql -h \"$DB_HOST\" -v ON_ERROR_STOP=1 -v password=\"$%s\" <<'SQL'\n%sSQL",
+		databaseUserPasswordEnv, sql.String())
+}
+
+// dropLogicalDatabaseScript renders the commands dropping the database, or the
+// schema with the objects of the owner in its database, and the owner if they
+// exist. Sessions connected to a dropped PostgreSQL database are terminated.
+func dropLogicalDatabaseScript(database *databasesv1alpha1.Database, logical *databasesv1alpha1.LogicalDatabase) string {
+	name, schema, owner := logical.Spec.DatabaseName, logical.Spec.Schema, logical.Spec.Owner
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB {
+		return mongoDBUserScript(fmt.Sprintf("db.getSiblingDB(%[2]q).dropDatabase();\nif (admin.getUser(%[1]q)) admin.dropUser(%[1]q);\n", owner, name))
+	}
+
+	var sql strings.Builder
+	if schema == "" {
+		fmt.Fprintf(&sql, "SELECT format('ALTER DATABASE %%I ALLOW_CONNECTIONS false', datname) FROM pg_database WHERE datname = '%s'\\gexec\n", name)
+		fmt.Fprintf(&sql, "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = '%s';\n", name)
+		fmt.Fprintf(&sql, "DROP DATABASE IF EXISTS \"%s\";\n", name)
+	} else {
+		fmt.Fprintf(&sql, "SELECT EXISTS (SELECT FROM pg_database WHERE datname = '%s') AND EXISTS (SELECT FROM pg_roles WHERE rolname = '%s') AS found \\gset\n", name, owner)
+		fmt.Fprintf(&sql, "\\if :found\n\\connect \"%s\"\nDROP SCHEMA IF EXISTS \"%s\" CASCADE;\nDROP OWNED BY \"%s\";\n\\endif\n", name, schema, owner)
+	}
+	sql.WriteString(revokeDatabasePrivilegesSQL(owner))
+	fmt.Fprintf(&sql, "DROP ROLE IF EXISTS \"%s\";\n", owner)
+	return fmt.Sprintf("psql -h \"$DB_HOST\" -v ON_ERROR_STOP=1 <<'SQL'\n%sSQL", sql.String())
+}
+
+func setLogicalDatabasePending(logical *databasesv1alpha1.LogicalDatabase, message string) {
+	logical.Status.Phase = databasesv1alpha1.LogicalDatabasePhasePending
+	logical.Status.Message = message
+}
+
+func failLogicalDatabase(logical *databasesv1alpha1.LogicalDatabase, message string) {
+	logical.Status.Phase = databasesv1alpha1.LogicalDatabasePhaseFailed
+	logical.Status.Message = message
+}
+
+// logicalDatabasesOfDatabase enqueues the LogicalDatabases of a Database, so
+// they are provisioned once it becomes ready
+func (r *LogicalDatabaseReconciler) logicalDatabasesOfDatabase(ctx context.Context, database client.Object) []reconcile.Request {
+	logicals := &databasesv1alpha1.LogicalDatabaseList{}
+	if err := r.List(ctx, logicals, client.InNamespace(database.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list LogicalDatabases", "database", database.GetName())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, logical := range logicals.Items {
+		if logical.Spec.DatabaseRef.Name == database.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&logical)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LogicalDatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&databasesv1alpha1.LogicalDatabase{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.Secret{}).
+		Watches(&databasesv1alpha1.Database{}, handler.EnqueueRequestsFromMapFunc(r.logicalDatabasesOfDatabase)).
+		Named("logicaldatabase").
+		Complete(r)
+}
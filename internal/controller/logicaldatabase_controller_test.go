@@ -0,0 +1,219 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("LogicalDatabase Controller", func() {
+	var (
+		ctx        context.Context
+		reconciler *LogicalDatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	logicalKey := types.NamespacedName{Name: "billing", Namespace: "shop"}
+	jobKey := types.NamespacedName{Name: "orders-logicaldb-billing", Namespace: "shop"}
+
+	newLogicalDatabase := func(name, databaseName, owner string) *databasesv1alpha1.LogicalDatabase {
+		return &databasesv1alpha1.LogicalDatabase{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: databasesv1alpha1.LogicalDatabaseSpec{
+				DatabaseRef:  corev1.LocalObjectReference{Name: "orders"},
+				DatabaseName: databaseName,
+				Owner:        owner,
+			},
+		}
+	}
+
+	reconcile := func() *databasesv1alpha1.LogicalDatabase {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: logicalKey})
+		Expect(err).NotTo(HaveOccurred())
+		logical := &databasesv1alpha1.LogicalDatabase{}
+		if err := reconciler.Get(ctx, logicalKey, logical); apierrors.IsNotFound(err) {
+			return nil
+		}
+		return logical
+	}
+
+	completeJob := func() {
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+	}
+
+	provision := func() {
+		reconcile()
+		completeJob()
+		Expect(reconcile().Status.Phase).To(Equal(databasesv1alpha1.LogicalDatabasePhaseReady))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Bootstrap: &databasesv1alpha1.BootstrapSpec{
+					Databases: []databasesv1alpha1.BootstrapDatabase{{Name: "app", Owner: "app"}},
+					Users:     []databasesv1alpha1.BootstrapUser{{Name: "app"}},
+				},
+			},
+			Status: databasesv1alpha1.DatabaseStatus{ReadyReplicas: 1},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.LogicalDatabase{}, &batchv1.Job{}).
+			WithObjects(database, newLogicalDatabase("billing", "billing", "billing")).
+			Build()
+		reconciler = &LogicalDatabaseReconciler{Client: c, Scheme: scheme}
+	})
+
+	It("should generate the credentials of the owner and provision the database in a Job", func() {
+		logical := reconcile()
+		Expect(logical.Finalizers).To(ContainElement(logicalDatabaseFinalizer))
+		Expect(logical.Status.Phase).To(Equal(databasesv1alpha1.LogicalDatabasePhasePending))
+		Expect(logical.Status.SecretName).To(Equal("billing"))
+
+		secret := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, logicalKey, secret)).To(Succeed())
+		Expect(metav1.IsControlledBy(secret, logical)).To(BeTrue())
+		Expect(secret.Data).To(HaveKeyWithValue("username", []byte("billing")))
+		Expect(secret.Data).To(HaveKeyWithValue("database", []byte("billing")))
+		Expect(secret.Data).To(HaveKeyWithValue("host", []byte("orders-service.shop.svc")))
+		Expect(secret.Data["password"]).To(HaveLen(generatedPasswordLength))
+
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(metav1.IsControlledBy(job, logical)).To(BeTrue())
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(And(
+			ContainSubstring(`SELECT 'CREATE DATABASE "billing"' WHERE NOT EXISTS`),
+			ContainSubstring(`ALTER DATABASE "billing" OWNER TO "billing";`),
+			ContainSubstring(`REVOKE ALL ON DATABASE "billing" FROM PUBLIC;`),
+		))
+
+		completeJob()
+		logical = reconcile()
+		Expect(logical.Status.Phase).To(Equal(databasesv1alpha1.LogicalDatabasePhaseReady))
+		Expect(logical.Status.AppliedHash).NotTo(BeEmpty())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+	})
+
+	It("should keep the database by default and drop it with the Delete deletion policy", func() {
+		provision()
+		logical := &databasesv1alpha1.LogicalDatabase{}
+		Expect(reconciler.Get(ctx, logicalKey, logical)).To(Succeed())
+		Expect(reconciler.Delete(ctx, logical)).To(Succeed())
+		Expect(reconcile()).To(BeNil())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		By("dropping the database and the owner")
+		Expect(reconciler.Create(ctx, newLogicalDatabase("billing", "billing", "billing"))).To(Succeed())
+		provision()
+		Expect(reconciler.Get(ctx, logicalKey, logical)).To(Succeed())
+		logical.Spec.DeletionPolicy = databasesv1alpha1.LogicalDatabaseDeletionPolicyDelete
+		Expect(reconciler.Update(ctx, logical)).To(Succeed())
+		Expect(reconciler.Delete(ctx, logical)).To(Succeed())
+		Expect(reconcile()).NotTo(BeNil())
+
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue(logicalDatabaseHashAnnotation, dropLogicalDatabaseHash))
+		Expect(job.Spec.Template.Spec.Containers[0].Command[2]).To(And(
+			ContainSubstring(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = 'billing';`),
+			ContainSubstring(`DROP DATABASE IF EXISTS "billing";`),
+			ContainSubstring(`DROP ROLE IF EXISTS "billing";`),
+		))
+
+		completeJob()
+		Expect(reconcile()).To(BeNil())
+	})
+
+	It("should reject databases and owners managed elsewhere", func() {
+		validate := func(logical *databasesv1alpha1.LogicalDatabase) error {
+			logical.CreationTimestamp = metav1.Now()
+			return reconciler.validateLogicalDatabase(ctx, logical, database)
+		}
+		Expect(validate(newLogicalDatabase("other", "template1", "other"))).To(MatchError("database template1 is a system database"))
+		Expect(validate(newLogicalDatabase("other", "app", "other"))).To(MatchError("database app is managed by Database orders"))
+		Expect(validate(newLogicalDatabase("other", "other", "postgres"))).To(MatchError("user postgres is managed by Database orders"))
+		Expect(validate(newLogicalDatabase("other", "billing", "other"))).To(MatchError("database billing is managed by LogicalDatabase billing"))
+		Expect(validate(newLogicalDatabase("other", "other", "billing"))).To(MatchError("user billing is managed by LogicalDatabase billing"))
+
+		By("sharing a database through schemas")
+		shared := newLogicalDatabase("other", "app", "other")
+		shared.Spec.Schema = "other"
+		Expect(validate(shared)).To(Succeed())
+
+		By("sharing owners with DatabaseUsers in neither direction")
+		user := &databasesv1alpha1.DatabaseUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "reporting", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseUserSpec{DatabaseRef: corev1.LocalObjectReference{Name: "orders"}, Username: "reporting"},
+		}
+		Expect(reconciler.Create(ctx, user)).To(Succeed())
+		Expect(validate(newLogicalDatabase("other", "other", "reporting"))).To(MatchError("user reporting is managed by DatabaseUser reporting"))
+		user.Spec.Username = "billing"
+		users := &DatabaseUserReconciler{Client: reconciler.Client, Scheme: reconciler.Scheme}
+		Expect(users.validateDatabaseUser(ctx, user, database)).To(MatchError("user billing is managed by LogicalDatabase billing"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		Expect(validate(shared)).To(MatchError("MongoDB does not support schemas"))
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		Expect(validate(newLogicalDatabase("other", "other", "other"))).To(MatchError("Redis does not support logical databases"))
+	})
+
+	It("should render schemas owned inside a shared database and MongoDB owners", func() {
+		logical := newLogicalDatabase("billing", "app", "billing")
+		logical.Spec.Schema = "billing"
+		Expect(logicalDatabaseScript(database, logical)).To(And(
+			ContainSubstring(`GRANT CONNECT ON DATABASE "app" TO "billing";`),
+			ContainSubstring(`ALTER ROLE "billing" IN DATABASE "app" SET search_path TO "billing";`),
+			ContainSubstring(`CREATE SCHEMA IF NOT EXISTS "billing" AUTHORIZATION "billing";`),
+			Not(ContainSubstring("ALTER DATABASE")),
+		))
+		Expect(dropLogicalDatabaseScript(database, logical)).To(And(
+			ContainSubstring(`DROP SCHEMA IF EXISTS "billing" CASCADE;`),
+			Not(ContainSubstring("DROP DATABASE")),
+		))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		logical.Spec.Schema = ""
+		Expect(logicalDatabaseScript(database, logical)).To(ContainSubstring(`const owner = {role: "dbOwner", db: "app"};`))
+		Expect(dropLogicalDatabaseScript(database, logical)).To(ContainSubstring(`db.getSiblingDB("app").dropDatabase();`))
+	})
+})
@@ -0,0 +1,164 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// maintenanceLocation returns the time zone the maintenance windows are
+// evaluated in
+func maintenanceLocation(maintenance *databasesv1alpha1.MaintenanceSpec) (*time.Location, error) {
+	if maintenance.TimeZone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(maintenance.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenance.timeZone %q: %w", maintenance.TimeZone, err)
+	}
+	return location, nil
+}
+
+// validateMaintenance checks the time zone and the clocks of the windows
+func validateMaintenance(database *databasesv1alpha1.Database) error {
+	maintenance := database.Spec.Maintenance
+	if maintenance == nil {
+		return nil
+	}
+	if _, err := maintenanceLocation(maintenance); err != nil {
+		return err
+	}
+	for i, window := range maintenance.Windows {
+		for _, clock := range []string{window.Start, window.End} {
+			if _, _, err := parseClock(clock); err != nil {
+				return fmt.Errorf("invalid maintenance window %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// maintenanceOverrideUntil returns the end of the emergency override of the
+// maintenance windows, or the zero time when none is in effect at now. An
+// invalid annotation overrides nothing.
+func maintenanceOverrideUntil(database *databasesv1alpha1.Database, now time.Time) time.Time {
+	value, ok := database.Annotations[databasesv1alpha1.MaintenanceOverrideUntilAnnotation]
+	if !ok {
+		return time.Time{}
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil || !until.After(now) {
+		return time.Time{}
+	}
+	return until
+}
+
+// evaluateMaintenance returns whether a maintenance window is open at now and
+// when the next one opens or closes
+func evaluateMaintenance(database *databasesv1alpha1.Database, now time.Time) (*databasesv1alpha1.MaintenanceStatus, error) {
+	maintenance := database.Spec.Maintenance
+	location, err := maintenanceLocation(maintenance)
+	if err != nil {
+		return nil, err
+	}
+	now = now.In(location)
+
+	status := &databasesv1alpha1.MaintenanceStatus{}
+	var next time.Time
+	for _, window := range maintenance.Windows {
+		for _, occurrence := range dailyWindows(window.Days, window.Start, window.End, now) {
+			if !occurrence.start.After(now) && occurrence.end.After(now) {
+				status.Open = true
+			}
+			for _, boundary := range []time.Time{occurrence.start, occurrence.end} {
+				if boundary.After(now) && (next.IsZero() || boundary.Before(next)) {
+					next = boundary
+				}
+			}
+		}
+	}
+
+	if until := maintenanceOverrideUntil(database, now); !until.IsZero() {
+		status.Open = true
+		if next.IsZero() || until.Before(next) {
+			next = until
+		}
+	}
+	if !next.IsZero() {
+		nextChange := metav1.NewTime(next)
+		status.NextChange = &nextChange
+	}
+	return status, nil
+}
+
+// reconcileMaintenance evaluates the maintenance windows into status, where the
+// disruptive operations record that they are deferred
+func reconcileMaintenance(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) error {
+	if database.Spec.Maintenance == nil {
+		database.Status.Maintenance = nil
+		return nil
+	}
+
+	status, err := evaluateMaintenance(database, now)
+	if err != nil {
+		return err
+	}
+	if previous := database.Status.Maintenance; previous == nil || previous.Open != status.Open {
+		log.FromContext(ctx).Info("Maintenance window changed", "open", status.Open)
+	}
+	database.Status.Maintenance = status
+	return nil
+}
+
+// deferredToMaintenance reports whether a disruptive operation has to wait for
+// the next maintenance window, and records it in status. An operation holding
+// the lock finishes after the window closed, and a Database without ready
+// replicas has nothing to disrupt.
+func deferredToMaintenance(database *databasesv1alpha1.Database, operation string, now time.Time) bool {
+	if database.Spec.Maintenance == nil || database.Status.ReadyReplicas == 0 || activeOperation(database) == operation {
+		return false
+	}
+	status, err := evaluateMaintenance(database, now)
+	if err != nil || status.Open {
+		return false
+	}
+
+	if database.Status.Maintenance == nil {
+		database.Status.Maintenance = status
+	}
+	if deferred := &database.Status.Maintenance.Deferred; !slices.Contains(*deferred, operation) {
+		*deferred = append(*deferred, operation)
+	}
+	return true
+}
+
+// nextMaintenanceChange returns the time until a maintenance window opens or
+// closes, if any
+func nextMaintenanceChange(database *databasesv1alpha1.Database, now time.Time) time.Duration {
+	if status := database.Status.Maintenance; status != nil && status.NextChange != nil {
+		return status.NextChange.Sub(now)
+	}
+	return 0
+}
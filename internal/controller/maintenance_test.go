@@ -0,0 +1,137 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Maintenance windows", func() {
+	var (
+		ctx      context.Context
+		database *databasesv1alpha1.Database
+	)
+
+	// Saturday 1 March 2025, 12:00 in Paris
+	now := time.Date(2025, 3, 1, 11, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", Annotations: map[string]string{}},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Maintenance: &databasesv1alpha1.MaintenanceSpec{
+					TimeZone: "Europe/Paris",
+					Windows: []databasesv1alpha1.MaintenanceWindow{
+						{Days: []databasesv1alpha1.Weekday{"Sat", "Sun"}, Start: "22:00", End: "04:00"},
+					},
+				},
+			},
+			Status: databasesv1alpha1.DatabaseStatus{ReadyReplicas: 1},
+		}
+	})
+
+	It("should report when the next window opens and closes", func() {
+		Expect(reconcileMaintenance(ctx, database, now)).To(Succeed())
+		status := database.Status.Maintenance
+		Expect(status.Open).To(BeFalse())
+		Expect(status.NextChange.Time).To(BeTemporally("==", time.Date(2025, 3, 1, 21, 0, 0, 0, time.UTC)))
+		Expect(nextMaintenanceChange(database, now)).To(Equal(10 * time.Hour))
+
+		// The Sunday window runs into Monday morning
+		Expect(reconcileMaintenance(ctx, database, time.Date(2025, 3, 3, 2, 0, 0, 0, time.UTC))).To(Succeed())
+		Expect(database.Status.Maintenance.Open).To(BeTrue())
+		Expect(database.Status.Maintenance.NextChange.Time).To(BeTemporally("==", time.Date(2025, 3, 3, 3, 0, 0, 0, time.UTC)))
+
+		database.Spec.Maintenance = nil
+		Expect(reconcileMaintenance(ctx, database, now)).To(Succeed())
+		Expect(database.Status.Maintenance).To(BeNil())
+	})
+
+	It("should defer disruptive operations outside the windows unless overridden", func() {
+		Expect(reconcileMaintenance(ctx, database, now)).To(Succeed())
+		Expect(deferredToMaintenance(database, disruptiveOperationUpdate, now)).To(BeTrue())
+		Expect(deferredToMaintenance(database, disruptiveOperationUpdate, now)).To(BeTrue())
+		Expect(database.Status.Maintenance.Deferred).To(Equal([]string{disruptiveOperationUpdate}))
+		Expect(deferredToMaintenance(database, disruptiveOperationUpdate, now.Add(11*time.Hour))).To(BeFalse())
+
+		database.Annotations[databasesv1alpha1.MaintenanceOverrideUntilAnnotation] = "2025-03-01T13:00:00Z"
+		Expect(deferredToMaintenance(database, disruptiveOperationRotation, now)).To(BeFalse())
+		Expect(reconcileMaintenance(ctx, database, now)).To(Succeed())
+		Expect(database.Status.Maintenance.Open).To(BeTrue())
+		Expect(database.Status.Maintenance.Deferred).To(BeEmpty())
+		Expect(nextMaintenanceChange(database, now)).To(Equal(2 * time.Hour))
+
+		// An expired or invalid override lets nothing through
+		Expect(deferredToMaintenance(database, disruptiveOperationRotation, now.Add(3*time.Hour))).To(BeTrue())
+		database.Annotations[databasesv1alpha1.MaintenanceOverrideUntilAnnotation] = "tonight"
+		Expect(deferredToMaintenance(database, disruptiveOperationRotation, now)).To(BeTrue())
+
+		// Without ready replicas there is nothing to disrupt
+		database.Status.ReadyReplicas = 0
+		Expect(deferredToMaintenance(database, disruptiveOperationUpdate, now)).To(BeFalse())
+	})
+
+	It("should reject an unknown time zone", func() {
+		database.Spec.Maintenance.TimeZone = "Mars/Olympus"
+		Expect(validateMaintenance(database)).To(MatchError(ContainSubstring("maintenance.timeZone")))
+	})
+
+	It("should defer scale-downs but not scale-ups", func() {
+		// A window opening in two hours is closed now
+		start := time.Now().UTC().Add(2 * time.Hour)
+		database.Spec.Maintenance = &databasesv1alpha1.MaintenanceSpec{
+			Windows: []databasesv1alpha1.MaintenanceWindow{
+				{Start: start.Format("15:04"), End: start.Add(time.Hour).Format("15:04")},
+			},
+		}
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		current := int32(3)
+		statefulSet := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &current},
+		}
+		reconciler := &DatabaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(statefulSet).Build(), Scheme: scheme}
+		key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+
+		Expect(reconciler.scaleStatefulSet(ctx, database, statefulSet, 1)).To(Succeed())
+		Expect(reconciler.Get(ctx, key, statefulSet)).To(Succeed())
+		Expect(*statefulSet.Spec.Replicas).To(Equal(int32(3)))
+		Expect(database.Status.Operations).To(BeNil())
+		Expect(database.Status.Maintenance.Deferred).To(ConsistOf(disruptiveOperationScale))
+
+		Expect(reconciler.scaleStatefulSet(ctx, database, statefulSet, 5)).To(Succeed())
+		Expect(reconciler.Get(ctx, key, statefulSet)).To(Succeed())
+		Expect(*statefulSet.Spec.Replicas).To(Equal(int32(5)))
+	})
+})
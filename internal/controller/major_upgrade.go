@@ -0,0 +1,578 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	majorUpgradeCheckComponent = "upgrade-check"
+	majorUpgradeComponent      = "upgrade"
+	majorUpgradeInitContainer  = "prepare"
+	defaultMajorUpgradeImage   = "tianon/postgres-upgrade"
+
+	// majorUpgradeRecheckInterval is how often the Jobs and volumes of a
+	// running upgrade are checked
+	majorUpgradeRecheckInterval = 15 * time.Second
+	// majorUpgradeVerifyTimeout is how long the workload has to become ready on
+	// the new major before the upgrade is rolled back
+	majorUpgradeVerifyTimeout = 15 * time.Minute
+)
+
+// +kubebuilder:rbac:groups="",resources=persistentvolumes,verbs=get;list;watch;update;patch
+
+// majorUpgradeInitScript initializes the cluster of the new major on the empty
+// volume, with data checksums like the cluster of the previous major. The check
+// and the upgrade share the cluster, which pg_upgrade --check leaves untouched.
+const majorUpgradeInitScript = `set -e
+if [ ! -s "$PGDATANEW/PG_VERSION" ]; then
+  checksums=--data-checksums
+  if "$PGBINOLD/pg_controldata" "$PGDATAOLD" | grep -q '^Data page checksum version: *0$'; then
+    checksums=
+    if "$PGBINNEW/initdb" --help | grep -q -- --no-data-checksums; then
+      checksums=--no-data-checksums
+    fi
+  fi
+  "$PGBINNEW/initdb" --username="$PGUSER" -D "$PGDATANEW" $checksums
+fi
+cd /tmp
+`
+
+// majorUpgradeScripts run pg_upgrade, configured by the PGBINOLD, PGBINNEW,
+// PGDATAOLD, PGDATANEW and PGUSER variables, for each step with Jobs. The data
+// is copied, so the volume of the previous major stays usable for a rollback.
+// Client authentication is carried over since initdb only allows local clients.
+var majorUpgradeScripts = map[databasesv1alpha1.MajorUpgradeStep]string{
+	databasesv1alpha1.MajorUpgradeStepPreCheck: majorUpgradeInitScript + `"$PGBINNEW/pg_upgrade" --check`,
+	databasesv1alpha1.MajorUpgradeStepUpgrade: majorUpgradeInitScript + `"$PGBINNEW/pg_upgrade"
+cp "$PGDATAOLD/pg_hba.conf" "$PGDATANEW/pg_hba.conf"`,
+}
+
+// offlineUpgrade reports whether spec.version is a new major of an engine whose
+// data volumes are converted offline
+func offlineUpgrade(database *databasesv1alpha1.Database) bool {
+	if !upgradeMatrix[database.Spec.Type].offlineMajor || database.Spec.Storage == nil {
+		return false
+	}
+	from, ok := parseEngineVersion(database.Status.Version)
+	if !ok {
+		return false
+	}
+	to, ok := parseEngineVersion(database.Spec.Version)
+	return ok && to.major > from.major
+}
+
+// runningVersion returns the version the workload runs: the version the data
+// was written by until a major upgrade cut over to the converted volumes
+func runningVersion(database *databasesv1alpha1.Database) string {
+	if !offlineUpgrade(database) {
+		return database.Spec.Version
+	}
+	if upgrade := database.Status.MajorUpgrade; upgrade != nil && upgrade.ToVersion == database.Spec.Version &&
+		upgrade.Step == databasesv1alpha1.MajorUpgradeStepVerify {
+		return upgrade.ToVersion
+	}
+	return database.Status.Version
+}
+
+// majorUpgradeRunning reports whether an upgrade has not completed or rolled back
+func majorUpgradeRunning(upgrade *databasesv1alpha1.MajorUpgradeStatus) bool {
+	return upgrade != nil && upgrade.Step != databasesv1alpha1.MajorUpgradeStepCompleted &&
+		upgrade.Step != databasesv1alpha1.MajorUpgradeStepRolledBack
+}
+
+// reconcileMajorUpgrade upgrades the data volumes of PostgreSQL to the new major
+// of spec.version, which cannot run on the data of the previous one. Holding a
+// lock that stops the workload, it checks every volume with pg_upgrade --check,
+// converts it into a new volume and binds the claim of the replica to it. The
+// workload then starts on the new major; when it fails to become ready, or a
+// step fails, the previous volumes are put back and it runs the previous major
+// again. A rolled back upgrade is retried once version changes.
+func (r *DatabaseReconciler) reconcileMajorUpgrade(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) error {
+	upgrade := database.Status.MajorUpgrade
+	if upgrade != nil && upgrade.ToVersion != database.Spec.Version {
+		if !majorUpgradeRunning(upgrade) {
+			database.Status.MajorUpgrade, upgrade = nil, nil
+		} else if upgrade.Step != databasesv1alpha1.MajorUpgradeStepRollingBack {
+			upgrade.Step = databasesv1alpha1.MajorUpgradeStepRollingBack
+			upgrade.Message = fmt.Sprintf("version changed to %s during the upgrade", database.Spec.Version)
+		}
+	}
+	if upgrade == nil {
+		if !offlineUpgrade(database) {
+			return nil
+		}
+		upgrade = &databasesv1alpha1.MajorUpgradeStatus{
+			FromVersion: database.Status.Version,
+			ToVersion:   database.Spec.Version,
+			Step:        databasesv1alpha1.MajorUpgradeStepPreCheck,
+			StartedAt:   metav1.NewTime(now),
+		}
+		database.Status.MajorUpgrade = upgrade
+	}
+	if !majorUpgradeRunning(upgrade) {
+		return nil
+	}
+
+	if activeOperation(database) != disruptiveOperationMajorUpgrade {
+		if frozen(database, now) {
+			upgrade.Message = "Deferred by the release freeze"
+			return nil
+		}
+		if deferredToMaintenance(database, disruptiveOperationMajorUpgrade, now) {
+			upgrade.Message = "Deferred to the maintenance window"
+			return nil
+		}
+		if !acquireOperation(database, disruptiveOperationMajorUpgrade, now) {
+			upgrade.Message = fmt.Sprintf("Waiting for operation %s to finish", activeOperation(database))
+			return nil
+		}
+		r.event(database, corev1.EventTypeNormal, "Upgrading", fmt.Sprintf("Upgrading %s from %s to %s with pg_upgrade",
+			database.Spec.Type, upgrade.FromVersion, upgrade.ToVersion))
+	}
+	var err error
+	switch upgrade.Step {
+	case databasesv1alpha1.MajorUpgradeStepPreCheck, databasesv1alpha1.MajorUpgradeStepUpgrade:
+		err = r.runMajorUpgradeJobs(ctx, database, upgrade)
+	case databasesv1alpha1.MajorUpgradeStepCutover:
+		err = r.cutOverMajorUpgrade(ctx, database, upgrade, now)
+	case databasesv1alpha1.MajorUpgradeStepVerify:
+		r.verifyMajorUpgrade(ctx, database, upgrade, now)
+	default:
+		err = r.rollBackMajorUpgrade(ctx, database, upgrade, now)
+	}
+	// The workload runs again once the claims are bound to the upgraded volumes
+	if activeOperation(database) == disruptiveOperationMajorUpgrade {
+		database.Status.Operations.Active.StopsWorkload = upgrade.Step != databasesv1alpha1.MajorUpgradeStepVerify
+	}
+	return err
+}
+
+// runMajorUpgradeJobs runs the Job of the step on every data volume, once the
+// workload is stopped, and moves on to the next step once all succeeded
+func (r *DatabaseReconciler) runMajorUpgradeJobs(ctx context.Context, database *databasesv1alpha1.Database, upgrade *databasesv1alpha1.MajorUpgradeStatus) error {
+	if stopped, err := r.deleteWorkload(ctx, database); err != nil || !stopped {
+		upgrade.Message = "Stopping database"
+		return err
+	}
+	if upgrade.Volumes == nil {
+		volumes, err := r.majorUpgradeVolumes(ctx, database)
+		if err != nil {
+			return err
+		}
+		upgrade.Volumes = volumes
+	}
+
+	succeeded := 0
+	for i, volume := range upgrade.Volumes {
+		claim := &corev1.PersistentVolumeClaim{}
+		name := upgradedClaimName(volume.Claim, upgrade)
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+		if apierrors.IsNotFound(err) {
+			if claim, err = r.newDataClaim(database, name); err != nil {
+				return err
+			}
+			if err := controllerutil.SetControllerReference(database, claim, r.Scheme); err != nil {
+				return err
+			}
+			log.FromContext(ctx).Info("Provisioning volume for the new major", "claim", name)
+			if err := r.Create(ctx, claim); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		job := &batchv1.Job{}
+		jobName := majorUpgradeJobName(database, upgrade.Step, i)
+		err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: database.Namespace}, job)
+		if apierrors.IsNotFound(err) {
+			job = r.createMajorUpgradeJob(database, upgrade, volume.Claim, i)
+			if err := controllerutil.SetControllerReference(database, job, r.Scheme); err != nil {
+				return err
+			}
+			log.FromContext(ctx).Info("Creating major upgrade Job", "name", jobName, "step", upgrade.Step)
+			if err := r.Create(ctx, job); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		switch finished, ok := jobFinished(job); {
+		case finished && !ok:
+			upgrade.Message = fmt.Sprintf("%s Job %s failed, see its logs", upgrade.Step, jobName)
+			upgrade.Step = databasesv1alpha1.MajorUpgradeStepRollingBack
+			return nil
+		case finished:
+			succeeded++
+		}
+	}
+
+	if succeeded < len(upgrade.Volumes) {
+		upgrade.Message = fmt.Sprintf("%s of %d/%d volumes done", upgrade.Step, succeeded, len(upgrade.Volumes))
+		return nil
+	}
+	upgrade.Message = ""
+	if upgrade.Step == databasesv1alpha1.MajorUpgradeStepPreCheck {
+		upgrade.Step = databasesv1alpha1.MajorUpgradeStepUpgrade
+	} else {
+		upgrade.Step = databasesv1alpha1.MajorUpgradeStepCutover
+	}
+	return nil
+}
+
+// majorUpgradeVolumes lists the bound data volumes; replicas that never ran
+// start on the new major without one
+func (r *DatabaseReconciler) majorUpgradeVolumes(ctx context.Context, database *databasesv1alpha1.Database) ([]databasesv1alpha1.MajorUpgradeVolume, error) {
+	volumes := []databasesv1alpha1.MajorUpgradeVolume{}
+	for _, name := range dataClaimNames(database) {
+		claim := &corev1.PersistentVolumeClaim{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+		if apierrors.IsNotFound(err) || (err == nil && claim.Spec.VolumeName == "") {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, databasesv1alpha1.MajorUpgradeVolume{Claim: name, PreviousVolume: claim.Spec.VolumeName})
+	}
+	return volumes, nil
+}
+
+// cutOverMajorUpgrade binds the claim of every replica to its upgraded volume.
+// The volumes of both majors are retained while their claims are swapped.
+func (r *DatabaseReconciler) cutOverMajorUpgrade(ctx context.Context, database *databasesv1alpha1.Database, upgrade *databasesv1alpha1.MajorUpgradeStatus, now time.Time) error {
+	bound := 0
+	for i := range upgrade.Volumes {
+		volume := &upgrade.Volumes[i]
+		claim := &corev1.PersistentVolumeClaim{}
+		name := upgradedClaimName(volume.Claim, upgrade)
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+		if err == nil {
+			if claim.Spec.VolumeName == "" {
+				return fmt.Errorf("volume claim %s of the new major is not bound", name)
+			}
+			volume.UpgradedVolume = claim.Spec.VolumeName
+			if err := r.releaseClaim(ctx, claim); err != nil {
+				return err
+			}
+			continue
+		} else if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		done, err := r.bindClaim(ctx, database, volume.Claim, volume.UpgradedVolume)
+		if err != nil {
+			return err
+		}
+		if done {
+			bound++
+		}
+	}
+
+	if bound < len(upgrade.Volumes) {
+		upgrade.Message = fmt.Sprintf("Bound %d/%d claims to the upgraded volumes", bound, len(upgrade.Volumes))
+		return nil
+	}
+	log.FromContext(ctx).Info("Cut over to the upgraded volumes", "version", upgrade.ToVersion)
+	upgrade.Step = databasesv1alpha1.MajorUpgradeStepVerify
+	upgrade.Message = ""
+	cutOverAt := metav1.NewTime(now)
+	upgrade.CutOverAt = &cutOverAt
+	return nil
+}
+
+// verifyMajorUpgrade completes the upgrade once every replica is ready on the
+// new major, and rolls it back when they are not in time
+func (r *DatabaseReconciler) verifyMajorUpgrade(ctx context.Context, database *databasesv1alpha1.Database, upgrade *databasesv1alpha1.MajorUpgradeStatus, now time.Time) {
+	desired := desiredReplicas(database)
+	if database.Status.ReadyReplicas < desired {
+		upgrade.Message = fmt.Sprintf("%d of %d replicas are ready on %s", database.Status.ReadyReplicas, desired, upgrade.ToVersion)
+		if upgrade.CutOverAt != nil && now.Sub(upgrade.CutOverAt.Time) > majorUpgradeVerifyTimeout {
+			upgrade.Step = databasesv1alpha1.MajorUpgradeStepRollingBack
+			upgrade.Message = fmt.Sprintf("the replicas did not become ready on %s within %s", upgrade.ToVersion, majorUpgradeVerifyTimeout)
+		}
+		return
+	}
+
+	message := fmt.Sprintf("Upgraded from %s to %s", upgrade.FromVersion, upgrade.ToVersion)
+	log.FromContext(ctx).Info("Completed major upgrade", "from", upgrade.FromVersion, "to", upgrade.ToVersion)
+	database.Status.Version = upgrade.ToVersion
+	r.finishMajorUpgrade(ctx, database, upgrade, databasesv1alpha1.MajorUpgradeStepCompleted, message, now)
+	r.event(database, corev1.EventTypeNormal, "Upgraded", message)
+	recordOperation(database, recordMajorUpgrade, databasesv1alpha1.OperationSucceeded, message, now)
+}
+
+// rollBackMajorUpgrade stops the workload and binds the claims back to the
+// volumes of the previous major, deleting the upgraded ones
+func (r *DatabaseReconciler) rollBackMajorUpgrade(ctx context.Context, database *databasesv1alpha1.Database, upgrade *databasesv1alpha1.MajorUpgradeStatus, now time.Time) error {
+	if stopped, err := r.deleteWorkload(ctx, database); err != nil || !stopped {
+		return err
+	}
+
+	restored := 0
+	for i, volume := range upgrade.Volumes {
+		// Before the cutover the upgraded volume is still claimed on its own
+		claim := &corev1.PersistentVolumeClaim{}
+		name := upgradedClaimName(volume.Claim, upgrade)
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+		if err == nil {
+			if claim.DeletionTimestamp.IsZero() {
+				if err := r.Delete(ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+					return err
+				}
+			}
+			continue
+		} else if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		if volume.UpgradedVolume != "" {
+			done, err := r.bindClaim(ctx, database, volume.Claim, volume.PreviousVolume)
+			if err != nil || !done {
+				return err
+			}
+			if err := r.reclaimVolume(ctx, volume.UpgradedVolume); err != nil {
+				return err
+			}
+			upgrade.Volumes[i].UpgradedVolume = ""
+		}
+		restored++
+	}
+	if restored < len(upgrade.Volumes) {
+		return nil
+	}
+
+	reason := upgrade.Message
+	message := fmt.Sprintf("Rolled back the upgrade from %s to %s: %s", upgrade.FromVersion, upgrade.ToVersion, reason)
+	log.FromContext(ctx).Info("Rolled back major upgrade", "from", upgrade.FromVersion, "to", upgrade.ToVersion, "reason", reason)
+	r.finishMajorUpgrade(ctx, database, upgrade, databasesv1alpha1.MajorUpgradeStepRolledBack, message, now)
+	r.event(database, corev1.EventTypeWarning, "UpgradeRolledBack", message)
+	recordOperation(database, recordMajorUpgrade, databasesv1alpha1.OperationFailed, message, now)
+	return nil
+}
+
+// finishMajorUpgrade records the outcome, removes the Jobs and releases the lock
+func (r *DatabaseReconciler) finishMajorUpgrade(ctx context.Context, database *databasesv1alpha1.Database, upgrade *databasesv1alpha1.MajorUpgradeStatus, step databasesv1alpha1.MajorUpgradeStep, message string, now time.Time) {
+	for i := range upgrade.Volumes {
+		for _, jobStep := range []databasesv1alpha1.MajorUpgradeStep{databasesv1alpha1.MajorUpgradeStepPreCheck, databasesv1alpha1.MajorUpgradeStepUpgrade} {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: majorUpgradeJobName(database, jobStep, i), Namespace: database.Namespace}}
+			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+				log.FromContext(ctx).Error(err, "Failed to delete major upgrade Job", "name", job.Name)
+			}
+		}
+	}
+	completedAt := metav1.NewTime(now)
+	upgrade.Step = step
+	upgrade.Message = message
+	upgrade.CompletedAt = &completedAt
+	releaseOperation(database, disruptiveOperationMajorUpgrade)
+}
+
+// bindClaim binds a data volume claim to a PersistentVolume, deleting the claim
+// first when it is bound to another volume, which is retained. The claim takes
+// the storage class of the volume, which differs from the spec when a storage
+// migration is rolled back. It reports whether the claim is bound to the volume.
+func (r *DatabaseReconciler) bindClaim(ctx context.Context, database *databasesv1alpha1.Database, name, volumeName string) (bool, error) {
+	claim := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+	if err == nil {
+		if claim.Spec.VolumeName == volumeName {
+			return true, nil
+		}
+		return false, r.releaseClaim(ctx, claim)
+	} else if !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	// A released volume keeps the reference to its deleted claim
+	volume := &corev1.PersistentVolume{}
+	if err := r.Get(ctx, types.NamespacedName{Name: volumeName}, volume); err != nil {
+		return false, err
+	}
+	if volume.Spec.ClaimRef != nil {
+		patch := client.MergeFrom(volume.DeepCopy())
+		volume.Spec.ClaimRef = nil
+		if err := r.Patch(ctx, volume, patch); err != nil {
+			return false, err
+		}
+	}
+
+	if claim, err = r.newDataClaim(database, name); err != nil {
+		return false, err
+	}
+	claim.Spec.VolumeName = volumeName
+	claim.Spec.StorageClassName = &volume.Spec.StorageClassName
+	log.FromContext(ctx).Info("Binding data volume claim", "claim", name, "volume", volumeName)
+	return false, r.Create(ctx, claim)
+}
+
+// releaseClaim deletes a volume claim, retaining its volume
+func (r *DatabaseReconciler) releaseClaim(ctx context.Context, claim *corev1.PersistentVolumeClaim) error {
+	if claim.Spec.VolumeName != "" {
+		if err := r.setReclaimPolicy(ctx, claim.Spec.VolumeName, corev1.PersistentVolumeReclaimRetain); err != nil {
+			return err
+		}
+	}
+	if !claim.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	if err := r.Delete(ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// reclaimVolume deletes a released volume, and its storage
+func (r *DatabaseReconciler) reclaimVolume(ctx context.Context, name string) error {
+	err := r.setReclaimPolicy(ctx, name, corev1.PersistentVolumeReclaimDelete)
+	return client.IgnoreNotFound(err)
+}
+
+func (r *DatabaseReconciler) setReclaimPolicy(ctx context.Context, name string, policy corev1.PersistentVolumeReclaimPolicy) error {
+	volume := &corev1.PersistentVolume{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, volume); err != nil {
+		return err
+	}
+	if volume.Spec.PersistentVolumeReclaimPolicy == policy {
+		return nil
+	}
+	patch := client.MergeFrom(volume.DeepCopy())
+	volume.Spec.PersistentVolumeReclaimPolicy = policy
+	return r.Patch(ctx, volume, patch)
+}
+
+// upgradedClaimName is the claim of the volume the new major is written to
+func upgradedClaimName(claim string, upgrade *databasesv1alpha1.MajorUpgradeStatus) string {
+	to, _ := parseEngineVersion(upgrade.ToVersion)
+	return fmt.Sprintf("%s-pg%d", claim, to.major)
+}
+
+// majorUpgradeJobName is the name of the Job of a step for the volume of a replica
+func majorUpgradeJobName(database *databasesv1alpha1.Database, step databasesv1alpha1.MajorUpgradeStep, ordinal int) string {
+	component := majorUpgradeComponent
+	if step == databasesv1alpha1.MajorUpgradeStepPreCheck {
+		component = majorUpgradeCheckComponent
+	}
+	return fmt.Sprintf("%s-%s-%d", database.Name, component, ordinal)
+}
+
+// majorUpgradeImage returns the image shipping the binaries of both majors
+func majorUpgradeImage(database *databasesv1alpha1.Database, from, to engineVersion) string {
+	if config := database.Spec.PostgreSQL; config != nil && config.UpgradeImage != "" {
+		return config.UpgradeImage
+	}
+	return fmt.Sprintf("%s:%d-to-%d", defaultMajorUpgradeImage, from.major, to.major)
+}
+
+// createMajorUpgradeJob builds the Job of the current step for the data volume
+// of a replica. The volumes of both majors are mounted where the upgrade image
+// expects them, and the Job runs as the postgres user owning the data.
+func (r *DatabaseReconciler) createMajorUpgradeJob(database *databasesv1alpha1.Database, upgrade *databasesv1alpha1.MajorUpgradeStatus, claim string, ordinal int) *batchv1.Job {
+	from, _ := parseEngineVersion(upgrade.FromVersion)
+	to, _ := parseEngineVersion(upgrade.ToVersion)
+	oldData := fmt.Sprintf("/var/lib/postgresql/%d/data", from.major)
+	newData := fmt.Sprintf("/var/lib/postgresql/%d/data", to.major)
+	env := []corev1.EnvVar{
+		{Name: "PGUSER", Value: adminUsername(database)},
+		{Name: "PGBINOLD", Value: fmt.Sprintf("/usr/lib/postgresql/%d/bin", from.major)},
+		{Name: "PGBINNEW", Value: fmt.Sprintf("/usr/lib/postgresql/%d/bin", to.major)},
+		{Name: "PGDATAOLD", Value: oldData},
+		{Name: "PGDATANEW", Value: newData},
+	}
+
+	component := majorUpgradeComponent
+	if upgrade.Step == databasesv1alpha1.MajorUpgradeStepPreCheck {
+		component = majorUpgradeCheckComponent
+	}
+	image := majorUpgradeImage(database, from, to)
+	backoffLimit := int32(0)
+	uid := postgresUID
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      majorUpgradeJobName(database, upgrade.Step, ordinal),
+			Namespace: database.Namespace,
+			Labels:    r.getComponentLabels(database, component),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template:     r.adminPodTemplate(database, component, image, majorUpgradeScripts[upgrade.Step], env),
+		},
+	}
+
+	mounts := []corev1.VolumeMount{
+		{Name: "data", MountPath: oldData},
+		{Name: "upgraded", MountPath: newData},
+	}
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes,
+		corev1.Volume{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			},
+		},
+		corev1.Volume{
+			Name: "upgraded",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: upgradedClaimName(claim, upgrade)},
+			},
+		},
+	)
+	// The new volume is created owned by root
+	root := int64(0)
+	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+		Name:            majorUpgradeInitContainer,
+		Image:           image,
+		Command:         []string{"/bin/sh", "-c", fmt.Sprintf(`chown %d:%d "$PGDATANEW" && chmod 700 "$PGDATANEW"`, uid, uid)},
+		Env:             env,
+		VolumeMounts:    mounts,
+		SecurityContext: &corev1.SecurityContext{RunAsUser: &root},
+	})
+
+	container := &podSpec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, mounts...)
+	container.SecurityContext = &corev1.SecurityContext{RunAsUser: &uid}
+	return job
+}
+
+// markUpgrading keeps the Database out of Ready while its data is upgraded to
+// a new major
+func markUpgrading(database *databasesv1alpha1.Database, upgrade *databasesv1alpha1.MajorUpgradeStatus) {
+	message := fmt.Sprintf("Upgrading from %s to %s: %s", upgrade.FromVersion, upgrade.ToVersion, upgrade.Step)
+	if upgrade.Message != "" {
+		message += ", " + upgrade.Message
+	}
+	setPhase(database, databasesv1alpha1.DatabasePhaseUpgrading, majorUpgradeSubPhase(upgrade.Step), time.Now())
+	database.Status.Message = message
+	setCondition(database, conditionReady, metav1.ConditionFalse, reasonUpgrading, message)
+}
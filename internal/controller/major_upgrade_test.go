@@ -0,0 +1,205 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Major upgrades", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		now        time.Time
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "shop"}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:  "16",
+				Replicas: ptr.To(int32(1)),
+				Storage:  &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+			},
+			Status: databasesv1alpha1.DatabaseStatus{Version: "15"},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"}},
+			&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "data-orders-0", Namespace: "shop"},
+				Spec:       corev1.PersistentVolumeClaimSpec{VolumeName: "pv-15"},
+			},
+			&corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-15"},
+				Spec: corev1.PersistentVolumeSpec{
+					PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+					ClaimRef:                      &corev1.ObjectReference{Name: "data-orders-0", Namespace: "shop"},
+				},
+			},
+			&corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-16"},
+				Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete},
+			},
+		).Build()
+		reconciler = &DatabaseReconciler{Client: c, Scheme: scheme}
+	})
+
+	reconcile := func() *databasesv1alpha1.MajorUpgradeStatus {
+		Expect(reconciler.reconcileMajorUpgrade(ctx, database, now)).To(Succeed())
+		return database.Status.MajorUpgrade
+	}
+
+	finishJob := func(name string, condition batchv1.JobConditionType) {
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, key(name), job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+		Expect(c.Status().Update(ctx, job)).To(Succeed())
+	}
+
+	volume := func(name string) *corev1.PersistentVolume {
+		volume := &corev1.PersistentVolume{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: name}, volume)).To(Succeed())
+		return volume
+	}
+
+	// runUpgrade runs both Jobs and binds the volume of the new major to pv-16
+	runUpgrade := func() {
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepPreCheck))
+		finishJob("orders-upgrade-check-0", batchv1.JobComplete)
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepUpgrade))
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepUpgrade))
+		finishJob("orders-upgrade-0", batchv1.JobComplete)
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepCutover))
+
+		claim := &corev1.PersistentVolumeClaim{}
+		Expect(c.Get(ctx, key("data-orders-0-pg16"), claim)).To(Succeed())
+		claim.Spec.VolumeName = "pv-16"
+		Expect(c.Update(ctx, claim)).To(Succeed())
+		for i := 0; i < 4 && database.Status.MajorUpgrade.Step == databasesv1alpha1.MajorUpgradeStepCutover; i++ {
+			reconcile()
+		}
+		Expect(database.Status.MajorUpgrade.Step).To(Equal(databasesv1alpha1.MajorUpgradeStepVerify))
+	}
+
+	It("should check, convert and cut over the data volumes with the workload stopped", func() {
+		upgrade := reconcile()
+		Expect(upgrade.FromVersion).To(Equal("15"))
+		Expect(activeOperation(database)).To(Equal(disruptiveOperationMajorUpgrade))
+		Expect(database.Status.Operations.Active.StopsWorkload).To(BeTrue())
+		Expect(runningVersion(database)).To(Equal("15"))
+		err := c.Get(ctx, key("orders"), &appsv1.StatefulSet{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		reconcile()
+		Expect(upgrade.Volumes).To(Equal([]databasesv1alpha1.MajorUpgradeVolume{{Claim: "data-orders-0", PreviousVolume: "pv-15"}}))
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, key("orders-upgrade-check-0"), job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("tianon/postgres-upgrade:15-to-16"))
+		Expect(job.Spec.Template.Spec.Containers[0].Command).To(ContainElement(ContainSubstring(`pg_upgrade" --check`)))
+		Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "data-orders-0-pg16")))
+
+		runUpgrade()
+		claim := &corev1.PersistentVolumeClaim{}
+		Expect(c.Get(ctx, key("data-orders-0"), claim)).To(Succeed())
+		Expect(claim.Spec.VolumeName).To(Equal("pv-16"))
+		Expect(volume("pv-15").Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimRetain))
+		Expect(database.Status.Operations.Active.StopsWorkload).To(BeFalse())
+		Expect(runningVersion(database)).To(Equal("16"))
+		Expect(database.Status.Version).To(Equal("15"))
+
+		database.Status.ReadyReplicas = 1
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepCompleted))
+		Expect(database.Status.Version).To(Equal("16"))
+		Expect(activeOperation(database)).To(BeEmpty())
+		Expect(database.Status.RecentOperations).To(ConsistOf(And(
+			HaveField("Type", recordMajorUpgrade),
+			HaveField("Outcome", databasesv1alpha1.OperationSucceeded),
+		)))
+		err = c.Get(ctx, key("orders-upgrade-0"), &batchv1.Job{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should delete the volumes of the new major when a Job fails", func() {
+		reconcile()
+		reconcile()
+		finishJob("orders-upgrade-check-0", batchv1.JobFailed)
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepRollingBack))
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepRollingBack))
+		err := c.Get(ctx, key("data-orders-0-pg16"), &corev1.PersistentVolumeClaim{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		upgrade := reconcile()
+		Expect(upgrade.Step).To(Equal(databasesv1alpha1.MajorUpgradeStepRolledBack))
+		Expect(upgrade.Message).To(ContainSubstring("orders-upgrade-check-0 failed"))
+		Expect(database.Status.RecentOperations).To(ConsistOf(HaveField("Outcome", databasesv1alpha1.OperationFailed)))
+		Expect(runningVersion(database)).To(Equal("15"))
+
+		// Not retried until the version changes
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepRolledBack))
+		database.Spec.Version = "17"
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepPreCheck))
+	})
+
+	It("should put the previous volumes back when the new major does not become ready", func() {
+		reconcile()
+		runUpgrade()
+
+		now = now.Add(majorUpgradeVerifyTimeout + time.Minute)
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.MajorUpgradeStepRollingBack))
+		for i := 0; i < 4 && database.Status.MajorUpgrade.Step == databasesv1alpha1.MajorUpgradeStepRollingBack; i++ {
+			reconcile()
+		}
+		Expect(database.Status.MajorUpgrade.Step).To(Equal(databasesv1alpha1.MajorUpgradeStepRolledBack))
+		claim := &corev1.PersistentVolumeClaim{}
+		Expect(c.Get(ctx, key("data-orders-0"), claim)).To(Succeed())
+		Expect(claim.Spec.VolumeName).To(Equal("pv-15"))
+		Expect(volume("pv-16").Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimDelete))
+		Expect(database.Status.Version).To(Equal("15"))
+	})
+})
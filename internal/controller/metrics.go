@@ -0,0 +1,174 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var (
+	redisKeys = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "database_operator_redis_keys",
+		Help: "Number of keys per type reported by the latest Redis keyspace analysis",
+	}, []string{"namespace", "database", "type"})
+
+	redisBiggestKeyBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "database_operator_redis_biggest_key_bytes",
+		Help: "Memory used by the biggest key of each type reported by the latest Redis keyspace analysis",
+	}, []string{"namespace", "database", "type", "key"})
+
+	elasticsearchShards = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "database_operator_elasticsearch_shards",
+		Help: "Number of shards reported by the latest Elasticsearch shard analysis",
+	}, []string{"namespace", "database"})
+
+	elasticsearchSmallShards = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "database_operator_elasticsearch_small_shards",
+		Help: "Number of shards below the small shard size reported by the latest Elasticsearch shard analysis",
+	}, []string{"namespace", "database"})
+
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "database_operator_reconcile_duration_seconds",
+		Help:    "Time taken to reconcile a Database, per engine",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"engine"})
+
+	reconcilesInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "database_operator_reconciles_in_flight",
+		Help: "Number of Database reconciles running, per engine",
+	}, []string{"engine"})
+
+	freezeUntil = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "database_operator_freeze_until_timestamp_seconds",
+		Help: "End of the release freeze of a frozen Database, as a Unix timestamp",
+	}, []string{"namespace", "database"})
+
+	stalledDatabasesDesc = prometheus.NewDesc("database_operator_stalled_databases",
+		"Number of Databases not Ready for longer than 15 minutes, per engine and phase",
+		[]string{"engine", "phase"}, nil)
+)
+
+// stalledDatabaseThreshold is how long a Database may stay out of Ready before it
+// counts as stalled
+const stalledDatabaseThreshold = 15 * time.Minute
+
+func init() {
+	metrics.Registry.MustRegister(
+		redisKeys,
+		redisBiggestKeyBytes,
+		elasticsearchShards,
+		elasticsearchSmallShards,
+		reconcileDuration,
+		reconcilesInFlight,
+		freezeUntil,
+	)
+}
+
+// observeReconcile tracks a running reconcile of a Database. The returned func
+// records its duration once it returns.
+func observeReconcile(database *databasesv1alpha1.Database) func() {
+	engine := string(database.Spec.Type)
+	start := time.Now()
+	reconcilesInFlight.WithLabelValues(engine).Inc()
+	return func() {
+		reconcilesInFlight.WithLabelValues(engine).Dec()
+		reconcileDuration.WithLabelValues(engine).Observe(time.Since(start).Seconds())
+	}
+}
+
+// stalledDatabasesCollector counts the stalled Databases of the cache on every
+// scrape, so the count never drifts from the objects
+type stalledDatabasesCollector struct {
+	reader client.Reader
+	now    func() time.Time
+}
+
+// registerStalledDatabasesCollector registers the collector once per process
+func registerStalledDatabasesCollector(reader client.Reader) error {
+	err := metrics.Registry.Register(&stalledDatabasesCollector{reader: reader, now: time.Now})
+	if errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		return nil
+	}
+	return err
+}
+
+func (c *stalledDatabasesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- stalledDatabasesDesc
+}
+
+func (c *stalledDatabasesCollector) Collect(ch chan<- prometheus.Metric) {
+	databases := &databasesv1alpha1.DatabaseList{}
+	if err := c.reader.List(context.Background(), databases); err != nil {
+		log.Log.Error(err, "Failed to list Databases for the stalled Databases metric")
+		return
+	}
+
+	type key struct{ engine, phase string }
+	counts := map[key]int{}
+	now := c.now()
+	for i := range databases.Items {
+		database := &databases.Items[i]
+		if databaseStalled(database, now) {
+			counts[key{string(database.Spec.Type), string(database.Status.Phase)}]++
+		}
+	}
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(stalledDatabasesDesc, prometheus.GaugeValue, float64(count), k.engine, k.phase)
+	}
+}
+
+// databaseStalled reports whether a Database has been out of Ready, or never
+// became Ready, for longer than stalledDatabaseThreshold
+func databaseStalled(database *databasesv1alpha1.Database, now time.Time) bool {
+	since := database.CreationTimestamp.Time
+	if ready := meta.FindStatusCondition(database.Status.Conditions, conditionReady); ready != nil {
+		if ready.Status == metav1.ConditionTrue {
+			return false
+		}
+		since = ready.LastTransitionTime.Time
+	}
+	return now.Sub(since) > stalledDatabaseThreshold
+}
+
+// databaseMetricLabels returns the labels identifying a Database in every metric
+func databaseMetricLabels(database *databasesv1alpha1.Database) prometheus.Labels {
+	return prometheus.Labels{
+		"namespace": database.Namespace,
+		"database":  database.Name,
+	}
+}
+
+// deleteDatabaseMetrics removes all series reported for a Database
+func deleteDatabaseMetrics(database *databasesv1alpha1.Database) {
+	labels := databaseMetricLabels(database)
+	redisKeys.DeletePartialMatch(labels)
+	redisBiggestKeyBytes.DeletePartialMatch(labels)
+	elasticsearchShards.DeletePartialMatch(labels)
+	elasticsearchSmallShards.DeletePartialMatch(labels)
+	freezeUntil.DeletePartialMatch(labels)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	metricsExporterContainer = "metrics-exporter"
+	metricsPortName          = "metrics"
+)
+
+// metricsExporter is the Prometheus exporter sidecar of an engine. It reaches
+// the engine on localhost, as the monitoring user where the engine has one.
+type metricsExporter struct {
+	image string
+	port  int32
+	args  []string
+	// env connects the exporter to the engine
+	env func(database *databasesv1alpha1.Database) []corev1.EnvVar
+}
+
+var metricsExporters = map[databasesv1alpha1.DatabaseType]metricsExporter{
+	databasesv1alpha1.DatabaseTypePostgreSQL: {
+		image: "quay.io/prometheuscommunity/postgres-exporter:v0.15.0",
+		port:  9187,
+		env: func(database *databasesv1alpha1.Database) []corev1.EnvVar {
+			return []corev1.EnvVar{
+				{Name: "DATA_SOURCE_URI", Value: "localhost:5432/postgres?sslmode=disable"},
+				{Name: "DATA_SOURCE_USER", Value: monitoringUsername},
+				exporterPassword(database, "DATA_SOURCE_PASS"),
+			}
+		},
+	},
+	databasesv1alpha1.DatabaseTypeMongoDB: {
+		image: "percona/mongodb_exporter:0.40.0",
+		port:  9216,
+		args:  []string{"--collect-all"},
+		env: func(database *databasesv1alpha1.Database) []corev1.EnvVar {
+			return []corev1.EnvVar{
+				{Name: "MONGODB_URI", Value: "mongodb://localhost:27017/admin"},
+				{Name: "MONGODB_USER", Value: monitoringUsername},
+				exporterPassword(database, "MONGODB_PASSWORD"),
+			}
+		},
+	},
+	databasesv1alpha1.DatabaseTypeRedis: {
+		image: "oliver006/redis_exporter:v1.62.0",
+		port:  9121,
+		env: func(database *databasesv1alpha1.Database) []corev1.EnvVar {
+			return []corev1.EnvVar{
+				{Name: "REDIS_ADDR", Value: "redis://localhost:6379"},
+				{Name: "REDIS_USER", Value: monitoringUsername},
+				exporterPassword(database, "REDIS_PASSWORD"),
+			}
+		},
+	},
+	// Security is disabled on Elasticsearch, the exporter needs no user
+	databasesv1alpha1.DatabaseTypeElasticsearch: {
+		image: "quay.io/prometheuscommunity/elasticsearch-exporter:v1.7.0",
+		port:  9114,
+		args:  []string{"--es.uri=http://localhost:9200", "--es.all"},
+		env:   func(*databasesv1alpha1.Database) []corev1.EnvVar { return nil },
+	},
+}
+
+// exporterPassword reads the password of the monitoring user into variable name
+func exporterPassword(database *databasesv1alpha1.Database, name string) corev1.EnvVar {
+	password := monitoringPassword(database)
+	password.Name = name
+	return password
+}
+
+// engineMetricsExporter returns the exporter of a Database with metrics enabled
+func engineMetricsExporter(database *databasesv1alpha1.Database) (metricsExporter, bool) {
+	observability := database.Spec.Observability
+	if observability == nil || observability.Metrics == nil || !observability.Metrics.Enabled {
+		return metricsExporter{}, false
+	}
+	exporter, ok := metricsExporters[database.Spec.Type]
+	if ok && observability.Metrics.ExporterImage != "" {
+		exporter.image = observability.Metrics.ExporterImage
+	}
+	// Slow queries are reported from pg_stat_statements
+	if _, slowQuery := slowQueryThreshold(database); slowQuery && database.Spec.Type == databasesv1alpha1.DatabaseTypePostgreSQL {
+		exporter.args = append(slices.Clone(exporter.args), "--collector.stat_statements")
+	}
+	return exporter, ok
+}
+
+// addMetricsExporter adds the exporter sidecar to a pod template. It has no
+// readiness probe, so an exporter failing to start never takes the database
+// out of the Service.
+func addMetricsExporter(database *databasesv1alpha1.Database, template *corev1.PodTemplateSpec) {
+	exporter, ok := engineMetricsExporter(database)
+	if !ok {
+		return
+	}
+	template.Spec.Containers = append(template.Spec.Containers, corev1.Container{
+		Name:  metricsExporterContainer,
+		Image: exporter.image,
+		Args:  exporter.args,
+		Env:   exporter.env(database),
+		Ports: []corev1.ContainerPort{{Name: metricsPortName, ContainerPort: exporter.port, Protocol: corev1.ProtocolTCP}},
+	})
+}
+
+// metricsServicePort returns the Service port of the exporter
+func metricsServicePort(database *databasesv1alpha1.Database) (corev1.ServicePort, bool) {
+	exporter, ok := engineMetricsExporter(database)
+	if !ok {
+		return corev1.ServicePort{}, false
+	}
+	return corev1.ServicePort{
+		Name:       metricsPortName,
+		Port:       exporter.port,
+		TargetPort: intstr.FromString(metricsPortName),
+		Protocol:   corev1.ProtocolTCP,
+	}, true
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Metrics exporter", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Observability: &databasesv1alpha1.ObservabilitySpec{
+					Metrics: &databasesv1alpha1.MetricsSpec{Enabled: true},
+				},
+			},
+		}
+	})
+
+	It("should run the exporter as the monitoring user and expose its port", func() {
+		containers := reconciler.createPostgreSQLStatefulSet(database, 1, reconciler.getPostgreSQLEnv(database)).Spec.Template.Spec.Containers
+		Expect(containers).To(HaveLen(2))
+		exporter := containers[1]
+		Expect(exporter.Name).To(Equal(metricsExporterContainer))
+		Expect(exporter.Image).To(HavePrefix("quay.io/prometheuscommunity/postgres-exporter:"))
+		Expect(exporter.Env).To(ContainElement(corev1.EnvVar{Name: "DATA_SOURCE_USER", Value: "monitoring"}))
+		Expect(exporter.Env).To(ContainElement(corev1.EnvVar{Name: "DATA_SOURCE_PASS", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "orders-monitoring"}, Key: "password"},
+		}}))
+		Expect(exporter.ReadinessProbe).To(BeNil())
+
+		ports := reconciler.getServicePorts(database)
+		Expect(ports).To(HaveLen(2))
+		Expect(ports[1].Name).To(Equal("metrics"))
+		Expect(ports[1].Port).To(Equal(int32(9187)))
+	})
+
+	It("should follow the engine and the image override", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeElasticsearch
+		database.Spec.Observability.Metrics.ExporterImage = "registry.internal/elasticsearch-exporter:v1.7.0"
+		containers := reconciler.createElasticsearchStatefulSet(database, 1, reconciler.getElasticsearchEnv(database)).Spec.Template.Spec.Containers
+		Expect(containers[1].Image).To(Equal("registry.internal/elasticsearch-exporter:v1.7.0"))
+		Expect(containers[1].Args).To(ContainElement("--es.uri=http://localhost:9200"))
+		Expect(containers[1].Env).To(BeEmpty())
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeSQLite
+		Expect(reconciler.createSQLiteDeployment(database, 1, nil).Spec.Template.Spec.Containers).To(HaveLen(1))
+		Expect(reconciler.getServicePorts(database)).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Operator metrics", func() {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	database := func(name string, engine databasesv1alpha1.DatabaseType, phase databasesv1alpha1.DatabasePhase, created time.Duration, ready *metav1.Condition) *databasesv1alpha1.Database {
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", CreationTimestamp: metav1.NewTime(now.Add(-created))},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: engine},
+			Status:     databasesv1alpha1.DatabaseStatus{Phase: phase},
+		}
+		if ready != nil {
+			database.Status.Conditions = []metav1.Condition{*ready}
+		}
+		return database
+	}
+
+	readyCondition := func(status metav1.ConditionStatus, since time.Duration) *metav1.Condition {
+		return &metav1.Condition{Type: "Ready", Status: status, LastTransitionTime: metav1.NewTime(now.Add(-since))}
+	}
+
+	It("should count the Databases not Ready for longer than the threshold", func() {
+		scheme := runtime.NewScheme()
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			database("orders", databasesv1alpha1.DatabaseTypePostgreSQL, databasesv1alpha1.DatabasePhaseReady, 48*time.Hour, readyCondition(metav1.ConditionTrue, 24*time.Hour)),
+			database("invoices", databasesv1alpha1.DatabaseTypePostgreSQL, databasesv1alpha1.DatabasePhaseCreating, time.Hour, nil),
+			database("ledger", databasesv1alpha1.DatabaseTypePostgreSQL, databasesv1alpha1.DatabasePhaseCreating, 2*time.Hour, readyCondition(metav1.ConditionFalse, 20*time.Minute)),
+			database("sessions", databasesv1alpha1.DatabaseTypeRedis, databasesv1alpha1.DatabasePhaseFailed, 48*time.Hour, readyCondition(metav1.ConditionFalse, 5*time.Minute)),
+			database("cache", databasesv1alpha1.DatabaseTypeRedis, databasesv1alpha1.DatabasePhasePending, 10*time.Minute, nil),
+		).Build()
+
+		collector := &stalledDatabasesCollector{reader: c, now: func() time.Time { return now }}
+		Expect(testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP database_operator_stalled_databases Number of Databases not Ready for longer than 15 minutes, per engine and phase
+# TYPE database_operator_stalled_databases gauge
+database_operator_stalled_databases{engine="PostgreSQL",phase="Creating"} 2
+`))).To(Succeed())
+	})
+
+	It("should track reconciles in flight per engine", func() {
+		redis := database("cache", databasesv1alpha1.DatabaseTypeRedis, databasesv1alpha1.DatabasePhaseReady, time.Hour, nil)
+		done := observeReconcile(redis)
+		Expect(testutil.ToFloat64(reconcilesInFlight.WithLabelValues("Redis"))).To(Equal(1.0))
+		done()
+		Expect(testutil.ToFloat64(reconcilesInFlight.WithLabelValues("Redis"))).To(BeZero())
+		Expect(testutil.CollectAndCount(reconcileDuration, "database_operator_reconcile_duration_seconds")).To(BeNumerically(">=", 1))
+	})
+})
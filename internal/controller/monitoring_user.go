@@ -0,0 +1,184 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	monitoringComponent     = "monitoring"
+	monitoringUserComponent = "monitoring-user"
+	monitoringUsername      = "monitoring"
+	monitoringPasswordEnv   = "MONITORING_PASSWORD"
+	// monitoringSecretVersionAnnotation records on the Job which Secret version it applies
+	monitoringSecretVersionAnnotation = "databases.database-operator.io/monitoring-secret-version"
+)
+
+// redisMonitoringRules limit the Redis monitoring user to the introspection
+// commands of metrics exporters, without access to any key
+var redisMonitoringRules = []string{
+	"-@all", "+info", "+ping", "+config|get", "+client|list", "+slowlog|get", "+latency|latest", "+memory|stats", "+cluster|info",
+}
+
+// Scripts creating the monitoring user, or setting its password, from $MONITORING_PASSWORD
+var monitoringUserScripts = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: fmt.Sprintf(`psql -h "$DB_HOST" -v ON_ERROR_STOP=1 -v password="$%[2]s" <<'SQL'
+SELECT 'CREATE ROLE "%[1]s" LOGIN' WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '%[1]s')\gexec
+ALTER ROLE "%[1]s" LOGIN PASSWORD :'password' CONNECTION LIMIT 5;
+GRANT pg_monitor TO "%[1]s";
+SQL
+# The exporter reports slow queries from pg_stat_statements, in the database it connects to
+psql -h "$DB_HOST" -d postgres -v ON_ERROR_STOP=1 <<'SQL'
+SELECT 'CREATE EXTENSION IF NOT EXISTS pg_stat_statements'
+WHERE 'pg_stat_statements' = ANY(string_to_array(current_setting('shared_preload_libraries'), ','))\gexec
+SQL`, monitoringUsername, monitoringPasswordEnv),
+	databasesv1alpha1.DatabaseTypeMongoDB: fmt.Sprintf(`cat > /tmp/monitoring.js <<'JS'
+const admin = db.getSiblingDB("admin");
+const roles = [{role: "clusterMonitor", db: "admin"}, {role: "read", db: "local"}];
+if (admin.getUser("%[1]s")) admin.updateUser("%[1]s", {pwd: process.env.%[2]s, roles: roles});
+else admin.createUser({user: "%[1]s", pwd: process.env.%[2]s, roles: roles});
+JS
+mongosh --host "$DB_HOST" -u "$MONGO_USERNAME" -p "$MONGO_PASSWORD" --authenticationDatabase admin --quiet --file /tmp/monitoring.js`,
+		monitoringUsername, monitoringPasswordEnv),
+	// Every replica is set, ACL changes are not replicated
+	databasesv1alpha1.DatabaseTypeRedis: fmt.Sprintf(`set -e
+for host in $(getent hosts "$PEERS_HOST" | cut -d' ' -f1); do
+  redis-cli -h "$host" ACL SETUSER %s reset on ">$%s" %s
+done`, monitoringUsername, monitoringPasswordEnv, strings.Join(redisMonitoringRules, " ")),
+}
+
+// monitoringUserEnabled reports whether the Database has a monitoring user
+func monitoringUserEnabled(database *databasesv1alpha1.Database) bool {
+	observability := database.Spec.Observability
+	if observability == nil || observability.Metrics == nil || !observability.Metrics.Enabled {
+		return false
+	}
+	_, ok := monitoringUserScripts[database.Spec.Type]
+	return ok
+}
+
+func monitoringSecretName(database *databasesv1alpha1.Database) string {
+	return database.Name + "-" + monitoringComponent
+}
+
+// monitoringPassword reads the password of the monitoring user
+func monitoringPassword(database *databasesv1alpha1.Database) corev1.EnvVar {
+	return passwordEnv(monitoringPasswordEnv, &databasesv1alpha1.SecretReference{
+		Name: monitoringSecretName(database), Key: credentialsPasswordKey,
+	})
+}
+
+// addRedisMonitoringUser declares the monitoring user on the command line of
+// Redis, so restarted pods have it before the Job sets it again
+func addRedisMonitoringUser(database *databasesv1alpha1.Database, container *corev1.Container) {
+	container.Env = append(container.Env, monitoringPassword(database))
+	container.Args = append(container.Args, "--user", monitoringUsername, "on", ">$("+monitoringPasswordEnv+")")
+	container.Args = append(container.Args, redisMonitoringRules...)
+}
+
+// reconcileMonitoringUser generates the credentials of the monitoring user and
+// applies them through an admin Job whenever its Secret changes, so a password
+// rotated in the Secret reaches the database
+func (r *DatabaseReconciler) reconcileMonitoringUser(ctx context.Context, database *databasesv1alpha1.Database) error {
+	log := log.FromContext(ctx)
+
+	if !monitoringUserEnabled(database) {
+		database.Status.Monitoring = nil
+		return nil
+	}
+
+	secret, err := r.ensureGeneratedSecret(ctx, database, monitoringSecretName(database), monitoringComponent, monitoringUsername, true,
+		func() (string, error) { return randomPassword(generatedPasswordLength) })
+	if err != nil {
+		return err
+	}
+	if database.Status.Monitoring == nil {
+		database.Status.Monitoring = &databasesv1alpha1.MonitoringStatus{}
+	}
+	status := database.Status.Monitoring
+	status.CredentialsSecret = secret.Name
+
+	// Wait for a server accepting connections
+	if database.Status.ReadyReplicas == 0 || status.AppliedSecretVersion == secret.ResourceVersion {
+		return nil
+	}
+
+	job := &batchv1.Job{}
+	jobName := database.Name + "-" + monitoringUserComponent
+	err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: database.Namespace}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if err == nil {
+		version := job.Annotations[monitoringSecretVersionAnnotation]
+		finished, succeeded := jobFinished(job)
+		if version == secret.ResourceVersion && !finished {
+			return nil
+		}
+
+		// Remove finished Jobs and Jobs applying an outdated password; the Job
+		// deletion event triggers the next step
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if version == secret.ResourceVersion {
+			if !succeeded {
+				return fmt.Errorf("failed to set the monitoring user, see the logs of Job %s", jobName)
+			}
+			log.Info("Set the monitoring user", "secret", secret.Name)
+			status.AppliedSecretVersion = version
+			recordOperation(database, recordMonitoringUser, databasesv1alpha1.OperationSucceeded,
+				fmt.Sprintf("Set the password of the %s user from Secret %s", monitoringUsername, secret.Name), time.Now())
+		}
+		return nil
+	}
+
+	// Rotated passwords wait for the end of a release freeze
+	if status.AppliedSecretVersion != "" && frozen(database, time.Now()) {
+		log.Info("Password rotation deferred by the release freeze", "secret", secret.Name)
+		return nil
+	}
+
+	env := []corev1.EnvVar{monitoringPassword(database)}
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeRedis {
+		env = append(env, corev1.EnvVar{Name: "PEERS_HOST", Value: peersHost(database)})
+	}
+	job = r.createAdminJob(database, monitoringUserComponent, monitoringUserScripts[database.Spec.Type], env)
+	job.Annotations = map[string]string{monitoringSecretVersionAnnotation: secret.ResourceVersion}
+	if err := controllerutil.SetControllerReference(database, job, r.Scheme); err != nil {
+		return err
+	}
+
+	log.Info("Setting the monitoring user", "secret", secret.Name)
+	return r.Create(ctx, job)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Monitoring user", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	secretKey := types.NamespacedName{Name: "orders-monitoring", Namespace: "shop"}
+	jobKey := types.NamespacedName{Name: "orders-monitoring-user", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Observability: &databasesv1alpha1.ObservabilitySpec{
+					Metrics: &databasesv1alpha1.MetricsSpec{Enabled: true},
+				},
+			},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(database).Build(),
+			Scheme: scheme,
+		}
+	})
+
+	It("should grant the engine monitoring roles only", func() {
+		Expect(monitoringUserScripts[databasesv1alpha1.DatabaseTypePostgreSQL]).To(ContainSubstring(`GRANT pg_monitor TO "monitoring";`))
+		Expect(monitoringUserScripts[databasesv1alpha1.DatabaseTypeMongoDB]).To(ContainSubstring(
+			`[{role: "clusterMonitor", db: "admin"}, {role: "read", db: "local"}]`))
+		Expect(monitoringUserScripts[databasesv1alpha1.DatabaseTypeRedis]).To(ContainSubstring(
+			`ACL SETUSER monitoring reset on ">$MONITORING_PASSWORD" -@all +info +ping`))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeElasticsearch
+		Expect(monitoringUserEnabled(database)).To(BeFalse())
+	})
+
+	It("should declare the Redis user on the workload", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		container := reconciler.createRedisStatefulSet(database, 1, nil).Spec.Template.Spec.Containers[0]
+		Expect(container.Args[:4]).To(Equal([]string{"--user", "monitoring", "on", ">$(MONITORING_PASSWORD)"}))
+		Expect(container.Args[4:]).To(Equal(redisMonitoringRules))
+		Expect(container.Env).To(ContainElement(And(
+			HaveField("Name", monitoringPasswordEnv),
+			HaveField("ValueFrom.SecretKeyRef.Name", "orders-monitoring"))))
+
+		database.Spec.Observability = nil
+		container = reconciler.createRedisStatefulSet(database, 1, nil).Spec.Template.Spec.Containers[0]
+		Expect(container.Args).To(BeEmpty())
+	})
+
+	It("should set the password again whenever the Secret changes", func() {
+		Expect(reconciler.reconcileMonitoringUser(ctx, database)).To(Succeed())
+		secret := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, secretKey, secret)).To(Succeed())
+		Expect(string(secret.Data["username"])).To(Equal("monitoring"))
+		Expect(string(secret.Data["password"])).To(MatchRegexp("^[A-Za-z0-9]{32}$"))
+		Expect(database.Status.Monitoring.CredentialsSecret).To(Equal("orders-monitoring"))
+
+		// Nothing runs before the database accepts connections
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		database.Status.ReadyReplicas = 1
+		Expect(reconciler.reconcileMonitoringUser(ctx, database)).To(Succeed())
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue(monitoringSecretVersionAnnotation, secret.ResourceVersion))
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(And(
+			HaveField("Name", monitoringPasswordEnv),
+			HaveField("ValueFrom.SecretKeyRef.Key", "password"))))
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		Expect(reconciler.reconcileMonitoringUser(ctx, database)).To(Succeed())
+		Expect(database.Status.Monitoring.AppliedSecretVersion).To(Equal(secret.ResourceVersion))
+		Expect(database.Status.RecentOperations).To(ContainElement(HaveField("Type", recordMonitoringUser)))
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		By("applying a rotated password")
+		secret.Data["password"] = []byte("rotated")
+		Expect(reconciler.Update(ctx, secret)).To(Succeed())
+		Expect(reconciler.reconcileMonitoringUser(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue(monitoringSecretVersionAnnotation, secret.ResourceVersion))
+
+		By("reporting no user once metrics are disabled")
+		database.Spec.Observability.Metrics.Enabled = false
+		Expect(reconciler.reconcileMonitoringUser(ctx, database)).To(Succeed())
+		Expect(database.Status.Monitoring).To(BeNil())
+	})
+})
@@ -0,0 +1,325 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	jobsNetworkPolicySuffix     = "-jobs"
+	databaseNetworkPolicySuffix = "-database"
+	defaultS3Port               = 443
+	kmsPort                     = 443
+	elasticsearchTransportPort  = 9300
+)
+
+// operatorPodLabels select the pods of the operator, which call the database
+// for the admin API
+var operatorPodLabels = map[string]string{
+	"control-plane":          "controller-manager",
+	"app.kubernetes.io/name": "database-operator",
+}
+
+// networkPoliciesEnabled reports whether the operator generates NetworkPolicies
+func networkPoliciesEnabled(database *databasesv1alpha1.Database) bool {
+	networking := database.Spec.Networking
+	return networking != nil && networking.NetworkPolicy != nil && networking.NetworkPolicy.Enabled
+}
+
+// reconcileJobsNetworkPolicy restricts the pods of operator-created Jobs
+func (r *DatabaseReconciler) reconcileJobsNetworkPolicy(ctx context.Context, database *databasesv1alpha1.Database) error {
+	var desired *networkingv1.NetworkPolicy
+	if networkPoliciesEnabled(database) {
+		desired = r.createJobsNetworkPolicy(database)
+	}
+	return r.reconcileNetworkPolicy(ctx, database, database.Name+jobsNetworkPolicySuffix, desired)
+}
+
+// reconcileDatabaseNetworkPolicy restricts the pods of the database
+func (r *DatabaseReconciler) reconcileDatabaseNetworkPolicy(ctx context.Context, database *databasesv1alpha1.Database) error {
+	var desired *networkingv1.NetworkPolicy
+	if networkPoliciesEnabled(database) {
+		desired = r.createDatabaseNetworkPolicy(database)
+	}
+	return r.reconcileNetworkPolicy(ctx, database, database.Name+databaseNetworkPolicySuffix, desired)
+}
+
+// reconcileNetworkPolicy creates, updates or deletes a NetworkPolicy
+func (r *DatabaseReconciler) reconcileNetworkPolicy(ctx context.Context, database *databasesv1alpha1.Database, name string, desired *networkingv1.NetworkPolicy) error {
+	log := log.FromContext(ctx)
+
+	policy := &networkingv1.NetworkPolicy{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, policy)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if desired == nil {
+		if err == nil {
+			log.Info("Deleting NetworkPolicy", "name", name)
+			if err := r.Delete(ctx, policy); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if errors.IsNotFound(err) {
+		log.Info("Creating NetworkPolicy", "name", name)
+	} else if equality.Semantic.DeepEqual(desired.Spec, policy.Spec) {
+		return nil
+	}
+
+	if err := controllerutil.SetControllerReference(database, desired, r.Scheme); err != nil {
+		return err
+	}
+	return r.apply(ctx, desired)
+}
+
+// createJobsNetworkPolicy builds the policy of the Job pods, the pods of the
+// instance carrying one of the jobComponents. They accept no traffic and may
+// only reach the database, DNS, S3, the KMS encrypting backups and their proxy.
+func (r *DatabaseReconciler) createJobsNetworkPolicy(database *databasesv1alpha1.Database) *networkingv1.NetworkPolicy {
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{
+			To: []networkingv1.NetworkPolicyPeer{
+				{PodSelector: &metav1.LabelSelector{MatchLabels: r.getLabels(database)}},
+			},
+			Ports: r.databasePolicyPorts(database),
+		},
+		dnsEgressRule(),
+	}
+
+	if rule := s3EgressRule(database); rule != nil {
+		egress = append(egress, *rule)
+	}
+	if rule := kmsEgressRule(database); rule != nil {
+		egress = append(egress, *rule)
+	}
+	egress = append(egress, r.proxyEgressRules(database)...)
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      database.Name + jobsNetworkPolicySuffix,
+			Namespace: database.Namespace,
+			Labels:    r.getComponentLabels(database, "jobs"),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/instance":   database.Name,
+					"app.kubernetes.io/managed-by": "database-operator",
+				},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app.kubernetes.io/component", Operator: metav1.LabelSelectorOpIn, Values: slices.Clone(jobComponents)},
+				},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Egress:      egress,
+		},
+	}
+}
+
+// createDatabaseNetworkPolicy builds the policy of the database pods. Clients,
+// operator Jobs and the operator reach the database ports, and so does any
+// address when the Service is exposed; peers additionally
+// reach the replication ports. The pods may only reach their peers, DNS, the
+// S3 endpoints wal-g and pgBackRest archive to, the KMS and proxy of wal-g, and
+// the output of the log shipping sidecar.
+func (r *DatabaseReconciler) createDatabaseNetworkPolicy(database *databasesv1alpha1.Database) *networkingv1.NetworkPolicy {
+	peers := []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{MatchLabels: r.getLabels(database)}},
+	}
+	clients := []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+			"app.kubernetes.io/instance":   database.Name,
+			"app.kubernetes.io/managed-by": "database-operator",
+		}}},
+		{
+			NamespaceSelector: &metav1.LabelSelector{},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: operatorPodLabels},
+		},
+	}
+	policy := database.Spec.Networking.NetworkPolicy
+	if len(policy.AllowedNamespaces) == 0 && len(policy.PodSelectors) == 0 {
+		clients = append(clients, networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{}})
+	}
+	if len(policy.AllowedNamespaces) > 0 {
+		clients = append(clients, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      corev1.LabelMetadataName,
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   policy.AllowedNamespaces,
+				}},
+			},
+		})
+	}
+	for i := range policy.PodSelectors {
+		clients = append(clients, networkingv1.NetworkPolicyPeer{PodSelector: &policy.PodSelectors[i]})
+	}
+	// An exposed Service accepts clients from outside the cluster
+	if serviceExposed(database) {
+		clients = append(clients,
+			networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}},
+			networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: "::/0"}},
+		)
+	}
+
+	replicationPorts := r.replicationPolicyPorts(database)
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{To: peers, Ports: replicationPorts},
+		dnsEgressRule(),
+	}
+	if rule := s3EgressRule(database); rule != nil {
+		egress = append(egress, *rule)
+	}
+	if rule := kmsEgressRule(database); rule != nil && walArchivingEnabled(database) {
+		egress = append(egress, *rule)
+	}
+	// wal-g archives through the proxy of the Jobs
+	if walArchivingEnabled(database) {
+		egress = append(egress, r.proxyEgressRules(database)...)
+	}
+	if rule := logShippingEgressRule(database); rule != nil {
+		egress = append(egress, *rule)
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      database.Name + databaseNetworkPolicySuffix,
+			Namespace: database.Namespace,
+			Labels:    r.getLabels(database),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: r.getLabels(database)},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{From: clients, Ports: r.databasePolicyPorts(database)},
+				{From: peers, Ports: replicationPorts},
+			},
+			Egress: egress,
+		},
+	}
+}
+
+// replicationPolicyPorts returns the ports database pods reach each other on:
+// the database ports, and the transport port of Elasticsearch
+func (r *DatabaseReconciler) replicationPolicyPorts(database *databasesv1alpha1.Database) []networkingv1.NetworkPolicyPort {
+	ports := r.databasePolicyPorts(database)
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeElasticsearch {
+		tcp, transport := corev1.ProtocolTCP, intstr.FromInt(elasticsearchTransportPort)
+		ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &transport})
+	}
+	return ports
+}
+
+// dnsEgressRule allows DNS lookups
+func dnsEgressRule() networkingv1.NetworkPolicyEgressRule {
+	tcp, udp := corev1.ProtocolTCP, corev1.ProtocolUDP
+	dns := intstr.FromInt(53)
+	return networkingv1.NetworkPolicyEgressRule{
+		Ports: []networkingv1.NetworkPolicyPort{
+			{Protocol: &udp, Port: &dns},
+			{Protocol: &tcp, Port: &dns},
+		},
+	}
+}
+
+// s3EgressRule allows the ports of the backup S3 endpoints, which have no
+// stable address, or returns nil without S3 destinations
+func s3EgressRule(database *databasesv1alpha1.Database) *networkingv1.NetworkPolicyEgressRule {
+	tcp := corev1.ProtocolTCP
+	ports := []networkingv1.NetworkPolicyPort{}
+	seen := map[int]bool{}
+	for _, destination := range backupS3Destinations(database) {
+		port := intstr.FromInt(s3Port(&destination))
+		if !seen[port.IntValue()] {
+			seen[port.IntValue()] = true
+			ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &port})
+		}
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+	return &networkingv1.NetworkPolicyEgressRule{
+		To: []networkingv1.NetworkPolicyPeer{
+			{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}},
+			{IPBlock: &networkingv1.IPBlock{CIDR: "::/0"}},
+		},
+		Ports: ports,
+	}
+}
+
+// kmsEgressRule allows HTTPS to the KMS encrypting backups, whose endpoints
+// have no stable address, or returns nil without backup encryption
+func kmsEgressRule(database *databasesv1alpha1.Database) *networkingv1.NetworkPolicyEgressRule {
+	if backupEncryption(database) == nil {
+		return nil
+	}
+	tcp, port := corev1.ProtocolTCP, intstr.FromInt(kmsPort)
+	return &networkingv1.NetworkPolicyEgressRule{
+		To: []networkingv1.NetworkPolicyPeer{
+			{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}},
+			{IPBlock: &networkingv1.IPBlock{CIDR: "::/0"}},
+		},
+		Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}},
+	}
+}
+
+// databasePolicyPorts returns the database Service ports as NetworkPolicy ports
+func (r *DatabaseReconciler) databasePolicyPorts(database *databasesv1alpha1.Database) []networkingv1.NetworkPolicyPort {
+	ports := []networkingv1.NetworkPolicyPort{}
+	for _, servicePort := range r.getServicePorts(database) {
+		protocol, port := servicePort.Protocol, servicePort.TargetPort
+		ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port})
+	}
+	return ports
+}
+
+// s3Port returns the port of the S3 endpoint, HTTPS unless the endpoint says otherwise
+func s3Port(s3 *databasesv1alpha1.S3Destination) int {
+	endpoint, err := url.Parse(s3.Endpoint)
+	if s3.Endpoint == "" || err != nil {
+		return defaultS3Port
+	}
+	if port, err := strconv.Atoi(endpoint.Port()); err == nil {
+		return port
+	}
+	if endpoint.Scheme == "http" {
+		return 80
+	}
+	return defaultS3Port
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Job NetworkPolicy", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build(), Scheme: scheme}
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type: databasesv1alpha1.DatabaseTypePostgreSQL,
+				Backup: &databasesv1alpha1.BackupSpec{
+					Enabled: true,
+					S3:      &databasesv1alpha1.S3Destination{Bucket: "archive", Endpoint: "http://minio.minio:9000"},
+				},
+				Networking: &databasesv1alpha1.NetworkingSpec{
+					NetworkPolicy: &databasesv1alpha1.NetworkPolicySpec{Enabled: true},
+				},
+			},
+		}
+	})
+
+	It("should select Job pods but not database pods or other components", func() {
+		policy := reconciler.createJobsNetworkPolicy(database)
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(selector.Matches(labels.Set(reconciler.getComponentLabels(database, backupComponent)))).To(BeTrue())
+		Expect(selector.Matches(labels.Set(reconciler.getLabels(database)))).To(BeFalse())
+		Expect(selector.Matches(labels.Set(reconciler.getComponentLabels(database, credentialsComponent)))).To(BeFalse())
+
+		// Jobs of schedules and of other resources label their pods with their kind
+		for _, component := range []string{"backup-nightly", "user-reporting", "ops-restart", bootstrapComponent} {
+			template := reconciler.adminPodTemplate(database, component, "postgres:16", "true", nil)
+			Expect(selector.Matches(labels.Set(template.Labels))).To(BeTrue(), component)
+		}
+		Expect(reconciler.adminPodTemplate(database, "user-reporting", "postgres:16", "true", nil).Labels).
+			To(HaveKeyWithValue("app.kubernetes.io/component", "user"))
+	})
+
+	It("should deny ingress and allow egress to the database, DNS and S3 only", func() {
+		policy := reconciler.createJobsNetworkPolicy(database)
+
+		Expect(policy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+		Expect(policy.Spec.Ingress).To(BeEmpty())
+		Expect(policy.Spec.Egress).To(HaveLen(3))
+		Expect(policy.Spec.Egress[0].To[0].PodSelector.MatchLabels).To(Equal(reconciler.getLabels(database)))
+		Expect(policy.Spec.Egress[0].Ports[0].Port.IntValue()).To(Equal(5432))
+		Expect(policy.Spec.Egress[1].Ports[0].Port.IntValue()).To(Equal(53))
+		Expect(policy.Spec.Egress[2].Ports[0].Port.IntValue()).To(Equal(9000))
+
+		database.Spec.Backup.S3 = nil
+		Expect(reconciler.createJobsNetworkPolicy(database).Spec.Egress).To(HaveLen(2))
+	})
+
+	It("should let the Jobs reach the KMS encrypting backups", func() {
+		database.Spec.Backup.Encryption = &databasesv1alpha1.BackupEncryption{
+			KMSKeyID: "arn:aws:kms:eu-west-1:123456789012:key/orders",
+		}
+
+		egress := reconciler.createJobsNetworkPolicy(database).Spec.Egress
+		Expect(egress).To(HaveLen(4))
+		Expect(egress[3].To[0].IPBlock.CIDR).To(Equal("0.0.0.0/0"))
+		Expect(egress[3].Ports).To(HaveLen(1))
+		Expect(egress[3].Ports[0].Port.IntValue()).To(Equal(443))
+	})
+
+	It("should delete the policy when the feature is disabled", func() {
+		ctx := context.Background()
+		key := types.NamespacedName{Name: "orders-jobs", Namespace: "shop"}
+
+		Expect(reconciler.reconcileJobsNetworkPolicy(ctx, database)).To(Succeed())
+		Expect(reconciler.Get(ctx, key, &networkingv1.NetworkPolicy{})).To(Succeed())
+
+		database.Spec.Networking = nil
+		Expect(reconciler.reconcileJobsNetworkPolicy(ctx, database)).To(Succeed())
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, key, &networkingv1.NetworkPolicy{}))).To(BeTrue())
+	})
+})
+
+var _ = Describe("Database NetworkPolicy", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "search", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type: databasesv1alpha1.DatabaseTypeElasticsearch,
+				Networking: &databasesv1alpha1.NetworkingSpec{
+					NetworkPolicy: &databasesv1alpha1.NetworkPolicySpec{Enabled: true},
+				},
+			},
+		}
+	})
+
+	It("should select database pods and let peers reach the transport port", func() {
+		policy := reconciler.createDatabaseNetworkPolicy(database)
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.Matches(labels.Set(reconciler.getLabels(database)))).To(BeTrue())
+		Expect(selector.Matches(labels.Set(reconciler.getComponentLabels(database, backupComponent)))).To(BeFalse())
+
+		Expect(policy.Spec.Ingress).To(HaveLen(2))
+		Expect(policy.Spec.Ingress[0].Ports).To(HaveLen(1))
+		Expect(policy.Spec.Ingress[0].Ports[0].Port.IntValue()).To(Equal(9200))
+		Expect(policy.Spec.Ingress[1].From[0].PodSelector.MatchLabels).To(Equal(reconciler.getLabels(database)))
+		Expect(policy.Spec.Ingress[1].Ports[1].Port.IntValue()).To(Equal(9300))
+		Expect(policy.Spec.Egress[0].Ports[1].Port.IntValue()).To(Equal(9300))
+		Expect(policy.Spec.Egress[1].Ports[0].Port.IntValue()).To(Equal(53))
+	})
+
+	It("should only admit the allowed clients besides the operator", func() {
+		// Without allowed clients the pods of the namespace connect
+		clients := reconciler.createDatabaseNetworkPolicy(database).Spec.Ingress[0].From
+		Expect(clients).To(HaveLen(3))
+		Expect(clients[1].PodSelector.MatchLabels).To(Equal(operatorPodLabels))
+		Expect(clients[2].PodSelector).To(Equal(&metav1.LabelSelector{}))
+		Expect(clients[2].NamespaceSelector).To(BeNil())
+
+		database.Spec.Networking.NetworkPolicy.AllowedNamespaces = []string{"storefront"}
+		database.Spec.Networking.NetworkPolicy.PodSelectors = []metav1.LabelSelector{
+			{MatchLabels: map[string]string{"app": "indexer"}},
+		}
+		clients = reconciler.createDatabaseNetworkPolicy(database).Spec.Ingress[0].From
+		Expect(clients).To(HaveLen(4))
+		Expect(clients[2].PodSelector).To(BeNil())
+		Expect(clients[2].NamespaceSelector.MatchExpressions[0].Values).To(Equal([]string{"storefront"}))
+		Expect(clients[3].PodSelector.MatchLabels).To(Equal(map[string]string{"app": "indexer"}))
+
+		// An exposed Service admits clients from outside the cluster
+		database.Spec.Networking.ServiceType = corev1.ServiceTypeLoadBalancer
+		clients = reconciler.createDatabaseNetworkPolicy(database).Spec.Ingress[0].From
+		Expect(clients).To(HaveLen(6))
+		Expect(clients[4].IPBlock.CIDR).To(Equal("0.0.0.0/0"))
+	})
+})
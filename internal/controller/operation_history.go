@@ -0,0 +1,82 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// maxRecentOperations bounds status.recentOperations, matching the MaxItems
+// validation of the field
+const maxRecentOperations = 20
+
+// Types of the recorded operations
+const (
+	recordProvision          = "Provision"
+	recordImageResolution    = "ImageResolution"
+	recordScale              = disruptiveOperationScale
+	recordUpdate             = disruptiveOperationUpdate
+	recordVerticalScale      = disruptiveOperationVerticalScale
+	recordMajorUpgrade       = disruptiveOperationMajorUpgrade
+	recordStorageMigration   = disruptiveOperationStorageMigration
+	recordAutoUpgrade        = "AutoUpgrade"
+	recordBootstrap          = "Bootstrap"
+	recordMonitoringUser     = "MonitoringUser"
+	recordRotation           = disruptiveOperationRotation
+	recordBackup             = "Backup"
+	recordBackupVerification = "BackupVerification"
+	recordRestore            = disruptiveOperationRestore
+	recordOpsRequest         = disruptiveOperationOpsRequest
+)
+
+// recordOperation appends an operation to the history of the Database, dropping
+// the oldest entries beyond maxRecentOperations
+func recordOperation(database *databasesv1alpha1.Database, operation string, outcome databasesv1alpha1.OperationOutcome, detail string, now time.Time) {
+	history := append(database.Status.RecentOperations, databasesv1alpha1.OperationRecord{
+		Type:    operation,
+		Time:    metav1.NewTime(now),
+		Outcome: outcome,
+		Detail:  detail,
+	})
+	if len(history) > maxRecentOperations {
+		history = history[len(history)-maxRecentOperations:]
+	}
+	database.Status.RecentOperations = history
+}
+
+// recordDatabaseOperation appends an operation to the history of a Database
+// owned by another controller, retrying on conflicts with the Database controller
+func recordDatabaseOperation(ctx context.Context, c client.Client, key types.NamespacedName, operation string, outcome databasesv1alpha1.OperationOutcome, detail string) error {
+	now := time.Now()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		database := &databasesv1alpha1.Database{}
+		if err := c.Get(ctx, key, database); err != nil {
+			return err
+		}
+		recordOperation(database, operation, outcome, detail, now)
+		return c.Status().Update(ctx, database)
+	})
+	return client.IgnoreNotFound(err)
+}
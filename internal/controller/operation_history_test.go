@@ -0,0 +1,99 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Operation history", func() {
+	It("should keep the last operations, oldest first", func() {
+		database := &databasesv1alpha1.Database{}
+		start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := range maxRecentOperations + 5 {
+			recordOperation(database, recordScale, databasesv1alpha1.OperationSucceeded,
+				fmt.Sprintf("Scaled to %d replicas", i), start.Add(time.Duration(i)*time.Minute))
+		}
+
+		history := database.Status.RecentOperations
+		Expect(history).To(HaveLen(maxRecentOperations))
+		Expect(history[0].Detail).To(Equal("Scaled to 5 replicas"))
+		Expect(history[maxRecentOperations-1].Detail).To(Equal(fmt.Sprintf("Scaled to %d replicas", maxRecentOperations+4)))
+		Expect(history[0].Time.Before(&history[1].Time)).To(BeTrue())
+	})
+
+	It("should record a scale once the StatefulSet runs the requested replicas", func() {
+		database := &databasesv1alpha1.Database{}
+		Expect(acquireOperation(database, disruptiveOperationScale, time.Now())).To(BeTrue())
+		statefulSet := &appsv1.StatefulSet{
+			Spec:   appsv1.StatefulSetSpec{Replicas: ptr.To(int32(3))},
+			Status: appsv1.StatefulSetStatus{Replicas: 2},
+		}
+		reconciler := &DatabaseReconciler{}
+
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 3)).To(Succeed())
+		Expect(database.Status.RecentOperations).To(BeEmpty())
+
+		statefulSet.Status.Replicas = 3
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 3)).To(Succeed())
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 3)).To(Succeed())
+		Expect(database.Status.RecentOperations).To(ConsistOf(And(
+			HaveField("Type", recordScale),
+			HaveField("Outcome", databasesv1alpha1.OperationSucceeded),
+			HaveField("Detail", "Scaled to 3 replicas"),
+		)))
+	})
+
+	It("should record operations of other controllers on the Database", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}).
+			WithObjects(&databasesv1alpha1.Database{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"}}).
+			Build()
+		ctx := context.Background()
+		key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+
+		Expect(recordDatabaseOperation(ctx, c, key, recordBackup, databasesv1alpha1.OperationFailed, "Backup nightly failed")).To(Succeed())
+		database := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key, database)).To(Succeed())
+		Expect(database.Status.RecentOperations).To(ConsistOf(And(
+			HaveField("Type", recordBackup),
+			HaveField("Outcome", databasesv1alpha1.OperationFailed),
+		)))
+
+		By("ignoring deleted Databases")
+		missing := types.NamespacedName{Name: "gone", Namespace: "shop"}
+		Expect(recordDatabaseOperation(ctx, c, missing, recordBackup, databasesv1alpha1.OperationSucceeded, "")).To(Succeed())
+	})
+})
@@ -0,0 +1,134 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// Disruptive operations serialized per Database. The lock lives in
+// status.operations, so it survives operator restarts and is visible to users.
+const (
+	disruptiveOperationScale   = "Scale"
+	disruptiveOperationRestore = "Restore"
+	// disruptiveOperationRotation changes the administrative password
+	disruptiveOperationRotation = "CredentialRotation"
+	// disruptiveOperationUpdate rolls the pods to a changed pod template
+	disruptiveOperationUpdate = "Update"
+	// disruptiveOperationMajorUpgrade converts the data volumes to a new major
+	disruptiveOperationMajorUpgrade = "MajorUpgrade"
+	// disruptiveOperationVerticalScale restarts the replicas one at a time on
+	// changed resources
+	disruptiveOperationVerticalScale = "VerticalScale"
+	// disruptiveOperationStorageMigration copies the data volumes to a new
+	// storage class
+	disruptiveOperationStorageMigration = "StorageMigration"
+	// disruptiveOperationOpsRequest restarts, fails over, compacts or resyncs
+	// the Database as a DatabaseOpsRequest asks
+	disruptiveOperationOpsRequest = "OpsRequest"
+)
+
+// acquireOperation reports whether the named disruptive operation may run now. An
+// operation that cannot run is queued; only the head of the queue acquires the lock
+// once it is free. Operations must call releaseOperation when they complete or are
+// no longer needed, otherwise they block the queue.
+func acquireOperation(database *databasesv1alpha1.Database, name string, now time.Time) bool {
+	ops := database.Status.Operations
+	if ops == nil {
+		ops = &databasesv1alpha1.OperationsStatus{}
+		database.Status.Operations = ops
+	}
+
+	if ops.Active != nil && ops.Active.Name == name {
+		return true
+	}
+
+	if ops.Active != nil || (len(ops.Pending) > 0 && ops.Pending[0] != name) {
+		if !slices.Contains(ops.Pending, name) {
+			ops.Pending = append(ops.Pending, name)
+		}
+		return false
+	}
+
+	ops.Pending = slices.DeleteFunc(ops.Pending, func(pending string) bool { return pending == name })
+	ops.Active = &databasesv1alpha1.ActiveOperation{Name: name, StartedAt: metav1.NewTime(now)}
+	return true
+}
+
+// releaseOperation releases the lock held by the named operation, or removes it
+// from the queue
+func releaseOperation(database *databasesv1alpha1.Database, name string) {
+	ops := database.Status.Operations
+	if ops == nil {
+		return
+	}
+
+	if ops.Active != nil && ops.Active.Name == name {
+		ops.Active = nil
+	}
+	ops.Pending = slices.DeleteFunc(ops.Pending, func(pending string) bool { return pending == name })
+	if len(ops.Pending) == 0 {
+		ops.Pending = nil
+	}
+
+	if ops.Active == nil && ops.Pending == nil {
+		database.Status.Operations = nil
+	}
+}
+
+// activeOperation returns the name of the running disruptive operation, if any
+func activeOperation(database *databasesv1alpha1.Database) string {
+	if ops := database.Status.Operations; ops != nil && ops.Active != nil {
+		return ops.Active.Name
+	}
+	return ""
+}
+
+// workloadStopped reports whether the running operation keeps the workload down
+func workloadStopped(database *databasesv1alpha1.Database) bool {
+	ops := database.Status.Operations
+	return ops != nil && ops.Active != nil && ops.Active.StopsWorkload
+}
+
+// updateDatabaseOperations applies a change to the operation lock or history of
+// a Database from another controller, retrying on conflicts with the Database
+// controller
+func updateDatabaseOperations(ctx context.Context, c client.Client, key types.NamespacedName, update func(*databasesv1alpha1.Database)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		database := &databasesv1alpha1.Database{}
+		if err := c.Get(ctx, key, database); err != nil {
+			return err
+		}
+
+		original := database.Status.DeepCopy()
+		update(database)
+		if equality.Semantic.DeepEqual(original, &database.Status) {
+			return nil
+		}
+		return c.Status().Update(ctx, database)
+	})
+}
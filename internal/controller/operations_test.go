@@ -0,0 +1,65 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Operation lock", func() {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	It("should run one disruptive operation at a time in queue order", func() {
+		database := &databasesv1alpha1.Database{}
+
+		Expect(acquireOperation(database, "Upgrade", now)).To(BeTrue())
+		Expect(acquireOperation(database, "Upgrade", now)).To(BeTrue())
+		Expect(acquireOperation(database, "Restore", now)).To(BeFalse())
+		Expect(acquireOperation(database, "Rotation", now)).To(BeFalse())
+		Expect(acquireOperation(database, "Restore", now)).To(BeFalse())
+		Expect(database.Status.Operations.Pending).To(Equal([]string{"Restore", "Rotation"}))
+
+		releaseOperation(database, "Upgrade")
+		Expect(activeOperation(database)).To(BeEmpty())
+
+		// The lock goes to the head of the queue only
+		Expect(acquireOperation(database, "Rotation", now)).To(BeFalse())
+		Expect(acquireOperation(database, "Restore", now)).To(BeTrue())
+		Expect(activeOperation(database)).To(Equal("Restore"))
+		Expect(database.Status.Operations.Pending).To(Equal([]string{"Rotation"}))
+
+		releaseOperation(database, "Restore")
+		Expect(acquireOperation(database, "Rotation", now)).To(BeTrue())
+		releaseOperation(database, "Rotation")
+		Expect(database.Status.Operations).To(BeNil())
+	})
+
+	It("should drop queued operations that are no longer needed", func() {
+		database := &databasesv1alpha1.Database{}
+		Expect(acquireOperation(database, "Upgrade", now)).To(BeTrue())
+		Expect(acquireOperation(database, "Restore", now)).To(BeFalse())
+
+		releaseOperation(database, "Restore")
+		Expect(database.Status.Operations.Pending).To(BeEmpty())
+		Expect(activeOperation(database)).To(Equal("Upgrade"))
+	})
+})
@@ -0,0 +1,114 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	conditionPaused = "Paused"
+
+	reasonPaused  = "Paused"
+	reasonResumed = "Resumed"
+)
+
+// paused reports whether the paused annotation stops the changes of the
+// operator to the child resources
+func paused(database *databasesv1alpha1.Database) bool {
+	return database.Annotations[databasesv1alpha1.PausedAnnotation] == "true"
+}
+
+// reconcilePaused only reports the status of a paused Database: the ready
+// replicas of its workloads and the health of the engine, which is checked
+// again every healthCheckInterval. Nothing is created, updated or deleted, so
+// spec changes, schedules and operations wait until the Database is resumed.
+func (r *DatabaseReconciler) reconcilePaused(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) (ctrl.Result, error) {
+	originalStatus := database.Status.DeepCopy()
+	if !meta.IsStatusConditionTrue(database.Status.Conditions, conditionPaused) {
+		log.FromContext(ctx).Info("Pausing reconciliation")
+		r.event(database, corev1.EventTypeNormal, reasonPaused,
+			"Reconciliation is paused, the child resources are left as they are")
+	}
+	setCondition(database, conditionPaused, metav1.ConditionTrue, reasonPaused,
+		fmt.Sprintf("Annotation %s=true stops the operator from changing the child resources", databasesv1alpha1.PausedAnnotation))
+
+	if err := r.observeReadyReplicas(ctx, database); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.checkHealth(ctx, database, now)
+	reportHealth(database, now)
+	if message, unhealthy := databaseUnhealthy(database); unhealthy {
+		setCondition(database, conditionReady, metav1.ConditionFalse, reasonHealthCheckFailed, message)
+	}
+
+	if !equality.Semantic.DeepEqual(originalStatus, &database.Status) {
+		if err := r.Status().Update(ctx, database); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to update Database status")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: healthCheckInterval}, nil
+}
+
+// resume drops the Paused condition once the annotation was removed
+func (r *DatabaseReconciler) resume(ctx context.Context, database *databasesv1alpha1.Database) {
+	if meta.FindStatusCondition(database.Status.Conditions, conditionPaused) == nil {
+		return
+	}
+	log.FromContext(ctx).Info("Resuming reconciliation")
+	meta.RemoveStatusCondition(&database.Status.Conditions, conditionPaused)
+	r.event(database, corev1.EventTypeNormal, reasonResumed, "Reconciliation resumed")
+}
+
+// observeReadyReplicas reads the ready replicas of the workloads without
+// changing them
+func (r *DatabaseReconciler) observeReadyReplicas(ctx context.Context, database *databasesv1alpha1.Database) error {
+	var ready int32
+	for _, resource := range workloadResources(database) {
+		key := types.NamespacedName{Name: resource.Name, Namespace: database.Namespace}
+		var err error
+		if resource.Kind == "Deployment" {
+			deployment := &appsv1.Deployment{}
+			err = r.Get(ctx, key, deployment)
+			ready += deployment.Status.ReadyReplicas
+		} else {
+			statefulSet := &appsv1.StatefulSet{}
+			err = r.Get(ctx, key, statefulSet)
+			ready += statefulSet.Status.ReadyReplicas
+		}
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	database.Status.ReadyReplicas = ready
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Paused reconciliation", func() {
+	It("should report the status of a paused Database without changing its child resources", func() {
+		ctx := context.Background()
+		key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "orders",
+				Namespace:   "shop",
+				Annotations: map[string]string{databasesv1alpha1.PausedAnnotation: "true"},
+			},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypeMongoDB,
+				Version:  "7.0",
+				Replicas: ptr.To(int32(3)),
+			},
+		}
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler := &DatabaseReconciler{Scheme: scheme}
+		statefulSet := reconciler.createMongoDBStatefulSet(database, 1, nil)
+		statefulSet.Status.ReadyReplicas = 1
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}).
+			WithInterceptorFuncs(applyPatches()).
+			WithObjects(database, statefulSet).Build()
+
+		reconcile := func() *databasesv1alpha1.Database {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reconciler.Get(ctx, key, database)).To(Succeed())
+			return database
+		}
+
+		reconcile()
+		Expect(database.Status.ReadyReplicas).To(Equal(int32(1)))
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, conditionPaused)).To(BeTrue())
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal Paused")))
+		Expect(reconciler.Get(ctx, key, statefulSet)).To(Succeed())
+		Expect(*statefulSet.Spec.Replicas).To(Equal(int32(1)))
+		err := reconciler.Get(ctx, types.NamespacedName{Name: "orders-service", Namespace: "shop"}, &corev1.Service{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		By("applying the spec once resumed")
+		delete(database.Annotations, databasesv1alpha1.PausedAnnotation)
+		Expect(reconciler.Update(ctx, database)).To(Succeed())
+		reconcile()
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionPaused)).To(BeNil())
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal Resumed")))
+		Expect(reconciler.Get(ctx, key, &appsv1.StatefulSet{})).To(Succeed())
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders-service", Namespace: "shop"}, &corev1.Service{})).To(Succeed())
+	})
+})
@@ -0,0 +1,286 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	pgBackRestContainer    = "pgbackrest"
+	pgBackRestStanza       = "db"
+	pgBackRestSocketVolume = "pgbackrest-socket"
+	// pgBackRest only reaches the local PostgreSQL through its socket
+	postgresSocketDir = "/var/run/postgresql"
+
+	defaultFullBackupInterval         = 7 * 24 * time.Hour
+	defaultDifferentialBackupInterval = 24 * time.Hour
+	defaultIncrementalBackupInterval  = time.Hour
+)
+
+// pgBackRestScript creates the stanza once PostgreSQL accepts connections, then
+// takes a backup every INCR_INTERVAL seconds, its type chosen from the start
+// times of the last full and differential backups found in the repository
+// labels (<yyyymmdd>-<hhmmss>F, ..._<yyyymmdd>-<hhmmss>D or I). Expired backups
+// are removed by pgBackRest after each backup.
+const pgBackRestScript = `until pg_isready -h localhost -q; do sleep 5; done
+pgbackrest stanza-create || echo "stanza-create failed" >&2
+last_backup() {
+  label="$(pgbackrest info --output=json | grep -o "\"label\":\"[0-9FD_-]*$1\"" | tail -n 1)"
+  time="$(echo "$label" | sed -n 's/.*\([0-9]\{8\}\)-\([0-9][0-9]\)\([0-9][0-9]\)\([0-9][0-9]\)[FD]"$/\1 \2:\3:\4/p')"
+  if [ -n "$time" ]; then date -u -d "$time" +%s; else echo 0; fi
+}
+while true; do
+  now=$(date +%s)
+  full=$(last_backup F)
+  diff=$(last_backup D)
+  [ "$diff" -gt "$full" ] || diff=$full
+  if [ $((now - full)) -ge "$FULL_INTERVAL" ]; then
+    type=full
+  elif [ $((now - diff)) -ge "$DIFF_INTERVAL" ]; then
+    type=diff
+  else
+    type=incr
+  fi
+  pgbackrest backup --type="$type" || echo "$type backup failed" >&2
+  sleep "$INCR_INTERVAL"
+done`
+
+// pgBackRestEnabled reports whether the Database uses the Incremental backup method
+func pgBackRestEnabled(database *databasesv1alpha1.Database) bool {
+	backup := database.Spec.Backup
+	return backup != nil && backup.Enabled && backupMethod(database) == databasesv1alpha1.BackupMethodIncremental
+}
+
+// validatePgBackRest checks the settings the Incremental backup method depends on
+func validatePgBackRest(database *databasesv1alpha1.Database) error {
+	backup := database.Spec.Backup
+	if backup.S3 == nil || backup.S3.Bucket == "" {
+		return errors.New("Incremental backups require backup.s3.bucket")
+	}
+	if backup.S3.Region == "" {
+		return errors.New("Incremental backups require backup.s3.region")
+	}
+	if backup.PgBackRest == nil || backup.PgBackRest.Image == "" {
+		return errors.New("Incremental backups require backup.pgBackRest.image")
+	}
+	if database.Spec.Storage == nil {
+		return errors.New("Incremental backups require spec.storage for the data directory")
+	}
+	if backup.Encryption != nil {
+		return errors.New("Incremental backups are encrypted with backup.pgBackRest.repo.cipherSecret, not backup.encryption")
+	}
+	return nil
+}
+
+// addPgBackRest runs a PostgreSQL pod on the pgBackRest image, archives every
+// completed WAL segment with pgbackrest archive-push and runs a sidecar taking
+// the scheduled backups. Each pod backs up to its own repository since replicas
+// are independent servers.
+func (r *DatabaseReconciler) addPgBackRest(database *databasesv1alpha1.Database, podSpec *corev1.PodSpec) {
+	spec := database.Spec.Backup.PgBackRest
+	socket := corev1.VolumeMount{Name: pgBackRestSocketVolume, MountPath: postgresSocketDir}
+	env := r.pgBackRestEnv(database)
+
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         pgBackRestSocketVolume,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	postgres := &podSpec.Containers[0]
+	postgres.Image = spec.Image
+	postgres.Env = append(postgres.Env, env...)
+	postgres.VolumeMounts = append(postgres.VolumeMounts, socket)
+	r.addCABundle(database, podSpec, postgres)
+	r.addProxyEnv(database, postgres)
+	postgres.Args = append(postgres.Args,
+		"-c", "wal_level=replica",
+		"-c", "archive_mode=on",
+		"-c", "archive_timeout="+walArchiveTimeout,
+		"-c", "archive_command=pgbackrest --stanza="+pgBackRestStanza+" archive-push %p",
+	)
+
+	uid := postgresUID
+	sidecarEnv := append([]corev1.EnvVar{}, env...)
+	sidecarEnv = append(sidecarEnv,
+		corev1.EnvVar{Name: "FULL_INTERVAL", Value: intervalSeconds(spec.FullInterval, defaultFullBackupInterval)},
+		corev1.EnvVar{Name: "DIFF_INTERVAL", Value: intervalSeconds(spec.DifferentialInterval, defaultDifferentialBackupInterval)},
+		corev1.EnvVar{Name: "INCR_INTERVAL", Value: intervalSeconds(spec.IncrementalInterval, defaultIncrementalBackupInterval)},
+	)
+	sidecar := corev1.Container{
+		Name:    pgBackRestContainer,
+		Image:   spec.Image,
+		Command: []string{"/bin/sh", "-c", pgBackRestScript},
+		Env:     sidecarEnv,
+		VolumeMounts: []corev1.VolumeMount{
+			socket,
+			{Name: "data", MountPath: postgresDataDir},
+		},
+		SecurityContext: &corev1.SecurityContext{RunAsUser: &uid},
+	}
+	r.addCABundle(database, podSpec, &sidecar)
+	r.addProxyEnv(database, &sidecar)
+	podSpec.Containers = append(podSpec.Containers, sidecar)
+}
+
+// pgBackRestEnv configures pgBackRest through its PGBACKREST_ variables, shared
+// by archive-push in the PostgreSQL container and the backup sidecar. POD_NAME
+// comes first so the repository path can reference it.
+func (r *DatabaseReconciler) pgBackRestEnv(database *databasesv1alpha1.Database) []corev1.EnvVar {
+	backup := database.Spec.Backup
+	s3 := backup.S3
+
+	env := []corev1.EnvVar{
+		{
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		},
+		{Name: "PGBACKREST_STANZA", Value: pgBackRestStanza},
+		{Name: "PGBACKREST_PG1_PATH", Value: postgresDataDir},
+		{Name: "PGBACKREST_PG1_SOCKET_PATH", Value: postgresSocketDir},
+		{Name: "PGBACKREST_LOG_LEVEL_FILE", Value: "off"},
+		{Name: "PGBACKREST_REPO1_TYPE", Value: "s3"},
+		{Name: "PGBACKREST_REPO1_PATH", Value: pgBackRestRepoPath(database, "$(POD_NAME)")},
+		{Name: "PGBACKREST_REPO1_S3_BUCKET", Value: s3.Bucket},
+		{Name: "PGBACKREST_REPO1_S3_REGION", Value: s3.Region},
+	}
+	env = append(env, renameEnv(r.getPostgreSQLEnv(database), map[string]string{
+		"POSTGRES_USER": "PGBACKREST_PG1_USER",
+	})...)
+
+	host, port := pgBackRestEndpoint(s3)
+	env = append(env, corev1.EnvVar{Name: "PGBACKREST_REPO1_S3_ENDPOINT", Value: host})
+	if port != "" {
+		env = append(env, corev1.EnvVar{Name: "PGBACKREST_REPO1_STORAGE_PORT", Value: port})
+	}
+	if s3.ForcePathStyle {
+		env = append(env, corev1.EnvVar{Name: "PGBACKREST_REPO1_S3_URI_STYLE", Value: "path"})
+	}
+	if s3.CredentialsSecret == "" {
+		env = append(env, corev1.EnvVar{Name: "PGBACKREST_REPO1_S3_KEY_TYPE", Value: "auto"})
+	}
+	env = append(env, renameEnv(s3CredentialsEnv(s3.CredentialsSecret), map[string]string{
+		"AWS_ACCESS_KEY_ID":     "PGBACKREST_REPO1_S3_KEY",
+		"AWS_SECRET_ACCESS_KEY": "PGBACKREST_REPO1_S3_KEY_SECRET",
+	})...)
+	if len(r.CABundle) > 0 {
+		env = append(env, corev1.EnvVar{Name: "PGBACKREST_REPO1_STORAGE_CA_FILE", Value: caBundleFile})
+	}
+
+	env = append(env, pgBackRestRetentionEnv(database)...)
+	if repo := backup.PgBackRest.Repo; repo != nil {
+		if repo.CipherSecret != "" {
+			env = append(env,
+				corev1.EnvVar{Name: "PGBACKREST_REPO1_CIPHER_TYPE", Value: "aes-256-cbc"},
+				corev1.EnvVar{
+					Name: "PGBACKREST_REPO1_CIPHER_PASS",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: repo.CipherSecret},
+							Key:                  "passphrase",
+						},
+					},
+				})
+		}
+		if repo.CompressType != "" {
+			env = append(env, corev1.EnvVar{Name: "PGBACKREST_COMPRESS_TYPE", Value: repo.CompressType})
+		}
+		if repo.ProcessMax != nil {
+			env = append(env, corev1.EnvVar{Name: "PGBACKREST_PROCESS_MAX", Value: strconv.Itoa(int(*repo.ProcessMax))})
+		}
+	}
+	return env
+}
+
+// pgBackRestRetentionEnv maps backup.retention onto the full backup retention of
+// pgBackRest, which counts full backups or keeps them for whole days
+func pgBackRestRetentionEnv(database *databasesv1alpha1.Database) []corev1.EnvVar {
+	backup := database.Spec.Backup
+	env := []corev1.EnvVar{}
+	if retention := backup.Retention; retention != nil {
+		switch {
+		case retention.MaxCount != nil:
+			env = append(env, corev1.EnvVar{Name: "PGBACKREST_REPO1_RETENTION_FULL", Value: strconv.Itoa(int(*retention.MaxCount))})
+		case retention.MaxAge != nil:
+			days := int(math.Ceil(retention.MaxAge.Hours() / 24))
+			env = append(env,
+				corev1.EnvVar{Name: "PGBACKREST_REPO1_RETENTION_FULL_TYPE", Value: "time"},
+				corev1.EnvVar{Name: "PGBACKREST_REPO1_RETENTION_FULL", Value: strconv.Itoa(days)})
+		}
+	}
+	if diff := backup.PgBackRest.DifferentialRetention; diff != nil {
+		env = append(env, corev1.EnvVar{Name: "PGBACKREST_REPO1_RETENTION_DIFF", Value: strconv.Itoa(int(*diff))})
+	}
+	return env
+}
+
+// pgBackRestRepoPath returns the repository of a pod within the bucket
+func pgBackRestRepoPath(database *databasesv1alpha1.Database, pod string) string {
+	parts := []string{""}
+	if path := strings.Trim(database.Spec.Backup.S3.Path, "/"); path != "" {
+		parts = append(parts, path)
+	}
+	parts = append(parts, database.Namespace, database.Name, pod)
+	return strings.Join(parts, "/")
+}
+
+// pgBackRestEndpoint splits the S3 endpoint into the host and port pgBackRest
+// expects, defaulting to the AWS endpoint of the region
+func pgBackRestEndpoint(s3 *databasesv1alpha1.S3Destination) (host, port string) {
+	if s3.Endpoint == "" {
+		return "s3." + s3.Region + ".amazonaws.com", ""
+	}
+	endpoint := s3.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return s3.Endpoint, ""
+	}
+	return u.Hostname(), u.Port()
+}
+
+// pgBackRestInterval returns the time between pgBackRest backups
+func pgBackRestInterval(database *databasesv1alpha1.Database) time.Duration {
+	return intervalOrDefault(database.Spec.Backup.PgBackRest.IncrementalInterval, defaultIncrementalBackupInterval)
+}
+
+// intervalOrDefault returns an interval, or the default when unset
+func intervalOrDefault(interval *metav1.Duration, defaultInterval time.Duration) time.Duration {
+	if interval == nil || interval.Duration <= 0 {
+		return defaultInterval
+	}
+	return interval.Duration
+}
+
+// intervalSeconds returns an interval in seconds, or the default when unset
+func intervalSeconds(interval *metav1.Duration, defaultInterval time.Duration) string {
+	return strconv.FormatInt(int64(intervalOrDefault(interval, defaultInterval).Seconds()), 10)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// The phase of a Database moves through these states:
+//
+//	Creating/Provisioning -> Bootstrapping -> Ready
+//	Ready -> Upgrading/PreCheck -> Upgrading/Canary -> Upgrading/Rolling -> Upgrading/Verify -> Ready
+//	Ready -> Upgrading/PreCheck -> Upgrading/Converting -> Upgrading/Verify -> Ready (offline majors)
+//	Ready -> Restoring -> Ready
+//	Ready -> Hibernated -> Ready
+//	Ready -> Migrating -> Ready
+//
+// Pending waits for referenced objects and Failed for the next successful
+// reconcile, which resumes from the observed state. The phase and sub-phase are
+// only changed through setPhase, which records when they changed.
+
+// setPhase moves the Database to a phase and sub-phase
+func setPhase(database *databasesv1alpha1.Database, phase databasesv1alpha1.DatabasePhase, subPhase databasesv1alpha1.DatabaseSubPhase, now time.Time) {
+	status := &database.Status
+	if status.Phase == phase && status.SubPhase == subPhase && status.PhaseTransitionTime != nil {
+		return
+	}
+	transition := metav1.NewTime(now)
+	status.Phase, status.SubPhase, status.PhaseTransitionTime = phase, subPhase, &transition
+}
+
+// startUpgrade enters the upgrade of a new version, which was checked by
+// validateSpec and rolls out once nothing defers it
+func startUpgrade(database *databasesv1alpha1.Database, now time.Time) {
+	setPhase(database, databasesv1alpha1.DatabasePhaseUpgrading, databasesv1alpha1.DatabaseSubPhasePreCheck, now)
+}
+
+// rollUpgrade moves an upgrade to its rollout once the new pod template is applied
+func rollUpgrade(database *databasesv1alpha1.Database, now time.Time) {
+	if database.Status.Phase != databasesv1alpha1.DatabasePhaseUpgrading {
+		return
+	}
+	subPhase := databasesv1alpha1.DatabaseSubPhaseRolling
+	if rollout := database.Status.Rollout; rollout != nil && rollout.Step == databasesv1alpha1.RolloutStepCanary {
+		subPhase = databasesv1alpha1.DatabaseSubPhaseCanary
+	}
+	setPhase(database, databasesv1alpha1.DatabasePhaseUpgrading, subPhase, now)
+}
+
+// majorUpgradeSubPhase returns the sub-phase of a step of an offline major upgrade
+func majorUpgradeSubPhase(step databasesv1alpha1.MajorUpgradeStep) databasesv1alpha1.DatabaseSubPhase {
+	switch step {
+	case databasesv1alpha1.MajorUpgradeStepPreCheck:
+		return databasesv1alpha1.DatabaseSubPhasePreCheck
+	case databasesv1alpha1.MajorUpgradeStepVerify:
+		return databasesv1alpha1.DatabaseSubPhaseVerify
+	case databasesv1alpha1.MajorUpgradeStepRollingBack:
+		return databasesv1alpha1.DatabaseSubPhaseRollingBack
+	}
+	return databasesv1alpha1.DatabaseSubPhaseConverting
+}
+
+// advancePhase moves a Database whose reconcile succeeded to its next phase: an
+// upgrade from its rollout to its verification and then to Ready, a Database
+// whose first bootstrap has not completed to Bootstrapping, a hibernated one
+// whose replicas all stopped to Hibernated, and others to Ready
+func advancePhase(database *databasesv1alpha1.Database, now time.Time) {
+	status := &database.Status
+	upgrading := status.Phase == databasesv1alpha1.DatabasePhaseUpgrading
+	switch {
+	case upgrading && status.SubPhase == databasesv1alpha1.DatabaseSubPhasePreCheck:
+		// Waits for the new pod template to be applied
+	case upgrading && activeOperation(database) == disruptiveOperationUpdate:
+		rollUpgrade(database, now)
+	case upgrading && !upgradeVerified(database):
+		setPhase(database, databasesv1alpha1.DatabasePhaseUpgrading, databasesv1alpha1.DatabaseSubPhaseVerify, now)
+	case hibernated(database) && status.ReadyReplicas == 0 && activeOperation(database) != disruptiveOperationScale:
+		setPhase(database, databasesv1alpha1.DatabasePhaseHibernated, "", now)
+	case bootstrapEnabled(database) && status.Bootstrap == nil:
+		setPhase(database, databasesv1alpha1.DatabasePhaseBootstrapping, "", now)
+	default:
+		setPhase(database, databasesv1alpha1.DatabasePhaseReady, "", now)
+	}
+}
+
+// upgradeVerified reports whether every replica is ready after an upgrade
+// rolled out and, for engines with a health check, a check since then found
+// the engine healthy
+func upgradeVerified(database *databasesv1alpha1.Database) bool {
+	status := &database.Status
+	if status.SubPhase != databasesv1alpha1.DatabaseSubPhaseVerify || status.ReadyReplicas < desiredReplicas(database) {
+		return false
+	}
+	if _, checked := healthCheckers[database.Spec.Type]; !checked {
+		return true
+	}
+	health := status.Health
+	return health != nil && health.State == databasesv1alpha1.HealthStateHealthy &&
+		health.CheckedAt.After(status.PhaseTransitionTime.Time)
+}
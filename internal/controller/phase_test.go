@@ -0,0 +1,105 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Database phases", func() {
+	var (
+		database *databasesv1alpha1.Database
+		now      time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16.4"},
+			Status:     databasesv1alpha1.DatabaseStatus{ReadyReplicas: 1},
+		}
+		setPhase(database, databasesv1alpha1.DatabasePhaseReady, "", now)
+	})
+
+	phase := func() []string {
+		return []string{string(database.Status.Phase), string(database.Status.SubPhase)}
+	}
+
+	It("should step an upgrade through its pre-check, rollout and verification", func() {
+		startUpgrade(database, now)
+		advancePhase(database, now)
+		Expect(phase()).To(Equal([]string{"Upgrading", "PreCheck"}))
+
+		By("rolling out through the canary")
+		Expect(acquireOperation(database, disruptiveOperationUpdate, now)).To(BeTrue())
+		database.Status.Rollout = &databasesv1alpha1.RolloutStatus{Step: databasesv1alpha1.RolloutStepCanary}
+		rollUpgrade(database, now.Add(time.Second))
+		Expect(phase()).To(Equal([]string{"Upgrading", "Canary"}))
+		database.Status.Rollout.Step = databasesv1alpha1.RolloutStepPromoted
+		advancePhase(database, now.Add(2*time.Second))
+		Expect(phase()).To(Equal([]string{"Upgrading", "Rolling"}))
+		Expect(database.Status.PhaseTransitionTime.Time).To(BeTemporally("==", now.Add(2*time.Second)))
+
+		By("verifying the engine with a health check after the rollout")
+		releaseOperation(database, disruptiveOperationUpdate)
+		database.Status.Health = &databasesv1alpha1.HealthStatus{
+			State:     databasesv1alpha1.HealthStateHealthy,
+			CheckedAt: metav1.NewTime(now.Add(3 * time.Second)),
+		}
+		advancePhase(database, now.Add(3*time.Second))
+		Expect(phase()).To(Equal([]string{"Upgrading", "Verify"}))
+		advancePhase(database, now.Add(4*time.Second))
+		Expect(phase()).To(Equal([]string{"Upgrading", "Verify"}))
+
+		database.Status.Health.CheckedAt = metav1.NewTime(now.Add(time.Minute))
+		advancePhase(database, now.Add(time.Minute))
+		Expect(phase()).To(Equal([]string{"Ready", ""}))
+	})
+
+	It("should report the step of an offline major upgrade", func() {
+		for step, subPhase := range map[databasesv1alpha1.MajorUpgradeStep]string{
+			databasesv1alpha1.MajorUpgradeStepPreCheck:    "PreCheck",
+			databasesv1alpha1.MajorUpgradeStepUpgrade:     "Converting",
+			databasesv1alpha1.MajorUpgradeStepCutover:     "Converting",
+			databasesv1alpha1.MajorUpgradeStepVerify:      "Verify",
+			databasesv1alpha1.MajorUpgradeStepRollingBack: "RollingBack",
+		} {
+			markUpgrading(database, &databasesv1alpha1.MajorUpgradeStatus{FromVersion: "15", ToVersion: "16", Step: step})
+			Expect(phase()).To(Equal([]string{"Upgrading", subPhase}))
+		}
+	})
+
+	It("should stay Bootstrapping until the first bootstrap completed", func() {
+		database.Spec.Bootstrap = &databasesv1alpha1.BootstrapSpec{
+			Databases: []databasesv1alpha1.BootstrapDatabase{{Name: "shop"}},
+		}
+		advancePhase(database, now)
+		Expect(phase()).To(Equal([]string{"Bootstrapping", ""}))
+
+		database.Status.Bootstrap = &databasesv1alpha1.BootstrapStatus{AppliedHash: "0123"}
+		advancePhase(database, now)
+		Expect(phase()).To(Equal([]string{"Ready", ""}))
+	})
+})
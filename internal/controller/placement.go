@@ -0,0 +1,73 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// preferredAntiAffinityWeight outweighs the default scheduler scores, so
+	// replicas share a node only when no other node fits
+	preferredAntiAffinityWeight = 100
+	defaultZoneTopologyKey      = "topology.kubernetes.io/zone"
+)
+
+// applyPlacement keeps the replicas selected by the labels of a workload on
+// different nodes and spreads them across zones, as topology asks
+func applyPlacement(database *databasesv1alpha1.Database, template *corev1.PodTemplateSpec, selector map[string]string) {
+	topology := database.Spec.Topology
+	if topology == nil {
+		return
+	}
+	labelSelector := &metav1.LabelSelector{MatchLabels: selector}
+
+	if topology.AntiAffinity != "" {
+		term := corev1.PodAffinityTerm{LabelSelector: labelSelector, TopologyKey: corev1.LabelHostname}
+		antiAffinity := &corev1.PodAntiAffinity{}
+		if topology.AntiAffinity == databasesv1alpha1.AntiAffinityRequired {
+			antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = []corev1.PodAffinityTerm{term}
+		} else {
+			antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = []corev1.WeightedPodAffinityTerm{
+				{Weight: preferredAntiAffinityWeight, PodAffinityTerm: term},
+			}
+		}
+		if template.Spec.Affinity == nil {
+			template.Spec.Affinity = &corev1.Affinity{}
+		}
+		template.Spec.Affinity.PodAntiAffinity = antiAffinity
+	}
+
+	if spread := topology.ZoneSpread; spread != nil {
+		constraint := corev1.TopologySpreadConstraint{
+			MaxSkew:           max(spread.MaxSkew, 1),
+			TopologyKey:       spread.TopologyKey,
+			WhenUnsatisfiable: spread.WhenUnsatisfiable,
+			LabelSelector:     labelSelector,
+		}
+		if constraint.TopologyKey == "" {
+			constraint.TopologyKey = defaultZoneTopologyKey
+		}
+		if constraint.WhenUnsatisfiable == "" {
+			constraint.WhenUnsatisfiable = corev1.ScheduleAnyway
+		}
+		template.Spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{constraint}
+	}
+}
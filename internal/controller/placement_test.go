@@ -0,0 +1,94 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Placement", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+		}
+	})
+
+	It("should leave the placement to the scheduler by default", func() {
+		statefulSet := reconciler.createPostgreSQLStatefulSet(database, 3, nil)
+		Expect(statefulSet.Spec.Template.Spec.Affinity).To(BeNil())
+		Expect(statefulSet.Spec.Template.Spec.TopologySpreadConstraints).To(BeEmpty())
+	})
+
+	It("should prefer different nodes and spread the replicas across zones", func() {
+		database.Spec.Topology = &databasesv1alpha1.TopologySpec{
+			AntiAffinity: databasesv1alpha1.AntiAffinityPreferred,
+			ZoneSpread:   &databasesv1alpha1.ZoneSpreadSpec{},
+		}
+		template := reconciler.createPostgreSQLStatefulSet(database, 3, nil).Spec.Template
+
+		antiAffinity := template.Spec.Affinity.PodAntiAffinity
+		Expect(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(BeEmpty())
+		Expect(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(HaveLen(1))
+		term := antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0]
+		Expect(term.Weight).To(Equal(int32(preferredAntiAffinityWeight)))
+		Expect(term.PodAffinityTerm.TopologyKey).To(Equal(corev1.LabelHostname))
+		Expect(term.PodAffinityTerm.LabelSelector.MatchLabels).To(Equal(reconciler.getLabels(database)))
+
+		Expect(template.Spec.TopologySpreadConstraints).To(Equal([]corev1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       defaultZoneTopologyKey,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: reconciler.getLabels(database)},
+		}}))
+	})
+
+	It("should spread every Elasticsearch node set on its own", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeElasticsearch
+		database.Spec.Topology = &databasesv1alpha1.TopologySpec{
+			AntiAffinity: databasesv1alpha1.AntiAffinityRequired,
+			ZoneSpread:   &databasesv1alpha1.ZoneSpreadSpec{MaxSkew: 2, WhenUnsatisfiable: corev1.DoNotSchedule},
+		}
+		nodeSet := databasesv1alpha1.ElasticsearchNodeSet{
+			Name: "master", Roles: []databasesv1alpha1.ElasticsearchNodeRole{"master"}, Replicas: 3,
+		}
+		database.Spec.Elasticsearch = &databasesv1alpha1.ElasticsearchConfig{
+			NodeSets: []databasesv1alpha1.ElasticsearchNodeSet{nodeSet},
+		}
+		template := reconciler.createElasticsearchNodeSet(database, nodeSet, "").Spec.Template
+
+		required := template.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+		Expect(required).To(HaveLen(1))
+		Expect(required[0].LabelSelector.MatchLabels).To(HaveKeyWithValue(elasticsearchNodeSetLabel, "master"))
+		Expect(template.Spec.TopologySpreadConstraints).To(HaveLen(1))
+		Expect(template.Spec.TopologySpreadConstraints[0].MaxSkew).To(Equal(int32(2)))
+		Expect(template.Spec.TopologySpreadConstraints[0].WhenUnsatisfiable).To(Equal(corev1.DoNotSchedule))
+		Expect(template.Spec.TopologySpreadConstraints[0].LabelSelector.MatchLabels).To(HaveKeyWithValue(elasticsearchNodeSetLabel, "master"))
+	})
+})
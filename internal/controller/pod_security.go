@@ -0,0 +1,114 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const conditionPodSecurity = "PodSecurity"
+
+// podSecurityLevel returns the Pod Security Standards level of the pods of a
+// Database: the one of spec.podSecurity, or restricted when the image of the
+// engine runs as non-root
+func podSecurityLevel(database *databasesv1alpha1.Database) databasesv1alpha1.PodSecurityLevel {
+	if database.Spec.PodSecurity != "" {
+		return database.Spec.PodSecurity
+	}
+	if engineLayout(database).user != 0 {
+		return databasesv1alpha1.PodSecurityRestricted
+	}
+	return databasesv1alpha1.PodSecurityBaseline
+}
+
+// applyPodSecurity labels a pod template with its level and, for the restricted
+// level, runs its pods as the non-root user of the engine image. Containers
+// added to the pod afterwards go through restrictContainer.
+func (r *DatabaseReconciler) applyPodSecurity(database *databasesv1alpha1.Database, template *corev1.PodTemplateSpec) {
+	level := podSecurityLevel(database)
+	// Workloads share their labels with the selector, which must not change
+	labels := make(map[string]string, len(template.Labels)+1)
+	maps.Copy(labels, template.Labels)
+	labels[databasesv1alpha1.PodSecurityLabel] = strings.ToLower(string(level))
+	template.Labels = labels
+
+	if level != databasesv1alpha1.PodSecurityRestricted {
+		return
+	}
+	uid := engineLayout(database).user
+	runAsNonRoot := true
+	podSpec := &template.Spec
+	if podSpec.SecurityContext == nil {
+		podSpec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	podSpec.SecurityContext.RunAsNonRoot = &runAsNonRoot
+	podSpec.SecurityContext.RunAsUser = &uid
+	// The data volumes are made writable by the group of the user
+	podSpec.SecurityContext.FSGroup = &uid
+	podSpec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+	for i := range podSpec.InitContainers {
+		r.restrictContainer(database, &podSpec.InitContainers[i])
+	}
+	for i := range podSpec.Containers {
+		r.restrictContainer(database, &podSpec.Containers[i])
+	}
+}
+
+// restrictContainer sets the container fields required by the restricted
+// level, keeping the user the container runs as
+func (r *DatabaseReconciler) restrictContainer(database *databasesv1alpha1.Database, container *corev1.Container) {
+	if podSecurityLevel(database) != databasesv1alpha1.PodSecurityRestricted {
+		return
+	}
+	allowPrivilegeEscalation := false
+	if container.SecurityContext == nil {
+		container.SecurityContext = &corev1.SecurityContext{}
+	}
+	container.SecurityContext.AllowPrivilegeEscalation = &allowPrivilegeEscalation
+	container.SecurityContext.Capabilities = &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}}
+}
+
+// reportPodSecurity sets the PodSecurity condition to the level new pods are
+// generated for
+func reportPodSecurity(database *databasesv1alpha1.Database) {
+	level := podSecurityLevel(database)
+	condition := metav1.Condition{
+		Type:               conditionPodSecurity,
+		Status:             metav1.ConditionTrue,
+		Reason:             string(level),
+		ObservedGeneration: database.Generation,
+	}
+	uid := engineLayout(database).user
+	switch {
+	case level == databasesv1alpha1.PodSecurityRestricted:
+		condition.Message = fmt.Sprintf("Pods run as non-root user %d and comply with the restricted Pod Security Standard", uid)
+	case uid == 0:
+		condition.Message = fmt.Sprintf("The %s %s image starts as root, pods comply with the baseline Pod Security Standard",
+			imageFlavor(database), database.Spec.Type)
+	default:
+		condition.Message = "spec.podSecurity selects the baseline Pod Security Standard"
+	}
+	meta.SetStatusCondition(&database.Status.Conditions, condition)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Pod security", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "sessions", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeRedis, Version: "7.2"},
+		}
+	})
+
+	restricted := func(podSpec corev1.PodSpec, uid int64) {
+		GinkgoHelper()
+		Expect(podSpec.SecurityContext).To(Equal(&corev1.PodSecurityContext{
+			RunAsNonRoot:   ptr.To(true),
+			RunAsUser:      ptr.To(uid),
+			FSGroup:        ptr.To(uid),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		}))
+		for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+			Expect(container.SecurityContext.AllowPrivilegeEscalation).To(Equal(ptr.To(false)), container.Name)
+			Expect(container.SecurityContext.Capabilities.Drop).To(Equal([]corev1.Capability{"ALL"}), container.Name)
+		}
+	}
+
+	It("should run engines with non-root images under the restricted level", func() {
+		statefulSet := reconciler.createRedisStatefulSet(database, 1, nil)
+		template := statefulSet.Spec.Template
+		restricted(template.Spec, 999)
+		Expect(template.Labels).To(HaveKeyWithValue(databasesv1alpha1.PodSecurityLabel, "restricted"))
+		Expect(statefulSet.Spec.Selector.MatchLabels).NotTo(HaveKey(databasesv1alpha1.PodSecurityLabel))
+
+		job := reconciler.createAdminJob(database, rotationComponent, "true", nil)
+		restricted(job.Spec.Template.Spec, 999)
+		Expect(job.Spec.Template.Labels).To(HaveKeyWithValue(databasesv1alpha1.PodSecurityLabel, "restricted"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeElasticsearch
+		database.Spec.Version = "8.11.0"
+		restricted(reconciler.createElasticsearchStatefulSet(database, 1, nil).Spec.Template.Spec, 1000)
+
+		reportPodSecurity(database)
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionPodSecurity)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("Restricted"))
+		Expect(condition.Message).To(Equal("Pods run as non-root user 1000 and comply with the restricted Pod Security Standard"))
+	})
+
+	It("should restrict the containers added to backup and restore pods", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		database.Spec.Version = "7.0"
+		database.Spec.Backup = &databasesv1alpha1.BackupSpec{
+			Enabled:    true,
+			Encryption: &databasesv1alpha1.BackupEncryption{KMSKeyID: "arn:aws:kms:eu-west-1:123456789012:key/2f1b7c3e-tenant-a"},
+		}
+		cronJob := reconciler.createBackupCronJob(database, mainBackupSchedule(database))
+		podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+		Expect(podSpec.Containers[0].Image).To(Equal(defaultAWSKMSImage))
+		restricted(podSpec, 999)
+
+		restore := &databasesv1alpha1.DatabaseRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "rollback", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseRestoreSpec{
+				Source:          databasesv1alpha1.RestoreSource{S3: &databasesv1alpha1.S3Source{URI: "s3://backups/orders.dump"}},
+				PerformanceMode: true,
+			},
+		}
+		job, err := reconciler.createRestoreJob(database, restore, "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.InitContainers).To(ContainElement(HaveField("Name", restoreDownloadContainer)))
+		restricted(job.Spec.Template.Spec, 999)
+	})
+
+	It("should keep engines whose images start as root under the baseline level", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypePostgreSQL
+		database.Spec.Version = "16"
+		template := reconciler.createPostgreSQLStatefulSet(database, 1, nil).Spec.Template
+		Expect(template.Spec.SecurityContext).To(BeNil())
+		Expect(template.Spec.Containers[0].SecurityContext).To(BeNil())
+		Expect(template.Labels).To(HaveKeyWithValue(databasesv1alpha1.PodSecurityLabel, "baseline"))
+
+		reportPodSecurity(database)
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionPodSecurity)
+		Expect(condition.Reason).To(Equal("Baseline"))
+		Expect(condition.Message).To(Equal("The Official PostgreSQL image starts as root, pods comply with the baseline Pod Security Standard"))
+
+		database.Spec.PodSecurity = databasesv1alpha1.PodSecurityRestricted
+		Expect(reconciler.validateSpec(database)).To(MatchError("the Official PostgreSQL image starts as root and requires the Baseline podSecurity level"))
+	})
+
+	It("should let the spec select the baseline level", func() {
+		database.Spec.PodSecurity = databasesv1alpha1.PodSecurityBaseline
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+		template := reconciler.createRedisStatefulSet(database, 1, nil).Spec.Template
+		Expect(template.Spec.SecurityContext).To(BeNil())
+		Expect(template.Labels).To(HaveKeyWithValue(databasesv1alpha1.PodSecurityLabel, "baseline"))
+
+		reportPodSecurity(database)
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionPodSecurity).Message).To(
+			Equal("spec.podSecurity selects the baseline Pod Security Standard"))
+	})
+})
@@ -0,0 +1,214 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	conditionMissingReference = "MissingReference"
+
+	// missingReferenceRecheckInterval is how often absent Secrets are looked up again
+	missingReferenceRecheckInterval = 30 * time.Second
+)
+
+// secretKeyReference is a Secret key the spec refers to
+type secretKeyReference struct {
+	// Field is the spec field holding the reference
+	Field string
+	Name  string
+	Key   string
+}
+
+// secretKeyReferences lists the Secret keys the workloads and Jobs of the
+// Database read, in spec order
+func secretKeyReferences(database *databasesv1alpha1.Database) []secretKeyReference {
+	references := []secretKeyReference{}
+	add := func(field string, secret *databasesv1alpha1.SecretReference) {
+		if secret != nil {
+			references = append(references, secretKeyReference{Field: field, Name: secret.Name, Key: secret.Key})
+		}
+	}
+
+	if database.Spec.PostgreSQL != nil {
+		add("spec.postgresql.passwordSecret", database.Spec.PostgreSQL.PasswordSecret)
+	}
+	if database.Spec.MongoDB != nil {
+		add("spec.mongodb.passwordSecret", database.Spec.MongoDB.PasswordSecret)
+	}
+	if database.Spec.Redis != nil {
+		add("spec.redis.passwordSecret", database.Spec.Redis.PasswordSecret)
+	}
+	for i, ev := range database.Spec.Env {
+		if ev.ValueFrom != nil {
+			add(fmt.Sprintf("spec.env[%d].valueFrom.secretKeyRef", i), ev.ValueFrom.SecretKeyRef)
+		}
+	}
+	if bootstrap := database.Spec.Bootstrap; bootstrap != nil {
+		for i := range bootstrap.Users {
+			add(fmt.Sprintf("spec.bootstrap.users[%d].passwordSecret", i), &bootstrap.Users[i].PasswordSecret)
+		}
+	}
+	if backup := database.Spec.Backup; backup != nil && backup.S3 != nil && backup.S3.CredentialsSecret != "" {
+		for _, ev := range s3CredentialsEnv(backup.S3.CredentialsSecret) {
+			references = append(references, secretKeyReference{
+				Field: "spec.backup.s3.credentialsSecret",
+				Name:  backup.S3.CredentialsSecret,
+				Key:   ev.ValueFrom.SecretKeyRef.Key,
+			})
+		}
+	}
+	addCopies := func(field string, copies []databasesv1alpha1.BackupCopy) {
+		for i, backupCopy := range copies {
+			for _, ev := range s3CredentialsEnv(backupCopy.S3.CredentialsSecret) {
+				references = append(references, secretKeyReference{
+					Field: fmt.Sprintf("%s[%d].s3.credentialsSecret", field, i),
+					Name:  backupCopy.S3.CredentialsSecret,
+					Key:   ev.ValueFrom.SecretKeyRef.Key,
+				})
+			}
+		}
+	}
+	if backup := database.Spec.Backup; backup != nil {
+		addCopies("spec.backup.copies", backup.Copies)
+		for i, schedule := range backup.Schedules {
+			addCopies(fmt.Sprintf("spec.backup.schedules[%d].copies", i), schedule.Copies)
+		}
+	}
+	if backup := database.Spec.Backup; backup != nil && backup.PgBackRest != nil && backup.PgBackRest.Repo != nil &&
+		backup.PgBackRest.Repo.CipherSecret != "" {
+		references = append(references, secretKeyReference{
+			Field: "spec.backup.pgBackRest.repo.cipherSecret",
+			Name:  backup.PgBackRest.Repo.CipherSecret,
+			Key:   "passphrase",
+		})
+	}
+	if encryption := backupEncryption(database); encryption != nil && encryption.CredentialsSecret != "" {
+		keys := []string{"credentials.json"}
+		if !isGoogleKMSKey(encryption.KMSKeyID) {
+			keys = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}
+		}
+		for _, key := range keys {
+			references = append(references, secretKeyReference{
+				Field: "spec.backup.encryption.credentialsSecret",
+				Name:  encryption.CredentialsSecret,
+				Key:   key,
+			})
+		}
+	}
+	if tls := database.Spec.TLS; tls != nil && tls.SecretName != "" {
+		for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+			references = append(references, secretKeyReference{Field: "spec.tls.secretName", Name: tls.SecretName, Key: key})
+		}
+	}
+	return references
+}
+
+// missingReferences describes the DatabaseClass and every referenced Secret or
+// Secret key that does not exist in the namespace of the Database
+func (r *DatabaseReconciler) missingReferences(ctx context.Context, database *databasesv1alpha1.Database) ([]string, error) {
+	missing := []string{}
+	if name := database.Spec.ClassName; name != "" {
+		err := r.Get(ctx, types.NamespacedName{Name: name}, &databasesv1alpha1.DatabaseClass{})
+		if errors.IsNotFound(err) {
+			missing = append(missing, fmt.Sprintf("DatabaseClass %q (spec.className)", name))
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	secrets := map[string]*corev1.Secret{}
+	for _, reference := range secretKeyReferences(database) {
+		secret, looked := secrets[reference.Name]
+		if !looked {
+			secret = &corev1.Secret{}
+			err := r.Get(ctx, types.NamespacedName{Name: reference.Name, Namespace: database.Namespace}, secret)
+			if errors.IsNotFound(err) {
+				secret = nil
+			} else if err != nil {
+				return nil, err
+			}
+			secrets[reference.Name] = secret
+		}
+
+		var problem string
+		switch {
+		case secret == nil:
+			problem = fmt.Sprintf("Secret %q (%s)", reference.Name, reference.Field)
+		case !secretHasKey(secret, reference.Key):
+			problem = fmt.Sprintf("key %q of Secret %q (%s)", reference.Key, reference.Name, reference.Field)
+		}
+		if problem != "" && !slices.Contains(missing, problem) {
+			missing = append(missing, problem)
+		}
+	}
+	return missing, nil
+}
+
+func secretHasKey(secret *corev1.Secret, key string) bool {
+	if _, ok := secret.Data[key]; ok {
+		return true
+	}
+	_, ok := secret.StringData[key]
+	return ok
+}
+
+// checkPrerequisites reports in the MissingReference condition whether all the
+// objects the spec refers to exist. Pods started without them crash-loop with
+// errors that do not name the missing object.
+func (r *DatabaseReconciler) checkPrerequisites(ctx context.Context, database *databasesv1alpha1.Database) (bool, error) {
+	missing, err := r.missingReferences(ctx, database)
+	if err != nil {
+		return false, err
+	}
+
+	if len(missing) == 0 {
+		meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+			Type:               conditionMissingReference,
+			Status:             metav1.ConditionFalse,
+			Reason:             "AllReferencesFound",
+			Message:            "All referenced objects exist",
+			ObservedGeneration: database.Generation,
+		})
+		return true, nil
+	}
+
+	message := "Missing " + strings.Join(missing, ", ")
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               conditionMissingReference,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ReferencesNotFound",
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+	setCondition(database, conditionReady, metav1.ConditionFalse, conditionMissingReference, message)
+	setPhase(database, databasesv1alpha1.DatabasePhasePending, "", time.Now())
+	database.Status.Message = message
+	return false, nil
+}
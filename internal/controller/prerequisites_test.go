@@ -0,0 +1,97 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Prerequisites", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "orders-credentials", Namespace: "shop"},
+				Data:       map[string][]byte{"password": []byte("s3cret")},
+			},
+		).Build()
+		reconciler = &DatabaseReconciler{Client: c, Scheme: scheme}
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				PostgreSQL: &databasesv1alpha1.PostgreSQLConfig{
+					PasswordSecret: &databasesv1alpha1.SecretReference{Name: "orders-credentials", Key: "password"},
+				},
+			},
+		}
+	})
+
+	It("should pass when every referenced Secret key exists", func() {
+		ready, err := reconciler.checkPrerequisites(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ready).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(database.Status.Conditions, conditionMissingReference)).To(BeTrue())
+	})
+
+	It("should list exactly which Secrets and keys are absent", func() {
+		database.Spec.Env = []databasesv1alpha1.EnvVar{
+			{Name: "TZ", Value: "UTC"},
+			{Name: "API_TOKEN", ValueFrom: &databasesv1alpha1.EnvVarSource{
+				SecretKeyRef: &databasesv1alpha1.SecretReference{Name: "orders-credentials", Key: "token"},
+			}},
+		}
+		database.Spec.Backup = &databasesv1alpha1.BackupSpec{
+			S3: &databasesv1alpha1.S3Destination{Bucket: "backups", CredentialsSecret: "s3-credentials"},
+		}
+
+		ready, err := reconciler.checkPrerequisites(ctx, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ready).To(BeFalse())
+		Expect(database.Status.Phase).To(Equal(databasesv1alpha1.DatabasePhasePending))
+
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionMissingReference)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(Equal(`Missing key "token" of Secret "orders-credentials" (spec.env[1].valueFrom.secretKeyRef), ` +
+			`Secret "s3-credentials" (spec.backup.s3.credentialsSecret)`))
+		Expect(meta.IsStatusConditionFalse(database.Status.Conditions, "Ready")).To(BeTrue())
+	})
+})
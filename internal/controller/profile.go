@@ -0,0 +1,118 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// profileResources are the resources of the engine container preset by each profile
+var profileResources = map[databasesv1alpha1.DatabaseProfile]databasesv1alpha1.ResourceRequirements{
+	databasesv1alpha1.DatabaseProfileDev:        {CPU: "100m", Memory: "256Mi", MemoryLimit: "512Mi"},
+	databasesv1alpha1.DatabaseProfileSmall:      {CPU: "500m", Memory: "2Gi", CPULimit: "1", MemoryLimit: "2Gi"},
+	databasesv1alpha1.DatabaseProfileProd:       {CPU: "2", Memory: "8Gi", MemoryLimit: "8Gi"},
+	databasesv1alpha1.DatabaseProfileHighMemory: {CPU: "2", Memory: "32Gi", MemoryLimit: "32Gi"},
+}
+
+// effectiveResources returns the resources of the engine container: those of
+// the profile, each replaced by the field set in spec.resources. It returns
+// nil when neither sets any.
+func effectiveResources(database *databasesv1alpha1.Database) *databasesv1alpha1.ResourceRequirements {
+	preset, found := profileResources[database.Spec.Profile]
+	spec := database.Spec.Resources
+	if !found {
+		return spec
+	}
+	if spec == nil {
+		return &preset
+	}
+	if spec.CPU != "" {
+		preset.CPU = spec.CPU
+	}
+	if spec.Memory != "" {
+		preset.Memory = spec.Memory
+	}
+	if spec.CPULimit != "" {
+		preset.CPULimit = spec.CPULimit
+	}
+	if spec.MemoryLimit != "" {
+		preset.MemoryLimit = spec.MemoryLimit
+	}
+	// A request of the spec above the limit of the profile raises the limit,
+	// a limit of the spec below the request of the profile lowers the request
+	fitRequest(&preset.CPU, &preset.CPULimit, spec.CPULimit != "")
+	fitRequest(&preset.Memory, &preset.MemoryLimit, spec.MemoryLimit != "")
+	return &preset
+}
+
+// fitRequest keeps a request within its limit by moving the one of the profile
+func fitRequest(request, limit *string, limitSet bool) {
+	requested, err := resource.ParseQuantity(*request)
+	if err != nil || *limit == "" {
+		return
+	}
+	limited, err := resource.ParseQuantity(*limit)
+	if err != nil || requested.Cmp(limited) <= 0 {
+		return
+	}
+	if limitSet {
+		*request = *limit
+	} else {
+		*limit = *request
+	}
+}
+
+// profileParameters returns the memory settings a profile sizes to the
+// memory of the engine container: the limit, or the request without one.
+// The engine parameters of the spec take precedence. Elasticsearch sizes its
+// heap from the memory limit of the container on its own.
+func profileParameters(database *databasesv1alpha1.Database) map[string]string {
+	if _, found := profileResources[database.Spec.Profile]; !found {
+		return nil
+	}
+	resources := effectiveResources(database)
+	memory := resources.MemoryLimit
+	if memory == "" {
+		memory = resources.Memory
+	}
+	quantity, err := resource.ParseQuantity(memory)
+	if err != nil {
+		// Rejected by validateSpec
+		return nil
+	}
+	mebibytes := quantity.Value() / (1 << 20)
+
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		return map[string]string{
+			"shared_buffers":       fmt.Sprintf("%dMB", mebibytes/4),
+			"effective_cache_size": fmt.Sprintf("%dMB", mebibytes*3/4),
+		}
+	case databasesv1alpha1.DatabaseTypeRedis:
+		return map[string]string{"maxmemory": fmt.Sprintf("%dmb", mebibytes*3/4)}
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		// The default of mongod, half of the memory above 1Gi, from the
+		// container rather than the node
+		cache := max(float64(mebibytes-1024)/2/1024, 0.25)
+		return map[string]string{"storage.wiredTiger.engineConfig.cacheSizeGB": fmt.Sprintf("%.2f", cache)}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Profiles", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Profile: databasesv1alpha1.DatabaseProfileSmall,
+			},
+		}
+	})
+
+	It("should preset the resources and size the engine memory to them", func() {
+		resources := reconciler.createPostgreSQLStatefulSet(database, 1, nil).Spec.Template.Spec.Containers[0].Resources
+		Expect(resources.Requests.Cpu().String()).To(Equal("500m"))
+		Expect(resources.Limits.Memory().String()).To(Equal("2Gi"))
+		Expect(postgreSQLParameters(database)).To(Equal(map[string]string{
+			"shared_buffers":       "512MB",
+			"effective_cache_size": "1536MB",
+		}))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		Expect(redisParameters(database)).To(Equal(map[string]string{"maxmemory": "1536mb"}))
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		Expect(mongoDBParameters(database)).To(Equal(map[string]string{"storage.wiredTiger.engineConfig.cacheSizeGB": "0.50"}))
+
+		By("leaving Databases without profile as they are")
+		database.Spec.Profile = ""
+		Expect(effectiveResources(database)).To(BeNil())
+		Expect(mongoDBParameters(database)).To(BeEmpty())
+	})
+
+	It("should let the resources and parameters of the spec take precedence", func() {
+		database.Spec.Resources = &databasesv1alpha1.ResourceRequirements{Memory: "4Gi"}
+		database.Spec.PostgreSQL = &databasesv1alpha1.PostgreSQLConfig{Parameters: map[string]string{"shared_buffers": "256MB"}}
+
+		resources := reconciler.buildResourceRequirements(effectiveResources(database))
+		Expect(resources.Requests.Cpu().String()).To(Equal("500m"))
+		Expect(resources.Requests.Memory().String()).To(Equal("4Gi"))
+		// The limit of the profile grows with the larger request
+		Expect(resources.Limits.Memory().String()).To(Equal("4Gi"))
+		Expect(postgreSQLParameters(database)).To(Equal(map[string]string{
+			"shared_buffers":       "256MB",
+			"effective_cache_size": "3072MB",
+		}))
+
+		By("lowering the request of the profile to a smaller limit of the spec")
+		database.Spec.Resources = &databasesv1alpha1.ResourceRequirements{CPULimit: "250m"}
+		Expect(*effectiveResources(database)).To(Equal(databasesv1alpha1.ResourceRequirements{
+			CPU: "250m", Memory: "2Gi", CPULimit: "250m", MemoryLimit: "2Gi",
+		}))
+	})
+})
@@ -0,0 +1,370 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	conditionProvisioningFailed = "ProvisioningFailed"
+
+	defaultProvisioningAttempts = int32(5)
+	provisioningBaseBackoff     = 10 * time.Second
+	provisioningMaxBackoff      = 5 * time.Minute
+)
+
+// provisioningStep creates or updates the resources of one stage of provisioning
+type provisioningStep struct {
+	name      string
+	reconcile func(context.Context, *databasesv1alpha1.Database) error
+	// resources lists what the step creates, for tracking and rollback
+	resources func(*databasesv1alpha1.Database) []databasesv1alpha1.ProvisionedResource
+}
+
+// provisioningSteps returns the provisioning stages in their guaranteed order:
+// configuration first since pods mount it, then the Service so the stable
+// network identity exists before the pods, then the workload, whose volume claim
+// templates provision the storage
+func (r *DatabaseReconciler) provisioningSteps() []provisioningStep {
+	return []provisioningStep{
+		{name: "Config", reconcile: r.reconcileConfig, resources: r.configResources},
+		{name: "Service", reconcile: r.reconcileService, resources: serviceResources},
+		{name: "Workload", reconcile: r.reconcileWorkload, resources: workloadResources},
+	}
+}
+
+// provisioningError is a failure of the provisioning transaction
+type provisioningError struct {
+	step string
+	err  error
+	// retryAfter is the backoff before the next attempt, zero once provisioning failed for good
+	retryAfter time.Duration
+}
+
+func (e *provisioningError) Error() string {
+	if e.step == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("provisioning step %s failed: %v", e.step, e.err)
+}
+
+func (e *provisioningError) Unwrap() error {
+	return e.err
+}
+
+// reconcileProvisioning runs the provisioning steps in order. Until all of them
+// succeeded once, failures are retried with exponential backoff and, after the
+// last attempt, the resources created so far are optionally rolled back. Once
+// provisioned, errors are returned as they are and nothing is ever rolled back.
+func (r *DatabaseReconciler) reconcileProvisioning(ctx context.Context, database *databasesv1alpha1.Database) error {
+	if database.Status.Provisioning == nil {
+		database.Status.Provisioning = &databasesv1alpha1.ProvisioningStatus{}
+	}
+	tx := database.Status.Provisioning
+
+	// Once provisioned, resources deleted behind the operator's back are
+	// recreated and reported
+	if tx.Completed {
+		for _, step := range r.provisioningSteps() {
+			recreated, err := r.runProvisioningStep(ctx, database, step)
+			for _, resource := range recreated {
+				r.event(database, corev1.EventTypeNormal, "ResourceRecreated",
+					fmt.Sprintf("Recreated the missing %s %s", resource.Kind, resource.Name))
+			}
+			if err != nil {
+				log.FromContext(ctx).Error(err, "Failed to reconcile", "step", step.name)
+				return err
+			}
+		}
+		return nil
+	}
+
+	// A failed provisioning waits for a spec change
+	if tx.FailedGeneration != 0 {
+		if tx.FailedGeneration == database.Generation {
+			return &provisioningError{err: fmt.Errorf("provisioning of generation %d failed, waiting for a spec change", tx.FailedGeneration)}
+		}
+		tx.FailedGeneration = 0
+		tx.Attempts = 0
+	}
+
+	for _, step := range r.provisioningSteps() {
+		created, err := r.runProvisioningStep(ctx, database, step)
+		for _, resource := range created {
+			if !slices.Contains(tx.Created, resource) {
+				tx.Created = append(tx.Created, resource)
+			}
+		}
+		if err != nil {
+			return r.failProvisioning(ctx, database, step.name, err)
+		}
+	}
+
+	tx.Completed = true
+	tx.Attempts = 0
+	tx.Created = nil
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               conditionProvisioningFailed,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Provisioned",
+		Message:            "Configuration, Service and workload are provisioned",
+		ObservedGeneration: database.Generation,
+	})
+	recordOperation(database, recordProvision, databasesv1alpha1.OperationSucceeded,
+		"Configuration, Service and workload are provisioned", time.Now())
+	r.event(database, corev1.EventTypeNormal, "Provisioned", "Configuration, Service and workload are provisioned")
+	return nil
+}
+
+// runProvisioningStep runs a step and returns the resources it created
+func (r *DatabaseReconciler) runProvisioningStep(ctx context.Context, database *databasesv1alpha1.Database, step provisioningStep) (created []databasesv1alpha1.ProvisionedResource, err error) {
+	ctx, span := startSpan(ctx, step.name)
+	defer func() { endSpan(span, err) }()
+
+	missing := []databasesv1alpha1.ProvisionedResource{}
+	for _, resource := range step.resources(database) {
+		exists, err := r.provisionedResourceExists(ctx, database, resource)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			missing = append(missing, resource)
+		}
+	}
+
+	stepErr := step.reconcile(ctx, database)
+
+	created = []databasesv1alpha1.ProvisionedResource{}
+	for _, resource := range missing {
+		exists, err := r.provisionedResourceExists(ctx, database, resource)
+		if err != nil {
+			return created, err
+		}
+		if exists {
+			created = append(created, resource)
+		}
+	}
+	return created, stepErr
+}
+
+// failProvisioning records a failed attempt, and rolls back once the attempts are exhausted
+func (r *DatabaseReconciler) failProvisioning(ctx context.Context, database *databasesv1alpha1.Database, step string, stepErr error) error {
+	log := log.FromContext(ctx)
+	tx := database.Status.Provisioning
+	tx.Attempts++
+
+	maxAttempts := defaultProvisioningAttempts
+	rollback := false
+	if spec := database.Spec.Provisioning; spec != nil {
+		if spec.MaxAttempts != nil {
+			maxAttempts = *spec.MaxAttempts
+		}
+		rollback = spec.RollbackOnFailure
+	}
+
+	if tx.Attempts < maxAttempts {
+		retryAfter := provisioningBackoff(tx.Attempts)
+		message := fmt.Sprintf("Step %s failed (attempt %d/%d, retrying in %s): %v", step, tx.Attempts, maxAttempts, retryAfter, stepErr)
+		setProvisioningFailed(database, step+"Failed", message)
+		r.event(database, corev1.EventTypeWarning, "ProvisioningRetrying", message)
+		log.Error(stepErr, "Provisioning step failed, retrying", "step", step, "attempt", tx.Attempts, "retryAfter", retryAfter)
+		return &provisioningError{step: step, err: stepErr, retryAfter: retryAfter}
+	}
+
+	tx.FailedGeneration = database.Generation
+	message := fmt.Sprintf("Step %s failed after %d attempts: %v", step, tx.Attempts, stepErr)
+	if rollback {
+		if err := r.rollbackProvisioning(ctx, database); err != nil {
+			// Keep the transaction open so the rollback is retried
+			tx.FailedGeneration = 0
+			return err
+		}
+		message += "; created resources were rolled back"
+	}
+	setProvisioningFailed(database, step+"Failed", message)
+	recordOperation(database, recordProvision, databasesv1alpha1.OperationFailed, message, time.Now())
+	r.event(database, corev1.EventTypeWarning, "ProvisioningFailed", message)
+	log.Error(stepErr, "Provisioning failed", "step", step, "rolledBack", rollback)
+	return &provisioningError{step: step, err: stepErr}
+}
+
+// rollbackProvisioning deletes the resources created by the transaction, newest first
+func (r *DatabaseReconciler) rollbackProvisioning(ctx context.Context, database *databasesv1alpha1.Database) error {
+	tx := database.Status.Provisioning
+	for i := len(tx.Created) - 1; i >= 0; i-- {
+		resource := tx.Created[i]
+		obj := newProvisionedObject(resource.Kind)
+		obj.SetName(resource.Name)
+		obj.SetNamespace(database.Namespace)
+
+		log.FromContext(ctx).Info("Rolling back provisioned resource", "kind", resource.Kind, "name", resource.Name)
+		if err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		tx.Created = tx.Created[:i]
+	}
+
+	database.Status.ServiceName = ""
+	database.Status.ConnectionString = ""
+	database.Status.AppliedConfigHash = ""
+	return nil
+}
+
+func setProvisioningFailed(database *databasesv1alpha1.Database, reason, message string) {
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:               conditionProvisioningFailed,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: database.Generation,
+	})
+}
+
+// provisioningBackoff doubles the delay after each failed attempt
+func provisioningBackoff(attempts int32) time.Duration {
+	backoff := provisioningBaseBackoff
+	for i := int32(1); i < attempts && backoff < provisioningMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, provisioningMaxBackoff)
+}
+
+func newProvisionedObject(kind string) client.Object {
+	switch kind {
+	case "ConfigMap":
+		return &corev1.ConfigMap{}
+	case "Service":
+		return &corev1.Service{}
+	case "Deployment":
+		return &appsv1.Deployment{}
+	default:
+		return &appsv1.StatefulSet{}
+	}
+}
+
+func (r *DatabaseReconciler) provisionedResourceExists(ctx context.Context, database *databasesv1alpha1.Database, resource databasesv1alpha1.ProvisionedResource) (bool, error) {
+	err := r.Get(ctx, types.NamespacedName{Name: resource.Name, Namespace: database.Namespace}, newProvisionedObject(resource.Kind))
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// reconcileConfig stores the CA bundle, requests the server certificate and
+// stores the rendered engine configuration
+func (r *DatabaseReconciler) reconcileConfig(ctx context.Context, database *databasesv1alpha1.Database) error {
+	if err := r.reconcileCABundle(ctx, database); err != nil {
+		return err
+	}
+	if err := r.reconcileTLSCertificate(ctx, database); err != nil {
+		return err
+	}
+	return r.reconcileEngineConfig(ctx, database)
+}
+
+func (r *DatabaseReconciler) configResources(database *databasesv1alpha1.Database) []databasesv1alpha1.ProvisionedResource {
+	resources := []databasesv1alpha1.ProvisionedResource{}
+	if len(r.CABundle) > 0 {
+		resources = append(resources, databasesv1alpha1.ProvisionedResource{Kind: "ConfigMap", Name: database.Name + caBundleSuffix})
+	}
+	if config, err := renderEngineConfig(database); err == nil && config != nil {
+		resources = append(resources, databasesv1alpha1.ProvisionedResource{Kind: "ConfigMap", Name: engineConfigName(database, config.Hash)})
+	}
+	return resources
+}
+
+func serviceResources(database *databasesv1alpha1.Database) []databasesv1alpha1.ProvisionedResource {
+	resources := []databasesv1alpha1.ProvisionedResource{{Kind: "Service", Name: database.Name + "-service"}}
+	if database.Spec.Type != databasesv1alpha1.DatabaseTypeSQLite {
+		resources = append(resources, databasesv1alpha1.ProvisionedResource{Kind: "Service", Name: database.Name + headlessServiceSuffix})
+	}
+	return resources
+}
+
+// reconcileWorkload reconciles the StatefulSet or Deployment of the engine
+func (r *DatabaseReconciler) reconcileWorkload(ctx context.Context, database *databasesv1alpha1.Database) error {
+	// The data volumes are being replaced; the workload is recreated on them
+	// once the operation releases the lock
+	if workloadStopped(database) {
+		database.Status.ReadyReplicas = 0
+		return nil
+	}
+
+	// The engine gets its own span below the Workload step
+	engineCtx, span := startSpan(ctx, string(database.Spec.Type))
+	var err error
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		err = r.reconcilePostgreSQL(engineCtx, database)
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		err = r.reconcileMongoDB(engineCtx, database)
+	case databasesv1alpha1.DatabaseTypeRedis:
+		err = r.reconcileRedis(engineCtx, database)
+	case databasesv1alpha1.DatabaseTypeElasticsearch:
+		err = r.reconcileElasticsearch(engineCtx, database)
+	case databasesv1alpha1.DatabaseTypeSQLite:
+		err = r.reconcileSQLite(engineCtx, database)
+	default:
+		err = fmt.Errorf("unsupported database type: %s", database.Spec.Type)
+	}
+	endSpan(span, err)
+	if err != nil {
+		return err
+	}
+	return r.reconcileWorkloadMetadata(ctx, database)
+}
+
+func workloadResources(database *databasesv1alpha1.Database) []databasesv1alpha1.ProvisionedResource {
+	kind := "StatefulSet"
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite {
+		kind = "Deployment"
+	}
+	var resources []databasesv1alpha1.ProvisionedResource
+	for _, name := range workloadNames(database) {
+		resources = append(resources, databasesv1alpha1.ProvisionedResource{Kind: kind, Name: name})
+	}
+	return resources
+}
+
+// workloadNames returns the names of the workloads running the engine: one
+// per Elasticsearch node set, otherwise the one named after the Database
+func workloadNames(database *databasesv1alpha1.Database) []string {
+	nodeSets := elasticsearchNodeSets(database)
+	if len(nodeSets) == 0 {
+		return []string{database.Name}
+	}
+	names := make([]string, len(nodeSets))
+	for i, nodeSet := range nodeSets {
+		names[i] = nodeSetName(database, nodeSet)
+	}
+	return names
+}
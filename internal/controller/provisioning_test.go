@@ -0,0 +1,183 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Provisioning", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		quotaFull  bool
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		quotaFull = true
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", Generation: 1},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypeRedis,
+				Version: "7",
+				Redis:   &databasesv1alpha1.RedisConfig{Parameters: map[string]string{"maxmemory": "256mb"}},
+				Provisioning: &databasesv1alpha1.ProvisioningSpec{
+					MaxAttempts:       ptr.To(int32(2)),
+					RollbackOnFailure: true,
+				},
+			},
+		}
+
+		apply := applyPatches()
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(database).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					if _, ok := obj.(*appsv1.StatefulSet); ok && quotaFull {
+						return errors.New(`exceeded quota: compute-resources, requested: limits.memory=2Gi`)
+					}
+					return apply.Patch(ctx, c, obj, patch, opts...)
+				},
+			}).Build()
+		reconciler = &DatabaseReconciler{Client: c, Scheme: scheme}
+	})
+
+	exists := func(obj client.Object, name string) bool {
+		err := reconciler.Get(ctx, types.NamespacedName{Name: name, Namespace: "shop"}, obj)
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	It("should retry with backoff and roll back once the attempts are exhausted", func() {
+		config, _ := renderEngineConfig(database)
+		configName := engineConfigName(database, config.Hash)
+
+		var provisioningErr *provisioningError
+		Expect(errors.As(reconciler.reconcileProvisioning(ctx, database), &provisioningErr)).To(BeTrue())
+		Expect(provisioningErr.retryAfter).To(Equal(10 * time.Second))
+		Expect(database.Status.Provisioning.Created).To(Equal([]databasesv1alpha1.ProvisionedResource{
+			{Kind: "ConfigMap", Name: configName},
+			{Kind: "Service", Name: "orders-service"},
+			{Kind: "Service", Name: "orders-headless"},
+		}))
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionProvisioningFailed)
+		Expect(condition.Reason).To(Equal("WorkloadFailed"))
+		Expect(condition.Message).To(ContainSubstring("attempt 1/2"))
+
+		Expect(errors.As(reconciler.reconcileProvisioning(ctx, database), &provisioningErr)).To(BeTrue())
+		Expect(provisioningErr.retryAfter).To(BeZero())
+		Expect(database.Status.Provisioning.FailedGeneration).To(Equal(int64(1)))
+		Expect(database.Status.Provisioning.Created).To(BeEmpty())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionProvisioningFailed).Message).
+			To(ContainSubstring("rolled back"))
+		Expect(exists(&corev1.ConfigMap{}, configName)).To(BeFalse())
+		Expect(exists(&corev1.Service{}, "orders-service")).To(BeFalse())
+		Expect(exists(&corev1.Service{}, "orders-headless")).To(BeFalse())
+
+		// Nothing is retried until the spec changes
+		quotaFull = false
+		Expect(reconciler.reconcileProvisioning(ctx, database)).To(HaveOccurred())
+		Expect(exists(&corev1.Service{}, "orders-service")).To(BeFalse())
+
+		database.Generation = 2
+		Expect(reconciler.reconcileProvisioning(ctx, database)).To(Succeed())
+		Expect(database.Status.Provisioning.Completed).To(BeTrue())
+		Expect(database.Status.Provisioning.Created).To(BeEmpty())
+		Expect(meta.IsStatusConditionFalse(database.Status.Conditions, conditionProvisioningFailed)).To(BeTrue())
+		Expect(exists(&appsv1.StatefulSet{}, "orders")).To(BeTrue())
+
+		// Replicas get their DNS names from the headless Service
+		headless := &corev1.Service{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders-headless", Namespace: "shop"}, headless)).To(Succeed())
+		Expect(headless.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+		Expect(headless.Spec.PublishNotReadyAddresses).To(BeTrue())
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders", Namespace: "shop"}, statefulSet)).To(Succeed())
+		Expect(statefulSet.Spec.ServiceName).To(Equal("orders-headless"))
+	})
+
+	It("should never roll back a provisioned database", func() {
+		quotaFull = false
+		Expect(reconciler.reconcileProvisioning(ctx, database)).To(Succeed())
+
+		Expect(reconciler.Delete(ctx, &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+		})).To(Succeed())
+		quotaFull = true
+		for range 3 {
+			err := reconciler.reconcileProvisioning(ctx, database)
+			Expect(err).To(MatchError(ContainSubstring("exceeded quota")))
+			var provisioningErr *provisioningError
+			Expect(errors.As(err, &provisioningErr)).To(BeFalse())
+		}
+		Expect(exists(&corev1.Service{}, "orders-service")).To(BeTrue())
+	})
+
+	It("should record provisioning and recreated resources as Events", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		Expect(reconciler.reconcileProvisioning(ctx, database)).To(HaveOccurred())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning ProvisioningRetrying Step Workload failed (attempt 1/2")))
+
+		quotaFull = false
+		Expect(reconciler.reconcileProvisioning(ctx, database)).To(Succeed())
+		Expect(recorder.Events).To(Receive(Equal("Normal Provisioned Configuration, Service and workload are provisioned")))
+
+		Expect(reconciler.Delete(ctx, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-service", Namespace: "shop"},
+		})).To(Succeed())
+		Expect(reconciler.reconcileProvisioning(ctx, database)).To(Succeed())
+		Expect(recorder.Events).To(Receive(Equal("Normal ResourceRecreated Recreated the missing Service orders-service")))
+		Expect(reconciler.reconcileProvisioning(ctx, database)).To(Succeed())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should double the backoff up to its maximum", func() {
+		Expect(provisioningBackoff(1)).To(Equal(10 * time.Second))
+		Expect(provisioningBackoff(3)).To(Equal(40 * time.Second))
+		Expect(provisioningBackoff(20)).To(Equal(5 * time.Minute))
+	})
+})
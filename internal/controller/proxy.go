@@ -0,0 +1,130 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// ProxyConfig is the operator-wide HTTP proxy configuration of generated Jobs
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// proxyConfig returns the proxy of the Jobs of a Database, which may override the
+// operator configuration
+func (r *DatabaseReconciler) proxyConfig(database *databasesv1alpha1.Database) ProxyConfig {
+	if networking := database.Spec.Networking; networking != nil && networking.Proxy != nil {
+		return ProxyConfig{
+			HTTPProxy:  networking.Proxy.HTTPProxy,
+			HTTPSProxy: networking.Proxy.HTTPSProxy,
+			NoProxy:    networking.Proxy.NoProxy,
+		}
+	}
+	return r.Proxy
+}
+
+// addProxyEnv sets the proxy variables on a container reaching external services.
+// Both spellings are set since tools disagree on which one they read. The
+// database Service is never proxied.
+func (r *DatabaseReconciler) addProxyEnv(database *databasesv1alpha1.Database, container *corev1.Container) {
+	proxy := r.proxyConfig(database)
+	if proxy.HTTPProxy == "" && proxy.HTTPSProxy == "" {
+		return
+	}
+
+	noProxy := serviceHost(database)
+	if proxy.NoProxy != "" {
+		noProxy = strings.TrimSuffix(proxy.NoProxy, ",") + "," + noProxy
+	}
+
+	for _, variable := range []struct{ name, value string }{
+		{"HTTP_PROXY", proxy.HTTPProxy},
+		{"HTTPS_PROXY", proxy.HTTPSProxy},
+		{"NO_PROXY", noProxy},
+	} {
+		if variable.value == "" {
+			continue
+		}
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: variable.name, Value: variable.value},
+			corev1.EnvVar{Name: strings.ToLower(variable.name), Value: variable.value},
+		)
+	}
+}
+
+// proxyEgressRules allows the proxies of a Database: the proxy address when it
+// is an IP, any address otherwise, on the port of the proxy URL, the default
+// port of its scheme when it has none
+func (r *DatabaseReconciler) proxyEgressRules(database *databasesv1alpha1.Database) []networkingv1.NetworkPolicyEgressRule {
+	proxy := r.proxyConfig(database)
+	rules := []networkingv1.NetworkPolicyEgressRule{}
+	seen := map[string]bool{}
+	for _, address := range []string{proxy.HTTPProxy, proxy.HTTPSProxy} {
+		if address == "" {
+			continue
+		}
+		if !strings.Contains(address, "://") {
+			address = "http://" + address
+		}
+		proxyURL, err := url.Parse(address)
+		if err != nil || proxyURL.Hostname() == "" {
+			continue
+		}
+		port, err := strconv.Atoi(proxyURL.Port())
+		if err != nil {
+			port = 80
+			if proxyURL.Scheme == "https" {
+				port = 443
+			}
+		}
+		key := proxyURL.Hostname() + ":" + strconv.Itoa(port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		peers := []networkingv1.NetworkPolicyPeer{
+			{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}},
+			{IPBlock: &networkingv1.IPBlock{CIDR: "::/0"}},
+		}
+		if ip := net.ParseIP(proxyURL.Hostname()); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			peers = []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: ip.String() + "/" + strconv.Itoa(bits)}}}
+		}
+		tcp, policyPort := corev1.ProtocolTCP, intstr.FromInt(port)
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To:    peers,
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &policyPort}},
+		})
+	}
+	return rules
+}
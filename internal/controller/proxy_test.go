@@ -0,0 +1,92 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Job proxy", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	jobEnv := func() []corev1.EnvVar {
+		return reconciler.adminPodTemplate(database, backupComponent, "postgres:16", "true", nil).Spec.Containers[0].Env
+	}
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{ReconcilerOptions: ReconcilerOptions{Proxy: ProxyConfig{
+			HTTPSProxy: "http://proxy.corp:3128",
+			NoProxy:    "10.0.0.0/8",
+		}}}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+		}
+	})
+
+	It("should propagate the operator proxy to Jobs without proxying the database", func() {
+		Expect(jobEnv()).To(ContainElements(
+			corev1.EnvVar{Name: "HTTPS_PROXY", Value: "http://proxy.corp:3128"},
+			corev1.EnvVar{Name: "https_proxy", Value: "http://proxy.corp:3128"},
+			corev1.EnvVar{Name: "NO_PROXY", Value: "10.0.0.0/8,orders-service.shop.svc"},
+		))
+		Expect(jobEnv()).NotTo(ContainElement(HaveField("Name", "HTTP_PROXY")))
+	})
+
+	It("should let a Database override or disable the proxy", func() {
+		database.Spec.Networking = &databasesv1alpha1.NetworkingSpec{
+			Proxy: &databasesv1alpha1.ProxySpec{HTTPProxy: "http://team-proxy:8080"},
+		}
+		Expect(jobEnv()).To(ContainElements(
+			corev1.EnvVar{Name: "HTTP_PROXY", Value: "http://team-proxy:8080"},
+			corev1.EnvVar{Name: "NO_PROXY", Value: "orders-service.shop.svc"},
+		))
+		Expect(jobEnv()).NotTo(ContainElement(HaveField("Name", "HTTPS_PROXY")))
+
+		database.Spec.Networking.Proxy = &databasesv1alpha1.ProxySpec{}
+		Expect(jobEnv()).To(BeEmpty())
+	})
+
+	It("should let Jobs reach the proxy on its port", func() {
+		database.Spec.Networking = &databasesv1alpha1.NetworkingSpec{
+			NetworkPolicy: &databasesv1alpha1.NetworkPolicySpec{Enabled: true},
+		}
+		egress := reconciler.createJobsNetworkPolicy(database).Spec.Egress
+		Expect(egress).To(HaveLen(3))
+		Expect(egress[2].To).To(HaveExactElements(
+			HaveField("IPBlock.CIDR", "0.0.0.0/0"), HaveField("IPBlock.CIDR", "::/0")))
+		Expect(egress[2].Ports[0].Port.IntValue()).To(Equal(3128))
+
+		database.Spec.Networking.Proxy = &databasesv1alpha1.ProxySpec{
+			HTTPProxy:  "http://10.1.2.3:8080",
+			HTTPSProxy: "10.1.2.3:8080",
+		}
+		egress = reconciler.createJobsNetworkPolicy(database).Spec.Egress
+		Expect(egress).To(HaveLen(3))
+		Expect(egress[2].To).To(HaveExactElements(HaveField("IPBlock.CIDR", "10.1.2.3/32")))
+		Expect(egress[2].Ports[0].Port.IntValue()).To(Equal(8080))
+	})
+})
@@ -0,0 +1,187 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	keyspaceAnalysisComponent      = "keyspace-analysis"
+	keyspaceAnalysisRecommendation = "RedisBigKey"
+	defaultKeyspaceSchedule        = "0 */6 * * *"
+	defaultBigKeyThreshold         = "10Mi"
+)
+
+var (
+	// Matches "Biggest hash found "user:1" has 1048576 bytes" (quotes differ between redis-cli versions)
+	biggestKeyPattern = regexp.MustCompile(`^Biggest\s+(\w+) found ["'](.*)["'] has (\d+) bytes`)
+	// Matches "12 hashs with 4096 bytes (20.00% of keys, avg size 341.33)"
+	keyCountPattern = regexp.MustCompile(`^(\d+) (\w+) with (\d+) bytes`)
+)
+
+// keyspaceAnalysisScript runs redis-cli --memkeys and keeps only the summary lines,
+// which are reported back to the operator through the container termination message.
+const keyspaceAnalysisScript = `out=$(redis-cli -h "$DB_HOST" -p 6379 --memkeys -i 0.01) || exit 1
+echo "$out" | grep -E '^(Biggest|[0-9]+ [a-z]+ with)' > /dev/termination-log || true`
+
+func (r *DatabaseReconciler) reconcileKeyspaceAnalysis(ctx context.Context, database *databasesv1alpha1.Database) error {
+	spec := keyspaceAnalysisSpec(database)
+
+	var desired *batchv1.CronJob
+	if spec != nil && spec.Enabled {
+		desired = r.createKeyspaceAnalysisCronJob(database, spec)
+	}
+
+	cronJob, err := r.reconcileCronJob(ctx, database, keyspaceAnalysisComponent, desired)
+	if err != nil {
+		return err
+	}
+	if cronJob == nil {
+		database.Status.KeyspaceAnalysis = nil
+		setRecommendations(database, keyspaceAnalysisRecommendation, nil)
+		labels := databaseMetricLabels(database)
+		redisKeys.DeletePartialMatch(labels)
+		redisBiggestKeyBytes.DeletePartialMatch(labels)
+		return nil
+	}
+
+	return r.collectKeyspaceAnalysis(ctx, database, spec)
+}
+
+// collectKeyspaceAnalysis reads the result of the latest successful analysis Job
+// and publishes it in status and metrics
+func (r *DatabaseReconciler) collectKeyspaceAnalysis(ctx context.Context, database *databasesv1alpha1.Database, spec *databasesv1alpha1.KeyspaceAnalysisSpec) error {
+	var since *metav1.Time
+	if database.Status.KeyspaceAnalysis != nil {
+		since = database.Status.KeyspaceAnalysis.LastAnalysisTime
+	}
+
+	output, completionTime, err := r.latestAnalysisOutput(ctx, database, keyspaceAnalysisComponent, since)
+	if err != nil || completionTime == nil {
+		return err
+	}
+
+	threshold, err := resource.ParseQuantity(bigKeyThreshold(spec))
+	if err != nil {
+		return fmt.Errorf("invalid keyspace analysis bigKeyThreshold: %w", err)
+	}
+
+	result := parseKeyspaceAnalysis(output)
+	result.LastAnalysisTime = completionTime
+	database.Status.KeyspaceAnalysis = result
+
+	var recommendations []databasesv1alpha1.Recommendation
+	for _, key := range result.TopKeys {
+		if key.Bytes < threshold.Value() {
+			continue
+		}
+		recommendations = append(recommendations, databasesv1alpha1.Recommendation{
+			Type:     keyspaceAnalysisRecommendation,
+			Severity: databasesv1alpha1.RecommendationSeverityWarning,
+			Target:   key.Key,
+			Message: fmt.Sprintf("%s key uses %s of memory (threshold %s); split it into smaller keys or set a TTL to avoid evictions",
+				key.Type, resource.NewQuantity(key.Bytes, resource.BinarySI).String(), threshold.String()),
+		})
+	}
+	setRecommendations(database, keyspaceAnalysisRecommendation, recommendations)
+
+	labels := databaseMetricLabels(database)
+	redisKeys.DeletePartialMatch(labels)
+	redisBiggestKeyBytes.DeletePartialMatch(labels)
+	for keyType, count := range result.KeyCounts {
+		redisKeys.WithLabelValues(database.Namespace, database.Name, keyType).Set(float64(count))
+	}
+	for _, key := range result.TopKeys {
+		redisBiggestKeyBytes.WithLabelValues(database.Namespace, database.Name, key.Type, key.Key).Set(float64(key.Bytes))
+	}
+
+	return nil
+}
+
+// parseKeyspaceAnalysis parses the summary lines of redis-cli --memkeys
+func parseKeyspaceAnalysis(output string) *databasesv1alpha1.KeyspaceAnalysisStatus {
+	result := &databasesv1alpha1.KeyspaceAnalysisStatus{
+		KeyCounts: map[string]int64{},
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		if m := biggestKeyPattern.FindStringSubmatch(line); m != nil {
+			bytes, err := strconv.ParseInt(m[3], 10, 64)
+			if err != nil {
+				continue
+			}
+			result.TopKeys = append(result.TopKeys, databasesv1alpha1.KeyUsage{
+				Key:   m[2],
+				Type:  m[1],
+				Bytes: bytes,
+			})
+			continue
+		}
+
+		if m := keyCountPattern.FindStringSubmatch(line); m != nil {
+			count, err := strconv.ParseInt(m[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			// redis-cli pluralizes types naively ("hashs", "zsets")
+			result.KeyCounts[strings.TrimSuffix(m[2], "s")] = count
+		}
+	}
+
+	sort.SliceStable(result.TopKeys, func(i, j int) bool {
+		return result.TopKeys[i].Bytes > result.TopKeys[j].Bytes
+	})
+
+	return result
+}
+
+func (r *DatabaseReconciler) createKeyspaceAnalysisCronJob(database *databasesv1alpha1.Database, spec *databasesv1alpha1.KeyspaceAnalysisSpec) *batchv1.CronJob {
+	schedule := spec.Schedule
+	if schedule == "" {
+		schedule = defaultKeyspaceSchedule
+	}
+
+	return r.createAdminCronJob(database, keyspaceAnalysisComponent, schedule, keyspaceAnalysisScript, nil)
+}
+
+func keyspaceAnalysisSpec(database *databasesv1alpha1.Database) *databasesv1alpha1.KeyspaceAnalysisSpec {
+	if database.Spec.Redis == nil {
+		return nil
+	}
+	return database.Spec.Redis.KeyspaceAnalysis
+}
+
+func bigKeyThreshold(spec *databasesv1alpha1.KeyspaceAnalysisSpec) string {
+	if spec.BigKeyThreshold == "" {
+		return defaultBigKeyThreshold
+	}
+	return spec.BigKeyThreshold
+}
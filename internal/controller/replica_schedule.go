@@ -0,0 +1,286 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var weekdays = map[databasesv1alpha1.Weekday]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// timeWindow is an occurrence of a daily time window
+type timeWindow struct {
+	start, end time.Time
+}
+
+// scheduleWindow is an occurrence of the time window of a schedule
+type scheduleWindow struct {
+	schedule *databasesv1alpha1.ReplicaSchedule
+	timeWindow
+}
+
+// specReplicas returns the replica count of the spec, outside of any schedule
+func specReplicas(database *databasesv1alpha1.Database) int32 {
+	if nodeSets := elasticsearchNodeSets(database); len(nodeSets) > 0 {
+		return nodeSetReplicas(nodeSets)
+	}
+	if database.Spec.Replicas != nil {
+		return *database.Spec.Replicas
+	}
+	return 1
+}
+
+// desiredReplicas returns the replica count the workload should run: none
+// while hibernated, otherwise awakeReplicas
+func desiredReplicas(database *databasesv1alpha1.Database) int32 {
+	if hibernated(database) {
+		return 0
+	}
+	return awakeReplicas(database)
+}
+
+// awakeReplicas returns the replica count of a Database that is not
+// hibernated, as evaluated by reconcileReplicaSchedule
+func awakeReplicas(database *databasesv1alpha1.Database) int32 {
+	if status := database.Status.Topology; status != nil && database.Spec.Topology != nil {
+		return status.Replicas
+	}
+	return specReplicas(database)
+}
+
+// scheduleLocation returns the time zone the schedules are evaluated in
+func scheduleLocation(database *databasesv1alpha1.Database) (*time.Location, error) {
+	name := database.Spec.Topology.TimeZone
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topology.timeZone %q: %w", name, err)
+	}
+	return location, nil
+}
+
+// validateTopology checks the time zone, the cron expressions and the replica
+// counts of the schedules
+func validateTopology(database *databasesv1alpha1.Database, maxReplicas int32) error {
+	topology := database.Spec.Topology
+	if topology == nil {
+		return nil
+	}
+	if _, err := scheduleLocation(database); err != nil {
+		return err
+	}
+	for _, schedule := range topology.Schedules {
+		if schedule.Cron != "" {
+			if _, err := parseCronSchedule(schedule.Cron); err != nil {
+				return fmt.Errorf("topology schedule %s: %w", schedule.Name, err)
+			}
+		}
+		if maxReplicas > 0 && schedule.Replicas > maxReplicas {
+			return fmt.Errorf("%s supports at most %d replicas, topology schedule %s has %d",
+				database.Spec.Type, maxReplicas, schedule.Name, schedule.Replicas)
+		}
+	}
+	return nil
+}
+
+// parseClock parses an HH:MM time of day
+func parseClock(clock string) (hour, minute int, err error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, 0, fmt.Errorf("expected HH:MM, got %q", clock)
+	}
+	return parsed.Hour(), parsed.Minute(), nil
+}
+
+// scheduleWindows returns the windows of the schedules starting between the day
+// before now and a week after it, in the time zone of the schedules
+func scheduleWindows(topology *databasesv1alpha1.TopologySpec, now time.Time) []scheduleWindow {
+	windows := []scheduleWindow{}
+	for i := range topology.Schedules {
+		schedule := &topology.Schedules[i]
+		for _, occurrence := range dailyWindows(schedule.Days, schedule.Start, schedule.End, now) {
+			windows = append(windows, scheduleWindow{schedule: schedule, timeWindow: occurrence})
+		}
+	}
+	return windows
+}
+
+// dailyWindows returns the occurrences of a window from start to end (HH:MM) on
+// days, every day when empty, starting between the day before now and a week
+// after it. A window ending at or before its start ends on the next day.
+func dailyWindows(days []databasesv1alpha1.Weekday, start, end string, now time.Time) []timeWindow {
+	startHour, startMinute, err := parseClock(start)
+	if err != nil {
+		return nil
+	}
+	endHour, endMinute, err := parseClock(end)
+	if err != nil {
+		return nil
+	}
+
+	windows := []timeWindow{}
+	for offset := -1; offset <= 7; offset++ {
+		day := now.AddDate(0, 0, offset)
+		start := time.Date(day.Year(), day.Month(), day.Day(), startHour, startMinute, 0, 0, now.Location())
+		if !onDays(days, start.Weekday()) {
+			continue
+		}
+		end := time.Date(day.Year(), day.Month(), day.Day(), endHour, endMinute, 0, 0, now.Location())
+		if !end.After(start) {
+			end = time.Date(day.Year(), day.Month(), day.Day()+1, endHour, endMinute, 0, 0, now.Location())
+		}
+		windows = append(windows, timeWindow{start: start, end: end})
+	}
+	return windows
+}
+
+func onDays(days []databasesv1alpha1.Weekday, weekday time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, day := range days {
+		if weekdays[day] == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateTopology returns the replica count the schedules call for at now, the
+// schedule applying it and when a window opens or closes or a cron schedule
+// fires next. An open window wins over the cron schedules, of which the one that
+// fired last applies.
+func evaluateTopology(database *databasesv1alpha1.Database, location *time.Location, now time.Time) *databasesv1alpha1.TopologyStatus {
+	now = now.In(location)
+	status := &databasesv1alpha1.TopologyStatus{Replicas: specReplicas(database)}
+
+	var active *databasesv1alpha1.ReplicaSchedule
+	var next time.Time
+	for _, window := range scheduleWindows(database.Spec.Topology, now) {
+		// Windows are listed schedule by schedule, so the first open one wins
+		if active == nil && !window.start.After(now) && window.end.After(now) {
+			active = window.schedule
+		}
+		for _, boundary := range []time.Time{window.start, window.end} {
+			if boundary.After(now) && (next.IsZero() || boundary.Before(next)) {
+				next = boundary
+			}
+		}
+	}
+
+	// A cron schedule applies from the last time it fired
+	var latest *databasesv1alpha1.ReplicaSchedule
+	var fired time.Time
+	for i := range database.Spec.Topology.Schedules {
+		schedule := &database.Spec.Topology.Schedules[i]
+		if schedule.Cron == "" {
+			continue
+		}
+		cron, err := parseCronSchedule(schedule.Cron)
+		if err != nil {
+			continue
+		}
+		// The first schedule wins when several fired at the same time
+		if previous := cron.prev(now); !previous.IsZero() && previous.After(fired) {
+			latest, fired = schedule, previous
+		}
+		if boundary := cron.next(now); !boundary.IsZero() && (next.IsZero() || boundary.Before(next)) {
+			next = boundary
+		}
+	}
+
+	if active == nil {
+		active = latest
+	}
+	if active != nil {
+		status.ActiveSchedule = active.Name
+		status.Replicas = active.Replicas
+	}
+	if !next.IsZero() {
+		nextChange := metav1.NewTime(next)
+		status.NextChange = &nextChange
+	}
+	return status
+}
+
+// autoscaled reports whether spec.replicas is set through the scale
+// subresource, by a HorizontalPodAutoscaler or KEDA. The update is recorded in
+// the managed fields, under the manager of the autoscaler, until another
+// manager takes the field over.
+func autoscaled(database *databasesv1alpha1.Database) bool {
+	for _, entry := range database.ManagedFields {
+		if entry.Subresource == "scale" {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileReplicaSchedule evaluates the replica schedules and records the
+// replica count they call for in status, where the workloads read it. The
+// schedules step aside while an autoscaler sets spec.replicas, so the replica
+// count it sets applies.
+func (r *DatabaseReconciler) reconcileReplicaSchedule(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) error {
+	if database.Spec.Topology == nil || len(database.Spec.Topology.Schedules) == 0 {
+		database.Status.Topology = nil
+		return nil
+	}
+	if autoscaled(database) {
+		if database.Status.Topology != nil {
+			log.FromContext(ctx).Info("Replica schedules suspended, spec.replicas is set through the scale subresource")
+		}
+		database.Status.Topology = nil
+		return nil
+	}
+
+	location, err := scheduleLocation(database)
+	if err != nil {
+		return err
+	}
+	status := evaluateTopology(database, location, now)
+	if previous := database.Status.Topology; previous == nil || previous.Replicas != status.Replicas ||
+		previous.ActiveSchedule != status.ActiveSchedule {
+		log.FromContext(ctx).Info("Replica schedule changed", "schedule", status.ActiveSchedule, "replicas", status.Replicas)
+	}
+	database.Status.Topology = status
+	return nil
+}
+
+// nextTopologyChange returns the time until the replica schedule changes, if any
+func nextTopologyChange(database *databasesv1alpha1.Database, now time.Time) time.Duration {
+	if status := database.Status.Topology; status != nil && status.NextChange != nil {
+		return status.NextChange.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Replica schedules", func() {
+	var (
+		database *databasesv1alpha1.Database
+		paris    *time.Location
+	)
+
+	BeforeEach(func() {
+		var err error
+		paris, err = time.LoadLocation("Europe/Paris")
+		Expect(err).NotTo(HaveOccurred())
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:  "16",
+				Replicas: ptr.To(int32(2)),
+				Topology: &databasesv1alpha1.TopologySpec{
+					TimeZone: "Europe/Paris",
+					Schedules: []databasesv1alpha1.ReplicaSchedule{
+						{Name: "business-hours", Days: []databasesv1alpha1.Weekday{"Mon", "Tue", "Wed", "Thu", "Fri"}, Start: "08:00", End: "20:00", Replicas: 5},
+						{Name: "nightly-batch", Start: "23:00", End: "02:00", Replicas: 3},
+					},
+				},
+			},
+		}
+	})
+
+	evaluate := func(now time.Time) *databasesv1alpha1.TopologyStatus {
+		Expect((&DatabaseReconciler{}).reconcileReplicaSchedule(context.Background(), database, now)).To(Succeed())
+		return database.Status.Topology
+	}
+
+	It("should run the replicas of the open window in the time zone of the schedules", func() {
+		// Wednesday 10:00 in Paris, 09:00 UTC
+		status := evaluate(time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC))
+		Expect(status.ActiveSchedule).To(Equal("business-hours"))
+		Expect(desiredReplicas(database)).To(Equal(int32(5)))
+		Expect(status.NextChange.Time).To(BeTemporally("==", time.Date(2025, 3, 5, 20, 0, 0, 0, paris)))
+
+		By("falling back to spec.replicas outside the windows")
+		status = evaluate(time.Date(2025, 3, 8, 12, 0, 0, 0, paris))
+		Expect(status.ActiveSchedule).To(BeEmpty())
+		Expect(desiredReplicas(database)).To(Equal(int32(2)))
+		Expect(status.NextChange.Time).To(BeTemporally("==", time.Date(2025, 3, 8, 23, 0, 0, 0, paris)))
+
+		By("keeping windows crossing midnight open on the next day")
+		status = evaluate(time.Date(2025, 3, 9, 1, 30, 0, 0, paris))
+		Expect(status.ActiveSchedule).To(Equal("nightly-batch"))
+		Expect(status.NextChange.Time).To(BeTemporally("==", time.Date(2025, 3, 9, 2, 0, 0, 0, paris)))
+
+		By("dropping the status without schedules")
+		database.Spec.Topology = nil
+		Expect(evaluate(time.Now())).To(BeNil())
+		Expect(desiredReplicas(database)).To(Equal(int32(2)))
+	})
+
+	It("should apply the cron schedule that fired last outside the windows", func() {
+		database.Spec.Topology.Schedules = append(database.Spec.Topology.Schedules,
+			databasesv1alpha1.ReplicaSchedule{Name: "weekend", Cron: "0 20 * * fri", Replicas: 1},
+			databasesv1alpha1.ReplicaSchedule{Name: "week", Cron: "30 7 * * mon", Replicas: 2},
+		)
+		Expect((&DatabaseReconciler{}).validateSpec(database)).To(Succeed())
+
+		// Saturday noon in Paris, the weekend started on Friday 20:00
+		status := evaluate(time.Date(2025, 3, 8, 12, 0, 0, 0, paris))
+		Expect(status.ActiveSchedule).To(Equal("weekend"))
+		Expect(desiredReplicas(database)).To(Equal(int32(1)))
+		Expect(status.NextChange.Time).To(BeTemporally("==", time.Date(2025, 3, 8, 23, 0, 0, 0, paris)))
+
+		By("letting an open window win")
+		Expect(evaluate(time.Date(2025, 3, 8, 23, 30, 0, 0, paris)).ActiveSchedule).To(Equal("nightly-batch"))
+
+		By("requeueing when the next cron schedule fires")
+		status = evaluate(time.Date(2025, 3, 10, 7, 0, 0, 0, paris))
+		Expect(status.ActiveSchedule).To(Equal("weekend"))
+		Expect(status.NextChange.Time).To(BeTemporally("==", time.Date(2025, 3, 10, 7, 30, 0, 0, paris)))
+		Expect(evaluate(time.Date(2025, 3, 10, 7, 30, 0, 0, paris)).ActiveSchedule).To(Equal("week"))
+
+		database.Spec.Topology.Schedules[3].Cron = "0 25 * * *"
+		Expect((&DatabaseReconciler{}).validateSpec(database)).To(MatchError(ContainSubstring("topology schedule week: invalid cron schedule")))
+	})
+
+	It("should leave the replicas to an autoscaler scaling the Database", func() {
+		wednesday := time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+		Expect(evaluate(wednesday).ActiveSchedule).To(Equal("business-hours"))
+
+		database.ManagedFields = []metav1.ManagedFieldsEntry{
+			{Manager: "kubectl-client-side-apply", Operation: metav1.ManagedFieldsOperationUpdate},
+			{Manager: "horizontal-pod-autoscaler", Operation: metav1.ManagedFieldsOperationUpdate, Subresource: "scale"},
+		}
+		database.Spec.Replicas = ptr.To(int32(4))
+		Expect(evaluate(wednesday)).To(BeNil())
+		Expect(desiredReplicas(database)).To(Equal(int32(4)))
+	})
+
+	It("should validate the time zone and the replica limit of the engine", func() {
+		Expect((&DatabaseReconciler{}).validateSpec(database)).To(Succeed())
+
+		database.Spec.Topology.TimeZone = "Mars/Olympus_Mons"
+		Expect((&DatabaseReconciler{}).validateSpec(database)).To(MatchError(ContainSubstring("invalid topology.timeZone")))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeSQLite
+		database.Spec.Replicas = ptr.To(int32(1))
+		database.Spec.Topology.TimeZone = ""
+		Expect((&DatabaseReconciler{}).validateSpec(database)).To(MatchError(ContainSubstring("topology schedule business-hours has 5")))
+	})
+
+	It("should scale the StatefulSet to the scheduled replicas", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		statefulSet := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To(int32(2))},
+		}
+		Expect(controllerutil.SetControllerReference(database, statefulSet, scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(statefulSet).Build()
+		reconciler := &DatabaseReconciler{Client: c, Scheme: scheme}
+
+		evaluate(time.Date(2025, 3, 5, 10, 0, 0, 0, paris))
+		Expect(reconciler.reconcilePostgreSQL(context.Background(), database)).To(Succeed())
+
+		Expect(c.Get(context.Background(), types.NamespacedName{Name: "orders", Namespace: "shop"}, statefulSet)).To(Succeed())
+		Expect(*statefulSet.Spec.Replicas).To(Equal(int32(5)))
+	})
+})
@@ -0,0 +1,101 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// progressingRequeueInterval follows a workload moving towards the spec
+	progressingRequeueInterval = 15 * time.Second
+	// notReadyRequeueInterval checks a Database that is neither progressing nor Ready
+	notReadyRequeueInterval = time.Minute
+	// readyRequeueInterval checks a Ready Database for changes not watched
+	readyRequeueInterval = 10 * time.Minute
+
+	// failureBaseBackoff and failureMaxBackoff bound the backoff of failed reconciles
+	failureBaseBackoff = 5 * time.Second
+	failureMaxBackoff  = 10 * time.Minute
+	// failureJitter is the fraction of the backoff added at random, so Databases
+	// failing together, e.g. on an API outage, are not retried together
+	failureJitter = 0.2
+)
+
+// requeueInterval returns how long a reconciled Database waits for the next
+// reconcile, from its state: short while the workload progresses, long once
+// it is Ready
+func requeueInterval(database *databasesv1alpha1.Database) time.Duration {
+	// Progressing covers provisioning, starting replicas and the rollouts of
+	// upgrades and other operations
+	if meta.IsStatusConditionTrue(database.Status.Conditions, conditionProgressing) {
+		return progressingRequeueInterval
+	}
+	if meta.IsStatusConditionTrue(database.Status.Conditions, conditionReady) {
+		return readyRequeueInterval
+	}
+	return notReadyRequeueInterval
+}
+
+// failureBackoff doubles the delay after each consecutive failed reconcile and
+// adds up to failureJitter of it at random
+func failureBackoff(failures int) time.Duration {
+	backoff := failureBaseBackoff
+	for i := 1; i < failures && backoff < failureMaxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, failureMaxBackoff)
+	return backoff + time.Duration(rand.Float64()*failureJitter*float64(backoff))
+}
+
+// reconcileFailures counts the consecutive failed reconciles of each Database
+type reconcileFailures struct {
+	mu     sync.Mutex
+	counts map[types.NamespacedName]int
+}
+
+func newReconcileFailures() *reconcileFailures {
+	return &reconcileFailures{counts: map[types.NamespacedName]int{}}
+}
+
+// record counts a failed reconcile and returns the number of consecutive ones
+func (f *reconcileFailures) record(key types.NamespacedName) int {
+	if f == nil {
+		return 1
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counts[key]++
+	return f.counts[key]
+}
+
+// reset forgets the failures of a Database once it reconciled
+func (f *reconcileFailures) reset(key types.NamespacedName) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.counts, key)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Requeue", func() {
+	It("should follow the state of the Database", func() {
+		database := &databasesv1alpha1.Database{}
+		Expect(requeueInterval(database)).To(Equal(notReadyRequeueInterval))
+
+		setCondition(database, conditionReady, metav1.ConditionTrue, reasonDatabaseReady, "ready")
+		Expect(requeueInterval(database)).To(Equal(readyRequeueInterval))
+
+		setCondition(database, conditionProgressing, metav1.ConditionTrue, reasonOperationRunning, "Update")
+		Expect(requeueInterval(database)).To(Equal(progressingRequeueInterval))
+	})
+
+	It("should back off exponentially with jitter up to the maximum", func() {
+		for failures, base := range map[int]time.Duration{
+			1:  failureBaseBackoff,
+			2:  2 * failureBaseBackoff,
+			4:  8 * failureBaseBackoff,
+			50: failureMaxBackoff,
+		} {
+			backoff := failureBackoff(failures)
+			Expect(backoff).To(BeNumerically(">=", base))
+			Expect(backoff).To(BeNumerically("<=", base+time.Duration(failureJitter*float64(base))))
+		}
+	})
+
+	It("should count consecutive failures until the Database reconciles", func() {
+		failures := newReconcileFailures()
+		key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+		Expect(failures.record(key)).To(Equal(1))
+		Expect(failures.record(key)).To(Equal(2))
+		Expect(failures.record(types.NamespacedName{Name: "cache", Namespace: "shop"})).To(Equal(1))
+
+		failures.reset(key)
+		Expect(failures.record(key)).To(Equal(1))
+	})
+})
@@ -0,0 +1,154 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// operatorDomain prefixes the labels and annotations the operator manages
+const operatorDomain = "databases.database-operator.io/"
+
+// operatorLabels are the labels the operator selects its resources with
+var operatorLabels = []string{
+	"app", "database-type", "app.kubernetes.io/name", "app.kubernetes.io/instance",
+	"app.kubernetes.io/component", "app.kubernetes.io/managed-by",
+}
+
+// resourceMetadata returns the metadata added to generated resources
+func resourceMetadata(database *databasesv1alpha1.Database) databasesv1alpha1.ResourceMetadataSpec {
+	if database.Spec.Metadata == nil {
+		return databasesv1alpha1.ResourceMetadataSpec{}
+	}
+	return *database.Spec.Metadata
+}
+
+// validateResourceMetadata rejects the labels and annotations the operator
+// manages, which spec.metadata would fight over
+func validateResourceMetadata(database *databasesv1alpha1.Database) error {
+	metadata := resourceMetadata(database)
+	for _, entry := range []struct {
+		field    string
+		metadata *databasesv1alpha1.ObjectMetadata
+	}{
+		{"service", metadata.Service},
+		{"workload", metadata.Workload},
+		{"pods", metadata.Pods},
+		{"persistentVolumeClaims", metadata.PersistentVolumeClaims},
+	} {
+		if entry.metadata == nil {
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(entry.metadata.Labels)) {
+			if slices.Contains(operatorLabels, key) || strings.HasPrefix(key, operatorDomain) {
+				return fmt.Errorf("metadata.%s.labels: label %s is managed by the operator", entry.field, key)
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(entry.metadata.Annotations)) {
+			if strings.HasPrefix(key, operatorDomain) {
+				return fmt.Errorf("metadata.%s.annotations: annotation %s is managed by the operator", entry.field, key)
+			}
+			if entry.field == "service" && (key == externalDNSHostnameAnnotation || key == externalDNSTTLAnnotation) {
+				return fmt.Errorf("metadata.service.annotations: annotation %s is managed by the operator, use networking.externalDNS", key)
+			}
+		}
+	}
+	return nil
+}
+
+// mergeMetadata adds labels and annotations to a generated resource and
+// reports whether it changed. Entries removed from the spec stay on the
+// resource.
+func mergeMetadata(object *metav1.ObjectMeta, metadata *databasesv1alpha1.ObjectMetadata) bool {
+	if metadata == nil {
+		return false
+	}
+	changed := false
+	merge := func(current map[string]string, added map[string]string) map[string]string {
+		for key, value := range added {
+			if existing, ok := current[key]; ok && existing == value {
+				continue
+			}
+			if current == nil {
+				current = map[string]string{}
+			}
+			current[key] = value
+			changed = true
+		}
+		return current
+	}
+	object.Labels = merge(object.Labels, metadata.Labels)
+	object.Annotations = merge(object.Annotations, metadata.Annotations)
+	return changed
+}
+
+// applyWorkloadMetadata adds the metadata of the workload, its pods and its
+// volume claims to a workload being created
+func applyWorkloadMetadata(database *databasesv1alpha1.Database, workload *metav1.ObjectMeta, template *corev1.PodTemplateSpec, claims []corev1.PersistentVolumeClaim) {
+	metadata := resourceMetadata(database)
+	mergeMetadata(workload, metadata.Workload)
+	mergeMetadata(&template.ObjectMeta, metadata.Pods)
+	for i := range claims {
+		mergeMetadata(&claims[i].ObjectMeta, metadata.PersistentVolumeClaims)
+	}
+}
+
+// reconcileWorkloadMetadata adds the workload metadata to an existing
+// workload. Its metadata is not part of the recorded applied spec.
+func (r *DatabaseReconciler) reconcileWorkloadMetadata(ctx context.Context, database *databasesv1alpha1.Database) error {
+	metadata := resourceMetadata(database).Workload
+	if metadata == nil {
+		return nil
+	}
+	for _, name := range workloadNames(database) {
+		var workload client.Object = &appsv1.StatefulSet{}
+		if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite {
+			workload = &appsv1.Deployment{}
+		}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, workload); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		objectMeta := metav1.ObjectMeta{Labels: workload.GetLabels(), Annotations: workload.GetAnnotations()}
+		if !mergeMetadata(&objectMeta, metadata) {
+			continue
+		}
+		workload.SetLabels(objectMeta.Labels)
+		workload.SetAnnotations(objectMeta.Annotations)
+		log.FromContext(ctx).Info("Updating the metadata of the workload", "name", name)
+		if err := r.Update(ctx, workload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Resource metadata", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+	costCenter := map[string]string{"cost-center": "checkout"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build(), Scheme: scheme}
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Storage: &databasesv1alpha1.StorageSpec{Size: "1Gi"},
+				Metadata: &databasesv1alpha1.ResourceMetadataSpec{
+					Workload:               &databasesv1alpha1.ObjectMetadata{Labels: costCenter},
+					Pods:                   &databasesv1alpha1.ObjectMetadata{Annotations: map[string]string{"sidecar.istio.io/inject": "false"}},
+					PersistentVolumeClaims: &databasesv1alpha1.ObjectMetadata{Labels: costCenter},
+				},
+			},
+		}
+	})
+
+	It("should add the metadata to the workload, its pods and its volume claims", func() {
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+		statefulSet := reconciler.createPostgreSQLStatefulSet(database, 1, reconciler.getPostgreSQLEnv(database))
+
+		Expect(statefulSet.Labels).To(HaveKeyWithValue("cost-center", "checkout"))
+		Expect(statefulSet.Labels).To(HaveKeyWithValue("app", "orders"))
+		Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue("sidecar.istio.io/inject", "false"))
+		Expect(statefulSet.Spec.Template.Labels).NotTo(HaveKey("cost-center"))
+		Expect(statefulSet.Spec.VolumeClaimTemplates[0].Labels).To(Equal(costCenter))
+
+		database.Spec.Metadata.Pods.Labels = map[string]string{"app": "checkout"}
+		Expect(reconciler.validateSpec(database)).To(MatchError("metadata.pods.labels: label app is managed by the operator"))
+		database.Spec.Metadata.Pods.Labels = nil
+		database.Spec.Metadata.Service = &databasesv1alpha1.ObjectMetadata{
+			Annotations: map[string]string{externalDNSHostnameAnnotation: "orders.example.com"},
+		}
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("use networking.externalDNS")))
+	})
+
+	It("should add the metadata to the existing Service and workload", func() {
+		database.Spec.Metadata = nil
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		Expect(reconciler.reconcileWorkload(ctx, database)).To(Succeed())
+
+		database.Spec.Metadata = &databasesv1alpha1.ResourceMetadataSpec{
+			Service: &databasesv1alpha1.ObjectMetadata{
+				Labels:      costCenter,
+				Annotations: map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"},
+			},
+			Workload: &databasesv1alpha1.ObjectMetadata{Labels: costCenter},
+		}
+		Expect(reconciler.reconcileService(ctx, database)).To(Succeed())
+		Expect(reconciler.reconcileWorkload(ctx, database)).To(Succeed())
+
+		service := &corev1.Service{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders-service", Namespace: "shop"}, service)).To(Succeed())
+		Expect(service.Labels).To(HaveKeyWithValue("cost-center", "checkout"))
+		Expect(service.Annotations).To(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-internal", "true"))
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(reconciler.Get(ctx, key, statefulSet)).To(Succeed())
+		Expect(statefulSet.Labels).To(HaveKeyWithValue("cost-center", "checkout"))
+		Expect(statefulSet.Spec.Template.Labels).NotTo(HaveKey("cost-center"))
+	})
+})
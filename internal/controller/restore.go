@@ -0,0 +1,297 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	restoreComponent            = "restore"
+	restoreMountPath            = "/restore"
+	restoreDownloadContainer    = "download"
+	restoreDecryptedVolume      = "decrypted"
+	restoreDecryptedPath        = "/decrypted"
+	defaultRestoreDownloadImage = "amazon/aws-cli"
+	connectionLimitTimeout      = 10 * time.Second
+)
+
+// Commands restoring $RESTORE_FILE into the database, replacing the objects it
+// contains. Redis is missing since an RDB file can only be loaded at startup.
+var restoreScripts = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: postgreSQLRestoreScript,
+	databasesv1alpha1.DatabaseTypeMongoDB: `mongorestore --host "$DB_HOST" -u "$MONGO_USERNAME" -p "$MONGO_PASSWORD" ` +
+		`--authenticationDatabase admin --drop --gzip --archive="$RESTORE_FILE" $RESTORE_OPTIONS`,
+	databasesv1alpha1.DatabaseTypeSQLite: `sqlite3 "$SQLITE_DATABASE" ".restore '$RESTORE_FILE'"`,
+}
+
+// postgreSQLRestoreScript quiesces the database before restoring it: new client
+// sessions are refused through a connection limit of 0, which superusers such as
+// the restore itself are exempt from, and open sessions are terminated. The
+// controller records the previous limit beforehand and puts it back once the Job
+// finished, so it survives a killed restore pod. The restore runs in a single
+// transaction, so a failed restore leaves the database untouched.
+const postgreSQLRestoreScript = `set -e
+export PGHOST="$DB_HOST"
+psql -q -v ON_ERROR_STOP=1 <<'SQL'
+SELECT format('ALTER DATABASE %I CONNECTION LIMIT 0', current_database())\gexec
+SQL
+psql -q -v ON_ERROR_STOP=1 -c "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = current_database() AND pid <> pg_backend_pid() AND backend_type = 'client backend'" >/dev/null
+pg_restore -d "$PGDATABASE" --clean --if-exists --no-owner --single-transaction "$RESTORE_FILE"`
+
+// restoreConnectionLimits read and set the connection limit of the database a
+// PostgreSQL restore quiesces
+var restoreConnectionLimits = struct {
+	get func(ctx context.Context, conn adminConnection) (int32, error)
+	set func(ctx context.Context, conn adminConnection, limit int32) error
+}{
+	get: func(ctx context.Context, conn adminConnection) (int32, error) {
+		rows, err := postgresQuery(ctx, conn, "SELECT datconnlimit FROM pg_database WHERE datname = current_database()")
+		if err != nil {
+			return 0, err
+		}
+		if len(rows) != 1 {
+			return 0, fmt.Errorf("database %q not found", conn.database)
+		}
+		value, _ := rows[0]["datconnlimit"].(string)
+		limit, err := strconv.ParseInt(value, 10, 32)
+		return int32(limit), err
+	},
+	set: func(ctx context.Context, conn adminConnection, limit int32) error {
+		return postgresExec(ctx, conn, fmt.Sprintf("ALTER DATABASE %s CONNECTION LIMIT %d",
+			pgx.Identifier{conn.database}.Sanitize(), limit))
+	},
+}
+
+// quiescedConnectionLimit records the connection limit a restore lowers to 0,
+// nil for engines other than PostgreSQL
+func (r *DatabaseRestoreReconciler) quiescedConnectionLimit(ctx context.Context, database *databasesv1alpha1.Database) (*databasesv1alpha1.RestoreConnectionLimitStatus, error) {
+	if database.Spec.Type != databasesv1alpha1.DatabaseTypePostgreSQL {
+		return nil, nil
+	}
+	conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, connectionLimitTimeout)
+	defer cancel()
+	limit, err := restoreConnectionLimits.get(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the connection limit: %w", err)
+	}
+	return &databasesv1alpha1.RestoreConnectionLimitStatus{Previous: limit}, nil
+}
+
+// restoreConnectionLimit puts back the connection limit a restore lowered
+func (r *DatabaseRestoreReconciler) restoreConnectionLimit(ctx context.Context, database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore) error {
+	status := restore.Status.ConnectionLimit
+	if status == nil || status.RestoredAt != nil {
+		return nil
+	}
+	conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, connectionLimitTimeout)
+	defer cancel()
+	if err := restoreConnectionLimits.set(ctx, conn, status.Previous); err != nil {
+		return fmt.Errorf("failed to restore the connection limit of %d: %w", status.Previous, err)
+	}
+	log.FromContext(ctx).Info("Restored the connection limit", "limit", status.Previous)
+	now := metav1.Now()
+	status.RestoredAt = &now
+	return nil
+}
+
+// downloadScript fetches the backup of an S3 restore source
+const downloadScript = `aws s3 cp "$SOURCE_URI" "$RESTORE_FILE"`
+
+// parseBackupLocation splits a pvc://<claim>/<file> backup location
+func parseBackupLocation(location string) (claim, file string, err error) {
+	rest, ok := strings.CutPrefix(location, "pvc://")
+	if !ok {
+		return "", "", fmt.Errorf("unsupported backup location %q", location)
+	}
+	claim, file, ok = strings.Cut(rest, "/")
+	if !ok || claim == "" || file == "" {
+		return "", "", fmt.Errorf("invalid backup location %q", location)
+	}
+	return claim, file, nil
+}
+
+// createRestoreJob builds the Job restoring a backup into the database. Backups on
+// a volume are read in place; S3 backups are downloaded by an init container.
+// Encrypted backups are decrypted by an init container with the given settings.
+func (r *DatabaseReconciler) createRestoreJob(database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore, backupLocation string, encryption *databasesv1alpha1.BackupEncryption) (*batchv1.Job, error) {
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite && database.Spec.Storage == nil {
+		return nil, fmt.Errorf("%s restores into the data volume, Database %s has no storage", database.Spec.Type, database.Name)
+	}
+
+	volume := corev1.Volume{Name: restoreComponent}
+	var restoreFile string
+
+	if s3 := restore.Spec.Source.S3; s3 != nil {
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+		restoreFile = path.Join(restoreMountPath, path.Base(s3.URI))
+	} else {
+		claim, file, err := parseBackupLocation(backupLocation)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasSuffix(strings.TrimSuffix(file, backupEncryptedExtension), "."+backupExtensions[database.Spec.Type]) {
+			return nil, fmt.Errorf("backup %s is not a %s backup", file, database.Spec.Type)
+		}
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim, ReadOnly: true}
+		restoreFile = path.Join(restoreMountPath, file)
+	}
+
+	encrypted := strings.HasSuffix(restoreFile, backupEncryptedExtension)
+	if encrypted && encryption == nil {
+		return nil, fmt.Errorf("backup %s is encrypted, backup.encryption of Database %s must give access to its KMS key",
+			path.Base(restoreFile), database.Name)
+	}
+
+	env := []corev1.EnvVar{{Name: "RESTORE_FILE", Value: restoreFile}}
+	job := r.createAdminJob(database, restoreComponent, restoreScripts[database.Spec.Type], env)
+	job.Name = restore.Name + "-" + restoreComponent
+	// The Job is owned by the DatabaseRestore and kept until it is deleted
+	job.Spec.TTLSecondsAfterFinished = nil
+	backoffLimit := int32(0)
+	job.Spec.BackoffLimit = &backoffLimit
+
+	podSpec := &job.Spec.Template.Spec
+	mount := corev1.VolumeMount{Name: restoreComponent, MountPath: restoreMountPath}
+	podSpec.Volumes = append(podSpec.Volumes, volume)
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, mount)
+
+	// SQLite is restored into its data volume rather than over the network
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite {
+		r.mountSQLiteData(database, podSpec)
+	}
+
+	if s3 := restore.Spec.Source.S3; s3 != nil {
+		podSpec.InitContainers = append(podSpec.InitContainers, restoreDownloadInitContainer(s3, restoreFile, mount))
+		download := &podSpec.InitContainers[len(podSpec.InitContainers)-1]
+		if encrypted {
+			download.Command[2] += ` && aws s3 cp "$SOURCE_URI` + backupManifestSuffix + `" "$RESTORE_FILE` + backupManifestSuffix + `"`
+		}
+		r.addCABundle(database, podSpec, download)
+		r.addProxyEnv(database, download)
+		r.restrictContainer(database, download)
+	}
+
+	if encrypted {
+		decrypted := corev1.VolumeMount{Name: restoreDecryptedVolume, MountPath: restoreDecryptedPath}
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name:         restoreDecryptedVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+		file := r.addBackupDecryption(database, podSpec, encryption, restoreFile, mount, decrypted)
+		container := &podSpec.Containers[0]
+		container.VolumeMounts = append(container.VolumeMounts, decrypted)
+		for i := range container.Env {
+			if container.Env[i].Name == "RESTORE_FILE" {
+				container.Env[i].Value = file
+			}
+		}
+	}
+
+	if restore.Spec.PerformanceMode {
+		r.addRestorePerformanceMode(database, job)
+	}
+
+	return job, nil
+}
+
+// restoreDownloadInitContainer downloads an S3 backup into the restore volume
+func restoreDownloadInitContainer(s3 *databasesv1alpha1.S3Source, restoreFile string, mount corev1.VolumeMount) corev1.Container {
+	image := s3.Image
+	if image == "" {
+		image = defaultRestoreDownloadImage
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "SOURCE_URI", Value: s3.URI},
+		{Name: "RESTORE_FILE", Value: restoreFile},
+	}
+	if s3.Endpoint != "" {
+		env = append(env, corev1.EnvVar{Name: "AWS_ENDPOINT_URL", Value: s3.Endpoint})
+	}
+	if s3.Region != "" {
+		env = append(env, corev1.EnvVar{Name: "AWS_REGION", Value: s3.Region})
+	}
+	env = append(env, s3CredentialsEnv(s3.CredentialsSecret)...)
+
+	return corev1.Container{
+		Name:         restoreDownloadContainer,
+		Image:        image,
+		Command:      []string{"/bin/sh", "-c", downloadScript},
+		Env:          env,
+		VolumeMounts: []corev1.VolumeMount{mount},
+	}
+}
+
+// restoreProgress describes the step of a running restore from its pods
+func restoreProgress(pods []corev1.Pod) string {
+	for _, pod := range pods {
+		for _, status := range pod.Status.InitContainerStatuses {
+			if status.State.Running == nil {
+				continue
+			}
+			switch status.Name {
+			case restoreDownloadContainer:
+				return "Downloading backup"
+			case backupDecryptContainer:
+				return "Decrypting backup"
+			case restoreRelaxContainer:
+				return "Relaxing durability settings"
+			}
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == restoreComponent && status.State.Running != nil {
+				return "Restoring backup"
+			}
+		}
+	}
+	return "Starting"
+}
+
+// runningRestore returns the name of the DatabaseRestore holding the operation lock
+func runningRestore(database *databasesv1alpha1.Database) (string, bool) {
+	return strings.CutPrefix(activeOperation(database), disruptiveOperationRestore+"/")
+}
+
+// markRestoring keeps the Database out of Ready while a restore rewrites its data
+func markRestoring(database *databasesv1alpha1.Database, restore string) {
+	message := fmt.Sprintf("DatabaseRestore %s in progress", restore)
+	setPhase(database, databasesv1alpha1.DatabasePhaseRestoring, "", time.Now())
+	database.Status.Message = message
+	setCondition(database, conditionReady, metav1.ConditionFalse, reasonRestoring, message)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// forkOrigin returns the name of the Database a fork copies. It is empty, with the
+// restore status set, while the origin cannot be determined.
+func (r *DatabaseRestoreReconciler) forkOrigin(ctx context.Context, restore *databasesv1alpha1.DatabaseRestore) (string, error) {
+	if from := restore.Spec.Fork.From; from != nil && from.Name != "" {
+		return from.Name, nil
+	}
+
+	source := restore.Spec.Source
+	switch {
+	case source.BackupRef != nil:
+		backup := &databasesv1alpha1.DatabaseBackup{}
+		if err := r.Get(ctx, types.NamespacedName{Name: source.BackupRef.Name, Namespace: restore.Namespace}, backup); err != nil {
+			if !errors.IsNotFound(err) {
+				return "", err
+			}
+			setRestorePending(restore, fmt.Sprintf("DatabaseBackup %q not found", source.BackupRef.Name))
+			return "", nil
+		}
+		return backup.Spec.DatabaseRef.Name, nil
+	case source.WALArchive != nil && source.WALArchive.DatabaseRef != nil:
+		return source.WALArchive.DatabaseRef.Name, nil
+	}
+	failRestore(restore, "fork.from is required to fork this source")
+	return "", nil
+}
+
+// reconcileFork creates the Database a fork restores into from the spec of its
+// origin, and reports whether the restore can start. A Database that exists and
+// was not created by the restore is never restored into, so a fork cannot
+// overwrite the data of another Database.
+func (r *DatabaseRestoreReconciler) reconcileFork(ctx context.Context, restore *databasesv1alpha1.DatabaseRestore) (bool, error) {
+	origin, err := r.forkOrigin(ctx, restore)
+	if err != nil || origin == "" {
+		return false, err
+	}
+	if origin == restore.Spec.DatabaseRef.Name {
+		failRestore(restore, fmt.Sprintf("A fork restores into a new Database, databaseRef names its origin %q", origin))
+		return false, nil
+	}
+
+	fork := &databasesv1alpha1.Database{}
+	err = r.Get(ctx, databaseKey(restore), fork)
+	if errors.IsNotFound(err) {
+		source := &databasesv1alpha1.Database{}
+		if err := r.Get(ctx, types.NamespacedName{Name: origin, Namespace: restore.Namespace}, source); err != nil {
+			if !errors.IsNotFound(err) {
+				return false, err
+			}
+			setRestorePending(restore, fmt.Sprintf("Database %q not found", origin))
+			return false, nil
+		}
+
+		fork = newForkDatabase(source, restore)
+		log.FromContext(ctx).Info("Creating forked Database", "name", fork.Name, "origin", origin)
+		if err := r.Create(ctx, fork); err != nil {
+			return false, err
+		}
+		setRestorePending(restore, fmt.Sprintf("Waiting for forked Database %q to become ready", fork.Name))
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if fork.Annotations[databasesv1alpha1.ForkRestoreAnnotation] != restore.Name {
+		failRestore(restore, fmt.Sprintf("Database %q already exists, a fork restores into a new Database", fork.Name))
+		return false, nil
+	}
+	// Once started the restore itself takes the Database out of Ready
+	started := restore.Status.Phase != "" && restore.Status.Phase != databasesv1alpha1.DatabaseRestorePhasePending
+	if !started && fork.Status.Phase != databasesv1alpha1.DatabasePhaseReady {
+		setRestorePending(restore, fmt.Sprintf("Waiting for forked Database %q to become ready", fork.Name))
+		return false, nil
+	}
+	return true, nil
+}
+
+// newForkDatabase builds the Database of a fork from the spec of its origin.
+// Backups are left out so the fork never writes into the backups or the WAL
+// archive settings of its origin, and deleting it never takes a final backup.
+func newForkDatabase(origin *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore) *databasesv1alpha1.Database {
+	fork := &databasesv1alpha1.Database{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        restore.Spec.DatabaseRef.Name,
+			Namespace:   restore.Namespace,
+			Labels:      map[string]string{databasesv1alpha1.ForkedFromLabel: origin.Name},
+			Annotations: map[string]string{databasesv1alpha1.ForkRestoreAnnotation: restore.Name},
+		},
+		Spec: *origin.Spec.DeepCopy(),
+	}
+	fork.Spec.Backup = nil
+	fork.Spec.DeletionPolicy = databasesv1alpha1.DeletionPolicyDelete
+	fork.Spec.DeletionSnapshotTimeout = nil
+	if replicas := restore.Spec.Fork.Replicas; replicas != nil {
+		fork.Spec.Replicas = replicas
+		fork.Spec.Topology = nil
+	}
+	return fork
+}
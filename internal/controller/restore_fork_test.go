@@ -0,0 +1,145 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Fork restores", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *DatabaseRestoreReconciler
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "shop"}
+	}
+
+	reconcile := func(name string) *databasesv1alpha1.DatabaseRestore {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key(name)})
+		Expect(err).NotTo(HaveOccurred())
+		restore := &databasesv1alpha1.DatabaseRestore{}
+		Expect(c.Get(ctx, key(name), restore)).To(Succeed())
+		return restore
+	}
+
+	newFork := func(name, target string) *databasesv1alpha1.DatabaseRestore {
+		return &databasesv1alpha1.DatabaseRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseRestoreSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: target},
+				Source:      databasesv1alpha1.RestoreSource{BackupRef: &corev1.LocalObjectReference{Name: "nightly"}},
+				Fork:        &databasesv1alpha1.ForkSpec{Replicas: ptr.To(int32(1))},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}, &databasesv1alpha1.DatabaseRestore{}).
+			WithObjects(
+				&databasesv1alpha1.Database{
+					ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+					Spec: databasesv1alpha1.DatabaseSpec{
+						Type:           databasesv1alpha1.DatabaseTypePostgreSQL,
+						Version:        "16",
+						Replicas:       ptr.To(int32(3)),
+						Backup:         &databasesv1alpha1.BackupSpec{Enabled: true, Schedule: "0 2 * * *"},
+						DeletionPolicy: databasesv1alpha1.DeletionPolicySnapshot,
+					},
+				},
+				&databasesv1alpha1.Database{
+					ObjectMeta: metav1.ObjectMeta{Name: "reporting", Namespace: "shop"},
+					Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+				},
+				&databasesv1alpha1.DatabaseBackup{
+					ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "shop"},
+					Spec:       databasesv1alpha1.DatabaseBackupSpec{DatabaseRef: corev1.LocalObjectReference{Name: "orders"}},
+					Status: databasesv1alpha1.DatabaseBackupStatus{
+						Phase:    databasesv1alpha1.DatabaseBackupPhaseCompleted,
+						Location: "pvc://orders-backups/nightly.dump",
+					},
+				},
+			).Build()
+		reconciler = &DatabaseRestoreReconciler{Client: c, Scheme: scheme, APIReader: passwordReader{}}
+		stubConnectionLimits(100)
+	})
+
+	It("should create the fork from its origin and restore into it once ready", func() {
+		Expect(c.Create(ctx, newFork("drill", "orders-drill"))).To(Succeed())
+
+		restore := reconcile("drill")
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhasePending))
+		Expect(restore.Status.Message).To(Equal(`Waiting for forked Database "orders-drill" to become ready`))
+
+		fork := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key("orders-drill"), fork)).To(Succeed())
+		Expect(fork.Labels).To(HaveKeyWithValue(databasesv1alpha1.ForkedFromLabel, "orders"))
+		Expect(fork.Spec.Version).To(Equal("16"))
+		Expect(*fork.Spec.Replicas).To(Equal(int32(1)))
+		Expect(fork.Spec.Backup).To(BeNil())
+		Expect(fork.Spec.DeletionPolicy).To(Equal(databasesv1alpha1.DeletionPolicyDelete))
+
+		By("restoring once the fork is ready")
+		fork.Status.Phase = databasesv1alpha1.DatabasePhaseReady
+		Expect(c.Status().Update(ctx, fork)).To(Succeed())
+		restore = reconcile("drill")
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, key("drill-restore"), job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "orders-backups")))
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "DB_HOST", Value: "orders-drill-service.shop.svc"}))
+
+		origin := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key("orders"), origin)).To(Succeed())
+		Expect(origin.Status.Operations).To(BeNil())
+	})
+
+	It("should never restore into an existing Database", func() {
+		Expect(c.Create(ctx, newFork("overwrite", "reporting"))).To(Succeed())
+		Expect(reconcile("overwrite").Status.Message).To(Equal(
+			`Database "reporting" already exists, a fork restores into a new Database`))
+
+		Expect(c.Create(ctx, newFork("in-place", "orders"))).To(Succeed())
+		Expect(reconcile("in-place").Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseFailed))
+	})
+})
@@ -0,0 +1,165 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	restoreRelaxContainer  = "relax"
+	restoreRevertComponent = "restore-revert"
+)
+
+// restorePerformanceMode describes how an engine is tuned for bulk loads
+type restorePerformanceMode struct {
+	// settings are reported in the restore status
+	settings []string
+	// relax runs in an init container of the restore Job, and revert in a Job
+	// once it finished. Without them the settings only apply to the restore session.
+	relax, revert string
+	// options are appended to the restore command
+	options string
+}
+
+// postgreSQLRestoreSettings are changed with ALTER SYSTEM and a configuration
+// reload, none of them needing a restart
+var postgreSQLRestoreSettings = []struct{ name, value string }{
+	{"fsync", "off"},
+	{"full_page_writes", "off"},
+	{"synchronous_commit", "off"},
+	{"maintenance_work_mem", "1GB"},
+}
+
+var restorePerformanceModes = map[databasesv1alpha1.DatabaseType]restorePerformanceMode{
+	databasesv1alpha1.DatabaseTypePostgreSQL: postgreSQLRestorePerformanceMode(),
+	// Journaling cannot be turned off at runtime, so documents are written
+	// without waiting for the journal, by several workers per collection
+	databasesv1alpha1.DatabaseTypeMongoDB: {
+		settings: []string{"writeConcern={w:1,j:false}", "numInsertionWorkersPerCollection=4"},
+		options:  "--writeConcern={w:1,j:false} --numInsertionWorkersPerCollection=4",
+	},
+}
+
+// postgreSQLRestorePerformanceMode relaxes the PostgreSQL settings, and reverts
+// them with a checkpoint so the pages written meanwhile are flushed
+func postgreSQLRestorePerformanceMode() restorePerformanceMode {
+	mode := restorePerformanceMode{}
+	relax := []string{`psql -h "$DB_HOST" -v ON_ERROR_STOP=1`}
+	revert := []string{`psql -h "$DB_HOST" -v ON_ERROR_STOP=1`}
+	for _, setting := range postgreSQLRestoreSettings {
+		mode.settings = append(mode.settings, setting.name+"="+setting.value)
+		relax = append(relax, fmt.Sprintf(`-c "ALTER SYSTEM SET %s = '%s'"`, setting.name, setting.value))
+		revert = append(revert, fmt.Sprintf(`-c "ALTER SYSTEM RESET %s"`, setting.name))
+	}
+	mode.relax = strings.Join(append(relax, `-c "SELECT pg_reload_conf()"`), " ")
+	mode.revert = strings.Join(append(revert, `-c "SELECT pg_reload_conf()"`, `-c "CHECKPOINT"`), " ")
+	return mode
+}
+
+// restorePerformanceError explains why a restore cannot run in performance mode
+func restorePerformanceError(database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore, snapshot bool) string {
+	if !restore.Spec.PerformanceMode {
+		return ""
+	}
+	if snapshot || restore.Spec.Source.WALArchive != nil {
+		return "performanceMode only applies to restores of dumps"
+	}
+	if _, ok := restorePerformanceModes[database.Spec.Type]; !ok {
+		return fmt.Sprintf("%s does not support restore performance mode", database.Spec.Type)
+	}
+	return ""
+}
+
+// addRestorePerformanceMode relaxes the engine settings in the restore Job. The
+// init container runs after the download, to keep the relaxed window short.
+func (r *DatabaseReconciler) addRestorePerformanceMode(database *databasesv1alpha1.Database, job *batchv1.Job) {
+	mode := restorePerformanceModes[database.Spec.Type]
+	podSpec := &job.Spec.Template.Spec
+	restore := &podSpec.Containers[0]
+
+	if mode.options != "" {
+		restore.Env = append(restore.Env, corev1.EnvVar{Name: "RESTORE_OPTIONS", Value: mode.options})
+	}
+	if mode.relax != "" {
+		podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+			Name:    restoreRelaxContainer,
+			Image:   restore.Image,
+			Command: []string{"/bin/sh", "-c", mode.relax},
+			Env:     restore.Env,
+		})
+		r.restrictContainer(database, &podSpec.InitContainers[len(podSpec.InitContainers)-1])
+	}
+}
+
+// revertRestorePerformanceMode puts back the settings relaxed by a finished
+// restore Job, through a Job running whether the restore succeeded or not. It
+// returns once there is nothing left to do; status.performanceMode.revertedAt
+// stays unset when the revert Job failed.
+func (r *DatabaseRestoreReconciler) revertRestorePerformanceMode(ctx context.Context, database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore) (bool, error) {
+	status := restore.Status.PerformanceMode
+	if status == nil || status.RevertedAt != nil {
+		return true, nil
+	}
+
+	mode := restorePerformanceModes[database.Spec.Type]
+	if mode.revert == "" {
+		now := metav1.Now()
+		status.RevertedAt = &now
+		return true, nil
+	}
+
+	job := &batchv1.Job{}
+	jobName := restore.Name + "-" + restoreRevertComponent
+	err := r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: restore.Namespace}, job)
+	if errors.IsNotFound(err) {
+		job = r.databaseReconciler(r.Client, r.Scheme).createAdminJob(database, restoreRevertComponent, mode.revert, nil)
+		job.Name = jobName
+		job.Spec.TTLSecondsAfterFinished = nil
+		if err := controllerutil.SetControllerReference(restore, job, r.Scheme); err != nil {
+			return false, err
+		}
+		log.FromContext(ctx).Info("Reverting restore performance settings", "name", job.Name)
+		return false, r.Create(ctx, job)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	finished, succeeded := jobFinished(job)
+	if !finished {
+		return false, nil
+	}
+	if !succeeded {
+		return true, nil
+	}
+	now := metav1.Now()
+	status.RevertedAt = &now
+	return true, nil
+}
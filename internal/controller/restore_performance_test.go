@@ -0,0 +1,167 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Restore performance mode", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *DatabaseRestoreReconciler
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "shop"}
+	}
+
+	reconcile := func() *databasesv1alpha1.DatabaseRestore {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key("bulk")})
+		Expect(err).NotTo(HaveOccurred())
+		restore := &databasesv1alpha1.DatabaseRestore{}
+		Expect(c.Get(ctx, key("bulk"), restore)).To(Succeed())
+		return restore
+	}
+
+	finishJob := func(name string, conditionType batchv1.JobConditionType) {
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, key(name), job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue}}
+		Expect(c.Status().Update(ctx, job)).To(Succeed())
+	}
+
+	newRestore := func() *databasesv1alpha1.DatabaseRestore {
+		return &databasesv1alpha1.DatabaseRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "bulk", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseRestoreSpec{
+				DatabaseRef:     corev1.LocalObjectReference{Name: "orders"},
+				Source:          databasesv1alpha1.RestoreSource{BackupRef: &corev1.LocalObjectReference{Name: "nightly"}},
+				PerformanceMode: true,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}, &databasesv1alpha1.DatabaseRestore{}).
+			WithObjects(
+				&databasesv1alpha1.Database{
+					ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+					Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+				},
+				&databasesv1alpha1.DatabaseBackup{
+					ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "shop"},
+					Status: databasesv1alpha1.DatabaseBackupStatus{
+						Phase:    databasesv1alpha1.DatabaseBackupPhaseCompleted,
+						Location: "pvc://orders-backups/nightly.dump",
+					},
+				},
+				newRestore(),
+			).Build()
+		reconciler = &DatabaseRestoreReconciler{Client: c, Scheme: scheme, APIReader: passwordReader{}}
+		stubConnectionLimits(100)
+	})
+
+	It("should relax the PostgreSQL durability settings and revert them before releasing the lock", func() {
+		restore := reconcile()
+		Expect(restore.Status.PerformanceMode.Settings).To(ConsistOf(
+			"fsync=off", "full_page_writes=off", "synchronous_commit=off", "maintenance_work_mem=1GB"))
+		Expect(restore.Status.PerformanceMode.AppliedAt).NotTo(BeNil())
+
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, key("bulk-restore"), job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.InitContainers).To(ContainElement(And(
+			HaveField("Name", restoreRelaxContainer),
+			HaveField("Command", ContainElement(And(
+				ContainSubstring(`ALTER SYSTEM SET fsync = 'off'`), ContainSubstring("pg_reload_conf()")))))))
+
+		By("reverting the settings once the restore Job completed")
+		finishJob("bulk-restore", batchv1.JobComplete)
+		restore = reconcile()
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+		Expect(restore.Status.Progress).To(Equal("Reverting durability settings"))
+
+		revert := &batchv1.Job{}
+		Expect(c.Get(ctx, key("bulk-restore-revert"), revert)).To(Succeed())
+		Expect(revert.Spec.Template.Spec.Containers[0].Command[2]).To(And(
+			ContainSubstring("ALTER SYSTEM RESET fsync"), ContainSubstring("CHECKPOINT")))
+
+		database := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key("orders"), database)).To(Succeed())
+		Expect(activeOperation(database)).To(Equal("Restore/bulk"))
+
+		finishJob("bulk-restore-revert", batchv1.JobComplete)
+		restore = reconcile()
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseCompleted))
+		Expect(restore.Status.PerformanceMode.RevertedAt).NotTo(BeNil())
+		Expect(restore.Status.Message).To(ContainSubstring("in performance mode (fsync=off,"))
+	})
+
+	It("should fail the restore when the settings could not be reverted", func() {
+		reconcile()
+		finishJob("bulk-restore", batchv1.JobComplete)
+		reconcile()
+		finishJob("bulk-restore-revert", batchv1.JobFailed)
+
+		restore := reconcile()
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseFailed))
+		Expect(restore.Status.Message).To(HavePrefix("Reverting fsync=off, "))
+		Expect(restore.Status.Message).To(HaveSuffix("see the logs of Job bulk-restore-revert"))
+		Expect(restore.Status.PerformanceMode.RevertedAt).To(BeNil())
+	})
+
+	It("should only tune the restore session of MongoDB and refuse unsupported restores", func() {
+		mongo := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "catalog", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeMongoDB, Version: "7.0"},
+		}
+		job, err := reconciler.databaseReconciler(reconciler.Client, reconciler.Scheme).createRestoreJob(mongo, newRestore(), "pvc://catalog-backups/nightly.archive.gz", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.InitContainers).To(BeEmpty())
+		container := job.Spec.Template.Spec.Containers[0]
+		Expect(container.Command[2]).To(HaveSuffix("$RESTORE_OPTIONS"))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{
+			Name: "RESTORE_OPTIONS", Value: "--writeConcern={w:1,j:false} --numInsertionWorkersPerCollection=4"}))
+
+		sqlite := &databasesv1alpha1.Database{Spec: databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeSQLite}}
+		Expect(restorePerformanceError(sqlite, newRestore(), false)).To(Equal("SQLite does not support restore performance mode"))
+		Expect(restorePerformanceError(mongo, newRestore(), true)).To(Equal("performanceMode only applies to restores of dumps"))
+	})
+})
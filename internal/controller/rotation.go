@@ -0,0 +1,335 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=update
+
+const (
+	rotationComponent = "rotation"
+	// rotationPendingKey holds the new password in the credentials Secret until
+	// the engine accepted it
+	rotationPendingKey  = "pending-password"
+	rotationPasswordEnv = "NEW_PASSWORD"
+)
+
+// rotationScripts set the password of the administrative user to NEW_PASSWORD.
+// They succeed when the new password is already set, so a rotation whose Job
+// went away is safely run again.
+var rotationScripts = map[databasesv1alpha1.DatabaseType]string{
+	databasesv1alpha1.DatabaseTypePostgreSQL: `if PGPASSWORD="$NEW_PASSWORD" psql -h "$DB_HOST" -c 'SELECT 1' >/dev/null 2>&1; then
+  echo "The new password is already set"
+  exit 0
+fi
+psql -h "$DB_HOST" -v ON_ERROR_STOP=1 -v password="$NEW_PASSWORD" <<'SQL'
+ALTER ROLE CURRENT_USER PASSWORD :'password';
+SQL`,
+	databasesv1alpha1.DatabaseTypeMongoDB: `if mongosh --host "$DB_HOST" -u "$MONGO_USERNAME" -p "$NEW_PASSWORD" --authenticationDatabase admin --quiet --eval 'db.runCommand({ping: 1})' >/dev/null 2>&1; then
+  echo "The new password is already set"
+  exit 0
+fi
+mongosh --host "$DB_HOST" -u "$MONGO_USERNAME" -p "$MONGO_PASSWORD" --authenticationDatabase admin --quiet \
+  --eval 'db.getSiblingDB("admin").updateUser(process.env.MONGO_USERNAME, {pwd: process.env.NEW_PASSWORD})'`,
+	// requirepass is not replicated, so every replica is set. Servers started
+	// before the rotation read the password from the Secret when they restart.
+	// A replica failing to take the new password puts the current one back on
+	// the replicas already switched, so a failed rotation keeps it valid.
+	databasesv1alpha1.DatabaseTypeRedis: `current="$REDISCLI_AUTH"
+hosts=$(getent hosts "$PEERS_HOST" | cut -d' ' -f1)
+if [ -z "$hosts" ]; then
+  echo "No Redis server found behind $PEERS_HOST" >&2
+  exit 1
+fi
+switched() {
+  [ "$(env -u REDISCLI_AUTH redis-cli -h "$1" --no-auth-warning AUTH "$NEW_PASSWORD")" = "OK" ]
+}
+for host in $hosts; do
+  if switched "$host"; then
+    echo "The new password is already set on $host"
+    continue
+  fi
+  if [ "$(redis-cli -h "$host" --no-auth-warning CONFIG SET requirepass "$NEW_PASSWORD")" != "OK" ]; then
+    echo "Failed to set the new password on $host, restoring the current password" >&2
+    for host in $hosts; do
+      if switched "$host"; then
+        REDISCLI_AUTH="$NEW_PASSWORD" redis-cli -h "$host" --no-auth-warning CONFIG SET requirepass "$current"
+      fi
+    done
+    exit 1
+  fi
+done`,
+}
+
+// validateRotation checks the rotation policy and the password it rotates
+func validateRotation(database *databasesv1alpha1.Database) error {
+	policy := database.Spec.RotationPolicy
+	if policy == nil {
+		return nil
+	}
+	if err := validateRotationTarget(database, "rotationPolicy"); err != nil {
+		return err
+	}
+	if _, err := parseCronSchedule(policy.Schedule); err != nil {
+		return fmt.Errorf("rotationPolicy: %w", err)
+	}
+	return nil
+}
+
+// validateRotationTarget checks that the engine supports rotation and has a
+// password to rotate: the generated one, or for Redis, which generates none, its
+// passwordSecret. subject names what requests the rotation in errors.
+func validateRotationTarget(database *databasesv1alpha1.Database, subject string) error {
+	if _, ok := rotationScripts[database.Spec.Type]; !ok {
+		return fmt.Errorf("%s does not support %s", database.Spec.Type, subject)
+	}
+	reference := passwordSecret(database)
+	switch {
+	case reference == nil:
+		return fmt.Errorf("%s rotates the password of passwordSecret, set it", subject)
+	case database.Spec.Type != databasesv1alpha1.DatabaseTypeRedis && reference.Name != database.Name+credentialsSuffix:
+		return fmt.Errorf("%s rotates the generated password, remove passwordSecret", subject)
+	case reference.Key == rotationPendingKey:
+		return fmt.Errorf("%s stores the new password in key %s, use another passwordSecret key", subject, rotationPendingKey)
+	}
+	return nil
+}
+
+// rotationRemaining returns the time left until the next scheduled rotation
+func rotationRemaining(database *databasesv1alpha1.Database, now time.Time) time.Duration {
+	status := database.Status.Rotation
+	if status == nil || status.NextRotation == nil || status.Phase == databasesv1alpha1.RotationPhaseRotating {
+		return 0
+	}
+	return status.NextRotation.Sub(now)
+}
+
+// reconcileRotation rotates the administrative password on the schedule of the
+// rotation policy, and when the rotate-credentials annotation requests it. A
+// rotation holds the operation lock while it stores the new password next to
+// the current one in the password Secret, sets it in the engine with a Job, and
+// then makes it the current password. A failed rotation keeps the current
+// password.
+func (r *DatabaseReconciler) reconcileRotation(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) error {
+	status := database.Status.Rotation
+	rotating := status != nil && status.Phase == databasesv1alpha1.RotationPhaseRotating
+	request := database.Annotations[databasesv1alpha1.RotateCredentialsAnnotation]
+	// A rotation in flight completes even when the policy was removed, and the
+	// handled request is kept as long as the annotation is set
+	if database.Spec.RotationPolicy == nil && !rotating && request == "" {
+		database.Status.Rotation = nil
+		return nil
+	}
+	if status == nil {
+		status = &databasesv1alpha1.RotationStatus{Phase: databasesv1alpha1.RotationPhaseScheduled}
+		database.Status.Rotation = status
+	}
+	requested := request != "" && request != status.HandledRequest
+	if requested && !rotating {
+		if err := validateRotationTarget(database, databasesv1alpha1.RotateCredentialsAnnotation); err != nil {
+			status.HandledRequest = request
+			status.Message = fmt.Sprintf("Ignored rotation request %q: %v", request, err)
+			r.event(database, corev1.EventTypeWarning, "RotationRequestIgnored", status.Message)
+			return nil
+		}
+	}
+
+	reference := passwordSecret(database)
+	if reference == nil {
+		// The Redis password was removed, there is nothing left to rotate
+		releaseOperation(database, disruptiveOperationRotation)
+		database.Status.Rotation = nil
+		return nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: reference.Name, Namespace: database.Namespace}, secret); err != nil {
+		return err
+	}
+	if rotating {
+		return r.completeRotation(ctx, database, secret, reference.Key, now)
+	}
+
+	// Schedule the first rotation, and reschedule when the schedule changed
+	if policy := database.Spec.RotationPolicy; policy != nil && (status.NextRotation == nil || status.Schedule != policy.Schedule) {
+		scheduleRotation(database, now)
+		if !requested {
+			return nil
+		}
+	}
+	due := status.NextRotation != nil && !now.Before(status.NextRotation.Time)
+	if (!due && !requested) || database.Status.ReadyReplicas == 0 {
+		return nil
+	}
+	// A requested rotation answers a suspected leak, which does not wait for
+	// the end of a release freeze or for a maintenance window
+	if !requested && frozen(database, now) {
+		status.Message = "Rotation deferred by the release freeze"
+		return nil
+	}
+	if !requested && deferredToMaintenance(database, disruptiveOperationRotation, now) {
+		status.Message = "Rotation deferred to the maintenance window"
+		return nil
+	}
+	if !acquireOperation(database, disruptiveOperationRotation, now) {
+		status.Message = fmt.Sprintf("Waiting for operation %s to complete", activeOperation(database))
+		return nil
+	}
+	if requested {
+		status.HandledRequest = request
+		r.event(database, corev1.EventTypeNormal, "RotationRequested",
+			fmt.Sprintf("Rotating the administrative password as requested by annotation %s=%q",
+				databasesv1alpha1.RotateCredentialsAnnotation, request))
+	}
+
+	password, err := randomPassword(generatedPasswordLength)
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[rotationPendingKey] = []byte(password)
+	if err := r.Update(ctx, secret); err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("Rotating the administrative password", "secret", secret.Name)
+	r.event(database, corev1.EventTypeNormal, "RotationStarted",
+		fmt.Sprintf("Rotating the administrative password in Secret %s", secret.Name))
+	status.Phase = databasesv1alpha1.RotationPhaseRotating
+	status.Message = ""
+	return r.createRotationJob(ctx, database)
+}
+
+// completeRotation follows the rotation Job: the new password becomes the
+// current one once the engine accepted it, and is discarded otherwise
+func (r *DatabaseReconciler) completeRotation(ctx context.Context, database *databasesv1alpha1.Database, secret *corev1.Secret, key string, now time.Time) error {
+	log := log.FromContext(ctx)
+	status := database.Status.Rotation
+
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: database.Name + "-" + rotationComponent, Namespace: database.Namespace}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if errors.IsNotFound(err) {
+		if _, ok := secret.Data[rotationPendingKey]; ok {
+			return r.createRotationJob(ctx, database)
+		}
+		// The new password was made current before the Job was deleted
+		r.finishRotation(database, secret.Name, now)
+		r.restartCredentialConsumers(ctx, database, secret.Data[key])
+		return nil
+	}
+
+	finished, succeeded := jobFinished(job)
+	if !finished {
+		return nil
+	}
+
+	// Update the Secret before the Job is deleted, so an interrupted rotation
+	// resumes from the Secret
+	if pending, ok := secret.Data[rotationPendingKey]; ok {
+		if succeeded {
+			secret.Data[key] = pending
+		}
+		delete(secret.Data, rotationPendingKey)
+		if err := r.Update(ctx, secret); err != nil {
+			return err
+		}
+	}
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if !succeeded {
+		message := fmt.Sprintf("Failed to rotate the password, see the logs of Job %s; the current password stays valid", job.Name)
+		log.Info("Password rotation failed", "job", job.Name)
+		r.event(database, corev1.EventTypeWarning, "RotationFailed", message)
+		recordOperation(database, recordRotation, databasesv1alpha1.OperationFailed, message, now)
+		releaseOperation(database, disruptiveOperationRotation)
+		status.Phase = databasesv1alpha1.RotationPhaseFailed
+		status.Message = message
+		scheduleRotation(database, now)
+		return nil
+	}
+
+	log.Info("Rotated the administrative password", "secret", secret.Name)
+	r.finishRotation(database, secret.Name, now)
+	r.restartCredentialConsumers(ctx, database, secret.Data[key])
+	return nil
+}
+
+func (r *DatabaseReconciler) finishRotation(database *databasesv1alpha1.Database, secretName string, now time.Time) {
+	message := fmt.Sprintf("Rotated the administrative password in Secret %s", secretName)
+	recordOperation(database, recordRotation, databasesv1alpha1.OperationSucceeded, message, now)
+	r.event(database, corev1.EventTypeNormal, "RotationCompleted", message)
+	releaseOperation(database, disruptiveOperationRotation)
+	lastRotation := metav1.NewTime(now)
+	database.Status.Rotation.Phase = databasesv1alpha1.RotationPhaseScheduled
+	database.Status.Rotation.LastRotation = &lastRotation
+	database.Status.Rotation.Message = ""
+	scheduleRotation(database, now)
+}
+
+// scheduleRotation sets the next rotation from the schedule of the policy
+func scheduleRotation(database *databasesv1alpha1.Database, now time.Time) {
+	status := database.Status.Rotation
+	status.NextRotation = nil
+	status.Schedule = ""
+	if database.Spec.RotationPolicy == nil {
+		return
+	}
+	status.Schedule = database.Spec.RotationPolicy.Schedule
+	schedule, err := parseCronSchedule(database.Spec.RotationPolicy.Schedule)
+	if err != nil {
+		return
+	}
+	if next := schedule.next(now.UTC()); !next.IsZero() {
+		status.NextRotation = &metav1.Time{Time: next}
+	}
+}
+
+// createRotationJob runs the Job setting the pending password in the engine,
+// connected with the current one
+func (r *DatabaseReconciler) createRotationJob(ctx context.Context, database *databasesv1alpha1.Database) error {
+	env := []corev1.EnvVar{
+		passwordEnv(rotationPasswordEnv, &databasesv1alpha1.SecretReference{Name: passwordSecret(database).Name, Key: rotationPendingKey}),
+	}
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeRedis {
+		env = append(env, corev1.EnvVar{Name: "PEERS_HOST", Value: peersHost(database)})
+	}
+	job := r.createAdminJob(database, rotationComponent, rotationScripts[database.Spec.Type], env)
+	if err := controllerutil.SetControllerReference(database, job, r.Scheme); err != nil {
+		return err
+	}
+	return r.Create(ctx, job)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// credentialsChecksumAnnotation records on a pod template the checksum of the
+// password its pods were restarted for
+const credentialsChecksumAnnotation = "databases.database-operator.io/credentials-checksum"
+
+// restartCredentialConsumers rolls the pods of the Deployments labeled as
+// consumers of the Database, and of its workload when the rotation policy asks
+// for it, by setting the checksum of the rotated password on their pod
+// template. The password is already rotated, so failures are reported without
+// failing the rotation.
+func (r *DatabaseReconciler) restartCredentialConsumers(ctx context.Context, database *databasesv1alpha1.Database, password []byte) {
+	sum := sha256.Sum256(password)
+	checksum := hex.EncodeToString(sum[:])[:16]
+
+	var restarted, failed []string
+	restart := func(kind, name string, update func() error) {
+		if err := update(); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to restart credentials consumer", "kind", kind, "name", name)
+			failed = append(failed, fmt.Sprintf("%s %s", kind, name))
+			return
+		}
+		restarted = append(restarted, fmt.Sprintf("%s %s", kind, name))
+	}
+
+	if policy := database.Spec.RotationPolicy; policy != nil && policy.RestartWorkload {
+		for _, name := range workloadNames(database) {
+			restart("StatefulSet", name, func() error {
+				statefulSet := &appsv1.StatefulSet{}
+				if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, statefulSet); err != nil {
+					return err
+				}
+				before := *statefulSet.Spec.DeepCopy()
+				setCredentialsChecksum(&statefulSet.Spec.Template, checksum)
+				restampAppliedSpec(statefulSet, before)
+				return r.Update(ctx, statefulSet)
+			})
+		}
+	}
+
+	consumers := &appsv1.DeploymentList{}
+	if err := r.List(ctx, consumers, client.InNamespace(database.Namespace),
+		client.MatchingLabels{databasesv1alpha1.CredentialsConsumerLabel: database.Name}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list credentials consumers")
+		failed = append(failed, "the labeled Deployments")
+	}
+	for i := range consumers.Items {
+		deployment := &consumers.Items[i]
+		restart("Deployment", deployment.Name, func() error {
+			setCredentialsChecksum(&deployment.Spec.Template, checksum)
+			return r.Update(ctx, deployment)
+		})
+	}
+
+	if len(restarted) > 0 {
+		r.event(database, corev1.EventTypeNormal, "CredentialsConsumersRestarted",
+			fmt.Sprintf("Restarted %s for the rotated password", strings.Join(restarted, ", ")))
+	}
+	if len(failed) > 0 {
+		r.event(database, corev1.EventTypeWarning, "CredentialsConsumersRestartFailed",
+			fmt.Sprintf("Failed to restart %s for the rotated password, restart them to use it", strings.Join(failed, ", ")))
+	}
+}
+
+// setCredentialsChecksum sets the password checksum on a pod template, which
+// rolls its pods when it changes
+func setCredentialsChecksum(template *corev1.PodTemplateSpec, checksum string) {
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[credentialsChecksumAnnotation] = checksum
+}
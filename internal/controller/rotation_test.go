@@ -0,0 +1,307 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Credential rotation", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		now        time.Time
+	)
+
+	secretKey := types.NamespacedName{Name: "orders-credentials", Namespace: "shop"}
+	jobKey := types.NamespacedName{Name: "orders-rotation", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:           databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:        "16",
+				RotationPolicy: &databasesv1alpha1.RotationPolicy{Schedule: "0 3 1 * *"},
+			},
+			Status: databasesv1alpha1.DatabaseStatus{ReadyReplicas: 1},
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-credentials", Namespace: "shop"},
+			Data:       map[string][]byte{"username": []byte("postgres"), "password": []byte("current")},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(database, secret).Build(),
+			Scheme: scheme,
+		}
+	})
+
+	// startRotation schedules the rotation and reconciles once it is due
+	startRotation := func() {
+		Expect(reconciler.reconcileRotation(ctx, database, now)).To(Succeed())
+		Expect(database.Status.Rotation.NextRotation.Time).To(Equal(time.Date(2025, 4, 1, 3, 0, 0, 0, time.UTC)))
+		Expect(rotationRemaining(database, now)).To(Equal(15 * time.Hour))
+
+		now = database.Status.Rotation.NextRotation.Time
+		Expect(reconciler.reconcileRotation(ctx, database, now)).To(Succeed())
+	}
+
+	finishJob := func(condition batchv1.JobConditionType) {
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+		Expect(reconciler.Status().Update(ctx, job)).To(Succeed())
+		Expect(reconciler.reconcileRotation(ctx, database, now)).To(Succeed())
+	}
+
+	It("should make the new password current once the engine accepted it", func() {
+		startRotation()
+		Expect(database.Status.Rotation.Phase).To(Equal(databasesv1alpha1.RotationPhaseRotating))
+		Expect(activeOperation(database)).To(Equal(disruptiveOperationRotation))
+
+		secret := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, secretKey, secret)).To(Succeed())
+		pending := secret.Data[rotationPendingKey]
+		Expect(string(pending)).To(MatchRegexp("^[A-Za-z0-9]{32}$"))
+		Expect(string(secret.Data["password"])).To(Equal("current"))
+
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		container := job.Spec.Template.Spec.Containers[0]
+		Expect(container.Env).To(ContainElement(And(
+			HaveField("Name", "PGPASSWORD"), HaveField("ValueFrom.SecretKeyRef.Key", "password"))))
+		Expect(container.Env).To(ContainElement(And(
+			HaveField("Name", rotationPasswordEnv), HaveField("ValueFrom.SecretKeyRef.Key", rotationPendingKey))))
+		Expect(container.Command[2]).To(ContainSubstring("ALTER ROLE CURRENT_USER PASSWORD :'password';"))
+
+		finishJob(batchv1.JobComplete)
+		Expect(reconciler.Get(ctx, secretKey, secret)).To(Succeed())
+		Expect(secret.Data["password"]).To(Equal(pending))
+		Expect(secret.Data).NotTo(HaveKey(rotationPendingKey))
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		status := database.Status.Rotation
+		Expect(status.Phase).To(Equal(databasesv1alpha1.RotationPhaseScheduled))
+		Expect(status.LastRotation.Time).To(Equal(now))
+		Expect(status.NextRotation.Time).To(Equal(time.Date(2025, 5, 1, 3, 0, 0, 0, time.UTC)))
+		Expect(database.Status.Operations).To(BeNil())
+		Expect(database.Status.RecentOperations).To(ContainElement(And(
+			HaveField("Type", recordRotation), HaveField("Outcome", databasesv1alpha1.OperationSucceeded))))
+	})
+
+	It("should keep the current password when the rotation fails", func() {
+		startRotation()
+		finishJob(batchv1.JobFailed)
+
+		secret := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, secretKey, secret)).To(Succeed())
+		Expect(string(secret.Data["password"])).To(Equal("current"))
+		Expect(secret.Data).NotTo(HaveKey(rotationPendingKey))
+		Expect(database.Status.Rotation.Phase).To(Equal(databasesv1alpha1.RotationPhaseFailed))
+		Expect(database.Status.Rotation.NextRotation.Time).To(Equal(time.Date(2025, 5, 1, 3, 0, 0, 0, time.UTC)))
+		Expect(database.Status.Operations).To(BeNil())
+	})
+
+	It("should resume a rotation whose Job went away", func() {
+		startRotation()
+		Expect(reconciler.Delete(ctx, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "orders-rotation", Namespace: "shop"}})).To(Succeed())
+		Expect(reconciler.reconcileRotation(ctx, database, now)).To(Succeed())
+		Expect(reconciler.Get(ctx, jobKey, &batchv1.Job{})).To(Succeed())
+		Expect(database.Status.Rotation.Phase).To(Equal(databasesv1alpha1.RotationPhaseRotating))
+	})
+
+	It("should defer rotations during release freezes and other operations", func() {
+		database.Annotations = map[string]string{databasesv1alpha1.FreezeUntilAnnotation: "2025-04-02T00:00:00Z"}
+		startRotation()
+		Expect(database.Status.Rotation.Phase).To(Equal(databasesv1alpha1.RotationPhaseScheduled))
+		Expect(database.Status.Rotation.Message).To(Equal("Rotation deferred by the release freeze"))
+
+		database.Annotations = nil
+		Expect(acquireOperation(database, disruptiveOperationScale, now)).To(BeTrue())
+		Expect(reconciler.reconcileRotation(ctx, database, now)).To(Succeed())
+		Expect(database.Status.Operations.Pending).To(Equal([]string{disruptiveOperationRotation}))
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+	})
+
+	It("should rotate the passwordSecret of Redis on every replica", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		database.Spec.Redis = &databasesv1alpha1.RedisConfig{
+			PasswordSecret: &databasesv1alpha1.SecretReference{Name: "orders-redis", Key: "auth"},
+		}
+		Expect(validateRotation(database)).To(Succeed())
+		Expect(reconciler.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders-redis", Namespace: "shop"},
+			Data:       map[string][]byte{"auth": []byte("current")},
+		})).To(Succeed())
+		container := reconciler.createRedisStatefulSet(database, 1, reconciler.getRedisEnv(database)).Spec.Template.Spec.Containers[0]
+		Expect(container.Args).To(Equal([]string{"--requirepass", "$(REDIS_PASSWORD)"}))
+
+		startRotation()
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		container = job.Spec.Template.Spec.Containers[0]
+		Expect(container.Env).To(ContainElement(And(
+			HaveField("Name", "REDISCLI_AUTH"), HaveField("ValueFrom.SecretKeyRef.Key", "auth"))))
+		Expect(container.Env).To(ContainElement(passwordEnv(rotationPasswordEnv,
+			&databasesv1alpha1.SecretReference{Name: "orders-redis", Key: rotationPendingKey})))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "PEERS_HOST", Value: "orders-headless.shop.svc"}))
+		Expect(container.Command[2]).To(ContainSubstring(`CONFIG SET requirepass "$NEW_PASSWORD"`))
+		// No replica found fails, and a failure puts the current password back
+		Expect(container.Command[2]).To(ContainSubstring(`if [ -z "$hosts" ]; then`))
+		Expect(container.Command[2]).To(ContainSubstring(`CONFIG SET requirepass "$current"`))
+
+		secret := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders-redis", Namespace: "shop"}, secret)).To(Succeed())
+		pending := secret.Data[rotationPendingKey]
+		finishJob(batchv1.JobComplete)
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders-redis", Namespace: "shop"}, secret)).To(Succeed())
+		Expect(secret.Data).To(Equal(map[string][]byte{"auth": pending}))
+		Expect(database.Status.RecentOperations).To(ContainElement(
+			HaveField("Detail", "Rotated the administrative password in Secret orders-redis")))
+	})
+
+	It("should update the MongoDB administrative user", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		startRotation()
+		job := &batchv1.Job{}
+		Expect(reconciler.Get(ctx, jobKey, job)).To(Succeed())
+		container := job.Spec.Template.Spec.Containers[0]
+		Expect(container.Env).To(ContainElement(And(
+			HaveField("Name", "MONGO_PASSWORD"), HaveField("ValueFrom.SecretKeyRef.Key", "password"))))
+		Expect(container.Command[2]).To(And(
+			ContainSubstring(`-p "$NEW_PASSWORD"`),
+			ContainSubstring("updateUser(process.env.MONGO_USERNAME, {pwd: process.env.NEW_PASSWORD})"),
+		))
+	})
+
+	It("should restart the consumers of the rotated password", func() {
+		database.Spec.RotationPolicy.RestartWorkload = true
+		Expect(reconciler.Create(ctx, &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+		})).To(Succeed())
+		consumer := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "shop",
+			Labels: map[string]string{databasesv1alpha1.CredentialsConsumerLabel: "orders"}}}
+		other := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "catalog", Namespace: "shop",
+			Labels: map[string]string{databasesv1alpha1.CredentialsConsumerLabel: "products"}}}
+		Expect(reconciler.Create(ctx, consumer)).To(Succeed())
+		Expect(reconciler.Create(ctx, other)).To(Succeed())
+
+		startRotation()
+		finishJob(batchv1.JobComplete)
+		secret := &corev1.Secret{}
+		Expect(reconciler.Get(ctx, secretKey, secret)).To(Succeed())
+		sum := sha256.Sum256(secret.Data["password"])
+		checksum := hex.EncodeToString(sum[:])[:16]
+
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "orders", Namespace: "shop"}, statefulSet)).To(Succeed())
+		Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue(credentialsChecksumAnnotation, checksum))
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(consumer), consumer)).To(Succeed())
+		Expect(consumer.Spec.Template.Annotations).To(HaveKeyWithValue(credentialsChecksumAnnotation, checksum))
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(other), other)).To(Succeed())
+		Expect(other.Spec.Template.Annotations).NotTo(HaveKey(credentialsChecksumAnnotation))
+	})
+
+	It("should rotate on request without a policy or outside the schedule", func() {
+		database.Spec.RotationPolicy = nil
+		database.Annotations = map[string]string{
+			databasesv1alpha1.FreezeUntilAnnotation:       "2025-04-02T00:00:00Z",
+			databasesv1alpha1.RotateCredentialsAnnotation: "now",
+		}
+		Expect(reconciler.reconcileRotation(ctx, database, now)).To(Succeed())
+		Expect(database.Status.Rotation.Phase).To(Equal(databasesv1alpha1.RotationPhaseRotating))
+		Expect(database.Status.Rotation.HandledRequest).To(Equal("now"))
+		Expect(database.Status.Rotation.NextRotation).To(BeNil())
+		Expect(reconciler.Get(ctx, jobKey, &batchv1.Job{})).To(Succeed())
+
+		finishJob(batchv1.JobComplete)
+		Expect(database.Status.Rotation.Phase).To(Equal(databasesv1alpha1.RotationPhaseScheduled))
+		Expect(database.Status.Rotation.LastRotation.Time).To(Equal(now))
+
+		// The handled request does not rotate again
+		Expect(reconciler.reconcileRotation(ctx, database, now.Add(time.Hour))).To(Succeed())
+		Expect(database.Status.Rotation.Phase).To(Equal(databasesv1alpha1.RotationPhaseScheduled))
+		Expect(database.Status.Rotation.HandledRequest).To(Equal("now"))
+	})
+
+	It("should ignore requests for passwords it cannot rotate", func() {
+		database.Spec.RotationPolicy = nil
+		database.Spec.PostgreSQL = &databasesv1alpha1.PostgreSQLConfig{
+			PasswordSecret: &databasesv1alpha1.SecretReference{Name: "orders-admin", Key: "password"},
+		}
+		database.Annotations = map[string]string{databasesv1alpha1.RotateCredentialsAnnotation: "2025-03-31"}
+		Expect(reconciler.reconcileRotation(ctx, database, now)).To(Succeed())
+		Expect(database.Status.Rotation.Phase).To(Equal(databasesv1alpha1.RotationPhaseScheduled))
+		Expect(database.Status.Rotation.HandledRequest).To(Equal("2025-03-31"))
+		Expect(database.Status.Rotation.Message).To(Equal(`Ignored rotation request "2025-03-31": ` +
+			"databases.database-operator.io/rotate-credentials rotates the generated password, remove passwordSecret"))
+		Expect(apierrors.IsNotFound(reconciler.Get(ctx, jobKey, &batchv1.Job{}))).To(BeTrue())
+
+		delete(database.Annotations, databasesv1alpha1.RotateCredentialsAnnotation)
+		Expect(reconciler.reconcileRotation(ctx, database, now)).To(Succeed())
+		Expect(database.Status.Rotation).To(BeNil())
+	})
+
+	It("should only rotate generated passwords", func() {
+		Expect(validateRotation(database)).To(Succeed())
+		database.Spec.RotationPolicy.Schedule = "monthly"
+		Expect(validateRotation(database)).To(MatchError(ContainSubstring("rotationPolicy: invalid cron schedule")))
+
+		database.Spec.RotationPolicy.Schedule = "@monthly"
+		database.Spec.PostgreSQL = &databasesv1alpha1.PostgreSQLConfig{
+			PasswordSecret: &databasesv1alpha1.SecretReference{Name: "orders-admin", Key: "password"},
+		}
+		Expect(validateRotation(database)).To(MatchError("rotationPolicy rotates the generated password, remove passwordSecret"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		database.Spec.PostgreSQL = nil
+		Expect(validateRotation(database)).To(MatchError("rotationPolicy rotates the password of passwordSecret, set it"))
+		database.Spec.Redis = &databasesv1alpha1.RedisConfig{
+			PasswordSecret: &databasesv1alpha1.SecretReference{Name: "orders-redis", Key: rotationPendingKey},
+		}
+		Expect(validateRotation(database)).To(MatchError(ContainSubstring("use another passwordSecret key")))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeSQLite
+		Expect(validateRotation(database)).To(MatchError("SQLite does not support rotationPolicy"))
+	})
+})
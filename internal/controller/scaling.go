@@ -0,0 +1,228 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	conditionDownscaleBlocked = "DownscaleBlocked"
+	// downscaleRecheckInterval is how often a deferred scale-down is retried
+	downscaleRecheckInterval = 30 * time.Second
+	connectionCountTimeout   = 5 * time.Second
+)
+
+// connectionCounters count the client connections of a single replica, leaving out
+// the connection used to count them
+var connectionCounters = map[databasesv1alpha1.DatabaseType]func(ctx context.Context, conn adminConnection) (int64, error){
+	databasesv1alpha1.DatabaseTypePostgreSQL: func(ctx context.Context, conn adminConnection) (int64, error) {
+		rows, err := postgresQuery(ctx, conn,
+			"SELECT count(*) AS connections FROM pg_stat_activity "+
+				"WHERE backend_type = 'client backend' AND pid <> pg_backend_pid()")
+		if err != nil {
+			return 0, err
+		}
+		if len(rows) != 1 {
+			return 0, fmt.Errorf("unexpected result counting connections")
+		}
+		value, _ := rows[0]["connections"].(string)
+		return strconv.ParseInt(value, 10, 64)
+	},
+	databasesv1alpha1.DatabaseTypeRedis: func(ctx context.Context, conn adminConnection) (int64, error) {
+		info, err := redisInfo(ctx, conn)
+		if err != nil {
+			return 0, err
+		}
+		clients, err := strconv.ParseInt(info["connected_clients"], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid connected_clients %q", info["connected_clients"])
+		}
+		return max(clients-1, 0), nil
+	},
+}
+
+// scaleStatefulSet applies the desired replica count to an existing StatefulSet
+// while holding the Scale operation lock. Scale-downs are deferred while the
+// replicas to be removed still serve client connections, see
+// scaleDownProtection, and the lock is released meanwhile. Hibernation stops
+// every replica right away: the data stays on the volumes, so nothing has to be
+// drained and it does not wait for a maintenance window.
+func (r *DatabaseReconciler) scaleStatefulSet(ctx context.Context, database *databasesv1alpha1.Database, statefulSet *appsv1.StatefulSet, replicas int32) error {
+	current := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		current = *statefulSet.Spec.Replicas
+	}
+
+	if replicas == current {
+		clearDownscale(database)
+		// Scaling completes once the StatefulSet runs the requested replicas
+		if statefulSet.Status.Replicas == replicas {
+			if activeOperation(database) == disruptiveOperationScale {
+				if err := r.liftShardExclusion(ctx, database); err != nil {
+					return err
+				}
+				recordOperation(database, recordScale, databasesv1alpha1.OperationSucceeded,
+					fmt.Sprintf("Scaled to %d replicas", replicas), time.Now())
+			}
+			releaseOperation(database, disruptiveOperationScale)
+		}
+		return nil
+	}
+
+	if frozen(database, time.Now()) {
+		log.FromContext(ctx).Info("Scaling deferred by the release freeze", "from", current, "to", replicas)
+		return nil
+	}
+	hibernating := hibernated(database) && replicas == 0
+	if replicas < current && !hibernating && deferredToMaintenance(database, disruptiveOperationScale, time.Now()) {
+		log.FromContext(ctx).Info("Scale-down deferred to the maintenance window", "from", current, "to", replicas)
+		return nil
+	}
+
+	if !acquireOperation(database, disruptiveOperationScale, time.Now()) {
+		log.FromContext(ctx).Info("Scaling queued behind another operation", "active", activeOperation(database))
+		return nil
+	}
+
+	if replicas < current && !hibernating {
+		drained, err := r.shardsDrained(ctx, database, statefulSet, current, replicas)
+		if err != nil || !drained {
+			return err
+		}
+		allowed, err := r.downscaleAllowed(ctx, database, statefulSet, current, replicas, time.Now())
+		if err != nil {
+			return err
+		}
+		// Other operations run while the connections drain
+		if !allowed {
+			releaseOperation(database, disruptiveOperationScale)
+			return nil
+		}
+	} else {
+		clearDownscale(database)
+	}
+
+	log.FromContext(ctx).Info("Scaling StatefulSet", "from", current, "to", replicas)
+	before := *statefulSet.Spec.DeepCopy()
+	statefulSet.Spec.Replicas = &replicas
+	restampAppliedSpec(statefulSet, before)
+	return r.Update(ctx, statefulSet)
+}
+
+// downscaleAllowed counts the connections of the replicas a scale-down removes and
+// records a deferred scale-down in status
+func (r *DatabaseReconciler) downscaleAllowed(ctx context.Context, database *databasesv1alpha1.Database, statefulSet *appsv1.StatefulSet, current, replicas int32, now time.Time) (bool, error) {
+	log := log.FromContext(ctx)
+
+	protection := database.Spec.ScaleDownProtection
+	count, ok := connectionCounters[database.Spec.Type]
+	if protection == nil || !protection.Enabled || !ok {
+		clearDownscale(database)
+		return true, nil
+	}
+
+	conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+	if err != nil {
+		return false, err
+	}
+
+	connections := map[string]int64{}
+	var problems []string
+	for ordinal := replicas; ordinal < current; ordinal++ {
+		pod := &corev1.Pod{}
+		podName := fmt.Sprintf("%s-%d", statefulSet.Name, ordinal)
+		if err := r.Get(ctx, types.NamespacedName{Name: podName, Namespace: database.Namespace}, pod); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+
+		podConn := conn
+		podConn.host = pod.Status.PodIP
+		countCtx, cancel := context.WithTimeout(ctx, connectionCountTimeout)
+		n, err := count(countCtx, podConn)
+		cancel()
+		if err != nil {
+			log.Error(err, "Failed to count connections", "pod", podName)
+			problems = append(problems, fmt.Sprintf("%s: connections unknown", podName))
+			continue
+		}
+
+		connections[podName] = n
+		if n > protection.MaxConnections {
+			problems = append(problems, fmt.Sprintf("%s: %d connections", podName, n))
+		}
+	}
+
+	if len(problems) == 0 {
+		clearDownscale(database)
+		return true, nil
+	}
+
+	status := database.Status.Downscale
+	if status == nil || status.TargetReplicas != replicas {
+		since := metav1.NewTime(now)
+		status = &databasesv1alpha1.DownscaleStatus{TargetReplicas: replicas, BlockedSince: &since}
+	}
+	status.Connections = connections
+	database.Status.Downscale = status
+
+	if protection.DrainTimeout != nil && now.Sub(status.BlockedSince.Time) >= protection.DrainTimeout.Duration {
+		log.Info("Drain timeout expired, scaling down with open connections", "connections", connections)
+		clearDownscale(database)
+		return true, nil
+	}
+
+	sort.Strings(problems)
+	meta.SetStatusCondition(&database.Status.Conditions, metav1.Condition{
+		Type:   conditionDownscaleBlocked,
+		Status: metav1.ConditionTrue,
+		Reason: "ActiveConnections",
+		Message: fmt.Sprintf("Scale-down from %d to %d replicas deferred (max %d connections): %s",
+			current, replicas, protection.MaxConnections, strings.Join(problems, ", ")),
+		ObservedGeneration: database.Generation,
+	})
+	log.Info("Deferring scale-down", "from", current, "to", replicas, "connections", connections)
+
+	return false, nil
+}
+
+// clearDownscale forgets a deferred scale-down
+func clearDownscale(database *databasesv1alpha1.Database) {
+	database.Status.Downscale = nil
+	meta.RemoveStatusCondition(&database.Status.Conditions, conditionDownscaleBlocked)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Scale-down protection", func() {
+	var (
+		reconciler  *DatabaseReconciler
+		database    *databasesv1alpha1.Database
+		statefulSet *appsv1.StatefulSet
+		connections map[string]int64
+		original    func(context.Context, adminConnection) (int64, error)
+	)
+
+	pod := func(name, ip string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip},
+		}
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+
+		current := int32(3)
+		statefulSet = &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &current},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			statefulSet, pod("cache-1", "10.0.0.2"), pod("cache-2", "10.0.0.3"),
+		).Build()
+		reconciler = &DatabaseReconciler{Client: c, Scheme: scheme}
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:                databasesv1alpha1.DatabaseTypeRedis,
+				ScaleDownProtection: &databasesv1alpha1.ScaleDownProtectionSpec{Enabled: true, MaxConnections: 1},
+			},
+		}
+
+		connections = map[string]int64{"10.0.0.2": 0, "10.0.0.3": 5}
+		original = connectionCounters[databasesv1alpha1.DatabaseTypeRedis]
+		connectionCounters[databasesv1alpha1.DatabaseTypeRedis] = func(_ context.Context, conn adminConnection) (int64, error) {
+			return connections[conn.host], nil
+		}
+		DeferCleanup(func() {
+			connectionCounters[databasesv1alpha1.DatabaseTypeRedis] = original
+		})
+	})
+
+	replicas := func() int32 {
+		current := &appsv1.StatefulSet{}
+		Expect(reconciler.Get(context.Background(), types.NamespacedName{Name: "cache", Namespace: "shop"}, current)).To(Succeed())
+		return *current.Spec.Replicas
+	}
+
+	It("should defer a scale-down while removed replicas serve connections", func() {
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 1)).To(Succeed())
+
+		Expect(replicas()).To(Equal(int32(3)))
+		Expect(database.Status.Downscale.TargetReplicas).To(Equal(int32(1)))
+		Expect(database.Status.Downscale.Connections).To(Equal(map[string]int64{"cache-1": 0, "cache-2": 5}))
+		condition := meta.FindStatusCondition(database.Status.Conditions, conditionDownscaleBlocked)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Message).To(ContainSubstring("cache-2: 5 connections"))
+		Expect(activeOperation(database)).To(BeEmpty())
+
+		connections["10.0.0.3"] = 1
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 1)).To(Succeed())
+		Expect(replicas()).To(Equal(int32(1)))
+		Expect(database.Status.Downscale).To(BeNil())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionDownscaleBlocked)).To(BeNil())
+	})
+
+	It("should read the credentials of the counted replicas uncached", func() {
+		database.Spec.Redis = &databasesv1alpha1.RedisConfig{
+			PasswordSecret: &databasesv1alpha1.SecretReference{Name: "cache-auth", Key: "password"},
+		}
+		reconciler.APIReader = fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache-auth", Namespace: "shop"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		}).Build()
+		passwords := []string{}
+		connectionCounters[databasesv1alpha1.DatabaseTypeRedis] = func(_ context.Context, conn adminConnection) (int64, error) {
+			passwords = append(passwords, conn.password)
+			return 0, nil
+		}
+
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 1)).To(Succeed())
+		Expect(passwords).To(Equal([]string{"secret", "secret"}))
+		Expect(replicas()).To(Equal(int32(1)))
+	})
+
+	It("should scale down once the drain timeout expired", func() {
+		database.Spec.ScaleDownProtection.DrainTimeout = &metav1.Duration{Duration: time.Minute}
+		blockedSince := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+		database.Status.Downscale = &databasesv1alpha1.DownscaleStatus{TargetReplicas: 2, BlockedSince: &blockedSince}
+
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 2)).To(Succeed())
+		Expect(replicas()).To(Equal(int32(2)))
+		Expect(database.Status.Downscale).To(BeNil())
+	})
+
+	It("should wait for another disruptive operation to complete", func() {
+		database.Spec.ScaleDownProtection = nil
+		Expect(acquireOperation(database, "Restore", time.Now())).To(BeTrue())
+
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 1)).To(Succeed())
+		Expect(replicas()).To(Equal(int32(3)))
+		Expect(database.Status.Operations.Pending).To(Equal([]string{disruptiveOperationScale}))
+
+		releaseOperation(database, "Restore")
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 1)).To(Succeed())
+		Expect(replicas()).To(Equal(int32(1)))
+		Expect(activeOperation(database)).To(Equal(disruptiveOperationScale))
+	})
+
+	It("should scale down immediately without protection", func() {
+		database.Spec.ScaleDownProtection = nil
+		Expect(reconciler.scaleStatefulSet(context.Background(), database, statefulSet, 1)).To(Succeed())
+		Expect(replicas()).To(Equal(int32(1)))
+	})
+})
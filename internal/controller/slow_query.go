@@ -0,0 +1,84 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// defaultSlowQueryThreshold is the threshold of slowQuery without one
+const defaultSlowQueryThreshold = time.Second
+
+// slowQueryThreshold returns the threshold of a Database capturing slow queries
+func slowQueryThreshold(database *databasesv1alpha1.Database) (time.Duration, bool) {
+	observability := database.Spec.Observability
+	if observability == nil || observability.SlowQuery == nil || !observability.SlowQuery.Enabled {
+		return 0, false
+	}
+	if threshold := observability.SlowQuery.Threshold; threshold != nil {
+		return threshold.Duration, true
+	}
+	return defaultSlowQueryThreshold, true
+}
+
+// validateSlowQuery checks that the engine captures slow queries
+func validateSlowQuery(database *databasesv1alpha1.Database) error {
+	threshold, ok := slowQueryThreshold(database)
+	if !ok {
+		return nil
+	}
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypeElasticsearch:
+		return fmt.Errorf("Elasticsearch sets slow log thresholds per index, remove observability.slowQuery")
+	case databasesv1alpha1.DatabaseTypeSQLite:
+		return fmt.Errorf("SQLite has no slow query log, remove observability.slowQuery")
+	}
+	if threshold < 0 {
+		return fmt.Errorf("observability.slowQuery.threshold must not be negative, got %s", threshold)
+	}
+	return nil
+}
+
+// slowQueryParameters returns the engine parameters capturing slow queries.
+// With the metrics exporter, PostgreSQL also loads pg_stat_statements, which
+// the monitoring user Job creates and the exporter reports.
+func slowQueryParameters(database *databasesv1alpha1.Database) map[string]string {
+	threshold, ok := slowQueryThreshold(database)
+	if !ok {
+		return nil
+	}
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		parameters := map[string]string{"log_min_duration_statement": strconv.FormatInt(threshold.Milliseconds(), 10)}
+		if _, ok := engineMetricsExporter(database); ok {
+			parameters["shared_preload_libraries"] = "pg_stat_statements"
+		}
+		return parameters
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		return map[string]string{
+			"operationProfiling.mode":              "slowOp",
+			"operationProfiling.slowOpThresholdMs": strconv.FormatInt(threshold.Milliseconds(), 10),
+		}
+	case databasesv1alpha1.DatabaseTypeRedis:
+		return map[string]string{"slowlog-log-slower-than": strconv.FormatInt(threshold.Microseconds(), 10)}
+	}
+	return nil
+}
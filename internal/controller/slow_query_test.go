@@ -0,0 +1,86 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Slow query capture", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		reconciler = &DatabaseReconciler{}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Storage: &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+				Observability: &databasesv1alpha1.ObservabilitySpec{
+					SlowQuery: &databasesv1alpha1.SlowQuerySpec{Enabled: true},
+				},
+			},
+		}
+	})
+
+	It("should log slow PostgreSQL statements and report them from pg_stat_statements", func() {
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+		Expect(postgreSQLParameters(database)).To(Equal(map[string]string{"log_min_duration_statement": "1000"}))
+
+		database.Spec.Observability.Metrics = &databasesv1alpha1.MetricsSpec{Enabled: true}
+		database.Spec.Observability.SlowQuery.Threshold = &metav1.Duration{Duration: 250 * time.Millisecond}
+		containers := reconciler.createPostgreSQLStatefulSet(database, 1, reconciler.getPostgreSQLEnv(database)).Spec.Template.Spec.Containers
+		Expect(containers[0].Args).To(ContainElements("log_min_duration_statement=250", "shared_preload_libraries=pg_stat_statements"))
+		Expect(containers[1].Args).To(ContainElement("--collector.stat_statements"))
+		Expect(metricsExporters[databasesv1alpha1.DatabaseTypePostgreSQL].args).To(BeEmpty())
+
+		// Engine parameters of the spec take precedence
+		database.Spec.PostgreSQL = &databasesv1alpha1.PostgreSQLConfig{Parameters: map[string]string{"log_min_duration_statement": "5000"}}
+		Expect(postgreSQLParameters(database)).To(HaveKeyWithValue("log_min_duration_statement", "5000"))
+	})
+
+	It("should use the profiler of MongoDB and the slow log of Redis", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		Expect(mongoDBParameters(database)).To(Equal(map[string]string{
+			"operationProfiling.mode":              "slowOp",
+			"operationProfiling.slowOpThresholdMs": "1000",
+		}))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		database.Spec.Version = "7"
+		Expect(redisParameters(database)).To(Equal(map[string]string{"slowlog-log-slower-than": "1000000"}))
+
+		database.Spec.Observability.SlowQuery.Enabled = false
+		Expect(redisParameters(database)).To(BeEmpty())
+
+		database.Spec.Observability.SlowQuery.Enabled = true
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeElasticsearch
+		database.Spec.Version = "8.11.0"
+		Expect(reconciler.validateSpec(database)).To(MatchError(ContainSubstring("slow log thresholds per index")))
+	})
+})
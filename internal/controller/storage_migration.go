@@ -0,0 +1,483 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	storageMigrationComponent = "storage-migration"
+
+	// storageMigrationRecheckInterval is how often the Jobs and volumes of a
+	// running migration are checked
+	storageMigrationRecheckInterval = 15 * time.Second
+	// storageMigrationVerifyTimeout is how long the workload has to become
+	// ready on the migrated volumes before the migration is rolled back
+	storageMigrationVerifyTimeout = 15 * time.Minute
+	// storageMigrationCopyDeadline bounds a copy Job, so a copy that hangs or
+	// whose pod never starts rolls the migration back
+	storageMigrationCopyDeadline = int64(6 * 3600)
+)
+
+// storageMigrationScript copies a data volume, with the ownership and modes of
+// its files, into the empty volume of the new class
+const storageMigrationScript = `set -e
+cp -a /var/lib/source/. /var/lib/target/`
+
+// storageMigrationTarget returns the storage class the data volumes must have,
+// or "" when spec.storage leaves it to the default class. The volumes of
+// Elasticsearch node sets follow the storage of their node set.
+func storageMigrationTarget(database *databasesv1alpha1.Database) string {
+	if database.Spec.Storage == nil || len(elasticsearchNodeSets(database)) > 0 {
+		return ""
+	}
+	return ptr.Deref(database.Spec.Storage.StorageClass, "")
+}
+
+// storageMigrationRunning reports whether a migration has not completed or rolled back
+func storageMigrationRunning(migration *databasesv1alpha1.StorageMigrationStatus) bool {
+	return migration != nil && migration.Step != databasesv1alpha1.StorageMigrationStepCompleted &&
+		migration.Step != databasesv1alpha1.StorageMigrationStepRolledBack
+}
+
+// reconcileStorageMigration moves the data volumes to a changed
+// storage.storageClassName, which the claims of the replicas cannot change in
+// place. Holding a lock that stops the workload, it copies every volume into a
+// new volume of the new class with a Job and binds the claim of the replica
+// to it. The workload then starts on the new volumes; when it fails to become
+// ready, or a copy fails, the previous volumes are put back. Once completed,
+// the previous volumes are deleted with the Delete deletionPolicy and retained
+// with Snapshot. A rolled back migration is retried once the class changes.
+func (r *DatabaseReconciler) reconcileStorageMigration(ctx context.Context, database *databasesv1alpha1.Database, now time.Time) error {
+	target := storageMigrationTarget(database)
+	migration := database.Status.StorageMigration
+	if migration != nil && migration.ToClass != target {
+		switch {
+		case !storageMigrationRunning(migration) || activeOperation(database) != disruptiveOperationStorageMigration:
+			// Nothing was copied yet
+			releaseOperation(database, disruptiveOperationStorageMigration)
+			database.Status.StorageMigration, migration = nil, nil
+		case migration.Step != databasesv1alpha1.StorageMigrationStepRollingBack:
+			migration.Step = databasesv1alpha1.StorageMigrationStepRollingBack
+			migration.Message = fmt.Sprintf("storageClassName changed to %q during the migration", target)
+		}
+	}
+	if migration == nil {
+		if target == "" {
+			return nil
+		}
+		from, found, err := r.storageClassToMigrate(ctx, database, target)
+		if err != nil || !found {
+			return err
+		}
+		migration = &databasesv1alpha1.StorageMigrationStatus{
+			FromClass: from,
+			ToClass:   target,
+			Step:      databasesv1alpha1.StorageMigrationStepCopy,
+			StartedAt: metav1.NewTime(now),
+		}
+		database.Status.StorageMigration = migration
+	}
+	if !storageMigrationRunning(migration) {
+		return nil
+	}
+
+	if activeOperation(database) != disruptiveOperationStorageMigration {
+		if frozen(database, now) {
+			migration.Message = "Deferred by the release freeze"
+			return nil
+		}
+		if deferredToMaintenance(database, disruptiveOperationStorageMigration, now) {
+			migration.Message = "Deferred to the maintenance window"
+			return nil
+		}
+		if !acquireOperation(database, disruptiveOperationStorageMigration, now) {
+			migration.Message = fmt.Sprintf("Waiting for operation %s to finish", activeOperation(database))
+			return nil
+		}
+		r.event(database, corev1.EventTypeNormal, "StorageMigrating", fmt.Sprintf("Copying the data volumes from storage class %q to %q",
+			migration.FromClass, migration.ToClass))
+	}
+	var err error
+	switch migration.Step {
+	case databasesv1alpha1.StorageMigrationStepCopy:
+		err = r.runStorageMigrationJobs(ctx, database, migration)
+	case databasesv1alpha1.StorageMigrationStepCutover:
+		err = r.cutOverStorageMigration(ctx, database, migration, now)
+	case databasesv1alpha1.StorageMigrationStepVerify:
+		err = r.verifyStorageMigration(ctx, database, migration, now)
+	default:
+		err = r.rollBackStorageMigration(ctx, database, migration, now)
+	}
+	// The workload runs again once the claims are bound to the migrated volumes
+	if activeOperation(database) == disruptiveOperationStorageMigration {
+		database.Status.Operations.Active.StopsWorkload = migration.Step != databasesv1alpha1.StorageMigrationStepVerify
+	}
+	return err
+}
+
+// storageClassToMigrate returns the class of the first bound data volume
+// whose class is not the target, and whether there is one
+func (r *DatabaseReconciler) storageClassToMigrate(ctx context.Context, database *databasesv1alpha1.Database, target string) (string, bool, error) {
+	for _, name := range dataClaimNames(database) {
+		claim := &corev1.PersistentVolumeClaim{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+		if apierrors.IsNotFound(err) || (err == nil && claim.Spec.VolumeName == "") {
+			continue
+		} else if err != nil {
+			return "", false, err
+		}
+		if class := ptr.Deref(claim.Spec.StorageClassName, ""); class != target {
+			return class, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// runStorageMigrationJobs copies every data volume into a volume of the new
+// class, once the workload is stopped, and moves on to the cutover once all
+// copies succeeded
+func (r *DatabaseReconciler) runStorageMigrationJobs(ctx context.Context, database *databasesv1alpha1.Database, migration *databasesv1alpha1.StorageMigrationStatus) error {
+	if stopped, err := r.deleteWorkload(ctx, database); err != nil || !stopped {
+		migration.Message = "Stopping database"
+		return err
+	}
+	if migration.Volumes == nil {
+		volumes, err := r.storageMigrationVolumes(ctx, database)
+		if err != nil {
+			return err
+		}
+		migration.Volumes = volumes
+	}
+
+	succeeded := 0
+	for i, volume := range migration.Volumes {
+		claim := &corev1.PersistentVolumeClaim{}
+		name := migratedClaimName(volume.Claim)
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+		if apierrors.IsNotFound(err) {
+			if claim, err = r.newMigratedClaim(ctx, database, volume.Claim); err != nil {
+				return err
+			}
+			if err := controllerutil.SetControllerReference(database, claim, r.Scheme); err != nil {
+				return err
+			}
+			log.FromContext(ctx).Info("Provisioning volume of the new storage class", "claim", name, "storageClass", migration.ToClass)
+			if err := r.Create(ctx, claim); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		job := &batchv1.Job{}
+		jobName := storageMigrationJobName(database, i)
+		err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: database.Namespace}, job)
+		if apierrors.IsNotFound(err) {
+			job = r.createStorageMigrationJob(database, volume.Claim, i)
+			if err := controllerutil.SetControllerReference(database, job, r.Scheme); err != nil {
+				return err
+			}
+			log.FromContext(ctx).Info("Creating storage migration Job", "name", jobName)
+			if err := r.Create(ctx, job); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		switch finished, ok := jobFinished(job); {
+		case finished && !ok:
+			migration.Message = fmt.Sprintf("copy Job %s failed, see its logs", jobName)
+			if jobDeadlineExceeded(job) {
+				migration.Message = fmt.Sprintf("copy Job %s did not finish within %s", jobName,
+					time.Duration(storageMigrationCopyDeadline)*time.Second)
+			}
+			migration.Step = databasesv1alpha1.StorageMigrationStepRollingBack
+			return nil
+		case finished:
+			succeeded++
+		}
+	}
+
+	if succeeded < len(migration.Volumes) {
+		migration.Message = fmt.Sprintf("Copied %d/%d volumes", succeeded, len(migration.Volumes))
+		return nil
+	}
+	migration.Message = ""
+	migration.Step = databasesv1alpha1.StorageMigrationStepCutover
+	return nil
+}
+
+// storageMigrationVolumes lists the bound data volumes; replicas that never
+// ran start on a volume of the new class
+func (r *DatabaseReconciler) storageMigrationVolumes(ctx context.Context, database *databasesv1alpha1.Database) ([]databasesv1alpha1.StorageMigrationVolume, error) {
+	volumes := []databasesv1alpha1.StorageMigrationVolume{}
+	for _, name := range dataClaimNames(database) {
+		claim := &corev1.PersistentVolumeClaim{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+		if apierrors.IsNotFound(err) || (err == nil && claim.Spec.VolumeName == "") {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, databasesv1alpha1.StorageMigrationVolume{Claim: name, PreviousVolume: claim.Spec.VolumeName})
+	}
+	return volumes, nil
+}
+
+// newMigratedClaim builds the volume of the new class a data volume is copied
+// to, at least as large as the data volume
+func (r *DatabaseReconciler) newMigratedClaim(ctx context.Context, database *databasesv1alpha1.Database, name string) (*corev1.PersistentVolumeClaim, error) {
+	claim, err := r.newDataClaim(database, migratedClaimName(name))
+	if err != nil {
+		return nil, err
+	}
+	current := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, current); err != nil {
+		return nil, err
+	}
+	requested := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	if size, ok := current.Spec.Resources.Requests[corev1.ResourceStorage]; ok && size.Cmp(requested) > 0 {
+		claim.Spec.Resources.Requests[corev1.ResourceStorage] = size
+	}
+	return claim, nil
+}
+
+// cutOverStorageMigration binds the claim of every replica to its migrated
+// volume. The volumes of both classes are retained while their claims are swapped.
+func (r *DatabaseReconciler) cutOverStorageMigration(ctx context.Context, database *databasesv1alpha1.Database, migration *databasesv1alpha1.StorageMigrationStatus, now time.Time) error {
+	bound := 0
+	for i := range migration.Volumes {
+		volume := &migration.Volumes[i]
+		claim := &corev1.PersistentVolumeClaim{}
+		name := migratedClaimName(volume.Claim)
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+		if err == nil {
+			if claim.Spec.VolumeName == "" {
+				return fmt.Errorf("volume claim %s of the new storage class is not bound", name)
+			}
+			volume.MigratedVolume = claim.Spec.VolumeName
+			if err := r.releaseClaim(ctx, claim); err != nil {
+				return err
+			}
+			continue
+		} else if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		done, err := r.bindClaim(ctx, database, volume.Claim, volume.MigratedVolume)
+		if err != nil {
+			return err
+		}
+		if done {
+			bound++
+		}
+	}
+
+	if bound < len(migration.Volumes) {
+		migration.Message = fmt.Sprintf("Bound %d/%d claims to the migrated volumes", bound, len(migration.Volumes))
+		return nil
+	}
+	log.FromContext(ctx).Info("Cut over to the migrated volumes", "storageClass", migration.ToClass)
+	migration.Step = databasesv1alpha1.StorageMigrationStepVerify
+	migration.Message = ""
+	cutOverAt := metav1.NewTime(now)
+	migration.CutOverAt = &cutOverAt
+	return nil
+}
+
+// verifyStorageMigration completes the migration once every replica is ready
+// on the migrated volumes, and rolls it back when they are not in time. The
+// previous volumes are then cleaned up according to the deletionPolicy.
+func (r *DatabaseReconciler) verifyStorageMigration(ctx context.Context, database *databasesv1alpha1.Database, migration *databasesv1alpha1.StorageMigrationStatus, now time.Time) error {
+	desired := desiredReplicas(database)
+	if database.Status.ReadyReplicas < desired {
+		migration.Message = fmt.Sprintf("%d of %d replicas are ready on the migrated volumes", database.Status.ReadyReplicas, desired)
+		if migration.CutOverAt != nil && now.Sub(migration.CutOverAt.Time) > storageMigrationVerifyTimeout {
+			migration.Step = databasesv1alpha1.StorageMigrationStepRollingBack
+			migration.Message = fmt.Sprintf("the replicas did not become ready on the migrated volumes within %s", storageMigrationVerifyTimeout)
+		}
+		return nil
+	}
+
+	message := fmt.Sprintf("Migrated the data volumes from storage class %q to %q", migration.FromClass, migration.ToClass)
+	if database.Spec.DeletionPolicy == databasesv1alpha1.DeletionPolicySnapshot {
+		message += ", the previous volumes are retained"
+	} else {
+		for _, volume := range migration.Volumes {
+			if err := r.reclaimVolume(ctx, volume.PreviousVolume); err != nil {
+				return err
+			}
+		}
+	}
+	log.FromContext(ctx).Info("Completed storage migration", "from", migration.FromClass, "to", migration.ToClass)
+	r.finishStorageMigration(ctx, database, migration, databasesv1alpha1.StorageMigrationStepCompleted, message, now)
+	r.event(database, corev1.EventTypeNormal, "StorageMigrated", message)
+	recordOperation(database, recordStorageMigration, databasesv1alpha1.OperationSucceeded, message, now)
+	return nil
+}
+
+// rollBackStorageMigration stops the workload and binds the claims back to
+// the previous volumes, deleting the migrated ones
+func (r *DatabaseReconciler) rollBackStorageMigration(ctx context.Context, database *databasesv1alpha1.Database, migration *databasesv1alpha1.StorageMigrationStatus, now time.Time) error {
+	if stopped, err := r.deleteWorkload(ctx, database); err != nil || !stopped {
+		return err
+	}
+
+	restored := 0
+	for i, volume := range migration.Volumes {
+		// Before the cutover the migrated volume is still claimed on its own
+		claim := &corev1.PersistentVolumeClaim{}
+		name := migratedClaimName(volume.Claim)
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+		if err == nil {
+			if claim.DeletionTimestamp.IsZero() {
+				if err := r.Delete(ctx, claim); err != nil && !apierrors.IsNotFound(err) {
+					return err
+				}
+			}
+			continue
+		} else if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		if volume.MigratedVolume != "" {
+			done, err := r.bindClaim(ctx, database, volume.Claim, volume.PreviousVolume)
+			if err != nil || !done {
+				return err
+			}
+			if err := r.reclaimVolume(ctx, volume.MigratedVolume); err != nil {
+				return err
+			}
+			migration.Volumes[i].MigratedVolume = ""
+		}
+		restored++
+	}
+	if restored < len(migration.Volumes) {
+		return nil
+	}
+
+	reason := migration.Message
+	message := fmt.Sprintf("Rolled back the migration from storage class %q to %q: %s", migration.FromClass, migration.ToClass, reason)
+	log.FromContext(ctx).Info("Rolled back storage migration", "from", migration.FromClass, "to", migration.ToClass, "reason", reason)
+	r.finishStorageMigration(ctx, database, migration, databasesv1alpha1.StorageMigrationStepRolledBack, message, now)
+	r.event(database, corev1.EventTypeWarning, "StorageMigrationRolledBack", message)
+	recordOperation(database, recordStorageMigration, databasesv1alpha1.OperationFailed, message, now)
+	return nil
+}
+
+// finishStorageMigration records the outcome, removes the Jobs and releases the lock
+func (r *DatabaseReconciler) finishStorageMigration(ctx context.Context, database *databasesv1alpha1.Database, migration *databasesv1alpha1.StorageMigrationStatus, step databasesv1alpha1.StorageMigrationStep, message string, now time.Time) {
+	for i := range migration.Volumes {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: storageMigrationJobName(database, i), Namespace: database.Namespace}}
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+			log.FromContext(ctx).Error(err, "Failed to delete storage migration Job", "name", job.Name)
+		}
+	}
+	completedAt := metav1.NewTime(now)
+	migration.Step = step
+	migration.Message = message
+	migration.CompletedAt = &completedAt
+	releaseOperation(database, disruptiveOperationStorageMigration)
+}
+
+// migratedClaimName is the claim of the volume of the new class a data volume
+// is copied to
+func migratedClaimName(claim string) string {
+	return claim + "-migrated"
+}
+
+// storageMigrationJobName is the name of the copy Job of the volume of a replica
+func storageMigrationJobName(database *databasesv1alpha1.Database, ordinal int) string {
+	return fmt.Sprintf("%s-%s-%d", database.Name, storageMigrationComponent, ordinal)
+}
+
+// createStorageMigrationJob builds the Job copying the data volume of a
+// replica. It runs the engine image as root, so the copy keeps the owner of
+// the files the engine writes as. Restricted pods cannot run as root: they copy
+// as the user of the engine, which owns the files, and the fsGroup of the pod
+// makes the new volume writable.
+func (r *DatabaseReconciler) createStorageMigrationJob(database *databasesv1alpha1.Database, claim string, ordinal int) *batchv1.Job {
+	backoffLimit := int32(0)
+	deadline := storageMigrationCopyDeadline
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      storageMigrationJobName(database, ordinal),
+			Namespace: database.Namespace,
+			Labels:    r.getComponentLabels(database, storageMigrationComponent),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &deadline,
+			Template:              r.adminPodTemplate(database, storageMigrationComponent, engineImage(database), storageMigrationScript, nil),
+		},
+	}
+
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes,
+		corev1.Volume{
+			Name: "source",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim, ReadOnly: true},
+			},
+		},
+		corev1.Volume{
+			Name: "target",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: migratedClaimName(claim)},
+			},
+		},
+	)
+	container := &podSpec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts,
+		corev1.VolumeMount{Name: "source", MountPath: "/var/lib/source", ReadOnly: true},
+		corev1.VolumeMount{Name: "target", MountPath: "/var/lib/target"},
+	)
+	if podSecurityLevel(database) != databasesv1alpha1.PodSecurityRestricted {
+		container.SecurityContext = &corev1.SecurityContext{RunAsUser: ptr.To(int64(0))}
+	}
+	return job
+}
+
+// markMigrating keeps the Database out of Ready while its data volumes move to
+// a new storage class
+func markMigrating(database *databasesv1alpha1.Database, migration *databasesv1alpha1.StorageMigrationStatus) {
+	message := fmt.Sprintf("Migrating the data volumes to storage class %q: %s", migration.ToClass, migration.Step)
+	if migration.Message != "" {
+		message += ", " + migration.Message
+	}
+	setPhase(database, databasesv1alpha1.DatabasePhaseMigrating, "", time.Now())
+	database.Status.Message = message
+	setCondition(database, conditionReady, metav1.ConditionFalse, reasonMigrating, message)
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Storage class migrations", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		now        time.Time
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "shop"}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypeRedis,
+				Version:  "7.2",
+				Replicas: ptr.To(int32(1)),
+				Storage:  &databasesv1alpha1.StorageSpec{Size: "10Gi", StorageClass: ptr.To("fast")},
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"}},
+			&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "data-orders-0", Namespace: "shop"},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					StorageClassName: ptr.To("standard"),
+					VolumeName:       "pv-standard",
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")},
+					},
+				},
+			},
+			&corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-standard"},
+				Spec: corev1.PersistentVolumeSpec{
+					StorageClassName:              "standard",
+					PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+					ClaimRef:                      &corev1.ObjectReference{Name: "data-orders-0", Namespace: "shop"},
+				},
+			},
+			&corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-fast"},
+				Spec: corev1.PersistentVolumeSpec{
+					StorageClassName:              "fast",
+					PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+				},
+			},
+		).Build()
+		reconciler = &DatabaseReconciler{Client: c, Scheme: scheme}
+	})
+
+	reconcile := func() *databasesv1alpha1.StorageMigrationStatus {
+		Expect(reconciler.reconcileStorageMigration(ctx, database, now)).To(Succeed())
+		return database.Status.StorageMigration
+	}
+
+	finishJob := func(condition batchv1.JobConditionType) {
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, key("orders-storage-migration-0"), job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+		Expect(c.Status().Update(ctx, job)).To(Succeed())
+	}
+
+	volume := func(name string) *corev1.PersistentVolume {
+		volume := &corev1.PersistentVolume{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: name}, volume)).To(Succeed())
+		return volume
+	}
+
+	claim := func(name string) *corev1.PersistentVolumeClaim {
+		claim := &corev1.PersistentVolumeClaim{}
+		Expect(c.Get(ctx, key(name), claim)).To(Succeed())
+		return claim
+	}
+
+	It("should copy the data volumes to the new class and delete the previous ones", func() {
+		migration := reconcile()
+		Expect(migration.FromClass).To(Equal("standard"))
+		Expect(migration.ToClass).To(Equal("fast"))
+		Expect(activeOperation(database)).To(Equal(disruptiveOperationStorageMigration))
+		Expect(database.Status.Operations.Active.StopsWorkload).To(BeTrue())
+		err := c.Get(ctx, key("orders"), &appsv1.StatefulSet{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		reconcile()
+		Expect(migration.Volumes).To(Equal([]databasesv1alpha1.StorageMigrationVolume{{Claim: "data-orders-0", PreviousVolume: "pv-standard"}}))
+		migrated := claim("data-orders-0-migrated")
+		Expect(*migrated.Spec.StorageClassName).To(Equal("fast"))
+		// As large as the volume it is copied from
+		Expect(migrated.Spec.Resources.Requests.Storage().String()).To(Equal("20Gi"))
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, key("orders-storage-migration-0"), job)).To(Succeed())
+		Expect(job.Spec.Template.Spec.Volumes).To(ContainElements(
+			HaveField("PersistentVolumeClaim.ClaimName", "data-orders-0"),
+			HaveField("PersistentVolumeClaim.ClaimName", "data-orders-0-migrated"),
+		))
+		Expect(*job.Spec.ActiveDeadlineSeconds).To(Equal(storageMigrationCopyDeadline))
+		// Redis pods are restricted, the copy runs as the user of the engine
+		podSpec := job.Spec.Template.Spec
+		Expect(*podSpec.SecurityContext.RunAsUser).To(Equal(int64(999)))
+		Expect(*podSpec.SecurityContext.RunAsNonRoot).To(BeTrue())
+		Expect(podSpec.Containers[0].SecurityContext.RunAsUser).To(BeNil())
+		Expect(*podSpec.Containers[0].SecurityContext.AllowPrivilegeEscalation).To(BeFalse())
+
+		finishJob(batchv1.JobComplete)
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.StorageMigrationStepCutover))
+		migrated.Spec.VolumeName = "pv-fast"
+		Expect(c.Update(ctx, migrated)).To(Succeed())
+		for i := 0; i < 4 && migration.Step == databasesv1alpha1.StorageMigrationStepCutover; i++ {
+			reconcile()
+		}
+		Expect(migration.Step).To(Equal(databasesv1alpha1.StorageMigrationStepVerify))
+		Expect(claim("data-orders-0").Spec.VolumeName).To(Equal("pv-fast"))
+		Expect(*claim("data-orders-0").Spec.StorageClassName).To(Equal("fast"))
+		Expect(volume("pv-standard").Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimRetain))
+		Expect(database.Status.Operations.Active.StopsWorkload).To(BeFalse())
+
+		database.Status.ReadyReplicas = 1
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.StorageMigrationStepCompleted))
+		Expect(activeOperation(database)).To(BeEmpty())
+		Expect(volume("pv-standard").Spec.PersistentVolumeReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimDelete))
+		Expect(database.Status.RecentOperations).To(ConsistOf(And(
+			HaveField("Type", recordStorageMigration),
+			HaveField("Outcome", databasesv1alpha1.OperationSucceeded),
+		)))
+		err = c.Get(ctx, key("orders-storage-migration-0"), &batchv1.Job{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should run the copy as root for baseline pods", func() {
+		database.Spec.PodSecurity = databasesv1alpha1.PodSecurityBaseline
+		job := reconciler.createStorageMigrationJob(database, "data-orders-0", 0)
+		Expect(*job.Spec.Template.Spec.Containers[0].SecurityContext.RunAsUser).To(BeZero())
+	})
+
+	It("should roll back a copy that does not finish in time", func() {
+		reconcile()
+		reconcile()
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, key("orders-storage-migration-0"), job)).To(Succeed())
+		job.Status.Conditions = []batchv1.JobCondition{{
+			Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: batchv1.JobReasonDeadlineExceeded,
+		}}
+		Expect(c.Status().Update(ctx, job)).To(Succeed())
+
+		migration := reconcile()
+		Expect(migration.Step).To(Equal(databasesv1alpha1.StorageMigrationStepRollingBack))
+		Expect(migration.Message).To(Equal("copy Job orders-storage-migration-0 did not finish within 6h0m0s"))
+	})
+
+	It("should put the previous volumes back when the copy fails", func() {
+		reconcile()
+		reconcile()
+		finishJob(batchv1.JobFailed)
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.StorageMigrationStepRollingBack))
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.StorageMigrationStepRollingBack))
+		err := c.Get(ctx, key("data-orders-0-migrated"), &corev1.PersistentVolumeClaim{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		migration := reconcile()
+		Expect(migration.Step).To(Equal(databasesv1alpha1.StorageMigrationStepRolledBack))
+		Expect(migration.Message).To(ContainSubstring("orders-storage-migration-0 failed"))
+		Expect(database.Status.RecentOperations).To(ConsistOf(HaveField("Outcome", databasesv1alpha1.OperationFailed)))
+		Expect(claim("data-orders-0").Spec.VolumeName).To(Equal("pv-standard"))
+
+		// Not retried until the class changes
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.StorageMigrationStepRolledBack))
+		database.Spec.Storage.StorageClass = ptr.To("premium")
+		Expect(reconcile().Step).To(Equal(databasesv1alpha1.StorageMigrationStepCopy))
+	})
+})
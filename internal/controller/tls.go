@@ -0,0 +1,243 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	tlsSuffix    = "-tls"
+	tlsVolume    = "tls"
+	tlsMountPath = "/etc/database-tls"
+	// tlsCombinedVolume holds the certificate and key of a TLS Secret in a
+	// single file for MongoDB, which cert-manager writes itself
+	tlsCombinedVolume    = "tls-combined"
+	tlsCombinedMountPath = "/etc/database-tls-combined"
+	tlsCombinedFile      = "tls-combined.pem"
+	// redisTLSPort serves TLS next to the plaintext port used by the Jobs of
+	// the operator
+	redisTLSPort = 6380
+)
+
+var certificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// tlsEnabled reports whether the database endpoint is served over TLS
+func tlsEnabled(database *databasesv1alpha1.Database) bool {
+	tls := database.Spec.TLS
+	return tls != nil && (tls.CertManager != nil || tls.SecretName != "")
+}
+
+// tlsSecretName returns the Secret holding the server certificate: the one of
+// the spec, or the one cert-manager issues
+func tlsSecretName(database *databasesv1alpha1.Database) string {
+	if database.Spec.TLS != nil && database.Spec.TLS.SecretName != "" {
+		return database.Spec.TLS.SecretName
+	}
+	return database.Name + tlsSuffix
+}
+
+// mongoDBCertificateKeyFile returns the file holding the certificate and the
+// key for mongod
+func mongoDBCertificateKeyFile(database *databasesv1alpha1.Database) string {
+	if database.Spec.TLS.CertManager != nil {
+		return path.Join(tlsMountPath, tlsCombinedFile)
+	}
+	return path.Join(tlsCombinedMountPath, tlsCombinedFile)
+}
+
+// validateTLS checks that the engine serves TLS with the settings of its
+// configuration file
+func validateTLS(database *databasesv1alpha1.Database) error {
+	if !tlsEnabled(database) {
+		return nil
+	}
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+	case databasesv1alpha1.DatabaseTypeMongoDB, databasesv1alpha1.DatabaseTypeRedis:
+		if engineLayout(database).configPath == "" {
+			return fmt.Errorf("the %s %s image does not read the configuration file of the TLS settings", imageFlavor(database), database.Spec.Type)
+		}
+	default:
+		return fmt.Errorf("%s does not support tls", database.Spec.Type)
+	}
+	return nil
+}
+
+// tlsParameters returns the engine parameters serving TLS with the mounted
+// certificate. Plaintext connections stay accepted, for the Jobs and probes of
+// the operator, and client certificates are not requested.
+func tlsParameters(database *databasesv1alpha1.Database) map[string]string {
+	if !tlsEnabled(database) {
+		return nil
+	}
+	file := func(name string) string { return path.Join(tlsMountPath, name) }
+	switch database.Spec.Type {
+	case databasesv1alpha1.DatabaseTypePostgreSQL:
+		return map[string]string{
+			"ssl":           "on",
+			"ssl_cert_file": file(corev1.TLSCertKey),
+			"ssl_key_file":  file(corev1.TLSPrivateKeyKey),
+		}
+	case databasesv1alpha1.DatabaseTypeMongoDB:
+		return map[string]string{
+			"net.tls.mode":                                "preferTLS",
+			"net.tls.certificateKeyFile":                  mongoDBCertificateKeyFile(database),
+			"net.tls.allowConnectionsWithoutCertificates": "true",
+		}
+	case databasesv1alpha1.DatabaseTypeRedis:
+		return map[string]string{
+			"tls-port":         fmt.Sprint(redisTLSPort),
+			"tls-cert-file":    file(corev1.TLSCertKey),
+			"tls-key-file":     file(corev1.TLSPrivateKeyKey),
+			"tls-auth-clients": "no",
+		}
+	}
+	return nil
+}
+
+// applyTLS mounts the server certificate into a new workload. The private key
+// is readable by the group of the engine only, as PostgreSQL requires.
+func applyTLS(database *databasesv1alpha1.Database, template *corev1.PodTemplateSpec) {
+	if !tlsEnabled(database) {
+		return
+	}
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name: tlsVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  tlsSecretName(database),
+				DefaultMode: ptr.To(int32(0o440)),
+			},
+		},
+	})
+	container := &template.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      tlsVolume,
+		MountPath: tlsMountPath,
+		ReadOnly:  true,
+	})
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeRedis {
+		container.Ports = append(container.Ports, corev1.ContainerPort{
+			Name:          "redis-tls",
+			ContainerPort: redisTLSPort,
+			Protocol:      corev1.ProtocolTCP,
+		})
+	}
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB && database.Spec.TLS.CertManager == nil {
+		combined := corev1.VolumeMount{Name: tlsCombinedVolume, MountPath: tlsCombinedMountPath}
+		template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+			Name:         tlsCombinedVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}},
+		})
+		template.Spec.InitContainers = append(template.Spec.InitContainers, corev1.Container{
+			Name:  "combine-tls",
+			Image: container.Image,
+			Command: []string{"sh", "-c", fmt.Sprintf("cat %s %s > %s",
+				path.Join(tlsMountPath, corev1.TLSCertKey), path.Join(tlsMountPath, corev1.TLSPrivateKeyKey),
+				path.Join(tlsCombinedMountPath, tlsCombinedFile))},
+			VolumeMounts: []corev1.VolumeMount{{Name: tlsVolume, MountPath: tlsMountPath, ReadOnly: true}, combined},
+		})
+		combined.ReadOnly = true
+		container.VolumeMounts = append(container.VolumeMounts, combined)
+	}
+
+	layout := engineLayout(database)
+	group := layout.user
+	if group == 0 {
+		group = layout.group
+	}
+	if template.Spec.SecurityContext == nil {
+		template.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if template.Spec.SecurityContext.FSGroup == nil && group != 0 {
+		template.Spec.SecurityContext.FSGroup = ptr.To(group)
+	}
+}
+
+// reconcileTLSCertificate requests the server certificate from cert-manager,
+// for the names of the database Service and of the replicas
+func (r *DatabaseReconciler) reconcileTLSCertificate(ctx context.Context, database *databasesv1alpha1.Database) error {
+	if database.Spec.TLS == nil || database.Spec.TLS.CertManager == nil {
+		return nil
+	}
+	issuer := database.Spec.TLS.CertManager.IssuerRef
+	kind := issuer.Kind
+	if kind == "" {
+		kind = "Issuer"
+	}
+	service, headless := database.Name+"-service", database.Name+headlessServiceSuffix
+	spec := map[string]any{
+		"secretName": tlsSecretName(database),
+		"dnsNames": []any{
+			service,
+			fmt.Sprintf("%s.%s", service, database.Namespace),
+			fmt.Sprintf("%s.%s.svc", service, database.Namespace),
+			fmt.Sprintf("%s.%s.svc.cluster.local", service, database.Namespace),
+			fmt.Sprintf("*.%s.%s.svc", headless, database.Namespace),
+			fmt.Sprintf("*.%s.%s.svc.cluster.local", headless, database.Namespace),
+		},
+		"issuerRef": map[string]any{"name": issuer.Name, "kind": kind, "group": certificateGVK.Group},
+	}
+	// mongod reads the certificate and the key from a single file
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeMongoDB {
+		spec["additionalOutputFormats"] = []any{map[string]any{"type": "CombinedPEM"}}
+	}
+
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(certificateGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: tlsSecretName(database), Namespace: database.Namespace}, certificate)
+	switch {
+	case meta.IsNoMatchError(err):
+		return fmt.Errorf("tls.certManager requires cert-manager to be installed: %w", err)
+	case errors.IsNotFound(err):
+		log.FromContext(ctx).Info("Creating Certificate", "name", tlsSecretName(database), "issuer", issuer.Name)
+	case err != nil:
+		return err
+	case equality.Semantic.DeepEqual(certificate.Object["spec"], spec):
+		return nil
+	default:
+		log.FromContext(ctx).Info("Updating Certificate", "name", certificate.GetName())
+	}
+
+	certificate = &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	certificate.SetGroupVersionKind(certificateGVK)
+	certificate.SetName(tlsSecretName(database))
+	certificate.SetNamespace(database.Namespace)
+	certificate.SetLabels(r.getLabels(database))
+	if err := controllerutil.SetControllerReference(database, certificate, r.Scheme); err != nil {
+		return err
+	}
+	return r.apply(ctx, certificate)
+}
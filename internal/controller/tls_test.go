@@ -0,0 +1,152 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("TLS", func() {
+	var (
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(applyPatches()).Build(), Scheme: scheme}
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				TLS: &databasesv1alpha1.TLSSpec{CertManager: &databasesv1alpha1.CertManagerTLSSpec{
+					IssuerRef: databasesv1alpha1.CertManagerIssuerReference{Name: "internal-ca", Kind: "ClusterIssuer"},
+				}},
+				PostgreSQL: &databasesv1alpha1.PostgreSQLConfig{Parameters: map[string]string{"ssl_min_protocol_version": "TLSv1.3"}},
+			},
+		}
+	})
+
+	It("should serve PostgreSQL over TLS with the issued certificate", func() {
+		Expect(reconciler.validateSpec(database)).To(Succeed())
+		template := reconciler.createPostgreSQLStatefulSet(database, 1, reconciler.getPostgreSQLEnv(database)).Spec.Template
+		container := template.Spec.Containers[0]
+		Expect(container.Args).To(ContainElements(
+			"ssl=on",
+			"ssl_cert_file=/etc/database-tls/tls.crt",
+			"ssl_key_file=/etc/database-tls/tls.key",
+			"ssl_min_protocol_version=TLSv1.3",
+		))
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "tls", MountPath: "/etc/database-tls", ReadOnly: true}))
+		Expect(template.Spec.Volumes).To(ContainElement(corev1.Volume{Name: "tls", VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: "orders-tls", DefaultMode: ptr.To(int32(0o440))},
+		}}))
+		// The postgres user reads the key through the group of the volume
+		Expect(*template.Spec.SecurityContext.FSGroup).To(Equal(int64(999)))
+
+		Expect(reconciler.reconcileTLSCertificate(context.Background(), database)).To(Succeed())
+		certificate := &unstructured.Unstructured{}
+		certificate.SetGroupVersionKind(certificateGVK)
+		Expect(reconciler.Get(context.Background(), types.NamespacedName{Name: "orders-tls", Namespace: "shop"}, certificate)).To(Succeed())
+		Expect(certificate.Object["spec"]).To(Equal(map[string]any{
+			"secretName": "orders-tls",
+			"dnsNames": []any{
+				"orders-service", "orders-service.shop", "orders-service.shop.svc", "orders-service.shop.svc.cluster.local",
+				"*.orders-headless.shop.svc", "*.orders-headless.shop.svc.cluster.local",
+			},
+			"issuerRef": map[string]any{"name": "internal-ca", "kind": "ClusterIssuer", "group": "cert-manager.io"},
+		}))
+		Expect(certificate.GetOwnerReferences()).To(ConsistOf(HaveField("Name", "orders")))
+	})
+
+	It("should add a TLS port to Redis and a combined PEM to MongoDB", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		database.Spec.PostgreSQL = nil
+		Expect(redisParameters(database)).To(HaveKeyWithValue("tls-port", "6380"))
+		Expect(reconciler.getServicePorts(database)).To(ContainElement(HaveField("Port", int32(6380))))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		Expect(mongoDBParameters(database)).To(HaveKeyWithValue("net.tls.certificateKeyFile", "/etc/database-tls/tls-combined.pem"))
+		Expect(reconciler.reconcileTLSCertificate(context.Background(), database)).To(Succeed())
+		certificate := &unstructured.Unstructured{}
+		certificate.SetGroupVersionKind(certificateGVK)
+		Expect(reconciler.Get(context.Background(), types.NamespacedName{Name: "orders-tls", Namespace: "shop"}, certificate)).To(Succeed())
+		Expect(certificate.Object["spec"]).To(HaveKeyWithValue("additionalOutputFormats", []any{map[string]any{"type": "CombinedPEM"}}))
+	})
+
+	It("should serve the certificate of a TLS Secret", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		database.Spec.PostgreSQL = nil
+		database.Spec.TLS = &databasesv1alpha1.TLSSpec{SecretName: "orders-server-cert"}
+		Expect(secretKeyReferences(database)).To(Equal([]secretKeyReference{
+			{Field: "spec.tls.secretName", Name: "orders-server-cert", Key: "tls.crt"},
+			{Field: "spec.tls.secretName", Name: "orders-server-cert", Key: "tls.key"},
+		}))
+
+		template := reconciler.createMongoDBStatefulSet(database, 1, reconciler.getMongoDBEnv(database)).Spec.Template
+		Expect(template.Spec.Volumes).To(ContainElement(HaveField("Secret.SecretName", "orders-server-cert")))
+		// mongod reads the certificate and the key from one file
+		Expect(template.Spec.InitContainers).To(ConsistOf(And(
+			HaveField("Name", "combine-tls"),
+			HaveField("Command", ContainElement(
+				"cat /etc/database-tls/tls.crt /etc/database-tls/tls.key > /etc/database-tls-combined/tls-combined.pem")),
+		)))
+		Expect(template.Spec.Containers[0].VolumeMounts).To(ContainElement(
+			corev1.VolumeMount{Name: "tls-combined", MountPath: "/etc/database-tls-combined", ReadOnly: true}))
+		Expect(mongoDBParameters(database)).To(HaveKeyWithValue(
+			"net.tls.certificateKeyFile", "/etc/database-tls-combined/tls-combined.pem"))
+
+		// No Certificate is requested
+		Expect(reconciler.reconcileTLSCertificate(context.Background(), database)).To(Succeed())
+		Expect(reconciler.getConnectionString(database, "orders-service")).To(
+			Equal("mongodb://<username>:<password>@orders-service.shop.svc.cluster.local:27017/admin?tls=true"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeRedis
+		Expect(reconciler.getConnectionString(database, "orders-service")).To(
+			Equal("rediss://:<password>@orders-service.shop.svc.cluster.local:6380"))
+		database.Spec.Type = databasesv1alpha1.DatabaseTypePostgreSQL
+		Expect(reconciler.getConnectionString(database, "orders-service")).To(
+			Equal("postgresql://<username>:<password>@orders-service.shop.svc.cluster.local:5432/postgres?sslmode=verify-full"))
+	})
+
+	It("should reject engines and images without TLS settings", func() {
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeElasticsearch
+		database.Spec.PostgreSQL = nil
+		Expect(validateTLS(database)).To(MatchError("Elasticsearch does not support tls"))
+
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		database.Spec.Image = &databasesv1alpha1.ImageSpec{Flavor: databasesv1alpha1.ImageFlavorBitnami}
+		Expect(validateTLS(database)).To(MatchError("the Bitnami MongoDB image does not read the configuration file of the TLS settings"))
+	})
+})
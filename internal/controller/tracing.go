@@ -0,0 +1,81 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// tracerName is the instrumentation scope of the spans of the operator
+	tracerName = "github.com/ivikasavnish/database-crd/internal/controller"
+	// tracingServiceName is the service.name of the exported spans
+	tracingServiceName = "database-operator"
+)
+
+// SetupTracing exports the spans of the reconcile pipeline to the OTLP gRPC
+// endpoint and returns the function flushing them on shutdown. Without it,
+// spans go to the no-op global provider.
+func SetupTracing(ctx context.Context, endpoint string, insecure bool) (func(context.Context) error, error) {
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", tracingServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// startSpan starts a span of the reconcile pipeline
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// endSpan ends a span, marking it failed when err is set
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// databaseAttributes identify the Database a span reconciles
+func databaseAttributes(database *databasesv1alpha1.Database) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("database.type", string(database.Spec.Type)),
+		attribute.String("database.version", database.Spec.Version),
+		attribute.Int64("database.generation", database.Generation),
+	}
+}
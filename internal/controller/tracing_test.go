@@ -0,0 +1,119 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		spans      *tracetest.SpanRecorder
+		previous   trace.TracerProvider
+		quotaFull  bool
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		quotaFull = false
+		spans = tracetest.NewSpanRecorder()
+		previous = otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+		DeferCleanup(func() { otel.SetTracerProvider(previous) })
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", Generation: 1},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypeRedis,
+				Version: "7",
+			},
+		}
+		apply := applyPatches()
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(database).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					if _, ok := obj.(*appsv1.StatefulSet); ok && quotaFull {
+						return errors.New("exceeded quota")
+					}
+					return apply.Patch(ctx, c, obj, patch, opts...)
+				},
+			}).Build()
+		reconciler = &DatabaseReconciler{Client: c, Scheme: scheme}
+	})
+
+	// ended returns the ended spans by name
+	ended := func() map[string]sdktrace.ReadOnlySpan {
+		byName := map[string]sdktrace.ReadOnlySpan{}
+		for _, span := range spans.Ended() {
+			byName[span.Name()] = span
+		}
+		return byName
+	}
+
+	It("should trace every provisioning step and the engine below the workload", func() {
+		ctx, root := startSpan(ctx, "Reconcile")
+		Expect(reconciler.reconcileProvisioning(ctx, database)).To(Succeed())
+		root.End()
+
+		byName := ended()
+		Expect(byName).To(HaveKey("Config"))
+		Expect(byName).To(HaveKey("Service"))
+		Expect(byName).To(HaveKey("Workload"))
+		Expect(byName).To(HaveKey("Redis"))
+		for _, step := range []string{"Config", "Service", "Workload"} {
+			Expect(byName[step].Parent().SpanID()).To(Equal(root.SpanContext().SpanID()))
+			Expect(byName[step].Status().Code).To(Equal(codes.Unset))
+		}
+		Expect(byName["Redis"].Parent().SpanID()).To(Equal(byName["Workload"].SpanContext().SpanID()))
+	})
+
+	It("should mark the spans of a failed step", func() {
+		quotaFull = true
+		Expect(reconciler.reconcileProvisioning(ctx, database)).To(HaveOccurred())
+
+		byName := ended()
+		Expect(byName["Service"].Status().Code).To(Equal(codes.Unset))
+		Expect(byName["Workload"].Status().Code).To(Equal(codes.Error))
+		Expect(byName["Workload"].Status().Description).To(ContainSubstring("exceeded quota"))
+		Expect(byName["Redis"].Status().Code).To(Equal(codes.Error))
+	})
+})
@@ -0,0 +1,212 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// engineVersion is the major and minor number of an engine version
+type engineVersion struct {
+	major, minor int
+}
+
+var engineVersionPattern = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?`)
+
+// parseEngineVersion reads the major and minor number a version tag such as 16,
+// 8.11.0 or 7.2-alpine starts with. Tags like latest, and numbers out of range,
+// carry no version.
+func parseEngineVersion(version string) (engineVersion, bool) {
+	match := engineVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return engineVersion{}, false
+	}
+	major, err := strconv.Atoi(match[1])
+	if err != nil {
+		return engineVersion{}, false
+	}
+	parsed := engineVersion{major: major}
+	if match[2] != "" {
+		if parsed.minor, err = strconv.Atoi(match[2]); err != nil {
+			return engineVersion{}, false
+		}
+	}
+	return parsed, true
+}
+
+var releasePattern = regexp.MustCompile(`^v?\d+(?:\.\d+)*`)
+
+// compareReleases compares the full release numbers two version tags start
+// with, such as 16.4 and 16.1-alpine, returning -1, 0 or +1. Missing numbers
+// count as zero, so 16 and 16.0 are the same release. ok is false when either
+// tag carries no release number.
+func compareReleases(a, b string) (result int, ok bool) {
+	releaseA, releaseB := releasePattern.FindString(a), releasePattern.FindString(b)
+	if releaseA == "" || releaseB == "" {
+		return 0, false
+	}
+	numbersA := strings.Split(strings.TrimPrefix(releaseA, "v"), ".")
+	numbersB := strings.Split(strings.TrimPrefix(releaseB, "v"), ".")
+	for i := range max(len(numbersA), len(numbersB)) {
+		var numberA, numberB string
+		if i < len(numbersA) {
+			numberA = strings.TrimLeft(numbersA[i], "0")
+		}
+		if i < len(numbersB) {
+			numberB = strings.TrimLeft(numbersB[i], "0")
+		}
+		// Without leading zeros a longer number is larger, so numbers of any
+		// size compare without overflowing
+		if result = cmp.Or(cmp.Compare(len(numberA), len(numberB)), strings.Compare(numberA, numberB)); result != 0 {
+			return result, true
+		}
+	}
+	return 0, true
+}
+
+func (v engineVersion) less(other engineVersion) bool {
+	return v.major < other.major || (v.major == other.major && v.minor < other.minor)
+}
+
+func (v engineVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+// upgradeRule describes the in-place upgrade paths of an engine. Versions of the
+// same release series, a major unless releases says otherwise, upgrade and
+// downgrade freely.
+type upgradeRule struct {
+	// releases lists the release series, oldest first, that an upgrade steps
+	// through one at a time. A version belongs to the last series at or below
+	// it; majors past the list are series of their own.
+	releases []engineVersion
+	// majorBridges maps a major to the oldest version of the previous major a
+	// rolling upgrade to it starts from
+	majorBridges map[int]engineVersion
+	// offlineMajor reports that the data of a new major has to be converted
+	// offline rather than by rolling the pods, see reconcileMajorUpgrade
+	offlineMajor bool
+}
+
+var upgradeMatrix = map[databasesv1alpha1.DatabaseType]upgradeRule{
+	databasesv1alpha1.DatabaseTypePostgreSQL: {
+		offlineMajor: true,
+	},
+	databasesv1alpha1.DatabaseTypeMongoDB: {
+		releases: []engineVersion{{3, 6}, {4, 0}, {4, 2}, {4, 4}, {5, 0}, {6, 0}, {7, 0}, {8, 0}},
+	},
+	databasesv1alpha1.DatabaseTypeElasticsearch: {
+		majorBridges: map[int]engineVersion{7: {6, 8}, 8: {7, 17}, 9: {8, 18}},
+	},
+}
+
+// series returns the release series of a version
+func (rule upgradeRule) series(version engineVersion) engineVersion {
+	series := engineVersion{major: version.major}
+	for _, release := range rule.releases {
+		if version.less(release) {
+			break
+		}
+		if release.major == version.major {
+			series = release
+		}
+	}
+	return series
+}
+
+// intermediates returns the versions an upgrade from one series to a later one
+// has to go through
+func (rule upgradeRule) intermediates(from, to engineVersion) []engineVersion {
+	steps := []engineVersion{}
+	for _, release := range rule.releases {
+		if rule.series(from).less(release) && release.less(rule.series(to)) {
+			steps = append(steps, release)
+		}
+	}
+	for _, major := range slices.Sorted(maps.Keys(rule.majorBridges)) {
+		bridge := rule.majorBridges[major]
+		if major > from.major && major <= to.major && (major > from.major+1 || from.less(bridge)) {
+			steps = append(steps, bridge)
+		}
+	}
+	return steps
+}
+
+// validateVersionUpgrade checks that spec.version is reachable in place from the
+// version the Database was last reconciled at, according to the upgrade matrix.
+// Downgrades are rejected, and upgrades to a new major need the approval of the
+// ApproveMajorUpgradeAnnotation.
+func validateVersionUpgrade(database *databasesv1alpha1.Database) error {
+	return validateVersionChange(database, database.Status.Version)
+}
+
+// validateVersionChange checks a change of spec.version from current
+func validateVersionChange(database *databasesv1alpha1.Database, current string) error {
+	target := database.Spec.Version
+	if current == "" || current == target {
+		return nil
+	}
+	from, ok := parseEngineVersion(current)
+	if !ok {
+		return nil
+	}
+	to, ok := parseEngineVersion(target)
+	if !ok {
+		return nil
+	}
+
+	if order, _ := compareReleases(target, current); order < 0 {
+		return fmt.Errorf("%s cannot be downgraded from %s to %s, set version back to %s",
+			database.Spec.Type, current, target, current)
+	}
+	rule := upgradeMatrix[database.Spec.Type]
+	if rule.series(from) == rule.series(to) {
+		return nil
+	}
+	if steps := rule.intermediates(from, to); len(steps) > 0 {
+		versions := make([]string, 0, len(steps))
+		for _, step := range steps {
+			versions = append(versions, step.String())
+		}
+		return fmt.Errorf("%s cannot be upgraded from %s to %s directly: upgrade to %s first",
+			database.Spec.Type, current, target, strings.Join(versions, ", then "))
+	}
+	if from.major != to.major && database.Annotations[databasesv1alpha1.ApproveMajorUpgradeAnnotation] != target {
+		return fmt.Errorf("%s %s to %s is a major upgrade, approve it by setting the %s annotation to %s",
+			database.Spec.Type, current, target, databasesv1alpha1.ApproveMajorUpgradeAnnotation, target)
+	}
+	if rule.offlineMajor && from.major != to.major && database.Spec.Storage != nil &&
+		imageFlavor(database) != databasesv1alpha1.ImageFlavorOfficial {
+		return fmt.Errorf("%s cannot be upgraded from %s to %s: pg_upgrade only converts the data of the %s image",
+			database.Spec.Type, current, target, databasesv1alpha1.ImageFlavorOfficial)
+	}
+	// The Jobs hand the new volume over to the postgres user as root
+	if rule.offlineMajor && from.major != to.major && database.Spec.Storage != nil &&
+		podSecurityLevel(database) == databasesv1alpha1.PodSecurityRestricted {
+		return fmt.Errorf("%s cannot be upgraded from %s to %s: the pg_upgrade Jobs need the %s podSecurity level",
+			database.Spec.Type, current, target, databasesv1alpha1.PodSecurityBaseline)
+	}
+	return nil
+}
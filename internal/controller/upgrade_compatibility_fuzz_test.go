@@ -0,0 +1,127 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// versionSeeds cover release tags, pre-release tags, leading zeros and
+// malformed input
+var versionSeeds = []string{
+	"16", "16.4", "8.11.0", "7.2-alpine", "v7.0.4", "latest", "",
+	"8.0.0-rc1", "16beta2", "7.0.0-alpha.1+build.5",
+	"007", "08.010", "v016.0004",
+	".", "1.", ".5", "v", "1..2", "-1", "1e3", " 16", "16 ", "١٦",
+	"99999999999999999999", "1.99999999999999999999",
+}
+
+func FuzzParseEngineVersion(f *testing.F) {
+	for _, seed := range versionSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, version string) {
+		parsed, ok := parseEngineVersion(version)
+		if !ok {
+			if parsed != (engineVersion{}) {
+				t.Fatalf("%q carries no version but parsed as %s", version, parsed)
+			}
+			return
+		}
+		if parsed.major < 0 || parsed.minor < 0 {
+			t.Fatalf("%q parsed as negative version %s", version, parsed)
+		}
+		if reparsed, ok := parseEngineVersion(parsed.String()); !ok || reparsed != parsed {
+			t.Fatalf("%q parsed as %s, which parses as %s", version, parsed, reparsed)
+		}
+		if parsed.less(parsed) {
+			t.Fatalf("%s is less than itself", parsed)
+		}
+		// Pre-release and build suffixes do not change the release
+		for _, suffix := range []string{"-rc1", "-alpine", "+build.5"} {
+			if tagged, ok := parseEngineVersion(version + suffix); !ok || tagged != parsed {
+				t.Fatalf("%q parsed as %s, but %q as %s", version, parsed, version+suffix, tagged)
+			}
+		}
+		// Leading zeros do not change the release
+		if version[0] >= '0' && version[0] <= '9' {
+			if padded, ok := parseEngineVersion("0" + version); !ok || padded != parsed {
+				t.Fatalf("%q parsed as %s, but 0%s as %s", version, parsed, version, padded)
+			}
+		}
+	})
+}
+
+func FuzzValidateVersionUpgrade(f *testing.F) {
+	for _, from := range versionSeeds {
+		f.Add(from, "8.0")
+		f.Add("4.4", from)
+	}
+	f.Add("1", "9999999999999999999")
+	f.Add("6.8", "1000000000.0")
+	f.Fuzz(func(t *testing.T, from, to string) {
+		for _, dbType := range []databasesv1alpha1.DatabaseType{
+			databasesv1alpha1.DatabaseTypePostgreSQL,
+			databasesv1alpha1.DatabaseTypeMongoDB,
+			databasesv1alpha1.DatabaseTypeRedis,
+			databasesv1alpha1.DatabaseTypeElasticsearch,
+			databasesv1alpha1.DatabaseTypeSQLite,
+		} {
+			upgrade := func(from, to string) error {
+				return validateVersionUpgrade(&databasesv1alpha1.Database{
+					Spec:   databasesv1alpha1.DatabaseSpec{Type: dbType, Version: to},
+					Status: databasesv1alpha1.DatabaseStatus{Version: from},
+				})
+			}
+			if err := upgrade(from, from); err != nil {
+				t.Fatalf("%s rejected keeping version %q: %v", dbType, from, err)
+			}
+			fromVersion, fromOK := parseEngineVersion(from)
+			toVersion, toOK := parseEngineVersion(to)
+			if !fromOK || !toOK {
+				if err := upgrade(from, to); err != nil {
+					t.Fatalf("%s rejected the unversioned change from %q to %q: %v", dbType, from, to, err)
+				}
+				continue
+			}
+			// Tags of the same release change freely, otherwise at most one
+			// direction is an upgrade, and within a release series it is
+			order, ok := compareReleases(to, from)
+			if !ok {
+				t.Fatalf("%q and %q parse but do not compare", from, to)
+			}
+			rule := upgradeMatrix[dbType]
+			forward, backward := upgrade(from, to), upgrade(to, from)
+			switch {
+			case order == 0:
+				if forward != nil || backward != nil {
+					t.Fatalf("%s rejected a change within release %q: %v, %v", dbType, from, forward, backward)
+				}
+			case forward == nil && backward == nil:
+				t.Fatalf("%s allowed both %q to %q and back", dbType, from, to)
+			case rule.series(fromVersion) == rule.series(toVersion) && (order > 0) == (forward != nil):
+				t.Fatalf("%s rejected the upgrade within series %s from %q to %q: %v, %v",
+					dbType, rule.series(fromVersion), from, to, forward, backward)
+			}
+			if forward == nil && order < 0 {
+				t.Fatalf("%s allowed the downgrade from %q to %q", dbType, from, to)
+			}
+		}
+	})
+}
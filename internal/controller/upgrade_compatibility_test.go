@@ -0,0 +1,143 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Upgrade compatibility", func() {
+	upgrade := func(dbType databasesv1alpha1.DatabaseType, from, to string) error {
+		return validateVersionUpgrade(&databasesv1alpha1.Database{
+			Spec:   databasesv1alpha1.DatabaseSpec{Type: dbType, Version: to},
+			Status: databasesv1alpha1.DatabaseStatus{Version: from},
+		})
+	}
+
+	// approved upgrades with the approval of the major of the target version
+	approved := func(dbType databasesv1alpha1.DatabaseType, from, to string) error {
+		return validateVersionUpgrade(&databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				databasesv1alpha1.ApproveMajorUpgradeAnnotation: to,
+			}},
+			Spec:   databasesv1alpha1.DatabaseSpec{Type: dbType, Version: to},
+			Status: databasesv1alpha1.DatabaseStatus{Version: from},
+		})
+	}
+
+	It("should step MongoDB through each release series", func() {
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeMongoDB, "4.2", "4.4")).To(Succeed())
+		Expect(approved(databasesv1alpha1.DatabaseTypeMongoDB, "6.0", "7.0.4")).To(Succeed())
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeMongoDB, "4.4.18", "7.0")).To(MatchError(
+			"MongoDB cannot be upgraded from 4.4.18 to 7.0 directly: upgrade to 5.0, then 6.0 first"))
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeMongoDB, "7.0", "6.0")).To(MatchError(ContainSubstring("cannot be downgraded")))
+	})
+
+	It("should roll Elasticsearch one major at a time from the last minor", func() {
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeElasticsearch, "8.11.0", "8.15.1")).To(Succeed())
+		Expect(approved(databasesv1alpha1.DatabaseTypeElasticsearch, "7.17.18", "8.11.0")).To(Succeed())
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeElasticsearch, "7.10.2", "8.11.0")).To(MatchError(
+			"Elasticsearch cannot be upgraded from 7.10.2 to 8.11.0 directly: upgrade to 7.17 first"))
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeElasticsearch, "6.5.4", "8.11.0")).To(MatchError(
+			ContainSubstring("upgrade to 6.8, then 7.17 first")))
+	})
+
+	It("should upgrade PostgreSQL majors of the Official image offline", func() {
+		Expect(upgrade(databasesv1alpha1.DatabaseTypePostgreSQL, "16.1", "16.4-alpine")).To(Succeed())
+		Expect(upgrade(databasesv1alpha1.DatabaseTypePostgreSQL, "15", "16")).To(MatchError(ContainSubstring("major upgrade")))
+		Expect(approved(databasesv1alpha1.DatabaseTypePostgreSQL, "15", "16")).To(Succeed())
+
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				databasesv1alpha1.ApproveMajorUpgradeAnnotation: "16",
+			}},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16",
+				Image:   &databasesv1alpha1.ImageSpec{Flavor: databasesv1alpha1.ImageFlavorBitnami},
+				Storage: &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+			},
+			Status: databasesv1alpha1.DatabaseStatus{Version: "15"},
+		}
+		Expect(validateVersionUpgrade(database)).To(MatchError(ContainSubstring("pg_upgrade only converts")))
+
+		database.Spec.Image = nil
+		database.Spec.PodSecurity = databasesv1alpha1.PodSecurityRestricted
+		Expect(validateVersionUpgrade(database)).To(MatchError(ContainSubstring("need the Baseline podSecurity level")))
+	})
+
+	It("should allow engines without rules and unversioned tags", func() {
+		Expect(approved(databasesv1alpha1.DatabaseTypeRedis, "6.2", "7.2")).To(Succeed())
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeSQLite, "latest", "3.45")).To(Succeed())
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeMongoDB, "", "7.0")).To(Succeed())
+	})
+
+	It("should reject downgrades within a release", func() {
+		Expect(upgrade(databasesv1alpha1.DatabaseTypePostgreSQL, "16.4", "16.1")).To(MatchError(
+			"PostgreSQL cannot be downgraded from 16.4 to 16.1, set version back to 16.4"))
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeRedis, "7.2.5", "7.2.4-alpine")).To(MatchError(ContainSubstring("cannot be downgraded")))
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeElasticsearch, "8.15.1", "8.15.10")).To(Succeed())
+		Expect(upgrade(databasesv1alpha1.DatabaseTypePostgreSQL, "16.4", "16.4.0-alpine")).To(Succeed())
+	})
+
+	It("should require an approval of the target version for a new major", func() {
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeRedis, "6.2", "7.2")).To(MatchError(
+			"Redis 6.2 to 7.2 is a major upgrade, approve it by setting the " +
+				databasesv1alpha1.ApproveMajorUpgradeAnnotation + " annotation to 7.2"))
+		Expect(upgrade(databasesv1alpha1.DatabaseTypeMongoDB, "4.2", "4.4")).To(Succeed())
+
+		// An approval of an earlier major does not carry over
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				databasesv1alpha1.ApproveMajorUpgradeAnnotation: "6.0",
+			}},
+			Spec:   databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeMongoDB, Version: "7.0"},
+			Status: databasesv1alpha1.DatabaseStatus{Version: "6.0"},
+		}
+		Expect(validateVersionUpgrade(database)).To(MatchError(ContainSubstring("major upgrade")))
+	})
+
+	It("should compare full release numbers", func() {
+		order := func(a, b string) int {
+			result, ok := compareReleases(a, b)
+			Expect(ok).To(BeTrue())
+			return result
+		}
+		for _, versions := range [][2]string{
+			{"16", "16.0"}, {"v8.11.0", "8.11"}, {"7.2-alpine", "7.2.0"}, {"08.010", "8.10"},
+		} {
+			Expect(order(versions[0], versions[1])).To(Equal(0), versions[0])
+		}
+		Expect(order("8.9.1", "8.10")).To(Equal(-1))
+		Expect(order("99999999999999999999", "9")).To(Equal(1))
+		_, ok := compareReleases("latest", "16")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should validate the version as part of the spec", func() {
+		database := &databasesv1alpha1.Database{
+			Spec:   databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeMongoDB, Version: "8.0"},
+			Status: databasesv1alpha1.DatabaseStatus{Version: "6.0"},
+		}
+		Expect((&DatabaseReconciler{}).validateSpec(database)).To(MatchError(ContainSubstring("upgrade to 7.0 first")))
+	})
+})
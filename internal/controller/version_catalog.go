@@ -0,0 +1,164 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// listDatabaseVersions returns the catalog of the engine and image flavor of a
+// Database, sorted by name. An engine without DatabaseVersions runs the images
+// of its flavor tagged with the version.
+func listDatabaseVersions(ctx context.Context, c client.Reader, database *databasesv1alpha1.Database) ([]databasesv1alpha1.DatabaseVersion, error) {
+	list := &databasesv1alpha1.DatabaseVersionList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list DatabaseVersions: %w", err)
+	}
+	flavor := imageFlavor(database)
+	entries := slices.DeleteFunc(list.Items, func(entry databasesv1alpha1.DatabaseVersion) bool {
+		entryFlavor := entry.Spec.Flavor
+		if entryFlavor == "" {
+			entryFlavor = databasesv1alpha1.ImageFlavorOfficial
+		}
+		return entry.Spec.Type != database.Spec.Type || entryFlavor != flavor
+	})
+	slices.SortFunc(entries, func(a, b databasesv1alpha1.DatabaseVersion) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return entries, nil
+}
+
+// catalogEntry returns the first entry of the catalog for a version, nil when
+// it is not listed
+func catalogEntry(entries []databasesv1alpha1.DatabaseVersion, version string) *databasesv1alpha1.DatabaseVersion {
+	for i := range entries {
+		if entries[i].Spec.Version == version {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// validateVersionCatalog checks that the catalog lists spec.version when it
+// lists the engine at all
+func validateVersionCatalog(database *databasesv1alpha1.Database, entries []databasesv1alpha1.DatabaseVersion) error {
+	if len(entries) == 0 || catalogEntry(entries, database.Spec.Version) != nil {
+		return nil
+	}
+	listed := []string{}
+	for _, entry := range entries {
+		if !slices.Contains(listed, entry.Spec.Version) {
+			listed = append(listed, entry.Spec.Version)
+		}
+	}
+	return fmt.Errorf("no DatabaseVersion lists %s %s %s (listed: %s)",
+		imageFlavor(database), database.Spec.Type, database.Spec.Version, strings.Join(listed, ", "))
+}
+
+// catalogVersions returns the versions of the catalog that are not deprecated,
+// the ones automatic patch upgrades move to
+func catalogVersions(entries []databasesv1alpha1.DatabaseVersion) []string {
+	versions := []string{}
+	for _, entry := range entries {
+		if !entry.Spec.Deprecated {
+			versions = append(versions, entry.Spec.Version)
+		}
+	}
+	return versions
+}
+
+// reconcileVersionCatalog records in status.catalogImage the catalog entry of
+// the version the workload runs, whose image engineImage returns, and warns
+// once when the version is deprecated
+func (r *DatabaseReconciler) reconcileVersionCatalog(ctx context.Context, database *databasesv1alpha1.Database) error {
+	entries, err := listDatabaseVersions(ctx, r.Client, database)
+	if err != nil {
+		return err
+	}
+	version := runningVersion(database)
+	entry := catalogEntry(entries, version)
+	if entry == nil {
+		database.Status.CatalogImage = nil
+		return nil
+	}
+
+	previous := database.Status.CatalogImage
+	database.Status.CatalogImage = &databasesv1alpha1.CatalogImageStatus{
+		DatabaseVersion: entry.Name,
+		Version:         version,
+		Image:           entry.Spec.Image,
+		Deprecated:      entry.Spec.Deprecated,
+	}
+	if entry.Spec.Deprecated && (previous == nil || !previous.Deprecated || previous.Version != version) {
+		message := fmt.Sprintf("%s %s is deprecated by DatabaseVersion %s", database.Spec.Type, version, entry.Name)
+		if entry.Spec.DeprecationMessage != "" {
+			message += ": " + entry.Spec.DeprecationMessage
+		}
+		log.FromContext(ctx).Info("Running a deprecated version", "version", version, "databaseVersion", entry.Name)
+		r.event(database, corev1.EventTypeWarning, "DeprecatedVersion", message)
+	}
+	return nil
+}
+
+// catalogImage returns the image the catalog lists for the version the
+// workload runs
+func catalogImage(database *databasesv1alpha1.Database) (string, bool) {
+	status := database.Status.CatalogImage
+	if status == nil || status.Version != runningVersion(database) {
+		return "", false
+	}
+	return status.Image, true
+}
+
+// catalogDigest returns the digest the catalog pins the image of the running
+// version to, if any
+func catalogDigest(database *databasesv1alpha1.Database) string {
+	image, _ := catalogImage(database)
+	_, digest, _ := strings.Cut(image, "@")
+	return digest
+}
+
+// databasesForVersion maps a DatabaseVersion to the Databases of its engine in
+// every namespace, so they run its image and validate their version again
+func (r *DatabaseReconciler) databasesForVersion(ctx context.Context, object client.Object) []reconcile.Request {
+	entry, ok := object.(*databasesv1alpha1.DatabaseVersion)
+	if !ok {
+		return nil
+	}
+	databases := &databasesv1alpha1.DatabaseList{}
+	if err := r.List(ctx, databases); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list the Databases of a DatabaseVersion", "databaseVersion", entry.Name)
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for i := range databases.Items {
+		if databases.Items[i].Spec.Type == entry.Spec.Type {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&databases.Items[i])})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Version catalog", func() {
+	const digest = "sha256:4b1e3c5d7f9a2b4c6d8e0f1a3b5c7d9e2f4a6b8c0d1e3f5a7b9c2d4e6f8a0b1c"
+
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *DatabaseReconciler
+		recorder   *record.FakeRecorder
+		database   *databasesv1alpha1.Database
+	)
+
+	version := func(name string, spec databasesv1alpha1.DatabaseVersionSpec) *databasesv1alpha1.DatabaseVersion {
+		return &databasesv1alpha1.DatabaseVersion{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "16.2",
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			version("postgresql-16.2", databasesv1alpha1.DatabaseVersionSpec{
+				Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16.2",
+				Image: "registry.example.com/postgres:16.2", Deprecated: true, DeprecationMessage: "move to 16.4",
+			}),
+			version("postgresql-16.3", databasesv1alpha1.DatabaseVersionSpec{
+				Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16.3",
+				Image: "registry.example.com/postgres:16.3", Deprecated: true,
+			}),
+			version("postgresql-16.4", databasesv1alpha1.DatabaseVersionSpec{
+				Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16.4",
+				Image: "registry.example.com/postgres:16.4@" + digest,
+			}),
+			version("postgresql-17.0-bitnami", databasesv1alpha1.DatabaseVersionSpec{
+				Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "17.0",
+				Flavor: databasesv1alpha1.ImageFlavorBitnami, Image: "bitnami/postgresql:17.0",
+			}),
+			version("redis-7.2", databasesv1alpha1.DatabaseVersionSpec{
+				Type: databasesv1alpha1.DatabaseTypeRedis, Version: "7.2", Image: "redis:7.2",
+			}),
+		).Build()
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &DatabaseReconciler{Client: c, Scheme: scheme, Recorder: recorder}
+	})
+
+	It("should run the image of the DatabaseVersion and warn about deprecated versions", func() {
+		Expect(reconciler.reconcileVersionCatalog(ctx, database)).To(Succeed())
+		Expect(database.Status.CatalogImage).To(Equal(&databasesv1alpha1.CatalogImageStatus{
+			DatabaseVersion: "postgresql-16.2",
+			Version:         "16.2",
+			Image:           "registry.example.com/postgres:16.2",
+			Deprecated:      true,
+		}))
+		Expect(engineImage(database)).To(Equal("registry.example.com/postgres:16.2"))
+		Expect(recorder.Events).To(Receive(Equal("Warning DeprecatedVersion PostgreSQL 16.2 is deprecated by DatabaseVersion postgresql-16.2: move to 16.4")))
+
+		By("warning only once")
+		Expect(reconciler.reconcileVersionCatalog(ctx, database)).To(Succeed())
+		Expect(recorder.Events).NotTo(Receive())
+
+		By("running a version pinned by digest as it is")
+		database.Spec.Version = "16.4"
+		Expect(engineImage(database)).To(Equal("postgres:16.4"))
+		Expect(reconciler.reconcileVersionCatalog(ctx, database)).To(Succeed())
+		Expect(engineImage(database)).To(Equal("registry.example.com/postgres:16.4@" + digest))
+		Expect(engineImageTag(database)).To(Equal("registry.example.com/postgres:16.4"))
+		Expect(recorder.Events).NotTo(Receive())
+
+		By("falling back to the image of the flavor for versions it does not list")
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		database.Spec.Version = "7.0"
+		Expect(reconciler.reconcileVersionCatalog(ctx, database)).To(Succeed())
+		Expect(database.Status.CatalogImage).To(BeNil())
+		Expect(engineImage(database)).To(Equal("mongo:7.0"))
+	})
+
+	It("should take the digest of the DatabaseVersion without resolving the tag", func() {
+		resolver := &staticResolver{digest: "sha256:other"}
+		reconciler.ImageResolver = resolver
+		database.Spec.Version = "16.4"
+		database.Spec.ImageResolution = databasesv1alpha1.ImageResolutionDigest
+		Expect(reconciler.reconcileVersionCatalog(ctx, database)).To(Succeed())
+		Expect(reconciler.reconcileImageResolution(ctx, database)).To(Succeed())
+		Expect(resolver.images).To(BeEmpty())
+		Expect(database.Status.Image).To(And(
+			HaveField("Tag", "registry.example.com/postgres:16.4"),
+			HaveField("Digest", digest),
+		))
+		Expect(engineImage(database)).To(Equal("registry.example.com/postgres:16.4@" + digest))
+
+		By("resolving the tag of a DatabaseVersion that does not pin a digest")
+		database.Spec.Version = "16.3"
+		Expect(reconciler.reconcileVersionCatalog(ctx, database)).To(Succeed())
+		Expect(reconciler.reconcileImageResolution(ctx, database)).To(Succeed())
+		Expect(resolver.images).To(Equal([]string{"registry.example.com/postgres:16.3"}))
+		Expect(engineImage(database)).To(Equal("registry.example.com/postgres:16.3@sha256:other"))
+	})
+
+	It("should only accept the versions the catalog of the engine and flavor lists", func() {
+		versions, err := listDatabaseVersions(ctx, c, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(validateVersionCatalog(database, versions)).To(Succeed())
+
+		database.Spec.Version = "17.0"
+		Expect(validateVersionCatalog(database, versions)).To(MatchError(
+			"no DatabaseVersion lists Official PostgreSQL 17.0 (listed: 16.2, 16.3, 16.4)"))
+
+		By("accepting the version in the catalog of its flavor")
+		database.Spec.Image = &databasesv1alpha1.ImageSpec{Flavor: databasesv1alpha1.ImageFlavorBitnami}
+		versions, err = listDatabaseVersions(ctx, c, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(validateVersionCatalog(database, versions)).To(Succeed())
+
+		By("accepting any version of an engine without catalog")
+		database.Spec.Type = databasesv1alpha1.DatabaseTypeMongoDB
+		versions, err = listDatabaseVersions(ctx, c, database)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(BeEmpty())
+		Expect(validateVersionCatalog(database, versions)).To(Succeed())
+	})
+
+	It("should upgrade to the latest patch release the catalog does not deprecate", func() {
+		now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		catalog := &staticCatalog{versions: []string{"16.9"}}
+		reconciler.VersionCatalog = catalog
+		database.Spec.Version = "16.1"
+		database.Spec.Maintenance = &databasesv1alpha1.MaintenanceSpec{AutoUpgrade: true}
+		Expect(reconciler.reconcileAutoUpgrade(ctx, database, now)).To(Succeed())
+		Expect(catalog.calls).To(BeZero())
+		Expect(database.Status.AutoUpgrade.LatestVersion).To(Equal("16.4"))
+	})
+})
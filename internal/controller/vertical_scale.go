@@ -0,0 +1,241 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// verticalScaleInterval is how often a vertical scale checks the replica
+	// being restarted and the switchover
+	verticalScaleInterval = 15 * time.Second
+	// switchoverTimeout is how long the primary role has to move before the
+	// primary is restarted anyway
+	switchoverTimeout = 2 * time.Minute
+	// roleCheckTimeout bounds the query of the role of a replica
+	roleCheckTimeout = 5 * time.Second
+)
+
+// primaryCheckers report whether the replica answering on conn.host is the
+// primary. Engines without one restart the replica with the lowest ordinal
+// last, the one the others replicate from when they start.
+var primaryCheckers = map[databasesv1alpha1.DatabaseType]func(ctx context.Context, conn adminConnection) (bool, error){
+	databasesv1alpha1.DatabaseTypePostgreSQL: func(ctx context.Context, conn adminConnection) (bool, error) {
+		rows, err := postgresQuery(ctx, conn,
+			"SELECT pg_is_in_recovery() AS in_recovery")
+		if err != nil {
+			return false, err
+		}
+		if len(rows) != 1 {
+			return false, fmt.Errorf("unexpected result checking the role")
+		}
+		return rows[0]["in_recovery"] == "f", nil
+	},
+	databasesv1alpha1.DatabaseTypeRedis: func(ctx context.Context, conn adminConnection) (bool, error) {
+		info, err := redisInfo(ctx, conn)
+		if err != nil {
+			return false, err
+		}
+		return info["role"] == "master", nil
+	},
+}
+
+// switchovers hand the primary role of the replica answering on conn.host over
+// to another replica. Engines without one restart the primary without it. None
+// has one yet, as no engine replicates from a primary: Redis replicas are
+// standalone servers, leaving FAILOVER no replica to hand the role over to.
+var switchovers = map[databasesv1alpha1.DatabaseType]func(ctx context.Context, conn adminConnection) error{}
+
+// resourcesOnlyChange reports whether a generated pod template only changes
+// the resources of the containers of the current one
+func resourcesOnlyChange(current, desired *corev1.PodTemplateSpec) bool {
+	unchanged := desired.DeepCopy()
+	for i := range unchanged.Spec.Containers {
+		for _, container := range current.Spec.Containers {
+			if container.Name == unchanged.Spec.Containers[i].Name {
+				unchanged.Spec.Containers[i].Resources = container.Resources
+			}
+		}
+	}
+	return equality.Semantic.DeepDerivative(*unchanged, *current)
+}
+
+// startVerticalScale switches a StatefulSet about to get new resources to the
+// OnDelete strategy, so the replicas are only restarted by the vertical scale,
+// and records it in status
+func startVerticalScale(database *databasesv1alpha1.Database, statefulSet *appsv1.StatefulSet, now time.Time) {
+	statefulSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}
+	database.Status.VerticalScale = &databasesv1alpha1.VerticalScaleStatus{
+		StatefulSet: statefulSet.Name,
+		Message:     "Applying the new resources",
+		StartedAt:   metav1.NewTime(now),
+	}
+}
+
+// reconcileVerticalScale restarts the replicas of a StatefulSet with new
+// resources one at a time: secondaries first, from the highest ordinal, each
+// once the previous one is ready again, then the primary after the engine
+// handed its role over to a restarted replica. It reports whether the vertical
+// scale still runs, while the pod templates of every StatefulSet are left as
+// they are.
+func (r *DatabaseReconciler) reconcileVerticalScale(ctx context.Context, database *databasesv1alpha1.Database, statefulSet *appsv1.StatefulSet, now time.Time) (bool, error) {
+	scale := database.Status.VerticalScale
+	if scale == nil {
+		return false, nil
+	}
+	if scale.StatefulSet != statefulSet.Name {
+		// Another Elasticsearch node set is being scaled
+		return true, nil
+	}
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation || statefulSet.Status.UpdateRevision == "" {
+		return true, nil
+	}
+	scale.Revision = statefulSet.Status.UpdateRevision
+
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	pods := make([]*corev1.Pod, replicas)
+	var outdated []*corev1.Pod
+	for ordinal := range pods {
+		name := fmt.Sprintf("%s-%d", statefulSet.Name, ordinal)
+		pod := &corev1.Pod{}
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, pod)
+		if apierrors.IsNotFound(err) || (err == nil && !podReady(pod)) {
+			scale.Message = fmt.Sprintf("Waiting for %s to become ready", name)
+			return true, nil
+		} else if err != nil {
+			return true, err
+		}
+		pods[ordinal] = pod
+		if pod.Labels[appsv1.ControllerRevisionHashLabelKey] != scale.Revision {
+			outdated = append(outdated, pod)
+		}
+	}
+
+	if len(outdated) == 0 {
+		return false, r.completeVerticalScale(ctx, database, statefulSet, now)
+	}
+
+	primary := r.primaryReplica(ctx, database, pods)
+	scale.Primary = primary.Name
+	next := outdated[len(outdated)-1]
+	if next.Name == primary.Name && len(outdated) > 1 {
+		next = outdated[len(outdated)-2]
+	}
+
+	if next.Name == primary.Name && replicas > 1 {
+		switchover, supported := switchovers[database.Spec.Type]
+		switch {
+		case !supported:
+		case scale.SwitchoverAt == nil:
+			log.FromContext(ctx).Info("Handing the primary role over before restarting the primary", "pod", primary.Name)
+			conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+			if err != nil {
+				return true, err
+			}
+			conn.host = primary.Status.PodIP
+			switchoverCtx, cancel := context.WithTimeout(ctx, roleCheckTimeout)
+			err = switchover(switchoverCtx, conn)
+			cancel()
+			if err != nil {
+				log.FromContext(ctx).Error(err, "Switchover failed", "pod", primary.Name)
+			}
+			switchoverAt := metav1.NewTime(now)
+			scale.SwitchoverAt = &switchoverAt
+			scale.Message = fmt.Sprintf("Handing the primary role of %s over to another replica", primary.Name)
+			return true, nil
+		case now.Sub(scale.SwitchoverAt.Time) < switchoverTimeout:
+			scale.Message = fmt.Sprintf("Waiting for %s to hand the primary role over", primary.Name)
+			return true, nil
+		default:
+			log.FromContext(ctx).Info("The primary role did not move, restarting the primary", "pod", primary.Name)
+		}
+	}
+
+	log.FromContext(ctx).Info("Restarting replica with the new resources", "pod", next.Name)
+	if err := r.Delete(ctx, next); err != nil && !apierrors.IsNotFound(err) {
+		return true, err
+	}
+	scale.Pod = next.Name
+	scale.Message = fmt.Sprintf("Restarting %s (%d of %d replicas left)", next.Name, len(outdated), replicas)
+	return true, nil
+}
+
+// primaryReplica returns the replica the engine reports as the primary, the
+// one with the lowest ordinal when it cannot tell
+func (r *DatabaseReconciler) primaryReplica(ctx context.Context, database *databasesv1alpha1.Database, pods []*corev1.Pod) *corev1.Pod {
+	check, ok := primaryCheckers[database.Spec.Type]
+	if !ok {
+		return pods[0]
+	}
+	conn, err := resolveAdminConnection(ctx, r.APIReader, r.HTTPClient, database)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to resolve the admin connection to find the primary")
+		return pods[0]
+	}
+	for _, pod := range pods {
+		podConn := conn
+		podConn.host = pod.Status.PodIP
+		checkCtx, cancel := context.WithTimeout(ctx, roleCheckTimeout)
+		primary, err := check(checkCtx, podConn)
+		cancel()
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Failed to check the role of the replica", "pod", pod.Name)
+			continue
+		}
+		if primary {
+			return pod
+		}
+	}
+	return pods[0]
+}
+
+// completeVerticalScale puts the StatefulSet back on rolling updates once every
+// replica runs the new resources, and releases the lock
+func (r *DatabaseReconciler) completeVerticalScale(ctx context.Context, database *databasesv1alpha1.Database, statefulSet *appsv1.StatefulSet, now time.Time) error {
+	if statefulSet.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		before := *statefulSet.Spec.DeepCopy()
+		statefulSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType}
+		restampAppliedSpec(statefulSet, before)
+		if err := r.Update(ctx, statefulSet); err != nil {
+			return err
+		}
+	}
+	log.FromContext(ctx).Info("Every replica runs the new resources", "statefulSet", statefulSet.Name)
+	if activeOperation(database) == disruptiveOperationVerticalScale {
+		recordOperation(database, recordVerticalScale, databasesv1alpha1.OperationSucceeded,
+			fmt.Sprintf("Restarted the replicas of %s with the new resources", statefulSet.Name), now)
+	}
+	releaseOperation(database, disruptiveOperationVerticalScale)
+	database.Status.VerticalScale = nil
+	return nil
+}
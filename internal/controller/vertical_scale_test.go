@@ -0,0 +1,169 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Vertical scaling", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		c          client.Client
+		primaryIP  string
+	)
+
+	key := types.NamespacedName{Name: "cache", Namespace: "shop"}
+
+	// runPod creates a ready replica on a revision, the IP of a replica being
+	// 10.0.0.<ordinal>
+	runPod := func(ordinal int, revision string) {
+		Expect(c.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("cache-%d", ordinal),
+				Namespace: "shop",
+				Labels:    map[string]string{appsv1.ControllerRevisionHashLabelKey: revision},
+			},
+			Status: corev1.PodStatus{
+				PodIP:      fmt.Sprintf("10.0.0.%d", ordinal),
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop", UID: "cache-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:      databasesv1alpha1.DatabaseTypeRedis,
+				Version:   "7.2",
+				Replicas:  ptr.To(int32(3)),
+				Resources: &databasesv1alpha1.ResourceRequirements{Memory: "1Gi"},
+			},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Scheme: scheme}
+		statefulSet := reconciler.createRedisStatefulSet(database, 3, reconciler.getRedisEnv(database))
+		Expect(controllerutil.SetControllerReference(database, statefulSet, scheme)).To(Succeed())
+		statefulSet.Status = appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 3, CurrentRevision: "cache-old", UpdateRevision: "cache-old"}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(statefulSet).
+			WithStatusSubresource(statefulSet).WithInterceptorFuncs(applyPatches()).Build()
+		reconciler.Client = c
+		for ordinal := range 3 {
+			runPod(ordinal, "cache-old")
+		}
+
+		primaryIP = "10.0.0.0"
+		originalChecker := primaryCheckers[databasesv1alpha1.DatabaseTypeRedis]
+		primaryCheckers[databasesv1alpha1.DatabaseTypeRedis] = func(_ context.Context, conn adminConnection) (bool, error) {
+			return conn.host == primaryIP, nil
+		}
+		DeferCleanup(func() {
+			primaryCheckers[databasesv1alpha1.DatabaseTypeRedis] = originalChecker
+		})
+	})
+
+	current := func() *appsv1.StatefulSet {
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(c.Get(ctx, key, statefulSet)).To(Succeed())
+		return statefulSet
+	}
+
+	// restarted reports whether a replica was deleted, and recreates it on the
+	// new revision as the StatefulSet controller does
+	restarted := func(ordinal int) bool {
+		err := c.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("cache-%d", ordinal), Namespace: "shop"}, &corev1.Pod{})
+		if !apierrors.IsNotFound(err) {
+			return false
+		}
+		runPod(ordinal, "cache-new")
+		return true
+	}
+
+	It("should restart the secondaries first and the primary last", func() {
+		database.Spec.Resources.Memory = "2Gi"
+		Expect(reconciler.reconcileRedis(ctx, database)).To(Succeed())
+		Expect(activeOperation(database)).To(Equal(disruptiveOperationVerticalScale))
+		Expect(current().Spec.UpdateStrategy.Type).To(Equal(appsv1.OnDeleteStatefulSetStrategyType))
+		Expect(current().Spec.Template.Spec.Containers[0].Resources.Requests.Memory().String()).To(Equal("2Gi"))
+
+		statefulSet := current()
+		statefulSet.Status.ObservedGeneration = statefulSet.Generation
+		statefulSet.Status.UpdateRevision = "cache-new"
+		Expect(c.Status().Update(ctx, statefulSet)).To(Succeed())
+
+		By("restarting the secondaries from the highest ordinal")
+		Expect(reconciler.reconcileRedis(ctx, database)).To(Succeed())
+		Expect(database.Status.VerticalScale.Primary).To(Equal("cache-0"))
+		Expect(restarted(2)).To(BeTrue())
+		Expect(reconciler.reconcileRedis(ctx, database)).To(Succeed())
+		Expect(restarted(1)).To(BeTrue())
+
+		By("restarting the primary without a switchover, Redis replicas being standalone servers")
+		Expect(reconciler.reconcileRedis(ctx, database)).To(Succeed())
+		Expect(database.Status.VerticalScale.SwitchoverAt).To(BeNil())
+		Expect(database.Status.VerticalScale.Primary).To(Equal("cache-0"))
+		Expect(restarted(0)).To(BeTrue())
+
+		By("completing once every replica runs the new resources")
+		Expect(reconciler.reconcileRedis(ctx, database)).To(Succeed())
+		Expect(database.Status.VerticalScale).To(BeNil())
+		Expect(database.Status.Operations).To(BeNil())
+		Expect(database.Status.RecentOperations).To(ConsistOf(HaveField("Type", recordVerticalScale)))
+		Expect(current().Spec.UpdateStrategy.Type).To(Equal(appsv1.RollingUpdateStatefulSetStrategyType))
+	})
+
+	It("should defer the restarts to the maintenance window", func() {
+		// A window three days from now is closed
+		day := databasesv1alpha1.Weekday(time.Now().UTC().AddDate(0, 0, 3).Weekday().String()[:3])
+		database.Spec.Maintenance = &databasesv1alpha1.MaintenanceSpec{
+			Windows: []databasesv1alpha1.MaintenanceWindow{{Days: []databasesv1alpha1.Weekday{day}, Start: "02:00", End: "04:00"}},
+		}
+		database.Status.ReadyReplicas = 3
+
+		database.Spec.Resources.Memory = "2Gi"
+		Expect(reconciler.reconcileRedis(ctx, database)).To(Succeed())
+		Expect(database.Status.Maintenance.Deferred).To(ConsistOf(disruptiveOperationVerticalScale))
+		Expect(database.Status.VerticalScale).To(BeNil())
+		Expect(current().Spec.Template.Spec.Containers[0].Resources.Requests.Memory().String()).To(Equal("1Gi"))
+	})
+})
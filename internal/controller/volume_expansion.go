@@ -0,0 +1,272 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+
+const (
+	conditionStorageResizing = "StorageResizing"
+
+	reasonVolumeExpanding             = "VolumeExpanding"
+	reasonVolumeExpanded              = "VolumeExpanded"
+	reasonVolumeExpansionNotSupported = "VolumeExpansionNotSupported"
+
+	// volumeExpansionInterval is how often the claims being resized are checked
+	volumeExpansionInterval = 30 * time.Second
+)
+
+// dataVolumeSet is a workload with the data volumes it claims and the storage
+// they are requested with
+type dataVolumeSet struct {
+	workload string
+	storage  *databasesv1alpha1.StorageSpec
+}
+
+// dataVolumeSets lists the workloads with data volumes: one per Elasticsearch
+// node set, with the storage of the node set, otherwise the one named after
+// the Database
+func dataVolumeSets(database *databasesv1alpha1.Database) []dataVolumeSet {
+	nodeSets := elasticsearchNodeSets(database)
+	if len(nodeSets) == 0 {
+		if database.Spec.Storage == nil {
+			return nil
+		}
+		storage := *database.Spec.Storage
+		storage.Size = dataVolumeSize(database)
+		return []dataVolumeSet{{workload: database.Name, storage: &storage}}
+	}
+	var sets []dataVolumeSet
+	for _, nodeSet := range nodeSets {
+		storage := database.Spec.Storage
+		if nodeSet.Storage != nil {
+			storage = nodeSet.Storage
+		}
+		if storage != nil {
+			sets = append(sets, dataVolumeSet{workload: nodeSetName(database, nodeSet), storage: storage})
+		}
+	}
+	return sets
+}
+
+// dataVolumeSize returns the size of the data volumes outside Elasticsearch
+// node sets: storage.size, or the larger size diskPressure.autoExpand grew
+// them to
+func dataVolumeSize(database *databasesv1alpha1.Database) string {
+	size := database.Spec.Storage.Size
+	usage := database.Status.DiskUsage
+	if usage == nil || usage.ExpandedSize == "" {
+		return size
+	}
+	requested, err := resource.ParseQuantity(size)
+	if err != nil {
+		return size
+	}
+	expanded, err := resource.ParseQuantity(usage.ExpandedSize)
+	if err != nil || expanded.Cmp(requested) <= 0 {
+		return size
+	}
+	return usage.ExpandedSize
+}
+
+// reconcileVolumeExpansion grows the data volumes to spec.storage.size, or
+// the size diskPressure.autoExpand grew them to. The
+// requests of the claims are raised when their storage class allows
+// expansion; volumes cannot shrink, so smaller sizes are ignored. The volume
+// claim templates of a StatefulSet cannot change, so once its claims are
+// expanded the StatefulSet is deleted without its pods and recreated by the
+// provisioning with the new size. The pods keep running meanwhile.
+// StorageResizing reports the resize until the file systems are grown.
+func (r *DatabaseReconciler) reconcileVolumeExpansion(ctx context.Context, database *databasesv1alpha1.Database) error {
+	// The data volumes are being replaced
+	if workloadStopped(database) {
+		return nil
+	}
+
+	var resizing, unsupported []string
+	for _, set := range dataVolumeSets(database) {
+		size, err := resource.ParseQuantity(set.storage.Size)
+		if err != nil {
+			return fmt.Errorf("invalid storage size %q: %w", set.storage.Size, err)
+		}
+
+		var statefulSet *appsv1.StatefulSet
+		claims := []string{database.Name + "-data"}
+		if database.Spec.Type != databasesv1alpha1.DatabaseTypeSQLite {
+			statefulSet = &appsv1.StatefulSet{}
+			err := r.Get(ctx, types.NamespacedName{Name: set.workload, Namespace: database.Namespace}, statefulSet)
+			if apierrors.IsNotFound(err) {
+				// Created with the new size
+				continue
+			} else if err != nil {
+				return err
+			}
+			if !statefulSet.DeletionTimestamp.IsZero() {
+				resizing = append(resizing, set.workload)
+				continue
+			}
+			claims = statefulSetClaimNames(statefulSet)
+		}
+
+		expanded := true
+		for _, name := range claims {
+			claim := &corev1.PersistentVolumeClaim{}
+			err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, claim)
+			if apierrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			requested := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+			if requested.Cmp(size) < 0 {
+				allowed, err := r.volumeExpansionAllowed(ctx, claim)
+				if err != nil {
+					return err
+				}
+				if !allowed {
+					unsupported = append(unsupported, name)
+					expanded = false
+					continue
+				}
+				log.FromContext(ctx).Info("Expanding data volume", "claim", name, "from", requested.String(), "to", size.String())
+				patch := client.MergeFrom(claim.DeepCopy())
+				if claim.Spec.Resources.Requests == nil {
+					claim.Spec.Resources.Requests = corev1.ResourceList{}
+				}
+				claim.Spec.Resources.Requests[corev1.ResourceStorage] = size
+				if err := r.Patch(ctx, claim, patch); err != nil {
+					return err
+				}
+			}
+			if claimResizing(claim) {
+				resizing = append(resizing, name)
+			}
+		}
+
+		if statefulSet != nil && expanded && claimTemplateSmaller(statefulSet, size) {
+			log.FromContext(ctx).Info("Recreating StatefulSet with the new volume size", "name", statefulSet.Name, "size", size.String())
+			if err := r.Delete(ctx, statefulSet, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			resizing = append(resizing, set.workload)
+		}
+	}
+
+	r.reportVolumeExpansion(database, resizing, unsupported)
+	return nil
+}
+
+// statefulSetClaimNames lists the data volumes claimed by the replicas of a StatefulSet
+func statefulSetClaimNames(statefulSet *appsv1.StatefulSet) []string {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	claims := make([]string, replicas)
+	for i := range claims {
+		claims[i] = "data-" + statefulSet.Name + "-" + strconv.Itoa(i)
+	}
+	return claims
+}
+
+// claimTemplateSmaller reports whether the data volume claim template of a
+// StatefulSet requests less than size
+func claimTemplateSmaller(statefulSet *appsv1.StatefulSet, size resource.Quantity) bool {
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		if template.Name == "data" {
+			requested := template.Spec.Resources.Requests[corev1.ResourceStorage]
+			return requested.Cmp(size) < 0
+		}
+	}
+	return false
+}
+
+// claimResizing reports whether the volume of a claim is smaller than its
+// request, or its file system waits for the pod to be restarted to grow
+func claimResizing(claim *corev1.PersistentVolumeClaim) bool {
+	for _, condition := range claim.Status.Conditions {
+		if condition.Status == corev1.ConditionTrue &&
+			(condition.Type == corev1.PersistentVolumeClaimResizing || condition.Type == corev1.PersistentVolumeClaimFileSystemResizePending) {
+			return true
+		}
+	}
+	capacity, found := claim.Status.Capacity[corev1.ResourceStorage]
+	requested := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	return found && capacity.Cmp(requested) < 0
+}
+
+// volumeExpansionAllowed reports whether the storage class of a claim allows
+// its volume to be expanded. Statically bound claims without class cannot be.
+func (r *DatabaseReconciler) volumeExpansionAllowed(ctx context.Context, claim *corev1.PersistentVolumeClaim) (bool, error) {
+	if claim.Spec.StorageClassName == nil || *claim.Spec.StorageClassName == "" {
+		return false, nil
+	}
+	class := &storagev1.StorageClass{}
+	if err := r.Get(ctx, types.NamespacedName{Name: *claim.Spec.StorageClassName}, class); apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return class.AllowVolumeExpansion != nil && *class.AllowVolumeExpansion, nil
+}
+
+// reportVolumeExpansion sets the StorageResizing condition: True while volumes
+// are being expanded, False once they are or when their storage class does
+// not allow it. Databases whose volumes were never resized have no condition.
+func (r *DatabaseReconciler) reportVolumeExpansion(database *databasesv1alpha1.Database, resizing, unsupported []string) {
+	previous := meta.FindStatusCondition(database.Status.Conditions, conditionStorageResizing)
+	switch {
+	case len(unsupported) > 0:
+		message := fmt.Sprintf("The storage class of %v does not allow volume expansion, the volumes keep their size", unsupported)
+		if previous == nil || previous.Reason != reasonVolumeExpansionNotSupported {
+			r.event(database, corev1.EventTypeWarning, reasonVolumeExpansionNotSupported, message)
+		}
+		setCondition(database, conditionStorageResizing, metav1.ConditionFalse, reasonVolumeExpansionNotSupported, message)
+	case len(resizing) > 0:
+		if previous == nil || previous.Status != metav1.ConditionTrue {
+			r.event(database, corev1.EventTypeNormal, reasonVolumeExpanding, "Expanding the data volumes to the requested size")
+		}
+		setCondition(database, conditionStorageResizing, metav1.ConditionTrue, reasonVolumeExpanding,
+			fmt.Sprintf("Waiting for %v to be resized", resizing))
+	case previous != nil && previous.Reason == reasonVolumeExpanding:
+		r.event(database, corev1.EventTypeNormal, reasonVolumeExpanded, "The data volumes are expanded")
+		setCondition(database, conditionStorageResizing, metav1.ConditionFalse, reasonVolumeExpanded, "The data volumes have the requested size")
+	case previous != nil && previous.Reason == reasonVolumeExpansionNotSupported:
+		// The size was set back
+		meta.RemoveStatusCondition(&database.Status.Conditions, conditionStorageResizing)
+	}
+}
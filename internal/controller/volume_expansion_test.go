@@ -0,0 +1,149 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Volume expansion", func() {
+	var (
+		ctx         context.Context
+		database    *databasesv1alpha1.Database
+		statefulSet *appsv1.StatefulSet
+		reconciler  *DatabaseReconciler
+		recorder    *record.FakeRecorder
+	)
+
+	dataClaim := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: ptr.To("standard"),
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{
+				Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			},
+		}
+	}
+
+	build := func(allowExpansion bool) {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:  "16.4",
+				Replicas: ptr.To(int32(2)),
+				Storage:  &databasesv1alpha1.StorageSpec{Size: "10Gi", StorageClass: ptr.To("standard")},
+			},
+		}
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		recorder = record.NewFakeRecorder(10)
+		reconciler = &DatabaseReconciler{Scheme: scheme, Recorder: recorder}
+		statefulSet = reconciler.createPostgreSQLStatefulSet(database, 2, nil)
+		database.Spec.Storage.Size = "20Gi"
+		class := &storagev1.StorageClass{
+			ObjectMeta:           metav1.ObjectMeta{Name: "standard"},
+			Provisioner:          "ebs.csi.aws.com",
+			AllowVolumeExpansion: ptr.To(allowExpansion),
+		}
+		reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(database, statefulSet, class, dataClaim("data-orders-0"), dataClaim("data-orders-1")).Build()
+	}
+
+	claimRequest := func(name string) string {
+		claim := &corev1.PersistentVolumeClaim{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: name, Namespace: "shop"}, claim)).To(Succeed())
+		return ptr.To(claim.Spec.Resources.Requests[corev1.ResourceStorage]).String()
+	}
+
+	It("should expand the claims and recreate the StatefulSet with the new size", func() {
+		build(true)
+		Expect(reconciler.reconcileVolumeExpansion(ctx, database)).To(Succeed())
+		Expect(claimRequest("data-orders-0")).To(Equal("20Gi"))
+		Expect(claimRequest("data-orders-1")).To(Equal("20Gi"))
+		err := reconciler.Get(ctx, client.ObjectKeyFromObject(statefulSet), &appsv1.StatefulSet{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(database.Status.Conditions, conditionStorageResizing)).To(BeTrue())
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal VolumeExpanding")))
+
+		By("waiting for the volumes to grow")
+		Expect(reconciler.Create(ctx, reconciler.createPostgreSQLStatefulSet(database, 2, nil))).To(Succeed())
+		claim := &corev1.PersistentVolumeClaim{}
+		Expect(reconciler.Get(ctx, types.NamespacedName{Name: "data-orders-0", Namespace: "shop"}, claim)).To(Succeed())
+		claim.Status.Conditions = []corev1.PersistentVolumeClaimCondition{
+			{Type: corev1.PersistentVolumeClaimFileSystemResizePending, Status: corev1.ConditionTrue},
+		}
+		Expect(reconciler.Status().Update(ctx, claim)).To(Succeed())
+		Expect(reconciler.reconcileVolumeExpansion(ctx, database)).To(Succeed())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionStorageResizing).Message).
+			To(ContainSubstring("data-orders-0 data-orders-1"))
+
+		By("reporting the expansion once the file systems are grown")
+		for _, name := range []string{"data-orders-0", "data-orders-1"} {
+			Expect(reconciler.Get(ctx, types.NamespacedName{Name: name, Namespace: "shop"}, claim)).To(Succeed())
+			claim.Status.Conditions = nil
+			claim.Status.Capacity[corev1.ResourceStorage] = resource.MustParse("20Gi")
+			Expect(reconciler.Status().Update(ctx, claim)).To(Succeed())
+		}
+		Expect(reconciler.reconcileVolumeExpansion(ctx, database)).To(Succeed())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionStorageResizing)).
+			To(HaveField("Reason", reasonVolumeExpanded))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal VolumeExpanded")))
+	})
+
+	It("should keep the volumes when their storage class does not allow expansion", func() {
+		build(false)
+		Expect(reconciler.reconcileVolumeExpansion(ctx, database)).To(Succeed())
+		Expect(claimRequest("data-orders-0")).To(Equal("10Gi"))
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(statefulSet), &appsv1.StatefulSet{})).To(Succeed())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionStorageResizing)).
+			To(HaveField("Reason", reasonVolumeExpansionNotSupported))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning VolumeExpansionNotSupported")))
+
+		By("dropping the condition once the size is set back")
+		database.Spec.Storage.Size = "10Gi"
+		Expect(reconciler.reconcileVolumeExpansion(ctx, database)).To(Succeed())
+		Expect(meta.FindStatusCondition(database.Status.Conditions, conditionStorageResizing)).To(BeNil())
+	})
+})
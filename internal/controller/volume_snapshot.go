@@ -0,0 +1,469 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	volumeSnapshotScheme = "volumesnapshot://"
+
+	// restoredByAnnotation records on a data volume the UID of the restore that
+	// provisioned it from a snapshot
+	restoredByAnnotation = "databases.database-operator.io/restored-by"
+
+	// snapshotRecheckInterval is how often snapshots and volumes being provisioned are checked
+	snapshotRecheckInterval = 15 * time.Second
+)
+
+// volumeSnapshotGVK is the CSI VolumeSnapshot kind. The external-snapshotter
+// CRDs are optional, so snapshots are handled as unstructured objects.
+var volumeSnapshotGVK = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot"}
+
+// +kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=databases.database-operator.io,resources=databasebackups,verbs=create
+
+// validateSnapshotBackups checks that the data volumes can be snapshotted
+func validateSnapshotBackups(database *databasesv1alpha1.Database) error {
+	if database.Spec.Storage == nil || !database.Spec.Storage.Snapshots {
+		return errors.New("Snapshot backups require spec.storage.snapshots")
+	}
+	return nil
+}
+
+// dataClaimNames lists the data volumes of the Database in replica order
+func dataClaimNames(database *databasesv1alpha1.Database) []string {
+	if database.Spec.Type == databasesv1alpha1.DatabaseTypeSQLite {
+		return []string{database.Name + "-data"}
+	}
+	if nodeSets := elasticsearchNodeSets(database); len(nodeSets) > 0 {
+		var claims []string
+		for _, nodeSet := range nodeSets {
+			for _, node := range nodeNames(nodeSetName(database, nodeSet), 0, nodeSet.Replicas) {
+				claims = append(claims, "data-"+node)
+			}
+		}
+		return claims
+	}
+
+	// The volumes of a hibernated Database are kept for the replicas it wakes up with
+	replicas := awakeReplicas(database)
+	claims := make([]string, 0, replicas)
+	for i := range replicas {
+		claims = append(claims, fmt.Sprintf("data-%s-%d", database.Name, i))
+	}
+	return claims
+}
+
+// newVolumeSnapshot builds the VolumeSnapshot of a data volume
+func (r *DatabaseReconciler) newVolumeSnapshot(database *databasesv1alpha1.Database, name, claim string) *unstructured.Unstructured {
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	snapshot.SetName(name)
+	snapshot.SetNamespace(database.Namespace)
+	snapshot.SetLabels(r.getComponentLabels(database, backupComponent))
+
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{"persistentVolumeClaimName": claim},
+	}
+	if class := database.Spec.Storage.SnapshotClassName; class != nil {
+		spec["volumeSnapshotClassName"] = *class
+	}
+	snapshot.Object["spec"] = spec
+	return snapshot
+}
+
+// volumeSnapshotState reads whether a VolumeSnapshot is ready, its restore size
+// and the error reported by the snapshot controller
+func volumeSnapshotState(snapshot *unstructured.Unstructured) (ready bool, size *resource.Quantity, failure string) {
+	ready, _, _ = unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	failure, _, _ = unstructured.NestedString(snapshot.Object, "status", "error", "message")
+	if restoreSize, found, _ := unstructured.NestedString(snapshot.Object, "status", "restoreSize"); found {
+		if quantity, err := resource.ParseQuantity(restoreSize); err == nil {
+			size = &quantity
+		}
+	}
+	return ready, size, failure
+}
+
+// reconcileSnapshotBackup takes a VolumeSnapshot of every data volume and
+// completes the backup once all of them are ready to use
+func (r *DatabaseBackupReconciler) reconcileSnapshotBackup(ctx context.Context, database *databasesv1alpha1.Database, backup *databasesv1alpha1.DatabaseBackup) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if err := validateSnapshotBackups(database); err != nil {
+		backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseFailed
+		backup.Status.Message = err.Error()
+		return ctrl.Result{}, nil
+	}
+
+	if backup.Status.StartTime == nil {
+		now := metav1.Now()
+		backup.Status.StartTime = &now
+	}
+	backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseRunning
+
+	names := []string{}
+	total := resource.NewQuantity(0, resource.BinarySI)
+	ready := 0
+	for i, claim := range dataClaimNames(database) {
+		name := fmt.Sprintf("%s-%d", backup.Name, i)
+		names = append(names, name)
+
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: backup.Namespace}, snapshot)
+		if apierrors.IsNotFound(err) {
+			snapshot = r.databaseReconciler(r.Client, r.Scheme).newVolumeSnapshot(database, name, claim)
+			if err := controllerutil.SetControllerReference(backup, snapshot, r.Scheme); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			log.Info("Creating VolumeSnapshot", "name", name, "claim", claim)
+			err = r.Create(ctx, snapshot)
+		}
+		if meta.IsNoMatchError(err) {
+			backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseFailed
+			backup.Status.Message = "The VolumeSnapshot API is not installed in the cluster"
+			return ctrl.Result{}, nil
+		} else if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		snapshotReady, size, failure := volumeSnapshotState(snapshot)
+		if failure != "" {
+			now := metav1.Now()
+			backup.Status.CompletionTime = &now
+			backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseFailed
+			backup.Status.Message = fmt.Sprintf("VolumeSnapshot %s failed: %s", name, failure)
+			backup.Status.Snapshots = names
+			return ctrl.Result{}, nil
+		}
+		if snapshotReady {
+			ready++
+			if size != nil {
+				total.Add(*size)
+			}
+		}
+	}
+	backup.Status.Snapshots = names
+
+	if ready < len(names) {
+		backup.Status.Message = fmt.Sprintf("%d/%d VolumeSnapshots ready", ready, len(names))
+		return ctrl.Result{RequeueAfter: snapshotRecheckInterval}, nil
+	}
+
+	now := metav1.Now()
+	backup.Status.CompletionTime = &now
+	backup.Status.Phase = databasesv1alpha1.DatabaseBackupPhaseCompleted
+	backup.Status.Location = volumeSnapshotScheme + strings.Join(names, ",")
+	backup.Status.Size = total
+	backup.Status.Message = ""
+	return ctrl.Result{}, nil
+}
+
+// reconcileScheduledSnapshot creates a DatabaseBackup for the last run of the
+// snapshot CronJob of a schedule. The CronJob only keeps the schedule: the
+// snapshots are taken by the DatabaseBackup controller.
+func (r *DatabaseReconciler) reconcileScheduledSnapshot(ctx context.Context, database *databasesv1alpha1.Database, schedule databasesv1alpha1.BackupSchedule, cronJob *batchv1.CronJob) error {
+	if cronJob.Status.LastScheduleTime == nil {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s-%s", scheduleBackupPrefix(database, schedule), strings.ToLower(cronJob.Status.LastScheduleTime.UTC().Format("20060102t150405z")))
+	backup := &databasesv1alpha1.DatabaseBackup{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: database.Namespace}, backup)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	backup = &databasesv1alpha1.DatabaseBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: database.Namespace,
+			Labels:    map[string]string{databasesv1alpha1.DatabaseLabel: database.Name},
+		},
+		Spec: databasesv1alpha1.DatabaseBackupSpec{
+			DatabaseRef: corev1.LocalObjectReference{Name: database.Name},
+		},
+	}
+	if schedule.Name != "" {
+		backup.Labels[databasesv1alpha1.BackupScheduleLabel] = schedule.Name
+		backup.Spec.Method = databasesv1alpha1.BackupMethodSnapshot
+	}
+
+	log.FromContext(ctx).Info("Creating scheduled snapshot backup", "name", name, "schedule", schedule.Name)
+	return r.Create(ctx, backup)
+}
+
+// createSnapshotCronJob builds the CronJob marking the snapshot schedule
+func (r *DatabaseReconciler) createSnapshotCronJob(database *databasesv1alpha1.Database, schedule databasesv1alpha1.BackupSchedule) *batchv1.CronJob {
+	return r.createAdminCronJob(database, scheduleComponent(schedule), cronSchedule(schedule),
+		fmt.Sprintf("echo \"Snapshot backup of %s scheduled\"", database.Name), nil)
+}
+
+// snapshotBackup returns the backup a restore reads from when it is a completed
+// Snapshot method backup, and nil otherwise
+func (r *DatabaseRestoreReconciler) snapshotBackup(ctx context.Context, restore *databasesv1alpha1.DatabaseRestore) (*databasesv1alpha1.DatabaseBackup, error) {
+	ref := restore.Spec.Source.BackupRef
+	if ref == nil {
+		return nil, nil
+	}
+
+	backup := &databasesv1alpha1.DatabaseBackup{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: restore.Namespace}, backup); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if backup.Status.Phase != databasesv1alpha1.DatabaseBackupPhaseCompleted || len(backup.Status.Snapshots) == 0 {
+		return nil, nil
+	}
+	return backup, nil
+}
+
+// reconcileSnapshotRestore restores a Snapshot method backup by replacing the data
+// volumes with volumes provisioned from its VolumeSnapshots. The lock keeps the
+// workload stopped meanwhile; the Database controller recreates it on the restored
+// volumes once the lock is released.
+func (r *DatabaseRestoreReconciler) reconcileSnapshotRestore(ctx context.Context, database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore, backup *databasesv1alpha1.DatabaseBackup) (ctrl.Result, error) {
+	if database.Spec.Storage == nil {
+		failRestore(restore, "Snapshot restores require spec.storage")
+		return ctrl.Result{}, nil
+	}
+
+	unready, err := r.unreadySnapshot(ctx, restore, backup)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if unready != "" {
+		failRestore(restore, unready)
+		return ctrl.Result{}, nil
+	}
+
+	acquired, active, err := r.acquireOfflineRestoreLock(ctx, restore)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !acquired {
+		restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseQueued
+		restore.Status.Message = fmt.Sprintf("Waiting for operation %s to finish", active)
+		return ctrl.Result{RequeueAfter: restoreRecheckInterval}, nil
+	}
+
+	if restore.Status.StartTime == nil {
+		now := metav1.Now()
+		restore.Status.StartTime = &now
+	}
+	restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseRunning
+	restore.Status.Message = ""
+
+	done, err := r.replaceDataVolumes(ctx, database, restore, backup)
+	if err != nil || !done {
+		return ctrl.Result{RequeueAfter: snapshotRecheckInterval}, err
+	}
+
+	now := metav1.Now()
+	restore.Status.CompletionTime = &now
+	restore.Status.Progress = ""
+	restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseCompleted
+	restore.Status.Message = fmt.Sprintf("Restored VolumeSnapshots of DatabaseBackup %q into Database %q", backup.Name, database.Name)
+	return ctrl.Result{}, nil
+}
+
+// unreadySnapshot checks every VolumeSnapshot of the backup before any data volume
+// is touched, and describes the first one that is missing or not ready to use
+func (r *DatabaseRestoreReconciler) unreadySnapshot(ctx context.Context, restore *databasesv1alpha1.DatabaseRestore, backup *databasesv1alpha1.DatabaseBackup) (string, error) {
+	for _, name := range backup.Status.Snapshots {
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: restore.Namespace}, snapshot)
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("VolumeSnapshot %s of DatabaseBackup %s not found", name, backup.Name), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if ready, _, failure := volumeSnapshotState(snapshot); !ready {
+			message := fmt.Sprintf("VolumeSnapshot %s of DatabaseBackup %s is not ready to use", name, backup.Name)
+			if failure != "" {
+				message += ": " + failure
+			}
+			return message, nil
+		}
+	}
+	return "", nil
+}
+
+// acquireOfflineRestoreLock takes the operation lock for a restore that stops the workload
+func (r *DatabaseRestoreReconciler) acquireOfflineRestoreLock(ctx context.Context, restore *databasesv1alpha1.DatabaseRestore) (acquired bool, active string, err error) {
+	err = updateDatabaseOperations(ctx, r.Client, databaseKey(restore), func(database *databasesv1alpha1.Database) {
+		acquired = acquireOperation(database, restoreOperation(restore), time.Now())
+		if acquired {
+			database.Status.Operations.Active.StopsWorkload = true
+		}
+		active = activeOperation(database)
+	})
+	return acquired, active, err
+}
+
+// replaceDataVolumes deletes the workload, then swaps every data volume for one
+// provisioned from the matching snapshot. Replicas beyond the snapshotted ones are
+// provisioned from the first snapshot. It reports whether all volumes are restored.
+func (r *DatabaseRestoreReconciler) replaceDataVolumes(ctx context.Context, database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore, backup *databasesv1alpha1.DatabaseBackup) (bool, error) {
+	log := log.FromContext(ctx)
+
+	if stopped, err := r.stopWorkload(ctx, database, restore); err != nil || !stopped {
+		return false, err
+	}
+
+	claims := dataClaimNames(database)
+	restored := 0
+	for i, claim := range claims {
+		snapshot := backup.Status.Snapshots[min(i, len(backup.Status.Snapshots)-1)]
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		err := r.Get(ctx, types.NamespacedName{Name: claim, Namespace: database.Namespace}, pvc)
+		switch {
+		case apierrors.IsNotFound(err):
+			pvc, err = r.databaseReconciler(r.Client, r.Scheme).newRestoredClaim(database, restore, claim, snapshot)
+			if err != nil {
+				return false, err
+			}
+			log.Info("Provisioning volume from snapshot", "claim", claim, "snapshot", snapshot)
+			if err := r.Create(ctx, pvc); err != nil {
+				return false, err
+			}
+			restored++
+		case err != nil:
+			return false, err
+		case pvc.Annotations[restoredByAnnotation] == string(restore.UID):
+			restored++
+		case pvc.DeletionTimestamp.IsZero():
+			log.Info("Deleting volume to restore it from snapshot", "claim", claim)
+			if err := r.Delete(ctx, pvc); err != nil && !apierrors.IsNotFound(err) {
+				return false, err
+			}
+		}
+	}
+
+	restore.Status.Progress = fmt.Sprintf("Replacing volumes (%d/%d)", restored, len(claims))
+	return restored == len(claims), nil
+}
+
+// stopWorkload deletes the workload of a restore holding a lock that stops it,
+// and reports whether it is gone
+func (r *DatabaseRestoreReconciler) stopWorkload(ctx context.Context, database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore) (bool, error) {
+	stopped, err := r.databaseReconciler(r.Client, r.Scheme).deleteWorkload(ctx, database)
+	if err == nil && !stopped {
+		restore.Status.Progress = "Stopping database"
+	}
+	return stopped, err
+}
+
+// deleteWorkload deletes the workload of an operation replacing the data
+// volumes, and reports whether it is gone. Volumes are only released once the
+// pods are gone, which foreground deletion waits for.
+func (r *DatabaseReconciler) deleteWorkload(ctx context.Context, database *databasesv1alpha1.Database) (bool, error) {
+	workloadResource := workloadResources(database)[0]
+	workload := newProvisionedObject(workloadResource.Kind)
+	err := r.Get(ctx, types.NamespacedName{Name: workloadResource.Name, Namespace: database.Namespace}, workload)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if workload.GetDeletionTimestamp().IsZero() {
+		log.FromContext(ctx).Info("Deleting workload to replace its volumes", "kind", workloadResource.Kind, "name", workloadResource.Name)
+		if err := r.Delete(ctx, workload, client.PropagationPolicy(metav1.DeletePropagationForeground)); err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// newRestoredClaim builds a data volume provisioned from a VolumeSnapshot, or an
+// empty one without snapshot, named like the volume the workload claims
+func (r *DatabaseReconciler) newRestoredClaim(database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore, claim, snapshot string) (*corev1.PersistentVolumeClaim, error) {
+	pvc, err := r.newDataClaim(database, claim)
+	if err != nil {
+		return nil, err
+	}
+	pvc.Annotations = map[string]string{restoredByAnnotation: string(restore.UID)}
+	if snapshot != "" {
+		apiGroup := volumeSnapshotGVK.Group
+		pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+			APIGroup: &apiGroup,
+			Kind:     volumeSnapshotGVK.Kind,
+			Name:     snapshot,
+		}
+	}
+	return pvc, nil
+}
+
+// newDataClaim builds an empty data volume of the size and class of the storage spec
+func (r *DatabaseReconciler) newDataClaim(database *databasesv1alpha1.Database, claim string) (*corev1.PersistentVolumeClaim, error) {
+	storage := database.Spec.Storage
+	size := dataVolumeSize(database)
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage size %q: %w", size, err)
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      claim,
+			Namespace: database.Namespace,
+			Labels:    r.getLabels(database),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{dataAccessMode(storage)},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: quantity},
+			},
+			StorageClassName: storage.StorageClass,
+		},
+	}, nil
+}
+
+// dataAccessMode returns the access mode of the storage spec, ReadWriteOnce by default
+func dataAccessMode(storage *databasesv1alpha1.StorageSpec) corev1.PersistentVolumeAccessMode {
+	if storage == nil || storage.AccessMode == "" {
+		return corev1.ReadWriteOnce
+	}
+	return corev1.PersistentVolumeAccessMode(storage.AccessMode)
+}
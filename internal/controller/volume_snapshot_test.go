@@ -0,0 +1,269 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Volume snapshots", func() {
+	var (
+		ctx    context.Context
+		scheme *runtime.Scheme
+		c      client.Client
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "shop"}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		scheme.AddKnownTypeWithName(volumeSnapshotGVK, &unstructured.Unstructured{})
+
+		database := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:  "16",
+				Replicas: ptr.To(int32(2)),
+				Storage: &databasesv1alpha1.StorageSpec{
+					Size:              "10Gi",
+					Snapshots:         true,
+					SnapshotClassName: ptr.To("csi-snapclass"),
+				},
+				Backup: &databasesv1alpha1.BackupSpec{Method: databasesv1alpha1.BackupMethodSnapshot},
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}, &databasesv1alpha1.DatabaseBackup{}, &databasesv1alpha1.DatabaseRestore{}).
+			WithObjects(database).Build()
+	})
+
+	It("should require snapshot support of the data storage", func() {
+		database := &databasesv1alpha1.Database{
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Storage: &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+				Backup:  &databasesv1alpha1.BackupSpec{Enabled: true, Method: databasesv1alpha1.BackupMethodSnapshot},
+			},
+		}
+		Expect((&DatabaseReconciler{}).validateSpec(database)).To(MatchError(ContainSubstring("spec.storage.snapshots")))
+
+		database.Spec.Storage.Snapshots = true
+		Expect((&DatabaseReconciler{}).validateSpec(database)).To(Succeed())
+	})
+
+	It("should snapshot every data volume and complete once they are ready", func() {
+		Expect(c.Create(ctx, &databasesv1alpha1.DatabaseBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseBackupSpec{DatabaseRef: corev1.LocalObjectReference{Name: "orders"}},
+		})).To(Succeed())
+		reconciler := &DatabaseBackupReconciler{Client: c, Scheme: scheme}
+
+		reconcile := func() *databasesv1alpha1.DatabaseBackup {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key("nightly")})
+			Expect(err).NotTo(HaveOccurred())
+			backup := &databasesv1alpha1.DatabaseBackup{}
+			Expect(c.Get(ctx, key("nightly"), backup)).To(Succeed())
+			return backup
+		}
+
+		backup := reconcile()
+		Expect(backup.Status.Phase).To(Equal(databasesv1alpha1.DatabaseBackupPhaseRunning))
+		Expect(backup.Status.Snapshots).To(Equal([]string{"nightly-0", "nightly-1"}))
+		Expect(backup.Status.JobName).To(BeEmpty())
+
+		for i, name := range backup.Status.Snapshots {
+			snapshot := &unstructured.Unstructured{}
+			snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+			Expect(c.Get(ctx, key(name), snapshot)).To(Succeed())
+			Expect(snapshot.GetOwnerReferences()).To(ContainElement(HaveField("Name", "nightly")))
+			claim, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName")
+			Expect(claim).To(Equal([]string{"data-orders-0", "data-orders-1"}[i]))
+			class, _, _ := unstructured.NestedString(snapshot.Object, "spec", "volumeSnapshotClassName")
+			Expect(class).To(Equal("csi-snapclass"))
+
+			snapshot.Object["status"] = map[string]interface{}{"readyToUse": true, "restoreSize": "1Gi"}
+			Expect(c.Update(ctx, snapshot)).To(Succeed())
+		}
+
+		backup = reconcile()
+		Expect(backup.Status.Phase).To(Equal(databasesv1alpha1.DatabaseBackupPhaseCompleted))
+		Expect(backup.Status.Location).To(Equal("volumesnapshot://nightly-0,nightly-1"))
+		Expect(backup.Status.Size.Cmp(resource.MustParse("2Gi"))).To(Equal(0))
+
+		database := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key("orders"), database)).To(Succeed())
+		Expect(database.Status.RecentOperations).To(ConsistOf(And(
+			HaveField("Type", recordBackup),
+			HaveField("Outcome", databasesv1alpha1.OperationSucceeded),
+			HaveField("Detail", "Backup nightly stored at volumesnapshot://nightly-0,nightly-1"),
+		)))
+
+		By("keeping nothing to clean up when the backup is deleted")
+		Expect(reconciler.runBackupCleanup(ctx, backup)).To(BeTrue())
+	})
+
+	It("should fail the restore without touching the volumes when a snapshot is not ready", func() {
+		Expect(c.Create(ctx, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"}})).To(Succeed())
+		Expect(c.Create(ctx, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-orders-0", Namespace: "shop"}})).To(Succeed())
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		snapshot.SetName("nightly-0")
+		snapshot.SetNamespace("shop")
+		snapshot.Object["status"] = map[string]interface{}{
+			"readyToUse": false,
+			"error":      map[string]interface{}{"message": "snapshot content deleted"},
+		}
+		Expect(c.Create(ctx, snapshot)).To(Succeed())
+		backup := &databasesv1alpha1.DatabaseBackup{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "shop"}}
+		Expect(c.Create(ctx, backup)).To(Succeed())
+		backup.Status = databasesv1alpha1.DatabaseBackupStatus{
+			Phase:     databasesv1alpha1.DatabaseBackupPhaseCompleted,
+			Location:  "volumesnapshot://nightly-0,nightly-1",
+			Snapshots: []string{"nightly-0", "nightly-1"},
+		}
+		Expect(c.Status().Update(ctx, backup)).To(Succeed())
+		Expect(c.Create(ctx, &databasesv1alpha1.DatabaseRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "rollback", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseRestoreSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: "orders"},
+				Source:      databasesv1alpha1.RestoreSource{BackupRef: &corev1.LocalObjectReference{Name: "nightly"}},
+			},
+		})).To(Succeed())
+		reconciler := &DatabaseRestoreReconciler{Client: c, Scheme: scheme}
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key("rollback")})
+		Expect(err).NotTo(HaveOccurred())
+		restore := &databasesv1alpha1.DatabaseRestore{}
+		Expect(c.Get(ctx, key("rollback"), restore)).To(Succeed())
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseFailed))
+		Expect(restore.Status.Message).To(Equal("VolumeSnapshot nightly-0 of DatabaseBackup nightly is not ready to use: snapshot content deleted"))
+
+		Expect(c.Get(ctx, key("orders"), &appsv1.StatefulSet{})).To(Succeed())
+		Expect(c.Get(ctx, key("data-orders-0"), &corev1.PersistentVolumeClaim{})).To(Succeed())
+		database := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key("orders"), database)).To(Succeed())
+		Expect(workloadStopped(database)).To(BeFalse())
+		Expect(database.Status.Operations).To(BeNil())
+
+		By("reporting a missing snapshot")
+		snapshot.Object["status"] = map[string]interface{}{"readyToUse": true}
+		Expect(c.Update(ctx, snapshot)).To(Succeed())
+		Expect(reconciler.unreadySnapshot(ctx, restore, backup)).To(Equal("VolumeSnapshot nightly-1 of DatabaseBackup nightly not found"))
+	})
+
+	It("should restore by replacing the data volumes while the workload is stopped", func() {
+		Expect(c.Create(ctx, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"}})).To(Succeed())
+		orders := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key("orders"), orders)).To(Succeed())
+		orders.Spec.Storage.AccessMode = string(corev1.ReadWriteOncePod)
+		Expect(c.Update(ctx, orders)).To(Succeed())
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		snapshot.SetName("nightly-0")
+		snapshot.SetNamespace("shop")
+		snapshot.Object["status"] = map[string]interface{}{"readyToUse": true, "restoreSize": "10Gi"}
+		Expect(c.Create(ctx, snapshot)).To(Succeed())
+		for _, claim := range []string{"data-orders-0", "data-orders-1"} {
+			Expect(c.Create(ctx, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: claim, Namespace: "shop"}})).To(Succeed())
+		}
+		backup := &databasesv1alpha1.DatabaseBackup{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "shop"}}
+		Expect(c.Create(ctx, backup)).To(Succeed())
+		backup.Status = databasesv1alpha1.DatabaseBackupStatus{
+			Phase:     databasesv1alpha1.DatabaseBackupPhaseCompleted,
+			Location:  "volumesnapshot://nightly-0",
+			Snapshots: []string{"nightly-0"},
+		}
+		Expect(c.Status().Update(ctx, backup)).To(Succeed())
+		Expect(c.Create(ctx, &databasesv1alpha1.DatabaseRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "rollback", Namespace: "shop", UID: "restore-uid"},
+			Spec: databasesv1alpha1.DatabaseRestoreSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: "orders"},
+				Source:      databasesv1alpha1.RestoreSource{BackupRef: &corev1.LocalObjectReference{Name: "nightly"}},
+			},
+		})).To(Succeed())
+		reconciler := &DatabaseRestoreReconciler{Client: c, Scheme: scheme}
+
+		reconcile := func() *databasesv1alpha1.DatabaseRestore {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key("rollback")})
+			Expect(err).NotTo(HaveOccurred())
+			restore := &databasesv1alpha1.DatabaseRestore{}
+			Expect(c.Get(ctx, key("rollback"), restore)).To(Succeed())
+			return restore
+		}
+		database := func() *databasesv1alpha1.Database {
+			database := &databasesv1alpha1.Database{}
+			Expect(c.Get(ctx, key("orders"), database)).To(Succeed())
+			return database
+		}
+
+		restore := reconcile()
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+		Expect(restore.Status.Progress).To(Equal("Stopping database"))
+		Expect(workloadStopped(database())).To(BeTrue())
+		Expect(apierrors.IsNotFound(c.Get(ctx, key("orders"), &appsv1.StatefulSet{}))).To(BeTrue())
+
+		By("keeping the workload down in the Database controller")
+		builder := &DatabaseReconciler{Client: c, Scheme: scheme}
+		Expect(builder.reconcileWorkload(ctx, database())).To(Succeed())
+		Expect(apierrors.IsNotFound(c.Get(ctx, key("orders"), &appsv1.StatefulSet{}))).To(BeTrue())
+
+		Expect(reconcile().Status.Progress).To(Equal("Replacing volumes (0/2)"))
+
+		restore = reconcile()
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseCompleted))
+		for _, claim := range []string{"data-orders-0", "data-orders-1"} {
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(c.Get(ctx, key(claim), pvc)).To(Succeed())
+			Expect(pvc.Spec.DataSource).To(Equal(&corev1.TypedLocalObjectReference{
+				APIGroup: ptr.To("snapshot.storage.k8s.io"), Kind: "VolumeSnapshot", Name: "nightly-0",
+			}))
+			Expect(pvc.Annotations).To(HaveKeyWithValue(restoredByAnnotation, "restore-uid"))
+			Expect(pvc.Spec.AccessModes).To(Equal([]corev1.PersistentVolumeAccessMode{corev1.ReadWriteOncePod}))
+		}
+		Expect(database().Status.Operations).To(BeNil())
+		Expect(database().Status.RecentOperations).To(ConsistOf(And(
+			HaveField("Type", recordRestore),
+			HaveField("Outcome", databasesv1alpha1.OperationSucceeded),
+		)))
+	})
+})
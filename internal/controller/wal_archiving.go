@@ -0,0 +1,201 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	walgVolume                = "wal-g"
+	walgMountPath             = "/wal-g"
+	walgBinary                = walgMountPath + "/wal-g"
+	postgresDataDir           = "/var/lib/postgresql/data"
+	postgresUID               = int64(999)
+	defaultBaseBackupInterval = 24 * time.Hour
+	walArchiveTimeout         = "60s"
+)
+
+// walgInstallScript copies wal-g out of its image into the shared volume
+const walgInstallScript = `cp "$(command -v wal-g)" ` + walgBinary
+
+// baseBackupScript takes a base backup as soon as PostgreSQL accepts connections
+// and then every BASE_BACKUP_INTERVAL seconds. After each base backup the ones
+// beyond RETAIN_FULL or older than RETAIN_SECONDS are deleted.
+const baseBackupScript = `until pg_isready -h localhost -q; do sleep 5; done
+while true; do
+  ` + walgBinary + ` backup-push "$PGDATA" || echo "base backup failed" >&2
+  if [ -n "$RETAIN_FULL" ]; then
+    ` + walgBinary + ` delete retain FULL "$RETAIN_FULL" --confirm || echo "retention failed" >&2
+  fi
+  if [ -n "$RETAIN_SECONDS" ]; then
+    before=$(date -u -d "@$(( $(date +%s) - RETAIN_SECONDS ))" +%Y-%m-%dT%H:%M:%SZ)
+    ` + walgBinary + ` delete before FIND_FULL "$before" --confirm || echo "retention failed" >&2
+  fi
+  sleep "$BASE_BACKUP_INTERVAL"
+done`
+
+// walArchivingEnabled reports whether the Database uses the WAL backup method
+func walArchivingEnabled(database *databasesv1alpha1.Database) bool {
+	backup := database.Spec.Backup
+	return backup != nil && backup.Enabled && backupMethod(database) == databasesv1alpha1.BackupMethodWAL
+}
+
+// validateWALArchiving checks the settings the WAL backup method depends on
+func validateWALArchiving(database *databasesv1alpha1.Database) error {
+	backup := database.Spec.Backup
+	if backup.S3 == nil || backup.S3.Bucket == "" {
+		return errors.New("WAL backups require backup.s3.bucket")
+	}
+	if backup.WAL == nil || backup.WAL.Image == "" {
+		return errors.New("WAL backups require backup.wal.image")
+	}
+	if database.Spec.Storage == nil {
+		return errors.New("WAL backups require spec.storage for the data directory")
+	}
+	if encryption := backup.Encryption; encryption != nil && isGoogleKMSKey(encryption.KMSKeyID) {
+		return errors.New("WAL archives can only be encrypted with AWS KMS keys")
+	}
+	return nil
+}
+
+// addWALArchiving installs wal-g into a PostgreSQL pod, archives every completed
+// WAL segment to S3 and runs a sidecar taking periodic base backups. Each pod
+// archives under its own prefix since replicas are independent servers.
+func (r *DatabaseReconciler) addWALArchiving(database *databasesv1alpha1.Database, podSpec *corev1.PodSpec) {
+	walgMount := corev1.VolumeMount{Name: walgVolume, MountPath: walgMountPath}
+	env := walgEnv(database)
+
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         walgVolume,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+		Name:         "wal-g-install",
+		Image:        database.Spec.Backup.WAL.Image,
+		Command:      []string{"/bin/sh", "-c", walgInstallScript},
+		VolumeMounts: []corev1.VolumeMount{walgMount},
+	})
+
+	postgres := &podSpec.Containers[0]
+	postgres.Env = append(postgres.Env, env...)
+	postgres.VolumeMounts = append(postgres.VolumeMounts, walgMount)
+	r.addCABundle(database, podSpec, postgres)
+	r.addProxyEnv(database, postgres)
+	postgres.Args = append(postgres.Args,
+		"-c", "wal_level=replica",
+		"-c", "archive_mode=on",
+		"-c", "archive_timeout="+walArchiveTimeout,
+		"-c", fmt.Sprintf("archive_command=%s wal-push %%p", walgBinary),
+	)
+
+	interval := baseBackupInterval(database)
+	uid := postgresUID
+	sidecarEnv := append([]corev1.EnvVar{}, env...)
+	sidecarEnv = append(sidecarEnv, renameEnv(r.getPostgreSQLEnv(database), map[string]string{
+		"POSTGRES_DB":       "PGDATABASE",
+		"POSTGRES_USER":     "PGUSER",
+		"POSTGRES_PASSWORD": "PGPASSWORD",
+	})...)
+	sidecarEnv = append(sidecarEnv,
+		corev1.EnvVar{Name: "PGHOST", Value: "localhost"},
+		corev1.EnvVar{Name: "PGDATA", Value: postgresDataDir},
+		corev1.EnvVar{Name: "BASE_BACKUP_INTERVAL", Value: strconv.FormatInt(int64(interval.Seconds()), 10)},
+	)
+	sidecarEnv = append(sidecarEnv, walRetentionEnv(database)...)
+
+	podSpec.Containers = append(podSpec.Containers, corev1.Container{
+		Name:    "wal-g",
+		Image:   postgres.Image,
+		Command: []string{"/bin/sh", "-c", baseBackupScript},
+		Env:     sidecarEnv,
+		VolumeMounts: []corev1.VolumeMount{
+			walgMount,
+			{Name: "data", MountPath: postgresDataDir},
+		},
+		SecurityContext: &corev1.SecurityContext{RunAsUser: &uid},
+	})
+	sidecar := &podSpec.Containers[len(podSpec.Containers)-1]
+	r.addCABundle(database, podSpec, sidecar)
+	r.addProxyEnv(database, sidecar)
+}
+
+// walgEnv configures the wal-g storage. POD_NAME comes first so WALG_S3_PREFIX can
+// reference it.
+func walgEnv(database *databasesv1alpha1.Database) []corev1.EnvVar {
+	s3 := database.Spec.Backup.S3
+
+	env := []corev1.EnvVar{
+		{
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		},
+		{
+			Name:  "WALG_S3_PREFIX",
+			Value: walgPrefix(database, "$(POD_NAME)"),
+		},
+	}
+
+	if s3.Endpoint != "" {
+		env = append(env, corev1.EnvVar{Name: "AWS_ENDPOINT", Value: s3.Endpoint})
+	}
+	if s3.Region != "" {
+		env = append(env, corev1.EnvVar{Name: "AWS_REGION", Value: s3.Region})
+	}
+	if s3.ForcePathStyle {
+		env = append(env, corev1.EnvVar{Name: "AWS_S3_FORCE_PATH_STYLE", Value: "true"})
+	}
+	env = append(env, s3CredentialsEnv(s3.CredentialsSecret)...)
+	// wal-g encrypts archives client-side with data keys of the KMS key
+	if encryption := database.Spec.Backup.Encryption; encryption != nil {
+		env = append(env,
+			corev1.EnvVar{Name: "WALG_CSE_KMS_ID", Value: encryption.KMSKeyID},
+			corev1.EnvVar{Name: "WALG_CSE_KMS_REGION", Value: awsKMSRegion(encryption.KMSKeyID)})
+	}
+
+	return env
+}
+
+// walgPrefix returns the S3 location a pod archives to
+func walgPrefix(database *databasesv1alpha1.Database, pod string) string {
+	s3 := database.Spec.Backup.S3
+	parts := []string{s3.Bucket}
+	if path := strings.Trim(s3.Path, "/"); path != "" {
+		parts = append(parts, path)
+	}
+	parts = append(parts, database.Namespace, database.Name, pod)
+	return "s3://" + strings.Join(parts, "/")
+}
+
+// baseBackupInterval returns the time between base backups
+func baseBackupInterval(database *databasesv1alpha1.Database) time.Duration {
+	if wal := database.Spec.Backup.WAL; wal != nil && wal.BaseBackupInterval != nil && wal.BaseBackupInterval.Duration > 0 {
+		return wal.BaseBackupInterval.Duration
+	}
+	return defaultBaseBackupInterval
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const walRestoreComponent = "wal-restore"
+
+// walRestoreScript recovers a data volume from a WAL archive. The latest base
+// backup taken before TARGET_TIME is selected first, so a missing one leaves the
+// volume untouched, and fetched into the emptied data directory; a local server
+// then replays the archived WAL up to TARGET_TIME, or to the end of the archive
+// without it, and promotes. The recovery settings are reset before the
+// server is stopped so the workload starts from the recovered data as a primary.
+const walRestoreScript = `set -e
+backup=LATEST
+if [ -n "$TARGET_TIME" ]; then
+  backup="$(` + walgBinary + ` backup-list | awk -v target="$TARGET_TIME" 'NR > 1 && $2 <= target { name = $1 } END { print name }')"
+  if [ -z "$backup" ]; then
+    echo "no base backup was taken before $TARGET_TIME" >&2
+    exit 1
+  fi
+fi
+find "$PGDATA" -mindepth 1 -maxdepth 1 ! -name lost+found -exec rm -rf {} +
+echo "Fetching base backup $backup"
+` + walgBinary + ` backup-fetch "$PGDATA" "$backup"
+chmod 700 "$PGDATA"
+cat >> "$PGDATA/postgresql.auto.conf" <<EOF
+restore_command = '` + walgBinary + ` wal-fetch %f %p'
+recovery_target_action = 'promote'
+EOF
+if [ -n "$TARGET_TIME" ]; then
+  echo "recovery_target_time = '$TARGET_TIME'" >> "$PGDATA/postgresql.auto.conf"
+fi
+touch "$PGDATA/recovery.signal"
+pg_ctl -D "$PGDATA" -o "-c listen_addresses=''" -l /tmp/recovery.log -W start
+until [ "$(psql -h /var/run/postgresql -d postgres -Atc 'SELECT pg_is_in_recovery()' 2>/dev/null)" = f ]; do
+  sleep 5
+  if ! pg_ctl -D "$PGDATA" status >/dev/null; then
+    cat /tmp/recovery.log >&2
+    echo "recovery stopped before the target was reached" >&2
+    exit 1
+  fi
+done
+psql -h /var/run/postgresql -d postgres -q -v ON_ERROR_STOP=1 \
+  -c 'ALTER SYSTEM RESET restore_command' \
+  -c 'ALTER SYSTEM RESET recovery_target_time' \
+  -c 'ALTER SYSTEM RESET recovery_target_action'
+pg_ctl -D "$PGDATA" -m fast -w stop
+echo "Recovered from the WAL archive"`
+
+// walArchiveDatabase returns the Database whose WAL archive a restore replays
+func walArchiveDatabase(restore *databasesv1alpha1.DatabaseRestore) types.NamespacedName {
+	if ref := restore.Spec.Source.WALArchive.DatabaseRef; ref != nil && ref.Name != "" {
+		return types.NamespacedName{Name: ref.Name, Namespace: restore.Namespace}
+	}
+	if fork := restore.Spec.Fork; fork != nil && fork.From != nil {
+		return types.NamespacedName{Name: fork.From.Name, Namespace: restore.Namespace}
+	}
+	return databaseKey(restore)
+}
+
+// reconcileWALRestore recovers a PostgreSQL Database to a point in time from a WAL
+// archive. Like snapshot restores it stops the workload, then runs a Job per data
+// volume replacing its content with the recovered data directory. The Database
+// controller recreates the workload once the lock is released.
+func (r *DatabaseRestoreReconciler) reconcileWALRestore(ctx context.Context, database *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore) (ctrl.Result, error) {
+	if database.Spec.Type != databasesv1alpha1.DatabaseTypePostgreSQL || database.Spec.Storage == nil {
+		failRestore(restore, "walArchive restores require a PostgreSQL Database with spec.storage")
+		return ctrl.Result{}, nil
+	}
+	if target := restore.Spec.PointInTime; target != nil && target.After(time.Now()) {
+		failRestore(restore, fmt.Sprintf("pointInTime %s is in the future", target.UTC().Format(time.RFC3339)))
+		return ctrl.Result{}, nil
+	}
+
+	source := &databasesv1alpha1.Database{}
+	sourceKey := walArchiveDatabase(restore)
+	if err := r.Get(ctx, sourceKey, source); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		setRestorePending(restore, fmt.Sprintf("Database %q not found", sourceKey.Name))
+		return ctrl.Result{RequeueAfter: backupPendingRecheckInterval}, nil
+	}
+	if !walArchivingEnabled(source) || source.Spec.Type != databasesv1alpha1.DatabaseTypePostgreSQL {
+		failRestore(restore, fmt.Sprintf("Database %q does not archive WAL", source.Name))
+		return ctrl.Result{}, nil
+	}
+
+	acquired, active, err := r.acquireOfflineRestoreLock(ctx, restore)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !acquired {
+		restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseQueued
+		restore.Status.Message = fmt.Sprintf("Waiting for operation %s to finish", active)
+		return ctrl.Result{RequeueAfter: restoreRecheckInterval}, nil
+	}
+
+	if restore.Status.StartTime == nil {
+		now := metav1.Now()
+		restore.Status.StartTime = &now
+	}
+	restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseRunning
+	restore.Status.Message = ""
+
+	if stopped, err := r.stopWorkload(ctx, database, restore); err != nil || !stopped {
+		return ctrl.Result{RequeueAfter: restoreRecheckInterval}, err
+	}
+
+	claims := dataClaimNames(database)
+	recovered := 0
+	for i, claim := range claims {
+		finished, err := r.recoverDataVolume(ctx, database, source, restore, claim, i)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if finished {
+			recovered++
+		}
+	}
+	if restoreFinished(restore) {
+		return ctrl.Result{}, nil
+	}
+	if recovered < len(claims) {
+		restore.Status.Progress = fmt.Sprintf("Recovering volumes (%d/%d)", recovered, len(claims))
+		return ctrl.Result{RequeueAfter: restoreRecheckInterval}, nil
+	}
+
+	now := metav1.Now()
+	restore.Status.CompletionTime = &now
+	restore.Status.Progress = ""
+	restore.Status.Phase = databasesv1alpha1.DatabaseRestorePhaseCompleted
+	restore.Status.Message = fmt.Sprintf("Recovered the WAL archive of Database %q into Database %q", source.Name, database.Name)
+	if target := restore.Spec.PointInTime; target != nil {
+		restore.Status.Message += " as of " + target.UTC().Format(time.RFC3339)
+	}
+	return ctrl.Result{}, nil
+}
+
+// recoverDataVolume runs the Job recovering one data volume, creating the volume
+// when the replica never ran. It reports whether the Job succeeded, and fails the
+// restore when it did not.
+func (r *DatabaseRestoreReconciler) recoverDataVolume(ctx context.Context, database, source *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore, claim string, ordinal int) (bool, error) {
+	log := log.FromContext(ctx)
+	builder := r.databaseReconciler(r.Client, r.Scheme)
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: claim, Namespace: database.Namespace}, pvc)
+	if apierrors.IsNotFound(err) {
+		if pvc, err = builder.newRestoredClaim(database, restore, claim, ""); err != nil {
+			return false, err
+		}
+		log.Info("Provisioning volume to recover", "claim", claim)
+		if err := r.Create(ctx, pvc); err != nil {
+			return false, err
+		}
+	} else if err != nil {
+		return false, err
+	}
+
+	job := &batchv1.Job{}
+	jobName := walRestoreJobName(restore, ordinal)
+	err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: restore.Namespace}, job)
+	if apierrors.IsNotFound(err) {
+		job = builder.createWALRestoreJob(database, source, restore, claim, ordinal)
+		if err := controllerutil.SetControllerReference(restore, job, r.Scheme); err != nil {
+			return false, err
+		}
+		log.Info("Creating WAL restore Job", "name", job.Name, "claim", claim)
+		return false, r.Create(ctx, job)
+	} else if err != nil {
+		return false, err
+	}
+
+	finished, succeeded := jobFinished(job)
+	if finished && !succeeded {
+		now := metav1.Now()
+		restore.Status.CompletionTime = &now
+		failRestore(restore, fmt.Sprintf("WAL restore Job %s failed, volume %s is left partially recovered", job.Name, claim))
+	}
+	return finished && succeeded, nil
+}
+
+// createWALRestoreJob builds the Job recovering a data volume of the database
+// from the WAL archive of the source Database. It runs the engine image of the
+// restored Database as the postgres user, with wal-g installed like in the
+// archiving pods.
+func (r *DatabaseReconciler) createWALRestoreJob(database, source *databasesv1alpha1.Database, restore *databasesv1alpha1.DatabaseRestore, claim string, ordinal int) *batchv1.Job {
+	pod := source.Name + "-0"
+	if walArchive := restore.Spec.Source.WALArchive; walArchive.Pod != "" {
+		pod = walArchive.Pod
+	}
+
+	env := walgEnv(source)
+	for i := range env {
+		if env[i].Name == "WALG_S3_PREFIX" {
+			env[i].Value = walgPrefix(source, pod)
+		}
+	}
+	env = append(env, renameEnv(r.getPostgreSQLEnv(database), map[string]string{
+		"POSTGRES_USER":     "PGUSER",
+		"POSTGRES_PASSWORD": "PGPASSWORD",
+	})...)
+	env = append(env, corev1.EnvVar{Name: "PGDATA", Value: postgresDataDir})
+	if target := restore.Spec.PointInTime; target != nil {
+		env = append(env, corev1.EnvVar{Name: "TARGET_TIME", Value: target.UTC().Format(time.RFC3339)})
+	}
+
+	backoffLimit := int32(0)
+	uid := postgresUID
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      walRestoreJobName(restore, ordinal),
+			Namespace: database.Namespace,
+			Labels:    r.getComponentLabels(database, walRestoreComponent),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template:     r.adminPodTemplate(database, walRestoreComponent, engineImage(database), walRestoreScript, env),
+		},
+	}
+
+	walgMount := corev1.VolumeMount{Name: walgVolume, MountPath: walgMountPath}
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes,
+		corev1.Volume{
+			Name:         walgVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+		corev1.Volume{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+			},
+		},
+	)
+	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+		Name:         "wal-g-install",
+		Image:        source.Spec.Backup.WAL.Image,
+		Command:      []string{"/bin/sh", "-c", walgInstallScript},
+		VolumeMounts: []corev1.VolumeMount{walgMount},
+	})
+
+	container := &podSpec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, walgMount,
+		corev1.VolumeMount{Name: "data", MountPath: postgresDataDir})
+	container.SecurityContext = &corev1.SecurityContext{RunAsUser: &uid}
+	return job
+}
+
+// walRestoreJobName is the name of the Job recovering the data volume of a replica
+func walRestoreJobName(restore *databasesv1alpha1.DatabaseRestore, ordinal int) string {
+	return fmt.Sprintf("%s-%s-%d", restore.Name, walRestoreComponent, ordinal)
+}
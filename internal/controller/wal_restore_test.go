@@ -0,0 +1,171 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Point-in-time recovery", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *DatabaseRestoreReconciler
+		target     metav1.Time
+	)
+
+	key := func(name string) types.NamespacedName {
+		return types.NamespacedName{Name: name, Namespace: "shop"}
+	}
+
+	reconcile := func(name string) *databasesv1alpha1.DatabaseRestore {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key(name)})
+		Expect(err).NotTo(HaveOccurred())
+		restore := &databasesv1alpha1.DatabaseRestore{}
+		Expect(c.Get(ctx, key(name), restore)).To(Succeed())
+		return restore
+	}
+
+	newRestore := func(name string, source *databasesv1alpha1.WALArchiveSource) *databasesv1alpha1.DatabaseRestore {
+		return &databasesv1alpha1.DatabaseRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseRestoreSpec{
+				DatabaseRef: corev1.LocalObjectReference{Name: "orders"},
+				Source:      databasesv1alpha1.RestoreSource{WALArchive: source},
+				PointInTime: &target,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		target = metav1.NewTime(time.Date(2025, 3, 2, 9, 30, 0, 0, time.UTC))
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithStatusSubresource(&databasesv1alpha1.Database{}, &databasesv1alpha1.DatabaseRestore{}).
+			WithObjects(
+				&databasesv1alpha1.Database{
+					ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+					Spec: databasesv1alpha1.DatabaseSpec{
+						Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+						Version: "16",
+						Storage: &databasesv1alpha1.StorageSpec{Size: "10Gi"},
+						Backup: &databasesv1alpha1.BackupSpec{
+							Enabled: true,
+							Method:  databasesv1alpha1.BackupMethodWAL,
+							S3:      &databasesv1alpha1.S3Destination{Bucket: "archive", CredentialsSecret: "s3-credentials"},
+							WAL:     &databasesv1alpha1.WALArchivingSpec{Image: "wal-g/wal-g:pg"},
+						},
+					},
+				},
+				&databasesv1alpha1.Database{
+					ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop"},
+					Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypePostgreSQL, Version: "16"},
+				},
+				&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"}},
+				&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-orders-0", Namespace: "shop"}},
+			).Build()
+		reconciler = &DatabaseRestoreReconciler{Client: c, Scheme: scheme}
+	})
+
+	It("should recover the data volumes from the WAL archive while the workload is stopped", func() {
+		Expect(c.Create(ctx, newRestore("pitr", &databasesv1alpha1.WALArchiveSource{}))).To(Succeed())
+
+		restore := reconcile("pitr")
+		Expect(restore.Status.Progress).To(Equal("Stopping database"))
+		Expect(apierrors.IsNotFound(c.Get(ctx, key("orders"), &appsv1.StatefulSet{}))).To(BeTrue())
+
+		restore = reconcile("pitr")
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseRunning))
+		Expect(restore.Status.Progress).To(Equal("Recovering volumes (0/1)"))
+
+		job := &batchv1.Job{}
+		Expect(c.Get(ctx, key("pitr-wal-restore-0"), job)).To(Succeed())
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(ContainElement(HaveField("Image", "wal-g/wal-g:pg")))
+		Expect(podSpec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "data-orders-0")))
+		Expect(podSpec.Containers[0].Image).To(Equal("postgres:16"))
+		Expect(podSpec.Containers[0].Env).To(ContainElements(
+			corev1.EnvVar{Name: "WALG_S3_PREFIX", Value: "s3://archive/shop/orders/orders-0"},
+			corev1.EnvVar{Name: "TARGET_TIME", Value: "2025-03-02T09:30:00Z"},
+			corev1.EnvVar{Name: "PGDATA", Value: postgresDataDir},
+		))
+		script := podSpec.Containers[0].Command[2]
+		Expect(script).To(ContainSubstring("recovery_target_time"))
+		Expect(strings.Index(script, "no base backup was taken before")).To(BeNumerically("<", strings.Index(script, "rm -rf")))
+
+		By("completing once every volume is recovered")
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		Expect(c.Status().Update(ctx, job)).To(Succeed())
+
+		restore = reconcile("pitr")
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseCompleted))
+		Expect(restore.Status.Message).To(ContainSubstring("as of 2025-03-02T09:30:00Z"))
+		database := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key("orders"), database)).To(Succeed())
+		Expect(database.Status.Operations).To(BeNil())
+	})
+
+	It("should replay the archive of another pod or Database", func() {
+		restore := newRestore("clone", &databasesv1alpha1.WALArchiveSource{
+			DatabaseRef: &corev1.LocalObjectReference{Name: "orders"},
+			Pod:         "orders-1",
+		})
+		source := &databasesv1alpha1.Database{}
+		Expect(c.Get(ctx, key("orders"), source)).To(Succeed())
+
+		job := reconciler.databaseReconciler(reconciler.Client, reconciler.Scheme).createWALRestoreJob(source, source, restore, "data-orders-0", 0)
+		Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(
+			corev1.EnvVar{Name: "WALG_S3_PREFIX", Value: "s3://archive/shop/orders/orders-1"}))
+
+		By("rejecting Databases without a WAL archive")
+		restore.Spec.Source.WALArchive.DatabaseRef.Name = "cache"
+		Expect(c.Create(ctx, restore)).To(Succeed())
+		Expect(reconcile("clone").Status.Message).To(Equal(`Database "cache" does not archive WAL`))
+	})
+
+	It("should reject a point in time in the future", func() {
+		target = metav1.NewTime(time.Now().Add(time.Hour))
+		Expect(c.Create(ctx, newRestore("future", &databasesv1alpha1.WALArchiveSource{}))).To(Succeed())
+		restore := reconcile("future")
+		Expect(restore.Status.Phase).To(Equal(databasesv1alpha1.DatabaseRestorePhaseFailed))
+		Expect(restore.Status.Message).To(ContainSubstring("in the future"))
+	})
+})
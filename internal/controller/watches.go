@@ -0,0 +1,91 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+const (
+	// managedByLabel and managedByValue mark the resources generated by the operator
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "database-operator"
+
+	// secretNamesField indexes Databases by the names of the Secrets they reference
+	secretNamesField = ".spec.secretNames"
+)
+
+// databaseChanged lets through the changes of the spec, including deletions,
+// and of the annotations of a Database. The status the operator writes does not
+// trigger another reconcile.
+func databaseChanged() predicate.Predicate {
+	return predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})
+}
+
+// managedByOperator lets through the events of the resources the operator
+// generated, which carry its managed-by label
+func managedByOperator() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return object.GetLabels()[managedByLabel] == managedByValue
+	})
+}
+
+// secretNames returns the names of the Secrets a Database references, including
+// the generated credentials Secret, which it does not own
+func secretNames(object client.Object) []string {
+	database, ok := object.(*databasesv1alpha1.Database)
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, reference := range secretKeyReferences(database) {
+		if !slices.Contains(names, reference.Name) {
+			names = append(names, reference.Name)
+		}
+	}
+	if reference := passwordSecret(database); reference != nil && !slices.Contains(names, reference.Name) {
+		names = append(names, reference.Name)
+	}
+	if target := database.Spec.TargetCluster; target != nil && !slices.Contains(names, target.KubeconfigSecret.Name) {
+		names = append(names, target.KubeconfigSecret.Name)
+	}
+	return names
+}
+
+// databasesForSecret maps a Secret to the Databases of its namespace that
+// reference it, so other Secrets of the namespace trigger no reconcile
+func (r *DatabaseReconciler) databasesForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	databases := &databasesv1alpha1.DatabaseList{}
+	if err := r.List(ctx, databases, client.InNamespace(secret.GetNamespace()),
+		client.MatchingFields{secretNamesField: secret.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list the Databases referencing a Secret", "secret", secret.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(databases.Items))
+	for i := range databases.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&databases.Items[i])})
+	}
+	return requests
+}
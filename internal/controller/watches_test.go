@@ -0,0 +1,98 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Watches", func() {
+	It("should ignore status updates of a Database", func() {
+		old := &databasesv1alpha1.Database{ObjectMeta: metav1.ObjectMeta{Name: "orders", Generation: 1}}
+		predicate := databaseChanged()
+
+		statusUpdate := old.DeepCopy()
+		statusUpdate.Status.Phase = databasesv1alpha1.DatabasePhaseReady
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: statusUpdate})).To(BeFalse())
+
+		specUpdate := old.DeepCopy()
+		specUpdate.Generation = 2
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: specUpdate})).To(BeTrue())
+
+		annotated := old.DeepCopy()
+		annotated.Annotations = map[string]string{databasesv1alpha1.FreezeUntilAnnotation: "2025-12-31T00:00:00Z"}
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: annotated})).To(BeTrue())
+	})
+
+	It("should only follow the resources generated by the operator", func() {
+		predicate := managedByOperator()
+		reconciler := &DatabaseReconciler{}
+		database := &databasesv1alpha1.Database{ObjectMeta: metav1.ObjectMeta{Name: "orders"}}
+		generated := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Labels: reconciler.getComponentLabels(database, "config")}}
+		Expect(predicate.Create(event.CreateEvent{Object: generated})).To(BeTrue())
+		Expect(predicate.Create(event.CreateEvent{Object: &corev1.ConfigMap{}})).To(BeFalse())
+	})
+
+	It("should reconcile the Databases referencing a Secret", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		orders := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:       databasesv1alpha1.DatabaseTypePostgreSQL,
+				PostgreSQL: &databasesv1alpha1.PostgreSQLConfig{PasswordSecret: &databasesv1alpha1.SecretReference{Name: "orders-admin", Key: "password"}},
+				Backup: &databasesv1alpha1.BackupSpec{
+					S3: &databasesv1alpha1.S3Destination{CredentialsSecret: "s3-credentials"},
+				},
+			},
+		}
+		cache := &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop"},
+			Spec:       databasesv1alpha1.DatabaseSpec{Type: databasesv1alpha1.DatabaseTypeRedis},
+		}
+		Expect(secretNames(orders)).To(Equal([]string{"orders-admin", "s3-credentials"}))
+
+		reconciler := &DatabaseReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(orders, cache).
+				WithIndex(&databasesv1alpha1.Database{}, secretNamesField, secretNames).Build(),
+			Scheme: scheme,
+		}
+		secret := func(name, namespace string) *corev1.Secret {
+			return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		}
+		Expect(reconciler.databasesForSecret(context.Background(), secret("s3-credentials", "shop"))).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "orders", Namespace: "shop"}},
+		}))
+		Expect(reconciler.databasesForSecret(context.Background(), secret("s3-credentials", "billing"))).To(BeEmpty())
+		Expect(reconciler.databasesForSecret(context.Background(), secret("unrelated", "shop"))).To(BeEmpty())
+	})
+})
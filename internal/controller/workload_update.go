@@ -0,0 +1,145 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+// updateStatefulSet converges an existing StatefulSet to the one generated from
+// the spec: its replicas, then its pod template
+func (r *DatabaseReconciler) updateStatefulSet(ctx context.Context, database *databasesv1alpha1.Database, statefulSet, desired *appsv1.StatefulSet) error {
+	// Recreated with new volume claim templates once deleted
+	if !statefulSet.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	if err := r.claimExisting(ctx, database, "StatefulSet", statefulSet); err != nil {
+		return err
+	}
+	if err := r.scaleStatefulSet(ctx, database, statefulSet, *desired.Spec.Replicas); err != nil {
+		return err
+	}
+	// Replicas are scaled above; the governing Service and the volume claim
+	// templates cannot change
+	desired.Spec.Replicas = statefulSet.Spec.Replicas
+	desired.Spec.ServiceName = statefulSet.Spec.ServiceName
+	desired.Spec.VolumeClaimTemplates = statefulSet.Spec.VolumeClaimTemplates
+	return r.updatePodTemplate(ctx, database, statefulSet, &statefulSet.Spec.Template, desired, &desired.Spec.Template)
+}
+
+// updatePodTemplate applies the workload generated from the spec when its pod
+// template differs from the current one, so changes of the version, the
+// resources, the environment or the engine configuration roll out to the pods.
+// The generated template only has to be a derivative of the current one:
+// fields defaulted by the API server and set by others, like restart stamps
+// and injected sidecars, are left alone. The rollout holds the Update
+// operation lock until every pod runs the new template, and goes through a
+// canary replica first when spec.rollout.canary is set. A change of the
+// resources alone is a vertical scale instead, restarting the replicas of a
+// StatefulSet one at a time under the VerticalScale lock, the primary last.
+func (r *DatabaseReconciler) updatePodTemplate(ctx context.Context, database *databasesv1alpha1.Database, workload client.Object, current *corev1.PodTemplateSpec, desired client.Object, template *corev1.PodTemplateSpec) error {
+	statefulSet, isStatefulSet := workload.(*appsv1.StatefulSet)
+	if isStatefulSet {
+		// Further changes wait for the replicas to run the new resources
+		if scaling, err := r.reconcileVerticalScale(ctx, database, statefulSet, time.Now()); err != nil || scaling {
+			return err
+		}
+	}
+	if equality.Semantic.DeepDerivative(*template, *current) {
+		if isStatefulSet {
+			if err := r.reconcileCanary(ctx, database, statefulSet, time.Now()); err != nil {
+				return err
+			}
+		}
+		if rolledOut(workload) {
+			if isStatefulSet {
+				completeRollout(database, statefulSet)
+			}
+			if activeOperation(database) == disruptiveOperationUpdate {
+				recordOperation(database, recordUpdate, databasesv1alpha1.OperationSucceeded,
+					"Rolled out the workload to the spec", time.Now())
+			}
+			releaseOperation(database, disruptiveOperationUpdate)
+		}
+		return nil
+	}
+
+	// Replicas restarted one by one have to be ready to start with
+	operation := disruptiveOperationUpdate
+	if isStatefulSet && statefulSet.Spec.Replicas != nil && *statefulSet.Spec.Replicas > 1 &&
+		statefulSet.Status.ReadyReplicas == *statefulSet.Spec.Replicas && resourcesOnlyChange(current, template) {
+		operation = disruptiveOperationVerticalScale
+	}
+	if frozen(database, time.Now()) {
+		log.FromContext(ctx).Info("Workload update deferred by the release freeze", "operation", operation)
+		return nil
+	}
+	if deferredToMaintenance(database, operation, time.Now()) {
+		log.FromContext(ctx).Info("Workload update deferred to the maintenance window", "operation", operation)
+		return nil
+	}
+
+	if !acquireOperation(database, operation, time.Now()) {
+		log.FromContext(ctx).Info("Workload update queued behind another operation", "operation", operation, "active", activeOperation(database))
+		return nil
+	}
+
+	log.FromContext(ctx).Info("Updating the pod template of the workload", "name", workload.GetName(), "operation", operation)
+	if desired, ok := desired.(*appsv1.StatefulSet); ok {
+		if operation == disruptiveOperationVerticalScale {
+			startVerticalScale(database, desired, time.Now())
+		} else {
+			startCanary(database, desired, time.Now())
+		}
+	}
+	stamped := appliedSpecStamped(workload)
+	if err := controllerutil.SetControllerReference(database, desired, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.apply(ctx, desired); err != nil {
+		return err
+	}
+	rollUpgrade(database, time.Now())
+	return r.stampApplied(ctx, desired, stamped)
+}
+
+// rolledOut reports whether every pod of a workload runs its current template
+func rolledOut(workload client.Object) bool {
+	switch workload := workload.(type) {
+	case *appsv1.StatefulSet:
+		return workload.Status.ObservedGeneration >= workload.Generation &&
+			workload.Status.UpdateRevision == workload.Status.CurrentRevision
+	case *appsv1.Deployment:
+		replicas := int32(1)
+		if workload.Spec.Replicas != nil {
+			replicas = *workload.Spec.Replicas
+		}
+		return workload.Status.ObservedGeneration >= workload.Generation &&
+			workload.Status.UpdatedReplicas == replicas
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Workload updates", func() {
+	var (
+		ctx        context.Context
+		reconciler *DatabaseReconciler
+		database   *databasesv1alpha1.Database
+		c          client.Client
+	)
+
+	key := types.NamespacedName{Name: "orders", Namespace: "shop"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		database = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop", UID: "orders-uid"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:     databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version:  "16.2",
+				Replicas: ptr.To(int32(2)),
+			},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(databasesv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reconciler = &DatabaseReconciler{Scheme: scheme}
+		statefulSet := reconciler.createPostgreSQLStatefulSet(database, 2, reconciler.getPostgreSQLEnv(database))
+		setCredentialsChecksum(&statefulSet.Spec.Template, "0123456789abcdef")
+		Expect(controllerutil.SetControllerReference(database, statefulSet, scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(statefulSet).WithInterceptorFuncs(applyPatches()).Build()
+		reconciler.Client = c
+	})
+
+	current := func() *appsv1.StatefulSet {
+		statefulSet := &appsv1.StatefulSet{}
+		Expect(c.Get(ctx, key, statefulSet)).To(Succeed())
+		return statefulSet
+	}
+
+	It("should leave an unchanged workload alone", func() {
+		before := current()
+		Expect(reconciler.reconcilePostgreSQL(ctx, database)).To(Succeed())
+		Expect(current().ResourceVersion).To(Equal(before.ResourceVersion))
+		Expect(database.Status.Operations).To(BeNil())
+	})
+
+	It("should roll out spec changes to the pod template", func() {
+		database.Spec.Version = "16.4"
+		database.Spec.Resources = &databasesv1alpha1.ResourceRequirements{Memory: "1Gi"}
+		Expect(reconciler.reconcilePostgreSQL(ctx, database)).To(Succeed())
+
+		statefulSet := current()
+		container := statefulSet.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(HaveSuffix(":16.4"))
+		Expect(container.Resources.Requests.Memory().String()).To(Equal("1Gi"))
+		Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue(credentialsChecksumAnnotation, "0123456789abcdef"))
+		Expect(activeOperation(database)).To(Equal(disruptiveOperationUpdate))
+
+		By("releasing the lock once every pod runs the new template")
+		statefulSet.Status.ObservedGeneration = statefulSet.Generation
+		statefulSet.Status.CurrentRevision = "orders-2"
+		statefulSet.Status.UpdateRevision = "orders-2"
+		statefulSet.Status.Replicas = 2
+		Expect(c.Status().Update(ctx, statefulSet)).To(Succeed())
+		Expect(reconciler.reconcilePostgreSQL(ctx, database)).To(Succeed())
+		Expect(database.Status.Operations).To(BeNil())
+		Expect(database.Status.RecentOperations).To(HaveLen(1))
+		Expect(database.Status.RecentOperations[0].Type).To(Equal(recordUpdate))
+	})
+
+	It("should defer the update during a release freeze", func() {
+		database.Annotations = map[string]string{
+			databasesv1alpha1.FreezeUntilAnnotation: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		}
+		database.Spec.Version = "16.4"
+		Expect(reconciler.reconcilePostgreSQL(ctx, database)).To(Succeed())
+		Expect(current().Spec.Template.Spec.Containers[0].Image).To(HaveSuffix(":16.2"))
+	})
+
+	It("should revert pod template changes made by others", func() {
+		statefulSet := current()
+		statefulSet.Spec.Template.Spec.Containers[0].Env = append(statefulSet.Spec.Template.Spec.Containers[0].Env[:1],
+			corev1.EnvVar{Name: "POSTGRES_USER", Value: "intruder"})
+		Expect(c.Update(ctx, statefulSet)).To(Succeed())
+
+		Expect(reconciler.reconcilePostgreSQL(ctx, database)).To(Succeed())
+		Expect(current().Spec.Template.Spec.Containers[0].Env[1].Value).To(Equal(adminUsername(database)))
+	})
+})
@@ -0,0 +1,85 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+	"github.com/ivikasavnish/database-crd/internal/controller"
+)
+
+// log is for logging in this package.
+var databaselog = logf.Log.WithName("database-resource")
+
+// SetupDatabaseWebhookWithManager registers the webhook for Database in the manager.
+func SetupDatabaseWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&databasesv1alpha1.Database{}).
+		WithValidator(&DatabaseCustomValidator{}).
+		Complete()
+}
+
+// The webhook only returns warnings, so it fails open: an unavailable webhook
+// never blocks changes to Databases.
+// +kubebuilder:webhook:path=/validate-databases-database-operator-io-v1alpha1-database,mutating=false,failurePolicy=ignore,sideEffects=None,groups=databases.database-operator.io,resources=databases,verbs=create;update,versions=v1alpha1,name=vdatabase-v1alpha1.kb.io,admissionReviewVersions=v1,timeoutSeconds=5
+
+// DatabaseCustomValidator returns the soft issues of Database specs as
+// admission warnings, shown by kubectl when the Database is applied. It never
+// rejects a Database: the controller validates specs and reports errors in the
+// status.
+type DatabaseCustomValidator struct{}
+
+var _ webhook.CustomValidator = &DatabaseCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Database.
+func (v *DatabaseCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	database, ok := obj.(*databasesv1alpha1.Database)
+	if !ok {
+		return nil, fmt.Errorf("expected a Database object but got %T", obj)
+	}
+	databaselog.V(1).Info("Validation for Database upon creation", "name", database.GetName())
+
+	return controller.SpecWarnings(database, nil, time.Now()), nil
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Database.
+func (v *DatabaseCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	database, ok := newObj.(*databasesv1alpha1.Database)
+	if !ok {
+		return nil, fmt.Errorf("expected a Database object for the newObj but got %T", newObj)
+	}
+	old, ok := oldObj.(*databasesv1alpha1.Database)
+	if !ok {
+		return nil, fmt.Errorf("expected a Database object for the oldObj but got %T", oldObj)
+	}
+	databaselog.V(1).Info("Validation for Database upon update", "name", database.GetName())
+
+	return controller.SpecWarnings(database, old, time.Now()), nil
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Database.
+func (v *DatabaseCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
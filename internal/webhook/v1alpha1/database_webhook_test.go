@@ -0,0 +1,76 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	databasesv1alpha1 "github.com/ivikasavnish/database-crd/api/v1alpha1"
+)
+
+var _ = Describe("Database Webhook", func() {
+	var (
+		obj       *databasesv1alpha1.Database
+		oldObj    *databasesv1alpha1.Database
+		validator DatabaseCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &databasesv1alpha1.Database{
+			ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+			Spec: databasesv1alpha1.DatabaseSpec{
+				Type:    databasesv1alpha1.DatabaseTypePostgreSQL,
+				Version: "12.20",
+				Storage: &databasesv1alpha1.StorageSpec{Size: "1Gi"},
+			},
+		}
+		oldObj = obj.DeepCopy()
+		validator = DatabaseCustomValidator{}
+	})
+
+	Context("When creating or updating Database under Validating Webhook", func() {
+		It("Should warn about end of life versions on creation", func() {
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(ContainSubstring("PostgreSQL 12.20 reached its end of life on 2024-11-21")))
+		})
+
+		It("Should warn about storage smaller than the data on update", func() {
+			obj.Spec.Version = "latest"
+			oldObj.Status.DiskUsage = &databasesv1alpha1.DiskUsageStatus{Volume: "data-orders-0", UsedBytes: 3 << 30}
+			warnings, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf("spec.storage.size 1Gi is smaller than the 3Gi of data on volume data-orders-0"))
+		})
+
+		It("Should never reject a Database", func() {
+			obj.Spec.Type = databasesv1alpha1.DatabaseTypeSQLite
+			replicas := int32(5)
+			obj.Spec.Replicas = &replicas
+			warnings, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+
+			warnings, err = validator.ValidateDelete(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+	})
+})
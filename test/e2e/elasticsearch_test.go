@@ -0,0 +1,39 @@
+//go:build e2e_elasticsearch
+
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+const elasticsearchIndex = "http://localhost:9200/e2e"
+
+// Elasticsearch has no dump backups; its scenario covers provisioning, upgrade
+// and deletion
+var _ = describeEngine(engineScenario{
+	engine:         "Elasticsearch",
+	version:        "8.15.0",
+	upgradeVersion: "8.17.0",
+	spec: `  resources:
+    memory: 2Gi
+    memoryLimit: 2Gi
+`,
+	workload: "statefulset/" + scenarioDatabase,
+	write: `curl -sf -XPUT -H 'Content-Type: application/json' ` + elasticsearchIndex +
+		`/_doc/1?refresh=true -d '{"marker": "` + scenarioMarker + `"}'`,
+	read:        `curl -sf ` + elasticsearchIndex + `/_doc/1 | sed -n 's/.*"marker":"\([^"]*\)".*/\1/p'`,
+	erase:       `curl -sf -XDELETE ` + elasticsearchIndex,
+	podSecurity: "restricted",
+})
@@ -0,0 +1,34 @@
+//go:build e2e_mongodb
+
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+const mongoDBClient = `mongosh -u "$MONGO_INITDB_ROOT_USERNAME" -p "$MONGO_INITDB_ROOT_PASSWORD" --quiet --eval`
+
+var _ = describeEngine(engineScenario{
+	engine:         "MongoDB",
+	version:        "7.0",
+	upgradeVersion: "8.0",
+	workload:       "statefulset/" + scenarioDatabase,
+	write:          mongoDBClient + ` 'db.getSiblingDB("e2e").markers.insertOne({marker: "` + scenarioMarker + `"})'`,
+	read:           mongoDBClient + ` 'db.getSiblingDB("e2e").markers.findOne().marker'`,
+	erase:          mongoDBClient + ` 'db.getSiblingDB("e2e").dropDatabase()'`,
+	backup:         true,
+	restore:        true,
+	podSecurity:    "restricted",
+})
@@ -0,0 +1,34 @@
+//go:build e2e_postgresql
+
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+const postgreSQLClient = `psql -U "$POSTGRES_USER" -d "$POSTGRES_DB" -v ON_ERROR_STOP=1 -tAc`
+
+var _ = describeEngine(engineScenario{
+	engine:         "PostgreSQL",
+	version:        "16",
+	upgradeVersion: "16.4",
+	workload:       "statefulset/" + scenarioDatabase,
+	write:          postgreSQLClient + ` "CREATE TABLE e2e (marker text); INSERT INTO e2e VALUES ('` + scenarioMarker + `')"`,
+	read:           postgreSQLClient + ` "SELECT marker FROM e2e"`,
+	erase:          postgreSQLClient + ` "DROP TABLE e2e"`,
+	backup:         true,
+	restore:        true,
+	podSecurity:    "baseline",
+})
@@ -0,0 +1,34 @@
+//go:build e2e_redis
+
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+const redisClient = `redis-cli ${REDIS_PASSWORD:+-a "$REDIS_PASSWORD" --no-auth-warning}`
+
+// Redis loads RDB files only at startup, so its backups are not restored
+var _ = describeEngine(engineScenario{
+	engine:         "Redis",
+	version:        "7.2",
+	upgradeVersion: "7.4",
+	workload:       "statefulset/" + scenarioDatabase,
+	write:          redisClient + " SET e2e " + scenarioMarker,
+	read:           redisClient + " GET e2e",
+	erase:          redisClient + " DEL e2e",
+	backup:         true,
+	podSecurity:    "restricted",
+})
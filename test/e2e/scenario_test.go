@@ -0,0 +1,221 @@
+/*
+Copyright 2025 Vikas Avnish.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/ivikasavnish/database-crd/test/utils"
+)
+
+// scenarioDatabase is the name of the Database of every engine scenario
+const scenarioDatabase = "e2e"
+
+// scenarioMarker is the value written before the backup and read after the restore
+const scenarioMarker = "e2e-marker"
+
+// engineScenario is the contract an engine passes end to end: a Database is
+// provisioned, backed up, restored, upgraded and deleted. Each engine registers
+// its scenario from a file built with its own e2e_<engine> tag.
+type engineScenario struct {
+	// engine is the Database type, e.g. PostgreSQL
+	engine string
+	// version is the version the Database is created at
+	version string
+	// upgradeVersion is the version the Database is upgraded to, in place
+	upgradeVersion string
+	// spec holds further fields of the Database spec, indented by two spaces
+	spec string
+	// workload is the StatefulSet or Deployment running the engine, as kind/name,
+	// where commands are run
+	workload string
+
+	// write stores scenarioMarker, read prints it and erase removes it; they
+	// are shell commands run in the engine container
+	write, read, erase string
+
+	// backup is false for engines without DatabaseBackup support
+	backup bool
+	// restore is false for engines without DatabaseRestore support
+	restore bool
+	// podSecurity is the Pod Security Standards level the pods of the engine
+	// comply with, enforced on the scenario namespace
+	podSecurity string
+}
+
+// scenarioTimeout bounds every step of a scenario, including image pulls
+const scenarioTimeout = 10 * time.Minute
+
+// describeEngine registers the ordered steps of an engine scenario in a
+// namespace of its own, so scenarios of different engines run in parallel
+func describeEngine(scenario engineScenario) bool {
+	return Describe(scenario.engine, Ordered, Label("engine", strings.ToLower(scenario.engine)), func() {
+		ns := "e2e-" + strings.ToLower(scenario.engine)
+
+		kubectl := func(args ...string) (string, error) {
+			return utils.Run(exec.Command("kubectl", append([]string{"-n", ns}, args...)...))
+		}
+		apply := func(manifest string) {
+			cmd := exec.Command("kubectl", "apply", "-n", ns, "-f", "-")
+			cmd.Stdin = strings.NewReader(manifest)
+			_, err := utils.Run(cmd)
+			Expect(err).NotTo(HaveOccurred(), "Failed to apply\n%s", manifest)
+		}
+		run := func(script string) (string, error) {
+			output, err := kubectl("exec", scenario.workload, "--", "sh", "-c", script)
+			return strings.TrimSpace(output), err
+		}
+		// phaseOf waits for the phase of a resource
+		phaseOf := func(resource, phase string) {
+			Eventually(func(g Gomega) {
+				output, err := kubectl("get", resource, "-o", "jsonpath={.status.phase}")
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(output).NotTo(Equal("Failed"), "%s failed", resource)
+				g.Expect(output).To(Equal(phase))
+			}, scenarioTimeout, 5*time.Second).Should(Succeed())
+		}
+		readMarker := func() {
+			Eventually(func(g Gomega) {
+				output, err := run(scenario.read)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(output).To(HaveSuffix(scenarioMarker))
+			}, time.Minute, 5*time.Second).Should(Succeed())
+		}
+
+		BeforeAll(func() {
+			By("creating the scenario namespace")
+			_, err := utils.Run(exec.Command("kubectl", "create", "ns", ns))
+			Expect(err).NotTo(HaveOccurred(), "Failed to create namespace")
+			_, err = utils.Run(exec.Command("kubectl", "label", "ns", ns,
+				"pod-security.kubernetes.io/enforce="+scenario.podSecurity))
+			Expect(err).NotTo(HaveOccurred(), "Failed to label namespace")
+		})
+
+		AfterAll(func() {
+			if CurrentSpecReport().Failed() {
+				By("fetching the resources of the failed scenario")
+				output, _ := kubectl("get", "databases,databasebackups,databaserestores,jobs,pods", "-o", "wide")
+				_, _ = fmt.Fprintf(GinkgoWriter, "Resources:\n%s", output)
+				output, _ = kubectl("get", "events", "--sort-by=.lastTimestamp")
+				_, _ = fmt.Fprintf(GinkgoWriter, "Events:\n%s", output)
+			}
+
+			By("removing the scenario namespace")
+			_, _ = utils.Run(exec.Command("kubectl", "delete", "ns", ns, "--wait=false"))
+		})
+
+		It("should provision the Database", func() {
+			apply(fmt.Sprintf(`apiVersion: databases.database-operator.io/v1alpha1
+kind: Database
+metadata:
+  name: %s
+spec:
+  type: %s
+  version: %q
+  replicas: 1
+  storage:
+    size: 1Gi
+%s`, scenarioDatabase, scenario.engine, scenario.version, scenario.spec))
+			phaseOf("database/"+scenarioDatabase, "Ready")
+			output, err := kubectl("get", "database", scenarioDatabase,
+				"-o", `jsonpath={.status.conditions[?(@.type=="PodSecurity")].reason}`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.ToLower(output)).To(Equal(scenario.podSecurity))
+
+			By("writing the marker")
+			Eventually(func() error {
+				_, err := run(scenario.write)
+				return err
+			}, 2*time.Minute, 5*time.Second).Should(Succeed())
+			readMarker()
+		})
+
+		It("should back up the Database", func() {
+			if !scenario.backup {
+				Skip(scenario.engine + " has no DatabaseBackup support")
+			}
+			apply(fmt.Sprintf(`apiVersion: databases.database-operator.io/v1alpha1
+kind: DatabaseBackup
+metadata:
+  name: %[1]s
+spec:
+  databaseRef:
+    name: %[1]s
+`, scenarioDatabase))
+			phaseOf("databasebackup/"+scenarioDatabase, "Completed")
+		})
+
+		It("should restore the backup", func() {
+			if !scenario.backup || !scenario.restore {
+				Skip(scenario.engine + " has no DatabaseRestore support")
+			}
+			By("erasing the marker")
+			_, err := run(scenario.erase)
+			Expect(err).NotTo(HaveOccurred())
+
+			apply(fmt.Sprintf(`apiVersion: databases.database-operator.io/v1alpha1
+kind: DatabaseRestore
+metadata:
+  name: %[1]s
+spec:
+  databaseRef:
+    name: %[1]s
+  source:
+    backupRef:
+      name: %[1]s
+`, scenarioDatabase))
+			phaseOf("databaserestore/"+scenarioDatabase, "Completed")
+			phaseOf("database/"+scenarioDatabase, "Ready")
+			readMarker()
+		})
+
+		It("should upgrade the Database", func() {
+			if scenario.upgradeVersion == "" {
+				Skip(scenario.engine + " has no upgrade path in the scenario")
+			}
+			_, err := kubectl("patch", "database", scenarioDatabase, "--type=merge",
+				"-p", fmt.Sprintf(`{"spec":{"version":%q}}`, scenario.upgradeVersion))
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func(g Gomega) {
+				output, err := kubectl("get", "database", scenarioDatabase, "-o", "jsonpath={.status.version}")
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(output).To(Equal(scenario.upgradeVersion))
+			}, scenarioTimeout, 5*time.Second).Should(Succeed())
+			phaseOf("database/"+scenarioDatabase, "Ready")
+			readMarker()
+		})
+
+		It("should delete the Database and its resources", func() {
+			_, err := kubectl("delete", "database", scenarioDatabase, "--timeout=5m")
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func(g Gomega) {
+				output, err := kubectl("get", scenario.workload, "service/"+scenarioDatabase+"-service",
+					"-o", "name", "--ignore-not-found")
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(output).To(BeEmpty())
+			}, 2*time.Minute, 5*time.Second).Should(Succeed())
+		})
+	})
+}